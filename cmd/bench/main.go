@@ -0,0 +1,131 @@
+// Command bench measures the compile and eval throughput of the CEL engine.
+//
+// It runs the workloads shared with the celengine benchmarks and prints one line per
+// workload, along with the cel-go version, so results can be compared across cel-go bumps:
+//
+//	go run ./cmd/bench -run eval/ -benchtime 2s
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/invakid404/wasm-cel/internal/bench"
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+// Result is the measurement of a single workload
+type Result struct {
+	Name        string  `json:"name"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     int64   `json:"nsPerOp"`
+	OpsPerSec   float64 `json:"opsPerSec"`
+	BytesPerOp  int64   `json:"bytesPerOp"`
+	AllocsPerOp int64   `json:"allocsPerOp"`
+}
+
+// Report is the output of a benchmark run
+type Report struct {
+	CelGoVersion string   `json:"celGoVersion"`
+	Results      []Result `json:"results"`
+}
+
+func main() {
+	testing.Init()
+
+	run := flag.String("run", "", "comma-separated workload name prefixes to run (default all)")
+	benchtime := flag.String("benchtime", "1s", "time or iteration count (e.g. 100x) per workload")
+	jsonOutput := flag.Bool("json", false, "print the report as JSON")
+	list := flag.Bool("list", false, "list the workloads and exit")
+	flag.Parse()
+
+	if *list {
+		for _, workload := range bench.Workloads {
+			fmt.Printf("%-20s %s\n", workload.Name, workload.Description)
+		}
+		return
+	}
+
+	if err := flag.Set("test.benchtime", *benchtime); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid benchtime: %v\n", err)
+		os.Exit(2)
+	}
+
+	report := Report{CelGoVersion: fmt.Sprint(celengine.GetCapabilities()["celGoVersion"])}
+	for _, workload := range bench.Workloads {
+		if !bench.Match(workload.Name, *run) {
+			continue
+		}
+
+		result, err := measure(workload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", workload.Name, err)
+			os.Exit(1)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	printReport(os.Stdout, report)
+}
+
+// measure runs a workload with the testing package's benchmark driver
+func measure(workload bench.Workload) (Result, error) {
+	op, cleanup, err := workload.Setup()
+	if err != nil {
+		return Result{}, fmt.Errorf("setup failed: %w", err)
+	}
+	defer cleanup()
+
+	var opErr error
+	benchResult := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := op(); err != nil {
+				opErr = err
+				b.FailNow()
+			}
+		}
+	})
+	if opErr != nil {
+		return Result{}, opErr
+	}
+
+	result := Result{
+		Name:        workload.Name,
+		Iterations:  benchResult.N,
+		NsPerOp:     benchResult.NsPerOp(),
+		BytesPerOp:  benchResult.AllocedBytesPerOp(),
+		AllocsPerOp: benchResult.AllocsPerOp(),
+	}
+	if result.NsPerOp > 0 {
+		result.OpsPerSec = 1e9 / float64(result.NsPerOp)
+	}
+	return result, nil
+}
+
+// printReport writes the report as an aligned table
+func printReport(out io.Writer, report Report) {
+	fmt.Fprintf(out, "cel-go %s\n\n", report.CelGoVersion)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "workload\titerations\tns/op\tops/s\tB/op\tallocs/op")
+	for _, result := range report.Results {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.0f\t%d\t%d\n",
+			result.Name, result.Iterations, result.NsPerOp, result.OpsPerSec, result.BytesPerOp, result.AllocsPerOp)
+	}
+	w.Flush()
+}
@@ -0,0 +1,394 @@
+// Command buildwasm builds the standard, slim and full WASM modules in one run.
+//
+// Each variant is built with its own build tags for the Go/JavaScript runtime and for
+// WASI, optimized with wasm-opt when it is installed, and listed with its size and
+// SHA-256 hash in a manifest.json written next to the modules. The JavaScript modules
+// come with the wasm_exec.js of the toolchain that built them and a loader.mjs loading
+// them, which checks that it loads the version it was generated with:
+//
+//	go run ./cmd/buildwasm -out build/wasm -variants slim,full
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// versionVariable is the variable the module reports its version from
+const versionVariable = "github.com/invakid404/wasm-cel/pkg/celengine.Version"
+
+// Variant is a set of build tags the modules are built with
+type Variant struct {
+	Name        string
+	Description string
+	Tags        []string
+}
+
+// variants are the variants built by default, from the fullest to the slimmest
+var variants = []Variant{
+	{
+		Name:        "full",
+		Description: "every feature",
+	},
+	{
+		Name:        "standard",
+		Description: "every feature but Locale and Lint",
+		Tags:        []string{"wasmcel_nolocale", "wasmcel_nolint"},
+	},
+	{
+		Name:        "slim",
+		Description: "the CEL standard library and the module's own options only",
+		Tags:        []string{"wasmcel_noext", "wasmcel_noproto", "wasmcel_nolocale", "wasmcel_nojwt", "wasmcel_nolint"},
+	},
+}
+
+// Target is a runtime the modules are built for
+type Target struct {
+	Name    string
+	GOOS    string
+	Package string
+	// FileName is the base name of the module, suffixed with the name of the variant
+	FileName string
+}
+
+// targets are the runtimes each variant is built for
+var targets = []Target{
+	{Name: "js", GOOS: "js", Package: "./cmd/wasm", FileName: "main"},
+	{Name: "wasip1", GOOS: "wasip1", Package: "./cmd/wasip1", FileName: "cel-wasip1"},
+}
+
+// Config controls a single build run
+type Config struct {
+	// RootDir is the root of the repository, the current directory if empty
+	RootDir string
+	// OutputDir is the directory the modules and the manifest are written to
+	OutputDir string
+	// Variants are the variants to build
+	Variants []Variant
+	// Version is reported by the modules, read from the package.json of the repository if
+	// empty
+	Version string
+	// WasmOpt is the wasm-opt binary optimizing the modules. Modules aren't optimized if
+	// it's empty or can't be found
+	WasmOpt string
+	// Log receives progress messages, if set
+	Log io.Writer
+}
+
+// Artifact is a module listed in the manifest
+type Artifact struct {
+	Variant string   `json:"variant"`
+	Target  string   `json:"target"`
+	File    string   `json:"file"`
+	Tags    []string `json:"tags"`
+	Size    int64    `json:"size"`
+	SHA256  string   `json:"sha256"`
+	WasmOpt bool     `json:"wasmOpt"`
+}
+
+// Manifest describes the modules of a build run
+type Manifest struct {
+	Version   string     `json:"version"`
+	GoVersion string     `json:"goVersion"`
+	Artifacts []Artifact `json:"artifacts"`
+	// Loader is the file loading the JavaScript modules
+	Loader string `json:"loader"`
+	// WasmExec is the Go glue the JavaScript modules run with
+	WasmExec string `json:"wasmExec"`
+}
+
+// loaderTemplate generates the loader of the JavaScript modules
+//
+//go:embed loader.mjs.tmpl
+var loaderTemplate string
+
+// loaderFile and wasmExecFile are the names of the files the JavaScript modules are
+// loaded with
+const (
+	loaderFile   = "loader.mjs"
+	wasmExecFile = "wasm_exec.js"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Println("Usage: buildwasm [flags]")
+		fmt.Println("Builds the WASM modules of each variant and writes a manifest of them")
+		fmt.Println("Variants:")
+		for _, variant := range variants {
+			fmt.Printf("  %-10s %s\n", variant.Name, variant.Description)
+		}
+		flag.PrintDefaults()
+	}
+	out := flag.String("out", "build/wasm", "directory to write the modules and manifest.json to")
+	names := flag.String("variants", "", "comma-separated variants to build (default all)")
+	version := flag.String("version", "", "version reported by the modules (default the version of package.json)")
+	wasmOpt := flag.String("wasm-opt", "wasm-opt", "wasm-opt binary to optimize the modules with, or empty to skip optimizing")
+	flag.Parse()
+
+	selected, err := selectVariants(*names)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config := Config{
+		OutputDir: *out,
+		Variants:  selected,
+		Version:   *version,
+		WasmOpt:   *wasmOpt,
+		Log:       os.Stdout,
+	}
+	if _, err := build(config); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// selectVariants returns the variants named in a comma-separated list, or every variant
+// if the list is empty
+func selectVariants(names string) ([]Variant, error) {
+	if names == "" {
+		return variants, nil
+	}
+	var selected []Variant
+	for _, name := range strings.Split(names, ",") {
+		variant, ok := findVariant(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown variant %q", name)
+		}
+		selected = append(selected, variant)
+	}
+	return selected, nil
+}
+
+// findVariant returns the variant with a name
+func findVariant(name string) (Variant, bool) {
+	for _, variant := range variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+// build builds the modules of every variant for every target into the output directory
+// and writes their manifest
+func build(config Config) (*Manifest, error) {
+	logf := func(format string, args ...interface{}) {
+		if config.Log != nil {
+			fmt.Fprintf(config.Log, format, args...)
+		}
+	}
+
+	version := config.Version
+	if version == "" {
+		var err error
+		if version, err = packageVersion(filepath.Join(config.RootDir, "package.json")); err != nil {
+			return nil, err
+		}
+	}
+	wasmOpt := ""
+	if config.WasmOpt != "" {
+		if path, err := exec.LookPath(config.WasmOpt); err == nil {
+			wasmOpt = path
+		} else {
+			logf("%s not found, skipping optimization\n", config.WasmOpt)
+		}
+	}
+
+	outputDir, err := filepath.Abs(config.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{Version: version, GoVersion: runtime.Version()}
+	for _, variant := range config.Variants {
+		for _, target := range targets {
+			file := target.FileName + "-" + variant.Name + ".wasm"
+			path := filepath.Join(outputDir, file)
+			logf("Building %s\n", file)
+
+			if err := goBuild(config.RootDir, target, variant, version, path); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if wasmOpt != "" {
+				if err := optimize(wasmOpt, path); err != nil {
+					return nil, fmt.Errorf("%s: %w", file, err)
+				}
+			}
+
+			size, hash, err := fileDigest(path)
+			if err != nil {
+				return nil, err
+			}
+			tags := variant.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			manifest.Artifacts = append(manifest.Artifacts, Artifact{
+				Variant: variant.Name,
+				Target:  target.Name,
+				File:    file,
+				Tags:    tags,
+				Size:    size,
+				SHA256:  hash,
+				WasmOpt: wasmOpt != "",
+			})
+		}
+	}
+
+	if err := copyWasmExec(config.RootDir, filepath.Join(outputDir, wasmExecFile)); err != nil {
+		return nil, err
+	}
+	if err := writeLoader(filepath.Join(outputDir, loaderFile), manifest); err != nil {
+		return nil, err
+	}
+	manifest.Loader = loaderFile
+	manifest.WasmExec = wasmExecFile
+	logf("Generated %s\n", loaderFile)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifestPath := filepath.Join(config.OutputDir, "manifest.json")
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	logf("Wrote %s\n", manifestPath)
+	return manifest, nil
+}
+
+// goBuild builds the module of a target with the tags of a variant, stripped like the
+// modules published to npm
+func goBuild(rootDir string, target Target, variant Variant, version string, path string) error {
+	args := []string{"build", "-trimpath", "-ldflags", fmt.Sprintf("-s -w -X %s=%s", versionVariable, version)}
+	if len(variant.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(variant.Tags, ","))
+	}
+	args = append(args, "-o", path, target.Package)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = rootDir
+	cmd.Env = append(os.Environ(), "GOOS="+target.GOOS, "GOARCH=wasm")
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// optimize optimizes a module for size with wasm-opt, in place
+// Go modules use the sign extension, bulk memory and non-trapping conversion features
+func optimize(wasmOpt string, path string) error {
+	cmd := exec.Command(wasmOpt, "-Oz",
+		"--enable-sign-ext", "--enable-bulk-memory", "--enable-nontrapping-float-to-int",
+		path, "-o", path)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// fileDigest returns the size and hex-encoded SHA-256 hash of a file
+func fileDigest(path string) (int64, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", err
+	}
+	sum := sha256.Sum256(data)
+	return int64(len(data)), hex.EncodeToString(sum[:]), nil
+}
+
+// packageVersion returns the version of the npm package described by a package.json
+func packageVersion(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the version: %w", err)
+	}
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if pkg.Version == "" {
+		return "", fmt.Errorf("%s has no version", path)
+	}
+	return pkg.Version, nil
+}
+
+// copyWasmExec copies the wasm_exec.js of the Go toolchain building the modules, which
+// is the only one they run with
+func copyWasmExec(rootDir string, path string) error {
+	cmd := exec.Command("go", "env", "GOROOT")
+	cmd.Dir = rootDir
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to find GOROOT: %w", err)
+	}
+	goroot := strings.TrimSpace(string(output))
+
+	// Go 1.24 moved wasm_exec.js from misc/wasm to lib/wasm
+	for _, dir := range []string{"lib", "misc"} {
+		data, err := os.ReadFile(filepath.Join(goroot, dir, "wasm", wasmExecFile))
+		if err == nil {
+			return os.WriteFile(path, data, 0644)
+		}
+	}
+	return fmt.Errorf("%s not found in %s", wasmExecFile, goroot)
+}
+
+// loaderVariant is a JavaScript module as listed by the loader
+type loaderVariant struct {
+	File   string `json:"file"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// writeLoader generates the loader of the JavaScript modules of a manifest, which loads
+// the fullest variant built by default
+func writeLoader(path string, manifest *Manifest) error {
+	built := make(map[string]loaderVariant)
+	for _, artifact := range manifest.Artifacts {
+		if artifact.Target == "js" {
+			built[artifact.Variant] = loaderVariant{File: artifact.File, Size: artifact.Size, SHA256: artifact.SHA256}
+		}
+	}
+	defaultVariant := ""
+	for _, variant := range variants {
+		if _, ok := built[variant.Name]; ok {
+			defaultVariant = variant.Name
+			break
+		}
+	}
+
+	tmpl, err := template.New(loaderFile).Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+	}).Parse(loaderTemplate)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"Version":        manifest.Version,
+		"GoVersion":      manifest.GoVersion,
+		"Variants":       built,
+		"DefaultVariant": defaultVariant,
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
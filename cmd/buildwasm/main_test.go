@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// rootDir is the root of the repository, relative to this package
+const rootDir = "../.."
+
+func TestSelectVariants(t *testing.T) {
+	selected, err := selectVariants("slim, full")
+	if err != nil {
+		t.Fatalf("selectVariants failed: %v", err)
+	}
+	if len(selected) != 2 || selected[0].Name != "slim" || selected[1].Name != "full" {
+		t.Errorf("expected the slim and full variants, got %+v", selected)
+	}
+
+	if all, _ := selectVariants(""); len(all) != len(variants) {
+		t.Errorf("expected every variant, got %+v", all)
+	}
+	if _, err := selectVariants("tiny"); err == nil {
+		t.Errorf("expected an error for an unknown variant")
+	}
+}
+
+// The slim variant leaves out every feature internal/options puts behind a build tag
+func TestSlimTags(t *testing.T) {
+	slim, _ := findVariant("slim")
+	tags := make(map[string]bool)
+	for _, tag := range slim.Tags {
+		tags[tag] = true
+	}
+
+	constraint := regexp.MustCompile(`^//go:build !(wasmcel_no\w+)\n`)
+	files, err := filepath.Glob(filepath.Join(rootDir, "internal", "options", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := constraint.FindSubmatch(data); match != nil && !tags[string(match[1])] {
+			t.Errorf("the slim variant is missing the %s tag of %s", match[1], filepath.Base(file))
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds WASM modules")
+	}
+
+	slim, _ := findVariant("slim")
+	output := t.TempDir()
+	manifest, err := build(Config{
+		RootDir:   rootDir,
+		OutputDir: output,
+		Variants:  []Variant{slim},
+		Version:   "1.2.3",
+	})
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	if manifest.Version != "1.2.3" || len(manifest.Artifacts) != len(targets) {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	for _, artifact := range manifest.Artifacts {
+		data, err := os.ReadFile(filepath.Join(output, artifact.File))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", artifact.File, err)
+		}
+		sum := sha256.Sum256(data)
+		if artifact.Size != int64(len(data)) || artifact.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s doesn't match its manifest entry", artifact.File)
+		}
+		if artifact.WasmOpt {
+			t.Errorf("%s shouldn't be optimized without wasm-opt", artifact.File)
+		}
+	}
+
+	if manifest.Loader != loaderFile || manifest.WasmExec != wasmExecFile {
+		t.Errorf("expected the manifest to list the loader files, got %+v", manifest)
+	}
+	if _, err := os.Stat(filepath.Join(output, wasmExecFile)); err != nil {
+		t.Errorf("expected %s to be copied: %v", wasmExecFile, err)
+	}
+
+	var written Manifest
+	if err := json.Unmarshal(readFile(t, filepath.Join(output, "manifest.json")), &written); err != nil {
+		t.Fatalf("failed to parse manifest.json: %v", err)
+	}
+	if len(written.Artifacts) != len(manifest.Artifacts) {
+		t.Errorf("expected manifest.json to list %d modules, got %d", len(manifest.Artifacts), len(written.Artifacts))
+	}
+}
+
+func TestWriteLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), loaderFile)
+	err := writeLoader(path, &Manifest{
+		Version: "1.2.3",
+		Artifacts: []Artifact{
+			{Variant: "slim", Target: "js", File: "main-slim.wasm", SHA256: "abc"},
+			{Variant: "slim", Target: "wasip1", File: "cel-wasip1-slim.wasm"},
+			{Variant: "full", Target: "js", File: "main-full.wasm"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("writeLoader failed: %v", err)
+	}
+
+	loader := string(readFile(t, path))
+	for _, want := range []string{
+		`export const version = "1.2.3";`,
+		`const defaultVariant = "full";`,
+		`"slim":{"file":"main-slim.wasm","size":0,"sha256":"abc"}`,
+	} {
+		if !strings.Contains(loader, want) {
+			t.Errorf("expected the loader to contain %s", want)
+		}
+	}
+	if strings.Contains(loader, "cel-wasip1-slim.wasm") {
+		t.Errorf("expected the loader to leave out the WASI modules")
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return data
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/dave/jennifer/jen"
@@ -16,13 +19,53 @@ import (
 
 const (
 	celPackageName = "github.com/google/cel-go/cel"
-	envOptionType  = celPackageName + ".EnvOption"
+	extPackageName = "github.com/google/cel-go/ext"
 )
 
+// defaultPackages are always scanned for option constructors, in this order
+var defaultPackages = []string{celPackageName, extPackageName}
+
+// OptionKind describes a family of option constructors and the registry generated for them
+type OptionKind struct {
+	// ResultType is the unqualified cel type returned by the discovered constructors
+	ResultType string
+	// BuilderInterface is the name of the generated builder interface
+	BuilderInterface string
+	// RegistryType is the name of the generated registry struct
+	RegistryType string
+	// DefaultRegistry is the name of the generated default registry variable
+	DefaultRegistry string
+	// FileName is the name of the generated file
+	FileName string
+	// IncludeSharedTypes emits types shared across kinds, such as the FromJSON interface
+	IncludeSharedTypes bool
+}
+
+var optionKinds = []OptionKind{
+	{
+		ResultType:         "EnvOption",
+		BuilderInterface:   "OptionBuilder",
+		RegistryType:       "Registry",
+		DefaultRegistry:    "DefaultRegistry",
+		FileName:           "options.go",
+		IncludeSharedTypes: true,
+	},
+	{
+		ResultType:       "ProgramOption",
+		BuilderInterface: "ProgramOptionBuilder",
+		RegistryType:     "ProgramRegistry",
+		DefaultRegistry:  "DefaultProgramRegistry",
+		FileName:         "program_options.go",
+	},
+}
+
 type OptionParam struct {
 	Name     string
 	Type     types.Type
 	Variadic bool
+	// SubOptions are the constructors of a functional-option parameter type, such as
+	// cel.OptionalTypesVersion for ...cel.OptionalTypesOption, that can be configured from JSON
+	SubOptions []OptionInfo
 }
 
 type OptionInfo struct {
@@ -30,48 +73,183 @@ type OptionInfo struct {
 	Params      []OptionParam
 	Description string
 	Package     string
+	PackagePath string
+}
+
+// RegistryName returns the name the option is registered under
+// Options from the cel package keep their bare name, others are qualified by package name
+func (o OptionInfo) RegistryName() string {
+	if o.PackagePath == celPackageName {
+		return o.Name
+	}
+	return o.Package + "." + o.Name
+}
+
+// buildTagPrefix starts the build tags leaving the options of a feature out of the module
+const buildTagPrefix = "wasmcel_no"
+
+// featureOptions maps options of the cel package that belong to an optional feature to
+// the feature
+var featureOptions = map[string]string{
+	"DeclareContextProto": "proto",
+}
+
+// Feature returns the optional feature an option belongs to, whose options are only built
+// without the wasmcel_no<feature> build tag, or "" if the option is always built
+// Options from packages other than cel belong to the feature named after their package
+func (o OptionInfo) Feature() string {
+	if feature, ok := featureOptions[o.RegistryName()]; ok {
+		return feature
+	}
+	if o.PackagePath != celPackageName {
+		return o.Package
+	}
+	return ""
+}
+
+// BuilderName returns the name of the generated builder struct
+func (o OptionInfo) BuilderName() string {
+	if o.PackagePath == celPackageName {
+		return o.Name + "Builder"
+	}
+	return strings.Title(o.Package) + o.Name + "Builder"
+}
+
+// Config controls a single generator run
+type Config struct {
+	// OutputDir is the directory the generated files are written to
+	OutputDir string
+	// Packages are the packages scanned for option constructors, in order
+	Packages []string
+	// Split writes each option builder to its own file instead of one file per kind
+	Split bool
+	// Log receives progress messages, if set
+	Log io.Writer
 }
 
 func main() {
-	if len(os.Args) > 1 && os.Args[1] == "--help" {
-		fmt.Println("Usage: extensionsgen [output_dir]")
+	flag.Usage = func() {
+		fmt.Println("Usage: extensionsgen [--packages pkg1,pkg2] [--split] [output_dir]")
 		fmt.Println("Generates CEL environment option structs and interfaces")
 		fmt.Println("Default output directory: internal/options")
-		os.Exit(0)
+		fmt.Printf("Always scanned packages: %s\n", strings.Join(defaultPackages, ", "))
+		flag.PrintDefaults()
 	}
+	extraPackages := flag.String("packages", "", "comma-separated list of additional packages to scan for option constructors")
+	split := flag.Bool("split", false, "write each option builder to its own file (remove previously generated files when switching modes)")
+	flag.Parse()
 
 	outputDir := "internal/options"
-	if len(os.Args) > 1 {
-		outputDir = os.Args[1]
+	if flag.NArg() > 0 {
+		outputDir = flag.Arg(0)
+	}
+
+	packagePaths := append([]string{}, defaultPackages...)
+	for _, path := range strings.Split(*extraPackages, ",") {
+		path = strings.TrimSpace(path)
+		if path != "" && !containsString(packagePaths, path) {
+			packagePaths = append(packagePaths, path)
+		}
 	}
 
-	options, err := discoverOptions()
+	err := generate(Config{
+		OutputDir: outputDir,
+		Packages:  packagePaths,
+		Split:     *split,
+		Log:       os.Stdout,
+	})
 	if err != nil {
-		log.Fatalln("failed to discover options:", err)
+		log.Fatalln(err)
 	}
+}
 
-	if err := generateCode(options, outputDir); err != nil {
-		log.Fatalln("failed to generate code:", err)
+// generate discovers options in the configured packages and writes the generated code
+func generate(config Config) error {
+	logf := func(format string, args ...interface{}) {
+		if config.Log != nil {
+			fmt.Fprintf(config.Log, format, args...)
+		}
 	}
 
-	fmt.Printf("Generated %d option definitions in %s\n", len(options), outputDir)
+	pkgs, err := loadPackages(config.Packages)
+	if err != nil {
+		return fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	for _, kind := range optionKinds {
+		var options []OptionInfo
+		for _, pkg := range pkgs {
+			pkgOptions, skipped := discoverOptions(pkg, kind)
+			for _, name := range skipped {
+				logf("Skipping complex option: %s\n", name)
+			}
+			options = append(options, pkgOptions...)
+		}
+
+		// Sort by registry name so the output doesn't depend on discovery order
+		sort.SliceStable(options, func(i, j int) bool {
+			return options[i].RegistryName() < options[j].RegistryName()
+		})
+
+		if err := generateCode(kind, options, config.OutputDir, config.Split); err != nil {
+			return fmt.Errorf("failed to generate code: %w", err)
+		}
+
+		logf("Generated %d %s definitions in %s\n", len(options), kind.ResultType, config.OutputDir)
+	}
+
+	return nil
 }
 
-func discoverOptions() ([]OptionInfo, error) {
+// loadPackages loads the given packages and returns them in the requested order
+func loadPackages(paths []string) ([]*packages.Package, error) {
 	cfg := &packages.Config{
-		Mode: packages.NeedTypes | packages.NeedSyntax | packages.NeedImports | packages.NeedName | packages.NeedFiles,
+		Mode: packages.NeedTypes | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps | packages.NeedName | packages.NeedFiles,
 		Fset: token.NewFileSet(),
 	}
 
-	pkgs, err := packages.Load(cfg, celPackageName)
+	loaded, err := packages.Load(cfg, paths...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load CEL package: %w", err)
+		return nil, err
+	}
+
+	byPath := make(map[string]*packages.Package, len(loaded))
+	for _, pkg := range loaded {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		byPath[pkg.PkgPath] = pkg
+	}
+
+	pkgs := make([]*packages.Package, 0, len(paths))
+	for _, path := range paths {
+		pkg, ok := byPath[path]
+		if !ok {
+			return nil, fmt.Errorf("package %s was not loaded", path)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
+}
 
-	pkg := pkgs[0]
+// discoverOptions returns the option constructors of the given kind found in the package,
+// along with the qualified names of constructors skipped because of unsupported parameters
+func discoverOptions(pkg *packages.Package, kind OptionKind) ([]OptionInfo, []string) {
 	scope := pkg.Types.Scope()
+	resultType := celPackageName + "." + kind.ResultType
 
 	var options []OptionInfo
+	var skipped []string
 
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
@@ -86,14 +264,19 @@ func discoverOptions() ([]OptionInfo, error) {
 
 		sig := funcObj.Type().(*types.Signature)
 
-		// Check if function returns EnvOption
+		// Check if function returns the option type of this kind
 		results := sig.Results()
-		if results.Len() != 1 || results.At(0).Type().String() != envOptionType {
+		if results.Len() != 1 || results.At(0).Type().String() != resultType {
 			continue
 		}
 
 		// Extract parameters
 		params := extractParams(sig, funcObj.Name())
+		for i := range params {
+			if params[i].Variadic {
+				params[i].SubOptions = discoverSubOptions(pkg, params[i].Type)
+			}
+		}
 
 		// Skip options with complex types that are hard to handle
 		skipOption := false
@@ -109,7 +292,7 @@ func discoverOptions() ([]OptionInfo, error) {
 		}
 
 		if skipOption {
-			fmt.Printf("Skipping complex option: %s\n", funcObj.Name())
+			skipped = append(skipped, pkg.Name+"."+funcObj.Name())
 			continue
 		}
 
@@ -120,11 +303,100 @@ func discoverOptions() ([]OptionInfo, error) {
 			Name:        funcObj.Name(),
 			Params:      params,
 			Description: doc,
-			Package:     "cel",
+			Package:     pkg.Name,
+			PackagePath: pkg.PkgPath,
 		})
 	}
 
-	return options, nil
+	return options, skipped
+}
+
+// discoverSubOptions returns the JSON-configurable constructors of a functional-option type,
+// i.e. a named func type like cel.OptionalTypesOption, found in the package declaring the type
+func discoverSubOptions(pkg *packages.Package, typ types.Type) []OptionInfo {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return nil
+	}
+	if _, ok := named.Underlying().(*types.Signature); !ok {
+		return nil
+	}
+
+	typePkg := named.Obj().Pkg()
+	if typePkg == nil {
+		return nil
+	}
+
+	// Documentation is only available when the declaring package was loaded with syntax
+	docPkg := pkg
+	if typePkg.Path() != pkg.PkgPath {
+		docPkg = pkg.Imports[typePkg.Path()]
+	}
+
+	var subOptions []OptionInfo
+	scope := typePkg.Scope()
+	for _, name := range scope.Names() {
+		funcObj, ok := scope.Lookup(name).(*types.Func)
+		if !ok || !funcObj.Exported() {
+			continue
+		}
+
+		sig := funcObj.Type().(*types.Signature)
+		results := sig.Results()
+		if results.Len() != 1 || !types.Identical(results.At(0).Type(), named) {
+			continue
+		}
+
+		params := extractParams(sig, funcObj.Name())
+		supported := true
+		for _, param := range params {
+			if param.Variadic || jsonSchemaType(param.Type) == "" {
+				supported = false
+				break
+			}
+		}
+		if !supported {
+			continue
+		}
+
+		doc := ""
+		if docPkg != nil {
+			doc = extractDocumentation(docPkg, funcObj)
+		}
+
+		subOptions = append(subOptions, OptionInfo{
+			Name:        funcObj.Name(),
+			Params:      params,
+			Description: doc,
+			Package:     typePkg.Name(),
+			PackagePath: typePkg.Path(),
+		})
+	}
+
+	return subOptions
+}
+
+// jsonSchemaType returns the JSON Schema type used for a parameter of the given Go type,
+// or an empty string if the type can't be configured from JSON
+func jsonSchemaType(typ types.Type) string {
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+
+	info := basic.Info()
+	switch {
+	case info&types.IsString != 0:
+		return "string"
+	case info&types.IsBoolean != 0:
+		return "boolean"
+	case info&types.IsInteger != 0:
+		return "integer"
+	case info&types.IsFloat != 0:
+		return "number"
+	default:
+		return ""
+	}
 }
 
 func extractParams(sig *types.Signature, funcName string) []OptionParam {
@@ -177,92 +449,167 @@ func extractDocumentation(pkg *packages.Package, funcObj *types.Func) string {
 	return ""
 }
 
-func generateCode(options []OptionInfo, outputDir string) error {
+func generateCode(kind OptionKind, options []OptionInfo, outputDir string, split bool) error {
 	// Create output directory
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Generate single consolidated options file
-	if err := generateSingleOptionsFile(options, outputDir); err != nil {
-		return fmt.Errorf("failed to generate options file: %w", err)
+	if !split {
+		// Generate single consolidated options file for this kind, and one file for the
+		// options of each optional feature, so build tags can leave them out
+		byFeature := make(map[string][]OptionInfo)
+		var features []string
+		for _, option := range options {
+			feature := option.Feature()
+			if _, ok := byFeature[feature]; !ok && feature != "" {
+				features = append(features, feature)
+			}
+			byFeature[feature] = append(byFeature[feature], option)
+		}
+		sort.Strings(features)
+
+		f := newGeneratedFile("")
+		generateRegistry(f, kind)
+		for _, option := range byFeature[""] {
+			generateOptionBuilder(f, kind, option)
+		}
+		if err := f.Save(filepath.Join(outputDir, kind.FileName)); err != nil {
+			return fmt.Errorf("failed to generate %s file: %w", kind.FileName, err)
+		}
+
+		for _, feature := range features {
+			fileName := featureFileName(kind, feature)
+			f := newGeneratedFile(feature)
+			for _, option := range byFeature[feature] {
+				generateOptionBuilder(f, kind, option)
+			}
+			if err := f.Save(filepath.Join(outputDir, fileName)); err != nil {
+				return fmt.Errorf("failed to generate %s file: %w", fileName, err)
+			}
+		}
+		return nil
+	}
+
+	// Generate the registry file followed by one file per option builder
+	f := newGeneratedFile("")
+	generateRegistry(f, kind)
+	if err := f.Save(filepath.Join(outputDir, kind.FileName)); err != nil {
+		return fmt.Errorf("failed to generate %s file: %w", kind.FileName, err)
+	}
+
+	for _, option := range options {
+		fileName := optionFileName(kind, option)
+		f := newGeneratedFile(option.Feature())
+		generateOptionBuilder(f, kind, option)
+		if err := f.Save(filepath.Join(outputDir, fileName)); err != nil {
+			return fmt.Errorf("failed to generate %s file: %w", fileName, err)
+		}
 	}
 
 	return nil
 }
 
-func generateSingleOptionsFile(options []OptionInfo, outputDir string) error {
+// newGeneratedFile creates a file in the options package with the generated code header,
+// only built without the build tag of feature unless it's ""
+func newGeneratedFile(feature string) *jen.File {
 	f := jen.NewFile("options")
+	if feature != "" {
+		f.HeaderComment("//go:build !" + buildTagPrefix + feature)
+	}
 
 	// Add package comment
 	f.PackageComment("Code generated by extensionsgen. DO NOT EDIT.")
 
-	// OptionBuilder interface
-	f.Comment("OptionBuilder is the interface that all option builders must implement")
-	f.Type().Id("OptionBuilder").Interface(
-		jen.Comment("Build creates the actual CEL environment option"),
-		jen.Id("Build").Params().Params(jen.Qual("github.com/google/cel-go/cel", "EnvOption"), jen.Error()),
+	return f
+}
+
+// featureFileName returns the file name used for the option builders of a feature
+func featureFileName(kind OptionKind, feature string) string {
+	return strings.TrimSuffix(kind.FileName, ".go") + "_" + feature + ".go"
+}
+
+// optionFileName returns the file name used for an option builder in split mode
+func optionFileName(kind OptionKind, option OptionInfo) string {
+	base := strings.TrimSuffix(kind.FileName, ".go")
+	name := strings.ToLower(strings.ReplaceAll(option.RegistryName(), ".", "_"))
+	return base + "_" + name + ".go"
+}
+
+func generateRegistry(f *jen.File, kind OptionKind) {
+	builderIface := kind.BuilderInterface
+	registryType := kind.RegistryType
+	newRegistry := "New" + registryType
+
+	// Builder interface
+	f.Comment(fmt.Sprintf("%s is the interface that all %s builders must implement", builderIface, optionNoun(kind)))
+	f.Type().Id(builderIface).Interface(
+		jen.Comment(fmt.Sprintf("Build creates the actual CEL %s", celOptionNoun(kind))),
+		jen.Id("Build").Params().Params(jen.Qual(celPackageName, kind.ResultType), jen.Error()),
 		jen.Comment("Name returns the name of the option"),
 		jen.Id("Name").Params().String(),
 		jen.Comment("Description returns a description of what this option does"),
 		jen.Id("Description").Params().String(),
 	)
 
-	// FromJSON interface
-	f.Comment("FromJSON is the interface that maintainers implement for options they want to expose to WASM")
-	f.Type().Id("FromJSON").Interface(
-		jen.Comment("FromJSON configures the option builder from JSON parameters"),
-		jen.Id("FromJSON").Params(jen.Id("params").Map(jen.String()).Interface()).Error(),
-	)
+	if kind.IncludeSharedTypes {
+		// FromJSON interface
+		f.Comment("FromJSON is the interface that maintainers implement for options they want to expose to WASM")
+		f.Type().Id("FromJSON").Interface(
+			jen.Comment("FromJSON configures the option builder from JSON parameters"),
+			jen.Id("FromJSON").Params(jen.Id("params").Map(jen.String()).Interface()).Error(),
+		)
+	}
 
 	// Registry struct
-	f.Comment("Registry holds all available option builders")
-	f.Type().Id("Registry").Struct(
-		jen.Id("builders").Map(jen.String()).Func().Params().Id("OptionBuilder"),
+	f.Comment(fmt.Sprintf("%s holds all available %s builders", registryType, optionNoun(kind)))
+	f.Type().Id(registryType).Struct(
+		jen.Id("builders").Map(jen.String()).Func().Params().Id(builderIface),
 	)
 
 	// NewRegistry function
-	f.Comment("NewRegistry creates a new option registry")
-	f.Func().Id("NewRegistry").Params().Op("*").Id("Registry").Block(
-		jen.Return(jen.Op("&").Id("Registry").Values(
-			jen.Id("builders").Op(":").Make(jen.Map(jen.String()).Func().Params().Id("OptionBuilder")),
+	f.Comment(fmt.Sprintf("%s creates a new %s registry", newRegistry, optionNoun(kind)))
+	f.Func().Id(newRegistry).Params().Op("*").Id(registryType).Block(
+		jen.Return(jen.Op("&").Id(registryType).Values(
+			jen.Id("builders").Op(":").Make(jen.Map(jen.String()).Func().Params().Id(builderIface)),
 		)),
 	)
 
 	// Register method
-	f.Comment("Register registers an option builder factory function")
-	f.Func().Params(jen.Id("r").Op("*").Id("Registry")).Id("Register").Params(
+	f.Comment(fmt.Sprintf("Register registers %s builder factory function", article(optionNoun(kind))))
+	f.Func().Params(jen.Id("r").Op("*").Id(registryType)).Id("Register").Params(
 		jen.Id("name").String(),
-		jen.Id("factory").Func().Params().Id("OptionBuilder"),
+		jen.Id("factory").Func().Params().Id(builderIface),
 	).Block(
 		jen.Id("r").Dot("builders").Index(jen.Id("name")).Op("=").Id("factory"),
 	)
 
 	// Create method
-	f.Comment("Create creates a new option builder by name")
-	f.Func().Params(jen.Id("r").Op("*").Id("Registry")).Id("Create").Params(
+	f.Comment(fmt.Sprintf("Create creates a new %s builder by name", optionNoun(kind)))
+	f.Func().Params(jen.Id("r").Op("*").Id(registryType)).Id("Create").Params(
 		jen.Id("name").String(),
-	).Params(jen.Id("OptionBuilder"), jen.Error()).Block(
+	).Params(jen.Id(builderIface), jen.Error()).Block(
 		jen.List(jen.Id("factory"), jen.Id("exists")).Op(":=").Id("r").Dot("builders").Index(jen.Id("name")),
 		jen.If(jen.Op("!").Id("exists")).Block(
-			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("option %q not found"), jen.Id("name"))),
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit(optionNoun(kind)+" %q not found"), jen.Id("name"))),
 		),
 		jen.Return(jen.Id("factory").Call(), jen.Nil()),
 	)
 
 	// List method
-	f.Comment("List returns all available option names")
-	f.Func().Params(jen.Id("r").Op("*").Id("Registry")).Id("List").Params().Index().String().Block(
+	f.Comment(fmt.Sprintf("List returns all available %s names in sorted order", optionNoun(kind)))
+	f.Func().Params(jen.Id("r").Op("*").Id(registryType)).Id("List").Params().Index().String().Block(
 		jen.Var().Id("names").Index().String(),
 		jen.For(jen.Id("name").Op(":=").Range().Id("r").Dot("builders")).Block(
 			jen.Id("names").Op("=").Append(jen.Id("names"), jen.Id("name")),
 		),
+		jen.Qual("sort", "Strings").Call(jen.Id("names")),
 		jen.Return(jen.Id("names")),
 	)
 
 	// ListWithFromJSON method - returns only options that implement FromJSON
-	f.Comment("ListWithFromJSON returns option names that implement the FromJSON interface")
-	f.Func().Params(jen.Id("r").Op("*").Id("Registry")).Id("ListWithFromJSON").Params().Index().String().Block(
+	f.Comment(fmt.Sprintf("ListWithFromJSON returns %s names that implement the FromJSON interface in sorted order", optionNoun(kind)))
+	f.Func().Params(jen.Id("r").Op("*").Id(registryType)).Id("ListWithFromJSON").Params().Index().String().Block(
 		jen.Var().Id("names").Index().String(),
 		jen.For(jen.List(jen.Id("name"), jen.Id("factory")).Op(":=").Range().Id("r").Dot("builders")).Block(
 			jen.Id("builder").Op(":=").Id("factory").Call(),
@@ -270,24 +617,41 @@ func generateSingleOptionsFile(options []OptionInfo, outputDir string) error {
 				jen.Id("names").Op("=").Append(jen.Id("names"), jen.Id("name")),
 			),
 		),
+		jen.Qual("sort", "Strings").Call(jen.Id("names")),
 		jen.Return(jen.Id("names")),
 	)
 
 	// DefaultRegistry variable
-	f.Comment("DefaultRegistry is the default registry with all built-in options")
-	f.Var().Id("DefaultRegistry").Op("=").Id("NewRegistry").Call()
+	f.Comment(fmt.Sprintf("%s is the default registry with all built-in %ss", kind.DefaultRegistry, optionNoun(kind)))
+	f.Var().Id(kind.DefaultRegistry).Op("=").Id(newRegistry).Call()
+}
 
-	// Generate all option builders
-	for _, option := range options {
-		generateOptionBuilder(f, option)
+// optionNoun returns the noun used in generated comments for options of this kind
+func optionNoun(kind OptionKind) string {
+	if kind.ResultType == "ProgramOption" {
+		return "program option"
 	}
+	return "option"
+}
 
-	// Write to file
-	return f.Save(filepath.Join(outputDir, "options.go"))
+// celOptionNoun returns the description of the cel type built by options of this kind
+func celOptionNoun(kind OptionKind) string {
+	if kind.ResultType == "ProgramOption" {
+		return "program option"
+	}
+	return "environment option"
 }
 
-func generateOptionBuilder(f *jen.File, option OptionInfo) {
-	builderName := option.Name + "Builder"
+// article prefixes a noun with the matching indefinite article
+func article(noun string) string {
+	if strings.ContainsRune("aeiou", rune(noun[0])) {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+func generateOptionBuilder(f *jen.File, kind OptionKind, option OptionInfo) {
+	builderName := option.BuilderName()
 
 	// Add description comment if available
 	if option.Description != "" {
@@ -304,12 +668,7 @@ func generateOptionBuilder(f *jen.File, option OptionInfo) {
 	structFields := []jen.Code{}
 	for _, param := range option.Params {
 		goType := convertToJenType(param.Type, param.Variadic)
-		// Avoid naming conflicts with methods by using different field names
-		fieldName := strings.Title(param.Name)
-		if fieldName == "Name" {
-			fieldName = "NameValue"
-		}
-		structFields = append(structFields, jen.Id(fieldName).Add(goType))
+		structFields = append(structFields, jen.Id(fieldName(param)).Add(goType))
 	}
 
 	f.Type().Id(builderName).Struct(structFields...)
@@ -317,7 +676,7 @@ func generateOptionBuilder(f *jen.File, option OptionInfo) {
 	// Name method
 	f.Comment("Name returns the name of this option")
 	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("Name").Params().String().Block(
-		jen.Return(jen.Lit(option.Name)),
+		jen.Return(jen.Lit(option.RegistryName())),
 	)
 
 	// Description method
@@ -327,63 +686,289 @@ func generateOptionBuilder(f *jen.File, option OptionInfo) {
 	)
 
 	// Setter methods for each parameter
-	for _, param := range option.Params {
+	generateSetters(f, builderName, option.Params)
+
+	// Build method
+	f.Comment(fmt.Sprintf("Build creates the CEL %s", celOptionNoun(kind)))
+	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("Build").Params().Params(
+		jen.Qual(celPackageName, kind.ResultType),
+		jen.Error(),
+	).Block(
+		jen.Return(buildCall(option), jen.Nil()),
+	)
+
+	// JSON configuration for options taking only functional sub-options
+	if configurableFromSubOptions(option) {
+		generateSubOptionsFromJSON(f, option)
+	}
+
+	// Generate init function to register this option
+	f.Func().Id("init").Params().Block(
+		jen.Id(kind.DefaultRegistry).Dot("Register").Call(
+			jen.Lit(option.RegistryName()),
+			jen.Func().Params().Id(kind.BuilderInterface).Block(
+				jen.Return(jen.Op("&").Id(builderName).Values()),
+			),
+		),
+	)
+}
+
+// fieldName returns the builder struct field used to store a parameter
+func fieldName(param OptionParam) string {
+	// Avoid naming conflicts with methods by using different field names
+	name := strings.Title(param.Name)
+	if name == "Name" {
+		name = "NameValue"
+	}
+	return name
+}
+
+// localName returns a local variable name for a parameter that doesn't shadow generated identifiers
+func localName(param OptionParam) string {
+	switch param.Name {
+	case "b", "params", "err":
+		return param.Name + "Value"
+	}
+	return param.Name
+}
+
+func generateSetters(f *jen.File, builderName string, params []OptionParam) {
+	for _, param := range params {
 		methodName := "Set" + strings.Title(param.Name)
-		fieldName := strings.Title(param.Name)
-		if fieldName == "Name" {
-			fieldName = "NameValue"
-		}
 		goType := convertToJenType(param.Type, param.Variadic)
 
 		f.Comment(fmt.Sprintf("Set%s sets the %s parameter", strings.Title(param.Name), param.Name))
 		f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id(methodName).Params(
 			jen.Id(param.Name).Add(goType),
 		).Op("*").Id(builderName).Block(
-			jen.Id("b").Dot(fieldName).Op("=").Id(param.Name),
+			jen.Id("b").Dot(fieldName(param)).Op("=").Id(param.Name),
 			jen.Return(jen.Id("b")),
 		)
 	}
+}
 
-	// Build method
-	f.Comment("Build creates the CEL environment option")
+// buildCall returns the constructor call made by a builder's Build method
+func buildCall(option OptionInfo) *jen.Statement {
 	buildParams := []jen.Code{}
 	for _, param := range option.Params {
-		fieldName := strings.Title(param.Name)
-		if fieldName == "Name" {
-			fieldName = "NameValue"
-		}
-		fieldRef := jen.Id("b").Dot(fieldName)
+		fieldRef := jen.Id("b").Dot(fieldName(param))
 		if param.Variadic {
 			fieldRef = fieldRef.Op("...")
 		}
 		buildParams = append(buildParams, fieldRef)
 	}
 
-	var buildCall *jen.Statement
-	if len(buildParams) > 0 {
-		buildCall = jen.Qual("github.com/google/cel-go/cel", option.Name).Call(buildParams...)
-	} else {
-		buildCall = jen.Qual("github.com/google/cel-go/cel", option.Name).Call()
+	return jen.Qual(option.PackagePath, option.Name).Call(buildParams...)
+}
+
+// configurableFromSubOptions reports whether every parameter of the option is a
+// functional-option parameter with at least one JSON-configurable sub-option
+func configurableFromSubOptions(option OptionInfo) bool {
+	if len(option.Params) == 0 {
+		return false
+	}
+	for _, param := range option.Params {
+		if len(param.SubOptions) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// subOptionBuildersName returns the name of the generated map of sub-option builders for a parameter
+func subOptionBuildersName(option OptionInfo, param OptionParam) string {
+	base := strings.TrimSuffix(option.BuilderName(), "Builder")
+	return strings.ToLower(base[:1]) + base[1:] + strings.Title(param.Name) + "Builders"
+}
+
+// generateSubOptionsFromJSON emits builders for the functional sub-options of an option
+// along with FromJSON and ParamsSchema methods that configure them from a list of
+// {"type": ..., "params": ...} entries, mirroring the top-level option configuration
+func generateSubOptionsFromJSON(f *jen.File, option OptionInfo) {
+	builderName := option.BuilderName()
+
+	for _, param := range option.Params {
+		elemType := parseGoType(param.Type)
+		builderType := jen.Id("SubOptionBuilder").Types(elemType)
+
+		for _, subOption := range param.SubOptions {
+			generateSubOptionBuilder(f, subOption, param.Type)
+		}
+
+		entries := []jen.Code{}
+		for _, subOption := range param.SubOptions {
+			entries = append(entries, jen.Lit(subOption.Name).Op(":").Func().Params().Add(builderType.Clone()).Block(
+				jen.Return(jen.Op("&").Id(subOption.BuilderName()).Values()),
+			))
+		}
+
+		buildersName := subOptionBuildersName(option, param)
+		f.Comment(fmt.Sprintf("%s maps the sub-options accepted by the %s parameter of %s to their builders", buildersName, param.Name, option.RegistryName()))
+		f.Var().Id(buildersName).Op("=").Map(jen.String()).Func().Params().Add(builderType.Clone()).Custom(
+			jen.Options{Open: "{", Close: "}", Separator: ",", Multi: true},
+			entries...,
+		)
+	}
+
+	// FromJSON method
+	fromJSONBody := []jen.Code{}
+	for _, param := range option.Params {
+		local := localName(param)
+		fromJSONBody = append(fromJSONBody,
+			jen.List(jen.Id(local), jen.Err()).Op(":=").Id("subOptionsFromJSON").Call(
+				jen.Id("params"), jen.Lit(param.Name), jen.Id(subOptionBuildersName(option, param)),
+			),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+			jen.Id("b").Dot("Set"+strings.Title(param.Name)).Call(jen.Id(local)),
+		)
+	}
+	fromJSONBody = append(fromJSONBody, jen.Return(jen.Nil()))
+
+	f.Comment(fmt.Sprintf("FromJSON configures the %s from JSON parameters", builderName))
+	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("FromJSON").Params(
+		jen.Id("params").Map(jen.String()).Interface(),
+	).Error().Block(fromJSONBody...)
+
+	// ParamsSchema method
+	properties := jen.Dict{}
+	for _, param := range option.Params {
+		properties[jen.Lit(param.Name)] = jen.Id("subOptionsSchema").Call(jen.Id(subOptionBuildersName(option, param)))
+	}
+
+	f.Comment("ParamsSchema describes the JSON parameters accepted by FromJSON")
+	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("ParamsSchema").Params().Map(jen.String()).Interface().Block(
+		jen.Return(jen.Id("objectSchema").Call(jen.Map(jen.String()).Interface().Values(properties))),
+	)
+}
+
+// generateSubOptionBuilder emits a builder for a single functional sub-option constructor
+func generateSubOptionBuilder(f *jen.File, subOption OptionInfo, resultType types.Type) {
+	builderName := subOption.BuilderName()
+
+	if subOption.Description != "" {
+		for _, line := range strings.Split(subOption.Description, "\n") {
+			if strings.TrimSpace(line) != "" {
+				f.Comment(strings.TrimSpace(line))
+			}
+		}
+	}
+
+	structFields := []jen.Code{}
+	for _, param := range subOption.Params {
+		structFields = append(structFields, jen.Id(fieldName(param)).Add(parseGoType(param.Type)))
 	}
+	f.Type().Id(builderName).Struct(structFields...)
+
+	// Description method
+	f.Comment("Description returns the description of this sub-option")
+	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("Description").Params().String().Block(
+		jen.Return(jen.Lit(subOption.Description)),
+	)
+
+	generateSetters(f, builderName, subOption.Params)
 
+	// Build method
+	f.Comment(fmt.Sprintf("Build creates the %s", subOption.Package+"."+typeName(resultType)))
 	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("Build").Params().Params(
-		jen.Qual("github.com/google/cel-go/cel", "EnvOption"),
+		parseGoType(resultType),
 		jen.Error(),
 	).Block(
-		jen.Return(buildCall, jen.Nil()),
+		jen.Return(buildCall(subOption), jen.Nil()),
 	)
 
-	// Generate init function to register this option
-	f.Func().Id("init").Params().Block(
-		jen.Id("DefaultRegistry").Dot("Register").Call(
-			jen.Lit(option.Name),
-			jen.Func().Params().Id("OptionBuilder").Block(
-				jen.Return(jen.Op("&").Id(builderName).Values()),
-			),
-		),
+	// FromJSON method, all sub-option parameters are required
+	fromJSONBody := []jen.Code{}
+	for _, param := range subOption.Params {
+		local := localName(param)
+		value := jen.Id(local)
+		helper, bitSize, converted := jsonParamHelper(param.Type)
+		args := []jen.Code{jen.Id("params"), jen.Lit(param.Name)}
+		if bitSize >= 0 {
+			args = append(args, jen.Lit(bitSize))
+		}
+		if converted {
+			value = parseGoType(param.Type).Call(jen.Id(local))
+		}
+		fromJSONBody = append(fromJSONBody,
+			jen.List(jen.Id(local), jen.Err()).Op(":=").Id(helper).Call(args...),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Err())),
+			jen.Id("b").Dot("Set"+strings.Title(param.Name)).Call(value),
+		)
+	}
+	fromJSONBody = append(fromJSONBody, jen.Return(jen.Nil()))
+
+	f.Comment(fmt.Sprintf("FromJSON configures the %s from JSON parameters", builderName))
+	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("FromJSON").Params(
+		jen.Id("params").Map(jen.String()).Interface(),
+	).Error().Block(fromJSONBody...)
+
+	// ParamsSchema method
+	properties := jen.Dict{}
+	required := []jen.Code{}
+	for _, param := range subOption.Params {
+		schema := jen.Dict{jen.Lit("type"): jen.Lit(jsonSchemaType(param.Type))}
+		if basic, ok := param.Type.Underlying().(*types.Basic); ok && basic.Info()&types.IsUnsigned != 0 {
+			schema[jen.Lit("minimum")] = jen.Lit(0)
+		}
+		properties[jen.Lit(param.Name)] = jen.Map(jen.String()).Interface().Values(schema)
+		required = append(required, jen.Lit(param.Name))
+	}
+
+	f.Comment("ParamsSchema describes the JSON parameters accepted by FromJSON")
+	f.Func().Params(jen.Id("b").Op("*").Id(builderName)).Id("ParamsSchema").Params().Map(jen.String()).Interface().Block(
+		jen.Return(jen.Id("objectSchema").Call(
+			append([]jen.Code{jen.Map(jen.String()).Interface().Values(properties)}, required...)...,
+		)),
 	)
 }
 
+// jsonParamHelper returns the options package helper that reads a parameter of the given
+// basic type from JSON, its bit size argument (-1 if it takes none), and whether the
+// helper's result must be converted to the parameter type
+func jsonParamHelper(typ types.Type) (string, int, bool) {
+	basic := typ.Underlying().(*types.Basic)
+	_, named := typ.(*types.Named)
+
+	switch basic.Kind() {
+	case types.String:
+		return "stringParam", -1, named
+	case types.Bool:
+		return "boolParam", -1, named
+	case types.Float32:
+		return "floatParam", -1, true
+	case types.Float64:
+		return "floatParam", -1, named
+	case types.Int8, types.Uint8:
+		return integerHelper(basic), 8, true
+	case types.Int16, types.Uint16:
+		return integerHelper(basic), 16, true
+	case types.Int32, types.Uint32:
+		return integerHelper(basic), 32, true
+	case types.Int64:
+		return "intParam", 64, named
+	case types.Uint64:
+		return "uintParam", 64, named
+	default:
+		// int, uint and uintptr are platform sized
+		return integerHelper(basic), 0, true
+	}
+}
+
+func integerHelper(basic *types.Basic) string {
+	if basic.Info()&types.IsUnsigned != 0 {
+		return "uintParam"
+	}
+	return "intParam"
+}
+
+// typeName returns the unqualified name of a named type
+func typeName(typ types.Type) string {
+	if named, ok := typ.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return typ.String()
+}
+
 func convertToJenType(typ types.Type, variadic bool) *jen.Statement {
 	statement := parseGoType(typ)
 
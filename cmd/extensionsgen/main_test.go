@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the checked-in generated files in internal/options")
+
+// goldenDir holds the checked-in output of the generator, relative to this package
+const goldenDir = "../../internal/options"
+
+func generateInto(t *testing.T, split bool) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	err := generate(Config{
+		OutputDir: dir,
+		Packages:  defaultPackages,
+		Split:     split,
+	})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	return dir
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return data
+}
+
+// generatedFiles returns the names of the files generated into a directory
+func generatedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read output directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestGoldenFiles(t *testing.T) {
+	dir := generateInto(t, false)
+
+	for _, name := range generatedFiles(t, dir) {
+		t.Run(name, func(t *testing.T) {
+			got := readFile(t, filepath.Join(dir, name))
+			goldenPath := filepath.Join(goldenDir, name)
+
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatalf("failed to update %s: %v", goldenPath, err)
+				}
+				return
+			}
+
+			want := readFile(t, goldenPath)
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s is out of date; run `go run ./cmd/extensionsgen` or `go test ./cmd/extensionsgen -update`", goldenPath)
+			}
+		})
+	}
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	first := generateInto(t, false)
+	second := generateInto(t, false)
+
+	for _, name := range generatedFiles(t, first) {
+		a := readFile(t, filepath.Join(first, name))
+		b := readFile(t, filepath.Join(second, name))
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s differs between runs", name)
+		}
+	}
+}
+
+func TestFeatureFiles(t *testing.T) {
+	dir := generateInto(t, false)
+
+	tests := map[string]string{
+		"options_ext.go":   "//go:build !wasmcel_noext",
+		"options_proto.go": "//go:build !wasmcel_noproto",
+	}
+	for name, tag := range tests {
+		contents := string(readFile(t, filepath.Join(dir, name)))
+		if !strings.HasPrefix(contents, tag+"\n") {
+			t.Errorf("%s should start with %q", name, tag)
+		}
+	}
+
+	options := string(readFile(t, filepath.Join(dir, "options.go")))
+	for _, builder := range []string{"ExtStringsBuilder struct", "DeclareContextProtoBuilder struct"} {
+		if strings.Contains(options, builder) {
+			t.Errorf("options.go should leave the %s out", builder)
+		}
+	}
+}
+
+func TestSplitOutput(t *testing.T) {
+	dir := generateInto(t, true)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read output directory: %v", err)
+	}
+
+	files := make(map[string]bool)
+	for _, entry := range entries {
+		files[entry.Name()] = true
+	}
+
+	for _, kind := range optionKinds {
+		if !files[kind.FileName] {
+			t.Errorf("missing registry file %s", kind.FileName)
+			continue
+		}
+
+		registry := string(readFile(t, filepath.Join(dir, kind.FileName)))
+		if strings.Contains(registry, "Builder struct") {
+			t.Errorf("%s should only contain the registry in split mode", kind.FileName)
+		}
+	}
+
+	for _, name := range []string{"options_stdlib.go", "options_ext_strings.go", "program_options_costlimit.go"} {
+		if !files[name] {
+			t.Errorf("missing builder file %s", name)
+			continue
+		}
+
+		contents := string(readFile(t, filepath.Join(dir, name)))
+		header, _, _ := strings.Cut(contents, "package options")
+		if !strings.Contains(header, "// Code generated by extensionsgen. DO NOT EDIT.") {
+			t.Errorf("%s is missing the generated code header", name)
+		}
+	}
+}
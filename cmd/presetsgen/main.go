@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+// presetName matches the names presets can be created by, taken from their file names
+var presetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Config controls a single generator run
+type Config struct {
+	// InputDir holds the preset sources, one <name>.json file per preset
+	InputDir string
+	// OutputDir is the directory embedded into the module, which the generated presets
+	// are written to
+	OutputDir string
+	// Log receives progress messages, if set
+	Log io.Writer
+}
+
+// presetSource is the source of a preset: the configuration of its environment, as in
+// a bundle, and the descriptor set of its DeclareContextProto option, if any
+type presetSource struct {
+	Env celengine.BundleEnv `json:"env"`
+	// DescriptorSet is a serialized google.protobuf.FileDescriptorSet, in base64
+	DescriptorSet []byte `json:"descriptorSet,omitempty"`
+	// DescriptorSetFile is the path of a serialized google.protobuf.FileDescriptorSet,
+	// such as the output of protoc --descriptor_set_out, relative to the source
+	DescriptorSetFile string `json:"descriptorSetFile,omitempty"`
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Println("Usage: presetsgen [input_dir] [output_dir]")
+		fmt.Println("Generates the environment presets baked into the module")
+		fmt.Println("Default input directory: presets")
+		fmt.Println("Default output directory: pkg/celengine/presets")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	config := Config{
+		InputDir:  "presets",
+		OutputDir: "pkg/celengine/presets",
+		Log:       os.Stdout,
+	}
+	if flag.NArg() > 0 {
+		config.InputDir = flag.Arg(0)
+	}
+	if flag.NArg() > 1 {
+		config.OutputDir = flag.Arg(1)
+	}
+
+	if err := generate(config); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// generate checks the preset sources of the input directory and writes them as bundles
+// to the output directory, replacing the presets generated before
+func generate(config Config) error {
+	logf := func(format string, args ...interface{}) {
+		if config.Log != nil {
+			fmt.Fprintf(config.Log, format, args...)
+		}
+	}
+
+	sources, err := filepath.Glob(filepath.Join(config.InputDir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(sources)
+
+	generated := make(map[string][]byte, len(sources))
+	for _, source := range sources {
+		name := strings.TrimSuffix(filepath.Base(source), ".json")
+		if !presetName.MatchString(name) {
+			return fmt.Errorf("%s: invalid preset name %q", source, name)
+		}
+		data, err := buildPreset(source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		generated[name] = data
+	}
+
+	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
+		return err
+	}
+	stale, err := filepath.Glob(filepath.Join(config.OutputDir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	for _, source := range sources {
+		name := strings.TrimSuffix(filepath.Base(source), ".json")
+		if err := os.WriteFile(filepath.Join(config.OutputDir, name+".json"), generated[name], 0644); err != nil {
+			return err
+		}
+		logf("Generated preset %s\n", name)
+	}
+	return nil
+}
+
+// buildPreset reads the source of a preset and returns the preset as an encoded bundle,
+// after checking that its environment can be created
+func buildPreset(source string) ([]byte, error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var preset presetSource
+	if err := decoder.Decode(&preset); err != nil {
+		return nil, fmt.Errorf("failed to parse preset: %w", err)
+	}
+
+	if preset.DescriptorSetFile != "" {
+		if preset.DescriptorSet != nil {
+			return nil, fmt.Errorf("expected one of descriptorSet or descriptorSetFile")
+		}
+		preset.DescriptorSet, err = os.ReadFile(filepath.Join(filepath.Dir(source), preset.DescriptorSetFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := json.Marshal(celengine.Bundle{
+		Version:       celengine.BundleVersion,
+		Env:           preset.Env,
+		DescriptorSet: preset.DescriptorSet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := celengine.CheckPreset(encoded); err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+func writeFile(t *testing.T, path string, contents string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func readPreset(t *testing.T, path string) celengine.Bundle {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	var bundle celengine.Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	return bundle
+}
+
+func TestGenerate(t *testing.T) {
+	output := t.TempDir()
+	writeFile(t, filepath.Join(output, "removed.json"), "{}")
+	writeFile(t, filepath.Join(output, "README.md"), "kept")
+
+	if err := generate(Config{InputDir: "testdata", OutputDir: output}); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	bundle := readPreset(t, filepath.Join(output, "acme-default.json"))
+	if bundle.Version != celengine.BundleVersion {
+		t.Errorf("expected version %d, got %d", celengine.BundleVersion, bundle.Version)
+	}
+	if len(bundle.Env.Constants) != 1 || bundle.Env.Constants[0].Name != "MIN_AGE" {
+		t.Errorf("expected the MIN_AGE constant, got %+v", bundle.Env.Constants)
+	}
+	if _, err := os.Stat(filepath.Join(output, "removed.json")); !os.IsNotExist(err) {
+		t.Errorf("expected the stale preset to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(output, "README.md")); err != nil {
+		t.Errorf("expected other files to be kept: %v", err)
+	}
+}
+
+func TestDescriptorSetFile(t *testing.T) {
+	input := t.TempDir()
+	writeFile(t, filepath.Join(input, "descriptors.binpb"), "descriptors")
+	writeFile(t, filepath.Join(input, "proto.json"), `{"env": {}, "descriptorSetFile": "descriptors.binpb"}`)
+
+	output := t.TempDir()
+	if err := generate(Config{InputDir: input, OutputDir: output}); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+
+	bundle := readPreset(t, filepath.Join(output, "proto.json"))
+	if string(bundle.DescriptorSet) != "descriptors" {
+		t.Errorf("expected the descriptor set to be inlined, got %q", bundle.DescriptorSet)
+	}
+}
+
+func TestInvalidPresets(t *testing.T) {
+	tests := map[string]struct {
+		name     string
+		contents string
+		want     string
+	}{
+		"unknown option": {
+			name:     "broken",
+			contents: `{"env": {"options": [{"type": "NoSuchOption"}]}}`,
+			want:     "NoSuchOption",
+		},
+		"unknown field": {
+			name:     "typo",
+			contents: `{"env": {"variable": []}}`,
+			want:     `unknown field "variable"`,
+		},
+		"invalid name": {
+			name:     ".hidden",
+			contents: `{"env": {}}`,
+			want:     "invalid preset name",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			input := t.TempDir()
+			writeFile(t, filepath.Join(input, test.name+".json"), test.contents)
+
+			err := generate(Config{InputDir: input, OutputDir: t.TempDir()})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
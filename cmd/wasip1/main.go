@@ -0,0 +1,20 @@
+//go:build wasip1
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/invakid404/wasm-cel/internal/rpc"
+)
+
+func main() {
+	server := rpc.NewServer(os.Stdin, os.Stdout)
+	server.Install()
+
+	if err := server.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
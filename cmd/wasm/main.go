@@ -3,16 +3,18 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
 	"syscall/js"
 
-	"github.com/invakid404/wasm-cel/internal/cel"
+	"github.com/invakid404/wasm-cel/pkg/celengine"
 	"github.com/invakid404/wasm-cel/internal/common"
 	"github.com/invakid404/wasm-cel/internal/options"
 )
 
-// jsFunctionCaller implements cel.JSFunctionCaller using syscall/js
+// jsFunctionCaller implements celengine.JSFunctionCaller using syscall/js
 type jsFunctionCaller struct {
 	registry map[string]js.Value
 }
@@ -23,25 +25,40 @@ func (c *jsFunctionCaller) CallJSFunction(implID string, args []interface{}) (in
 		return nil, fmt.Errorf("function implementation not found: %s", implID)
 	}
 
+	// An interrupted evaluation only runs until it next checks, so it skips further calls
+	if runningJob != nil {
+		if err := runningJob.interrupted(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Convert Go values to JavaScript values
-	jsArgs := make([]interface{}, len(args))
+	jsArgs := make([]interface{}, len(args), len(args)+1)
 	for i, arg := range args {
-		jsArgs[i] = arg
+		jsArgs[i] = toJSValue(arg)
+	}
+	if runningJob != nil {
+		jsArgs = append(jsArgs, runningJob.callContext())
 	}
 
 	// Call the JavaScript function
-	result := fn.Invoke(jsArgs...)
-	if result.IsNull() || result.IsUndefined() {
-		return nil, nil
+	result, err := invokeFunction(fn, jsArgs)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert JavaScript result to Go value
-	resultJSON := js.Global().Get("JSON").Call("stringify", result).String()
-	var goResult interface{}
-	if err := json.Unmarshal([]byte(resultJSON), &goResult); err != nil {
-		return nil, fmt.Errorf("failed to parse function result: %v", err)
+	// Evaluations on the worker pool wait for Promises, the others need the result now
+	if runningJob != nil && jsTag(result) == "[object Promise]" {
+		if result, err = awaitPromise(result); err != nil {
+			return nil, err
+		}
 	}
 
+	// Convert JavaScript result to Go value
+	goResult, err := fromJSValue(result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid function result: %v", err)
+	}
 	return goResult, nil
 }
 
@@ -57,7 +74,7 @@ var functionCaller = &jsFunctionCaller{
 // compilationContextAdapter provides compilation context for the filename side-channel approach
 func compilationContextAdapter(compilationID string) common.CompilationIssueAdder {
 	// Since both packages now use the same common types, no adaptation needed
-	return cel.GetCompilationContextAdder(compilationID)
+	return celengine.GetCompilationContextAdder(compilationID)
 }
 
 // registerFunction registers a JavaScript function implementation
@@ -83,69 +100,322 @@ func registerFunction(this js.Value, args []js.Value) interface{} {
 	}
 }
 
+// defineGlobalFunction declares a custom function in every environment created afterwards
+func defineGlobalFunction(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: funcDef object",
+		}
+	}
+
+	var funcDef celengine.FunctionDef
+	funcDefJSON := js.Global().Get("JSON").Call("stringify", args[0]).String()
+	if err := json.Unmarshal([]byte(funcDefJSON), &funcDef); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse function definition: %v", err),
+		}
+	}
+
+	return celengine.DefineGlobalFunction(funcDef)
+}
+
+// registerLibrary registers a named library that environments can include
+func registerLibrary(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: name string, library object",
+		}
+	}
+
+	var library celengine.Library
+	libraryJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+	if err := json.Unmarshal([]byte(libraryJSON), &library); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse library: %v", err),
+		}
+	}
+
+	return celengine.RegisterLibrary(args[0].String(), library)
+}
+
 // createEnv creates a new CEL environment
 func createEnv(this js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
 		return map[string]interface{}{
-			"error": "expected at least 1 argument: varDecls array",
+			"error": "expected at least 1 argument: config object, or varDecls array and funcDefs array (optional)",
+		}
+	}
+
+	// The configuration is an object of celengine.EnvConfig fields, such as
+	// {varDecls, funcDefs, constants, libraries, sessionID, absentVariables, apiVersion}
+	var config celengine.EnvConfig
+	if args[0].Type() == js.TypeObject && !js.Global().Get("Array").Call("isArray", args[0]).Bool() {
+		configJSON := js.Global().Get("JSON").Call("stringify", args[0]).String()
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse environment config: %v", err),
+			}
 		}
+		return celengine.CreateEnvWithConfig(config)
 	}
 
-	// Parse variable declarations from first argument
-	var varDecls []cel.VarDecl
+	// Otherwise the arguments are the variable declarations and function definitions, as
+	// in earlier versions
 	if !args[0].IsNull() && !args[0].IsUndefined() {
 		varDeclsJSON := js.Global().Get("JSON").Call("stringify", args[0]).String()
-		if err := json.Unmarshal([]byte(varDeclsJSON), &varDecls); err != nil {
+		if err := json.Unmarshal([]byte(varDeclsJSON), &config.VarDecls); err != nil {
 			return map[string]interface{}{
 				"error": fmt.Sprintf("failed to parse variable declarations: %v", err),
 			}
 		}
 	}
-
-	// Parse function definitions from second argument if provided
-	var funcDefs []cel.FunctionDef
 	if len(args) >= 2 && !args[1].IsNull() && !args[1].IsUndefined() {
 		funcDefsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
-		if err := json.Unmarshal([]byte(funcDefsJSON), &funcDefs); err != nil {
+		if err := json.Unmarshal([]byte(funcDefsJSON), &config.FuncDefs); err != nil {
 			return map[string]interface{}{
 				"error": fmt.Sprintf("failed to parse function definitions: %v", err),
 			}
 		}
 	}
 
-	return cel.CreateEnv(varDecls, funcDefs)
+	return celengine.CreateEnvWithConfig(config)
+}
+
+// createEnvFromJSONSchema creates a CEL environment declaring the properties of a JSON Schema
+func createEnvFromJSONSchema(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: schema object or JSON string",
+		}
+	}
+
+	schemaJSON := args[0].String()
+	if args[0].Type() != js.TypeString {
+		schemaJSON = js.Global().Get("JSON").Call("stringify", args[0]).String()
+	}
+
+	return celengine.CreateEnvFromJSONSchema(schemaJSON)
+}
+
+// createEnvFromPreset creates the environment of a preset baked into the module
+func createEnvFromPreset(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return map[string]interface{}{
+			"error": "expected at least 1 argument: name string, sessionID string (optional)",
+		}
+	}
+
+	var sessionID string
+	if id := optionalStringArg(args, 1); id != nil {
+		sessionID = *id
+	}
+	return celengine.CreateEnvFromPreset(args[0].String(), sessionID)
+}
+
+// loadBundle creates the environment and programs of a policy bundle
+// The bundle may be a Uint8Array or string holding its JSON, or the parsed object. The
+// optional Ed25519 public key, a Uint8Array or base64 string, requires a signed bundle,
+// and a signed bundle without a key is only loaded when skipSignatureVerification is true
+func loadBundle(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected at least 1 argument: bundle Uint8Array|string|object, sessionID string (optional), publicKey Uint8Array|string (optional), skipSignatureVerification bool (optional)",
+		}
+	}
+
+	var data []byte
+	switch {
+	case args[0].InstanceOf(js.Global().Get("Uint8Array")):
+		data = make([]byte, args[0].Get("length").Int())
+		js.CopyBytesToGo(data, args[0])
+	case args[0].Type() == js.TypeString:
+		data = []byte(args[0].String())
+	case args[0].Type() == js.TypeObject:
+		data = []byte(js.Global().Get("JSON").Call("stringify", args[0]).String())
+	default:
+		return map[string]interface{}{
+			"error": "bundle must be a Uint8Array, JSON string or object",
+		}
+	}
+
+	var sessionID string
+	if id := optionalStringArg(args, 1); id != nil {
+		sessionID = *id
+	}
+
+	publicKey, err := publicKeyArg(args, 2)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	if publicKey == nil && len(args) >= 4 && args[3].Truthy() {
+		return celengine.LoadUnverifiedBundle(data, sessionID)
+	}
+	return celengine.LoadBundleWithKey(data, sessionID, publicKey)
+}
+
+// publicKeyArg returns the optional Ed25519 public key at an index, a Uint8Array or
+// base64 string, or nil if it's absent
+func publicKeyArg(args []js.Value, index int) ([]byte, error) {
+	switch {
+	case len(args) <= index || args[index].IsNull() || args[index].IsUndefined():
+		return nil, nil
+	case args[index].InstanceOf(js.Global().Get("Uint8Array")):
+		publicKey := make([]byte, args[index].Get("length").Int())
+		js.CopyBytesToGo(publicKey, args[index])
+		return publicKey, nil
+	case args[index].Type() == js.TypeString:
+		decoded, err := base64.StdEncoding.DecodeString(args[index].String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %v", err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("publicKey must be a Uint8Array or base64 string")
+	}
 }
 
 // compileExpr compiles a CEL expression using an environment
 func compileExpr(this js.Value, args []js.Value) interface{} {
 	if len(args) < 2 {
 		return map[string]interface{}{
-			"error": "expected 2 arguments: envID string, expression string",
+			"error": "expected at least 2 arguments: envID string, expression string",
 		}
 	}
 
 	envID := args[0].String()
 	exprStr := args[1].String()
 
-	return cel.Compile(envID, exprStr)
+	flags, err := compileFlagsArg(args, 3)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return celengine.CompileWithFlags(envID, exprStr, optionalStringArg(args, 2), flags)
 }
 
 // compileExprDetailed compiles a CEL expression with detailed results including all issues
 func compileExprDetailed(this js.Value, args []js.Value) interface{} {
 	if len(args) < 2 {
 		return map[string]interface{}{
-			"error": "expected 2 arguments: envID string, expression string",
+			"error": "expected at least 2 arguments: envID string, expression string",
 		}
 	}
 
 	envID := args[0].String()
 	exprStr := args[1].String()
 
-	return cel.CompileDetailed(envID, exprStr)
+	flags, err := compileFlagsArg(args, 3)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return celengine.CompileDetailedWithFlags(envID, exprStr, optionalStringArg(args, 2), flags)
+}
+
+// instantiateTemplate compiles an expression template with its placeholders bound to literals
+func instantiateTemplate(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return map[string]interface{}{
+			"error": "expected at least 3 arguments: envID string, template string, bindings object",
+		}
+	}
+
+	envID := args[0].String()
+	templateSource := args[1].String()
+
+	var bindings map[string]interface{}
+	if !args[2].IsNull() && !args[2].IsUndefined() {
+		bindingsJSON := js.Global().Get("JSON").Call("stringify", args[2]).String()
+		if err := json.Unmarshal([]byte(bindingsJSON), &bindings); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse bindings: %v", err),
+			}
+		}
+	}
+
+	flags, err := compileFlagsArg(args, 4)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return celengine.InstantiateTemplateWithFlags(envID, templateSource, bindings, optionalStringArg(args, 3), flags)
+}
+
+// recompilePrograms compiles the programs of an environment again after it was extended
+func recompilePrograms(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: envID string",
+		}
+	}
+
+	envID := args[0].String()
+	return celengine.RecompilePrograms(envID)
+}
+
+// compileFlagsArg parses the compile flags at the given index
+// A boolean is accepted in place of the flags object to enable metrics
+func compileFlagsArg(args []js.Value, index int) (celengine.CompileFlags, error) {
+	var flags celengine.CompileFlags
+	if len(args) <= index || args[index].IsNull() || args[index].IsUndefined() {
+		return flags, nil
+	}
+	if args[index].Type() == js.TypeBoolean {
+		flags.Metrics = args[index].Bool()
+		return flags, nil
+	}
+
+	flagsJSON := js.Global().Get("JSON").Call("stringify", args[index]).String()
+	if err := json.Unmarshal([]byte(flagsJSON), &flags); err != nil {
+		return flags, fmt.Errorf("failed to parse compile flags: %v", err)
+	}
+	return flags, nil
+}
+
+// optionalStringArg returns the string argument at the given index, or nil if it was not provided
+func optionalStringArg(args []js.Value, index int) *string {
+	if len(args) <= index || args[index].IsNull() || args[index].IsUndefined() {
+		return nil
+	}
+	value := args[index].String()
+	return &value
 }
 
 // typecheckExpr typechecks a CEL expression using an environment
 func typecheckExpr(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected at least 2 arguments: envID string, expression string, options? object",
+		}
+	}
+
+	envID := args[0].String()
+	exprStr := args[1].String()
+
+	var options celengine.TypecheckOptions
+	if len(args) >= 3 && !args[2].IsNull() && !args[2].IsUndefined() {
+		optionsJSON := js.Global().Get("JSON").Call("stringify", args[2]).String()
+		if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse typecheck options: %v", err),
+			}
+		}
+	}
+
+	return celengine.TypecheckWithOptions(envID, exprStr, options)
+}
+
+// canonicalHash hashes the canonical form of a CEL expression
+func canonicalHash(this js.Value, args []js.Value) interface{} {
 	if len(args) < 2 {
 		return map[string]interface{}{
 			"error": "expected 2 arguments: envID string, expression string",
@@ -155,7 +425,123 @@ func typecheckExpr(this js.Value, args []js.Value) interface{} {
 	envID := args[0].String()
 	exprStr := args[1].String()
 
-	return cel.Typecheck(envID, exprStr)
+	return celengine.CanonicalHash(envID, exprStr)
+}
+
+// diffExprs compares two CEL expressions structurally
+func diffExprs(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return map[string]interface{}{
+			"error": "expected 3 arguments: envID string, exprA string, exprB string",
+		}
+	}
+
+	envID := args[0].String()
+	exprA := args[1].String()
+	exprB := args[2].String()
+
+	return celengine.DiffExprs(envID, exprA, exprB)
+}
+
+// mutate compiles the mutants of an expression, for measuring rule test suites
+func mutate(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: envID string, expr string",
+		}
+	}
+
+	envID := args[0].String()
+	expr := args[1].String()
+
+	return celengine.Mutate(envID, expr)
+}
+
+// describeEnv describes the declarations and options of an environment
+func describeEnv(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: envID string",
+		}
+	}
+
+	return celengine.DescribeEnv(args[0].String())
+}
+
+// listFunctions lists the documented functions and macros of an environment
+func listFunctions(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected at least 1 argument: envID string, options? object",
+		}
+	}
+
+	var options celengine.ListFunctionsOptions
+	if len(args) >= 2 && !args[1].IsNull() && !args[1].IsUndefined() {
+		optionsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse list options: %v", err),
+			}
+		}
+	}
+
+	return celengine.ListFunctions(args[0].String(), options)
+}
+
+// generateSampleVars generates values of the variables an environment declares
+func generateSampleVars(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected at least 1 argument: envID string, options? object",
+		}
+	}
+
+	var options celengine.SampleOptions
+	if len(args) >= 2 && !args[1].IsNull() && !args[1].IsUndefined() {
+		optionsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse sample options: %v", err),
+			}
+		}
+	}
+
+	return celengine.GenerateSampleVars(args[0].String(), options)
+}
+
+// evaluate compiles and evaluates an expression in one call, inferring the variable
+// declarations from their values
+func evaluate(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected at least 1 argument: source string, vars? object, options? object",
+		}
+	}
+
+	source := args[0].String()
+
+	vars := make(map[string]interface{})
+	if len(args) >= 2 && !args[1].IsNull() && !args[1].IsUndefined() {
+		varsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(varsJSON), &vars); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse variables: %v", err),
+			}
+		}
+	}
+
+	var options celengine.EvaluateOptions
+	if len(args) >= 3 && !args[2].IsNull() && !args[2].IsUndefined() {
+		optionsJSON := js.Global().Get("JSON").Call("stringify", args[2]).String()
+		if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse evaluate options: %v", err),
+			}
+		}
+	}
+
+	return celengine.Evaluate(source, vars, options)
 }
 
 // evalProgram evaluates a compiled program
@@ -181,75 +567,670 @@ func evalProgram(this js.Value, args []js.Value) interface{} {
 		vars = make(map[string]interface{})
 	}
 
-	return cel.Eval(programID, vars)
-}
-
-// destroyEnv destroys an environment and cleans up associated resources
-func destroyEnv(this js.Value, args []js.Value) interface{} {
-	if len(args) < 1 {
+	options, err := evalOptionsArg(args, 2)
+	if err != nil {
 		return map[string]interface{}{
-			"error": "expected 1 argument: envID string",
+			"error": err.Error(),
 		}
 	}
 
-	envID := args[0].String()
-	return cel.DestroyEnv(envID)
+	return evalResponse(celengine.EvalWithOptions(programID, vars, options), options)
 }
 
-// destroyProgram destroys a compiled program
-func destroyProgram(this js.Value, args []js.Value) interface{} {
-	if len(args) < 1 {
+// evalPrograms evaluates several compiled programs against the same variables
+func evalPrograms(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
 		return map[string]interface{}{
-			"error": "expected 1 argument: programID string",
+			"error": "expected 2 arguments: programIDs array, vars object",
 		}
 	}
 
-	programID := args[0].String()
-	return cel.DestroyProgram(programID)
-}
-
-// extendEnv extends an existing environment with additional options
-func extendEnv(this js.Value, args []js.Value) interface{} {
-	if len(args) < 2 {
+	var programIDs []string
+	programIDsJSON := js.Global().Get("JSON").Call("stringify", args[0]).String()
+	if err := json.Unmarshal([]byte(programIDsJSON), &programIDs); err != nil {
 		return map[string]interface{}{
-			"error": "expected 2 arguments: envID string, options string",
+			"error": fmt.Sprintf("failed to parse program IDs: %v", err),
 		}
 	}
 
-	envID := args[0].String()
-	optionsJSON := args[1].String()
+	// Parse variables from second argument
+	var vars map[string]interface{}
+	if !args[1].IsNull() && !args[1].IsUndefined() {
+		varsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(varsJSON), &vars); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse variables: %v", err),
+			}
+		}
+	} else {
+		vars = make(map[string]interface{})
+	}
+
+	options, err := evalOptionsArg(args, 2)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
 
-	return cel.ExtendEnv(envID, optionsJSON)
+	return evalResponse(celengine.EvalPrograms(programIDs, vars, options), options)
 }
 
+// evalRepeated evaluates a compiled program several times and reports its timings
+func evalRepeated(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return map[string]interface{}{
+			"error": "expected 3 arguments: programID string, vars object, n number",
+		}
+	}
 
-func main() {
-	// Set the JavaScript function caller
-	cel.SetJSFunctionCaller(functionCaller)
-	// Set the unregister function caller (same instance)
-	cel.SetUnregisterFunctionCaller(functionCaller)
-	
-	// Set the JavaScript function caller for the options package (for AST validators)
-	options.SetJSFunctionCaller(functionCaller)
-	
+	programID := args[0].String()
+
+	// Parse variables from second argument
+	var vars map[string]interface{}
+	if !args[1].IsNull() && !args[1].IsUndefined() {
+		varsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(varsJSON), &vars); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse variables: %v", err),
+			}
+		}
+	} else {
+		vars = make(map[string]interface{})
+	}
+
+	if args[2].Type() != js.TypeNumber {
+		return map[string]interface{}{
+			"error": "n must be a number",
+		}
+	}
+	n := args[2].Int()
+
+	options, err := evalOptionsArg(args, 3)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return evalResponse(celengine.EvalRepeated(programID, vars, n, options), options)
+}
+
+// runTests evaluates a compiled program against test cases
+func runTests(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: programID string, cases array",
+		}
+	}
+
+	programID := args[0].String()
+
+	var cases []celengine.TestCase
+	casesJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+	if err := json.Unmarshal([]byte(casesJSON), &cases); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse test cases: %v", err),
+		}
+	}
+
+	options, err := evalOptionsArg(args, 2)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return evalResponse(celengine.RunTests(programID, cases, options), options)
+}
+
+// explain evaluates a compiled program and explains its result as a tree
+func explain(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: programID string, vars object",
+		}
+	}
+
+	programID := args[0].String()
+
+	// Parse variables from second argument
+	var vars map[string]interface{}
+	if !args[1].IsNull() && !args[1].IsUndefined() {
+		varsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(varsJSON), &vars); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse variables: %v", err),
+			}
+		}
+	} else {
+		vars = make(map[string]interface{})
+	}
+
+	options, err := evalOptionsArg(args, 2)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return evalResponse(celengine.Explain(programID, vars, options.ValueEncoding), options)
+}
+
+// evalProgramWithContextMessage evaluates a program using the fields of a protobuf message as variables
+// The message may be a Uint8Array in the binary encoding, a JSON string, or an object in the JSON mapping
+func evalProgramWithContextMessage(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return map[string]interface{}{
+			"error": "expected 3 arguments: programID string, typeName string, message Uint8Array|string|object",
+		}
+	}
+
+	programID := args[0].String()
+	typeName := args[1].String()
+
+	var message []byte
+	encoding := celengine.ContextMessageJSON
+	switch {
+	case args[2].InstanceOf(js.Global().Get("Uint8Array")):
+		message = make([]byte, args[2].Get("length").Int())
+		js.CopyBytesToGo(message, args[2])
+		encoding = celengine.ContextMessageBinary
+	case args[2].Type() == js.TypeString:
+		message = []byte(args[2].String())
+	case args[2].Type() == js.TypeObject:
+		message = []byte(js.Global().Get("JSON").Call("stringify", args[2]).String())
+	default:
+		return map[string]interface{}{
+			"error": "message must be a Uint8Array, JSON string or object",
+		}
+	}
+
+	options, err := evalOptionsArg(args, 3)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return evalResponse(celengine.EvalWithContextMessage(programID, typeName, message, encoding, options), options)
+}
+
+// evalOptionsArg parses the evaluation options at the given index
+// A boolean is accepted in place of the options object to enable metrics
+func evalOptionsArg(args []js.Value, index int) (celengine.EvalOptions, error) {
+	var options celengine.EvalOptions
+	if len(args) <= index || args[index].IsNull() || args[index].IsUndefined() {
+		return options, nil
+	}
+	if args[index].Type() == js.TypeBoolean {
+		options.Metrics = args[index].Bool()
+		return options, nil
+	}
+
+	optionsJSON := js.Global().Get("JSON").Call("stringify", args[index]).String()
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return options, fmt.Errorf("failed to parse evaluation options: %v", err)
+	}
+	return options, nil
+}
+
+// destroyEnv destroys an environment and cleans up associated resources
+func destroyEnv(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: envID string",
+		}
+	}
+
+	envID := args[0].String()
+	return celengine.DestroyEnv(envID)
+}
+
+// replaceProgram compiles a new expression under the ID of an existing program
+func replaceProgram(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: programID string, expression string",
+		}
+	}
+
+	programID := args[0].String()
+	exprStr := args[1].String()
+	return celengine.ReplaceProgram(programID, exprStr)
+}
+
+// exportCheckedExpr encodes the checked expression of a compiled program
+// The binary encoding is returned as a Uint8Array
+func exportCheckedExpr(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: programID string, format string (optional)",
+		}
+	}
+
+	programID := args[0].String()
+	var format string
+	if len(args) >= 2 && !args[1].IsNull() && !args[1].IsUndefined() {
+		format = args[1].String()
+	}
+	return toJSValue(celengine.ExportCheckedExpr(programID, format))
+}
+
+// programFromCheckedExpr compiles a checked expression into a program of an environment
+// The checked expression may be a Uint8Array in the binary encoding or a string in the JSON
+// or text encoding, named by format. With the optional Ed25519 public key, a Uint8Array or
+// base64 string, it must be a signed checked expression, as its JSON or the parsed object
+func programFromCheckedExpr(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected at least 2 arguments: envID string, checkedExpr Uint8Array|string|object",
+		}
+	}
+
+	envID := args[0].String()
+
+	var data []byte
+	switch {
+	case args[1].InstanceOf(js.Global().Get("Uint8Array")):
+		data = make([]byte, args[1].Get("length").Int())
+		js.CopyBytesToGo(data, args[1])
+	case args[1].Type() == js.TypeString:
+		data = []byte(args[1].String())
+	case args[1].Type() == js.TypeObject:
+		data = []byte(js.Global().Get("JSON").Call("stringify", args[1]).String())
+	default:
+		return map[string]interface{}{
+			"error": "checkedExpr must be a Uint8Array, string or signed checked expression",
+		}
+	}
+
+	var format string
+	if len(args) >= 3 && !args[2].IsNull() && !args[2].IsUndefined() {
+		format = args[2].String()
+	}
+
+	flags, err := compileFlagsArg(args, 4)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	publicKey, err := publicKeyArg(args, 5)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	if publicKey != nil {
+		return celengine.ProgramFromCheckedExprWithKey(envID, data, format, optionalStringArg(args, 3), flags, publicKey)
+	}
+	return celengine.ProgramFromCheckedExpr(envID, data, format, optionalStringArg(args, 3), flags)
+}
+
+// warmup prepares a compiled program for its first evaluation
+func warmup(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: programID string",
+		}
+	}
+
+	programID := args[0].String()
+	return celengine.Warmup(programID)
+}
+
+// destroyProgram destroys a compiled program
+func destroyProgram(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: programID string",
+		}
+	}
+
+	programID := args[0].String()
+	return celengine.DestroyProgram(programID)
+}
+
+// createRuleSet compiles rules into a rule set
+func createRuleSet(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: envID string, rules array",
+		}
+	}
+
+	var rules []celengine.Rule
+	rulesJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse rules: %v", err),
+		}
+	}
+
+	return celengine.CreateRuleSet(args[0].String(), rules)
+}
+
+// evalRuleSet evaluates the rules of a rule set
+func evalRuleSet(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected at least 2 arguments: ruleSetID string, vars object",
+		}
+	}
+
+	// Parse variables from second argument
+	var vars map[string]interface{}
+	if !args[1].IsNull() && !args[1].IsUndefined() {
+		varsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(varsJSON), &vars); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse variables: %v", err),
+			}
+		}
+	} else {
+		vars = make(map[string]interface{})
+	}
+
+	var options celengine.RuleSetEvalOptions
+	if len(args) >= 3 && !args[2].IsNull() && !args[2].IsUndefined() {
+		optionsJSON := js.Global().Get("JSON").Call("stringify", args[2]).String()
+		if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse rule set options: %v", err),
+			}
+		}
+	}
+
+	return celengine.EvalRuleSet(args[0].String(), vars, options)
+}
+
+// destroyRuleSet destroys a rule set and its programs
+func destroyRuleSet(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: ruleSetID string",
+		}
+	}
+
+	return celengine.DestroyRuleSet(args[0].String())
+}
+
+// compilePolicy compiles a policy document into a policy
+func compilePolicy(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: envID string, source string",
+		}
+	}
+
+	return celengine.CompilePolicy(args[0].String(), args[1].String())
+}
+
+// evalPolicy evaluates a policy
+func evalPolicy(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected at least 2 arguments: policyID string, vars object",
+		}
+	}
+
+	// Parse variables from second argument
+	var vars map[string]interface{}
+	if !args[1].IsNull() && !args[1].IsUndefined() {
+		varsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(varsJSON), &vars); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse variables: %v", err),
+			}
+		}
+	} else {
+		vars = make(map[string]interface{})
+	}
+
+	var encoding celengine.ValueEncoding
+	if len(args) >= 3 && !args[2].IsNull() && !args[2].IsUndefined() {
+		optionsJSON := js.Global().Get("JSON").Call("stringify", args[2]).String()
+		if err := json.Unmarshal([]byte(optionsJSON), &encoding); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to parse policy options: %v", err),
+			}
+		}
+	}
+
+	return celengine.EvalPolicy(args[0].String(), vars, encoding)
+}
+
+// destroyPolicy destroys a policy and its programs
+func destroyPolicy(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: policyID string",
+		}
+	}
+
+	return celengine.DestroyPolicy(args[0].String())
+}
+
+// extendEnv extends an existing environment with additional options
+func extendEnv(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{
+			"error": "expected 2 arguments: envID string, options string",
+		}
+	}
+
+	envID := args[0].String()
+	optionsJSON := args[1].String()
+
+	return celengine.ExtendEnv(envID, optionsJSON)
+}
+
+// createSession creates a session that groups environments for bulk destruction
+func createSession(this js.Value, args []js.Value) interface{} {
+	return celengine.CreateSession()
+}
+
+// destroySession destroys all environments and programs created within a session
+func destroySession(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: sessionID string",
+		}
+	}
+
+	return celengine.DestroySession(args[0].String())
+}
+
+// configure updates the runtime configuration, such as the TTLs of idle handles
+func configure(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: config string",
+		}
+	}
+
+	return celengine.Configure(args[0].String())
+}
+
+// sweep destroys environments and programs that have been idle for longer than their TTL
+func sweep(this js.Value, args []js.Value) interface{} {
+	return celengine.Sweep()
+}
+
+// setLogger routes internal warnings to a registered JavaScript function
+func setLogger(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected at least 1 argument: implID string, level string (optional)",
+		}
+	}
+
+	// A null implID removes the logger
+	implID := ""
+	if !args[0].IsNull() && !args[0].IsUndefined() {
+		implID = args[0].String()
+	}
+
+	level := ""
+	if value := optionalStringArg(args, 1); value != nil {
+		level = *value
+	}
+
+	return celengine.SetLogger(implID, level)
+}
+
+// setLifecycleListener sends lifecycle events to a registered JavaScript function
+func setLifecycleListener(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: implID string",
+		}
+	}
+
+	// A null implID removes the listener
+	implID := ""
+	if !args[0].IsNull() && !args[0].IsUndefined() {
+		implID = args[0].String()
+	}
+
+	return celengine.SetLifecycleListener(implID)
+}
+
+// getCapabilities returns version and feature information about the module
+func getCapabilities(this js.Value, args []js.Value) interface{} {
+	return celengine.GetCapabilities()
+}
+
+// describeOptions returns the parameter schemas of all JSON-configurable options
+func describeOptions(this js.Value, args []js.Value) interface{} {
+	return celengine.DescribeOptions()
+}
+
+// exportedFunc is an API function attached to the exports target
+// The target is kept because loaders may remove their global slot once main has run
+type exportedFunc struct {
+	target js.Value
+	name   string
+	fn     js.Func
+}
+
+var (
+	exported []exportedFunc
+	// done is closed by shutdown to let main return
+	done = make(chan struct{})
+)
+
+// export attaches a Go function to the exports target and keeps its handle for shutdown
+// Panics are returned to JavaScript as internal errors instead of aborting the module
+func export(target js.Value, name string, fn func(this js.Value, args []js.Value) interface{}) {
+	jsFn := js.FuncOf(func(this js.Value, args []js.Value) (result interface{}) {
+		defer celengine.RecoverPanic(&result)
+		return fn(this, args)
+	})
+	target.Set(name, jsFn)
+	exported = append(exported, exportedFunc{target: target, name: name, fn: jsFn})
+}
+
+// shutdown tears down all engine state, removes the exports and lets main return
+// so the host can dispose of the instance and create a new one
+func shutdown(this js.Value, args []js.Value) interface{} {
+	result := celengine.Shutdown()
+	functionCaller.registry = make(map[string]js.Value)
+	cancelEvals()
+
+	for _, e := range exported {
+		e.target.Delete(e.name)
+		// Releasing the running function is safe, it only stops future calls
+		e.fn.Release()
+	}
+	exported = nil
+
+	close(done)
+	return result
+}
+
+// exportsTarget returns the object the API functions are attached to
+// Loaders that don't want globals set the WASM_CEL_EXPORTS environment variable
+// to the name of a global object that receives the exports instead
+func exportsTarget() js.Value {
+	if name := os.Getenv("WASM_CEL_EXPORTS"); name != "" {
+		if target := js.Global().Get(name); target.Type() == js.TypeObject {
+			return target
+		}
+	}
+	return js.Global()
+}
+
+func main() {
+	// Set the JavaScript function caller
+	celengine.SetJSFunctionCaller(functionCaller)
+	// Set the unregister function caller (same instance)
+	celengine.SetUnregisterFunctionCaller(functionCaller)
+	
+	// Set the JavaScript function caller for the options package (for AST validators)
+	options.SetJSFunctionCaller(functionCaller)
+	
 	// Set up the compilation context function for the filename side-channel approach
 	options.SetGetCompilationContextFunc(compilationContextAdapter)
 
+	exports := exportsTarget()
+
 	// Register the registerFunction function for registering JS function implementations
-	js.Global().Set("registerCELFunction", js.FuncOf(registerFunction))
+	export(exports, "registerCELFunction", registerFunction)
 
 	// Register the API functions
-	js.Global().Set("createEnv", js.FuncOf(createEnv))
-	js.Global().Set("extendEnv", js.FuncOf(extendEnv))
-	js.Global().Set("compileExpr", js.FuncOf(compileExpr))
-	js.Global().Set("compileExprDetailed", js.FuncOf(compileExprDetailed))
-	js.Global().Set("typecheckExpr", js.FuncOf(typecheckExpr))
-	js.Global().Set("evalProgram", js.FuncOf(evalProgram))
-	js.Global().Set("destroyEnv", js.FuncOf(destroyEnv))
-	js.Global().Set("destroyProgram", js.FuncOf(destroyProgram))
-
-
-	// Keep the program running
-	// In WASM, we need to keep the main goroutine alive
-	select {}
+	export(exports, "defineGlobalFunction", defineGlobalFunction)
+	export(exports, "registerLibrary", registerLibrary)
+	export(exports, "createEnv", createEnv)
+	export(exports, "createEnvFromJSONSchema", createEnvFromJSONSchema)
+	export(exports, "createEnvFromPreset", createEnvFromPreset)
+	export(exports, "loadBundle", loadBundle)
+	export(exports, "extendEnv", extendEnv)
+	export(exports, "compileExpr", compileExpr)
+	export(exports, "compileExprDetailed", compileExprDetailed)
+	export(exports, "instantiateTemplate", instantiateTemplate)
+	export(exports, "recompilePrograms", recompilePrograms)
+	export(exports, "replaceProgram", replaceProgram)
+	export(exports, "warmup", warmup)
+	export(exports, "exportCheckedExpr", exportCheckedExpr)
+	export(exports, "programFromCheckedExpr", programFromCheckedExpr)
+	export(exports, "typecheckExpr", typecheckExpr)
+	export(exports, "canonicalHash", canonicalHash)
+	export(exports, "diffExprs", diffExprs)
+	export(exports, "mutate", mutate)
+	export(exports, "describeEnv", describeEnv)
+	export(exports, "listFunctions", listFunctions)
+	export(exports, "generateSampleVars", generateSampleVars)
+	export(exports, "evaluate", evaluate)
+	export(exports, "evalProgram", evalProgram)
+	export(exports, "evalPrograms", evalPrograms)
+	export(exports, "evalRepeated", evalRepeated)
+	export(exports, "explain", explain)
+	export(exports, "runTests", runTests)
+	export(exports, "evalProgramAsync", evalProgramAsync)
+	export(exports, "cancelEval", cancelEval)
+	export(exports, "evalProgramWithContextMessage", evalProgramWithContextMessage)
+	export(exports, "destroyEnv", destroyEnv)
+	export(exports, "destroyProgram", destroyProgram)
+	export(exports, "createRuleSet", createRuleSet)
+	export(exports, "evalRuleSet", evalRuleSet)
+	export(exports, "destroyRuleSet", destroyRuleSet)
+	export(exports, "compilePolicy", compilePolicy)
+	export(exports, "evalPolicy", evalPolicy)
+	export(exports, "destroyPolicy", destroyPolicy)
+	export(exports, "createSession", createSession)
+	export(exports, "destroySession", destroySession)
+	export(exports, "configure", configure)
+	export(exports, "sweep", sweep)
+	export(exports, "setLogger", setLogger)
+	export(exports, "setLifecycleListener", setLifecycleListener)
+	export(exports, "getCapabilities", getCapabilities)
+	export(exports, "describeOptions", describeOptions)
+	export(exports, "shutdown", shutdown)
+
+	// Keep the program running until shutdown
+	// In WASM, we need to keep the main goroutine alive for the exports to stay callable
+	<-done
 }
@@ -0,0 +1,202 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"syscall/js"
+	"time"
+
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+var (
+	jsBigInt         = js.Global().Get("BigInt")
+	jsString         = js.Global().Get("String")
+	jsDate           = js.Global().Get("Date")
+	jsUint8Array     = js.Global().Get("Uint8Array")
+	jsObjectKeys     = js.Global().Get("Object").Get("keys")
+	jsObjectToString = js.Global().Get("Object").Get("prototype").Get("toString")
+)
+
+// evalResponse prepares the response of an evaluation for JavaScript
+// With the "bigint" integers mode, the integers celengine encoded as json.Number
+// become BigInts
+func evalResponse(response map[string]interface{}, options celengine.EvalOptions) interface{} {
+	if options.Integers != celengine.IntegersBigInt {
+		return response
+	}
+	return bigIntsToJS(response)
+}
+
+// bigIntsToJS replaces the json.Numbers in an encoded value with BigInts
+func bigIntsToJS(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		return jsBigInt.Invoke(string(v))
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = bigIntsToJS(item)
+		}
+		return items
+	case map[string]interface{}:
+		entries := make(map[string]interface{}, len(v))
+		for key, entry := range v {
+			entries[key] = bigIntsToJS(entry)
+		}
+		return entries
+	}
+	return value
+}
+
+// toJSValue converts a function argument from celengine to a JavaScript value
+// Uints become BigInts so they don't lose precision, bytes become Uint8Arrays and
+// timestamps become Dates
+func toJSValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case uint64:
+		return jsBigInt.Invoke(strconv.FormatUint(v, 10))
+	case []byte:
+		array := jsUint8Array.New(len(v))
+		js.CopyBytesToJS(array, v)
+		return array
+	case time.Time:
+		return jsDate.New(float64(v.UnixNano()) / float64(time.Millisecond))
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = toJSValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		entries := make(map[string]interface{}, len(v))
+		for key, entry := range v {
+			entries[key] = toJSValue(entry)
+		}
+		return entries
+	}
+	return value
+}
+
+// fromJSValue converts a value returned by a JavaScript function to the Go value handed
+// back to celengine, the inverse of toJSValue
+// The value's tag is read with Object.prototype.toString, since js.Value.Type doesn't
+// support BigInts
+func fromJSValue(value js.Value) (interface{}, error) {
+	switch tag := jsTag(value); tag {
+	case "[object Undefined]", "[object Null]":
+		return nil, nil
+	case "[object Boolean]":
+		return jsPrimitive(value, js.TypeBoolean).Bool(), nil
+	case "[object Number]":
+		return jsPrimitive(value, js.TypeNumber).Float(), nil
+	case "[object String]":
+		return jsPrimitive(value, js.TypeString).String(), nil
+	case "[object BigInt]":
+		// Methods can't be called on primitives through syscall/js
+		digits := jsString.Invoke(value).String()
+		if i, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(digits, 10, 64); err == nil {
+			return u, nil
+		}
+		return nil, fmt.Errorf("BigInt %s is out of the range of int and uint", digits)
+	case "[object Uint8Array]":
+		bytes := make([]byte, value.Get("length").Int())
+		js.CopyBytesToGo(bytes, value)
+		return bytes, nil
+	case "[object Date]":
+		ms := value.Call("getTime").Float()
+		if math.IsNaN(ms) {
+			return nil, fmt.Errorf("invalid Date")
+		}
+		return time.UnixMilli(int64(ms)).UTC(), nil
+	case "[object Array]":
+		items := make([]interface{}, value.Length())
+		for i := range items {
+			item, err := fromJSValue(value.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			items[i] = item
+		}
+		return items, nil
+	case "[object Promise]":
+		return nil, fmt.Errorf("functions must return their result synchronously outside of asynchronous evaluations, got a Promise")
+	case "[object Function]", "[object AsyncFunction]", "[object Symbol]":
+		return nil, fmt.Errorf("unsupported value %s", tag)
+	default:
+		// Objects with a JSON form, like class instances, are converted through it
+		if jsTag(value.Get("toJSON")) == "[object Function]" {
+			return fromJSValue(value.Call("toJSON"))
+		}
+		keys := jsObjectKeys.Invoke(value)
+		entries := make(map[string]interface{}, keys.Length())
+		for i := 0; i < keys.Length(); i++ {
+			key := keys.Index(i).String()
+			entry := value.Get(key)
+			// JSON drops undefined properties and methods, so objects convert the same way
+			if tag := jsTag(entry); tag == "[object Undefined]" || tag == "[object Function]" {
+				continue
+			}
+			converted, err := fromJSValue(entry)
+			if err != nil {
+				return nil, fmt.Errorf("[%q]: %v", key, err)
+			}
+			entries[key] = converted
+		}
+		return entries, nil
+	}
+}
+
+// jsTag returns the tag Object.prototype.toString reports for a value, like "[object Date]"
+func jsTag(value js.Value) string {
+	return jsObjectToString.Call("call", value).String()
+}
+
+// jsPrimitive unwraps a Boolean, Number or String object to its primitive value
+func jsPrimitive(value js.Value, primitive js.Type) js.Value {
+	if value.Type() == primitive {
+		return value
+	}
+	return value.Call("valueOf")
+}
+
+// invokeFunction calls a function implementation, returning what it throws as a
+// celengine.FunctionError
+func invokeFunction(fn js.Value, args []interface{}) (result js.Value, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			thrown, ok := recovered.(js.Error)
+			if !ok {
+				panic(recovered)
+			}
+			err = thrownError(thrown.Value)
+		}
+	}()
+	return fn.Invoke(args...), nil
+}
+
+// thrownError describes a thrown value, using the code and message properties of errors
+func thrownError(value js.Value) *celengine.FunctionError {
+	fnErr := &celengine.FunctionError{Code: celengine.FunctionErrorCode}
+	switch jsTag(value) {
+	case "[object Error]", "[object Object]":
+		if code := value.Get("code"); jsTag(code) == "[object String]" {
+			if text := jsString.Invoke(code).String(); text != "" {
+				fnErr.Code = text
+			}
+		}
+		if message := value.Get("message"); jsTag(message) == "[object String]" {
+			fnErr.Message = jsString.Invoke(message).String()
+			return fnErr
+		}
+	}
+	fnErr.Message = jsString.Invoke(value).String()
+	return fnErr
+}
@@ -0,0 +1,303 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"syscall/js"
+	"time"
+
+	"github.com/invakid404/wasm-cel/internal/logging"
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+// evalJob is an evaluation queued on or running on the worker pool
+type evalJob struct {
+	token      string
+	programID  string
+	vars       map[string]interface{}
+	options    celengine.EvalOptions
+	callbackID string // Registered function receiving the result, removed once called
+	started    bool
+	cancel     context.CancelFunc
+	signal     js.Value    // AbortSignal passed to custom functions, created by the first call
+	stopSignal func() bool // Stops aborting the signal once the job's context is done
+}
+
+// The pool runs at most celengine.EvalWorkers() jobs at once, starting a goroutine per
+// worker while jobs are queued. JavaScript is single-threaded, so goroutines only switch
+// when one blocks, and the pool's state needs no locking
+var (
+	evalQueue     []*evalJob
+	evalRunning   int
+	evalJobs      = make(map[string]*evalJob) // Queued and running jobs by token
+	evalIDCounter int
+	// runningJob is the job a worker is evaluating, whose custom functions may return
+	// Promises that the worker waits for, letting other workers run in the meantime
+	runningJob *evalJob
+)
+
+// evalProgramAsync queues an evaluation on the worker pool and returns a token for
+// cancelEval right away
+// The result is passed to the callback registered under callbackImplID, which is removed
+// once called, or right away if the evaluation can't be queued
+func evalProgramAsync(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return map[string]interface{}{
+			"error": "expected at least 3 arguments: programID string, vars object, callbackImplID string",
+		}
+	}
+
+	programID := args[0].String()
+	callbackID := args[2].String()
+	if _, ok := functionCaller.registry[callbackID]; !ok {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("function implementation not found: %s", callbackID),
+		}
+	}
+
+	job, err := newEvalJob(programID, args[1], callbackID, args)
+	if err != nil {
+		delete(functionCaller.registry, callbackID)
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	evalJobs[job.token] = job
+	evalQueue = append(evalQueue, job)
+	if evalRunning < celengine.EvalWorkers() {
+		evalRunning++
+		go evalWorker()
+	}
+
+	return map[string]interface{}{
+		"token": job.token,
+		"error": nil,
+	}
+}
+
+// newEvalJob parses the variables and the options that follow the callback
+func newEvalJob(programID string, varsArg js.Value, callbackID string, args []js.Value) (*evalJob, error) {
+	var vars map[string]interface{}
+	if !varsArg.IsNull() && !varsArg.IsUndefined() {
+		varsJSON := js.Global().Get("JSON").Call("stringify", varsArg).String()
+		if err := json.Unmarshal([]byte(varsJSON), &vars); err != nil {
+			return nil, fmt.Errorf("failed to parse variables: %v", err)
+		}
+	} else {
+		vars = make(map[string]interface{})
+	}
+
+	options, err := evalOptionsArg(args, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout counts from the call, including the time spent waiting for a worker
+	evalIDCounter++
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := options.Timeout(); timeout > 0 {
+		ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(timeout))
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	options.Context = ctx
+	return &evalJob{
+		token:      fmt.Sprintf("eval_%s_%d", celengine.InstanceNonce(), evalIDCounter),
+		programID:  programID,
+		vars:       vars,
+		options:    options,
+		callbackID: callbackID,
+		cancel:     cancel,
+	}, nil
+}
+
+// cancelEval cancels an evaluation started by evalProgramAsync
+// A queued evaluation is passed its cancellation right away, and a running one as soon
+// as it checks for it, between the iterations of comprehensions and while it waits for a
+// custom function. "cancelled" is false if the evaluation had already completed
+func cancelEval(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{
+			"error": "expected 1 argument: token string",
+		}
+	}
+
+	job, ok := evalJobs[args[0].String()]
+	if !ok {
+		return map[string]interface{}{
+			"cancelled": false,
+			"error":     nil,
+		}
+	}
+
+	job.cancel()
+	if !job.started {
+		for i, queued := range evalQueue {
+			if queued == job {
+				evalQueue = append(evalQueue[:i], evalQueue[i+1:]...)
+				break
+			}
+		}
+		job.complete(job.interruptedResult())
+	}
+
+	return map[string]interface{}{
+		"cancelled": true,
+		"error":     nil,
+	}
+}
+
+// cancelEvals cancels every queued and running evaluation without calling their callbacks
+func cancelEvals() {
+	for _, job := range evalJobs {
+		job.cancel()
+	}
+	evalJobs = make(map[string]*evalJob)
+	evalQueue = nil
+}
+
+// evalWorker runs queued evaluations until the queue is empty
+func evalWorker() {
+	defer func() { evalRunning-- }()
+	for len(evalQueue) > 0 {
+		job := evalQueue[0]
+		evalQueue[0] = nil
+		evalQueue = evalQueue[1:]
+		job.started = true
+		job.complete(job.eval())
+	}
+}
+
+// complete passes the result of the job to its callback
+func (job *evalJob) complete(result interface{}) {
+	if job.stopSignal != nil {
+		job.stopSignal()
+	}
+	job.cancel()
+	delete(evalJobs, job.token)
+
+	callback, ok := functionCaller.registry[job.callbackID]
+	if !ok {
+		// Unregistered while the evaluation ran, such as by shutdown()
+		return
+	}
+	delete(functionCaller.registry, job.callbackID)
+	if _, err := invokeFunction(callback, []interface{}{js.ValueOf(result)}); err != nil {
+		logging.Warn("evaluation callback failed", map[string]interface{}{
+			"callbackImplID": job.callbackID,
+			"error":          err.Error(),
+		})
+	}
+}
+
+// eval evaluates the job, recovering from panics like the synchronous exports do
+func (job *evalJob) eval() (result interface{}) {
+	defer celengine.RecoverPanic(&result)
+	if job.interrupted() != nil {
+		return job.interruptedResult()
+	}
+
+	runningJob = job
+	defer func() { runningJob = nil }()
+	return evalResponse(celengine.EvalWithOptions(job.programID, job.vars, job.options), job.options)
+}
+
+// interrupted returns why the job was cancelled or timed out, or nil if it wasn't
+func (job *evalJob) interrupted() error {
+	return celengine.Interrupted(job.options.Context, job.options)
+}
+
+// interruptedResult is the result of a job that was interrupted before it ran
+func (job *evalJob) interruptedResult() map[string]interface{} {
+	return map[string]interface{}{
+		"error": job.interrupted().Error(),
+	}
+}
+
+// callContext is passed to custom functions after their arguments, with a signal that
+// aborts once the evaluation is cancelled or times out, and the time left until its
+// deadline if it has one
+func (job *evalJob) callContext() js.Value {
+	if job.signal.IsUndefined() {
+		controller := js.Global().Get("AbortController").New()
+		job.signal = controller.Get("signal")
+		job.stopSignal = context.AfterFunc(job.options.Context, func() {
+			name := "AbortError"
+			if errors.Is(job.options.Context.Err(), context.DeadlineExceeded) {
+				name = "TimeoutError"
+			}
+			reason := js.Global().Get("DOMException").New(job.interrupted().Error(), name)
+			controller.Call("abort", reason)
+		})
+	}
+
+	callContext := js.Global().Get("Object").New()
+	callContext.Set("signal", job.signal)
+	if deadline, ok := job.options.Context.Deadline(); ok {
+		callContext.Set("deadline", deadline.UnixMilli())
+		callContext.Set("remainingMs", max(0, float64(time.Until(deadline).Microseconds())/1000))
+	}
+	return callContext
+}
+
+// awaitPromise blocks the running worker until a Promise settles or its evaluation is
+// cancelled or times out, letting other workers and the JavaScript event loop run in the meantime
+func awaitPromise(promise js.Value) (js.Value, error) {
+	type settlement struct {
+		value    js.Value
+		rejected bool
+	}
+	settled := make(chan settlement, 1)
+	onFulfilled := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		settled <- settlement{value: argOrUndefined(args)}
+		return nil
+	})
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		settled <- settlement{value: argOrUndefined(args), rejected: true}
+		return nil
+	})
+	release := func() {
+		onFulfilled.Release()
+		onRejected.Release()
+	}
+	promise.Call("then", onFulfilled, onRejected)
+
+	job := runningJob
+	resume := celengine.SuspendEvaluation()
+	runningJob = nil
+	var outcome settlement
+	select {
+	case outcome = <-settled:
+		release()
+	case <-job.options.Context.Done():
+		// The handlers are called once the Promise settles, so they're released then
+		go func() {
+			<-settled
+			release()
+		}()
+	}
+	runningJob = job
+	resume()
+
+	if err := job.interrupted(); err != nil {
+		return js.Undefined(), err
+	}
+	if outcome.rejected {
+		return js.Undefined(), thrownError(outcome.value)
+	}
+	return outcome.value, nil
+}
+
+// argOrUndefined returns the first argument of a callback, or undefined if there is none
+func argOrUndefined(args []js.Value) js.Value {
+	if len(args) == 0 {
+		return js.Undefined()
+	}
+	return args[0]
+}
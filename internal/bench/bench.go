@@ -0,0 +1,254 @@
+// Package bench defines the workloads measured by cmd/bench and the celengine benchmarks,
+// so both report comparable numbers.
+package bench
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+// FakeCaller implements celengine.JSFunctionCaller with Go functions standing in for
+// JavaScript implementations
+type FakeCaller struct {
+	Funcs map[string]func(args []interface{}) (interface{}, error)
+}
+
+// NewFakeCaller creates a caller without functions
+func NewFakeCaller() *FakeCaller {
+	return &FakeCaller{Funcs: make(map[string]func(args []interface{}) (interface{}, error))}
+}
+
+// CallJSFunction calls the Go function registered under implID
+func (c *FakeCaller) CallJSFunction(implID string, args []interface{}) (interface{}, error) {
+	fn, ok := c.Funcs[implID]
+	if !ok {
+		return nil, fmt.Errorf("function implementation not found: %s", implID)
+	}
+	return fn(args)
+}
+
+// UnregisterFunction removes a function implementation
+func (c *FakeCaller) UnregisterFunction(implID string) {
+	delete(c.Funcs, implID)
+}
+
+// Install makes the caller the engine's function caller
+func (c *FakeCaller) Install() {
+	celengine.SetJSFunctionCaller(c)
+	celengine.SetUnregisterFunctionCaller(c)
+}
+
+// Workload is a single benchmarked operation
+type Workload struct {
+	Name        string
+	Description string
+	// Setup prepares the workload and returns the operation to measure and a cleanup function
+	Setup func() (op func() error, cleanup func(), err error)
+}
+
+// Workloads lists all benchmarked operations
+var Workloads = []Workload{
+	{
+		Name:        "compile/simple",
+		Description: "compile and destroy a small arithmetic expression",
+		Setup:       compileWorkload("x * 2.0 + y", []celengine.VarDecl{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}}),
+	},
+	{
+		Name:        "compile/complex",
+		Description: "compile and destroy an expression with macros, string functions and maps",
+		Setup: compileWorkload(
+			`user.roles.exists(r, r in ["admin", "owner"]) && user.name.startsWith("a") && `+
+				`request.items.filter(i, i.price > 10.0).map(i, i.price).size() < 100 && `+
+				`{"a": 1, "b": 2}.all(k, k.size() == 1)`,
+			[]celengine.VarDecl{{Name: "user", Type: "dyn"}, {Name: "request", Type: "dyn"}},
+		),
+	},
+	{
+		Name:        "env/new",
+		Description: "build a cel.Env with two variables from scratch, as createEnv does",
+		Setup:       envWorkload(false),
+	},
+	{
+		Name:        "env/extend",
+		Description: "build the same cel.Env by extending a shared standard environment",
+		Setup:       envWorkload(true),
+	},
+	{
+		Name:        "eval/small-vars",
+		Description: "evaluate an expression with three variables",
+		Setup: evalWorkload(
+			"x * 2.0 + y > z",
+			[]celengine.VarDecl{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}, {Name: "z", Type: "double"}},
+			nil,
+			func() map[string]interface{} {
+				return map[string]interface{}{"x": 1.0, "y": 2.0, "z": 3.0}
+			},
+		),
+	},
+	{
+		Name:        "eval/large-vars",
+		Description: "evaluate an expression over a 1000-entry map and a 1000-element list",
+		Setup: evalWorkload(
+			`items.exists(i, i == "item-999") && lookup["key-500"] == 500.0`,
+			[]celengine.VarDecl{
+				{Name: "items", Type: map[string]interface{}{"kind": "list", "elementType": "string"}},
+				{Name: "lookup", Type: map[string]interface{}{"kind": "map", "keyType": "string", "valueType": "double"}},
+			},
+			nil,
+			largeVars,
+		),
+	},
+	{
+		Name:        "eval/callbacks",
+		Description: "evaluate an expression that calls a custom function 100 times",
+		Setup: evalWorkload(
+			"values.map(v, inc(v)).size() == 100",
+			[]celengine.VarDecl{{Name: "values", Type: map[string]interface{}{"kind": "list", "elementType": "double"}}},
+			[]celengine.FunctionDef{{
+				Name:       "inc",
+				Params:     []celengine.ParamDef{{Name: "v", Type: "double"}},
+				ReturnType: "double",
+				ImplID:     "bench_inc",
+			}},
+			func() map[string]interface{} {
+				values := make([]interface{}, 100)
+				for i := range values {
+					values[i] = float64(i)
+				}
+				return map[string]interface{}{"values": values}
+			},
+		),
+	},
+}
+
+// largeVars builds the variables of the eval/large-vars workload
+func largeVars() map[string]interface{} {
+	items := make([]interface{}, 1000)
+	lookup := make(map[string]interface{}, 1000)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%d", i)
+		lookup[fmt.Sprintf("key-%d", i)] = float64(i)
+	}
+	return map[string]interface{}{"items": items, "lookup": lookup}
+}
+
+// compileWorkload measures compiling an expression in a shared environment
+func compileWorkload(expr string, varDecls []celengine.VarDecl) func() (func() error, func(), error) {
+	return func() (func() error, func(), error) {
+		envID, err := createEnv(varDecls, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		op := func() error {
+			result := celengine.Compile(envID, expr)
+			if err := resultError(result); err != nil {
+				return err
+			}
+			celengine.DestroyProgram(result["programID"].(string))
+			return nil
+		}
+		cleanup := func() { celengine.DestroyEnv(envID) }
+		return op, cleanup, nil
+	}
+}
+
+// envWorkload measures building an environment directly with cel.NewEnv, or by
+// extending a base environment built once, to compare the two ways createEnv could work
+func envWorkload(extend bool) func() (func() error, func(), error) {
+	return func() (func() error, func(), error) {
+		opts := []cel.EnvOption{cel.Variable("x", cel.DoubleType), cel.Variable("y", cel.StringType)}
+		newEnv := cel.NewEnv
+		if extend {
+			base, err := cel.NewEnv()
+			if err != nil {
+				return nil, nil, err
+			}
+			newEnv = base.Extend
+		}
+
+		op := func() error {
+			_, err := newEnv(opts...)
+			return err
+		}
+		return op, func() {}, nil
+	}
+}
+
+// evalWorkload measures evaluating a compiled program
+// Function definitions are backed by a fake caller that increments its first argument
+func evalWorkload(expr string, varDecls []celengine.VarDecl, funcDefs []celengine.FunctionDef, vars func() map[string]interface{}) func() (func() error, func(), error) {
+	return func() (func() error, func(), error) {
+		caller := NewFakeCaller()
+		for _, funcDef := range funcDefs {
+			caller.Funcs[funcDef.ImplID] = func(args []interface{}) (interface{}, error) {
+				return args[0].(float64) + 1, nil
+			}
+		}
+		caller.Install()
+
+		envID, err := createEnv(varDecls, funcDefs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		compiled := celengine.Compile(envID, expr)
+		if err := resultError(compiled); err != nil {
+			celengine.DestroyEnv(envID)
+			return nil, nil, err
+		}
+		programID := compiled["programID"].(string)
+		input := vars()
+
+		op := func() error {
+			result := celengine.Eval(programID, input)
+			if err := resultError(result); err != nil {
+				return err
+			}
+			if result["result"] != true {
+				return fmt.Errorf("unexpected result: %v", result["result"])
+			}
+			return nil
+		}
+		cleanup := func() {
+			celengine.DestroyProgram(programID)
+			celengine.DestroyEnv(envID)
+		}
+		return op, cleanup, nil
+	}
+}
+
+// createEnv creates an environment and returns its ID
+func createEnv(varDecls []celengine.VarDecl, funcDefs []celengine.FunctionDef) (string, error) {
+	result := celengine.CreateEnv(varDecls, funcDefs)
+	if err := resultError(result); err != nil {
+		return "", err
+	}
+	return result["envID"].(string), nil
+}
+
+// resultError returns the error reported in a result map, if any
+func resultError(result map[string]interface{}) error {
+	if result["error"] == nil {
+		return nil
+	}
+	return fmt.Errorf("%v", result["error"])
+}
+
+// Match reports whether a workload name matches a filter of comma-separated prefixes
+// An empty filter matches every workload
+func Match(name string, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	for _, prefix := range strings.Split(filter, ",") {
+		if strings.HasPrefix(name, strings.TrimSpace(prefix)) {
+			return true
+		}
+	}
+	return false
+}
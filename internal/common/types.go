@@ -5,6 +5,7 @@ type ValidatorIssue struct {
 	Severity string                 `json:"severity"`
 	Message  string                 `json:"message"`
 	Location map[string]interface{} `json:"location,omitempty"`
+	Rule     string                 `json:"rule,omitempty"` // Lint rule that reported the issue
 }
 
 // CompilationIssueAdder defines the interface for adding validator issues during compilation
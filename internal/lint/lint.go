@@ -0,0 +1,407 @@
+// Package lint finds suspicious patterns in checked CEL expressions, such as conditions
+// that don't depend on any variable, which compile fine but are almost always mistakes
+package lint
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/parser"
+)
+
+// Names of the built-in rules
+const (
+	ConstantCondition         = "constant-condition"
+	TautologicalComparison    = "tautological-comparison"
+	DuplicateClause           = "duplicate-clause"
+	TimestampStringComparison = "timestamp-string-comparison"
+	UnusedBinding             = "unused-binding"
+)
+
+// Rule is a built-in lint rule
+type Rule struct {
+	Name        string
+	Description string
+	check       func(l *linter, id int64, e ast.Expr)
+}
+
+// Rules are the built-in rules, in the order their findings are reported for a node
+var Rules = []Rule{
+	{
+		Name:        ConstantCondition,
+		Description: "Conditions of &&, ||, !, ternaries and macro predicates that don't depend on any variable",
+		check:       checkConstantCondition,
+	},
+	{
+		Name:        TautologicalComparison,
+		Description: "Comparisons that are always true or always false, such as x == x or size(l) >= 0",
+		check:       checkTautologicalComparison,
+	},
+	{
+		Name:        DuplicateClause,
+		Description: "Clauses repeated in a chain of && or ||",
+		check:       checkDuplicateClause,
+	},
+	{
+		Name:        TimestampStringComparison,
+		Description: "Comparisons of timestamps to strings, which compare text rather than instants",
+		check:       checkTimestampStringComparison,
+	},
+	{
+		Name:        UnusedBinding,
+		Description: "Variables bound with cel.bind that its expression never reads",
+		check:       checkUnusedBinding,
+	},
+}
+
+// RuleNames returns the names of the built-in rules in sorted order
+func RuleNames() []string {
+	names := make([]string, len(Rules))
+	for i, rule := range Rules {
+		names[i] = rule.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsRule reports whether name is the name of a built-in rule
+func IsRule(name string) bool {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Finding is a node of an expression that a rule reports
+type Finding struct {
+	Rule    string
+	NodeID  int64
+	Message string
+}
+
+// linter walks an expression as it was written, with macros as the calls they expand from
+type linter struct {
+	ast      *ast.AST
+	exprs    map[int64]ast.Expr
+	rule     string
+	reported map[Finding]bool
+	findings []Finding
+}
+
+// Check runs the enabled rules over a checked expression
+func Check(a *ast.AST, enabled func(rule string) bool) []Finding {
+	l := &linter{
+		ast:      a,
+		exprs:    make(map[int64]ast.Expr),
+		reported: make(map[Finding]bool),
+	}
+	ast.PostOrderVisit(a.Expr(), ast.NewExprVisitor(func(e ast.Expr) {
+		l.exprs[e.ID()] = e
+	}))
+
+	var walk func(e ast.Expr)
+	walk = func(e ast.Expr) {
+		written := l.written(e)
+		for _, rule := range Rules {
+			if enabled(rule.Name) {
+				l.rule = rule.Name
+				rule.check(l, e.ID(), written)
+			}
+		}
+		for _, sub := range subexpressions(written) {
+			walk(l.resolve(sub))
+		}
+	}
+	walk(a.Expr())
+
+	return l.findings
+}
+
+// report adds a finding of the current rule, once per node
+func (l *linter) report(id int64, message string) {
+	key := Finding{Rule: l.rule, NodeID: id}
+	if l.reported[key] {
+		return
+	}
+	l.reported[key] = true
+	l.findings = append(l.findings, Finding{Rule: l.rule, NodeID: id, Message: message})
+}
+
+// written returns a node as it was written: the call of a macro, or the node itself
+func (l *linter) written(e ast.Expr) ast.Expr {
+	if call, ok := l.ast.SourceInfo().GetMacroCall(e.ID()); ok {
+		return call
+	}
+	return e
+}
+
+// resolve returns the checked node with the ID of a macro call argument, since the
+// arguments recorded with a macro call are the ones parsed, or stand-ins for macros
+func (l *linter) resolve(e ast.Expr) ast.Expr {
+	if checked, ok := l.exprs[e.ID()]; ok {
+		return checked
+	}
+	return e
+}
+
+// text returns the source of a node, unparsed from the AST
+func (l *linter) text(e ast.Expr) string {
+	text, err := parser.Unparse(l.resolve(e), l.ast.SourceInfo())
+	if err != nil {
+		return "expression"
+	}
+	return text
+}
+
+// typeOf returns the checked type of a node, or dyn if it has none
+func (l *linter) typeOf(e ast.Expr) *types.Type {
+	if t, ok := l.ast.TypeMap()[e.ID()]; ok {
+		return t
+	}
+	return types.DynType
+}
+
+// same reports whether two nodes are written the same
+func (l *linter) same(a, b ast.Expr) bool {
+	return l.text(a) == l.text(b)
+}
+
+// subexpressions returns the direct subexpressions of a node
+func subexpressions(e ast.Expr) []ast.Expr {
+	switch e.Kind() {
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.IsMemberFunction() {
+			return append([]ast.Expr{call.Target()}, call.Args()...)
+		}
+		return call.Args()
+	case ast.ComprehensionKind:
+		comp := e.AsComprehension()
+		return []ast.Expr{comp.IterRange(), comp.AccuInit(), comp.LoopCondition(), comp.LoopStep(), comp.Result()}
+	case ast.SelectKind:
+		return []ast.Expr{e.AsSelect().Operand()}
+	case ast.ListKind:
+		return e.AsList().Elements()
+	case ast.MapKind:
+		var subs []ast.Expr
+		for _, entry := range e.AsMap().Entries() {
+			subs = append(subs, entry.AsMapEntry().Key(), entry.AsMapEntry().Value())
+		}
+		return subs
+	case ast.StructKind:
+		var subs []ast.Expr
+		for _, field := range e.AsStruct().Fields() {
+			subs = append(subs, field.AsStructField().Value())
+		}
+		return subs
+	}
+	return nil
+}
+
+// functionName returns the function of a call, or "" for other nodes
+func functionName(e ast.Expr) string {
+	if e.Kind() != ast.CallKind {
+		return ""
+	}
+	return e.AsCall().FunctionName()
+}
+
+// isConstant reports whether a node is built only from literals and operators, so its
+// value doesn't depend on any variable
+// Other functions, even without arguments, may not return the same value every time
+func (l *linter) isConstant(e ast.Expr) bool {
+	e = l.resolve(e)
+	switch e.Kind() {
+	case ast.LiteralKind:
+		return true
+	case ast.ListKind, ast.MapKind:
+	case ast.CallKind:
+		if _, ok := operators.FindReverse(e.AsCall().FunctionName()); !ok || e.AsCall().IsMemberFunction() {
+			return false
+		}
+	default:
+		return false
+	}
+	for _, sub := range subexpressions(e) {
+		if !l.isConstant(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkConstantCondition reports conditions that don't depend on any variable
+func checkConstantCondition(l *linter, id int64, e ast.Expr) {
+	var conditions []ast.Expr
+	switch name := functionName(e); name {
+	case operators.LogicalAnd, operators.LogicalOr:
+		conditions = e.AsCall().Args()
+	case operators.LogicalNot, operators.Conditional:
+		conditions = e.AsCall().Args()[:1]
+	case operators.All, operators.Exists, operators.ExistsOne, operators.Filter:
+		if args := e.AsCall().Args(); e.AsCall().IsMemberFunction() && len(args) >= 2 {
+			conditions = args[len(args)-1:]
+		}
+	}
+	for _, condition := range conditions {
+		if l.isConstant(condition) {
+			l.report(condition.ID(), "condition "+l.text(condition)+" doesn't depend on any variable")
+		}
+	}
+}
+
+// checkTautologicalComparison reports comparisons whose result doesn't depend on their
+// operands: of a value with itself, or of a size or uint with zero
+func checkTautologicalComparison(l *linter, id int64, e ast.Expr) {
+	name := functionName(e)
+	var always bool
+	switch name {
+	case operators.Equals, operators.LessEquals, operators.GreaterEquals:
+		always = true
+	case operators.NotEquals, operators.Less, operators.Greater:
+		always = false
+	default:
+		return
+	}
+	lhs, rhs := l.resolve(e.AsCall().Args()[0]), l.resolve(e.AsCall().Args()[1])
+
+	// NaN isn't equal to itself, so comparing a double with itself can be a NaN check
+	if l.same(lhs, rhs) && !l.typeOf(lhs).IsExactType(types.DoubleType) {
+		l.report(id, "comparison "+l.text(e)+" is always "+boolText(always))
+		return
+	}
+
+	// A size or uint compared with zero can only be at least zero
+	atLeastZero := func(operand ast.Expr) bool {
+		return functionName(operand) == "size" || l.typeOf(operand).IsExactType(types.UintType)
+	}
+	isZero := func(operand ast.Expr) bool {
+		if operand.Kind() != ast.LiteralKind {
+			return false
+		}
+		switch value := operand.AsLiteral().Value().(type) {
+		case int64:
+			return value == 0
+		case uint64:
+			return value == 0
+		}
+		return false
+	}
+	switch {
+	case atLeastZero(lhs) && isZero(rhs) && (name == operators.GreaterEquals || name == operators.Less):
+		l.report(id, "comparison "+l.text(e)+" is always "+boolText(name == operators.GreaterEquals))
+	case isZero(lhs) && atLeastZero(rhs) && (name == operators.LessEquals || name == operators.Greater):
+		l.report(id, "comparison "+l.text(e)+" is always "+boolText(name == operators.LessEquals))
+	}
+}
+
+// checkDuplicateClause reports clauses repeated in a chain of && or ||
+// Chains are reported from their outermost call, and nested calls of the same operator
+// don't report the same clauses again
+func checkDuplicateClause(l *linter, id int64, e ast.Expr) {
+	name := functionName(e)
+	if name != operators.LogicalAnd && name != operators.LogicalOr {
+		return
+	}
+
+	var clauses []ast.Expr
+	var flatten func(e ast.Expr)
+	flatten = func(e ast.Expr) {
+		e = l.resolve(e)
+		if functionName(e) == name {
+			for _, arg := range e.AsCall().Args() {
+				flatten(arg)
+			}
+			return
+		}
+		clauses = append(clauses, e)
+	}
+	flatten(e)
+
+	seen := make(map[string]bool, len(clauses))
+	for _, clause := range clauses {
+		text := l.text(clause)
+		if seen[text] {
+			l.report(clause.ID(), "clause "+text+" is repeated")
+		}
+		seen[text] = true
+	}
+}
+
+// checkTimestampStringComparison reports comparisons of timestamps to strings: of a
+// string to a timestamp, to a timestamp converted to a string, or of a string literal
+// that reads as a timestamp to a value that isn't a string
+func checkTimestampStringComparison(l *linter, id int64, e ast.Expr) {
+	switch functionName(e) {
+	case operators.Equals, operators.NotEquals, operators.Less, operators.LessEquals, operators.Greater, operators.GreaterEquals:
+	default:
+		return
+	}
+	lhs, rhs := l.resolve(e.AsCall().Args()[0]), l.resolve(e.AsCall().Args()[1])
+
+	isTimestamp := func(operand ast.Expr) bool {
+		if l.typeOf(operand).IsExactType(types.TimestampType) {
+			return true
+		}
+		// string(t), which formats the timestamp
+		return functionName(operand) == "string" && len(operand.AsCall().Args()) == 1 &&
+			l.typeOf(l.resolve(operand.AsCall().Args()[0])).IsExactType(types.TimestampType)
+	}
+	isString := func(operand ast.Expr) bool {
+		return l.typeOf(operand).IsExactType(types.StringType)
+	}
+	isTimestampLiteral := func(operand ast.Expr) bool {
+		if operand.Kind() != ast.LiteralKind {
+			return false
+		}
+		text, ok := operand.AsLiteral().Value().(string)
+		if !ok {
+			return false
+		}
+		_, err := time.Parse(time.RFC3339, text)
+		return err == nil
+	}
+
+	switch {
+	case isTimestamp(lhs) && isString(rhs), isString(lhs) && isTimestamp(rhs):
+		l.report(id, "comparison "+l.text(e)+" compares a timestamp to a string; convert the string with timestamp()")
+	case isTimestampLiteral(rhs) && !isString(lhs), isTimestampLiteral(lhs) && !isString(rhs):
+		l.report(id, "comparison "+l.text(e)+" compares a value to a string that reads as a timestamp; convert it with timestamp()")
+	}
+}
+
+// checkUnusedBinding reports variables bound with cel.bind that its expression never
+// reads
+func checkUnusedBinding(l *linter, id int64, e ast.Expr) {
+	if functionName(e) != "bind" || !e.AsCall().IsMemberFunction() {
+		return
+	}
+	target, args := e.AsCall().Target(), e.AsCall().Args()
+	if target.Kind() != ast.IdentKind || target.AsIdent() != "cel" || len(args) != 3 || args[0].Kind() != ast.IdentKind {
+		return
+	}
+
+	name := args[0].AsIdent()
+	used := false
+	ast.PostOrderVisit(l.resolve(args[2]), ast.NewExprVisitor(func(e ast.Expr) {
+		if e.Kind() == ast.IdentKind && e.AsIdent() == name {
+			used = true
+		}
+	}))
+	if !used {
+		l.report(id, "variable "+name+" of cel.bind is never used")
+	}
+}
+
+// boolText returns "true" or "false"
+func boolText(value bool) string {
+	if value {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,90 @@
+// Package logging routes internal warnings of the engine to a sink set by the host.
+// Without a sink, log entries are dropped.
+package logging
+
+import "fmt"
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels in increasing severity
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// String returns the name of the level
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if levelName == name {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level: %s (expected one of debug, info, warn, error)", name)
+}
+
+// Sink receives log entries at or above the configured level
+// Fields hold additional context and only contain JSON-compatible values
+type Sink func(level Level, message string, fields map[string]interface{})
+
+var (
+	sink     Sink
+	minLevel = LevelWarn
+	// logging is set while the sink runs, so entries logged by the sink itself are dropped
+	logging bool
+)
+
+// SetSink sets the sink and the minimum level of the entries passed to it
+// A nil sink disables logging
+func SetSink(s Sink, level Level) {
+	sink = s
+	minLevel = level
+}
+
+// Enabled reports whether entries of the given level reach the sink
+func Enabled(level Level) bool {
+	return sink != nil && level >= minLevel
+}
+
+// Log passes an entry to the sink if its level is enabled
+func Log(level Level, message string, fields map[string]interface{}) {
+	if !Enabled(level) || logging {
+		return
+	}
+
+	logging = true
+	defer func() { logging = false }()
+	sink(level, message, fields)
+}
+
+// Debug logs an entry at debug level
+func Debug(message string, fields map[string]interface{}) {
+	Log(LevelDebug, message, fields)
+}
+
+// Info logs an entry at info level
+func Info(message string, fields map[string]interface{}) {
+	Log(LevelInfo, message, fields)
+}
+
+// Warn logs an entry at warn level
+func Warn(message string, fields map[string]interface{}) {
+	Log(LevelWarn, message, fields)
+}
+
+// Error logs an entry at error level
+func Error(message string, fields map[string]interface{}) {
+	Log(LevelError, message, fields)
+}
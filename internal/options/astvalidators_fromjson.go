@@ -408,7 +408,7 @@ func (v *JSASTValidator) processIssues(ctx *JSValidationContext, issues *cel.Iss
 		// Note: CEL Issues doesn't have a direct way to add warnings, so we treat everything as errors
 		// Only report to CEL if it's an error OR if failOnWarning is true (treating warnings as errors)
 		if strings.ToLower(issue.Severity) == "error" || v.failOnWarning {
-			issues.ReportErrorAtID(nodeID, message)
+			issues.ReportErrorAtID(nodeID, "%s", message)
 		}
 	}
 }
@@ -463,3 +463,24 @@ func (b *ASTValidatorsBuilder) FromJSON(params map[string]interface{}) error {
 
 	return nil
 }
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ASTValidatorsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"validatorFunctionIds": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string"},
+			"description": "Implementation IDs of the registered JavaScript validator functions",
+		},
+		"failOnWarning": map[string]interface{}{
+			"type":        "boolean",
+			"default":     true,
+			"description": "Whether validator warnings fail compilation",
+		},
+		"includeWarnings": map[string]interface{}{
+			"type":        "boolean",
+			"default":     true,
+			"description": "Whether validator warnings are reported",
+		},
+	}, "validatorFunctionIds")
+}
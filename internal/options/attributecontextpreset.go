@@ -0,0 +1,305 @@
+//go:build !wasmcel_noproto
+
+package options
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/genproto/googleapis/rpc/context/attribute_context"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// The well-known types referenced by the Envoy attributes must be registered
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Dialects of the attribute context preset
+const (
+	AttributeDialectEnvoy  = "envoy"  // The attributes of Envoy's RBAC and ext_authz filters
+	AttributeDialectGoogle = "google" // The fields of google.rpc.context.AttributeContext
+)
+
+// AttributeContextPresetBuilder declares the standard attributes of a request as variables,
+// so RBAC and ext_authz expressions can be type-checked. The attributes are the fields of a
+// context message, which programs can be evaluated against with EvalWithContextMessage.
+type AttributeContextPresetBuilder struct {
+	Dialect string
+}
+
+// Name returns the name of this option
+func (b *AttributeContextPresetBuilder) Name() string {
+	return "AttributeContextPreset"
+}
+
+// Description returns the description of this option
+func (b *AttributeContextPresetBuilder) Description() string {
+	return "AttributeContextPreset declares the standard attributes of a request as typed variables.\n\nThe \"envoy\" dialect declares the attributes of Envoy's RBAC and ext_authz filters (request, response,\nsource, destination, connection, upstream, metadata, filter_state and xds) as the fields of the\nwasmcel.envoy.Attributes message. The \"google\" dialect declares the fields of\ngoogle.rpc.context.AttributeContext (origin, source, destination, request, response, resource, api\nand extensions). Programs can be evaluated against a message of either type with a context message."
+}
+
+// SetDialect sets the dialect parameter
+func (b *AttributeContextPresetBuilder) SetDialect(dialect string) *AttributeContextPresetBuilder {
+	b.Dialect = dialect
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *AttributeContextPresetBuilder) Build() (cel.EnvOption, error) {
+	switch b.Dialect {
+	case AttributeDialectEnvoy:
+		descriptor, err := envoyAttributesDescriptor()
+		if err != nil {
+			return nil, err
+		}
+		return cel.DeclareContextProto(descriptor), nil
+	case AttributeDialectGoogle:
+		return cel.DeclareContextProto((&attribute_context.AttributeContext{}).ProtoReflect().Descriptor()), nil
+	default:
+		return nil, fmt.Errorf("invalid dialect %q: expected %q or %q", b.Dialect, AttributeDialectEnvoy, AttributeDialectGoogle)
+	}
+}
+
+// FromJSON configures the AttributeContextPresetBuilder from JSON parameters
+func (b *AttributeContextPresetBuilder) FromJSON(params map[string]interface{}) error {
+	b.SetDialect(AttributeDialectEnvoy)
+
+	if value, exists := params["dialect"]; exists {
+		dialect, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("dialect must be a string")
+		}
+		b.SetDialect(dialect)
+	}
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *AttributeContextPresetBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"dialect": map[string]interface{}{
+			"type":        "string",
+			"enum":        []interface{}{AttributeDialectEnvoy, AttributeDialectGoogle},
+			"default":     AttributeDialectEnvoy,
+			"description": "Which attributes to declare: Envoy's, or those of google.rpc.context.AttributeContext",
+		},
+	})
+}
+
+func init() {
+	DefaultRegistry.Register("AttributeContextPreset", func() OptionBuilder {
+		return &AttributeContextPresetBuilder{Dialect: AttributeDialectEnvoy}
+	})
+}
+
+var (
+	envoyAttributesOnce sync.Once
+	envoyAttributes     protoreflect.MessageDescriptor
+	envoyAttributesErr  error
+)
+
+// envoyAttributesDescriptor builds the message declaring the Envoy attributes
+// Envoy doesn't define its attributes as a message, so one is built from the attribute
+// reference: https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/advanced/attributes
+func envoyAttributesDescriptor() (protoreflect.MessageDescriptor, error) {
+	envoyAttributesOnce.Do(func() {
+		file := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("wasmcel/envoy/attributes.proto"),
+			Package: proto.String("wasmcel.envoy"),
+			Syntax:  proto.String("proto3"),
+			Dependency: []string{
+				"google/protobuf/duration.proto",
+				"google/protobuf/struct.proto",
+				"google/protobuf/timestamp.proto",
+			},
+			MessageType: []*descriptorpb.DescriptorProto{
+				attributeMessage("Attributes",
+					messageField("request", ".wasmcel.envoy.Request"),
+					messageField("response", ".wasmcel.envoy.Response"),
+					messageField("source", ".wasmcel.envoy.Peer"),
+					messageField("destination", ".wasmcel.envoy.Peer"),
+					messageField("connection", ".wasmcel.envoy.Connection"),
+					messageField("upstream", ".wasmcel.envoy.Upstream"),
+					messageField("metadata", ".wasmcel.envoy.Metadata"),
+					mapField("filter_state", scalarField("value", descriptorpb.FieldDescriptorProto_TYPE_BYTES)),
+					messageField("xds", ".wasmcel.envoy.XDS"),
+				),
+				attributeMessage("Request",
+					stringField("path"),
+					stringField("url_path"),
+					stringField("host"),
+					stringField("scheme"),
+					stringField("method"),
+					mapField("headers", stringField("value")),
+					stringField("referer"),
+					stringField("useragent"),
+					messageField("time", ".google.protobuf.Timestamp"),
+					stringField("id"),
+					stringField("protocol"),
+					stringField("query"),
+					messageField("duration", ".google.protobuf.Duration"),
+					scalarField("size", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					scalarField("total_size", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+				),
+				attributeMessage("Response",
+					scalarField("code", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					stringField("code_details"),
+					scalarField("flags", descriptorpb.FieldDescriptorProto_TYPE_UINT64),
+					scalarField("grpc_status", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					mapField("headers", stringField("value")),
+					mapField("trailers", stringField("value")),
+					scalarField("size", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					scalarField("total_size", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					messageField("backend_latency", ".google.protobuf.Duration"),
+				),
+				attributeMessage("Peer",
+					stringField("address"),
+					scalarField("port", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+				),
+				attributeMessage("Connection",
+					scalarField("id", descriptorpb.FieldDescriptorProto_TYPE_UINT64),
+					scalarField("mtls", descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+					stringField("requested_server_name"),
+					stringField("tls_version"),
+					stringField("subject_local_certificate"),
+					stringField("subject_peer_certificate"),
+					stringField("dns_san_local_certificate"),
+					stringField("dns_san_peer_certificate"),
+					stringField("uri_san_local_certificate"),
+					stringField("uri_san_peer_certificate"),
+					stringField("sha256_peer_certificate_digest"),
+					stringField("transport_failure_reason"),
+					stringField("termination_details"),
+				),
+				attributeMessage("Upstream",
+					stringField("address"),
+					scalarField("port", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					stringField("tls_version"),
+					stringField("subject_local_certificate"),
+					stringField("subject_peer_certificate"),
+					stringField("dns_san_local_certificate"),
+					stringField("dns_san_peer_certificate"),
+					stringField("uri_san_local_certificate"),
+					stringField("uri_san_peer_certificate"),
+					stringField("sha256_peer_certificate_digest"),
+					stringField("local_address"),
+					stringField("transport_failure_reason"),
+					scalarField("request_attempt_count", descriptorpb.FieldDescriptorProto_TYPE_UINT64),
+				),
+				attributeMessage("Metadata",
+					mapField("filter_metadata", messageField("value", ".google.protobuf.Struct")),
+				),
+				attributeMessage("XDS",
+					stringField("cluster_name"),
+					messageField("cluster_metadata", ".wasmcel.envoy.Metadata"),
+					stringField("route_name"),
+					messageField("route_metadata", ".wasmcel.envoy.Metadata"),
+					messageField("upstream_host_metadata", ".wasmcel.envoy.Metadata"),
+					stringField("filter_chain_name"),
+					stringField("virtual_host_name"),
+					scalarField("listener_direction", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+				),
+			},
+		}
+
+		fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+		if err != nil {
+			envoyAttributesErr = fmt.Errorf("failed to build the Envoy attributes: %w", err)
+			return
+		}
+		envoyAttributes = fd.Messages().ByName("Attributes")
+	})
+	return envoyAttributes, envoyAttributesErr
+}
+
+// attributeField describes a field of an attribute message
+type attributeField struct {
+	name      string
+	fieldType descriptorpb.FieldDescriptorProto_Type
+	typeName  string          // Fully qualified name of a message type
+	mapValue  *attributeField // Value of a map field with string keys
+}
+
+// attributeMessage builds a message of the given fields, numbered in order
+func attributeMessage(name string, fields ...attributeField) *descriptorpb.DescriptorProto {
+	message := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	for i, field := range fields {
+		descriptor := field.descriptor(int32(i + 1))
+		if field.mapValue != nil {
+			// Map fields are repeated fields of a nested entry message, named as protoc names it
+			entry := &descriptorpb.DescriptorProto{
+				Name: proto.String(entryName(field.name)),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					stringField("key").descriptor(1),
+					field.mapValue.descriptor(2),
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}
+			message.NestedType = append(message.NestedType, entry)
+
+			descriptor.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			descriptor.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			descriptor.TypeName = proto.String(".wasmcel.envoy." + name + "." + entry.GetName())
+		}
+		message.Field = append(message.Field, descriptor)
+	}
+	return message
+}
+
+// descriptor builds the descriptor of a field with the given number
+func (f attributeField) descriptor(number int32) *descriptorpb.FieldDescriptorProto {
+	descriptor := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(f.name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   f.fieldType.Enum(),
+	}
+	if f.typeName != "" {
+		descriptor.TypeName = proto.String(f.typeName)
+	}
+	return descriptor
+}
+
+// entryName returns the name of the entry message of a map field
+func entryName(fieldName string) string {
+	name := make([]byte, 0, len(fieldName)+5)
+	upper := true
+	for i := 0; i < len(fieldName); i++ {
+		c := fieldName[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		name = append(name, c)
+	}
+	return string(name) + "Entry"
+}
+
+// mapField describes a map field with string keys and the given value
+func mapField(name string, value attributeField) attributeField {
+	return attributeField{name: name, mapValue: &value}
+}
+
+// messageField describes a singular field of a message type
+func messageField(name string, typeName string) attributeField {
+	return attributeField{name: name, fieldType: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, typeName: typeName}
+}
+
+// stringField describes a singular string field
+func stringField(name string) attributeField {
+	return scalarField(name, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+}
+
+// scalarField describes a singular field of the given type
+func scalarField(name string, fieldType descriptorpb.FieldDescriptorProto_Type) attributeField {
+	return attributeField{name: name, fieldType: fieldType}
+}
@@ -0,0 +1,41 @@
+package options
+
+import "fmt"
+
+// FromJSON configures the CostTrackingBuilder from JSON parameters
+func (b *CostTrackingBuilder) FromJSON(params map[string]interface{}) error {
+	// Custom cost estimators can't be expressed in JSON, so the default
+	// per-call costs are used
+	b.SetCostEstimator(nil)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *CostTrackingBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+// FromJSON configures the CostLimitBuilder from JSON parameters
+func (b *CostLimitBuilder) FromJSON(params map[string]interface{}) error {
+	limit, ok := params["costLimit"].(float64)
+	if !ok {
+		return fmt.Errorf("costLimit must be a number")
+	}
+	if limit < 0 {
+		return fmt.Errorf("costLimit must not be negative")
+	}
+
+	b.SetCostLimit(uint64(limit))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *CostLimitBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"costLimit": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"description": "Maximum runtime cost before evaluation is aborted",
+		},
+	}, "costLimit")
+}
@@ -15,3 +15,8 @@ func (b *CrossTypeNumericComparisonsBuilder) FromJSON(params map[string]interfac
 	b.SetEnabled(enabled)
 	return nil
 }
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *CrossTypeNumericComparisonsBuilder) ParamsSchema() map[string]interface{} {
+	return enabledParamsSchema("Whether to enable cross-type numeric ordering comparisons")
+}
@@ -0,0 +1,54 @@
+//go:build !wasmcel_noproto
+
+package options
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FromJSON configures the DeclareContextProtoBuilder from JSON parameters
+// The message named by typeName is looked up in descriptorSet, a base64-encoded
+// google.protobuf.FileDescriptorSet such as the output of protoc --include_imports
+func (b *DeclareContextProtoBuilder) FromJSON(params map[string]interface{}) error {
+	typeName, ok := params["typeName"].(string)
+	if !ok || typeName == "" {
+		return fmt.Errorf("typeName must be a non-empty string")
+	}
+	encoded, ok := params["descriptorSet"].(string)
+	if !ok {
+		return fmt.Errorf("descriptorSet must be a base64-encoded string")
+	}
+
+	resolver, err := resolverFromDescriptorSet(encoded)
+	if err != nil {
+		return err
+	}
+	descriptor, err := resolver.FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return fmt.Errorf("message %s not found in descriptorSet", typeName)
+	}
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return fmt.Errorf("%s is not a message", typeName)
+	}
+
+	b.SetDescriptor(messageDescriptor)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *DeclareContextProtoBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"descriptorSet": map[string]interface{}{
+			"type":            "string",
+			"contentEncoding": "base64",
+			"description":     "Serialized google.protobuf.FileDescriptorSet containing the message and its dependencies",
+		},
+		"typeName": map[string]interface{}{
+			"type":        "string",
+			"description": "Fully qualified name of the context message",
+		},
+	}, "descriptorSet", "typeName")
+}
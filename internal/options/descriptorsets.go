@@ -0,0 +1,80 @@
+package options
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DescriptorSetFiles returns the files of a base64-encoded FileDescriptorSet, without
+// the dependencies that are compiled into the module such as the well-known types
+func DescriptorSetFiles(encoded string) ([]protoreflect.FileDescriptor, error) {
+	resolver, err := resolverFromDescriptorSet(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var files []protoreflect.FileDescriptor
+	resolver.files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		files = append(files, file)
+		return true
+	})
+	return files, nil
+}
+
+// resolverFromDescriptorSet builds the files of a base64-encoded FileDescriptorSet
+// Dependencies missing from the set, such as the well-known types, are resolved from
+// the descriptors compiled into the module
+func resolverFromDescriptorSet(encoded string) (fallbackResolver, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fallbackResolver{}, fmt.Errorf("descriptorSet is not valid base64: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return fallbackResolver{}, fmt.Errorf("failed to parse descriptorSet: %w", err)
+	}
+
+	// Files are listed with their dependencies first, as protoc writes them
+	files := new(protoregistry.Files)
+	resolver := fallbackResolver{files}
+	for _, fileProto := range set.GetFile() {
+		if _, err := files.FindFileByPath(fileProto.GetName()); err == nil {
+			continue
+		}
+		if _, err := protoregistry.GlobalFiles.FindFileByPath(fileProto.GetName()); err == nil {
+			continue
+		}
+		file, err := protodesc.NewFile(fileProto, resolver)
+		if err != nil {
+			return fallbackResolver{}, fmt.Errorf("invalid file %s in descriptorSet: %w", fileProto.GetName(), err)
+		}
+		if err := files.RegisterFile(file); err != nil {
+			return fallbackResolver{}, fmt.Errorf("invalid file %s in descriptorSet: %w", fileProto.GetName(), err)
+		}
+	}
+	return resolver, nil
+}
+
+// fallbackResolver resolves descriptors from a set of files, then from the global registry
+type fallbackResolver struct {
+	files *protoregistry.Files
+}
+
+func (r fallbackResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r fallbackResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if descriptor, err := r.files.FindDescriptorByName(name); err == nil {
+		return descriptor, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
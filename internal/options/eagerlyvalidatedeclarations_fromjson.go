@@ -0,0 +1,22 @@
+package options
+
+// FromJSON configures the EagerlyValidateDeclarationsBuilder from JSON parameters
+func (b *EagerlyValidateDeclarationsBuilder) FromJSON(params map[string]interface{}) error {
+	// Default to enabled if no explicit value is provided
+	enabled := true
+
+	// Check if enabled parameter is provided
+	if enabledParam, exists := params["enabled"]; exists {
+		if enabledBool, ok := enabledParam.(bool); ok {
+			enabled = enabledBool
+		}
+	}
+
+	b.SetEnabled(enabled)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *EagerlyValidateDeclarationsBuilder) ParamsSchema() map[string]interface{} {
+	return enabledParamsSchema("Whether declaration collisions are reported when the environment is created")
+}
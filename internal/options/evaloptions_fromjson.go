@@ -0,0 +1,72 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// evalOptionsByName maps JSON names to CEL evaluation options
+var evalOptionsByName = map[string]cel.EvalOption{
+	"OptTrackState":        cel.OptTrackState,
+	"OptExhaustiveEval":    cel.OptExhaustiveEval,
+	"OptOptimize":          cel.OptOptimize,
+	"OptPartialEval":       cel.OptPartialEval,
+	"OptTrackCost":         cel.OptTrackCost,
+	"OptCheckStringFormat": cel.OptCheckStringFormat,
+}
+
+// evalOptionNames lists the supported evaluation option names in declaration order
+var evalOptionNames = []string{
+	"OptTrackState",
+	"OptExhaustiveEval",
+	"OptOptimize",
+	"OptPartialEval",
+	"OptTrackCost",
+	"OptCheckStringFormat",
+}
+
+// FromJSON configures the EvalOptionsBuilder from JSON parameters
+func (b *EvalOptionsBuilder) FromJSON(params map[string]interface{}) error {
+	optsParam, exists := params["opts"]
+	if !exists {
+		return nil
+	}
+
+	optNames, ok := optsParam.([]interface{})
+	if !ok {
+		return fmt.Errorf("opts must be an array")
+	}
+
+	var opts []cel.EvalOption
+	for _, optName := range optNames {
+		name, ok := optName.(string)
+		if !ok {
+			return fmt.Errorf("eval option must be a string")
+		}
+		opt, ok := evalOptionsByName[name]
+		if !ok {
+			return fmt.Errorf("unknown eval option: %s", name)
+		}
+		opts = append(opts, opt)
+	}
+
+	b.SetOpts(opts)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *EvalOptionsBuilder) ParamsSchema() map[string]interface{} {
+	names := make([]interface{}, len(evalOptionNames))
+	for i, name := range evalOptionNames {
+		names[i] = name
+	}
+
+	return objectSchema(map[string]interface{}{
+		"opts": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string", "enum": names},
+			"description": "Evaluation options to enable",
+		},
+	})
+}
@@ -0,0 +1,164 @@
+package options
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// hashEncodings are the encodings of digests, by name
+var hashEncodings = map[string]func([]byte) string{
+	"hex":    hex.EncodeToString,
+	"base64": base64.StdEncoding.EncodeToString,
+}
+
+// hashAlgorithms are the hash functions the Hash option declares, by name
+var hashAlgorithms = []struct {
+	name  string
+	new   func() hash.Hash
+	keyed bool // Whether HMACs are declared too
+}{
+	{"md5", md5.New, false},
+	{"sha1", sha1.New, false},
+	{"sha256", sha256.New, true},
+	{"sha512", sha512.New, true},
+}
+
+// HashBuilder declares hash and HMAC functions, implemented in Go, so rules bucketing
+// users or verifying signatures don't call back into JavaScript
+type HashBuilder struct {
+	Encoding string
+}
+
+// Name returns the name of this option
+func (b *HashBuilder) Name() string {
+	return "Hash"
+}
+
+// Description returns the description of this option
+func (b *HashBuilder) Description() string {
+	return "Hash declares hash.md5(), hash.sha1(), hash.sha256() and hash.sha512(), returning the digest of a\nstring or bytes, and hmac.sha256() and hmac.sha512(), returning the HMAC of a message with a key, both\nstrings or both bytes. Digests are strings in the encoding, hex or base64."
+}
+
+// SetEncoding sets the encoding parameter
+func (b *HashBuilder) SetEncoding(encoding string) *HashBuilder {
+	b.Encoding = encoding
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *HashBuilder) Build() (cel.EnvOption, error) {
+	encode, ok := hashEncodings[b.Encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown digest encoding %q, expected hex or base64", b.Encoding)
+	}
+	return cel.Lib(&hashLibrary{encode: encode}), nil
+}
+
+// FromJSON configures the HashBuilder from JSON parameters
+func (b *HashBuilder) FromJSON(params map[string]interface{}) error {
+	if value, exists := params["encoding"]; exists {
+		encoding, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("encoding must be a string")
+		}
+		if _, ok := hashEncodings[encoding]; !ok {
+			return fmt.Errorf("unknown digest encoding %q, expected hex or base64", encoding)
+		}
+		b.SetEncoding(encoding)
+	}
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *HashBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"encoding": map[string]interface{}{
+			"type":        "string",
+			"enum":        []interface{}{"hex", "base64"},
+			"default":     "hex",
+			"description": "Encoding of the digests the functions return",
+		},
+	})
+}
+
+func init() {
+	DefaultRegistry.Register("Hash", func() OptionBuilder {
+		return &HashBuilder{Encoding: "hex"}
+	})
+}
+
+// hashLibrary declares the hash and HMAC functions
+type hashLibrary struct {
+	encode func([]byte) string
+}
+
+// LibraryName implements cel.SingletonLibrary
+func (l *hashLibrary) LibraryName() string {
+	return "wasm-cel.hash"
+}
+
+// CompileOptions implements cel.Library
+func (l *hashLibrary) CompileOptions() []cel.EnvOption {
+	var options []cel.EnvOption
+	for _, algorithm := range hashAlgorithms {
+		newHash := algorithm.new
+		options = append(options, cel.Function("hash."+algorithm.name,
+			cel.Overload("wasmcel_hash_"+algorithm.name+"_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(data ref.Val) ref.Val {
+					return l.digest(newHash(), data)
+				}),
+			),
+			cel.Overload("wasmcel_hash_"+algorithm.name+"_bytes", []*cel.Type{cel.BytesType}, cel.StringType,
+				cel.UnaryBinding(func(data ref.Val) ref.Val {
+					return l.digest(newHash(), data)
+				}),
+			),
+		))
+		if !algorithm.keyed {
+			continue
+		}
+		options = append(options, cel.Function("hmac."+algorithm.name,
+			cel.Overload("wasmcel_hmac_"+algorithm.name+"_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.StringType,
+				cel.BinaryBinding(func(key, message ref.Val) ref.Val {
+					return l.digest(hmac.New(newHash, hashInput(key)), message)
+				}),
+			),
+			cel.Overload("wasmcel_hmac_"+algorithm.name+"_bytes_bytes", []*cel.Type{cel.BytesType, cel.BytesType}, cel.StringType,
+				cel.BinaryBinding(func(key, message ref.Val) ref.Val {
+					return l.digest(hmac.New(newHash, hashInput(key)), message)
+				}),
+			),
+		))
+	}
+	return options
+}
+
+// ProgramOptions implements cel.Library
+func (l *hashLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// digest returns the encoded digest of a string or bytes
+func (l *hashLibrary) digest(h hash.Hash, data ref.Val) ref.Val {
+	h.Write(hashInput(data))
+	return types.String(l.encode(h.Sum(nil)))
+}
+
+// hashInput returns the bytes of a string or bytes value
+func hashInput(val ref.Val) []byte {
+	if text, ok := val.(types.String); ok {
+		return []byte(text)
+	}
+	return []byte(val.(types.Bytes))
+}
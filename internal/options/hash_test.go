@@ -0,0 +1,71 @@
+package options
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+func TestHashFromJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		err    string
+	}{
+		{name: "default", params: map[string]interface{}{}},
+		{name: "hex", params: map[string]interface{}{"encoding": "hex"}},
+		{name: "base64", params: map[string]interface{}{"encoding": "base64"}},
+		{name: "unknown encoding", params: map[string]interface{}{"encoding": "base32"}, err: `unknown digest encoding "base32"`},
+		{name: "not a string", params: map[string]interface{}{"encoding": 64.0}, err: "encoding must be a string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder, _ := DefaultRegistry.Create("Hash")
+			err := builder.(FromJSON).FromJSON(test.params)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := builder.Build(); err != nil {
+				t.Errorf("failed to build: %v", err)
+			}
+		})
+	}
+
+	if _, err := (&HashBuilder{}).Build(); err == nil {
+		t.Error("expected a builder without an encoding to fail")
+	}
+}
+
+func TestHashFunctions(t *testing.T) {
+	env := optionEnv(t, "Hash", nil, cel.Variable("body", cel.StringType))
+	runOptionTests(t, env, map[string]interface{}{"body": "msg"}, []optionTest{
+		{expr: `hash.md5("abc")`, expected: "900150983cd24fb0d6963f7d28e17f72"},
+		{expr: `hash.sha1("abc")`, expected: "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{expr: `hash.sha256("abc")`, expected: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{expr: `hash.sha256(b"abc")`, expected: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{expr: `hash.sha256("")`, expected: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{expr: `hash.sha512("abc").size()`, expected: int64(128)},
+		{expr: `hmac.sha256("key", body)`, expected: "2d93cbc1be167bcb1637a4a23cbff01a7878f0c50ee833954ea5221bb1b8c628"},
+		{expr: `hmac.sha256(b"key", b"msg")`, expected: "2d93cbc1be167bcb1637a4a23cbff01a7878f0c50ee833954ea5221bb1b8c628"},
+		{expr: `hmac.sha512("key", "msg").size()`, expected: int64(128)},
+		// Keys and messages are both strings or both bytes
+		{expr: `hmac.sha256("key", b"msg")`, err: "found no matching overload"},
+		// Only the SHA-2 functions have HMACs
+		{expr: `hmac.md5("key", "msg")`, err: "undeclared reference to 'md5'"},
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		env := optionEnv(t, "Hash", map[string]interface{}{"encoding": "base64"})
+		runOptionTests(t, env, nil, []optionTest{
+			{expr: `hash.sha256("abc")`, expected: "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0="},
+		})
+	})
+}
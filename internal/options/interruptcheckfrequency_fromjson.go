@@ -0,0 +1,28 @@
+package options
+
+import "fmt"
+
+// FromJSON configures the InterruptCheckFrequencyBuilder from JSON parameters
+func (b *InterruptCheckFrequencyBuilder) FromJSON(params map[string]interface{}) error {
+	frequency, ok := params["checkFrequency"].(float64)
+	if !ok {
+		return fmt.Errorf("checkFrequency must be a number")
+	}
+	if frequency < 0 {
+		return fmt.Errorf("checkFrequency must not be negative")
+	}
+
+	b.SetCheckFrequency(uint(frequency))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *InterruptCheckFrequencyBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"checkFrequency": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"description": "Number of comprehension iterations between interrupt checks",
+		},
+	}, "checkFrequency")
+}
@@ -0,0 +1,340 @@
+package options
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// IPType is the CEL type of IP addresses, named as in the Kubernetes IP library
+var IPType = types.NewOpaqueType("net.IP")
+
+// CIDRType is the CEL type of CIDR ranges, named as in the Kubernetes CIDR library
+var CIDRType = types.NewOpaqueType("net.CIDR")
+
+// IPBuilder declares the functions of the Kubernetes IP and CIDR libraries, implemented
+// in Go, so network policies can be authored with the same functions as in a cluster
+type IPBuilder struct{}
+
+// Name returns the name of this option
+func (b *IPBuilder) Name() string {
+	return "IP"
+}
+
+// Description returns the description of this option
+func (b *IPBuilder) Description() string {
+	return "IP declares the functions of the Kubernetes IP and CIDR libraries: ip() and cidr() parse addresses and\nranges, isIP(), isCIDR() and ip.isCanonical() validate strings, addresses have family(), isUnspecified(),\nisLoopback(), isLinkLocalMulticast(), isLinkLocalUnicast(), isGlobalUnicast() and isPrivate(), and ranges\nhave containsIP(), containsCIDR(), ip(), masked() and prefixLength().\n\nLike in Kubernetes, IPv4-mapped IPv6 addresses and addresses with zones are rejected. Addresses and\nranges convert to their string form with string(), and results hold them in that form."
+}
+
+// Build creates the CEL environment option
+func (b *IPBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(ipLibrary{}), nil
+}
+
+// FromJSON configures the IPBuilder from JSON parameters
+func (b *IPBuilder) FromJSON(params map[string]interface{}) error {
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *IPBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+func init() {
+	DefaultRegistry.Register("IP", func() OptionBuilder {
+		return &IPBuilder{}
+	})
+}
+
+// ipValue is an IP address during evaluation
+type ipValue struct {
+	addr netip.Addr
+}
+
+// ConvertToNative implements ref.Val
+func (v ipValue) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	switch {
+	case reflect.TypeOf(v.addr).AssignableTo(typeDesc):
+		return v.addr, nil
+	case typeDesc.Kind() == reflect.String:
+		return v.addr.String(), nil
+	}
+	return nil, fmt.Errorf("type conversion error from '%s' to '%v'", IPType, typeDesc)
+}
+
+// ConvertToType implements ref.Val
+func (v ipValue) ConvertToType(typeVal ref.Type) ref.Val {
+	switch typeVal {
+	case types.StringType:
+		return types.String(v.addr.String())
+	case types.TypeType:
+		return IPType
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", IPType, typeVal)
+}
+
+// Equal implements ref.Val
+func (v ipValue) Equal(other ref.Val) ref.Val {
+	o, ok := other.(ipValue)
+	return types.Bool(ok && v.addr == o.addr)
+}
+
+// Type implements ref.Val
+func (v ipValue) Type() ref.Type {
+	return IPType
+}
+
+// Value implements ref.Val
+func (v ipValue) Value() any {
+	return v.addr
+}
+
+// cidrValue is a CIDR range during evaluation
+type cidrValue struct {
+	prefix netip.Prefix
+}
+
+// ConvertToNative implements ref.Val
+func (v cidrValue) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	switch {
+	case reflect.TypeOf(v.prefix).AssignableTo(typeDesc):
+		return v.prefix, nil
+	case typeDesc.Kind() == reflect.String:
+		return v.prefix.String(), nil
+	}
+	return nil, fmt.Errorf("type conversion error from '%s' to '%v'", CIDRType, typeDesc)
+}
+
+// ConvertToType implements ref.Val
+func (v cidrValue) ConvertToType(typeVal ref.Type) ref.Val {
+	switch typeVal {
+	case types.StringType:
+		return types.String(v.prefix.String())
+	case types.TypeType:
+		return CIDRType
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", CIDRType, typeVal)
+}
+
+// Equal implements ref.Val
+func (v cidrValue) Equal(other ref.Val) ref.Val {
+	o, ok := other.(cidrValue)
+	return types.Bool(ok && v.prefix == o.prefix)
+}
+
+// Type implements ref.Val
+func (v cidrValue) Type() ref.Type {
+	return CIDRType
+}
+
+// Value implements ref.Val
+func (v cidrValue) Value() any {
+	return v.prefix
+}
+
+// parseIP parses an IP address the way the Kubernetes IP library does
+func parseIP(text string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(text)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("IP address %q is invalid: %v", text, err)
+	}
+	if addr.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("IP address %q with zone value is not allowed", text)
+	}
+	if addr.Is4In6() {
+		return netip.Addr{}, fmt.Errorf("IPv4-mapped IPv6 address %q is not allowed", text)
+	}
+	return addr, nil
+}
+
+// parseCIDR parses a CIDR range the way the Kubernetes CIDR library does
+func parseCIDR(text string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(text)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("network address %q is invalid: %v", text, err)
+	}
+	if prefix.Addr().Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("IPv4-mapped IPv6 address %q is not allowed", text)
+	}
+	return prefix, nil
+}
+
+// ipLibrary declares the IP and CIDR functions
+type ipLibrary struct{}
+
+// LibraryName implements cel.SingletonLibrary
+func (ipLibrary) LibraryName() string {
+	return "wasm-cel.ip"
+}
+
+// CompileOptions implements cel.Library
+func (ipLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("ip",
+			cel.Overload("wasmcel_ip_string", []*cel.Type{cel.StringType}, IPType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					addr, err := parseIP(string(arg.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return ipValue{addr}
+				}),
+			),
+		),
+		cel.Function("isIP",
+			cel.Overload("wasmcel_is_ip_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					_, err := parseIP(string(arg.(types.String)))
+					return types.Bool(err == nil)
+				}),
+			),
+		),
+		cel.Function("ip.isCanonical",
+			cel.Overload("wasmcel_ip_is_canonical_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					text := string(arg.(types.String))
+					addr, err := parseIP(text)
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return types.Bool(addr.String() == text)
+				}),
+			),
+		),
+		ipMember("family", cel.IntType, func(addr netip.Addr) ref.Val {
+			if addr.Is4() {
+				return types.Int(4)
+			}
+			return types.Int(6)
+		}),
+		ipMember("isUnspecified", cel.BoolType, func(addr netip.Addr) ref.Val {
+			return types.Bool(addr.IsUnspecified())
+		}),
+		ipMember("isLoopback", cel.BoolType, func(addr netip.Addr) ref.Val {
+			return types.Bool(addr.IsLoopback())
+		}),
+		ipMember("isLinkLocalMulticast", cel.BoolType, func(addr netip.Addr) ref.Val {
+			return types.Bool(addr.IsLinkLocalMulticast())
+		}),
+		ipMember("isLinkLocalUnicast", cel.BoolType, func(addr netip.Addr) ref.Val {
+			return types.Bool(addr.IsLinkLocalUnicast())
+		}),
+		ipMember("isGlobalUnicast", cel.BoolType, func(addr netip.Addr) ref.Val {
+			return types.Bool(addr.IsGlobalUnicast())
+		}),
+		ipMember("isPrivate", cel.BoolType, func(addr netip.Addr) ref.Val {
+			return types.Bool(addr.IsPrivate())
+		}),
+
+		cel.Function("cidr",
+			cel.Overload("wasmcel_cidr_string", []*cel.Type{cel.StringType}, CIDRType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					prefix, err := parseCIDR(string(arg.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return cidrValue{prefix}
+				}),
+			),
+		),
+		cel.Function("isCIDR",
+			cel.Overload("wasmcel_is_cidr_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					_, err := parseCIDR(string(arg.(types.String)))
+					return types.Bool(err == nil)
+				}),
+			),
+		),
+		cel.Function("containsIP",
+			cel.MemberOverload("wasmcel_cidr_contains_ip_ip", []*cel.Type{CIDRType, IPType}, cel.BoolType,
+				cel.BinaryBinding(func(cidr, ip ref.Val) ref.Val {
+					return types.Bool(cidr.(cidrValue).prefix.Contains(ip.(ipValue).addr))
+				}),
+			),
+			cel.MemberOverload("wasmcel_cidr_contains_ip_string", []*cel.Type{CIDRType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(cidr, text ref.Val) ref.Val {
+					addr, err := parseIP(string(text.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return types.Bool(cidr.(cidrValue).prefix.Contains(addr))
+				}),
+			),
+		),
+		cel.Function("containsCIDR",
+			cel.MemberOverload("wasmcel_cidr_contains_cidr_cidr", []*cel.Type{CIDRType, CIDRType}, cel.BoolType,
+				cel.BinaryBinding(func(cidr, other ref.Val) ref.Val {
+					return types.Bool(containsCIDR(cidr.(cidrValue).prefix, other.(cidrValue).prefix))
+				}),
+			),
+			cel.MemberOverload("wasmcel_cidr_contains_cidr_string", []*cel.Type{CIDRType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(cidr, text ref.Val) ref.Val {
+					other, err := parseCIDR(string(text.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return types.Bool(containsCIDR(cidr.(cidrValue).prefix, other))
+				}),
+			),
+		),
+		cidrMember("ip", IPType, func(prefix netip.Prefix) ref.Val {
+			return ipValue{prefix.Addr()}
+		}),
+		cidrMember("masked", CIDRType, func(prefix netip.Prefix) ref.Val {
+			return cidrValue{prefix.Masked()}
+		}),
+		cidrMember("prefixLength", cel.IntType, func(prefix netip.Prefix) ref.Val {
+			return types.Int(prefix.Bits())
+		}),
+
+		cel.Function("string",
+			cel.Overload("wasmcel_string_ip", []*cel.Type{IPType}, cel.StringType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					return arg.ConvertToType(types.StringType)
+				}),
+			),
+			cel.Overload("wasmcel_string_cidr", []*cel.Type{CIDRType}, cel.StringType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					return arg.ConvertToType(types.StringType)
+				}),
+			),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (ipLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// ipMember declares a method of IP addresses without arguments
+func ipMember(name string, result *cel.Type, fn func(netip.Addr) ref.Val) cel.EnvOption {
+	return cel.Function(name,
+		cel.MemberOverload("wasmcel_ip_"+name, []*cel.Type{IPType}, result,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				return fn(arg.(ipValue).addr)
+			}),
+		),
+	)
+}
+
+// cidrMember declares a method of CIDR ranges without arguments
+func cidrMember(name string, result *cel.Type, fn func(netip.Prefix) ref.Val) cel.EnvOption {
+	return cel.Function(name,
+		cel.MemberOverload("wasmcel_cidr_"+name, []*cel.Type{CIDRType}, result,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				return fn(arg.(cidrValue).prefix)
+			}),
+		),
+	)
+}
+
+// containsCIDR reports whether a range contains another range of the same family
+func containsCIDR(prefix netip.Prefix, other netip.Prefix) bool {
+	return prefix.Bits() <= other.Bits() && prefix.Contains(other.Addr())
+}
@@ -0,0 +1,100 @@
+package options
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseIP(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{text: "192.168.0.1"},
+		{text: "::1"},
+		{text: "2001:db8::68"},
+		{text: "", err: "is invalid"},
+		{text: "256.0.0.1", err: "is invalid"},
+		{text: "192.168.0.1/24", err: "is invalid"},
+		{text: " 10.0.0.1", err: "is invalid"},
+		{text: "fe80::1%eth0", err: "with zone value is not allowed"},
+		{text: "::ffff:1.2.3.4", err: "IPv4-mapped IPv6 address"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			_, err := parseIP(test.text)
+			if test.err == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestParseCIDR(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{text: "10.0.0.0/8"},
+		{text: "10.1.2.3/8"},
+		{text: "::/0"},
+		{text: "2001:db8::/32"},
+		{text: "10.0.0.0", err: "is invalid"},
+		{text: "10.0.0.0/33", err: "is invalid"},
+		{text: "10.0.0.0/-1", err: "is invalid"},
+		{text: "::ffff:1.2.3.4/128", err: "IPv4-mapped IPv6 address"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			_, err := parseCIDR(test.text)
+			if test.err == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestIPFunctions(t *testing.T) {
+	env := optionEnv(t, "IP", nil)
+	runOptionTests(t, env, nil, []optionTest{
+		{expr: `isIP("10.0.0.1")`, expected: true},
+		{expr: `isIP("::ffff:1.2.3.4")`, expected: false},
+		{expr: `ip.isCanonical("2001:db8::68")`, expected: true},
+		{expr: `ip.isCanonical("2001:DB8::68")`, expected: false},
+		{expr: `ip.isCanonical("not an IP")`, err: "is invalid"},
+		{expr: `ip("10.0.0.1").family()`, expected: int64(4)},
+		{expr: `ip("::1").family()`, expected: int64(6)},
+		{expr: `ip("0.0.0.0").isUnspecified()`, expected: true},
+		{expr: `ip("127.0.0.1").isLoopback()`, expected: true},
+		{expr: `ip("224.0.0.1").isLinkLocalMulticast()`, expected: true},
+		{expr: `ip("fe80::1").isLinkLocalUnicast()`, expected: true},
+		{expr: `ip("8.8.8.8").isGlobalUnicast()`, expected: true},
+		{expr: `ip("192.168.1.1").isPrivate()`, expected: true},
+		{expr: `ip("8.8.8.8").isPrivate()`, expected: false},
+		{expr: `ip("10.0.0.1") == ip("10.0.0.1")`, expected: true},
+		{expr: `ip("::ffff:1.2.3.4")`, err: "is not allowed"},
+
+		{expr: `isCIDR("10.0.0.0/33")`, expected: false},
+		{expr: `cidr("10.0.0.0/8").containsIP(ip("10.1.2.3"))`, expected: true},
+		{expr: `cidr("10.0.0.0/8").containsIP("11.1.2.3")`, expected: false},
+		{expr: `cidr("10.0.0.0/8").containsIP("::ffff:10.1.2.3")`, err: "is not allowed"},
+		{expr: `cidr("10.0.0.0/8").containsCIDR(cidr("10.1.0.0/16"))`, expected: true},
+		{expr: `cidr("10.1.0.0/16").containsCIDR("10.0.0.0/8")`, expected: false},
+		// Ranges of another family are never contained
+		{expr: `cidr("::/0").containsCIDR("10.0.0.0/8")`, expected: false},
+		{expr: `cidr("10.1.2.3/8").ip()`, expected: "10.1.2.3"},
+		{expr: `cidr("10.1.2.3/8").masked()`, expected: "10.0.0.0/8"},
+		{expr: `cidr("2001:db8::/32").prefixLength()`, expected: int64(32)},
+		{expr: `string(ip("2001:DB8::68"))`, expected: "2001:db8::68"},
+		{expr: `string(cidr("10.0.0.0/8"))`, expected: "10.0.0.0/8"},
+		{expr: `cidr("10.0.0.0")`, err: "is invalid"},
+	})
+}
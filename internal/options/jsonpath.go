@@ -0,0 +1,251 @@
+package options
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// JSONPathBuilder declares jsonpath(), implemented in Go, which selects values out of
+// dynamic data with a subset of JSONPath rather than long chains of index expressions
+type JSONPathBuilder struct{}
+
+// Name returns the name of this option
+func (b *JSONPathBuilder) Name() string {
+	return "JSONPath"
+}
+
+// Description returns the description of this option
+func (b *JSONPathBuilder) Description() string {
+	return "JSONPath declares jsonpath(value, path), which returns the list of values a JSONPath selects. Paths\nsupport the subset of JSONPath made of fields (.name or ['name']), indexes ([0], or [-1] from the end) and\nwildcards (.* or [*]), optionally starting with $.\n\nMissing fields and indexes out of range select nothing, and wildcards go through the entries of maps in the\norder of their keys.\n\n\tjsonpath({\"a\": [{\"b\": 1}, {\"b\": 2}]}, \"$.a[*].b\") // [1, 2]\n\tjsonpath({\"a\": [1, 2, 3]}, \"a[-1]\") // [3]"
+}
+
+// Build creates the CEL environment option
+func (b *JSONPathBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(jsonPathLibrary{}), nil
+}
+
+// FromJSON configures the JSONPathBuilder from JSON parameters
+func (b *JSONPathBuilder) FromJSON(params map[string]interface{}) error {
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *JSONPathBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+func init() {
+	DefaultRegistry.Register("JSONPath", func() OptionBuilder {
+		return &JSONPathBuilder{}
+	})
+}
+
+// jsonPathStep is a step of a JSONPath: a field, an index or a wildcard
+type jsonPathStep struct {
+	field    string
+	index    int64
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the supported subset of JSONPath into its steps
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	invalid := func(offset int, reason string) error {
+		return fmt.Errorf("invalid JSONPath %q at offset %d: %s", path, offset, reason)
+	}
+
+	var steps []jsonPathStep
+	i := 0
+	if strings.HasPrefix(path, "$") {
+		i++
+	} else if path != "" && isJSONPathNameStart(path[0]) {
+		// Paths without $ may start with a bare field
+		path = "." + path
+	}
+	for i < len(path) {
+		switch path[i] {
+		case '.':
+			i++
+			if i < len(path) && path[i] == '*' {
+				steps = append(steps, jsonPathStep{wildcard: true})
+				i++
+				continue
+			}
+			start := i
+			for i < len(path) && isJSONPathNamePart(path[i]) {
+				i++
+			}
+			if start == i || !isJSONPathNameStart(path[start]) {
+				return nil, invalid(start, "expected a field name or * after .")
+			}
+			steps = append(steps, jsonPathStep{field: path[start:i]})
+		case '[':
+			i++
+			end := strings.IndexByte(path[i:], ']')
+			switch {
+			case i < len(path) && (path[i] == '\'' || path[i] == '"'):
+				field, length, err := unquoteJSONPathField(path[i:])
+				if err != nil {
+					return nil, invalid(i, err.Error())
+				}
+				i += length
+				if i >= len(path) || path[i] != ']' {
+					return nil, invalid(i, "expected ] after the field name")
+				}
+				steps = append(steps, jsonPathStep{field: field})
+			case end < 0:
+				return nil, invalid(i-1, "unterminated [")
+			case path[i:i+end] == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+				i += end
+			default:
+				index, err := strconv.ParseInt(path[i:i+end], 10, 64)
+				if err != nil {
+					return nil, invalid(i, "expected an index, a quoted field name or * inside []")
+				}
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+				i += end
+			}
+			i++
+		default:
+			return nil, invalid(i, fmt.Sprintf("unexpected %q", path[i]))
+		}
+	}
+	return steps, nil
+}
+
+// isJSONPathNameStart reports whether a byte can start a bare field name
+func isJSONPathNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// isJSONPathNamePart reports whether a byte can be part of a bare field name
+func isJSONPathNamePart(c byte) bool {
+	return isJSONPathNameStart(c) || c == '-' || c >= '0' && c <= '9'
+}
+
+// unquoteJSONPathField unquotes the field name a quoted string starts with, returning it
+// with the length of the quoted string
+func unquoteJSONPathField(text string) (string, int, error) {
+	quote := text[0]
+	var field strings.Builder
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case quote:
+			return field.String(), i + 1, nil
+		case '\\':
+			i++
+			if i == len(text) {
+				break
+			}
+			field.WriteByte(text[i])
+		default:
+			field.WriteByte(text[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated field name")
+}
+
+// selectJSONPath returns the values the steps select from a value
+func selectJSONPath(val ref.Val, steps []jsonPathStep) []ref.Val {
+	current := []ref.Val{val}
+	for _, step := range steps {
+		var next []ref.Val
+		for _, val := range current {
+			next = append(next, selectJSONPathStep(val, step)...)
+		}
+		current = next
+	}
+	return current
+}
+
+// selectJSONPathStep returns the values a step selects from a value
+func selectJSONPathStep(val ref.Val, step jsonPathStep) []ref.Val {
+	switch {
+	case step.wildcard:
+		switch val := val.(type) {
+		case traits.Lister:
+			var values []ref.Val
+			for it := val.Iterator(); it.HasNext() == types.True; {
+				values = append(values, it.Next())
+			}
+			return values
+		case traits.Mapper:
+			var keys []ref.Val
+			for it := val.Iterator(); it.HasNext() == types.True; {
+				keys = append(keys, it.Next())
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				return fmt.Sprint(keys[i].Value()) < fmt.Sprint(keys[j].Value())
+			})
+			values := make([]ref.Val, 0, len(keys))
+			for _, key := range keys {
+				values = append(values, val.Get(key))
+			}
+			return values
+		}
+	case step.isIndex:
+		if list, ok := val.(traits.Lister); ok {
+			size := int64(list.Size().(types.Int))
+			index := step.index
+			if index < 0 {
+				index += size
+			}
+			if index >= 0 && index < size {
+				return []ref.Val{list.Get(types.Int(index))}
+			}
+		}
+	default:
+		switch val := val.(type) {
+		case traits.Mapper:
+			if found, ok := val.Find(types.String(step.field)); ok {
+				return []ref.Val{found}
+			}
+		case traits.FieldTester:
+			// Objects and messages
+			if val.IsSet(types.String(step.field)) == types.True {
+				if indexer, ok := val.(traits.Indexer); ok {
+					return []ref.Val{indexer.Get(types.String(step.field))}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonPathLibrary declares jsonpath()
+type jsonPathLibrary struct{}
+
+// LibraryName implements cel.SingletonLibrary
+func (jsonPathLibrary) LibraryName() string {
+	return "wasm-cel.jsonpath"
+}
+
+// CompileOptions implements cel.Library
+func (jsonPathLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("jsonpath",
+			cel.Overload("wasmcel_jsonpath_dyn_string", []*cel.Type{cel.DynType, cel.StringType}, cel.ListType(cel.DynType),
+				cel.BinaryBinding(func(val, path ref.Val) ref.Val {
+					steps, err := parseJSONPath(string(path.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return types.DefaultTypeAdapter.NativeToValue(selectJSONPath(val, steps))
+				}),
+			),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (jsonPathLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
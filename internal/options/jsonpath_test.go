@@ -0,0 +1,96 @@
+package options
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	field := func(name string) jsonPathStep { return jsonPathStep{field: name} }
+	index := func(i int64) jsonPathStep { return jsonPathStep{index: i, isIndex: true} }
+	wildcard := jsonPathStep{wildcard: true}
+
+	tests := []struct {
+		path  string
+		steps []jsonPathStep
+		err   string
+	}{
+		{path: "$"},
+		{path: ""},
+		{path: "$.a.b", steps: []jsonPathStep{field("a"), field("b")}},
+		{path: "a.b", steps: []jsonPathStep{field("a"), field("b")}},
+		{path: "$.a-b_c9", steps: []jsonPathStep{field("a-b_c9")}},
+		{path: "$.é", steps: []jsonPathStep{field("é")}},
+		{path: "$[0][-1]", steps: []jsonPathStep{index(0), index(-1)}},
+		{path: "$.*[*]", steps: []jsonPathStep{wildcard, wildcard}},
+		{path: `$['a.b']["c"]`, steps: []jsonPathStep{field("a.b"), field("c")}},
+		{path: `$['it\'s']`, steps: []jsonPathStep{field("it's")}},
+		{path: `$['']`, steps: []jsonPathStep{field("")}},
+		{path: "[0]", steps: []jsonPathStep{index(0)}},
+
+		{path: "$.", err: "at offset 2: expected a field name or * after ."},
+		{path: "$.9", err: "at offset 2: expected a field name or * after ."},
+		{path: "$.spec[", err: "at offset 6: unterminated ["},
+		{path: "$[a]", err: "at offset 2: expected an index, a quoted field name or * inside []"},
+		{path: "$[]", err: "expected an index, a quoted field name or * inside []"},
+		{path: "$['a'", err: "expected ] after the field name"},
+		{path: "$['a", err: "unterminated field name"},
+		{path: "$a", err: `at offset 1: unexpected 'a'`},
+		{path: "9", err: `at offset 0: unexpected '9'`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			steps, err := parseJSONPath(test.path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected an error containing %q, got %v (%v)", test.err, err, steps)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(steps, test.steps) {
+				t.Errorf("expected %v, got %v", test.steps, steps)
+			}
+		})
+	}
+}
+
+func TestJSONPathFunction(t *testing.T) {
+	env := optionEnv(t, "JSONPath", nil, cel.Variable("pod", cel.DynType))
+	pod := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"app.kubernetes.io/name": "web", "tier": "front"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "nginx"},
+				map[string]interface{}{"name": "sidecar", "image": "envoy"},
+			},
+		},
+	}
+
+	runOptionTests(t, env, map[string]interface{}{"pod": pod}, []optionTest{
+		{expr: `jsonpath(pod, "$.spec.containers[*].image")`, expected: []string{"nginx", "envoy"}},
+		{expr: `jsonpath(pod, "spec.containers[-1].name")`, expected: []string{"sidecar"}},
+		{expr: `jsonpath(pod, "$.spec.containers[0]['name']")`, expected: []string{"app"}},
+		{expr: `jsonpath(pod, "$.metadata.labels['app.kubernetes.io/name']")`, expected: []string{"web"}},
+		// Wildcards go through maps in the order of their keys
+		{expr: `jsonpath(pod, "$.metadata.labels.*")`, expected: []string{"web", "front"}},
+		{expr: `jsonpath(pod, "$").size()`, expected: int64(1)},
+		// Missing fields, indexes out of range and steps into scalars select nothing
+		{expr: `jsonpath(pod, "$.spec.volumes[0]")`, expected: []string{}},
+		{expr: `jsonpath(pod, "$.spec.containers[2]")`, expected: []string{}},
+		{expr: `jsonpath(pod, "$.spec.containers[-3]")`, expected: []string{}},
+		{expr: `jsonpath(pod, "$.spec.containers[0].name.first")`, expected: []string{}},
+		{expr: `jsonpath(pod, "$.spec.containers.name")`, expected: []string{}},
+		{expr: `jsonpath([[1, 2], [3]], "[*][*]")`, expected: []int64{1, 2, 3}},
+		{expr: `jsonpath({1: "a"}, "$.*")`, expected: []string{"a"}},
+		{expr: `jsonpath(pod, "$.spec[")`, err: "invalid JSONPath"},
+	})
+}
@@ -0,0 +1,187 @@
+//go:build !wasmcel_nojwt
+
+package options
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// JWTBuilder declares functions decoding JSON Web Tokens, implemented in Go, so auth
+// previews can read claims without custom functions
+// Tokens are decoded but never verified: their signatures are ignored, so the claims
+// must not be trusted for anything but previews.
+type JWTBuilder struct{}
+
+// Name returns the name of this option
+func (b *JWTBuilder) Name() string {
+	return "JWT"
+}
+
+// Description returns the description of this option
+func (b *JWTBuilder) Description() string {
+	return "JWT declares functions decoding JSON Web Tokens without verifying them: jwt.decode() returns the claims\nof a token and jwt.header() its header, as maps holding numbers as doubles, jwt.hasClaim() checks whether a\ntoken has a claim, jwt.expiresAt() returns the timestamp of its exp claim, and jwt.isExpired() checks whether\nit has expired at a timestamp, such as now() of the SystemFunctions option.\n\nSignatures are ignored, so the claims must not be trusted for anything but previews."
+}
+
+// Build creates the CEL environment option
+func (b *JWTBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(jwtLibrary{}), nil
+}
+
+// FromJSON configures the JWTBuilder from JSON parameters
+func (b *JWTBuilder) FromJSON(params map[string]interface{}) error {
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *JWTBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+func init() {
+	DefaultRegistry.Register("JWT", func() OptionBuilder {
+		return &JWTBuilder{}
+	})
+}
+
+// decodeJWT decodes the header and claims of a compact JSON Web Token, ignoring its
+// signature
+func decodeJWT(token string) (header map[string]interface{}, claims map[string]interface{}, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("JWT is malformed: expected 3 parts separated by dots, got %d", len(parts))
+	}
+	if header, err = decodeJWTPart(parts[0]); err != nil {
+		return nil, nil, fmt.Errorf("JWT header is malformed: %v", err)
+	}
+	if claims, err = decodeJWTPart(parts[1]); err != nil {
+		return nil, nil, fmt.Errorf("JWT claims are malformed: %v", err)
+	}
+	return header, claims, nil
+}
+
+// decodeJWTPart decodes a base64url encoded JSON object
+func decodeJWTPart(part string) (map[string]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	return object, nil
+}
+
+// jwtExpiry returns the time of the exp claim of a token, if it has one
+func jwtExpiry(claims map[string]interface{}) (time.Time, bool, error) {
+	value, exists := claims["exp"]
+	if !exists {
+		return time.Time{}, false, nil
+	}
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("JWT exp claim must be a number of seconds")
+	}
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*1e9)).UTC(), true, nil
+}
+
+// jwtLibrary declares the JWT functions
+type jwtLibrary struct{}
+
+// LibraryName implements cel.SingletonLibrary
+func (jwtLibrary) LibraryName() string {
+	return "wasm-cel.jwt"
+}
+
+// CompileOptions implements cel.Library
+func (jwtLibrary) CompileOptions() []cel.EnvOption {
+	jsonObject := cel.MapType(cel.StringType, cel.DynType)
+	return []cel.EnvOption{
+		cel.Function("jwt.decode",
+			cel.Overload("wasmcel_jwt_decode_string", []*cel.Type{cel.StringType}, jsonObject,
+				cel.UnaryBinding(func(token ref.Val) ref.Val {
+					_, claims, err := decodeJWT(string(token.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return types.DefaultTypeAdapter.NativeToValue(claims)
+				}),
+			),
+		),
+		cel.Function("jwt.header",
+			cel.Overload("wasmcel_jwt_header_string", []*cel.Type{cel.StringType}, jsonObject,
+				cel.UnaryBinding(func(token ref.Val) ref.Val {
+					header, _, err := decodeJWT(string(token.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return types.DefaultTypeAdapter.NativeToValue(header)
+				}),
+			),
+		),
+		cel.Function("jwt.hasClaim",
+			cel.Overload("wasmcel_jwt_has_claim_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(token, name ref.Val) ref.Val {
+					_, claims, err := decodeJWT(string(token.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					_, exists := claims[string(name.(types.String))]
+					return types.Bool(exists)
+				}),
+			),
+		),
+		cel.Function("jwt.expiresAt",
+			cel.Overload("wasmcel_jwt_expires_at_string", []*cel.Type{cel.StringType}, cel.TimestampType,
+				cel.UnaryBinding(func(token ref.Val) ref.Val {
+					_, claims, err := decodeJWT(string(token.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					expiry, exists, err := jwtExpiry(claims)
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					if !exists {
+						return types.NewErr("JWT has no exp claim")
+					}
+					return types.Timestamp{Time: expiry}
+				}),
+			),
+		),
+		cel.Function("jwt.isExpired",
+			cel.Overload("wasmcel_jwt_is_expired_string_timestamp", []*cel.Type{cel.StringType, cel.TimestampType}, cel.BoolType,
+				cel.BinaryBinding(func(token, at ref.Val) ref.Val {
+					_, claims, err := decodeJWT(string(token.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					// Tokens without an exp claim never expire
+					expiry, exists, err := jwtExpiry(claims)
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return types.Bool(exists && !at.(types.Timestamp).Time.Before(expiry))
+				}),
+			),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (jwtLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
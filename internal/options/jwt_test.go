@@ -0,0 +1,95 @@
+//go:build !wasmcel_nojwt
+
+package options
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+// jwtToken encodes a header and claims, both JSON, into an unsigned token
+func jwtToken(header, claims string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+}
+
+func TestDecodeJWT(t *testing.T) {
+	const header = `{"alg":"HS256","typ":"JWT"}`
+	encodedHeader := base64.RawURLEncoding.EncodeToString([]byte(header))
+
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{name: "valid", token: jwtToken(header, `{"sub":"alice"}`)},
+		{name: "padded", token: base64.URLEncoding.EncodeToString([]byte(header)) + "." +
+			base64.URLEncoding.EncodeToString([]byte(`{"sub":"alice"}`)) + "."},
+		{name: "two parts", token: encodedHeader + ".e30", err: "expected 3 parts separated by dots, got 2"},
+		{name: "four parts", token: jwtToken(header, `{}`) + ".extra", err: "expected 3 parts separated by dots, got 4"},
+		{name: "header not base64", token: "!!!.e30.signature", err: "JWT header is malformed"},
+		{name: "claims not JSON", token: encodedHeader + "." + base64.RawURLEncoding.EncodeToString([]byte("alice")) + ".", err: "JWT claims are malformed"},
+		{name: "claims not an object", token: jwtToken(header, `["alice"]`), err: "JWT claims are malformed"},
+		{name: "null claims", token: jwtToken(header, `null`), err: "expected a JSON object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, claims, err := decodeJWT(test.token)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claims["sub"] != "alice" {
+				t.Errorf("expected the sub claim to be alice, got %v", claims)
+			}
+		})
+	}
+}
+
+func TestJWTFunctions(t *testing.T) {
+	env := optionEnv(t, "JWT", nil,
+		cel.Variable("token", cel.StringType),
+		cel.Variable("at", cel.TimestampType),
+	)
+	token := jwtToken(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"alice","roles":["admin"],"exp":1717243200.5}`)
+	expiry := time.Date(2024, 6, 1, 12, 0, 0, 5e8, time.UTC)
+
+	runOptionTests(t, env, map[string]interface{}{"token": token, "at": expiry}, []optionTest{
+		{expr: `jwt.decode(token).sub`, expected: "alice"},
+		{expr: `jwt.decode(token).roles`, expected: []string{"admin"}},
+		// JSON numbers are doubles
+		{expr: `jwt.decode(token).exp`, expected: 1717243200.5},
+		{expr: `jwt.header(token).alg`, expected: "HS256"},
+		{expr: `jwt.hasClaim(token, "roles")`, expected: true},
+		{expr: `jwt.hasClaim(token, "aud")`, expected: false},
+		{expr: `jwt.expiresAt(token)`, expected: expiry},
+		{expr: `jwt.isExpired(token, at - duration("1ms"))`, expected: false},
+		{expr: `jwt.isExpired(token, at)`, expected: true},
+		{expr: `jwt.decode("not-a-token")`, err: "JWT is malformed"},
+		{expr: `jwt.hasClaim("not-a-token", "sub")`, err: "JWT is malformed"},
+	})
+
+	t.Run("without exp", func(t *testing.T) {
+		runOptionTests(t, env, map[string]interface{}{"token": jwtToken(`{}`, `{"sub":"alice"}`), "at": expiry}, []optionTest{
+			{expr: `jwt.expiresAt(token)`, err: "JWT has no exp claim"},
+			// Tokens without an exp claim never expire
+			{expr: `jwt.isExpired(token, at)`, expected: false},
+		})
+	})
+	t.Run("exp not a number", func(t *testing.T) {
+		runOptionTests(t, env, map[string]interface{}{"token": jwtToken(`{}`, `{"exp":"tomorrow"}`), "at": expiry}, []optionTest{
+			{expr: `jwt.expiresAt(token)`, err: "JWT exp claim must be a number of seconds"},
+			{expr: `jwt.isExpired(token, at)`, err: "JWT exp claim must be a number of seconds"},
+		})
+	})
+}
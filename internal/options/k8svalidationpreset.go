@@ -0,0 +1,121 @@
+//go:build !wasmcel_noext
+
+package options
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/ext"
+	"github.com/google/cel-go/interpreter"
+)
+
+// k8sPerCallLimit is the runtime cost limit of a single expression in Kubernetes
+const k8sPerCallLimit = 1000000
+
+// K8sValidationPresetBuilder configures an environment like the CEL environment of
+// Kubernetes ValidatingAdmissionPolicy, so rules behave as they would in a cluster.
+// The Kubernetes-specific libraries, such as quantity, IP and URL functions, aren't
+// part of cel-go and aren't included.
+type K8sValidationPresetBuilder struct {
+	PerCallLimit uint64
+}
+
+// Name returns the name of this option
+func (b *K8sValidationPresetBuilder) Name() string {
+	return "K8sValidationPreset"
+}
+
+// Description returns the description of this option
+func (b *K8sValidationPresetBuilder) Description() string {
+	return "K8sValidationPreset configures the environment like the CEL environment of Kubernetes ValidatingAdmissionPolicy.\n\nIt enables the same cel-go extensions (strings version 2, sets, optional types and two-variable comprehensions),\nhomogeneous aggregate literals, cross-type numeric comparisons, UTC as the default time zone, validation of\nduration, timestamp and regex literals, and the Kubernetes cost semantics: programs track their cost, presence\ntests are free, and an evaluation is aborted once its cost exceeds the per-call limit.\n\nThe Kubernetes-specific libraries, such as quantity, IP and URL functions, are not included."
+}
+
+// SetPerCallLimit sets the perCallLimit parameter
+func (b *K8sValidationPresetBuilder) SetPerCallLimit(perCallLimit uint64) *K8sValidationPresetBuilder {
+	b.PerCallLimit = perCallLimit
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *K8sValidationPresetBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(&k8sValidationLibrary{perCallLimit: b.PerCallLimit}), nil
+}
+
+// FromJSON configures the K8sValidationPresetBuilder from JSON parameters
+func (b *K8sValidationPresetBuilder) FromJSON(params map[string]interface{}) error {
+	b.SetPerCallLimit(k8sPerCallLimit)
+
+	if value, exists := params["perCallLimit"]; exists {
+		limit, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("perCallLimit must be a number")
+		}
+		if limit < 0 {
+			return fmt.Errorf("perCallLimit must not be negative")
+		}
+		b.SetPerCallLimit(uint64(limit))
+	}
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *K8sValidationPresetBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"perCallLimit": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"default":     k8sPerCallLimit,
+			"description": "Maximum runtime cost of a single evaluation, as in Kubernetes",
+		},
+	})
+}
+
+func init() {
+	DefaultRegistry.Register("K8sValidationPreset", func() OptionBuilder {
+		return &K8sValidationPresetBuilder{PerCallLimit: k8sPerCallLimit}
+	})
+}
+
+// k8sValidationLibrary holds the compile and program options of the preset
+// Program options of a library apply to every program of the environment, which is
+// how the cost limit reaches programs compiled without program options
+type k8sValidationLibrary struct {
+	perCallLimit uint64
+}
+
+// LibraryName implements cel.SingletonLibrary, so the preset is only applied once
+func (l *k8sValidationLibrary) LibraryName() string {
+	return "wasm-cel.preset.k8s.validation"
+}
+
+// CompileOptions implements cel.Library
+func (l *k8sValidationLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.HomogeneousAggregateLiterals(),
+		cel.EagerlyValidateDeclarations(true),
+		cel.DefaultUTCTimeZone(true),
+		cel.CrossTypeNumericComparisons(true),
+		cel.OptionalTypes(),
+		ext.Strings(ext.StringsVersion(2)),
+		ext.Sets(),
+		ext.TwoVarComprehensions(),
+		cel.ASTValidators(
+			cel.ValidateDurationLiterals(),
+			cel.ValidateTimestampLiterals(),
+			cel.ValidateRegexLiterals(),
+			cel.ValidateHomogeneousAggregateLiterals(),
+		),
+		cel.CostEstimatorOptions(checker.PresenceTestHasCost(false)),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (l *k8sValidationLibrary) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{
+		cel.EvalOptions(cel.OptOptimize, cel.OptTrackCost),
+		cel.CostTrackerOptions(interpreter.PresenceTestHasCost(false)),
+		cel.CostLimit(l.perCallLimit),
+	}
+}
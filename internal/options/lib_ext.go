@@ -0,0 +1,18 @@
+//go:build !wasmcel_noext
+
+package options
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/env"
+	"github.com/google/cel-go/ext"
+)
+
+// extensionOption resolves an extension to the option enabling it, the way cel.FromConfig does
+func extensionOption(extension *env.Extension) (cel.EnvOption, bool) {
+	if extension.Name == "optional" {
+		version, _ := extension.VersionNumber()
+		return cel.OptionalTypes(cel.OptionalTypesVersion(version)), true
+	}
+	return ext.ExtensionOptionFactory(extension)
+}
@@ -0,0 +1,71 @@
+package options
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/env"
+)
+
+// FromJSON configures the LibBuilder from JSON parameters
+// The library is looked up by name in cel-go's registry of versioned extensions, the one
+// used for environment configuration files, so libraries added to cel-go are available
+// without a builder of their own. Omitting the version selects the latest one.
+func (b *LibBuilder) FromJSON(params map[string]interface{}) error {
+	name, ok := params["name"].(string)
+	if !ok || name == "" {
+		return fmt.Errorf("name must be a non-empty string")
+	}
+
+	version := uint64(math.MaxUint32)
+	if value, exists := params["version"]; exists && value != "latest" {
+		number, err := uintParam(params, "version", 32)
+		if err != nil {
+			return fmt.Errorf("%w, or \"latest\"", err)
+		}
+		version = number
+	}
+
+	extension := env.NewExtension(name, uint32(version))
+	option, ok := extensionOption(extension)
+	if !ok {
+		return fmt.Errorf("unknown library %q", name)
+	}
+
+	b.SetL(&versionedLibrary{option: option})
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *LibBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"name": map[string]interface{}{
+			"type":        "string",
+			"description": "Name of the library, such as \"strings\", \"lists\", \"math\" or \"optional\"",
+		},
+		"version": map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "integer", "minimum": 0},
+				map[string]interface{}{"const": "latest"},
+			},
+			"default":     "latest",
+			"description": "Version of the library, which limits the functions it provides",
+		},
+	}, "name")
+}
+
+// versionedLibrary is a library of cel-go's extension registry as a cel.Library
+type versionedLibrary struct {
+	option cel.EnvOption
+}
+
+// CompileOptions implements cel.Library
+func (l *versionedLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{l.option}
+}
+
+// ProgramOptions implements cel.Library
+func (l *versionedLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
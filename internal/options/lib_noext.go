@@ -0,0 +1,18 @@
+//go:build wasmcel_noext
+
+package options
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/env"
+)
+
+// extensionOption resolves an extension to the option enabling it. Builds without the
+// ext libraries only know the optional library, which is part of cel-go itself
+func extensionOption(extension *env.Extension) (cel.EnvOption, bool) {
+	if extension.Name != "optional" {
+		return nil, false
+	}
+	version, _ := extension.VersionNumber()
+	return cel.OptionalTypes(cel.OptionalTypesVersion(version)), true
+}
@@ -0,0 +1,146 @@
+//go:build !wasmcel_nolint
+
+package options
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/invakid404/wasm-cel/internal/lint"
+)
+
+// Severities of lint rules
+const (
+	lintOff     = "off"
+	lintInfo    = "info"
+	lintWarning = "warning"
+	lintError   = "error"
+)
+
+// LintBuilder runs the built-in lint rules over every expression compiled in the
+// environment, reporting what they find as compilation issues
+type LintBuilder struct {
+	// Severities maps rule names to their severity. Rules not listed are warnings
+	Severities map[string]string
+}
+
+// Name returns the name of this option
+func (b *LintBuilder) Name() string {
+	return "Lint"
+}
+
+// Description returns the description of this option
+func (b *LintBuilder) Description() string {
+	return "Lint runs built-in lint rules over every expression compiled in the environment: constant conditions,\ncomparisons that are always true or false, duplicate clauses of && and ||, comparisons of timestamps to\nstrings and unused cel.bind variables.\n\nFindings are reported as warnings by default, which compileDetailed() returns as issues. Each rule can be\nset to \"off\", \"info\", \"warning\" or \"error\", and rules set to \"error\" fail compilation."
+}
+
+// SetSeverity sets the severity of a rule
+func (b *LintBuilder) SetSeverity(rule string, severity string) *LintBuilder {
+	if b.Severities == nil {
+		b.Severities = make(map[string]string)
+	}
+	b.Severities[rule] = severity
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *LintBuilder) Build() (cel.EnvOption, error) {
+	severities := make(map[string]string, len(lint.Rules))
+	for _, rule := range lint.Rules {
+		severities[rule.Name] = lintWarning
+	}
+	for rule, severity := range b.Severities {
+		severities[rule] = severity
+	}
+	return cel.ASTValidators(&lintValidator{severities: severities}), nil
+}
+
+// FromJSON configures the LintBuilder from JSON parameters
+func (b *LintBuilder) FromJSON(params map[string]interface{}) error {
+	value, exists := params["rules"]
+	if !exists {
+		return nil
+	}
+	rules, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("rules must be an object")
+	}
+	for rule, value := range rules {
+		if !lint.IsRule(rule) {
+			return fmt.Errorf("unknown lint rule %q: expected one of %v", rule, lint.RuleNames())
+		}
+		severity, ok := value.(string)
+		if !ok || (severity != lintOff && severity != lintInfo && severity != lintWarning && severity != lintError) {
+			return fmt.Errorf("severity of lint rule %s must be %q, %q, %q or %q", rule, lintOff, lintInfo, lintWarning, lintError)
+		}
+		b.SetSeverity(rule, severity)
+	}
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *LintBuilder) ParamsSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(lint.Rules))
+	for _, rule := range lint.Rules {
+		properties[rule.Name] = map[string]interface{}{
+			"type":        "string",
+			"enum":        []interface{}{lintOff, lintInfo, lintWarning, lintError},
+			"default":     lintWarning,
+			"description": rule.Description,
+		}
+	}
+	return objectSchema(map[string]interface{}{
+		"rules": objectSchema(properties),
+	})
+}
+
+func init() {
+	DefaultRegistry.Register("Lint", func() OptionBuilder {
+		return &LintBuilder{}
+	})
+}
+
+// lintValidator reports the findings of the lint rules as compilation issues
+type lintValidator struct {
+	severities map[string]string
+}
+
+// Name returns the name of this validator
+func (v *lintValidator) Name() string {
+	return "wasm-cel.lint"
+}
+
+// Validate runs the enabled lint rules over a checked expression
+// Findings of rules set to "error" fail compilation. The others only reach compilations
+// that collect issues, like the JavaScript validators' warnings
+func (v *lintValidator) Validate(env *cel.Env, config cel.ValidatorConfig, a *ast.AST, issues *cel.Issues) {
+	var compilationCollector CompilationIssueAdder
+	if getCompilationContextFunc != nil {
+		compilationCollector = getCompilationContextFunc(a.SourceInfo().Description())
+	}
+
+	findings := lint.Check(a, func(rule string) bool {
+		return v.severities[rule] != lintOff
+	})
+	for _, finding := range findings {
+		severity := v.severities[finding.Rule]
+		if severity == lintError {
+			issues.ReportErrorAtID(finding.NodeID, "%s (%s)", finding.Message, finding.Rule)
+			continue
+		}
+		if compilationCollector == nil {
+			continue
+		}
+		location := a.SourceInfo().GetStartLocation(finding.NodeID)
+		compilationCollector.AddValidatorIssue(ValidatorIssue{
+			Severity: severity,
+			Message:  finding.Message,
+			Location: map[string]interface{}{
+				"line":   location.Line(),
+				"column": location.Column(),
+			},
+			Rule: finding.Rule,
+		})
+	}
+}
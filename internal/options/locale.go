@@ -0,0 +1,214 @@
+//go:build !wasmcel_nolocale
+
+package options
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// LocaleBuilder declares functions comparing and casing strings the way a locale does,
+// so text rules agree with the collation of backends rather than comparing bytes
+type LocaleBuilder struct {
+	Locale           string
+	IgnoreCase       bool
+	IgnoreDiacritics bool
+	Numeric          bool
+}
+
+// Name returns the name of this option
+func (b *LocaleBuilder) Name() string {
+	return "Locale"
+}
+
+// Description returns the description of this option
+func (b *LocaleBuilder) Description() string {
+	return "Locale declares functions following the rules of a locale: locale.compare() returns -1, 0 or 1 as a\nstring sorts before, with or after another, locale.equals() checks whether two strings collate equally,\nlocale.sort() sorts a list of strings, and locale.lower(), locale.upper() and locale.title() change the case\nof a string.\n\nCollation can ignore case and diacritics, and sort digits by their numeric value."
+}
+
+// SetLocale sets the locale parameter
+func (b *LocaleBuilder) SetLocale(locale string) *LocaleBuilder {
+	b.Locale = locale
+	return b
+}
+
+// SetIgnoreCase sets the ignoreCase parameter
+func (b *LocaleBuilder) SetIgnoreCase(ignoreCase bool) *LocaleBuilder {
+	b.IgnoreCase = ignoreCase
+	return b
+}
+
+// SetIgnoreDiacritics sets the ignoreDiacritics parameter
+func (b *LocaleBuilder) SetIgnoreDiacritics(ignoreDiacritics bool) *LocaleBuilder {
+	b.IgnoreDiacritics = ignoreDiacritics
+	return b
+}
+
+// SetNumeric sets the numeric parameter
+func (b *LocaleBuilder) SetNumeric(numeric bool) *LocaleBuilder {
+	b.Numeric = numeric
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *LocaleBuilder) Build() (cel.EnvOption, error) {
+	tag, err := language.Parse(b.Locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale %q: %v", b.Locale, err)
+	}
+	var collation []collate.Option
+	if b.IgnoreCase {
+		collation = append(collation, collate.IgnoreCase)
+	}
+	if b.IgnoreDiacritics {
+		collation = append(collation, collate.IgnoreDiacritics)
+	}
+	if b.Numeric {
+		collation = append(collation, collate.Numeric)
+	}
+	return cel.Lib(&localeLibrary{tag: tag, collator: collate.New(tag, collation...)}), nil
+}
+
+// FromJSON configures the LocaleBuilder from JSON parameters
+func (b *LocaleBuilder) FromJSON(params map[string]interface{}) error {
+	if value, exists := params["locale"]; exists {
+		locale, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("locale must be a string")
+		}
+		b.SetLocale(locale)
+	}
+	for name, set := range map[string]func(bool) *LocaleBuilder{
+		"ignoreCase":       b.SetIgnoreCase,
+		"ignoreDiacritics": b.SetIgnoreDiacritics,
+		"numeric":          b.SetNumeric,
+	} {
+		if value, exists := params[name]; exists {
+			enabled, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("%s must be a boolean", name)
+			}
+			set(enabled)
+		}
+	}
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *LocaleBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"locale": map[string]interface{}{
+			"type":        "string",
+			"default":     "und",
+			"description": "BCP 47 language tag of the locale, such as \"de\" or \"sv-SE\"",
+		},
+		"ignoreCase": map[string]interface{}{
+			"type":        "boolean",
+			"default":     false,
+			"description": "Collate strings differing only in case equally",
+		},
+		"ignoreDiacritics": map[string]interface{}{
+			"type":        "boolean",
+			"default":     false,
+			"description": "Collate strings differing only in diacritics equally",
+		},
+		"numeric": map[string]interface{}{
+			"type":        "boolean",
+			"default":     false,
+			"description": "Sort sequences of digits by their numeric value",
+		},
+	})
+}
+
+func init() {
+	DefaultRegistry.Register("Locale", func() OptionBuilder {
+		return &LocaleBuilder{Locale: "und"}
+	})
+}
+
+// localeLibrary declares the locale functions
+type localeLibrary struct {
+	tag      language.Tag
+	mu       sync.Mutex // Guards the collator, which reuses its buffers
+	collator *collate.Collator
+}
+
+// LibraryName implements cel.SingletonLibrary
+func (l *localeLibrary) LibraryName() string {
+	return "wasm-cel.locale"
+}
+
+// CompileOptions implements cel.Library
+func (l *localeLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("locale.compare",
+			cel.Overload("wasmcel_locale_compare_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.IntType,
+				cel.BinaryBinding(func(a, b ref.Val) ref.Val {
+					return types.Int(l.compare(string(a.(types.String)), string(b.(types.String))))
+				}),
+			),
+		),
+		cel.Function("locale.equals",
+			cel.Overload("wasmcel_locale_equals_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(a, b ref.Val) ref.Val {
+					return types.Bool(l.compare(string(a.(types.String)), string(b.(types.String))) == 0)
+				}),
+			),
+		),
+		cel.Function("locale.sort",
+			cel.Overload("wasmcel_locale_sort_list_string", []*cel.Type{cel.ListType(cel.StringType)}, cel.ListType(cel.StringType),
+				cel.UnaryBinding(func(list ref.Val) ref.Val {
+					return l.sort(list.(traits.Lister))
+				}),
+			),
+		),
+		l.caser("lower", cases.Lower),
+		l.caser("upper", cases.Upper),
+		l.caser("title", cases.Title),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (l *localeLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// compare collates two strings
+func (l *localeLibrary) compare(a, b string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.collator.CompareString(a, b)
+}
+
+// sort returns a list of strings in collation order, keeping the order of strings that
+// collate equally
+func (l *localeLibrary) sort(list traits.Lister) ref.Val {
+	var items []string
+	for it := list.Iterator(); it.HasNext() == types.True; {
+		items = append(items, string(it.Next().(types.String)))
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return l.compare(items[i], items[j]) < 0
+	})
+	return types.DefaultTypeAdapter.NativeToValue(items)
+}
+
+// caser declares a function changing the case of a string the way the locale does
+func (l *localeLibrary) caser(name string, newCaser func(language.Tag, ...cases.Option) cases.Caser) cel.EnvOption {
+	return cel.Function("locale."+name,
+		cel.Overload("wasmcel_locale_"+name+"_string", []*cel.Type{cel.StringType}, cel.StringType,
+			cel.UnaryBinding(func(text ref.Val) ref.Val {
+				return types.String(newCaser(l.tag).String(string(text.(types.String))))
+			}),
+		),
+	)
+}
@@ -9,7 +9,7 @@ import (
 	types "github.com/google/cel-go/common/types"
 	ref "github.com/google/cel-go/common/types/ref"
 	v1alpha1 "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
-	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	"sort"
 )
 
 // OptionBuilder is the interface that all option builders must implement
@@ -52,16 +52,17 @@ func (r *Registry) Create(name string) (OptionBuilder, error) {
 	return factory(), nil
 }
 
-// List returns all available option names
+// List returns all available option names in sorted order
 func (r *Registry) List() []string {
 	var names []string
 	for name := range r.builders {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
 
-// ListWithFromJSON returns option names that implement the FromJSON interface
+// ListWithFromJSON returns option names that implement the FromJSON interface in sorted order
 func (r *Registry) ListWithFromJSON() []string {
 	var names []string
 	for name, factory := range r.builders {
@@ -70,6 +71,7 @@ func (r *Registry) ListWithFromJSON() []string {
 			names = append(names, name)
 		}
 	}
+	sort.Strings(names)
 	return names
 }
 
@@ -299,6 +301,65 @@ func (b *CostEstimatorOptionsBuilder) SetCostOpts(costOpts []checker.CostOption)
 func (b *CostEstimatorOptionsBuilder) Build() (cel.EnvOption, error) {
 	return cel.CostEstimatorOptions(b.CostOpts...), nil
 }
+
+// PresenceTestHasCost determines whether presence testing has a cost of one or zero.
+// Defaults to presence test has a cost of one.
+type CheckerPresenceTestHasCostBuilder struct {
+	HasCost bool
+}
+
+// Description returns the description of this sub-option
+func (b *CheckerPresenceTestHasCostBuilder) Description() string {
+	return "PresenceTestHasCost determines whether presence testing has a cost of one or zero.\n\nDefaults to presence test has a cost of one."
+}
+
+// SetHasCost sets the hasCost parameter
+func (b *CheckerPresenceTestHasCostBuilder) SetHasCost(hasCost bool) *CheckerPresenceTestHasCostBuilder {
+	b.HasCost = hasCost
+	return b
+}
+
+// Build creates the checker.CostOption
+func (b *CheckerPresenceTestHasCostBuilder) Build() (checker.CostOption, error) {
+	return checker.PresenceTestHasCost(b.HasCost), nil
+}
+
+// FromJSON configures the CheckerPresenceTestHasCostBuilder from JSON parameters
+func (b *CheckerPresenceTestHasCostBuilder) FromJSON(params map[string]interface{}) error {
+	hasCost, err := boolParam(params, "hasCost")
+	if err != nil {
+		return err
+	}
+	b.SetHasCost(hasCost)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *CheckerPresenceTestHasCostBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"hasCost": map[string]interface{}{"type": "boolean"}}, "hasCost")
+}
+
+// costEstimatorOptionsCostOptsBuilders maps the sub-options accepted by the costOpts parameter of CostEstimatorOptions to their builders
+var costEstimatorOptionsCostOptsBuilders = map[string]func() SubOptionBuilder[checker.CostOption]{
+	"PresenceTestHasCost": func() SubOptionBuilder[checker.CostOption] {
+		return &CheckerPresenceTestHasCostBuilder{}
+	},
+}
+
+// FromJSON configures the CostEstimatorOptionsBuilder from JSON parameters
+func (b *CostEstimatorOptionsBuilder) FromJSON(params map[string]interface{}) error {
+	costOpts, err := subOptionsFromJSON(params, "costOpts", costEstimatorOptionsCostOptsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetCostOpts(costOpts)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *CostEstimatorOptionsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"costOpts": subOptionsSchema(costEstimatorOptionsCostOptsBuilders)})
+}
 func init() {
 	DefaultRegistry.Register("CostEstimatorOptions", func() OptionBuilder {
 		return &CostEstimatorOptionsBuilder{}
@@ -403,39 +464,6 @@ func init() {
 	})
 }
 
-// DeclareContextProto returns an option to extend CEL environment with declarations from the given context proto.
-// Each field of the proto defines a variable of the same name in the environment.
-// https://github.com/google/cel-spec/blob/master/doc/langdef.md#evaluation-environment
-type DeclareContextProtoBuilder struct {
-	Descriptor protoreflect.MessageDescriptor
-}
-
-// Name returns the name of this option
-func (b *DeclareContextProtoBuilder) Name() string {
-	return "DeclareContextProto"
-}
-
-// Description returns the description of this option
-func (b *DeclareContextProtoBuilder) Description() string {
-	return "DeclareContextProto returns an option to extend CEL environment with declarations from the given context proto.\nEach field of the proto defines a variable of the same name in the environment.\nhttps://github.com/google/cel-spec/blob/master/doc/langdef.md#evaluation-environment"
-}
-
-// SetDescriptor sets the descriptor parameter
-func (b *DeclareContextProtoBuilder) SetDescriptor(descriptor protoreflect.MessageDescriptor) *DeclareContextProtoBuilder {
-	b.Descriptor = descriptor
-	return b
-}
-
-// Build creates the CEL environment option
-func (b *DeclareContextProtoBuilder) Build() (cel.EnvOption, error) {
-	return cel.DeclareContextProto(b.Descriptor), nil
-}
-func init() {
-	DefaultRegistry.Register("DeclareContextProto", func() OptionBuilder {
-		return &DeclareContextProtoBuilder{}
-	})
-}
-
 // DefaultUTCTimeZone ensures that time-based operations use the UTC timezone rather than the
 // input time's local timezone.
 type DefaultUTCTimeZoneBuilder struct {
@@ -944,6 +972,73 @@ func (b *OptionalTypesBuilder) SetOpts(opts []cel.OptionalTypesOption) *Optional
 func (b *OptionalTypesBuilder) Build() (cel.EnvOption, error) {
 	return cel.OptionalTypes(b.Opts...), nil
 }
+
+// OptionalTypesVersion configures the version of the optional type library.
+// The version limits which functions are available. Only functions introduced
+// below or equal to the given version included in the library. If this option
+// is not set, all functions are available.
+// See the library documentation to determine which version a function was introduced.
+// If the documentation does not state which version a function was introduced, it can
+// be assumed to be introduced at version 0, when the library was first created.
+type OptionalTypesVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *OptionalTypesVersionBuilder) Description() string {
+	return "OptionalTypesVersion configures the version of the optional type library.\n\nThe version limits which functions are available. Only functions introduced\nbelow or equal to the given version included in the library. If this option\nis not set, all functions are available.\n\nSee the library documentation to determine which version a function was introduced.\nIf the documentation does not state which version a function was introduced, it can\nbe assumed to be introduced at version 0, when the library was first created."
+}
+
+// SetVersion sets the version parameter
+func (b *OptionalTypesVersionBuilder) SetVersion(version uint32) *OptionalTypesVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the cel.OptionalTypesOption
+func (b *OptionalTypesVersionBuilder) Build() (cel.OptionalTypesOption, error) {
+	return cel.OptionalTypesVersion(b.Version), nil
+}
+
+// FromJSON configures the OptionalTypesVersionBuilder from JSON parameters
+func (b *OptionalTypesVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *OptionalTypesVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// optionalTypesOptsBuilders maps the sub-options accepted by the opts parameter of OptionalTypes to their builders
+var optionalTypesOptsBuilders = map[string]func() SubOptionBuilder[cel.OptionalTypesOption]{
+	"OptionalTypesVersion": func() SubOptionBuilder[cel.OptionalTypesOption] {
+		return &OptionalTypesVersionBuilder{}
+	},
+}
+
+// FromJSON configures the OptionalTypesBuilder from JSON parameters
+func (b *OptionalTypesBuilder) FromJSON(params map[string]interface{}) error {
+	opts, err := subOptionsFromJSON(params, "opts", optionalTypesOptsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOpts(opts)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *OptionalTypesBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"opts": subOptionsSchema(optionalTypesOptsBuilders)})
+}
 func init() {
 	DefaultRegistry.Register("OptionalTypes", func() OptionBuilder {
 		return &OptionalTypesBuilder{}
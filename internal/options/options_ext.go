@@ -0,0 +1,1589 @@
+//go:build !wasmcel_noext
+
+// Code generated by extensionsgen. DO NOT EDIT.
+package options
+
+import (
+	cel "github.com/google/cel-go/cel"
+	ext "github.com/google/cel-go/ext"
+)
+
+// Bindings returns a cel.EnvOption to configure support for local variable
+// bindings in expressions.
+// # Cel.Bind
+// Binds a simple identifier to an initialization expression which may be used
+// in a subsequenct result expression. Bindings may also be nested within each
+// other.
+// cel.bind(<varName>, <initExpr>, <resultExpr>)
+// Examples:
+// cel.bind(a, 'hello',
+// cel.bind(b, 'world', a + b + b + a)) // "helloworldworldhello"
+// Avoid a list allocation within the exists comprehension.
+// cel.bind(valid_values, [a, b, c],
+// [d, e, f].exists(elem, elem in valid_values))
+// Local bindings are not guaranteed to be evaluated before use.
+type ExtBindingsBuilder struct {
+	Options []ext.BindingsOption
+}
+
+// Name returns the name of this option
+func (b *ExtBindingsBuilder) Name() string {
+	return "ext.Bindings"
+}
+
+// Description returns the description of this option
+func (b *ExtBindingsBuilder) Description() string {
+	return "Bindings returns a cel.EnvOption to configure support for local variable\nbindings in expressions.\n\n# Cel.Bind\n\nBinds a simple identifier to an initialization expression which may be used\nin a subsequenct result expression. Bindings may also be nested within each\nother.\n\n\tcel.bind(<varName>, <initExpr>, <resultExpr>)\n\nExamples:\n\n\tcel.bind(a, 'hello',\n\tcel.bind(b, 'world', a + b + b + a)) // \"helloworldworldhello\"\n\n\t// Avoid a list allocation within the exists comprehension.\n\tcel.bind(valid_values, [a, b, c],\n\t[d, e, f].exists(elem, elem in valid_values))\n\nLocal bindings are not guaranteed to be evaluated before use."
+}
+
+// SetOptions sets the options parameter
+func (b *ExtBindingsBuilder) SetOptions(options []ext.BindingsOption) *ExtBindingsBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtBindingsBuilder) Build() (cel.EnvOption, error) {
+	return ext.Bindings(b.Options...), nil
+}
+
+// BindingsVersion sets the version of the bindings library to an explicit version.
+type ExtBindingsVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtBindingsVersionBuilder) Description() string {
+	return "BindingsVersion sets the version of the bindings library to an explicit version."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtBindingsVersionBuilder) SetVersion(version uint32) *ExtBindingsVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.BindingsOption
+func (b *ExtBindingsVersionBuilder) Build() (ext.BindingsOption, error) {
+	return ext.BindingsVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtBindingsVersionBuilder from JSON parameters
+func (b *ExtBindingsVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtBindingsVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extBindingsOptionsBuilders maps the sub-options accepted by the options parameter of ext.Bindings to their builders
+var extBindingsOptionsBuilders = map[string]func() SubOptionBuilder[ext.BindingsOption]{
+	"BindingsVersion": func() SubOptionBuilder[ext.BindingsOption] {
+		return &ExtBindingsVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtBindingsBuilder from JSON parameters
+func (b *ExtBindingsBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extBindingsOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtBindingsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extBindingsOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Bindings", func() OptionBuilder {
+		return &ExtBindingsBuilder{}
+	})
+}
+
+// Encoders returns a cel.EnvOption to configure extended functions for string, byte, and object
+// encodings.
+// # Base64.Decode
+// Decodes base64-encoded string to bytes.
+// This function will return an error if the string input is not base64-encoded.
+// base64.decode(<string>) -> <bytes>
+// Examples:
+// base64.decode('aGVsbG8=')  // return b'hello'
+// base64.decode('aGVsbG8')   // return b'hello'
+// # Base64.Encode
+// Encodes bytes to a base64-encoded string.
+// base64.encode(<bytes>)  -> <string>
+// Examples:
+// base64.encode(b'hello') // return b'aGVsbG8='
+type ExtEncodersBuilder struct {
+	Options []ext.EncodersOption
+}
+
+// Name returns the name of this option
+func (b *ExtEncodersBuilder) Name() string {
+	return "ext.Encoders"
+}
+
+// Description returns the description of this option
+func (b *ExtEncodersBuilder) Description() string {
+	return "Encoders returns a cel.EnvOption to configure extended functions for string, byte, and object\nencodings.\n\n# Base64.Decode\n\nDecodes base64-encoded string to bytes.\n\nThis function will return an error if the string input is not base64-encoded.\n\n\tbase64.decode(<string>) -> <bytes>\n\nExamples:\n\n\tbase64.decode('aGVsbG8=')  // return b'hello'\n\tbase64.decode('aGVsbG8')   // return b'hello'\n\n# Base64.Encode\n\nEncodes bytes to a base64-encoded string.\n\n\tbase64.encode(<bytes>)  -> <string>\n\nExamples:\n\n\tbase64.encode(b'hello') // return b'aGVsbG8='"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtEncodersBuilder) SetOptions(options []ext.EncodersOption) *ExtEncodersBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtEncodersBuilder) Build() (cel.EnvOption, error) {
+	return ext.Encoders(b.Options...), nil
+}
+
+// EncodersVersion sets the library version for encoder extensions.
+type ExtEncodersVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtEncodersVersionBuilder) Description() string {
+	return "EncodersVersion sets the library version for encoder extensions."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtEncodersVersionBuilder) SetVersion(version uint32) *ExtEncodersVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.EncodersOption
+func (b *ExtEncodersVersionBuilder) Build() (ext.EncodersOption, error) {
+	return ext.EncodersVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtEncodersVersionBuilder from JSON parameters
+func (b *ExtEncodersVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtEncodersVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extEncodersOptionsBuilders maps the sub-options accepted by the options parameter of ext.Encoders to their builders
+var extEncodersOptionsBuilders = map[string]func() SubOptionBuilder[ext.EncodersOption]{
+	"EncodersVersion": func() SubOptionBuilder[ext.EncodersOption] {
+		return &ExtEncodersVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtEncodersBuilder from JSON parameters
+func (b *ExtEncodersBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extEncodersOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtEncodersBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extEncodersOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Encoders", func() OptionBuilder {
+		return &ExtEncodersBuilder{}
+	})
+}
+
+// Lists returns a cel.EnvOption to configure extended functions for list manipulation.
+// As a general note, all indices are zero-based.
+// # Distinct
+// Introduced in version: 2 (cost support in version 3)
+// Returns the distinct elements of a list.
+// <list(T)>.distinct() -> <list(T)>
+// Examples:
+// [1, 2, 2, 3, 3, 3].distinct() // return [1, 2, 3]
+// ["b", "b", "c", "a", "c"].distinct() // return ["b", "c", "a"]
+// [1, "b", 2, "b"].distinct() // return [1, "b", 2]
+// # Range
+// Introduced in version: 2 (cost support in version 3)
+// Returns a list of integers from 0 to n-1.
+// lists.range(<int>) -> <list(int)>
+// Examples:
+// lists.range(5) -> [0, 1, 2, 3, 4]
+// # Reverse
+// Introduced in version: 2 (cost support in version 3)
+// Returns the elements of a list in reverse order.
+// <list(T)>.reverse() -> <list(T)>
+// Examples:
+// [5, 3, 1, 2].reverse() // return [2, 1, 3, 5]
+// # Slice
+// Introduced in version: 0 (cost support in version 3)
+// Returns a new sub-list using the indexes provided.
+// <list>.slice(<int>, <int>) -> <list>
+// Examples:
+// [1,2,3,4].slice(1, 3) // return [2, 3]
+// [1,2,3,4].slice(2, 4) // return [3 ,4]
+// # Flatten
+// Introduced in version: 1 (cost support in version 3)
+// Flattens a list recursively.
+// If an optional depth is provided, the list is flattened to a the specified level.
+// A negative depth value will result in an error.
+// <list>.flatten() -> <list>
+// <list>.flatten(<int>) -> <list>
+// Examples:
+// [1,[2,3],[4]].flatten() // return [1, 2, 3, 4]
+// [1,[2,[3,4]]].flatten() // return [1, 2, [3, 4]]
+// [1,2,[],[],[3,4]].flatten() // return [1, 2, 3, 4]
+// [1,[2,[3,[4]]]].flatten(2) // return [1, 2, 3, [4]]
+// [1,[2,[3,[4]]]].flatten(-1) // error
+// # Sort
+// Introduced in version: 2 (cost support in version 3)
+// Sorts a list with comparable elements. If the element type is not comparable
+// or the element types are not the same, the function will produce an error.
+// <list(T)>.sort() -> <list(T)>
+// T in {int, uint, double, bool, duration, timestamp, string, bytes}
+// Examples:
+// [3, 2, 1].sort() // return [1, 2, 3]
+// ["b", "c", "a"].sort() // return ["a", "b", "c"]
+// [1, "b"].sort() // error
+// [[1, 2, 3]].sort() // error
+// # SortBy
+// Introduced in version: 2 (cost support in version 3)
+// Sorts a list by a key value, i.e., the order is determined by the result of
+// an expression applied to each element of the list.
+// The output of the key expression must be a comparable type, otherwise the
+// function will return an error.
+// <list(T)>.sortBy(<bindingName>, <keyExpr>) -> <list(T)>
+// keyExpr returns a value in {int, uint, double, bool, duration, timestamp, string, bytes}
+// Examples:
+// [
+// Player { name: "foo", score: 0 },
+// Player { name: "bar", score: -10 },
+// Player { name: "baz", score: 1000 },
+// ].sortBy(e, e.score).map(e, e.name)
+// == ["bar", "foo", "baz"]
+type ExtListsBuilder struct {
+	Options []ext.ListsOption
+}
+
+// Name returns the name of this option
+func (b *ExtListsBuilder) Name() string {
+	return "ext.Lists"
+}
+
+// Description returns the description of this option
+func (b *ExtListsBuilder) Description() string {
+	return "Lists returns a cel.EnvOption to configure extended functions for list manipulation.\nAs a general note, all indices are zero-based.\n\n# Distinct\n\nIntroduced in version: 2 (cost support in version 3)\n\nReturns the distinct elements of a list.\n\n\t<list(T)>.distinct() -> <list(T)>\n\nExamples:\n\n\t[1, 2, 2, 3, 3, 3].distinct() // return [1, 2, 3]\n\t[\"b\", \"b\", \"c\", \"a\", \"c\"].distinct() // return [\"b\", \"c\", \"a\"]\n\t[1, \"b\", 2, \"b\"].distinct() // return [1, \"b\", 2]\n\n# Range\n\nIntroduced in version: 2 (cost support in version 3)\n\nReturns a list of integers from 0 to n-1.\n\n\tlists.range(<int>) -> <list(int)>\n\nExamples:\n\n\tlists.range(5) -> [0, 1, 2, 3, 4]\n\n# Reverse\n\nIntroduced in version: 2 (cost support in version 3)\n\nReturns the elements of a list in reverse order.\n\n\t<list(T)>.reverse() -> <list(T)>\n\nExamples:\n\n\t[5, 3, 1, 2].reverse() // return [2, 1, 3, 5]\n\n# Slice\n\nIntroduced in version: 0 (cost support in version 3)\n\nReturns a new sub-list using the indexes provided.\n\n\t<list>.slice(<int>, <int>) -> <list>\n\nExamples:\n\n\t[1,2,3,4].slice(1, 3) // return [2, 3]\n\t[1,2,3,4].slice(2, 4) // return [3 ,4]\n\n# Flatten\n\nIntroduced in version: 1 (cost support in version 3)\n\nFlattens a list recursively.\nIf an optional depth is provided, the list is flattened to a the specified level.\nA negative depth value will result in an error.\n\n\t<list>.flatten() -> <list>\n\t<list>.flatten(<int>) -> <list>\n\nExamples:\n\n[1,[2,3],[4]].flatten() // return [1, 2, 3, 4]\n[1,[2,[3,4]]].flatten() // return [1, 2, [3, 4]]\n[1,2,[],[],[3,4]].flatten() // return [1, 2, 3, 4]\n[1,[2,[3,[4]]]].flatten(2) // return [1, 2, 3, [4]]\n[1,[2,[3,[4]]]].flatten(-1) // error\n\n# Sort\n\nIntroduced in version: 2 (cost support in version 3)\n\nSorts a list with comparable elements. If the element type is not comparable\nor the element types are not the same, the function will produce an error.\n\n\t<list(T)>.sort() -> <list(T)>\n\tT in {int, uint, double, bool, duration, timestamp, string, bytes}\n\nExamples:\n\n\t[3, 2, 1].sort() // return [1, 2, 3]\n\t[\"b\", \"c\", \"a\"].sort() // return [\"a\", \"b\", \"c\"]\n\t[1, \"b\"].sort() // error\n\t[[1, 2, 3]].sort() // error\n\n# SortBy\n\nIntroduced in version: 2 (cost support in version 3)\n\nSorts a list by a key value, i.e., the order is determined by the result of\nan expression applied to each element of the list.\nThe output of the key expression must be a comparable type, otherwise the\nfunction will return an error.\n\n\t<list(T)>.sortBy(<bindingName>, <keyExpr>) -> <list(T)>\n\tkeyExpr returns a value in {int, uint, double, bool, duration, timestamp, string, bytes}\n\nExamples:\n\n\t[\n\t  Player { name: \"foo\", score: 0 },\n\t  Player { name: \"bar\", score: -10 },\n\t  Player { name: \"baz\", score: 1000 },\n\t].sortBy(e, e.score).map(e, e.name)\n\t== [\"bar\", \"foo\", \"baz\"]"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtListsBuilder) SetOptions(options []ext.ListsOption) *ExtListsBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtListsBuilder) Build() (cel.EnvOption, error) {
+	return ext.Lists(b.Options...), nil
+}
+
+// ListsVersion configures the version of the string library.
+// The version limits which functions are available. Only functions introduced
+// below or equal to the given version included in the library. If this option
+// is not set, all functions are available.
+// See the library documentation to determine which version a function was introduced.
+// If the documentation does not state which version a function was introduced, it can
+// be assumed to be introduced at version 0, when the library was first created.
+type ExtListsVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtListsVersionBuilder) Description() string {
+	return "ListsVersion configures the version of the string library.\n\nThe version limits which functions are available. Only functions introduced\nbelow or equal to the given version included in the library. If this option\nis not set, all functions are available.\n\nSee the library documentation to determine which version a function was introduced.\nIf the documentation does not state which version a function was introduced, it can\nbe assumed to be introduced at version 0, when the library was first created."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtListsVersionBuilder) SetVersion(version uint32) *ExtListsVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.ListsOption
+func (b *ExtListsVersionBuilder) Build() (ext.ListsOption, error) {
+	return ext.ListsVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtListsVersionBuilder from JSON parameters
+func (b *ExtListsVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtListsVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extListsOptionsBuilders maps the sub-options accepted by the options parameter of ext.Lists to their builders
+var extListsOptionsBuilders = map[string]func() SubOptionBuilder[ext.ListsOption]{
+	"ListsVersion": func() SubOptionBuilder[ext.ListsOption] {
+		return &ExtListsVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtListsBuilder from JSON parameters
+func (b *ExtListsBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extListsOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtListsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extListsOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Lists", func() OptionBuilder {
+		return &ExtListsBuilder{}
+	})
+}
+
+// Math returns a cel.EnvOption to configure namespaced math helper macros and
+// functions.
+// Note, all macros use the 'math' namespace; however, at the time of macro
+// expansion the namespace looks just like any other identifier. If you are
+// currently using a variable named 'math', the macro will likely work just as
+// intended; however, there is some chance for collision.
+// # Math.Greatest
+// Returns the greatest valued number present in the arguments to the macro.
+// Greatest is a variable argument count macro which must take at least one
+// argument. Simple numeric and list literals are supported as valid argument
+// types; however, other literals will be flagged as errors during macro
+// expansion. If the argument expression does not resolve to a numeric or
+// list(numeric) type during type-checking, or during runtime then an error
+// will be produced. If a list argument is empty, this too will produce an
+// error.
+// math.greatest(<arg>, ...) -> <double|int|uint>
+// Examples:
+// math.greatest(1)      // 1
+// math.greatest(1u, 2u) // 2u
+// math.greatest(-42.0, -21.5, -100.0)   // -21.5
+// math.greatest([-42.0, -21.5, -100.0]) // -21.5
+// math.greatest(numbers) // numbers must be list(numeric)
+// math.greatest()         // parse error
+// math.greatest('string') // parse error
+// math.greatest(a, b)     // check-time error if a or b is non-numeric
+// math.greatest(dyn('string')) // runtime error
+// # Math.Least
+// Returns the least valued number present in the arguments to the macro.
+// Least is a variable argument count macro which must take at least one
+// argument. Simple numeric and list literals are supported as valid argument
+// types; however, other literals will be flagged as errors during macro
+// expansion. If the argument expression does not resolve to a numeric or
+// list(numeric) type during type-checking, or during runtime then an error
+// will be produced. If a list argument is empty, this too will produce an
+// error.
+// math.least(<arg>, ...) -> <double|int|uint>
+// Examples:
+// math.least(1)      // 1
+// math.least(1u, 2u) // 1u
+// math.least(-42.0, -21.5, -100.0)   // -100.0
+// math.least([-42.0, -21.5, -100.0]) // -100.0
+// math.least(numbers) // numbers must be list(numeric)
+// math.least()         // parse error
+// math.least('string') // parse error
+// math.least(a, b)     // check-time error if a or b is non-numeric
+// math.least(dyn('string')) // runtime error
+// # Math.BitOr
+// Introduced at version: 1
+// Performs a bitwise-OR operation over two int or uint values.
+// math.bitOr(<int>, <int>) -> <int>
+// math.bitOr(<uint>, <uint>) -> <uint>
+// Examples:
+// math.bitOr(1u, 2u)    // returns 3u
+// math.bitOr(-2, -4)    // returns -2
+// # Math.BitAnd
+// Introduced at version: 1
+// Performs a bitwise-AND operation over two int or uint values.
+// math.bitAnd(<int>, <int>) -> <int>
+// math.bitAnd(<uint>, <uint>) -> <uint>
+// Examples:
+// math.bitAnd(3u, 2u)   // return 2u
+// math.bitAnd(3, 5)     // returns 3
+// math.bitAnd(-3, -5)   // returns -7
+// # Math.BitXor
+// Introduced at version: 1
+// math.bitXor(<int>, <int>) -> <int>
+// math.bitXor(<uint>, <uint>) -> <uint>
+// Performs a bitwise-XOR operation over two int or uint values.
+// Examples:
+// math.bitXor(3u, 5u) // returns 6u
+// math.bitXor(1, 3)   // returns 2
+// # Math.BitNot
+// Introduced at version: 1
+// Function which accepts a single int or uint and performs a bitwise-NOT
+// ones-complement of the given binary value.
+// math.bitNot(<int>) -> <int>
+// math.bitNot(<uint>) -> <uint>
+// Examples
+// math.bitNot(1)  // returns -1
+// math.bitNot(-1) // return 0
+// math.bitNot(0u) // returns 18446744073709551615u
+// # Math.BitShiftLeft
+// Introduced at version: 1
+// Perform a left shift of bits on the first parameter, by the amount of bits
+// specified in the second parameter. The first parameter is either a uint or
+// an int. The second parameter must be an int.
+// When the second parameter is 64 or greater, 0 will be always be returned
+// since the number of bits shifted is greater than or equal to the total bit
+// length of the number being shifted. Negative valued bit shifts will result
+// in a runtime error.
+// math.bitShiftLeft(<int>, <int>) -> <int>
+// math.bitShiftLeft(<uint>, <int>) -> <uint>
+// Examples
+// math.bitShiftLeft(1, 2)    // returns 4
+// math.bitShiftLeft(-1, 2)   // returns -4
+// math.bitShiftLeft(1u, 2)   // return 4u
+// math.bitShiftLeft(1u, 200) // returns 0u
+// # Math.BitShiftRight
+// Introduced at version: 1
+// Perform a right shift of bits on the first parameter, by the amount of bits
+// specified in the second parameter. The first parameter is either a uint or
+// an int. The second parameter must be an int.
+// When the second parameter is 64 or greater, 0 will always be returned since
+// the number of bits shifted is greater than or equal to the total bit length
+// of the number being shifted. Negative valued bit shifts will result in a
+// runtime error.
+// The sign bit extension will not be preserved for this operation: vacant bits
+// on the left are filled with 0.
+// math.bitShiftRight(<int>, <int>) -> <int>
+// math.bitShiftRight(<uint>, <int>) -> <uint>
+// Examples
+// math.bitShiftRight(1024, 2)    // returns 256
+// math.bitShiftRight(1024u, 2)   // returns 256u
+// math.bitShiftRight(1024u, 64)  // returns 0u
+// # Math.Ceil
+// Introduced at version: 1
+// Compute the ceiling of a double value.
+// math.ceil(<double>) -> <double>
+// Examples:
+// math.ceil(1.2)   // returns 2.0
+// math.ceil(-1.2)  // returns -1.0
+// # Math.Floor
+// Introduced at version: 1
+// Compute the floor of a double value.
+// math.floor(<double>) -> <double>
+// Examples:
+// math.floor(1.2)   // returns 1.0
+// math.floor(-1.2)  // returns -2.0
+// # Math.Round
+// Introduced at version: 1
+// Rounds the double value to the nearest whole number with ties rounding away
+// from zero, e.g. 1.5 -> 2.0, -1.5 -> -2.0.
+// math.round(<double>) -> <double>
+// Examples:
+// math.round(1.2)  // returns 1.0
+// math.round(1.5)  // returns 2.0
+// math.round(-1.5) // returns -2.0
+// # Math.Trunc
+// Introduced at version: 1
+// Truncates the fractional portion of the double value.
+// math.trunc(<double>) -> <double>
+// Examples:
+// math.trunc(-1.3)  // returns -1.0
+// math.trunc(1.3)   // returns 1.0
+// # Math.Abs
+// Introduced at version: 1
+// Returns the absolute value of the numeric type provided as input. If the
+// value is NaN, the output is NaN. If the input is int64 min, the function
+// will result in an overflow error.
+// math.abs(<double>) -> <double>
+// math.abs(<int>) -> <int>
+// math.abs(<uint>) -> <uint>
+// Examples:
+// math.abs(-1)  // returns 1
+// math.abs(1)   // returns 1
+// math.abs(-9223372036854775808) // overflow error
+// # Math.Sign
+// Introduced at version: 1
+// Returns the sign of the numeric type, either -1, 0, 1 as an int, double, or
+// uint depending on the overload. For floating point values, if NaN is
+// provided as input, the output is also NaN. The implementation does not
+// differentiate between positive and negative zero.
+// math.sign(<double>) -> <double>
+// math.sign(<int>) -> <int>
+// math.sign(<uint>) -> <uint>
+// Examples:
+// math.sign(-42) // returns -1
+// math.sign(0)   // returns 0
+// math.sign(42)  // returns 1
+// # Math.IsInf
+// Introduced at version: 1
+// Returns true if the input double value is -Inf or +Inf.
+// math.isInf(<double>) -> <bool>
+// Examples:
+// math.isInf(1.0/0.0)  // returns true
+// math.isInf(1.2)      // returns false
+// # Math.IsNaN
+// Introduced at version: 1
+// Returns true if the input double value is NaN, false otherwise.
+// math.isNaN(<double>) -> <bool>
+// Examples:
+// math.isNaN(0.0/0.0)  // returns true
+// math.isNaN(1.2)      // returns false
+// # Math.IsFinite
+// Introduced at version: 1
+// Returns true if the value is a finite number. Equivalent in behavior to:
+// !math.isNaN(double) && !math.isInf(double)
+// math.isFinite(<double>) -> <bool>
+// Examples:
+// math.isFinite(0.0/0.0)  // returns false
+// math.isFinite(1.2)      // returns true
+// # Math.Sqrt
+// Introduced at version: 2
+// Returns the square root of the given input as double
+// Throws error for negative or non-numeric inputs
+// math.sqrt(<double>) -> <double>
+// math.sqrt(<int>) -> <double>
+// math.sqrt(<uint>) -> <double>
+// Examples:
+// math.sqrt(81) // returns 9.0
+// math.sqrt(985.25)   // returns 31.388692231439016
+// math.sqrt(-15)  // returns NaN
+type ExtMathBuilder struct {
+	Options []ext.MathOption
+}
+
+// Name returns the name of this option
+func (b *ExtMathBuilder) Name() string {
+	return "ext.Math"
+}
+
+// Description returns the description of this option
+func (b *ExtMathBuilder) Description() string {
+	return "Math returns a cel.EnvOption to configure namespaced math helper macros and\nfunctions.\n\nNote, all macros use the 'math' namespace; however, at the time of macro\nexpansion the namespace looks just like any other identifier. If you are\ncurrently using a variable named 'math', the macro will likely work just as\nintended; however, there is some chance for collision.\n\n# Math.Greatest\n\nReturns the greatest valued number present in the arguments to the macro.\n\nGreatest is a variable argument count macro which must take at least one\nargument. Simple numeric and list literals are supported as valid argument\ntypes; however, other literals will be flagged as errors during macro\nexpansion. If the argument expression does not resolve to a numeric or\nlist(numeric) type during type-checking, or during runtime then an error\nwill be produced. If a list argument is empty, this too will produce an\nerror.\n\n\tmath.greatest(<arg>, ...) -> <double|int|uint>\n\nExamples:\n\n\tmath.greatest(1)      // 1\n\tmath.greatest(1u, 2u) // 2u\n\tmath.greatest(-42.0, -21.5, -100.0)   // -21.5\n\tmath.greatest([-42.0, -21.5, -100.0]) // -21.5\n\tmath.greatest(numbers) // numbers must be list(numeric)\n\n\tmath.greatest()         // parse error\n\tmath.greatest('string') // parse error\n\tmath.greatest(a, b)     // check-time error if a or b is non-numeric\n\tmath.greatest(dyn('string')) // runtime error\n\n# Math.Least\n\nReturns the least valued number present in the arguments to the macro.\n\nLeast is a variable argument count macro which must take at least one\nargument. Simple numeric and list literals are supported as valid argument\ntypes; however, other literals will be flagged as errors during macro\nexpansion. If the argument expression does not resolve to a numeric or\nlist(numeric) type during type-checking, or during runtime then an error\nwill be produced. If a list argument is empty, this too will produce an\nerror.\n\n\tmath.least(<arg>, ...) -> <double|int|uint>\n\nExamples:\n\n\tmath.least(1)      // 1\n\tmath.least(1u, 2u) // 1u\n\tmath.least(-42.0, -21.5, -100.0)   // -100.0\n\tmath.least([-42.0, -21.5, -100.0]) // -100.0\n\tmath.least(numbers) // numbers must be list(numeric)\n\n\tmath.least()         // parse error\n\tmath.least('string') // parse error\n\tmath.least(a, b)     // check-time error if a or b is non-numeric\n\tmath.least(dyn('string')) // runtime error\n\n# Math.BitOr\n\nIntroduced at version: 1\n\nPerforms a bitwise-OR operation over two int or uint values.\n\n\tmath.bitOr(<int>, <int>) -> <int>\n\tmath.bitOr(<uint>, <uint>) -> <uint>\n\nExamples:\n\n\tmath.bitOr(1u, 2u)    // returns 3u\n\tmath.bitOr(-2, -4)    // returns -2\n\n# Math.BitAnd\n\nIntroduced at version: 1\n\nPerforms a bitwise-AND operation over two int or uint values.\n\n\tmath.bitAnd(<int>, <int>) -> <int>\n\tmath.bitAnd(<uint>, <uint>) -> <uint>\n\nExamples:\n\n\tmath.bitAnd(3u, 2u)   // return 2u\n\tmath.bitAnd(3, 5)     // returns 3\n\tmath.bitAnd(-3, -5)   // returns -7\n\n# Math.BitXor\n\nIntroduced at version: 1\n\n\tmath.bitXor(<int>, <int>) -> <int>\n\tmath.bitXor(<uint>, <uint>) -> <uint>\n\nPerforms a bitwise-XOR operation over two int or uint values.\n\nExamples:\n\n\tmath.bitXor(3u, 5u) // returns 6u\n\tmath.bitXor(1, 3)   // returns 2\n\n# Math.BitNot\n\nIntroduced at version: 1\n\nFunction which accepts a single int or uint and performs a bitwise-NOT\nones-complement of the given binary value.\n\n\tmath.bitNot(<int>) -> <int>\n\tmath.bitNot(<uint>) -> <uint>\n\nExamples\n\n\tmath.bitNot(1)  // returns -1\n\tmath.bitNot(-1) // return 0\n\tmath.bitNot(0u) // returns 18446744073709551615u\n\n# Math.BitShiftLeft\n\nIntroduced at version: 1\n\nPerform a left shift of bits on the first parameter, by the amount of bits\nspecified in the second parameter. The first parameter is either a uint or\nan int. The second parameter must be an int.\n\nWhen the second parameter is 64 or greater, 0 will be always be returned\nsince the number of bits shifted is greater than or equal to the total bit\nlength of the number being shifted. Negative valued bit shifts will result\nin a runtime error.\n\n\tmath.bitShiftLeft(<int>, <int>) -> <int>\n\tmath.bitShiftLeft(<uint>, <int>) -> <uint>\n\nExamples\n\n\tmath.bitShiftLeft(1, 2)    // returns 4\n\tmath.bitShiftLeft(-1, 2)   // returns -4\n\tmath.bitShiftLeft(1u, 2)   // return 4u\n\tmath.bitShiftLeft(1u, 200) // returns 0u\n\n# Math.BitShiftRight\n\nIntroduced at version: 1\n\nPerform a right shift of bits on the first parameter, by the amount of bits\nspecified in the second parameter. The first parameter is either a uint or\nan int. The second parameter must be an int.\n\nWhen the second parameter is 64 or greater, 0 will always be returned since\nthe number of bits shifted is greater than or equal to the total bit length\nof the number being shifted. Negative valued bit shifts will result in a\nruntime error.\n\nThe sign bit extension will not be preserved for this operation: vacant bits\non the left are filled with 0.\n\n\tmath.bitShiftRight(<int>, <int>) -> <int>\n\tmath.bitShiftRight(<uint>, <int>) -> <uint>\n\nExamples\n\n\tmath.bitShiftRight(1024, 2)    // returns 256\n\tmath.bitShiftRight(1024u, 2)   // returns 256u\n\tmath.bitShiftRight(1024u, 64)  // returns 0u\n\n# Math.Ceil\n\nIntroduced at version: 1\n\nCompute the ceiling of a double value.\n\n\tmath.ceil(<double>) -> <double>\n\nExamples:\n\n\tmath.ceil(1.2)   // returns 2.0\n\tmath.ceil(-1.2)  // returns -1.0\n\n# Math.Floor\n\nIntroduced at version: 1\n\nCompute the floor of a double value.\n\n\tmath.floor(<double>) -> <double>\n\nExamples:\n\n\tmath.floor(1.2)   // returns 1.0\n\tmath.floor(-1.2)  // returns -2.0\n\n# Math.Round\n\nIntroduced at version: 1\n\nRounds the double value to the nearest whole number with ties rounding away\nfrom zero, e.g. 1.5 -> 2.0, -1.5 -> -2.0.\n\n\tmath.round(<double>) -> <double>\n\nExamples:\n\n\tmath.round(1.2)  // returns 1.0\n\tmath.round(1.5)  // returns 2.0\n\tmath.round(-1.5) // returns -2.0\n\n# Math.Trunc\n\nIntroduced at version: 1\n\nTruncates the fractional portion of the double value.\n\n\tmath.trunc(<double>) -> <double>\n\nExamples:\n\n\tmath.trunc(-1.3)  // returns -1.0\n\tmath.trunc(1.3)   // returns 1.0\n\n# Math.Abs\n\nIntroduced at version: 1\n\nReturns the absolute value of the numeric type provided as input. If the\nvalue is NaN, the output is NaN. If the input is int64 min, the function\nwill result in an overflow error.\n\n\tmath.abs(<double>) -> <double>\n\tmath.abs(<int>) -> <int>\n\tmath.abs(<uint>) -> <uint>\n\nExamples:\n\n\tmath.abs(-1)  // returns 1\n\tmath.abs(1)   // returns 1\n\tmath.abs(-9223372036854775808) // overflow error\n\n# Math.Sign\n\nIntroduced at version: 1\n\nReturns the sign of the numeric type, either -1, 0, 1 as an int, double, or\nuint depending on the overload. For floating point values, if NaN is\nprovided as input, the output is also NaN. The implementation does not\ndifferentiate between positive and negative zero.\n\n\tmath.sign(<double>) -> <double>\n\tmath.sign(<int>) -> <int>\n\tmath.sign(<uint>) -> <uint>\n\nExamples:\n\n\tmath.sign(-42) // returns -1\n\tmath.sign(0)   // returns 0\n\tmath.sign(42)  // returns 1\n\n# Math.IsInf\n\nIntroduced at version: 1\n\nReturns true if the input double value is -Inf or +Inf.\n\n\tmath.isInf(<double>) -> <bool>\n\nExamples:\n\n\tmath.isInf(1.0/0.0)  // returns true\n\tmath.isInf(1.2)      // returns false\n\n# Math.IsNaN\n\nIntroduced at version: 1\n\nReturns true if the input double value is NaN, false otherwise.\n\n\tmath.isNaN(<double>) -> <bool>\n\nExamples:\n\n\tmath.isNaN(0.0/0.0)  // returns true\n\tmath.isNaN(1.2)      // returns false\n\n# Math.IsFinite\n\nIntroduced at version: 1\n\nReturns true if the value is a finite number. Equivalent in behavior to:\n!math.isNaN(double) && !math.isInf(double)\n\n\tmath.isFinite(<double>) -> <bool>\n\nExamples:\n\n\tmath.isFinite(0.0/0.0)  // returns false\n\tmath.isFinite(1.2)      // returns true\n\n# Math.Sqrt\n\nIntroduced at version: 2\n\nReturns the square root of the given input as double\nThrows error for negative or non-numeric inputs\n\n\tmath.sqrt(<double>) -> <double>\n\tmath.sqrt(<int>) -> <double>\n\tmath.sqrt(<uint>) -> <double>\n\nExamples:\n\n\tmath.sqrt(81) // returns 9.0\n\tmath.sqrt(985.25)   // returns 31.388692231439016\n     math.sqrt(-15)  // returns NaN"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtMathBuilder) SetOptions(options []ext.MathOption) *ExtMathBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtMathBuilder) Build() (cel.EnvOption, error) {
+	return ext.Math(b.Options...), nil
+}
+
+// MathVersion sets the library version for math extensions.
+type ExtMathVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtMathVersionBuilder) Description() string {
+	return "MathVersion sets the library version for math extensions."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtMathVersionBuilder) SetVersion(version uint32) *ExtMathVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.MathOption
+func (b *ExtMathVersionBuilder) Build() (ext.MathOption, error) {
+	return ext.MathVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtMathVersionBuilder from JSON parameters
+func (b *ExtMathVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtMathVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extMathOptionsBuilders maps the sub-options accepted by the options parameter of ext.Math to their builders
+var extMathOptionsBuilders = map[string]func() SubOptionBuilder[ext.MathOption]{
+	"MathVersion": func() SubOptionBuilder[ext.MathOption] {
+		return &ExtMathVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtMathBuilder from JSON parameters
+func (b *ExtMathBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extMathOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtMathBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extMathOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Math", func() OptionBuilder {
+		return &ExtMathBuilder{}
+	})
+}
+
+// Protos returns a cel.EnvOption to configure extended macros and functions for
+// proto manipulation.
+// Note, all macros use the 'proto' namespace; however, at the time of macro
+// expansion the namespace looks just like any other identifier. If you are
+// currently using a variable named 'proto', the macro will likely work just as
+// intended; however, there is some chance for collision.
+// # Protos.GetExt
+// Macro which generates a select expression that retrieves an extension field
+// from the input proto2 syntax message. If the field is not set, the default
+// value forthe extension field is returned according to safe-traversal semantics.
+// proto.getExt(<msg>, <fully.qualified.extension.name>) -> <field-type>
+// Examples:
+// proto.getExt(msg, google.expr.proto2.test.int32_ext) // returns int value
+// # Protos.HasExt
+// Macro which generates a test-only select expression that determines whether
+// an extension field is set on a proto2 syntax message.
+// proto.hasExt(<msg>, <fully.qualified.extension.name>) -> <bool>
+// Examples:
+// proto.hasExt(msg, google.expr.proto2.test.int32_ext) // returns true || false
+type ExtProtosBuilder struct {
+	Options []ext.ProtosOption
+}
+
+// Name returns the name of this option
+func (b *ExtProtosBuilder) Name() string {
+	return "ext.Protos"
+}
+
+// Description returns the description of this option
+func (b *ExtProtosBuilder) Description() string {
+	return "Protos returns a cel.EnvOption to configure extended macros and functions for\nproto manipulation.\n\nNote, all macros use the 'proto' namespace; however, at the time of macro\nexpansion the namespace looks just like any other identifier. If you are\ncurrently using a variable named 'proto', the macro will likely work just as\nintended; however, there is some chance for collision.\n\n# Protos.GetExt\n\nMacro which generates a select expression that retrieves an extension field\nfrom the input proto2 syntax message. If the field is not set, the default\nvalue forthe extension field is returned according to safe-traversal semantics.\n\n\tproto.getExt(<msg>, <fully.qualified.extension.name>) -> <field-type>\n\nExamples:\n\n\tproto.getExt(msg, google.expr.proto2.test.int32_ext) // returns int value\n\n# Protos.HasExt\n\nMacro which generates a test-only select expression that determines whether\nan extension field is set on a proto2 syntax message.\n\n\tproto.hasExt(<msg>, <fully.qualified.extension.name>) -> <bool>\n\nExamples:\n\n\tproto.hasExt(msg, google.expr.proto2.test.int32_ext) // returns true || false"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtProtosBuilder) SetOptions(options []ext.ProtosOption) *ExtProtosBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtProtosBuilder) Build() (cel.EnvOption, error) {
+	return ext.Protos(b.Options...), nil
+}
+
+// ProtosVersion sets the library version for extensions for protobuf utilities.
+type ExtProtosVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtProtosVersionBuilder) Description() string {
+	return "ProtosVersion sets the library version for extensions for protobuf utilities."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtProtosVersionBuilder) SetVersion(version uint32) *ExtProtosVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.ProtosOption
+func (b *ExtProtosVersionBuilder) Build() (ext.ProtosOption, error) {
+	return ext.ProtosVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtProtosVersionBuilder from JSON parameters
+func (b *ExtProtosVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtProtosVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extProtosOptionsBuilders maps the sub-options accepted by the options parameter of ext.Protos to their builders
+var extProtosOptionsBuilders = map[string]func() SubOptionBuilder[ext.ProtosOption]{
+	"ProtosVersion": func() SubOptionBuilder[ext.ProtosOption] {
+		return &ExtProtosVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtProtosBuilder from JSON parameters
+func (b *ExtProtosBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extProtosOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtProtosBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extProtosOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Protos", func() OptionBuilder {
+		return &ExtProtosBuilder{}
+	})
+}
+
+// Regex returns a cel.EnvOption to configure extended functions for regular
+// expression operations.
+// Note: all functions use the 'regex' namespace. If you are
+// currently using a variable named 'regex', the functions will likely work as
+// intended, however there is some chance for collision.
+// This library depends on the CEL optional type. Please ensure that the
+// cel.OptionalTypes() is enabled when using regex extensions.
+// # Replace
+// The `regex.replace` function replaces all non-overlapping substring of a regex
+// pattern in the target string with a replacement string. Optionally, you can
+// limit the number of replacements by providing a count argument. When the count
+// is a negative number, the function acts as replace all. Only numeric (\N)
+// capture group references are supported in the replacement string, with
+// validation for correctness. Backslashed-escaped digits (\1 to \9) within the
+// replacement argument can be used to insert text matching the corresponding
+// parenthesized group in the regexp pattern. An error will be thrown for invalid
+// regex or replace string.
+// regex.replace(target: string, pattern: string, replacement: string) -> string
+// regex.replace(target: string, pattern: string, replacement: string, count: int) -> string
+// Examples:
+// regex.replace('hello world hello', 'hello', 'hi') == 'hi world hi'
+// regex.replace('banana', 'a', 'x', 0) == 'banana'
+// regex.replace('banana', 'a', 'x', 1) == 'bxnana'
+// regex.replace('banana', 'a', 'x', 2) == 'bxnxna'
+// regex.replace('banana', 'a', 'x', -12) == 'bxnxnx'
+// regex.replace('foo bar', '(fo)o (ba)r', r'\2 \1') == 'ba fo'
+// regex.replace('test', '(.)', r'\2') \\ Runtime Error invalid replace string
+// regex.replace('foo bar', '(', '$2 $1') \\ Runtime Error invalid regex string
+// regex.replace('id=123', r'id=(?P<value>\d+)', r'value: \values') \\ Runtime Error invalid replace string
+// # Extract
+// The `regex.extract` function returns the first match of a regex pattern in a
+// string. If no match is found, it returns an optional none value. An error will
+// be thrown for invalid regex or for multiple capture groups.
+// regex.extract(target: string, pattern: string) -> optional<string>
+// Examples:
+// regex.extract('hello world', 'hello(.*)') == optional.of(' world')
+// regex.extract('item-A, item-B', 'item-(\\w+)') == optional.of('A')
+// regex.extract('HELLO', 'hello') == optional.empty()
+// regex.extract('testuser@testdomain', '(.*)@([^.]*)') // Runtime Error multiple capture group
+// # Extract All
+// The `regex.extractAll` function returns a list of all matches of a regex
+// pattern in a target string. If no matches are found, it returns an empty list. An error will
+// be thrown for invalid regex or for multiple capture groups.
+// regex.extractAll(target: string, pattern: string) -> list<string>
+// Examples:
+// regex.extractAll('id:123, id:456', 'id:\\d+') == ['id:123', 'id:456']
+// regex.extractAll('id:123, id:456', 'assa') == []
+// regex.extractAll('testuser@testdomain', '(.*)@([^.]*)') // Runtime Error multiple capture group
+type ExtRegexBuilder struct {
+	Options []ext.RegexOptions
+}
+
+// Name returns the name of this option
+func (b *ExtRegexBuilder) Name() string {
+	return "ext.Regex"
+}
+
+// Description returns the description of this option
+func (b *ExtRegexBuilder) Description() string {
+	return "Regex returns a cel.EnvOption to configure extended functions for regular\nexpression operations.\n\nNote: all functions use the 'regex' namespace. If you are\ncurrently using a variable named 'regex', the functions will likely work as\nintended, however there is some chance for collision.\n\nThis library depends on the CEL optional type. Please ensure that the\ncel.OptionalTypes() is enabled when using regex extensions.\n\n# Replace\n\nThe `regex.replace` function replaces all non-overlapping substring of a regex\npattern in the target string with a replacement string. Optionally, you can\nlimit the number of replacements by providing a count argument. When the count\nis a negative number, the function acts as replace all. Only numeric (\\N)\ncapture group references are supported in the replacement string, with\nvalidation for correctness. Backslashed-escaped digits (\\1 to \\9) within the\nreplacement argument can be used to insert text matching the corresponding\nparenthesized group in the regexp pattern. An error will be thrown for invalid\nregex or replace string.\n\n\tregex.replace(target: string, pattern: string, replacement: string) -> string\n\tregex.replace(target: string, pattern: string, replacement: string, count: int) -> string\n\nExamples:\n\n\tregex.replace('hello world hello', 'hello', 'hi') == 'hi world hi'\n\tregex.replace('banana', 'a', 'x', 0) == 'banana'\n\tregex.replace('banana', 'a', 'x', 1) == 'bxnana'\n\tregex.replace('banana', 'a', 'x', 2) == 'bxnxna'\n\tregex.replace('banana', 'a', 'x', -12) == 'bxnxnx'\n\tregex.replace('foo bar', '(fo)o (ba)r', r'\\2 \\1') == 'ba fo'\n\tregex.replace('test', '(.)', r'\\2') \\\\ Runtime Error invalid replace string\n\tregex.replace('foo bar', '(', '$2 $1') \\\\ Runtime Error invalid regex string\n\tregex.replace('id=123', r'id=(?P<value>\\d+)', r'value: \\values') \\\\ Runtime Error invalid replace string\n\n# Extract\n\nThe `regex.extract` function returns the first match of a regex pattern in a\nstring. If no match is found, it returns an optional none value. An error will\nbe thrown for invalid regex or for multiple capture groups.\n\n\tregex.extract(target: string, pattern: string) -> optional<string>\n\nExamples:\n\n\tregex.extract('hello world', 'hello(.*)') == optional.of(' world')\n\tregex.extract('item-A, item-B', 'item-(\\\\w+)') == optional.of('A')\n\tregex.extract('HELLO', 'hello') == optional.empty()\n\tregex.extract('testuser@testdomain', '(.*)@([^.]*)') // Runtime Error multiple capture group\n\n# Extract All\n\nThe `regex.extractAll` function returns a list of all matches of a regex\npattern in a target string. If no matches are found, it returns an empty list. An error will\nbe thrown for invalid regex or for multiple capture groups.\n\n\tregex.extractAll(target: string, pattern: string) -> list<string>\n\nExamples:\n\n\tregex.extractAll('id:123, id:456', 'id:\\\\d+') == ['id:123', 'id:456']\n\tregex.extractAll('id:123, id:456', 'assa') == []\n\tregex.extractAll('testuser@testdomain', '(.*)@([^.]*)') // Runtime Error multiple capture group"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtRegexBuilder) SetOptions(options []ext.RegexOptions) *ExtRegexBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtRegexBuilder) Build() (cel.EnvOption, error) {
+	return ext.Regex(b.Options...), nil
+}
+
+// RegexVersion configures the version of the Regex library definitions to use. See [Regex] for supported values.
+type ExtRegexVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtRegexVersionBuilder) Description() string {
+	return "RegexVersion configures the version of the Regex library definitions to use. See [Regex] for supported values."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtRegexVersionBuilder) SetVersion(version uint32) *ExtRegexVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.RegexOptions
+func (b *ExtRegexVersionBuilder) Build() (ext.RegexOptions, error) {
+	return ext.RegexVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtRegexVersionBuilder from JSON parameters
+func (b *ExtRegexVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtRegexVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extRegexOptionsBuilders maps the sub-options accepted by the options parameter of ext.Regex to their builders
+var extRegexOptionsBuilders = map[string]func() SubOptionBuilder[ext.RegexOptions]{
+	"RegexVersion": func() SubOptionBuilder[ext.RegexOptions] {
+		return &ExtRegexVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtRegexBuilder from JSON parameters
+func (b *ExtRegexBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extRegexOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtRegexBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extRegexOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Regex", func() OptionBuilder {
+		return &ExtRegexBuilder{}
+	})
+}
+
+// Sets returns a cel.EnvOption to configure namespaced set relationship
+// functions.
+// There is no set type within CEL, and while one may be introduced in the
+// future, there are cases where a `list` type is known to behave like a set.
+// For such cases, this library provides some basic functionality for
+// determining set containment, equivalence, and intersection.
+// # Sets.Contains
+// Returns whether the first list argument contains all elements in the second
+// list argument. The list may contain elements of any type and standard CEL
+// equality is used to determine whether a value exists in both lists. If the
+// second list is empty, the result will always return true.
+// sets.contains(list(T), list(T)) -> bool
+// Examples:
+// sets.contains([], []) // true
+// sets.contains([], [1]) // false
+// sets.contains([1, 2, 3, 4], [2, 3]) // true
+// sets.contains([1, 2.0, 3u], [1.0, 2u, 3]) // true
+// # Sets.Equivalent
+// Returns whether the first and second list are set equivalent. Lists are set
+// equivalent if for every item in the first list, there is an element in the
+// second which is equal. The lists may not be of the same size as they do not
+// guarantee the elements within them are unique, so size does not factor into
+// the computation.
+// Examples:
+// sets.equivalent([], []) // true
+// sets.equivalent([1], [1, 1]) // true
+// sets.equivalent([1], [1u, 1.0]) // true
+// sets.equivalent([1, 2, 3], [3u, 2.0, 1]) // true
+// # Sets.Intersects
+// Returns whether the first list has at least one element whose value is equal
+// to an element in the second list. If either list is empty, the result will
+// be false.
+// Examples:
+// sets.intersects([1], []) // false
+// sets.intersects([1], [1, 2]) // true
+// sets.intersects([[1], [2, 3]], [[1, 2], [2, 3.0]]) // true
+type ExtSetsBuilder struct {
+	Options []ext.SetsOption
+}
+
+// Name returns the name of this option
+func (b *ExtSetsBuilder) Name() string {
+	return "ext.Sets"
+}
+
+// Description returns the description of this option
+func (b *ExtSetsBuilder) Description() string {
+	return "Sets returns a cel.EnvOption to configure namespaced set relationship\nfunctions.\n\nThere is no set type within CEL, and while one may be introduced in the\nfuture, there are cases where a `list` type is known to behave like a set.\nFor such cases, this library provides some basic functionality for\ndetermining set containment, equivalence, and intersection.\n\n# Sets.Contains\n\nReturns whether the first list argument contains all elements in the second\nlist argument. The list may contain elements of any type and standard CEL\nequality is used to determine whether a value exists in both lists. If the\nsecond list is empty, the result will always return true.\n\n\tsets.contains(list(T), list(T)) -> bool\n\nExamples:\n\n\tsets.contains([], []) // true\n\tsets.contains([], [1]) // false\n\tsets.contains([1, 2, 3, 4], [2, 3]) // true\n\tsets.contains([1, 2.0, 3u], [1.0, 2u, 3]) // true\n\n# Sets.Equivalent\n\nReturns whether the first and second list are set equivalent. Lists are set\nequivalent if for every item in the first list, there is an element in the\nsecond which is equal. The lists may not be of the same size as they do not\nguarantee the elements within them are unique, so size does not factor into\nthe computation.\n\nExamples:\n\n\tsets.equivalent([], []) // true\n\tsets.equivalent([1], [1, 1]) // true\n\tsets.equivalent([1], [1u, 1.0]) // true\n\tsets.equivalent([1, 2, 3], [3u, 2.0, 1]) // true\n\n# Sets.Intersects\n\nReturns whether the first list has at least one element whose value is equal\nto an element in the second list. If either list is empty, the result will\nbe false.\n\nExamples:\n\n\tsets.intersects([1], []) // false\n\tsets.intersects([1], [1, 2]) // true\n\tsets.intersects([[1], [2, 3]], [[1, 2], [2, 3.0]]) // true"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtSetsBuilder) SetOptions(options []ext.SetsOption) *ExtSetsBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtSetsBuilder) Build() (cel.EnvOption, error) {
+	return ext.Sets(b.Options...), nil
+}
+
+// SetsVersion sets the library version for set extensions.
+type ExtSetsVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtSetsVersionBuilder) Description() string {
+	return "SetsVersion sets the library version for set extensions."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtSetsVersionBuilder) SetVersion(version uint32) *ExtSetsVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.SetsOption
+func (b *ExtSetsVersionBuilder) Build() (ext.SetsOption, error) {
+	return ext.SetsVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtSetsVersionBuilder from JSON parameters
+func (b *ExtSetsVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtSetsVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extSetsOptionsBuilders maps the sub-options accepted by the options parameter of ext.Sets to their builders
+var extSetsOptionsBuilders = map[string]func() SubOptionBuilder[ext.SetsOption]{
+	"SetsVersion": func() SubOptionBuilder[ext.SetsOption] {
+		return &ExtSetsVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtSetsBuilder from JSON parameters
+func (b *ExtSetsBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extSetsOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtSetsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extSetsOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Sets", func() OptionBuilder {
+		return &ExtSetsBuilder{}
+	})
+}
+
+// Strings returns a cel.EnvOption to configure extended functions for string manipulation.
+// As a general note, all indices are zero-based.
+// # CharAt
+// Returns the character at the given position. If the position is negative, or greater than
+// the length of the string, the function will produce an error:
+// <string>.charAt(<int>) -> <string>
+// Examples:
+// 'hello'.charAt(4)  // return 'o'
+// 'hello'.charAt(5)  // return ”
+// 'hello'.charAt(-1) // error
+// # Format
+// Introduced at version: 1
+// Returns a new string with substitutions being performed, printf-style.
+// The valid formatting clauses are:
+// `%s` - substitutes a string. This can also be used on bools, lists, maps, bytes,
+// Duration and Timestamp, in addition to all numerical types (int, uint, and double).
+// Note that the dot/period decimal separator will always be used when printing a list
+// or map that contains a double, and that null can be passed (which results in the
+// string "null") in addition to types.
+// `%d` - substitutes an integer.
+// `%f` - substitutes a double with fixed-point precision. The default precision is 6, but
+// this can be adjusted. The strings `Infinity`, `-Infinity`, and `NaN` are also valid input
+// for this clause.
+// `%e` - substitutes a double in scientific notation. The default precision is 6, but this
+// can be adjusted.
+// `%b` - substitutes an integer with its equivalent binary string. Can also be used on bools.
+// `%x` - substitutes an integer with its equivalent in hexadecimal, or if given a string or
+// bytes, will output each character's equivalent in hexadecimal.
+// `%X` - same as above, but with A-F capitalized.
+// `%o` - substitutes an integer with its equivalent in octal.
+// <string>.format(<list>) -> <string>
+// Examples:
+// "this is a string: %s\nand an integer: %d".format(["str", 42]) // returns "this is a string: str\nand an integer: 42"
+// "a double substituted with %%s: %s".format([64.2]) // returns "a double substituted with %s: 64.2"
+// "string type: %s".format([type(string)]) // returns "string type: string"
+// "timestamp: %s".format([timestamp("2023-02-03T23:31:20+00:00")]) // returns "timestamp: 2023-02-03T23:31:20Z"
+// "duration: %s".format([duration("1h45m47s")]) // returns "duration: 6347s"
+// "%f".format([3.14]) // returns "3.140000"
+// "scientific notation: %e".format([2.71828]) // returns "scientific notation: 2.718280\u202f\u00d7\u202f10\u2070\u2070"
+// "5 in binary: %b".format([5]), // returns "5 in binary; 101"
+// "26 in hex: %x".format([26]), // returns "26 in hex: 1a"
+// "26 in hex (uppercase): %X".format([26]) // returns "26 in hex (uppercase): 1A"
+// "30 in octal: %o".format([30]) // returns "30 in octal: 36"
+// "a map inside a list: %s".format([[1, 2, 3, {"a": "x", "b": "y", "c": "z"}]]) // returns "a map inside a list: [1, 2, 3, {"a":"x", "b":"y", "c":"d"}]"
+// "true bool: %s - false bool: %s\nbinary bool: %b".format([true, false, true]) // returns "true bool: true - false bool: false\nbinary bool: 1"
+// Passing an incorrect type (a string to `%b`) is considered an error, as well as attempting
+// to use more formatting clauses than there are arguments (`%d %d %d` while passing two ints, for instance).
+// If compile-time checking is enabled, and the formatting string is a constant, and the argument list is a literal,
+// then letting any arguments go unused/unformatted is also considered an error.
+// # IndexOf
+// Returns the integer index of the first occurrence of the search string. If the search string is
+// not found the function returns -1.
+// The function also accepts an optional position from which to begin the substring search. If the
+// substring is the empty string, the index where the search starts is returned (zero or custom).
+// <string>.indexOf(<string>) -> <int>
+// <string>.indexOf(<string>, <int>) -> <int>
+// Examples:
+// 'hello mellow'.indexOf(”)         // returns 0
+// 'hello mellow'.indexOf('ello')     // returns 1
+// 'hello mellow'.indexOf('jello')    // returns -1
+// 'hello mellow'.indexOf(”, 2)      // returns 2
+// 'hello mellow'.indexOf('ello', 2)  // returns 7
+// 'hello mellow'.indexOf('ello', 20) // returns -1
+// 'hello mellow'.indexOf('ello', -1) // error
+// # Join
+// Returns a new string where the elements of string list are concatenated.
+// The function also accepts an optional separator which is placed between elements in the resulting string.
+// <list<string>>.join() -> <string>
+// <list<string>>.join(<string>) -> <string>
+// Examples:
+// ['hello', 'mellow'].join() // returns 'hellomellow'
+// ['hello', 'mellow'].join(' ') // returns 'hello mellow'
+// [].join() // returns ”
+// [].join('/') // returns ”
+// # LastIndexOf
+// Returns the integer index at the start of the last occurrence of the search string. If the
+// search string is not found the function returns -1.
+// The function also accepts an optional position which represents the last index to be
+// considered as the beginning of the substring match. If the substring is the empty string,
+// the index where the search starts is returned (string length or custom).
+// <string>.lastIndexOf(<string>) -> <int>
+// <string>.lastIndexOf(<string>, <int>) -> <int>
+// Examples:
+// 'hello mellow'.lastIndexOf(”)         // returns 12
+// 'hello mellow'.lastIndexOf('ello')     // returns 7
+// 'hello mellow'.lastIndexOf('jello')    // returns -1
+// 'hello mellow'.lastIndexOf('ello', 6)  // returns 1
+// 'hello mellow'.lastIndexOf('ello', 20) // returns -1
+// 'hello mellow'.lastIndexOf('ello', -1) // error
+// # LowerAscii
+// Returns a new string where all ASCII characters are lower-cased.
+// This function does not perform Unicode case-mapping for characters outside the ASCII range.
+// <string>.lowerAscii() -> <string>
+// Examples:
+// 'TacoCat'.lowerAscii()      // returns 'tacocat'
+// 'TacoCÆt Xii'.lowerAscii()  // returns 'tacocÆt xii'
+// # Strings.Quote
+// Introduced in version: 1
+// Takes the given string and makes it safe to print (without any formatting due to escape sequences).
+// If any invalid UTF-8 characters are encountered, they are replaced with \uFFFD.
+// strings.quote(<string>)
+// Examples:
+// strings.quote('single-quote with "double quote"') // returns '"single-quote with \"double quote\""'
+// strings.quote("two escape sequences \a\n") // returns '"two escape sequences \\a\\n"'
+// # Replace
+// Returns a new string based on the target, which replaces the occurrences of a search string
+// with a replacement string if present. The function accepts an optional limit on the number of
+// substring replacements to be made.
+// When the replacement limit is 0, the result is the original string. When the limit is a negative
+// number, the function behaves the same as replace all.
+// <string>.replace(<string>, <string>) -> <string>
+// <string>.replace(<string>, <string>, <int>) -> <string>
+// Examples:
+// 'hello hello'.replace('he', 'we')     // returns 'wello wello'
+// 'hello hello'.replace('he', 'we', -1) // returns 'wello wello'
+// 'hello hello'.replace('he', 'we', 1)  // returns 'wello hello'
+// 'hello hello'.replace('he', 'we', 0)  // returns 'hello hello'
+// 'hello hello'.replace(”, '_')  // returns '_h_e_l_l_o_ _h_e_l_l_o_'
+// 'hello hello'.replace('h', ”)  // returns 'ello ello'
+// # Split
+// Returns a list of strings split from the input by the given separator. The function accepts
+// an optional argument specifying a limit on the number of substrings produced by the split.
+// When the split limit is 0, the result is an empty list. When the limit is 1, the result is the
+// target string to split. When the limit is a negative number, the function behaves the same as
+// split all.
+// <string>.split(<string>) -> <list<string>>
+// <string>.split(<string>, <int>) -> <list<string>>
+// Examples:
+// 'hello hello hello'.split(' ')     // returns ['hello', 'hello', 'hello']
+// 'hello hello hello'.split(' ', 0)  // returns []
+// 'hello hello hello'.split(' ', 1)  // returns ['hello hello hello']
+// 'hello hello hello'.split(' ', 2)  // returns ['hello', 'hello hello']
+// 'hello hello hello'.split(' ', -1) // returns ['hello', 'hello', 'hello']
+// # Substring
+// Returns the substring given a numeric range corresponding to character positions. Optionally
+// may omit the trailing range for a substring from a given character position until the end of
+// a string.
+// Character offsets are 0-based with an inclusive start range and exclusive end range. It is an
+// error to specify an end range that is lower than the start range, or for either the start or end
+// index to be negative or exceed the string length.
+// <string>.substring(<int>) -> <string>
+// <string>.substring(<int>, <int>) -> <string>
+// Examples:
+// 'tacocat'.substring(4)    // returns 'cat'
+// 'tacocat'.substring(0, 4) // returns 'taco'
+// 'tacocat'.substring(-1)   // error
+// 'tacocat'.substring(2, 1) // error
+// # Trim
+// Returns a new string which removes the leading and trailing whitespace in the target string.
+// The trim function uses the Unicode definition of whitespace which does not include the
+// zero-width spaces. See: https://en.wikipedia.org/wiki/Whitespace_character#Unicode
+// <string>.trim() -> <string>
+// Examples:
+// '  \ttrim\n    '.trim() // returns 'trim'
+// # UpperAscii
+// Returns a new string where all ASCII characters are upper-cased.
+// This function does not perform Unicode case-mapping for characters outside the ASCII range.
+// <string>.upperAscii() -> <string>
+// Examples:
+// 'TacoCat'.upperAscii()      // returns 'TACOCAT'
+// 'TacoCÆt Xii'.upperAscii()  // returns 'TACOCÆT XII'
+// # Reverse
+// Introduced at version: 3
+// Returns a new string whose characters are the same as the target string, only formatted in
+// reverse order.
+// This function relies on converting strings to rune arrays in order to reverse
+// <string>.reverse() -> <string>
+// Examples:
+// 'gums'.reverse() // returns 'smug'
+// 'John Smith'.reverse() // returns 'htimS nhoJ'
+// Introduced at version: 4
+// Formatting updated to adhere to https://github.com/google/cel-spec/blob/master/doc/extensions/strings.md.
+// <string>.format(<list>) -> <string>
+type ExtStringsBuilder struct {
+	Options []ext.StringsOption
+}
+
+// Name returns the name of this option
+func (b *ExtStringsBuilder) Name() string {
+	return "ext.Strings"
+}
+
+// Description returns the description of this option
+func (b *ExtStringsBuilder) Description() string {
+	return "Strings returns a cel.EnvOption to configure extended functions for string manipulation.\nAs a general note, all indices are zero-based.\n\n# CharAt\n\nReturns the character at the given position. If the position is negative, or greater than\nthe length of the string, the function will produce an error:\n\n\t<string>.charAt(<int>) -> <string>\n\nExamples:\n\n\t'hello'.charAt(4)  // return 'o'\n\t'hello'.charAt(5)  // return ''\n\t'hello'.charAt(-1) // error\n\n# Format\n\nIntroduced at version: 1\n\nReturns a new string with substitutions being performed, printf-style.\nThe valid formatting clauses are:\n\n`%s` - substitutes a string. This can also be used on bools, lists, maps, bytes,\nDuration and Timestamp, in addition to all numerical types (int, uint, and double).\nNote that the dot/period decimal separator will always be used when printing a list\nor map that contains a double, and that null can be passed (which results in the\nstring \"null\") in addition to types.\n`%d` - substitutes an integer.\n`%f` - substitutes a double with fixed-point precision. The default precision is 6, but\nthis can be adjusted. The strings `Infinity`, `-Infinity`, and `NaN` are also valid input\nfor this clause.\n`%e` - substitutes a double in scientific notation. The default precision is 6, but this\ncan be adjusted.\n`%b` - substitutes an integer with its equivalent binary string. Can also be used on bools.\n`%x` - substitutes an integer with its equivalent in hexadecimal, or if given a string or\nbytes, will output each character's equivalent in hexadecimal.\n`%X` - same as above, but with A-F capitalized.\n`%o` - substitutes an integer with its equivalent in octal.\n\n\t<string>.format(<list>) -> <string>\n\nExamples:\n\n\t\"this is a string: %s\\nand an integer: %d\".format([\"str\", 42]) // returns \"this is a string: str\\nand an integer: 42\"\n\t\"a double substituted with %%s: %s\".format([64.2]) // returns \"a double substituted with %s: 64.2\"\n\t\"string type: %s\".format([type(string)]) // returns \"string type: string\"\n\t\"timestamp: %s\".format([timestamp(\"2023-02-03T23:31:20+00:00\")]) // returns \"timestamp: 2023-02-03T23:31:20Z\"\n\t\"duration: %s\".format([duration(\"1h45m47s\")]) // returns \"duration: 6347s\"\n\t\"%f\".format([3.14]) // returns \"3.140000\"\n\t\"scientific notation: %e\".format([2.71828]) // returns \"scientific notation: 2.718280\\u202f\\u00d7\\u202f10\\u2070\\u2070\"\n\t\"5 in binary: %b\".format([5]), // returns \"5 in binary; 101\"\n\t\"26 in hex: %x\".format([26]), // returns \"26 in hex: 1a\"\n\t\"26 in hex (uppercase): %X\".format([26]) // returns \"26 in hex (uppercase): 1A\"\n\t\"30 in octal: %o\".format([30]) // returns \"30 in octal: 36\"\n\t\"a map inside a list: %s\".format([[1, 2, 3, {\"a\": \"x\", \"b\": \"y\", \"c\": \"z\"}]]) // returns \"a map inside a list: [1, 2, 3, {\"a\":\"x\", \"b\":\"y\", \"c\":\"d\"}]\"\n\t\"true bool: %s - false bool: %s\\nbinary bool: %b\".format([true, false, true]) // returns \"true bool: true - false bool: false\\nbinary bool: 1\"\n\nPassing an incorrect type (a string to `%b`) is considered an error, as well as attempting\nto use more formatting clauses than there are arguments (`%d %d %d` while passing two ints, for instance).\nIf compile-time checking is enabled, and the formatting string is a constant, and the argument list is a literal,\nthen letting any arguments go unused/unformatted is also considered an error.\n\n# IndexOf\n\nReturns the integer index of the first occurrence of the search string. If the search string is\nnot found the function returns -1.\n\nThe function also accepts an optional position from which to begin the substring search. If the\nsubstring is the empty string, the index where the search starts is returned (zero or custom).\n\n\t<string>.indexOf(<string>) -> <int>\n\t<string>.indexOf(<string>, <int>) -> <int>\n\nExamples:\n\n\t'hello mellow'.indexOf('')         // returns 0\n\t'hello mellow'.indexOf('ello')     // returns 1\n\t'hello mellow'.indexOf('jello')    // returns -1\n\t'hello mellow'.indexOf('', 2)      // returns 2\n\t'hello mellow'.indexOf('ello', 2)  // returns 7\n\t'hello mellow'.indexOf('ello', 20) // returns -1\n\t'hello mellow'.indexOf('ello', -1) // error\n\n# Join\n\nReturns a new string where the elements of string list are concatenated.\n\nThe function also accepts an optional separator which is placed between elements in the resulting string.\n\n<list<string>>.join() -> <string>\n<list<string>>.join(<string>) -> <string>\n\nExamples:\n\n\t['hello', 'mellow'].join() // returns 'hellomellow'\n\t['hello', 'mellow'].join(' ') // returns 'hello mellow'\n\t[].join() // returns ''\n\t[].join('/') // returns ''\n\n# LastIndexOf\n\nReturns the integer index at the start of the last occurrence of the search string. If the\nsearch string is not found the function returns -1.\n\nThe function also accepts an optional position which represents the last index to be\nconsidered as the beginning of the substring match. If the substring is the empty string,\nthe index where the search starts is returned (string length or custom).\n\n\t<string>.lastIndexOf(<string>) -> <int>\n\t<string>.lastIndexOf(<string>, <int>) -> <int>\n\nExamples:\n\n\t'hello mellow'.lastIndexOf('')         // returns 12\n\t'hello mellow'.lastIndexOf('ello')     // returns 7\n\t'hello mellow'.lastIndexOf('jello')    // returns -1\n\t'hello mellow'.lastIndexOf('ello', 6)  // returns 1\n\t'hello mellow'.lastIndexOf('ello', 20) // returns -1\n\t'hello mellow'.lastIndexOf('ello', -1) // error\n\n# LowerAscii\n\nReturns a new string where all ASCII characters are lower-cased.\n\nThis function does not perform Unicode case-mapping for characters outside the ASCII range.\n\n\t<string>.lowerAscii() -> <string>\n\nExamples:\n\n\t'TacoCat'.lowerAscii()      // returns 'tacocat'\n\t'TacoCÆt Xii'.lowerAscii()  // returns 'tacocÆt xii'\n\n# Strings.Quote\n\nIntroduced in version: 1\n\nTakes the given string and makes it safe to print (without any formatting due to escape sequences).\nIf any invalid UTF-8 characters are encountered, they are replaced with \\uFFFD.\n\nstrings.quote(<string>)\n\nExamples:\n\nstrings.quote('single-quote with \"double quote\"') // returns '\"single-quote with \\\"double quote\\\"\"'\nstrings.quote(\"two escape sequences \\a\\n\") // returns '\"two escape sequences \\\\a\\\\n\"'\n\n# Replace\n\nReturns a new string based on the target, which replaces the occurrences of a search string\nwith a replacement string if present. The function accepts an optional limit on the number of\nsubstring replacements to be made.\n\nWhen the replacement limit is 0, the result is the original string. When the limit is a negative\nnumber, the function behaves the same as replace all.\n\n\t<string>.replace(<string>, <string>) -> <string>\n\t<string>.replace(<string>, <string>, <int>) -> <string>\n\nExamples:\n\n\t'hello hello'.replace('he', 'we')     // returns 'wello wello'\n\t'hello hello'.replace('he', 'we', -1) // returns 'wello wello'\n\t'hello hello'.replace('he', 'we', 1)  // returns 'wello hello'\n\t'hello hello'.replace('he', 'we', 0)  // returns 'hello hello'\n\t'hello hello'.replace('', '_')  // returns '_h_e_l_l_o_ _h_e_l_l_o_'\n\t'hello hello'.replace('h', '')  // returns 'ello ello'\n\n# Split\n\nReturns a list of strings split from the input by the given separator. The function accepts\nan optional argument specifying a limit on the number of substrings produced by the split.\n\nWhen the split limit is 0, the result is an empty list. When the limit is 1, the result is the\ntarget string to split. When the limit is a negative number, the function behaves the same as\nsplit all.\n\n\t<string>.split(<string>) -> <list<string>>\n\t<string>.split(<string>, <int>) -> <list<string>>\n\nExamples:\n\n\t'hello hello hello'.split(' ')     // returns ['hello', 'hello', 'hello']\n\t'hello hello hello'.split(' ', 0)  // returns []\n\t'hello hello hello'.split(' ', 1)  // returns ['hello hello hello']\n\t'hello hello hello'.split(' ', 2)  // returns ['hello', 'hello hello']\n\t'hello hello hello'.split(' ', -1) // returns ['hello', 'hello', 'hello']\n\n# Substring\n\nReturns the substring given a numeric range corresponding to character positions. Optionally\nmay omit the trailing range for a substring from a given character position until the end of\na string.\n\nCharacter offsets are 0-based with an inclusive start range and exclusive end range. It is an\nerror to specify an end range that is lower than the start range, or for either the start or end\nindex to be negative or exceed the string length.\n\n\t<string>.substring(<int>) -> <string>\n\t<string>.substring(<int>, <int>) -> <string>\n\nExamples:\n\n\t'tacocat'.substring(4)    // returns 'cat'\n\t'tacocat'.substring(0, 4) // returns 'taco'\n\t'tacocat'.substring(-1)   // error\n\t'tacocat'.substring(2, 1) // error\n\n# Trim\n\nReturns a new string which removes the leading and trailing whitespace in the target string.\nThe trim function uses the Unicode definition of whitespace which does not include the\nzero-width spaces. See: https://en.wikipedia.org/wiki/Whitespace_character#Unicode\n\n\t<string>.trim() -> <string>\n\nExamples:\n\n\t'  \\ttrim\\n    '.trim() // returns 'trim'\n\n# UpperAscii\n\nReturns a new string where all ASCII characters are upper-cased.\n\nThis function does not perform Unicode case-mapping for characters outside the ASCII range.\n\n\t<string>.upperAscii() -> <string>\n\nExamples:\n\n\t'TacoCat'.upperAscii()      // returns 'TACOCAT'\n\t'TacoCÆt Xii'.upperAscii()  // returns 'TACOCÆT XII'\n\n# Reverse\n\nIntroduced at version: 3\n\nReturns a new string whose characters are the same as the target string, only formatted in\nreverse order.\nThis function relies on converting strings to rune arrays in order to reverse\n\n\t<string>.reverse() -> <string>\n\nExamples:\n\n\t'gums'.reverse() // returns 'smug'\n\t'John Smith'.reverse() // returns 'htimS nhoJ'\n\nIntroduced at version: 4\n\nFormatting updated to adhere to https://github.com/google/cel-spec/blob/master/doc/extensions/strings.md.\n\n<string>.format(<list>) -> <string>"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtStringsBuilder) SetOptions(options []ext.StringsOption) *ExtStringsBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtStringsBuilder) Build() (cel.EnvOption, error) {
+	return ext.Strings(b.Options...), nil
+}
+
+// StringsLocale configures the library with the given locale. The locale tag will
+// be checked for validity at the time that EnvOptions are configured. If this option
+// is not passed, string.format will behave as if en_US was passed as the locale.
+// If StringsVersion is greater than or equal to 4, this option is ignored.
+type ExtStringsLocaleBuilder struct {
+	Locale string
+}
+
+// Description returns the description of this sub-option
+func (b *ExtStringsLocaleBuilder) Description() string {
+	return "StringsLocale configures the library with the given locale. The locale tag will\nbe checked for validity at the time that EnvOptions are configured. If this option\nis not passed, string.format will behave as if en_US was passed as the locale.\n\nIf StringsVersion is greater than or equal to 4, this option is ignored."
+}
+
+// SetLocale sets the locale parameter
+func (b *ExtStringsLocaleBuilder) SetLocale(locale string) *ExtStringsLocaleBuilder {
+	b.Locale = locale
+	return b
+}
+
+// Build creates the ext.StringsOption
+func (b *ExtStringsLocaleBuilder) Build() (ext.StringsOption, error) {
+	return ext.StringsLocale(b.Locale), nil
+}
+
+// FromJSON configures the ExtStringsLocaleBuilder from JSON parameters
+func (b *ExtStringsLocaleBuilder) FromJSON(params map[string]interface{}) error {
+	locale, err := stringParam(params, "locale")
+	if err != nil {
+		return err
+	}
+	b.SetLocale(locale)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtStringsLocaleBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"locale": map[string]interface{}{"type": "string"}}, "locale")
+}
+
+// StringsValidateFormatCalls validates type-checked ASTs to ensure that string.format() calls have
+// valid formatting clauses and valid argument types for each clause.
+// Deprecated
+type ExtStringsValidateFormatCallsBuilder struct {
+	Value bool
+}
+
+// Description returns the description of this sub-option
+func (b *ExtStringsValidateFormatCallsBuilder) Description() string {
+	return "StringsValidateFormatCalls validates type-checked ASTs to ensure that string.format() calls have\nvalid formatting clauses and valid argument types for each clause.\n\nDeprecated"
+}
+
+// SetValue sets the value parameter
+func (b *ExtStringsValidateFormatCallsBuilder) SetValue(value bool) *ExtStringsValidateFormatCallsBuilder {
+	b.Value = value
+	return b
+}
+
+// Build creates the ext.StringsOption
+func (b *ExtStringsValidateFormatCallsBuilder) Build() (ext.StringsOption, error) {
+	return ext.StringsValidateFormatCalls(b.Value), nil
+}
+
+// FromJSON configures the ExtStringsValidateFormatCallsBuilder from JSON parameters
+func (b *ExtStringsValidateFormatCallsBuilder) FromJSON(params map[string]interface{}) error {
+	value, err := boolParam(params, "value")
+	if err != nil {
+		return err
+	}
+	b.SetValue(value)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtStringsValidateFormatCallsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"value": map[string]interface{}{"type": "boolean"}}, "value")
+}
+
+// StringsVersion configures the version of the string library.
+// The version limits which functions are available. Only functions introduced
+// below or equal to the given version included in the library. If this option
+// is not set, all functions are available.
+// See the library documentation to determine which version a function was introduced.
+// If the documentation does not state which version a function was introduced, it can
+// be assumed to be introduced at version 0, when the library was first created.
+type ExtStringsVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtStringsVersionBuilder) Description() string {
+	return "StringsVersion configures the version of the string library.\n\nThe version limits which functions are available. Only functions introduced\nbelow or equal to the given version included in the library. If this option\nis not set, all functions are available.\n\nSee the library documentation to determine which version a function was introduced.\nIf the documentation does not state which version a function was introduced, it can\nbe assumed to be introduced at version 0, when the library was first created."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtStringsVersionBuilder) SetVersion(version uint32) *ExtStringsVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.StringsOption
+func (b *ExtStringsVersionBuilder) Build() (ext.StringsOption, error) {
+	return ext.StringsVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtStringsVersionBuilder from JSON parameters
+func (b *ExtStringsVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtStringsVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extStringsOptionsBuilders maps the sub-options accepted by the options parameter of ext.Strings to their builders
+var extStringsOptionsBuilders = map[string]func() SubOptionBuilder[ext.StringsOption]{
+	"StringsLocale": func() SubOptionBuilder[ext.StringsOption] {
+		return &ExtStringsLocaleBuilder{}
+	},
+	"StringsValidateFormatCalls": func() SubOptionBuilder[ext.StringsOption] {
+		return &ExtStringsValidateFormatCallsBuilder{}
+	},
+	"StringsVersion": func() SubOptionBuilder[ext.StringsOption] {
+		return &ExtStringsVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtStringsBuilder from JSON parameters
+func (b *ExtStringsBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extStringsOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtStringsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extStringsOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.Strings", func() OptionBuilder {
+		return &ExtStringsBuilder{}
+	})
+}
+
+// TwoVarComprehensions introduces support for two-variable comprehensions.
+// The two-variable form of comprehensions looks similar to the one-variable counterparts.
+// Where possible, the same macro names were used and additional macro signatures added.
+// The notable distinction for two-variable comprehensions is the introduction of
+// `transformList`, `transformMap`, and `transformMapEntry` support for list and map types
+// rather than the more traditional `map` and `filter` macros.
+// # All
+// Comprehension which tests whether all elements in the list or map satisfy a given
+// predicate. The `all` macro evaluates in a manner consistent with logical AND and will
+// short-circuit when encountering a `false` value.
+// <list>.all(indexVar, valueVar, <predicate>) -> bool
+// <map>.all(keyVar, valueVar, <predicate>) -> bool
+// Examples:
+// [1, 2, 3].all(i, j, i < j) // returns true
+// {'hello': 'world', 'taco': 'taco'}.all(k, v, k != v) // returns false
+// Combines two-variable comprehension with single variable
+// {'h': ['hello', 'hi'], 'j': ['joke', 'jog']}
+// .all(k, vals, vals.all(v, v.startsWith(k))) // returns true
+// # Exists
+// Comprehension which tests whether any element in a list or map exists which satisfies
+// a given predicate. The `exists` macro evaluates in a manner consistent with logical OR
+// and will short-circuit when encountering a `true` value.
+// <list>.exists(indexVar, valueVar, <predicate>) -> bool
+// <map>.exists(keyVar, valueVar, <predicate>) -> bool
+// Examples:
+// {'greeting': 'hello', 'farewell': 'goodbye'}
+// .exists(k, v, k.startsWith('good') || v.endsWith('bye')) // returns true
+// [1, 2, 4, 8, 16].exists(i, v, v == 1024 && i == 10) // returns false
+// # ExistsOne
+// Comprehension which tests whether exactly one element in a list or map exists which
+// satisfies a given predicate expression. This comprehension does not short-circuit in
+// keeping with the one-variable exists one macro semantics.
+// <list>.existsOne(indexVar, valueVar, <predicate>)
+// <map>.existsOne(keyVar, valueVar, <predicate>)
+// This macro may also be used with the `exists_one` function name, for compatibility
+// with the one-variable macro of the same name.
+// Examples:
+// [1, 2, 1, 3, 1, 4].existsOne(i, v, i == 1 || v == 1) // returns false
+// [1, 1, 2, 2, 3, 3].existsOne(i, v, i == 2 && v == 2) // returns true
+// {'i': 0, 'j': 1, 'k': 2}.existsOne(i, v, i == 'l' || v == 1) // returns true
+// # TransformList
+// Comprehension which converts a map or a list into a list value. The output expression
+// of the comprehension determines the contents of the output list. Elements in the list
+// may optionally be filtered according to a predicate expression, where elements that
+// satisfy the predicate are transformed.
+// <list>.transformList(indexVar, valueVar, <transform>)
+// <list>.transformList(indexVar, valueVar, <filter>, <transform>)
+// <map>.transformList(keyVar, valueVar, <transform>)
+// <map>.transformList(keyVar, valueVar, <filter>, <transform>)
+// Examples:
+// [1, 2, 3].transformList(indexVar, valueVar,
+// (indexVar * valueVar) + valueVar) // returns [1, 4, 9]
+// [1, 2, 3].transformList(indexVar, valueVar, indexVar % 2 == 0
+// (indexVar * valueVar) + valueVar) // returns [1, 9]
+// {'greeting': 'hello', 'farewell': 'goodbye'}
+// .transformList(k, _, k) // returns ['greeting', 'farewell']
+// {'greeting': 'hello', 'farewell': 'goodbye'}
+// .transformList(_, v, v) // returns ['hello', 'goodbye']
+// # TransformMap
+// Comprehension which converts a map or a list into a map value. The output expression
+// of the comprehension determines the value of the output map entry; however, the key
+// remains fixed. Elements in the map may optionally be filtered according to a predicate
+// expression, where elements that satisfy the predicate are transformed.
+// <list>.transformMap(indexVar, valueVar, <transform>)
+// <list>.transformMap(indexVar, valueVar, <filter>, <transform>)
+// <map>.transformMap(keyVar, valueVar, <transform>)
+// <map>.transformMap(keyVar, valueVar, <filter>, <transform>)
+// Examples:
+// [1, 2, 3].transformMap(indexVar, valueVar,
+// (indexVar * valueVar) + valueVar) // returns {0: 1, 1: 4, 2: 9}
+// [1, 2, 3].transformMap(indexVar, valueVar, indexVar % 2 == 0
+// (indexVar * valueVar) + valueVar) // returns {0: 1, 2: 9}
+// {'greeting': 'hello'}.transformMap(k, v, v + '!') // returns {'greeting': 'hello!'}
+// # TransformMapEntry
+// Comprehension which converts a map or a list into a map value; however, this transform
+// expects the entry expression be a map literal. If the tranform produces an entry which
+// duplicates a key in the target map, the comprehension will error.  Note, that key
+// equality is determined using CEL equality which asserts that numeric values which are
+// equal, even if they don't have the same type will cause a key collision.
+// Elements in the map may optionally be filtered according to a predicate expression, where
+// elements that satisfy the predicate are transformed.
+// <list>.transformMap(indexVar, valueVar, <transform>)
+// <list>.transformMap(indexVar, valueVar, <filter>, <transform>)
+// <map>.transformMap(keyVar, valueVar, <transform>)
+// <map>.transformMap(keyVar, valueVar, <filter>, <transform>)
+// Examples:
+// returns {'hello': 'greeting'}
+// {'greeting': 'hello'}.transformMapEntry(keyVar, valueVar, {valueVar: keyVar})
+// reverse lookup, require all values in list be unique
+// [1, 2, 3].transformMapEntry(indexVar, valueVar, {valueVar: indexVar})
+// {'greeting': 'aloha', 'farewell': 'aloha'}
+// .transformMapEntry(keyVar, valueVar, {valueVar: keyVar}) // error, duplicate key
+type ExtTwoVarComprehensionsBuilder struct {
+	Options []ext.TwoVarComprehensionsOption
+}
+
+// Name returns the name of this option
+func (b *ExtTwoVarComprehensionsBuilder) Name() string {
+	return "ext.TwoVarComprehensions"
+}
+
+// Description returns the description of this option
+func (b *ExtTwoVarComprehensionsBuilder) Description() string {
+	return "TwoVarComprehensions introduces support for two-variable comprehensions.\n\nThe two-variable form of comprehensions looks similar to the one-variable counterparts.\nWhere possible, the same macro names were used and additional macro signatures added.\nThe notable distinction for two-variable comprehensions is the introduction of\n`transformList`, `transformMap`, and `transformMapEntry` support for list and map types\nrather than the more traditional `map` and `filter` macros.\n\n# All\n\nComprehension which tests whether all elements in the list or map satisfy a given\npredicate. The `all` macro evaluates in a manner consistent with logical AND and will\nshort-circuit when encountering a `false` value.\n\n\t<list>.all(indexVar, valueVar, <predicate>) -> bool\n\t<map>.all(keyVar, valueVar, <predicate>) -> bool\n\nExamples:\n\n\t[1, 2, 3].all(i, j, i < j) // returns true\n\t{'hello': 'world', 'taco': 'taco'}.all(k, v, k != v) // returns false\n\n\t// Combines two-variable comprehension with single variable\n\t{'h': ['hello', 'hi'], 'j': ['joke', 'jog']}\n\t    .all(k, vals, vals.all(v, v.startsWith(k))) // returns true\n\n# Exists\n\nComprehension which tests whether any element in a list or map exists which satisfies\na given predicate. The `exists` macro evaluates in a manner consistent with logical OR\nand will short-circuit when encountering a `true` value.\n\n\t<list>.exists(indexVar, valueVar, <predicate>) -> bool\n\t<map>.exists(keyVar, valueVar, <predicate>) -> bool\n\nExamples:\n\n\t{'greeting': 'hello', 'farewell': 'goodbye'}\n\t    .exists(k, v, k.startsWith('good') || v.endsWith('bye')) // returns true\n\t[1, 2, 4, 8, 16].exists(i, v, v == 1024 && i == 10) // returns false\n\n# ExistsOne\n\nComprehension which tests whether exactly one element in a list or map exists which\nsatisfies a given predicate expression. This comprehension does not short-circuit in\nkeeping with the one-variable exists one macro semantics.\n\n\t<list>.existsOne(indexVar, valueVar, <predicate>)\n\t<map>.existsOne(keyVar, valueVar, <predicate>)\n\nThis macro may also be used with the `exists_one` function name, for compatibility\nwith the one-variable macro of the same name.\n\nExamples:\n\n\t[1, 2, 1, 3, 1, 4].existsOne(i, v, i == 1 || v == 1) // returns false\n\t[1, 1, 2, 2, 3, 3].existsOne(i, v, i == 2 && v == 2) // returns true\n\t{'i': 0, 'j': 1, 'k': 2}.existsOne(i, v, i == 'l' || v == 1) // returns true\n\n# TransformList\n\nComprehension which converts a map or a list into a list value. The output expression\nof the comprehension determines the contents of the output list. Elements in the list\nmay optionally be filtered according to a predicate expression, where elements that\nsatisfy the predicate are transformed.\n\n\t<list>.transformList(indexVar, valueVar, <transform>)\n\t<list>.transformList(indexVar, valueVar, <filter>, <transform>)\n\t<map>.transformList(keyVar, valueVar, <transform>)\n\t<map>.transformList(keyVar, valueVar, <filter>, <transform>)\n\nExamples:\n\n\t[1, 2, 3].transformList(indexVar, valueVar,\n\t  (indexVar * valueVar) + valueVar) // returns [1, 4, 9]\n\t[1, 2, 3].transformList(indexVar, valueVar, indexVar % 2 == 0\n\t  (indexVar * valueVar) + valueVar) // returns [1, 9]\n\t{'greeting': 'hello', 'farewell': 'goodbye'}\n\t  .transformList(k, _, k) // returns ['greeting', 'farewell']\n\t{'greeting': 'hello', 'farewell': 'goodbye'}\n\t  .transformList(_, v, v) // returns ['hello', 'goodbye']\n\n# TransformMap\n\nComprehension which converts a map or a list into a map value. The output expression\nof the comprehension determines the value of the output map entry; however, the key\nremains fixed. Elements in the map may optionally be filtered according to a predicate\nexpression, where elements that satisfy the predicate are transformed.\n\n\t<list>.transformMap(indexVar, valueVar, <transform>)\n\t<list>.transformMap(indexVar, valueVar, <filter>, <transform>)\n\t<map>.transformMap(keyVar, valueVar, <transform>)\n\t<map>.transformMap(keyVar, valueVar, <filter>, <transform>)\n\nExamples:\n\n\t[1, 2, 3].transformMap(indexVar, valueVar,\n\t  (indexVar * valueVar) + valueVar) // returns {0: 1, 1: 4, 2: 9}\n\t[1, 2, 3].transformMap(indexVar, valueVar, indexVar % 2 == 0\n\t  (indexVar * valueVar) + valueVar) // returns {0: 1, 2: 9}\n\t{'greeting': 'hello'}.transformMap(k, v, v + '!') // returns {'greeting': 'hello!'}\n\n# TransformMapEntry\n\nComprehension which converts a map or a list into a map value; however, this transform\nexpects the entry expression be a map literal. If the tranform produces an entry which\nduplicates a key in the target map, the comprehension will error.  Note, that key\nequality is determined using CEL equality which asserts that numeric values which are\nequal, even if they don't have the same type will cause a key collision.\n\nElements in the map may optionally be filtered according to a predicate expression, where\nelements that satisfy the predicate are transformed.\n\n\t<list>.transformMap(indexVar, valueVar, <transform>)\n\t<list>.transformMap(indexVar, valueVar, <filter>, <transform>)\n\t<map>.transformMap(keyVar, valueVar, <transform>)\n\t<map>.transformMap(keyVar, valueVar, <filter>, <transform>)\n\nExamples:\n\n\t// returns {'hello': 'greeting'}\n\t{'greeting': 'hello'}.transformMapEntry(keyVar, valueVar, {valueVar: keyVar})\n\t// reverse lookup, require all values in list be unique\n\t[1, 2, 3].transformMapEntry(indexVar, valueVar, {valueVar: indexVar})\n\n\t{'greeting': 'aloha', 'farewell': 'aloha'}\n\t  .transformMapEntry(keyVar, valueVar, {valueVar: keyVar}) // error, duplicate key"
+}
+
+// SetOptions sets the options parameter
+func (b *ExtTwoVarComprehensionsBuilder) SetOptions(options []ext.TwoVarComprehensionsOption) *ExtTwoVarComprehensionsBuilder {
+	b.Options = options
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *ExtTwoVarComprehensionsBuilder) Build() (cel.EnvOption, error) {
+	return ext.TwoVarComprehensions(b.Options...), nil
+}
+
+// TwoVarComprehensionsVersion sets the library version for two-variable comprehensions.
+type ExtTwoVarComprehensionsVersionBuilder struct {
+	Version uint32
+}
+
+// Description returns the description of this sub-option
+func (b *ExtTwoVarComprehensionsVersionBuilder) Description() string {
+	return "TwoVarComprehensionsVersion sets the library version for two-variable comprehensions."
+}
+
+// SetVersion sets the version parameter
+func (b *ExtTwoVarComprehensionsVersionBuilder) SetVersion(version uint32) *ExtTwoVarComprehensionsVersionBuilder {
+	b.Version = version
+	return b
+}
+
+// Build creates the ext.TwoVarComprehensionsOption
+func (b *ExtTwoVarComprehensionsVersionBuilder) Build() (ext.TwoVarComprehensionsOption, error) {
+	return ext.TwoVarComprehensionsVersion(b.Version), nil
+}
+
+// FromJSON configures the ExtTwoVarComprehensionsVersionBuilder from JSON parameters
+func (b *ExtTwoVarComprehensionsVersionBuilder) FromJSON(params map[string]interface{}) error {
+	version, err := uintParam(params, "version", 32)
+	if err != nil {
+		return err
+	}
+	b.SetVersion(uint32(version))
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtTwoVarComprehensionsVersionBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"version": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "version")
+}
+
+// extTwoVarComprehensionsOptionsBuilders maps the sub-options accepted by the options parameter of ext.TwoVarComprehensions to their builders
+var extTwoVarComprehensionsOptionsBuilders = map[string]func() SubOptionBuilder[ext.TwoVarComprehensionsOption]{
+	"TwoVarComprehensionsVersion": func() SubOptionBuilder[ext.TwoVarComprehensionsOption] {
+		return &ExtTwoVarComprehensionsVersionBuilder{}
+	},
+}
+
+// FromJSON configures the ExtTwoVarComprehensionsBuilder from JSON parameters
+func (b *ExtTwoVarComprehensionsBuilder) FromJSON(params map[string]interface{}) error {
+	options, err := subOptionsFromJSON(params, "options", extTwoVarComprehensionsOptionsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetOptions(options)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ExtTwoVarComprehensionsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"options": subOptionsSchema(extTwoVarComprehensionsOptionsBuilders)})
+}
+func init() {
+	DefaultRegistry.Register("ext.TwoVarComprehensions", func() OptionBuilder {
+		return &ExtTwoVarComprehensionsBuilder{}
+	})
+}
@@ -0,0 +1,86 @@
+package options
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// optionTest is an expression, with the value it results in or a part of the error it
+// fails with, at compile time or during evaluation
+type optionTest struct {
+	expr     string
+	expected interface{}
+	err      string
+}
+
+// optionEnv creates an environment with an option of the default registry, configured
+// from JSON parameters unless they're nil, and other environment options
+func optionEnv(t *testing.T, name string, params map[string]interface{}, envOptions ...cel.EnvOption) *cel.Env {
+	t.Helper()
+	builder, err := DefaultRegistry.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params != nil {
+		if err := builder.(FromJSON).FromJSON(params); err != nil {
+			t.Fatalf("failed to configure %s: %v", name, err)
+		}
+	}
+	option, err := builder.Build()
+	if err != nil {
+		t.Fatalf("failed to build %s: %v", name, err)
+	}
+	env, err := cel.NewEnv(append(envOptions, option)...)
+	if err != nil {
+		t.Fatalf("failed to create the environment: %v", err)
+	}
+	return env
+}
+
+// evalOption compiles and evaluates an expression
+func evalOption(env *cel.Env, expr string, vars map[string]interface{}) (ref.Val, error) {
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := prg.Eval(vars)
+	return out, err
+}
+
+// runOptionTests evaluates the tests with variables, comparing results with the expected
+// values once converted to their Go types
+func runOptionTests(t *testing.T, env *cel.Env, vars map[string]interface{}, tests []optionTest) {
+	t.Helper()
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			out, err := evalOption(env, test.expr, vars)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v (%v)", test.err, err, out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual, err := out.ConvertToNative(reflect.TypeOf(test.expected))
+			if err != nil {
+				t.Fatalf("expected %#v, got %v of type %s: %v", test.expected, out, out.Type().TypeName(), err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %#v, got %#v", test.expected, actual)
+			}
+		})
+	}
+}
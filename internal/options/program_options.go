@@ -0,0 +1,428 @@
+// Code generated by extensionsgen. DO NOT EDIT.
+package options
+
+import (
+	"fmt"
+	cel "github.com/google/cel-go/cel"
+	functions "github.com/google/cel-go/common/functions"
+	interpreter "github.com/google/cel-go/interpreter"
+	"sort"
+)
+
+// ProgramOptionBuilder is the interface that all program option builders must implement
+type ProgramOptionBuilder interface {
+	// Build creates the actual CEL program option
+	Build() (cel.ProgramOption, error)
+	// Name returns the name of the option
+	Name() string
+	// Description returns a description of what this option does
+	Description() string
+}
+
+// ProgramRegistry holds all available program option builders
+type ProgramRegistry struct {
+	builders map[string]func() ProgramOptionBuilder
+}
+
+// NewProgramRegistry creates a new program option registry
+func NewProgramRegistry() *ProgramRegistry {
+	return &ProgramRegistry{builders: make(map[string]func() ProgramOptionBuilder)}
+}
+
+// Register registers a program option builder factory function
+func (r *ProgramRegistry) Register(name string, factory func() ProgramOptionBuilder) {
+	r.builders[name] = factory
+}
+
+// Create creates a new program option builder by name
+func (r *ProgramRegistry) Create(name string) (ProgramOptionBuilder, error) {
+	factory, exists := r.builders[name]
+	if !exists {
+		return nil, fmt.Errorf("program option %q not found", name)
+	}
+	return factory(), nil
+}
+
+// List returns all available program option names in sorted order
+func (r *ProgramRegistry) List() []string {
+	var names []string
+	for name := range r.builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListWithFromJSON returns program option names that implement the FromJSON interface in sorted order
+func (r *ProgramRegistry) ListWithFromJSON() []string {
+	var names []string
+	for name, factory := range r.builders {
+		builder := factory()
+		if _, ok := builder.(FromJSON); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultProgramRegistry is the default registry with all built-in program options
+var DefaultProgramRegistry = NewProgramRegistry()
+
+// CostLimit enables cost tracking and sets configures program evaluation to exit early with a
+// "runtime cost limit exceeded" error if the runtime cost exceeds the costLimit.
+// The CostLimit is a metric that corresponds to the number and estimated expense of operations
+// performed while evaluating an expression. It is indicative of CPU usage, not memory usage.
+type CostLimitBuilder struct {
+	CostLimit uint64
+}
+
+// Name returns the name of this option
+func (b *CostLimitBuilder) Name() string {
+	return "CostLimit"
+}
+
+// Description returns the description of this option
+func (b *CostLimitBuilder) Description() string {
+	return "CostLimit enables cost tracking and sets configures program evaluation to exit early with a\n\"runtime cost limit exceeded\" error if the runtime cost exceeds the costLimit.\nThe CostLimit is a metric that corresponds to the number and estimated expense of operations\nperformed while evaluating an expression. It is indicative of CPU usage, not memory usage."
+}
+
+// SetCostLimit sets the costLimit parameter
+func (b *CostLimitBuilder) SetCostLimit(costLimit uint64) *CostLimitBuilder {
+	b.CostLimit = costLimit
+	return b
+}
+
+// Build creates the CEL program option
+func (b *CostLimitBuilder) Build() (cel.ProgramOption, error) {
+	return cel.CostLimit(b.CostLimit), nil
+}
+func init() {
+	DefaultProgramRegistry.Register("CostLimit", func() ProgramOptionBuilder {
+		return &CostLimitBuilder{}
+	})
+}
+
+// CostTrackerOptions configures a set of options for cost-tracking.
+// Note, CostTrackerOptions is a no-op unless CostTracking is also enabled.
+type CostTrackerOptionsBuilder struct {
+	CostOpts []interpreter.CostTrackerOption
+}
+
+// Name returns the name of this option
+func (b *CostTrackerOptionsBuilder) Name() string {
+	return "CostTrackerOptions"
+}
+
+// Description returns the description of this option
+func (b *CostTrackerOptionsBuilder) Description() string {
+	return "CostTrackerOptions configures a set of options for cost-tracking.\n\nNote, CostTrackerOptions is a no-op unless CostTracking is also enabled."
+}
+
+// SetCostOpts sets the costOpts parameter
+func (b *CostTrackerOptionsBuilder) SetCostOpts(costOpts []interpreter.CostTrackerOption) *CostTrackerOptionsBuilder {
+	b.CostOpts = costOpts
+	return b
+}
+
+// Build creates the CEL program option
+func (b *CostTrackerOptionsBuilder) Build() (cel.ProgramOption, error) {
+	return cel.CostTrackerOptions(b.CostOpts...), nil
+}
+
+// CostTrackerLimit sets the runtime limit on the evaluation cost during execution and will terminate the expression
+// evaluation if the limit is exceeded.
+type InterpreterCostTrackerLimitBuilder struct {
+	Limit uint64
+}
+
+// Description returns the description of this sub-option
+func (b *InterpreterCostTrackerLimitBuilder) Description() string {
+	return "CostTrackerLimit sets the runtime limit on the evaluation cost during execution and will terminate the expression\nevaluation if the limit is exceeded."
+}
+
+// SetLimit sets the limit parameter
+func (b *InterpreterCostTrackerLimitBuilder) SetLimit(limit uint64) *InterpreterCostTrackerLimitBuilder {
+	b.Limit = limit
+	return b
+}
+
+// Build creates the interpreter.CostTrackerOption
+func (b *InterpreterCostTrackerLimitBuilder) Build() (interpreter.CostTrackerOption, error) {
+	return interpreter.CostTrackerLimit(b.Limit), nil
+}
+
+// FromJSON configures the InterpreterCostTrackerLimitBuilder from JSON parameters
+func (b *InterpreterCostTrackerLimitBuilder) FromJSON(params map[string]interface{}) error {
+	limit, err := uintParam(params, "limit", 64)
+	if err != nil {
+		return err
+	}
+	b.SetLimit(limit)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *InterpreterCostTrackerLimitBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"limit": map[string]interface{}{
+		"minimum": 0,
+		"type":    "integer",
+	}}, "limit")
+}
+
+// PresenceTestHasCost determines whether presence testing has a cost of one or zero.
+// Defaults to presence test has a cost of one.
+type InterpreterPresenceTestHasCostBuilder struct {
+	HasCost bool
+}
+
+// Description returns the description of this sub-option
+func (b *InterpreterPresenceTestHasCostBuilder) Description() string {
+	return "PresenceTestHasCost determines whether presence testing has a cost of one or zero.\nDefaults to presence test has a cost of one."
+}
+
+// SetHasCost sets the hasCost parameter
+func (b *InterpreterPresenceTestHasCostBuilder) SetHasCost(hasCost bool) *InterpreterPresenceTestHasCostBuilder {
+	b.HasCost = hasCost
+	return b
+}
+
+// Build creates the interpreter.CostTrackerOption
+func (b *InterpreterPresenceTestHasCostBuilder) Build() (interpreter.CostTrackerOption, error) {
+	return interpreter.PresenceTestHasCost(b.HasCost), nil
+}
+
+// FromJSON configures the InterpreterPresenceTestHasCostBuilder from JSON parameters
+func (b *InterpreterPresenceTestHasCostBuilder) FromJSON(params map[string]interface{}) error {
+	hasCost, err := boolParam(params, "hasCost")
+	if err != nil {
+		return err
+	}
+	b.SetHasCost(hasCost)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *InterpreterPresenceTestHasCostBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"hasCost": map[string]interface{}{"type": "boolean"}}, "hasCost")
+}
+
+// costTrackerOptionsCostOptsBuilders maps the sub-options accepted by the costOpts parameter of CostTrackerOptions to their builders
+var costTrackerOptionsCostOptsBuilders = map[string]func() SubOptionBuilder[interpreter.CostTrackerOption]{
+	"CostTrackerLimit": func() SubOptionBuilder[interpreter.CostTrackerOption] {
+		return &InterpreterCostTrackerLimitBuilder{}
+	},
+	"PresenceTestHasCost": func() SubOptionBuilder[interpreter.CostTrackerOption] {
+		return &InterpreterPresenceTestHasCostBuilder{}
+	},
+}
+
+// FromJSON configures the CostTrackerOptionsBuilder from JSON parameters
+func (b *CostTrackerOptionsBuilder) FromJSON(params map[string]interface{}) error {
+	costOpts, err := subOptionsFromJSON(params, "costOpts", costTrackerOptionsCostOptsBuilders)
+	if err != nil {
+		return err
+	}
+	b.SetCostOpts(costOpts)
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *CostTrackerOptionsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{"costOpts": subOptionsSchema(costTrackerOptionsCostOptsBuilders)})
+}
+func init() {
+	DefaultProgramRegistry.Register("CostTrackerOptions", func() ProgramOptionBuilder {
+		return &CostTrackerOptionsBuilder{}
+	})
+}
+
+// CostTracking enables cost tracking and registers a ActualCostEstimator that can optionally provide a runtime cost estimate for any function calls.
+type CostTrackingBuilder struct {
+	CostEstimator interpreter.ActualCostEstimator
+}
+
+// Name returns the name of this option
+func (b *CostTrackingBuilder) Name() string {
+	return "CostTracking"
+}
+
+// Description returns the description of this option
+func (b *CostTrackingBuilder) Description() string {
+	return "CostTracking enables cost tracking and registers a ActualCostEstimator that can optionally provide a runtime cost estimate for any function calls."
+}
+
+// SetCostEstimator sets the costEstimator parameter
+func (b *CostTrackingBuilder) SetCostEstimator(costEstimator interpreter.ActualCostEstimator) *CostTrackingBuilder {
+	b.CostEstimator = costEstimator
+	return b
+}
+
+// Build creates the CEL program option
+func (b *CostTrackingBuilder) Build() (cel.ProgramOption, error) {
+	return cel.CostTracking(b.CostEstimator), nil
+}
+func init() {
+	DefaultProgramRegistry.Register("CostTracking", func() ProgramOptionBuilder {
+		return &CostTrackingBuilder{}
+	})
+}
+
+// CustomDecorator appends an InterpreterDecorator to the program.
+// InterpretableDecorators can be used to inspect, alter, or replace the Program plan.
+type CustomDecoratorBuilder struct {
+	Dec interpreter.InterpretableDecorator
+}
+
+// Name returns the name of this option
+func (b *CustomDecoratorBuilder) Name() string {
+	return "CustomDecorator"
+}
+
+// Description returns the description of this option
+func (b *CustomDecoratorBuilder) Description() string {
+	return "CustomDecorator appends an InterpreterDecorator to the program.\n\nInterpretableDecorators can be used to inspect, alter, or replace the Program plan."
+}
+
+// SetDec sets the dec parameter
+func (b *CustomDecoratorBuilder) SetDec(dec interpreter.InterpretableDecorator) *CustomDecoratorBuilder {
+	b.Dec = dec
+	return b
+}
+
+// Build creates the CEL program option
+func (b *CustomDecoratorBuilder) Build() (cel.ProgramOption, error) {
+	return cel.CustomDecorator(b.Dec), nil
+}
+func init() {
+	DefaultProgramRegistry.Register("CustomDecorator", func() ProgramOptionBuilder {
+		return &CustomDecoratorBuilder{}
+	})
+}
+
+// EvalOptions sets one or more evaluation options which may affect the evaluation or Result.
+type EvalOptionsBuilder struct {
+	Opts []cel.EvalOption
+}
+
+// Name returns the name of this option
+func (b *EvalOptionsBuilder) Name() string {
+	return "EvalOptions"
+}
+
+// Description returns the description of this option
+func (b *EvalOptionsBuilder) Description() string {
+	return "EvalOptions sets one or more evaluation options which may affect the evaluation or Result."
+}
+
+// SetOpts sets the opts parameter
+func (b *EvalOptionsBuilder) SetOpts(opts []cel.EvalOption) *EvalOptionsBuilder {
+	b.Opts = opts
+	return b
+}
+
+// Build creates the CEL program option
+func (b *EvalOptionsBuilder) Build() (cel.ProgramOption, error) {
+	return cel.EvalOptions(b.Opts...), nil
+}
+func init() {
+	DefaultProgramRegistry.Register("EvalOptions", func() ProgramOptionBuilder {
+		return &EvalOptionsBuilder{}
+	})
+}
+
+// Functions returns a shallow copy of the Functions, keyed by function name, that have been configured in the environment.
+type FunctionsBuilder struct {
+	Funcs []*functions.Overload
+}
+
+// Name returns the name of this option
+func (b *FunctionsBuilder) Name() string {
+	return "Functions"
+}
+
+// Description returns the description of this option
+func (b *FunctionsBuilder) Description() string {
+	return "Functions returns a shallow copy of the Functions, keyed by function name, that have been configured in the environment."
+}
+
+// SetFuncs sets the funcs parameter
+func (b *FunctionsBuilder) SetFuncs(funcs []*functions.Overload) *FunctionsBuilder {
+	b.Funcs = funcs
+	return b
+}
+
+// Build creates the CEL program option
+func (b *FunctionsBuilder) Build() (cel.ProgramOption, error) {
+	return cel.Functions(b.Funcs...), nil
+}
+func init() {
+	DefaultProgramRegistry.Register("Functions", func() ProgramOptionBuilder {
+		return &FunctionsBuilder{}
+	})
+}
+
+// InterruptCheckFrequency configures the number of iterations within a comprehension to evaluate
+// before checking whether the function evaluation has been interrupted.
+type InterruptCheckFrequencyBuilder struct {
+	CheckFrequency uint
+}
+
+// Name returns the name of this option
+func (b *InterruptCheckFrequencyBuilder) Name() string {
+	return "InterruptCheckFrequency"
+}
+
+// Description returns the description of this option
+func (b *InterruptCheckFrequencyBuilder) Description() string {
+	return "InterruptCheckFrequency configures the number of iterations within a comprehension to evaluate\nbefore checking whether the function evaluation has been interrupted."
+}
+
+// SetCheckFrequency sets the checkFrequency parameter
+func (b *InterruptCheckFrequencyBuilder) SetCheckFrequency(checkFrequency uint) *InterruptCheckFrequencyBuilder {
+	b.CheckFrequency = checkFrequency
+	return b
+}
+
+// Build creates the CEL program option
+func (b *InterruptCheckFrequencyBuilder) Build() (cel.ProgramOption, error) {
+	return cel.InterruptCheckFrequency(b.CheckFrequency), nil
+}
+func init() {
+	DefaultProgramRegistry.Register("InterruptCheckFrequency", func() ProgramOptionBuilder {
+		return &InterruptCheckFrequencyBuilder{}
+	})
+}
+
+// OptimizeRegex provides a way to replace the InterpretableCall for regex functions. This can be used
+// to compile regex string constants at program creation time and report any errors and then use the
+// compiled regex for all regex function invocations.
+type OptimizeRegexBuilder struct {
+	RegexOptimizations []*interpreter.RegexOptimization
+}
+
+// Name returns the name of this option
+func (b *OptimizeRegexBuilder) Name() string {
+	return "OptimizeRegex"
+}
+
+// Description returns the description of this option
+func (b *OptimizeRegexBuilder) Description() string {
+	return "OptimizeRegex provides a way to replace the InterpretableCall for regex functions. This can be used\nto compile regex string constants at program creation time and report any errors and then use the\ncompiled regex for all regex function invocations."
+}
+
+// SetRegexOptimizations sets the regexOptimizations parameter
+func (b *OptimizeRegexBuilder) SetRegexOptimizations(regexOptimizations []*interpreter.RegexOptimization) *OptimizeRegexBuilder {
+	b.RegexOptimizations = regexOptimizations
+	return b
+}
+
+// Build creates the CEL program option
+func (b *OptimizeRegexBuilder) Build() (cel.ProgramOption, error) {
+	return cel.OptimizeRegex(b.RegexOptimizations...), nil
+}
+func init() {
+	DefaultProgramRegistry.Register("OptimizeRegex", func() ProgramOptionBuilder {
+		return &OptimizeRegexBuilder{}
+	})
+}
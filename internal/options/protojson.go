@@ -0,0 +1,142 @@
+//go:build !wasmcel_noproto
+
+package options
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ProtoJSONBuilder declares toJSON() and fromJSON(), which convert messages to and from
+// their JSON mapping, so rules can build typed messages the host consumes
+// fromJSON() finds message types in the type registry of the environment the option
+// is applied to, so types registered by later extensions aren't found.
+type ProtoJSONBuilder struct{}
+
+// Name returns the name of this option
+func (b *ProtoJSONBuilder) Name() string {
+	return "ProtoJSON"
+}
+
+// Description returns the description of this option
+func (b *ProtoJSONBuilder) Description() string {
+	return "ProtoJSON declares toJSON(value), which returns the JSON of a value, using the JSON mapping of\nprotobuf for messages, and fromJSON(typeName, json), which decodes a message of a registered type from\nits JSON mapping. Messages are built with struct construction expressions, such as acme.Policy{name: \"x\"}.\n\nfromJSON() only finds types registered when the option is applied, such as with DeclareContextProto\nin the same or an earlier set of options."
+}
+
+// Build creates the CEL environment option
+func (b *ProtoJSONBuilder) Build() (cel.EnvOption, error) {
+	return func(e *cel.Env) (*cel.Env, error) {
+		return cel.Lib(&protoJSONLibrary{provider: e.CELTypeProvider()})(e)
+	}, nil
+}
+
+// FromJSON configures the ProtoJSONBuilder from JSON parameters
+func (b *ProtoJSONBuilder) FromJSON(params map[string]interface{}) error {
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *ProtoJSONBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+func init() {
+	DefaultRegistry.Register("ProtoJSON", func() OptionBuilder {
+		return &ProtoJSONBuilder{}
+	})
+}
+
+// jsonValueType is the type values convert to for their JSON
+var jsonValueType = reflect.TypeOf(&structpb.Value{})
+
+// protoJSONLibrary declares toJSON() and fromJSON()
+type protoJSONLibrary struct {
+	provider types.Provider // Provides the message types fromJSON() decodes
+}
+
+// LibraryName implements cel.SingletonLibrary
+func (l *protoJSONLibrary) LibraryName() string {
+	return "wasm-cel.protojson"
+}
+
+// CompileOptions implements cel.Library
+func (l *protoJSONLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("toJSON",
+			cel.Overload("wasmcel_to_json_dyn", []*cel.Type{cel.DynType}, cel.StringType,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					text, err := toJSON(val)
+					if err != nil {
+						return types.NewErr("toJSON() failed: %v", err)
+					}
+					return types.String(text)
+				}),
+			),
+		),
+		cel.Function("fromJSON",
+			cel.Overload("wasmcel_from_json_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.DynType,
+				cel.BinaryBinding(func(typeName, text ref.Val) ref.Val {
+					return l.fromJSON(string(typeName.(types.String)), string(text.(types.String)))
+				}),
+			),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (l *protoJSONLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// toJSON returns the compact JSON of a value, using the protobuf JSON mapping for messages
+func toJSON(val ref.Val) (string, error) {
+	if msg, ok := val.Value().(proto.Message); ok {
+		data, err := protojson.Marshal(msg)
+		if err != nil {
+			return "", err
+		}
+		// protojson varies its whitespace on purpose, so compact it into stable output
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err != nil {
+			return "", err
+		}
+		return compact.String(), nil
+	}
+
+	native, err := val.ConvertToNative(jsonValueType)
+	if err != nil {
+		return "", err
+	}
+	// encoding/json sorts the keys of maps, unlike protojson
+	data, err := json.Marshal(native.(*structpb.Value).AsInterface())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// fromJSON decodes a message of a registered type from its JSON mapping
+func (l *protoJSONLibrary) fromJSON(typeName string, text string) ref.Val {
+	zero := l.provider.NewValue(typeName, nil)
+	msg, ok := zero.Value().(proto.Message)
+	if !ok {
+		return types.NewErr("fromJSON() failed: unknown message type: %s", typeName)
+	}
+	msg = msg.ProtoReflect().New().Interface()
+	if err := protojson.Unmarshal([]byte(text), msg); err != nil {
+		return types.NewErr("fromJSON() failed to decode %s: %v", typeName, err)
+	}
+	adapter, ok := l.provider.(types.Adapter)
+	if !ok {
+		adapter = types.DefaultTypeAdapter
+	}
+	return adapter.NativeToValue(msg)
+}
@@ -0,0 +1,210 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+)
+
+// Defaults of the safe mode preset
+const (
+	safeModeCostLimit      = 10000
+	safeModeMaxLiteralSize = 100
+)
+
+// jsOverloadFunc reports whether an overload of an environment is implemented by a
+// JavaScript function. It is set by the engine, which keeps track of them
+var jsOverloadFunc func(env *cel.Env, overloadID string) bool
+
+// SetJSOverloadFunc sets the function reporting which overloads are JavaScript functions
+func SetJSOverloadFunc(fn func(env *cel.Env, overloadID string) bool) {
+	jsOverloadFunc = fn
+}
+
+// SafeModePresetBuilder restricts the expressions of an environment to cheap boolean
+// filters, for expressions typed by untrusted users, such as the filters of search boxes
+type SafeModePresetBuilder struct {
+	CostLimit      uint64
+	MaxLiteralSize int
+}
+
+// Name returns the name of this option
+func (b *SafeModePresetBuilder) Name() string {
+	return "SafeModePreset"
+}
+
+// Description returns the description of this option
+func (b *SafeModePresetBuilder) Description() string {
+	return "SafeModePreset restricts the environment to boolean filters safe to compile from untrusted input, such as\nfilters typed into product UIs.\n\nExpressions must have type bool, and may not concatenate strings, bytes or lists, build lists or maps with\ncomprehensions such as map() and filter(), write list or map literals with more than maxLiteralSize\nelements, or call custom JavaScript functions. Evaluations are aborted once their cost exceeds costLimit."
+}
+
+// SetCostLimit sets the costLimit parameter
+func (b *SafeModePresetBuilder) SetCostLimit(costLimit uint64) *SafeModePresetBuilder {
+	b.CostLimit = costLimit
+	return b
+}
+
+// SetMaxLiteralSize sets the maxLiteralSize parameter
+func (b *SafeModePresetBuilder) SetMaxLiteralSize(maxLiteralSize int) *SafeModePresetBuilder {
+	b.MaxLiteralSize = maxLiteralSize
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *SafeModePresetBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(&safeModeLibrary{costLimit: b.CostLimit, maxLiteralSize: b.MaxLiteralSize}), nil
+}
+
+// FromJSON configures the SafeModePresetBuilder from JSON parameters
+func (b *SafeModePresetBuilder) FromJSON(params map[string]interface{}) error {
+	if value, exists := params["costLimit"]; exists {
+		limit, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("costLimit must be a number")
+		}
+		if limit < 0 {
+			return fmt.Errorf("costLimit must not be negative")
+		}
+		b.SetCostLimit(uint64(limit))
+	}
+	if value, exists := params["maxLiteralSize"]; exists {
+		size, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("maxLiteralSize must be a number")
+		}
+		if size < 0 {
+			return fmt.Errorf("maxLiteralSize must not be negative")
+		}
+		b.SetMaxLiteralSize(int(size))
+	}
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *SafeModePresetBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"costLimit": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"default":     safeModeCostLimit,
+			"description": "Maximum runtime cost of a single evaluation",
+		},
+		"maxLiteralSize": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"default":     safeModeMaxLiteralSize,
+			"description": "Maximum number of elements of list and map literals",
+		},
+	})
+}
+
+func init() {
+	DefaultRegistry.Register("SafeModePreset", func() OptionBuilder {
+		return &SafeModePresetBuilder{CostLimit: safeModeCostLimit, MaxLiteralSize: safeModeMaxLiteralSize}
+	})
+}
+
+// safeModeLibrary holds the compile and program options of the preset
+type safeModeLibrary struct {
+	costLimit      uint64
+	maxLiteralSize int
+}
+
+// LibraryName implements cel.SingletonLibrary, so the preset is only applied once
+func (l *safeModeLibrary) LibraryName() string {
+	return "wasm-cel.preset.safemode"
+}
+
+// CompileOptions implements cel.Library
+func (l *safeModeLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.ASTValidators(&safeModeValidator{maxLiteralSize: l.maxLiteralSize}),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (l *safeModeLibrary) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{
+		cel.EvalOptions(cel.OptTrackCost),
+		cel.CostLimit(l.costLimit),
+	}
+}
+
+// safeModeValidator fails compilation of expressions the safe mode doesn't allow
+type safeModeValidator struct {
+	maxLiteralSize int
+}
+
+// Name returns the name of this validator
+func (v *safeModeValidator) Name() string {
+	return "wasm-cel.safemode"
+}
+
+// Validate reports an error for each construct of a checked expression that the safe
+// mode doesn't allow
+func (v *safeModeValidator) Validate(env *cel.Env, config cel.ValidatorConfig, a *ast.AST, issues *cel.Issues) {
+	root := a.Expr()
+	if t := a.GetType(root.ID()); t.Kind() != types.BoolKind {
+		issues.ReportErrorAtID(root.ID(), "expression has type %s, expected bool in safe mode", cel.FormatCELType(t))
+	}
+
+	// Comprehensions building lists, such as map(), add to their accumulators, which is
+	// reported as the comprehension rather than as concatenation
+	accumulators := make(map[string]bool)
+	ast.PreOrderVisit(root, ast.NewExprVisitor(func(e ast.Expr) {
+		if e.Kind() == ast.ComprehensionKind {
+			accumulators[e.AsComprehension().AccuVar()] = true
+		}
+	}))
+
+	references := a.ReferenceMap()
+	ast.PostOrderVisit(root, ast.NewExprVisitor(func(e ast.Expr) {
+		switch e.Kind() {
+		case ast.CallKind:
+			function := e.AsCall().FunctionName()
+			if function == operators.Add && !addsToAccumulator(e, accumulators) {
+				switch t := a.GetType(e.ID()); t.Kind() {
+				case types.StringKind, types.BytesKind, types.ListKind:
+					issues.ReportErrorAtID(e.ID(), "concatenation of %s values is not allowed in safe mode", cel.FormatCELType(t))
+				case types.DynKind:
+					issues.ReportErrorAtID(e.ID(), "addition of dyn values, which may concatenate them, is not allowed in safe mode: convert the operands, such as with int()")
+				}
+			}
+			if reference, ok := references[e.ID()]; ok && jsOverloadFunc != nil {
+				for _, overloadID := range reference.OverloadIDs {
+					if jsOverloadFunc(env, overloadID) {
+						issues.ReportErrorAtID(e.ID(), "custom function %s is not allowed in safe mode", function)
+						break
+					}
+				}
+			}
+		case ast.ComprehensionKind:
+			switch a.GetType(e.ID()).Kind() {
+			case types.ListKind, types.MapKind:
+				issues.ReportErrorAtID(e.ID(), "comprehensions building lists or maps, such as map() and filter(), are not allowed in safe mode")
+			}
+		case ast.ListKind:
+			if size := len(e.AsList().Elements()); size > v.maxLiteralSize {
+				issues.ReportErrorAtID(e.ID(), "list literal of %d elements exceeds the safe mode limit of %d", size, v.maxLiteralSize)
+			}
+		case ast.MapKind:
+			if size := len(e.AsMap().Entries()); size > v.maxLiteralSize {
+				issues.ReportErrorAtID(e.ID(), "map literal of %d entries exceeds the safe mode limit of %d", size, v.maxLiteralSize)
+			}
+		}
+	}))
+}
+
+// addsToAccumulator reports whether a call has the accumulator of a comprehension as an
+// argument
+func addsToAccumulator(call ast.Expr, accumulators map[string]bool) bool {
+	for _, arg := range call.AsCall().Args() {
+		if arg.Kind() == ast.IdentKind && accumulators[arg.AsIdent()] {
+			return true
+		}
+	}
+	return false
+}
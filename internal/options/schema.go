@@ -0,0 +1,35 @@
+package options
+
+// ParamsSchemaProvider is implemented by options that describe their FromJSON parameters
+type ParamsSchemaProvider interface {
+	// ParamsSchema returns a JSON Schema object describing the accepted parameters
+	ParamsSchema() map[string]interface{}
+}
+
+// objectSchema builds a JSON Schema object with the given properties and required keys
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		requiredList := make([]interface{}, len(required))
+		for i, name := range required {
+			requiredList[i] = name
+		}
+		schema["required"] = requiredList
+	}
+	return schema
+}
+
+// enabledParamsSchema builds the schema shared by options with a single "enabled" toggle
+func enabledParamsSchema(description string) map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"enabled": map[string]interface{}{
+			"type":        "boolean",
+			"default":     true,
+			"description": description,
+		},
+	})
+}
@@ -0,0 +1,206 @@
+package options
+
+import (
+	"math"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// SetOperationsBuilder declares functions combining lists as sets, implemented in Go, to
+// go with the predicates of ext.Sets, so authorization rules don't build sets with
+// comprehensions
+type SetOperationsBuilder struct{}
+
+// Name returns the name of this option
+func (b *SetOperationsBuilder) Name() string {
+	return "SetOperations"
+}
+
+// Description returns the description of this option
+func (b *SetOperationsBuilder) Description() string {
+	return "SetOperations declares functions combining lists as sets, to go with the predicates of ext.Sets:\nsets.union() returns the elements of either list, sets.intersection() the elements of the first list in the\nsecond, sets.difference() the elements of the first list not in the second, and sets.distinct() the elements\nof a list without duplicates.\n\nResults keep the order elements first appear in and leave out duplicates. Elements are compared with CEL\nequality, so 1, 1u and 1.0 are the same element.\n\n\tsets.union([1, 2], [2, 3]) // [1, 2, 3]\n\tsets.intersection([\"a\", \"b\"], [\"b\", \"c\"]) // [\"b\"]\n\tsets.difference([1, 2, 3], [dyn(2.0)]) // [1, 3]\n\tsets.distinct([1, 1, 2]) // [1, 2]"
+}
+
+// Build creates the CEL environment option
+func (b *SetOperationsBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(setOperationsLibrary{}), nil
+}
+
+// FromJSON configures the SetOperationsBuilder from JSON parameters
+func (b *SetOperationsBuilder) FromJSON(params map[string]interface{}) error {
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *SetOperationsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+func init() {
+	DefaultRegistry.Register("SetOperations", func() OptionBuilder {
+		return &SetOperationsBuilder{}
+	})
+}
+
+// setOperationsLibrary declares the set operations
+type setOperationsLibrary struct{}
+
+// LibraryName implements cel.SingletonLibrary
+func (setOperationsLibrary) LibraryName() string {
+	return "wasm-cel.setoperations"
+}
+
+// CompileOptions implements cel.Library
+func (setOperationsLibrary) CompileOptions() []cel.EnvOption {
+	listType := cel.ListType(cel.TypeParamType("T"))
+	return []cel.EnvOption{
+		cel.Function("sets.union",
+			cel.Overload("wasmcel_sets_union_list_list", []*cel.Type{listType, listType}, listType,
+				cel.BinaryBinding(func(a, b ref.Val) ref.Val {
+					result := newValueSet()
+					result.addAll(a.(traits.Lister))
+					result.addAll(b.(traits.Lister))
+					return result.list()
+				}),
+			),
+		),
+		cel.Function("sets.intersection",
+			cel.Overload("wasmcel_sets_intersection_list_list", []*cel.Type{listType, listType}, listType,
+				cel.BinaryBinding(func(a, b ref.Val) ref.Val {
+					other := newValueSet()
+					other.addAll(b.(traits.Lister))
+					return filterSet(a.(traits.Lister), func(elem ref.Val) bool {
+						return other.contains(elem)
+					})
+				}),
+			),
+		),
+		cel.Function("sets.difference",
+			cel.Overload("wasmcel_sets_difference_list_list", []*cel.Type{listType, listType}, listType,
+				cel.BinaryBinding(func(a, b ref.Val) ref.Val {
+					other := newValueSet()
+					other.addAll(b.(traits.Lister))
+					return filterSet(a.(traits.Lister), func(elem ref.Val) bool {
+						return !other.contains(elem)
+					})
+				}),
+			),
+		),
+		cel.Function("sets.distinct",
+			cel.Overload("wasmcel_sets_distinct_list", []*cel.Type{listType}, listType,
+				cel.UnaryBinding(func(a ref.Val) ref.Val {
+					result := newValueSet()
+					result.addAll(a.(traits.Lister))
+					return result.list()
+				}),
+			),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (setOperationsLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// filterSet returns the distinct elements of a list keep accepts
+func filterSet(list traits.Lister, keep func(ref.Val) bool) ref.Val {
+	result := newValueSet()
+	for it := list.Iterator(); it.HasNext() == types.True; {
+		if elem := it.Next(); keep(elem) {
+			result.add(elem)
+		}
+	}
+	return result.list()
+}
+
+// valueSet is a set of CEL values in insertion order, compared with CEL equality
+// Scalars are looked up by key, and other values, such as lists and maps, one by one
+type valueSet struct {
+	keys   map[interface{}]struct{}
+	others []ref.Val
+	elems  []ref.Val
+}
+
+// newValueSet returns an empty valueSet
+func newValueSet() *valueSet {
+	return &valueSet{keys: make(map[interface{}]struct{})}
+}
+
+// bytesKey keys bytes apart from strings holding the same text
+type bytesKey string
+
+// valueSetKey returns the key of a scalar, which is the same for numbers CEL equality
+// considers equal
+func valueSetKey(val ref.Val) (interface{}, bool) {
+	switch val := val.(type) {
+	case types.Bool:
+		return bool(val), true
+	case types.String:
+		return string(val), true
+	case types.Bytes:
+		return bytesKey(val), true
+	case types.Int:
+		return int64(val), true
+	case types.Uint:
+		if val <= math.MaxInt64 {
+			return int64(val), true
+		}
+		return uint64(val), true
+	case types.Double:
+		number := float64(val)
+		if number != math.Trunc(number) {
+			return number, true
+		}
+		if number >= math.MinInt64 && number < math.MaxInt64 {
+			return int64(number), true
+		}
+		if number >= 0 && number < math.MaxUint64 {
+			return uint64(number), true
+		}
+		return number, true
+	}
+	return nil, false
+}
+
+// contains reports whether the set holds a value
+func (s *valueSet) contains(val ref.Val) bool {
+	if key, ok := valueSetKey(val); ok {
+		_, exists := s.keys[key]
+		return exists
+	}
+	for _, other := range s.others {
+		if val.Equal(other) == types.True {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds a value the set doesn't hold yet
+func (s *valueSet) add(val ref.Val) {
+	if s.contains(val) {
+		return
+	}
+	if key, ok := valueSetKey(val); ok {
+		s.keys[key] = struct{}{}
+	} else {
+		s.others = append(s.others, val)
+	}
+	s.elems = append(s.elems, val)
+}
+
+// addAll adds the elements of a list
+func (s *valueSet) addAll(list traits.Lister) {
+	for it := list.Iterator(); it.HasNext() == types.True; {
+		s.add(it.Next())
+	}
+}
+
+// list returns the values of the set as a CEL list
+func (s *valueSet) list() ref.Val {
+	return types.DefaultTypeAdapter.NativeToValue(s.elems)
+}
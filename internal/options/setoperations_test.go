@@ -0,0 +1,57 @@
+package options
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+func TestValueSetKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  ref.Val
+		equal bool
+	}{
+		{"int and uint", types.Int(1), types.Uint(1), true},
+		{"int and double", types.Int(-3), types.Double(-3), true},
+		{"uint and double beyond int64", types.Uint(math.MaxUint64 - 2047), types.Double(math.MaxUint64 - 2047), true},
+		{"fractions", types.Double(0.5), types.Double(0.5), true},
+		{"int and fraction", types.Int(0), types.Double(0.5), false},
+		{"string and bytes", types.String("a"), types.Bytes("a"), false},
+		{"bool and int", types.True, types.Int(1), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, _ := valueSetKey(test.a)
+			b, _ := valueSetKey(test.b)
+			if (a == b) != test.equal {
+				t.Errorf("expected keys %v and %v to be equal: %v", a, b, test.equal)
+			}
+		})
+	}
+
+	if _, ok := valueSetKey(types.NewDynamicList(types.DefaultTypeAdapter, []int{1})); ok {
+		t.Error("expected lists to have no key")
+	}
+}
+
+func TestSetOperationsFunctions(t *testing.T) {
+	env := optionEnv(t, "SetOperations", nil)
+	runOptionTests(t, env, nil, []optionTest{
+		{expr: `sets.union([1, 2], [2, 3])`, expected: []int64{1, 2, 3}},
+		{expr: `sets.union([], [])`, expected: []int64{}},
+		{expr: `sets.intersection([1, 2, 2], [2, 3])`, expected: []int64{2}},
+		{expr: `sets.intersection(["a", "b"], ["b", "c"])`, expected: []string{"b"}},
+		{expr: `sets.difference([1, 2, 3], [dyn(2.0)])`, expected: []int64{1, 3}},
+		{expr: `sets.difference([1, 1, 2], [])`, expected: []int64{1, 2}},
+		// Equal elements keep the first of them
+		{expr: `sets.distinct([1, 1u, 2])`, expected: []int64{1, 2}},
+		{expr: `sets.distinct([b"a", b"a"]).size()`, expected: int64(1)},
+		{expr: `sets.distinct([[1], [1], [2]])`, expected: [][]int64{{1}, {2}}},
+		{expr: `sets.union([{"a": 1}], [{"a": dyn(1.0)}]).size()`, expected: int64(1)},
+		{expr: `sets.union([1], ["a"])`, err: "found no matching overload"},
+	})
+}
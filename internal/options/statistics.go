@@ -0,0 +1,223 @@
+package options
+
+import (
+	"math"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// statisticsElements are the element types of the lists the statistics functions take,
+// with the sum of an empty list of them
+var statisticsElements = []struct {
+	name string
+	typ  *cel.Type
+	zero ref.Val
+}{
+	{"int", cel.IntType, types.Int(0)},
+	{"uint", cel.UintType, types.Uint(0)},
+	{"double", cel.DoubleType, types.Double(0)},
+}
+
+// StatisticsBuilder declares functions aggregating lists of numbers, implemented in Go, so
+// analytics expressions don't fold over lists with comprehensions
+type StatisticsBuilder struct{}
+
+// Name returns the name of this option
+func (b *StatisticsBuilder) Name() string {
+	return "Statistics"
+}
+
+// Description returns the description of this option
+func (b *StatisticsBuilder) Description() string {
+	return "Statistics declares functions aggregating lists of ints, uints or doubles: math.sum() returns their sum,\nmath.min() and math.max() their smallest and largest elements, and math.avg(), math.median() and\nmath.percentile() their mean, median and percentile as doubles. Percentiles are between 0 and 100 and\ninterpolate linearly between elements.\n\nAll but math.sum() fail on empty lists, and sums of ints fail on overflow."
+}
+
+// Build creates the CEL environment option
+func (b *StatisticsBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(statisticsLibrary{}), nil
+}
+
+// FromJSON configures the StatisticsBuilder from JSON parameters
+func (b *StatisticsBuilder) FromJSON(params map[string]interface{}) error {
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *StatisticsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+func init() {
+	DefaultRegistry.Register("Statistics", func() OptionBuilder {
+		return &StatisticsBuilder{}
+	})
+}
+
+// statisticsLibrary declares the statistics functions
+type statisticsLibrary struct{}
+
+// LibraryName implements cel.SingletonLibrary
+func (statisticsLibrary) LibraryName() string {
+	return "wasm-cel.statistics"
+}
+
+// CompileOptions implements cel.Library
+func (statisticsLibrary) CompileOptions() []cel.EnvOption {
+	var sum, min, max, avg, median, percentile []cel.FunctionOpt
+	for _, element := range statisticsElements {
+		list := []*cel.Type{cel.ListType(element.typ)}
+		zero := element.zero
+		sum = append(sum, cel.Overload("wasmcel_math_sum_list_"+element.name, list, element.typ,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				return sumOf(arg.(traits.Lister), zero)
+			}),
+		))
+		min = append(min, cel.Overload("wasmcel_math_min_list_"+element.name, list, element.typ,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				return extremeOf("math.min", arg.(traits.Lister), types.IntNegOne)
+			}),
+		))
+		max = append(max, cel.Overload("wasmcel_math_max_list_"+element.name, list, element.typ,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				return extremeOf("math.max", arg.(traits.Lister), types.IntOne)
+			}),
+		))
+		avg = append(avg, cel.Overload("wasmcel_math_avg_list_"+element.name, list, cel.DoubleType,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				values, err := floatsOf("math.avg", arg.(traits.Lister))
+				if err != nil {
+					return err
+				}
+				total := 0.0
+				for _, value := range values {
+					total += value
+				}
+				return types.Double(total / float64(len(values)))
+			}),
+		))
+		median = append(median, cel.Overload("wasmcel_math_median_list_"+element.name, list, cel.DoubleType,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				return percentileOf("math.median", arg.(traits.Lister), types.Double(50))
+			}),
+		))
+		for _, rank := range []struct {
+			name string
+			typ  *cel.Type
+		}{{"int", cel.IntType}, {"double", cel.DoubleType}} {
+			percentile = append(percentile, cel.Overload("wasmcel_math_percentile_list_"+element.name+"_"+rank.name,
+				[]*cel.Type{cel.ListType(element.typ), rank.typ}, cel.DoubleType,
+				cel.BinaryBinding(func(arg, rank ref.Val) ref.Val {
+					return percentileOf("math.percentile", arg.(traits.Lister), rank)
+				}),
+			))
+		}
+	}
+	return []cel.EnvOption{
+		cel.Function("math.sum", sum...),
+		cel.Function("math.min", min...),
+		cel.Function("math.max", max...),
+		cel.Function("math.avg", avg...),
+		cel.Function("math.median", median...),
+		cel.Function("math.percentile", percentile...),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (statisticsLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// sumOf adds up the elements of a list with CEL's addition, so ints fail on overflow
+func sumOf(list traits.Lister, zero ref.Val) ref.Val {
+	total := zero
+	for it := list.Iterator(); it.HasNext() == types.True; {
+		adder, ok := total.(traits.Adder)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(total)
+		}
+		total = adder.Add(it.Next())
+		if types.IsError(total) {
+			return total
+		}
+	}
+	return total
+}
+
+// extremeOf returns the element of a non-empty list that compares to every other element
+// the way order does, keeping the first of equal elements
+func extremeOf(function string, list traits.Lister, order types.Int) ref.Val {
+	var extreme ref.Val
+	for it := list.Iterator(); it.HasNext() == types.True; {
+		elem := it.Next()
+		if extreme == nil {
+			extreme = elem
+			continue
+		}
+		comparer, ok := elem.(traits.Comparer)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(elem)
+		}
+		cmp := comparer.Compare(extreme)
+		if types.IsError(cmp) {
+			return cmp
+		}
+		if cmp == order {
+			extreme = elem
+		}
+	}
+	if extreme == nil {
+		return types.NewErr("%s() of an empty list", function)
+	}
+	return extreme
+}
+
+// floatsOf returns the elements of a non-empty list of numbers as floats
+func floatsOf(function string, list traits.Lister) ([]float64, ref.Val) {
+	var values []float64
+	for it := list.Iterator(); it.HasNext() == types.True; {
+		switch elem := it.Next().(type) {
+		case types.Int:
+			values = append(values, float64(elem))
+		case types.Uint:
+			values = append(values, float64(elem))
+		case types.Double:
+			values = append(values, float64(elem))
+		default:
+			return nil, types.MaybeNoSuchOverloadErr(elem)
+		}
+	}
+	if len(values) == 0 {
+		return nil, types.NewErr("%s() of an empty list", function)
+	}
+	return values, nil
+}
+
+// percentileOf returns a percentile of a non-empty list of numbers, interpolating linearly
+// between the elements around it
+func percentileOf(function string, list traits.Lister, rank ref.Val) ref.Val {
+	var p float64
+	switch rank := rank.(type) {
+	case types.Int:
+		p = float64(rank)
+	case types.Double:
+		p = float64(rank)
+	default:
+		return types.MaybeNoSuchOverloadErr(rank)
+	}
+	if !(p >= 0 && p <= 100) {
+		return types.NewErr("%s() percentile must be between 0 and 100, got %v", function, p)
+	}
+	values, err := floatsOf(function, list)
+	if err != nil {
+		return err
+	}
+	sort.Float64s(values)
+	position := p / 100 * float64(len(values)-1)
+	lower, upper := math.Floor(position), math.Ceil(position)
+	fraction := position - lower
+	return types.Double(values[int(lower)]*(1-fraction) + values[int(upper)]*fraction)
+}
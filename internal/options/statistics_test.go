@@ -0,0 +1,40 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+func TestStatisticsFunctions(t *testing.T) {
+	env := optionEnv(t, "Statistics", nil, cel.Variable("xs", cel.ListType(cel.DoubleType)))
+	runOptionTests(t, env, map[string]interface{}{"xs": []float64{3.5, 1, 10, 2}}, []optionTest{
+		{expr: `math.sum(xs)`, expected: 16.5},
+		{expr: `math.min(xs)`, expected: 1.0},
+		{expr: `math.max(xs)`, expected: 10.0},
+		{expr: `math.avg(xs)`, expected: 4.125},
+		{expr: `math.median(xs)`, expected: 2.75},
+		{expr: `math.percentile(xs, 0)`, expected: 1.0},
+		{expr: `math.percentile(xs, 90)`, expected: 8.05},
+		{expr: `math.percentile(xs, 100.0)`, expected: 10.0},
+		{expr: `math.percentile(xs, 101)`, err: "percentile must be between 0 and 100, got 101"},
+		{expr: `math.percentile(xs, -0.5)`, err: "percentile must be between 0 and 100"},
+		{expr: `math.percentile(xs, double("NaN"))`, err: "percentile must be between 0 and 100"},
+
+		// Sums keep the type of the elements, and the others are doubles
+		{expr: `math.sum([1, 2, 3])`, expected: int64(6)},
+		{expr: `math.sum([1u, 2u])`, expected: uint64(3)},
+		{expr: `math.sum([])`, expected: int64(0)},
+		{expr: `math.min([3, -1, 2])`, expected: int64(-1)},
+		{expr: `math.max([1u, 7u])`, expected: uint64(7)},
+		{expr: `math.avg([1, 2])`, expected: 1.5},
+		{expr: `math.median([5])`, expected: 5.0},
+		{expr: `math.median([4u, 1u, 3u])`, expected: 3.0},
+
+		{expr: `math.sum([9223372036854775807, 1])`, err: "overflow"},
+		{expr: `math.min([0].filter(x, x > 0))`, err: "math.min() of an empty list"},
+		{expr: `math.avg([0.0].filter(x, x > 0.0))`, err: "math.avg() of an empty list"},
+		{expr: `math.percentile([0.0].filter(x, x > 0.0), 50)`, err: "math.percentile() of an empty list"},
+		{expr: `math.sum(["a"])`, err: "found no matching overload"},
+	})
+}
@@ -0,0 +1,165 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// SubOptionBuilder is implemented by builders of functional sub-options, such as
+// cel.OptionalTypesVersion, that are passed to a variadic option parameter
+type SubOptionBuilder[T any] interface {
+	FromJSON
+	ParamsSchemaProvider
+	// Description returns the description of the sub-option
+	Description() string
+	// Build creates the sub-option
+	Build() (T, error)
+}
+
+// subOptionsFromJSON builds the sub-options listed under params[name] as
+// {"type": ..., "params": ...} entries, returning nil if the key is absent
+func subOptionsFromJSON[T any](params map[string]interface{}, name string, builders map[string]func() SubOptionBuilder[T]) ([]T, error) {
+	value, exists := params[name]
+	if !exists {
+		return nil, nil
+	}
+
+	entries, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array", name)
+	}
+
+	result := make([]T, 0, len(entries))
+	for i, entry := range entries {
+		config, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be an object", name, i)
+		}
+
+		subOptionType, ok := config["type"].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d].type must be a string", name, i)
+		}
+
+		factory, ok := builders[subOptionType]
+		if !ok {
+			return nil, fmt.Errorf("%s[%d]: unknown sub-option %q", name, i, subOptionType)
+		}
+
+		subParams := map[string]interface{}{}
+		if rawParams, exists := config["params"]; exists && rawParams != nil {
+			subParams, ok = rawParams.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s[%d].params must be an object", name, i)
+			}
+		}
+
+		builder := factory()
+		if err := builder.FromJSON(subParams); err != nil {
+			return nil, fmt.Errorf("%s[%d] (%s): %w", name, i, subOptionType, err)
+		}
+
+		subOption, err := builder.Build()
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d] (%s): %w", name, i, subOptionType, err)
+		}
+		result = append(result, subOption)
+	}
+
+	return result, nil
+}
+
+// subOptionsSchema builds the JSON Schema for a list of sub-option entries
+func subOptionsSchema[T any](builders map[string]func() SubOptionBuilder[T]) map[string]interface{} {
+	names := make([]string, 0, len(builders))
+	for name := range builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	variants := make([]interface{}, len(names))
+	for i, name := range names {
+		builder := builders[name]()
+		variants[i] = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type":   map[string]interface{}{"const": name},
+				"params": builder.ParamsSchema(),
+			},
+			"required":             []interface{}{"type"},
+			"additionalProperties": false,
+			"description":          builder.Description(),
+		}
+	}
+
+	return map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"oneOf": variants},
+		"description": "Sub-options, each given as {type, params}",
+	}
+}
+
+// stringParam reads a required string parameter
+func stringParam(params map[string]interface{}, name string) (string, error) {
+	value, ok := params[name].(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+	return value, nil
+}
+
+// boolParam reads a required boolean parameter
+func boolParam(params map[string]interface{}, name string) (bool, error) {
+	value, ok := params[name].(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", name)
+	}
+	return value, nil
+}
+
+// floatParam reads a required number parameter
+func floatParam(params map[string]interface{}, name string) (float64, error) {
+	value, ok := params[name].(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return value, nil
+}
+
+// intParam reads a required integer parameter that fits in a signed integer of the given
+// bit size, where 0 means the platform int size
+func intParam(params map[string]interface{}, name string, bitSize int) (int64, error) {
+	value, err := floatParam(params, name)
+	if err != nil {
+		return 0, err
+	}
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+
+	limit := math.Ldexp(1, bitSize-1)
+	if value != math.Trunc(value) || value < -limit || value >= limit {
+		return 0, fmt.Errorf("%s must be an integer in the %d-bit range", name, bitSize)
+	}
+	return int64(value), nil
+}
+
+// uintParam reads a required non-negative integer parameter that fits in an unsigned
+// integer of the given bit size, where 0 means the platform uint size
+func uintParam(params map[string]interface{}, name string, bitSize int) (uint64, error) {
+	value, err := floatParam(params, name)
+	if err != nil {
+		return 0, err
+	}
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+
+	limit := math.Ldexp(1, bitSize)
+	if value != math.Trunc(value) || value < 0 || value >= limit {
+		return 0, fmt.Errorf("%s must be a non-negative integer in the %d-bit range", name, bitSize)
+	}
+	return uint64(value), nil
+}
@@ -0,0 +1,255 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// systemOverloadPrefix prefixes the overload IDs of the system functions
+const systemOverloadPrefix = "wasmcel_system_"
+
+// SystemFunctionsBuilder declares uuid(), random() and now(), whose results depend on
+// when and how often they're called rather than on their arguments
+// In deterministic mode, every evaluation seeds the random source with Seed and freezes
+// the clock at Now, so rules calling them are reproducible. Evaluations can also inject
+// their own seed and clock, in either mode.
+type SystemFunctionsBuilder struct {
+	Deterministic bool
+	Seed          int64
+	Now           time.Time
+}
+
+// Name returns the name of this option
+func (b *SystemFunctionsBuilder) Name() string {
+	return "SystemFunctions"
+}
+
+// Description returns the description of this option
+func (b *SystemFunctionsBuilder) Description() string {
+	return "SystemFunctions declares uuid(), returning a random version 4 UUID string, random(), returning a double\nin [0, 1), and now(), returning the current timestamp, which is the same for every call of an evaluation.\n\nIn deterministic mode, every evaluation seeds the random source with the seed and freezes the clock at now,\nso rules calling the functions are reproducible. Evaluations can inject their own seed and clock in either\nmode. The functions are never folded into constants, and programs calling them can't be memoized."
+}
+
+// SetDeterministic sets the deterministic parameter
+func (b *SystemFunctionsBuilder) SetDeterministic(deterministic bool) *SystemFunctionsBuilder {
+	b.Deterministic = deterministic
+	return b
+}
+
+// SetSeed sets the seed parameter
+func (b *SystemFunctionsBuilder) SetSeed(seed int64) *SystemFunctionsBuilder {
+	b.Seed = seed
+	return b
+}
+
+// SetNow sets the now parameter
+func (b *SystemFunctionsBuilder) SetNow(now time.Time) *SystemFunctionsBuilder {
+	b.Now = now
+	return b
+}
+
+// Build creates the CEL environment option
+func (b *SystemFunctionsBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(&systemLibrary{deterministic: b.Deterministic, seed: b.Seed, now: b.Now}), nil
+}
+
+// FromJSON configures the SystemFunctionsBuilder from JSON parameters
+func (b *SystemFunctionsBuilder) FromJSON(params map[string]interface{}) error {
+	if value, exists := params["deterministic"]; exists {
+		deterministic, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("deterministic must be a boolean")
+		}
+		b.SetDeterministic(deterministic)
+	}
+	if value, exists := params["seed"]; exists {
+		seed, ok := value.(float64)
+		if !ok || seed != math.Trunc(seed) || math.Abs(seed) > 1<<53 {
+			return fmt.Errorf("seed must be an integer")
+		}
+		b.SetSeed(int64(seed))
+	}
+	if value, exists := params["now"]; exists {
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("now must be an RFC 3339 timestamp string")
+		}
+		now, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return fmt.Errorf("now must be an RFC 3339 timestamp string: %v", err)
+		}
+		b.SetNow(now)
+	}
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *SystemFunctionsBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{
+		"deterministic": map[string]interface{}{
+			"type":        "boolean",
+			"default":     false,
+			"description": "Seed the random source and freeze the clock of every evaluation",
+		},
+		"seed": map[string]interface{}{
+			"type":        "integer",
+			"default":     0,
+			"description": "Seed of the random source of uuid() and random() in deterministic mode",
+		},
+		"now": map[string]interface{}{
+			"type":        "string",
+			"format":      "date-time",
+			"default":     "1970-01-01T00:00:00Z",
+			"description": "Timestamp now() returns in deterministic mode",
+		},
+	})
+}
+
+func init() {
+	DefaultRegistry.Register("SystemFunctions", func() OptionBuilder {
+		return &SystemFunctionsBuilder{Now: time.Unix(0, 0).UTC()}
+	})
+}
+
+// IsSystemOverload reports whether an overload ID is one of the system functions, which
+// are impure: their results aren't determined by their arguments
+func IsSystemOverload(overloadID string) bool {
+	return strings.HasPrefix(overloadID, systemOverloadPrefix)
+}
+
+// systemEvaluation is the clock and random source the system functions of an evaluation
+// share, resolved on first use
+type systemEvaluation struct {
+	now   *time.Time // Clock injected by the evaluation
+	seed  *int64     // Seed injected by the evaluation
+	clock *time.Time
+	rand  *rand.Rand
+}
+
+// systemEval is the state of the running evaluation, if any
+var systemEval *systemEvaluation
+
+// StartSystemEvaluation gives the system functions of the evaluation that's starting
+// their own clock and random source, frozen at now and seeded with seed unless they're nil
+// Call the returned function once the evaluation ends
+func StartSystemEvaluation(now *time.Time, seed *int64) (stop func()) {
+	systemEval = &systemEvaluation{now: now, seed: seed}
+	return func() {
+		systemEval = nil
+	}
+}
+
+// SuspendSystemEvaluation detaches the state of the running evaluation, so other
+// evaluations can run while a custom function waits for its result
+// Call the returned function to reattach it before the evaluation continues
+func SuspendSystemEvaluation() (resume func()) {
+	state := systemEval
+	systemEval = nil
+	return func() {
+		systemEval = state
+	}
+}
+
+// systemLibrary declares the system functions
+type systemLibrary struct {
+	deterministic bool
+	seed          int64
+	now           time.Time
+}
+
+// LibraryName implements cel.SingletonLibrary
+func (l *systemLibrary) LibraryName() string {
+	return "wasm-cel.system"
+}
+
+// CompileOptions implements cel.Library
+func (l *systemLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("uuid",
+			cel.Overload(systemOverloadPrefix+"uuid", nil, cel.StringType,
+				cel.FunctionBinding(func(...ref.Val) ref.Val {
+					return types.String(l.uuid())
+				}),
+			),
+		),
+		cel.Function("random",
+			cel.Overload(systemOverloadPrefix+"random", nil, cel.DoubleType,
+				cel.FunctionBinding(func(...ref.Val) ref.Val {
+					return types.Double(l.rand().Float64())
+				}),
+			),
+		),
+		cel.Function("now",
+			cel.Overload(systemOverloadPrefix+"now", nil, cel.TimestampType,
+				cel.FunctionBinding(func(...ref.Val) ref.Val {
+					return types.Timestamp{Time: l.clock()}
+				}),
+			),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (l *systemLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// state returns the state of the running evaluation
+// Calls outside of an evaluation, such as while folding constants, get their own
+func (l *systemLibrary) state() *systemEvaluation {
+	if systemEval == nil {
+		return &systemEvaluation{}
+	}
+	return systemEval
+}
+
+// clock returns the time of the running evaluation
+func (l *systemLibrary) clock() time.Time {
+	state := l.state()
+	if state.clock == nil {
+		var now time.Time
+		switch {
+		case state.now != nil:
+			now = *state.now
+		case l.deterministic:
+			now = l.now
+		default:
+			now = time.Now().UTC()
+		}
+		state.clock = &now
+	}
+	return *state.clock
+}
+
+// rand returns the random source of the running evaluation
+func (l *systemLibrary) rand() *rand.Rand {
+	state := l.state()
+	if state.rand == nil {
+		var seed int64
+		switch {
+		case state.seed != nil:
+			seed = *state.seed
+		case l.deterministic:
+			seed = l.seed
+		default:
+			seed = time.Now().UnixNano()
+		}
+		state.rand = rand.New(rand.NewSource(seed))
+	}
+	return state.rand
+}
+
+// uuid returns a version 4 UUID from the random source of the running evaluation
+func (l *systemLibrary) uuid() string {
+	var id [16]byte
+	l.rand().Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // Version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
@@ -0,0 +1,113 @@
+package options
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+func TestSystemFunctionsFromJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		err    string
+	}{
+		{name: "deterministic", params: map[string]interface{}{"deterministic": true, "seed": 42.0, "now": "2024-06-01T12:00:00Z"}},
+		{name: "negative seed", params: map[string]interface{}{"seed": -7.0}},
+		{name: "deterministic not a boolean", params: map[string]interface{}{"deterministic": "yes"}, err: "deterministic must be a boolean"},
+		{name: "fractional seed", params: map[string]interface{}{"seed": 1.5}, err: "seed must be an integer"},
+		{name: "seed out of range", params: map[string]interface{}{"seed": 1e16}, err: "seed must be an integer"},
+		{name: "seed not a number", params: map[string]interface{}{"seed": "42"}, err: "seed must be an integer"},
+		{name: "now not a string", params: map[string]interface{}{"now": 0.0}, err: "now must be an RFC 3339 timestamp string"},
+		{name: "now not RFC 3339", params: map[string]interface{}{"now": "2024-06-01"}, err: "now must be an RFC 3339 timestamp string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder, _ := DefaultRegistry.Create("SystemFunctions")
+			err := builder.(FromJSON).FromJSON(test.params)
+			if test.err == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+// evalSystem evaluates an expression as an evaluation of its own, with an injected clock
+// and seed unless they're nil
+func evalSystem(t *testing.T, env *cel.Env, expr string, now *time.Time, seed *int64) ref.Val {
+	t.Helper()
+	stop := StartSystemEvaluation(now, seed)
+	defer stop()
+	out, err := evalOption(env, expr, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("failed to evaluate %s: %v", expr, err)
+	}
+	return out
+}
+
+func TestSystemFunctions(t *testing.T) {
+	t.Run("deterministic", func(t *testing.T) {
+		env := optionEnv(t, "SystemFunctions", map[string]interface{}{
+			"deterministic": true,
+			"seed":          42.0,
+			"now":           "2024-06-01T12:00:00Z",
+		})
+		tests := []struct {
+			expr     string
+			expected ref.Val
+		}{
+			{`string(now())`, types.String("2024-06-01T12:00:00Z")},
+			// Calls of an evaluation share the clock and the random source
+			{`now() == now()`, types.True},
+			{`random() != random()`, types.True},
+			{`random() >= 0.0 && random() < 1.0`, types.True},
+		}
+		for _, test := range tests {
+			if out := evalSystem(t, env, test.expr, nil, nil); out.Equal(test.expected) != types.True {
+				t.Errorf("%s: expected %v, got %v", test.expr, test.expected, out)
+			}
+		}
+
+		// Every evaluation starts from the seed
+		first := evalSystem(t, env, `[uuid(), random()]`, nil, nil)
+		if second := evalSystem(t, env, `[uuid(), random()]`, nil, nil); first.Equal(second) != types.True {
+			t.Errorf("expected evaluations to repeat, got %v and %v", first, second)
+		}
+	})
+
+	t.Run("uuid", func(t *testing.T) {
+		env := optionEnv(t, "SystemFunctions", nil)
+		version4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		if id := evalSystem(t, env, `uuid()`, nil, nil).Value().(string); !version4.MatchString(id) {
+			t.Errorf("expected a version 4 UUID, got %s", id)
+		}
+	})
+
+	t.Run("injected", func(t *testing.T) {
+		env := optionEnv(t, "SystemFunctions", map[string]interface{}{"deterministic": true})
+		const expr = `string(now()) + " " + uuid()`
+		now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		seed := int64(7)
+
+		injected := evalSystem(t, env, expr, &now, &seed).Value().(string)
+		if !strings.HasPrefix(injected, "2030-01-01T00:00:00Z ") {
+			t.Errorf("expected the injected clock, got %s", injected)
+		}
+		if again := evalSystem(t, env, expr, &now, &seed).Value().(string); again != injected {
+			t.Errorf("expected evaluations with the same seed to repeat, got %s and %s", injected, again)
+		}
+		configured := evalSystem(t, env, expr, nil, nil).Value().(string)
+		if configured == injected || !strings.HasPrefix(configured, "1970-01-01T00:00:00Z ") {
+			t.Errorf("expected the configured clock and seed without injection, got %s", configured)
+		}
+	})
+}
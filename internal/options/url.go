@@ -0,0 +1,180 @@
+package options
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// URLType is the CEL type of URLs, named as in the Kubernetes URL library
+var URLType = types.NewOpaqueType("kubernetes.URL")
+
+// URLBuilder declares the functions of the Kubernetes URL library, implemented in Go, so
+// request routing and firewall rules can take URLs apart without custom functions
+type URLBuilder struct{}
+
+// Name returns the name of this option
+func (b *URLBuilder) Name() string {
+	return "URL"
+}
+
+// Description returns the description of this option
+func (b *URLBuilder) Description() string {
+	return "URL declares the functions of the Kubernetes URL library: url() parses absolute URLs and absolute paths,\nisURL() validates strings, and URLs have getScheme(), getHost(), getHostname(), getPort(), getEscapedPath(),\ngetPath() and getQuery(), which returns a map from parameter names to their values.\n\nURLs convert to their string form with string(), and results hold them in that form."
+}
+
+// Build creates the CEL environment option
+func (b *URLBuilder) Build() (cel.EnvOption, error) {
+	return cel.Lib(urlLibrary{}), nil
+}
+
+// FromJSON configures the URLBuilder from JSON parameters
+func (b *URLBuilder) FromJSON(params map[string]interface{}) error {
+	return nil
+}
+
+// ParamsSchema describes the JSON parameters accepted by FromJSON
+func (b *URLBuilder) ParamsSchema() map[string]interface{} {
+	return objectSchema(map[string]interface{}{})
+}
+
+func init() {
+	DefaultRegistry.Register("URL", func() OptionBuilder {
+		return &URLBuilder{}
+	})
+}
+
+// urlValue is a URL during evaluation
+type urlValue struct {
+	url *url.URL
+}
+
+// ConvertToNative implements ref.Val
+func (v urlValue) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	switch {
+	case reflect.TypeOf(v.url).AssignableTo(typeDesc):
+		return v.url, nil
+	case typeDesc.Kind() == reflect.String:
+		return v.url.String(), nil
+	}
+	return nil, fmt.Errorf("type conversion error from '%s' to '%v'", URLType, typeDesc)
+}
+
+// ConvertToType implements ref.Val
+func (v urlValue) ConvertToType(typeVal ref.Type) ref.Val {
+	switch typeVal {
+	case types.StringType:
+		return types.String(v.url.String())
+	case types.TypeType:
+		return URLType
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", URLType, typeVal)
+}
+
+// Equal implements ref.Val
+func (v urlValue) Equal(other ref.Val) ref.Val {
+	o, ok := other.(urlValue)
+	return types.Bool(ok && v.url.String() == o.url.String())
+}
+
+// Type implements ref.Val
+func (v urlValue) Type() ref.Type {
+	return URLType
+}
+
+// Value implements ref.Val
+func (v urlValue) Value() any {
+	return v.url
+}
+
+// parseURL parses a URL the way the Kubernetes URL library does, accepting absolute URLs
+// and absolute paths
+func parseURL(text string) (*url.URL, error) {
+	parsed, err := url.ParseRequestURI(text)
+	if err != nil {
+		return nil, fmt.Errorf("URL parse error during conversion from string: %v", err)
+	}
+	return parsed, nil
+}
+
+// urlLibrary declares the URL functions
+type urlLibrary struct{}
+
+// LibraryName implements cel.SingletonLibrary
+func (urlLibrary) LibraryName() string {
+	return "wasm-cel.url"
+}
+
+// CompileOptions implements cel.Library
+func (urlLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("url",
+			cel.Overload("wasmcel_url_string", []*cel.Type{cel.StringType}, URLType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					parsed, err := parseURL(string(arg.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return urlValue{parsed}
+				}),
+			),
+		),
+		cel.Function("isURL",
+			cel.Overload("wasmcel_is_url_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					_, err := parseURL(string(arg.(types.String)))
+					return types.Bool(err == nil)
+				}),
+			),
+		),
+		urlMember("getScheme", cel.StringType, func(u *url.URL) ref.Val {
+			return types.String(u.Scheme)
+		}),
+		urlMember("getHost", cel.StringType, func(u *url.URL) ref.Val {
+			return types.String(u.Host)
+		}),
+		urlMember("getHostname", cel.StringType, func(u *url.URL) ref.Val {
+			return types.String(u.Hostname())
+		}),
+		urlMember("getPort", cel.StringType, func(u *url.URL) ref.Val {
+			return types.String(u.Port())
+		}),
+		urlMember("getEscapedPath", cel.StringType, func(u *url.URL) ref.Val {
+			return types.String(u.EscapedPath())
+		}),
+		urlMember("getPath", cel.StringType, func(u *url.URL) ref.Val {
+			return types.String(u.Path)
+		}),
+		urlMember("getQuery", cel.MapType(cel.StringType, cel.ListType(cel.StringType)), func(u *url.URL) ref.Val {
+			return types.DefaultTypeAdapter.NativeToValue(map[string][]string(u.Query()))
+		}),
+
+		cel.Function("string",
+			cel.Overload("wasmcel_string_url", []*cel.Type{URLType}, cel.StringType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					return arg.ConvertToType(types.StringType)
+				}),
+			),
+		),
+	}
+}
+
+// ProgramOptions implements cel.Library
+func (urlLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// urlMember declares a method of URLs without arguments
+func urlMember(name string, result *cel.Type, fn func(*url.URL) ref.Val) cel.EnvOption {
+	return cel.Function(name,
+		cel.MemberOverload("wasmcel_url_"+name, []*cel.Type{URLType}, result,
+			cel.UnaryBinding(func(arg ref.Val) ref.Val {
+				return fn(arg.(urlValue).url)
+			}),
+		),
+	)
+}
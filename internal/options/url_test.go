@@ -0,0 +1,55 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		text  string
+		valid bool
+	}{
+		{"https://example.com", true},
+		{"https://user@example.com:8443/a?b=c", true},
+		{"/admin", true},
+		// Parsed as a path, like request URIs are
+		{"//example.com", true},
+		{"mailto:alice@example.com", true},
+		{"admin", false},
+		{"", false},
+		{"https://example.com/%zz", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if _, err := parseURL(test.text); (err == nil) != test.valid {
+				t.Errorf("expected valid: %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestURLFunctions(t *testing.T) {
+	env := optionEnv(t, "URL", nil, cel.Variable("request", cel.StringType))
+	vars := map[string]interface{}{"request": "https://example.com:8443/a%20b?x=1&x=2&y"}
+	runOptionTests(t, env, vars, []optionTest{
+		{expr: `url(request).getScheme()`, expected: "https"},
+		{expr: `url(request).getHost()`, expected: "example.com:8443"},
+		{expr: `url(request).getHostname()`, expected: "example.com"},
+		{expr: `url(request).getPort()`, expected: "8443"},
+		{expr: `url(request).getEscapedPath()`, expected: "/a%20b"},
+		{expr: `url(request).getPath()`, expected: "/a b"},
+		{expr: `url(request).getQuery()`, expected: map[string][]string{"x": {"1", "2"}, "y": {""}}},
+		{expr: `url("https://[::1]:80/").getHostname()`, expected: "::1"},
+		{expr: `url("/a?b=c").getHost()`, expected: ""},
+		{expr: `url("https://example.com").getPort()`, expected: ""},
+		{expr: `url("https://example.com").getQuery()`, expected: map[string][]string{}},
+		{expr: `string(url("/a?b=c"))`, expected: "/a?b=c"},
+		{expr: `url("/a") == url("/a")`, expected: true},
+		{expr: `isURL("/admin")`, expected: true},
+		{expr: `isURL("admin")`, expected: false},
+		{expr: `url("admin")`, err: "URL parse error"},
+	})
+}
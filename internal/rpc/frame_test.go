@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	for _, body := range []string{`{"jsonrpc":"2.0"}`, ""} {
+		if err := writeFrame(&buf, []byte(body)); err != nil {
+			t.Fatalf("failed to write %q: %v", body, err)
+		}
+	}
+
+	if got := binary.BigEndian.Uint32(buf.Bytes()[:4]); got != 17 {
+		t.Fatalf("expected a length prefix of 17, got %d", got)
+	}
+	for _, want := range []string{`{"jsonrpc":"2.0"}`, ""} {
+		body, err := readFrame(&buf)
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", want, err)
+		}
+		if string(body) != want {
+			t.Fatalf("expected %q, got %q", want, body)
+		}
+	}
+	if _, err := readFrame(&buf); err != io.EOF {
+		t.Fatalf("expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-1])
+	if _, err := readFrame(truncated); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF for a truncated body, got %v", err)
+	}
+	if _, err := readFrame(bytes.NewReader([]byte{0, 0})); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF for a truncated header, got %v", err)
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], MaxFrameSize+1)
+	if _, err := readFrame(bytes.NewReader(header[:])); err == nil {
+		t.Fatal("expected an error for a frame over MaxFrameSize")
+	}
+}
@@ -0,0 +1,797 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+// method handles the params of a single JSON-RPC method
+type method func(params json.RawMessage) (interface{}, error)
+
+// methods maps JSON-RPC method names to their handlers, mirroring the WASM exports
+var methods = map[string]method{
+	"defineGlobalFunction":          defineGlobalFunction,
+	"registerLibrary":               registerLibrary,
+	"createEnv":                     createEnv,
+	"createEnvFromJSONSchema":       createEnvFromJSONSchema,
+	"createEnvFromPreset":           createEnvFromPreset,
+	"loadBundle":                    loadBundle,
+	"extendEnv":                     extendEnv,
+	"compileExpr":                   compileExpr,
+	"compileExprDetailed":           compileExprDetailed,
+	"instantiateTemplate":           instantiateTemplate,
+	"recompilePrograms":             recompilePrograms,
+	"replaceProgram":                replaceProgram,
+	"warmup":                        warmup,
+	"exportCheckedExpr":             exportCheckedExpr,
+	"programFromCheckedExpr":        programFromCheckedExpr,
+	"typecheckExpr":                 typecheckExpr,
+	"canonicalHash":                 canonicalHash,
+	"diffExprs":                     diffExprs,
+	"mutate":                        mutate,
+	"describeEnv":                   describeEnv,
+	"listFunctions":                 listFunctions,
+	"generateSampleVars":            generateSampleVars,
+	"evaluate":                      evaluate,
+	"evalProgram":                   evalProgram,
+	"evalPrograms":                  evalPrograms,
+	"evalRepeated":                  evalRepeated,
+	"explain":                       explain,
+	"runTests":                      runTests,
+	"evalProgramWithContextMessage": evalProgramWithContextMessage,
+	"destroyEnv":                    destroyEnv,
+	"destroyProgram":                destroyProgram,
+	"createRuleSet":                 createRuleSet,
+	"evalRuleSet":                   evalRuleSet,
+	"destroyRuleSet":                destroyRuleSet,
+	"compilePolicy":                 compilePolicy,
+	"evalPolicy":                    evalPolicy,
+	"destroyPolicy":                 destroyPolicy,
+	"createSession":                 createSession,
+	"destroySession":                destroySession,
+	"configure":                     configure,
+	"sweep":                         sweep,
+	"setLogger":                     setLogger,
+	"setLifecycleListener":          setLifecycleListener,
+	"getCapabilities":               getCapabilities,
+	"describeOptions":               describeOptions,
+	"shutdown":                      shutdown,
+}
+
+// dispatch runs a method, returning its result or a JSON-RPC error
+// Engine failures are reported in the result's "error" key, as in the WASM API
+func (s *Server) dispatch(name string, params json.RawMessage) (interface{}, *Error) {
+	handler, ok := methods[name]
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", name)}
+	}
+
+	// Blocking reads on stdin leave no room for a timer, so idle handles are swept between requests
+	celengine.SweepIfDue()
+
+	result, err := callMethod(handler, params)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// callMethod runs a handler, returning panics as results with an internal error
+// like the WASM exports do
+func callMethod(handler method, params json.RawMessage) (result interface{}, err error) {
+	defer celengine.RecoverPanic(&result)
+	return handler(params)
+}
+
+// decodeParams decodes the params object into target, allowing params to be omitted
+func decodeParams(params json.RawMessage, target interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, target); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
+
+// optionalJSON returns the raw JSON as a string pointer, or nil if it is absent
+func optionalJSON(raw json.RawMessage) *string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	value := string(raw)
+	return &value
+}
+
+func defineGlobalFunction(params json.RawMessage) (interface{}, error) {
+	var p celengine.FunctionDef
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" || p.ImplID == "" {
+		return nil, fmt.Errorf("expected params: name string, params array, returnType, implID string, isPure bool (optional)")
+	}
+	return celengine.DefineGlobalFunction(p), nil
+}
+
+func createEnv(params json.RawMessage) (interface{}, error) {
+	var config celengine.EnvConfig
+	if err := decodeParams(params, &config); err != nil {
+		return nil, err
+	}
+	return celengine.CreateEnvWithConfig(config), nil
+}
+
+func createEnvFromJSONSchema(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Schema json.RawMessage `json:"schema"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Schema) == 0 || string(p.Schema) == "null" {
+		return nil, fmt.Errorf("expected params: schema object")
+	}
+	return celengine.CreateEnvFromJSONSchema(string(p.Schema)), nil
+}
+
+func createEnvFromPreset(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Name      string `json:"name"`
+		SessionID string `json:"sessionID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, fmt.Errorf("expected params: name string, sessionID string (optional)")
+	}
+	return celengine.CreateEnvFromPreset(p.Name, p.SessionID), nil
+}
+
+func loadBundle(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Bundle                    json.RawMessage `json:"bundle"`
+		SessionID                 string          `json:"sessionID"`
+		PublicKey                 []byte          `json:"publicKey"` // Base64 Ed25519 public key
+		SkipSignatureVerification bool            `json:"skipSignatureVerification"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Bundle) == 0 || string(p.Bundle) == "null" {
+		return nil, fmt.Errorf("expected params: bundle object, sessionID string (optional), publicKey string (optional), skipSignatureVerification bool (optional)")
+	}
+	if p.PublicKey == nil && p.SkipSignatureVerification {
+		return celengine.LoadUnverifiedBundle(p.Bundle, p.SessionID), nil
+	}
+	return celengine.LoadBundleWithKey(p.Bundle, p.SessionID, p.PublicKey), nil
+}
+
+func registerLibrary(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Name string `json:"name"`
+		celengine.Library
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, fmt.Errorf("expected params: name string, varDecls array (optional), funcDefs array (optional), options array (optional)")
+	}
+	return celengine.RegisterLibrary(p.Name, p.Library), nil
+}
+
+func extendEnv(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID   string          `json:"envID"`
+		Options json.RawMessage `json:"options"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" || len(p.Options) == 0 {
+		return nil, fmt.Errorf("expected params: envID string, options array")
+	}
+
+	return celengine.ExtendEnv(p.EnvID, string(p.Options)), nil
+}
+
+// compileParams are shared by compileExpr and compileExprDetailed
+type compileParams struct {
+	EnvID          string          `json:"envID"`
+	Expr           string          `json:"expr"`
+	ProgramOptions json.RawMessage `json:"programOptions"`
+	celengine.CompileFlags
+}
+
+func decodeCompileParams(params json.RawMessage) (compileParams, error) {
+	var p compileParams
+	if err := decodeParams(params, &p); err != nil {
+		return p, err
+	}
+	if p.EnvID == "" {
+		return p, fmt.Errorf("expected params: envID string, expr string, programOptions array (optional), metrics bool (optional), optimize bool (optional), optimizedSource bool (optional), memoize number (optional), fieldMask bool (optional), sourceMap bool (optional)")
+	}
+	return p, nil
+}
+
+func compileExpr(params json.RawMessage) (interface{}, error) {
+	p, err := decodeCompileParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return celengine.CompileWithFlags(p.EnvID, p.Expr, optionalJSON(p.ProgramOptions), p.CompileFlags), nil
+}
+
+func compileExprDetailed(params json.RawMessage) (interface{}, error) {
+	p, err := decodeCompileParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return celengine.CompileDetailedWithFlags(p.EnvID, p.Expr, optionalJSON(p.ProgramOptions), p.CompileFlags), nil
+}
+
+func instantiateTemplate(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID          string                 `json:"envID"`
+		Template       string                 `json:"template"`
+		Bindings       map[string]interface{} `json:"bindings"`
+		ProgramOptions json.RawMessage        `json:"programOptions"`
+		celengine.CompileFlags
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, template string, bindings object, programOptions array (optional), plus the flags of compileExpr")
+	}
+
+	return celengine.InstantiateTemplateWithFlags(p.EnvID, p.Template, p.Bindings, optionalJSON(p.ProgramOptions), p.CompileFlags), nil
+}
+
+func typecheckExpr(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID   string                     `json:"envID"`
+		Expr    string                     `json:"expr"`
+		Options celengine.TypecheckOptions `json:"options"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, expr string, options? object")
+	}
+
+	return celengine.TypecheckWithOptions(p.EnvID, p.Expr, p.Options), nil
+}
+
+func canonicalHash(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID string `json:"envID"`
+		Expr  string `json:"expr"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, expr string")
+	}
+
+	return celengine.CanonicalHash(p.EnvID, p.Expr), nil
+}
+
+func diffExprs(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID string `json:"envID"`
+		ExprA string `json:"exprA"`
+		ExprB string `json:"exprB"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, exprA string, exprB string")
+	}
+
+	return celengine.DiffExprs(p.EnvID, p.ExprA, p.ExprB), nil
+}
+
+func mutate(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID string `json:"envID"`
+		Expr  string `json:"expr"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, expr string")
+	}
+
+	return celengine.Mutate(p.EnvID, p.Expr), nil
+}
+
+func describeEnv(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID string `json:"envID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string")
+	}
+
+	return celengine.DescribeEnv(p.EnvID), nil
+}
+
+func listFunctions(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID   string                         `json:"envID"`
+		Options celengine.ListFunctionsOptions `json:"options"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, options? object")
+	}
+
+	return celengine.ListFunctions(p.EnvID, p.Options), nil
+}
+
+func generateSampleVars(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID   string                  `json:"envID"`
+		Options celengine.SampleOptions `json:"options"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, options? object")
+	}
+
+	return celengine.GenerateSampleVars(p.EnvID, p.Options), nil
+}
+
+func evaluate(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Source  string                    `json:"source"`
+		Vars    map[string]interface{}    `json:"vars"`
+		Options celengine.EvaluateOptions `json:"options"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{})
+	}
+
+	return celengine.Evaluate(p.Source, p.Vars, p.Options), nil
+}
+
+func evalProgram(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string                 `json:"programID"`
+		Vars      map[string]interface{} `json:"vars"`
+		celengine.EvalOptions
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string, vars object")
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{})
+	}
+	// NaN and ±Inf can't be written as JSON numbers
+	if p.NonFinite == "" {
+		p.NonFinite = celengine.NonFiniteTagged
+	}
+
+	return celengine.EvalWithOptions(p.ProgramID, p.Vars, p.EvalOptions), nil
+}
+
+func evalPrograms(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramIDs []string               `json:"programIDs"`
+		Vars       map[string]interface{} `json:"vars"`
+		celengine.EvalOptions
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramIDs == nil {
+		return nil, fmt.Errorf("expected params: programIDs array, vars object (optional)")
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{})
+	}
+	// NaN and ±Inf can't be written as JSON numbers
+	if p.NonFinite == "" {
+		p.NonFinite = celengine.NonFiniteTagged
+	}
+
+	return celengine.EvalPrograms(p.ProgramIDs, p.Vars, p.EvalOptions), nil
+}
+
+func evalRepeated(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string                 `json:"programID"`
+		Vars      map[string]interface{} `json:"vars"`
+		N         int                    `json:"n"`
+		celengine.EvalOptions
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string, vars object (optional), n number")
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{})
+	}
+	// NaN and ±Inf can't be written as JSON numbers
+	if p.NonFinite == "" {
+		p.NonFinite = celengine.NonFiniteTagged
+	}
+
+	return celengine.EvalRepeated(p.ProgramID, p.Vars, p.N, p.EvalOptions), nil
+}
+
+func explain(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string                 `json:"programID"`
+		Vars      map[string]interface{} `json:"vars"`
+		celengine.ValueEncoding
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string, vars object (optional)")
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{})
+	}
+	// NaN and ±Inf can't be written as JSON numbers
+	if p.NonFinite == "" {
+		p.NonFinite = celengine.NonFiniteTagged
+	}
+
+	return celengine.Explain(p.ProgramID, p.Vars, p.ValueEncoding), nil
+}
+
+func runTests(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string               `json:"programID"`
+		Cases     []celengine.TestCase `json:"cases"`
+		celengine.EvalOptions
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string, cases array")
+	}
+	// NaN and ±Inf can't be written as JSON numbers
+	if p.NonFinite == "" {
+		p.NonFinite = celengine.NonFiniteTagged
+	}
+
+	return celengine.RunTests(p.ProgramID, p.Cases, p.EvalOptions), nil
+}
+
+func evalProgramWithContextMessage(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID    string          `json:"programID"`
+		TypeName     string          `json:"typeName"`
+		Message      json.RawMessage `json:"message"`
+		MessageBytes []byte          `json:"messageBytes"` // Base64 binary encoding
+		celengine.EvalOptions
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" || p.TypeName == "" || (p.Message == nil) == (p.MessageBytes == nil) {
+		return nil, fmt.Errorf("expected params: programID string, typeName string, and one of message object or messageBytes string")
+	}
+	// NaN and ±Inf can't be written as JSON numbers
+	if p.NonFinite == "" {
+		p.NonFinite = celengine.NonFiniteTagged
+	}
+
+	if p.MessageBytes != nil {
+		return celengine.EvalWithContextMessage(p.ProgramID, p.TypeName, p.MessageBytes, celengine.ContextMessageBinary, p.EvalOptions), nil
+	}
+	return celengine.EvalWithContextMessage(p.ProgramID, p.TypeName, p.Message, celengine.ContextMessageJSON, p.EvalOptions), nil
+}
+
+func recompilePrograms(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID string `json:"envID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string")
+	}
+
+	return celengine.RecompilePrograms(p.EnvID), nil
+}
+
+func replaceProgram(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string `json:"programID"`
+		Expr      string `json:"expr"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string, expr string")
+	}
+
+	return celengine.ReplaceProgram(p.ProgramID, p.Expr), nil
+}
+
+func warmup(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string `json:"programID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string")
+	}
+
+	return celengine.Warmup(p.ProgramID), nil
+}
+
+// exportCheckedExpr returns the binary encoding in base64, as JSON encodes bytes
+func exportCheckedExpr(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string `json:"programID"`
+		Format    string `json:"format"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string, format string (optional)")
+	}
+
+	return celengine.ExportCheckedExpr(p.ProgramID, p.Format), nil
+}
+
+// programFromCheckedExpr takes the binary encoding in base64 as checkedExprBytes, and the
+// JSON and text encodings as checkedExpr. With a publicKey, it takes the signed checked
+// expression object as signedCheckedExpr instead
+func programFromCheckedExpr(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID             string          `json:"envID"`
+		CheckedExpr       *string         `json:"checkedExpr"`
+		CheckedExprBytes  []byte          `json:"checkedExprBytes"`  // Base64 binary encoding
+		SignedCheckedExpr json.RawMessage `json:"signedCheckedExpr"` // With base64 fields
+		PublicKey         []byte          `json:"publicKey"`         // Base64 Ed25519 public key
+		Format            string          `json:"format"`
+		ProgramOptions    json.RawMessage `json:"programOptions"`
+		celengine.CompileFlags
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.PublicKey != nil {
+		if p.EnvID == "" || p.SignedCheckedExpr == nil || p.CheckedExpr != nil || p.CheckedExprBytes != nil {
+			return nil, fmt.Errorf("expected params with publicKey: envID string, signedCheckedExpr object, format string (optional), programOptions array (optional), plus the flags of compileExpr")
+		}
+		return celengine.ProgramFromCheckedExprWithKey(p.EnvID, p.SignedCheckedExpr, p.Format, optionalJSON(p.ProgramOptions), p.CompileFlags, p.PublicKey), nil
+	}
+	if p.EnvID == "" || (p.CheckedExpr == nil) == (p.CheckedExprBytes == nil) {
+		return nil, fmt.Errorf("expected params: envID string, one of checkedExpr string or checkedExprBytes string, format string (optional), programOptions array (optional), plus the flags of compileExpr")
+	}
+
+	data := p.CheckedExprBytes
+	if p.CheckedExpr != nil {
+		data = []byte(*p.CheckedExpr)
+	}
+	return celengine.ProgramFromCheckedExpr(p.EnvID, data, p.Format, optionalJSON(p.ProgramOptions), p.CompileFlags), nil
+}
+
+func destroyEnv(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID string `json:"envID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string")
+	}
+
+	return celengine.DestroyEnv(p.EnvID), nil
+}
+
+func destroyProgram(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProgramID string `json:"programID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ProgramID == "" {
+		return nil, fmt.Errorf("expected params: programID string")
+	}
+
+	return celengine.DestroyProgram(p.ProgramID), nil
+}
+
+func createRuleSet(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID string           `json:"envID"`
+		Rules []celengine.Rule `json:"rules"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, rules array")
+	}
+
+	return celengine.CreateRuleSet(p.EnvID, p.Rules), nil
+}
+
+func evalRuleSet(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		RuleSetID string                 `json:"ruleSetID"`
+		Vars      map[string]interface{} `json:"vars"`
+		celengine.RuleSetEvalOptions
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.RuleSetID == "" {
+		return nil, fmt.Errorf("expected params: ruleSetID string, vars object (optional), stopOnFirstMatch bool (optional)")
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{})
+	}
+
+	return celengine.EvalRuleSet(p.RuleSetID, p.Vars, p.RuleSetEvalOptions), nil
+}
+
+func destroyRuleSet(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		RuleSetID string `json:"ruleSetID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.RuleSetID == "" {
+		return nil, fmt.Errorf("expected params: ruleSetID string")
+	}
+
+	return celengine.DestroyRuleSet(p.RuleSetID), nil
+}
+
+func compilePolicy(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		EnvID  string `json:"envID"`
+		Source string `json:"source"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.EnvID == "" {
+		return nil, fmt.Errorf("expected params: envID string, source string")
+	}
+
+	return celengine.CompilePolicy(p.EnvID, p.Source), nil
+}
+
+func evalPolicy(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		PolicyID string                 `json:"policyID"`
+		Vars     map[string]interface{} `json:"vars"`
+		celengine.ValueEncoding
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.PolicyID == "" {
+		return nil, fmt.Errorf("expected params: policyID string, vars object (optional), mapKeys string (optional), nonFinite string (optional)")
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]interface{})
+	}
+
+	return celengine.EvalPolicy(p.PolicyID, p.Vars, p.ValueEncoding), nil
+}
+
+func destroyPolicy(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		PolicyID string `json:"policyID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.PolicyID == "" {
+		return nil, fmt.Errorf("expected params: policyID string")
+	}
+
+	return celengine.DestroyPolicy(p.PolicyID), nil
+}
+
+func createSession(params json.RawMessage) (interface{}, error) {
+	return celengine.CreateSession(), nil
+}
+
+func destroySession(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		SessionID string `json:"sessionID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.SessionID == "" {
+		return nil, fmt.Errorf("expected params: sessionID string")
+	}
+
+	return celengine.DestroySession(p.SessionID), nil
+}
+
+func configure(params json.RawMessage) (interface{}, error) {
+	if len(params) == 0 || string(params) == "null" {
+		return nil, fmt.Errorf("expected params: programTTLms number (optional), envTTLms number (optional)")
+	}
+
+	return celengine.Configure(string(params)), nil
+}
+
+func sweep(params json.RawMessage) (interface{}, error) {
+	return celengine.Sweep(), nil
+}
+
+func setLogger(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ImplID string `json:"implID"`
+		Level  string `json:"level"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	return celengine.SetLogger(p.ImplID, p.Level), nil
+}
+
+func setLifecycleListener(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ImplID string `json:"implID"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	return celengine.SetLifecycleListener(p.ImplID), nil
+}
+
+func getCapabilities(params json.RawMessage) (interface{}, error) {
+	return celengine.GetCapabilities(), nil
+}
+
+func describeOptions(params json.RawMessage) (interface{}, error) {
+	return celengine.DescribeOptions(), nil
+}
+
+func shutdown(params json.RawMessage) (interface{}, error) {
+	return celengine.Shutdown(), nil
+}
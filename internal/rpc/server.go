@@ -0,0 +1,231 @@
+// Package rpc serves the wasm-cel API as JSON-RPC 2.0 over a length-prefixed byte stream,
+// for hosts that run the module without the syscall/js glue (wasmtime, wazero, ...).
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/invakid404/wasm-cel/internal/common"
+	"github.com/invakid404/wasm-cel/internal/options"
+	"github.com/invakid404/wasm-cel/pkg/celengine"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message is a JSON-RPC 2.0 request, notification or response
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// incomingMessage is used to decode messages from the host, keeping the result raw
+type incomingMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Server dispatches JSON-RPC requests read from the host to the CEL engine
+// Custom functions and AST validators are called back on the host with
+// "callFunction" requests, which the host must answer before sending anything else
+type Server struct {
+	in         *bufio.Reader
+	out        io.Writer
+	nextCallID int
+	stopped    bool // Set by the shutdown method to end Serve
+}
+
+// NewServer creates a server reading requests from in and writing responses to out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Install registers the server as the function caller of the engine and options packages
+func (s *Server) Install() {
+	celengine.SetJSFunctionCaller(s)
+	celengine.SetUnregisterFunctionCaller(s)
+	options.SetJSFunctionCaller(s)
+	options.SetGetCompilationContextFunc(func(compilationID string) common.CompilationIssueAdder {
+		return celengine.GetCompilationContextAdder(compilationID)
+	})
+}
+
+// Serve handles requests until the input is closed or the host calls shutdown
+func (s *Server) Serve() error {
+	for !s.stopped {
+		body, err := readFrame(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+
+		if err := s.handle(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle processes a single incoming frame
+func (s *Server) handle(body []byte) error {
+	var msg incomingMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return s.send(Message{Error: &Error{Code: CodeParseError, Message: err.Error()}, ID: json.RawMessage("null")})
+	}
+
+	if msg.Method == "" {
+		if len(msg.ID) == 0 {
+			return s.send(Message{Error: &Error{Code: CodeInvalidRequest, Message: "missing method"}, ID: json.RawMessage("null")})
+		}
+		// A response with no pending call, nothing to do
+		return nil
+	}
+
+	result, rpcErr := s.dispatch(msg.Method, msg.Params)
+	if msg.Method == "shutdown" && rpcErr == nil {
+		s.stopped = true
+	}
+
+	// Notifications don't get a response
+	if len(msg.ID) == 0 {
+		return nil
+	}
+
+	// Results JSON can't represent, such as NaN with the "number" nonFinite mode,
+	// are reported instead of ending the server
+	if rpcErr == nil {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			rpcErr = &Error{Code: CodeInternalError, Message: fmt.Sprintf("failed to encode result: %v", err)}
+			result = nil
+		} else {
+			result = json.RawMessage(encoded)
+		}
+	}
+
+	return s.send(Message{ID: msg.ID, Result: result, Error: rpcErr})
+}
+
+// send writes a message to the host
+func (s *Server) send(msg Message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if err := writeFrame(s.out, body); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// CallJSFunction calls a function implementation on the host and waits for its result
+func (s *Server) CallJSFunction(implID string, args []interface{}) (interface{}, error) {
+	s.nextCallID++
+	callID := "fn-" + strconv.Itoa(s.nextCallID)
+	rawID, _ := json.Marshal(callID)
+
+	params := map[string]interface{}{
+		"implID": implID,
+		"args":   args,
+	}
+	if err := s.send(Message{ID: rawID, Method: "callFunction", Params: mustMarshal(params)}); err != nil {
+		return nil, err
+	}
+
+	for {
+		body, err := readFrame(s.in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read function result: %w", err)
+		}
+
+		var msg incomingMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return nil, fmt.Errorf("failed to parse function result: %w", err)
+		}
+
+		if msg.Method != "" {
+			// The engine is single-threaded, so nested requests can't be served
+			// Notifications have no ID to answer, so they are rejected with a null ID, like
+			// messages that fail to parse, rather than dropped without the host knowing
+			rpcErr := &Error{Code: CodeInvalidRequest, Message: "requests are not accepted while a function call is pending"}
+			id := msg.ID
+			if len(id) == 0 {
+				rpcErr.Message = fmt.Sprintf("notifications are not accepted while a function call is pending: %s", msg.Method)
+				id = json.RawMessage("null")
+			}
+			if err := s.send(Message{ID: id, Error: rpcErr}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if string(msg.ID) != string(rawID) {
+			continue
+		}
+
+		// Errors answered by the host are reported like errors thrown in JavaScript, with
+		// the code in the error's data if it has one
+		if msg.Error != nil {
+			fnErr := &celengine.FunctionError{Code: celengine.FunctionErrorCode, Message: msg.Error.Message}
+			if data, ok := msg.Error.Data.(map[string]interface{}); ok {
+				if code, ok := data["code"].(string); ok && code != "" {
+					fnErr.Code = code
+				}
+			}
+			return nil, fnErr
+		}
+
+		var result interface{}
+		if len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, &result); err != nil {
+				return nil, fmt.Errorf("failed to parse function result: %w", err)
+			}
+		}
+		return result, nil
+	}
+}
+
+// UnregisterFunction notifies the host that a function implementation is no longer referenced
+func (s *Server) UnregisterFunction(implID string) {
+	params := map[string]interface{}{"implID": implID}
+	_ = s.send(Message{Method: "unregisterFunction", Params: mustMarshal(params)})
+}
+
+func mustMarshal(value interface{}) json.RawMessage {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,197 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// serve runs a server over the given messages from the host, and returns the messages
+// it sent back
+func serve(t *testing.T, messages ...string) []incomingMessage {
+	t.Helper()
+
+	var in, out bytes.Buffer
+	for _, message := range messages {
+		if err := writeFrame(&in, []byte(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := NewServer(&in, &out)
+	server.Install()
+	if err := server.Serve(); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+
+	var sent []incomingMessage
+	for {
+		body, err := readFrame(&out)
+		if err == io.EOF {
+			return sent
+		}
+		if err != nil {
+			t.Fatalf("failed to read a message sent by the server: %v", err)
+		}
+		var msg incomingMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("failed to parse %s: %v", body, err)
+		}
+		if msg.JSONRPC != "2.0" {
+			t.Fatalf("expected jsonrpc 2.0, got %s", body)
+		}
+		sent = append(sent, msg)
+	}
+}
+
+// response returns the message sent with an ID
+func response(t *testing.T, sent []incomingMessage, id string) incomingMessage {
+	t.Helper()
+	for _, msg := range sent {
+		if string(msg.ID) == id && msg.Method == "" {
+			return msg
+		}
+	}
+	t.Fatalf("no response with ID %s", id)
+	return incomingMessage{}
+}
+
+// decodeResult decodes the result of a response
+func decodeResult(t *testing.T, msg incomingMessage) map[string]interface{} {
+	t.Helper()
+	if msg.Error != nil {
+		t.Fatalf("expected a result, got error %d: %s", msg.Error.Code, msg.Error.Message)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		t.Fatalf("failed to parse result %s: %v", msg.Result, err)
+	}
+	return result
+}
+
+func TestServeDispatch(t *testing.T) {
+	sent := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"evaluate","params":{"source":"x + 1","vars":{"x":41}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"evaluate","params":{"source":"x +"}}`,
+		`{"jsonrpc":"2.0","method":"sweep"}`,
+		`{"jsonrpc":"2.0","id":"last","method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"evaluate","params":{"source":"1"}}`,
+	)
+
+	if result := decodeResult(t, response(t, sent, "1")); result["result"] != 42.0 {
+		t.Errorf("expected 42, got %v", result)
+	}
+	if result := decodeResult(t, response(t, sent, "2")); result["error"] == nil {
+		t.Errorf("expected a compilation error in the result, got %v", result)
+	}
+	response(t, sent, `"last"`)
+
+	// The notification isn't answered, and nothing is served after shutdown
+	if len(sent) != 3 {
+		t.Errorf("expected 3 responses, got %d", len(sent))
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	sent := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"noSuchMethod"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"evalProgram","params":{}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"evaluate","params":[]}`,
+		`{"jsonrpc":"2.0","id":4}`,
+		`{"jsonrpc":"2.0"}`,
+		`not json`,
+	)
+
+	expectCode := func(msg incomingMessage, code int) {
+		t.Helper()
+		if msg.Error == nil || msg.Error.Code != code {
+			t.Errorf("expected error code %d, got %+v", code, msg.Error)
+		}
+	}
+	expectCode(response(t, sent, "1"), CodeMethodNotFound)
+	if message := response(t, sent, "1").Error.Message; message != "method not found: noSuchMethod" {
+		t.Errorf("unexpected message: %s", message)
+	}
+	expectCode(response(t, sent, "2"), CodeInvalidParams)
+	expectCode(response(t, sent, "3"), CodeInvalidParams)
+
+	// A response without a pending call is ignored, while the message without a method
+	// and the unparsable one are answered with a null ID
+	if len(sent) != 5 {
+		t.Fatalf("expected 5 responses, got %d", len(sent))
+	}
+	expectCode(sent[3], CodeInvalidRequest)
+	expectCode(sent[4], CodeParseError)
+	for _, msg := range sent[3:] {
+		if string(msg.ID) != "null" {
+			t.Errorf("expected a null ID, got %s", msg.ID)
+		}
+	}
+}
+
+func TestServeCallFunction(t *testing.T) {
+	sent := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"evaluate","params":{
+			"source": "twice(x)",
+			"vars": {"x": 20},
+			"options": {"functions": [{"name": "twice", "params": [{"name": "x", "type": "int"}], "returnType": "int", "implID": "twice"}]}
+		}}`,
+		// Sent while the call is pending, before its result
+		`{"jsonrpc":"2.0","id":2,"method":"sweep"}`,
+		`{"jsonrpc":"2.0","method":"sweep"}`,
+		`{"jsonrpc":"2.0","id":"fn-1","result":40}`,
+	)
+
+	if len(sent) < 4 {
+		t.Fatalf("expected at least 4 messages, got %d", len(sent))
+	}
+
+	call := sent[0]
+	if call.Method != "callFunction" || string(call.ID) != `"fn-1"` {
+		t.Fatalf("expected the fn-1 callFunction request first, got %+v", call)
+	}
+	var params struct {
+		ImplID string        `json:"implID"`
+		Args   []interface{} `json:"args"`
+	}
+	if err := json.Unmarshal(call.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.ImplID != "twice" || len(params.Args) != 1 || params.Args[0] != 20.0 {
+		t.Errorf("unexpected callFunction params: %s", call.Params)
+	}
+
+	// Neither the request nor the notification sent meanwhile is served or dropped silently
+	if rejected := response(t, sent, "2"); rejected.Error == nil || rejected.Error.Code != CodeInvalidRequest {
+		t.Errorf("expected the request to be rejected, got %+v", rejected)
+	}
+	if rejected := sent[2]; string(rejected.ID) != "null" || rejected.Error == nil ||
+		rejected.Error.Message != "notifications are not accepted while a function call is pending: sweep" {
+		t.Errorf("expected the notification to be rejected, got %+v", rejected)
+	}
+
+	if result := decodeResult(t, response(t, sent, "1")); result["result"] != 40.0 {
+		t.Errorf("expected 40, got %v", result)
+	}
+}
+
+func TestServeCallFunctionError(t *testing.T) {
+	sent := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"evaluate","params":{
+			"source": "fail(x)",
+			"vars": {"x": 1},
+			"options": {"functions": [{"name": "fail", "params": [{"name": "x", "type": "int"}], "returnType": "int", "implID": "fail"}]}
+		}}`,
+		`{"jsonrpc":"2.0","id":"fn-1","error":{"code":1,"message":"boom","data":{"code":"E_BOOM"}}}`,
+	)
+
+	result := decodeResult(t, response(t, sent, "1"))
+	if result["error"] == nil {
+		t.Fatalf("expected the evaluation to fail, got %v", result)
+	}
+	if message, _ := result["error"].(string); !strings.Contains(message, "boom") {
+		t.Errorf("expected the host's error message, got %v", result["error"])
+	}
+}
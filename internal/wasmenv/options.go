@@ -3,8 +3,10 @@ package wasmenv
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/google/cel-go/cel"
+	"github.com/invakid404/wasm-cel/internal/logging"
 	"github.com/invakid404/wasm-cel/internal/options"
 )
 
@@ -42,6 +44,8 @@ func CreateOptionsFromJSONWithEnvID(configJSON string, envID string) ([]cel.EnvO
 			return nil, fmt.Errorf("option %s does not support JSON configuration", config.Type)
 		}
 
+		warnUnknownParams("option", config, builder)
+
 		// Configure the builder from JSON parameters
 		if err := fromJSONBuilder.FromJSON(config.Params); err != nil {
 			return nil, fmt.Errorf("failed to configure option %s from JSON: %w", config.Type, err)
@@ -64,7 +68,134 @@ func CreateOptionsFromJSONWithEnvID(configJSON string, envID string) ([]cel.EnvO
 	return envOptions, nil
 }
 
+// CreateProgramOptionsFromJSON creates CEL program options from JSON configuration
+// Uses the program registry to find options that implement FromJSON interface
+func CreateProgramOptionsFromJSON(configJSON string) ([]cel.ProgramOption, error) {
+	var configs []OptionConfig
+	if err := json.Unmarshal([]byte(configJSON), &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse program options configuration: %w", err)
+	}
+
+	var programOptions []cel.ProgramOption
+	for _, config := range configs {
+		// Create builder from registry
+		builder, err := options.DefaultProgramRegistry.Create(config.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create program option %s: %w", config.Type, err)
+		}
+
+		// Check if the builder implements FromJSON
+		fromJSONBuilder, ok := builder.(options.FromJSON)
+		if !ok {
+			return nil, fmt.Errorf("program option %s does not support JSON configuration", config.Type)
+		}
+
+		warnUnknownParams("program option", config, builder)
+
+		// Configure the builder from JSON parameters
+		if err := fromJSONBuilder.FromJSON(config.Params); err != nil {
+			return nil, fmt.Errorf("failed to configure program option %s from JSON: %w", config.Type, err)
+		}
+
+		// Build the CEL program option
+		option, err := builder.Build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build program option %s: %w", config.Type, err)
+		}
+
+		programOptions = append(programOptions, option)
+	}
+
+	return programOptions, nil
+}
+
+// warnUnknownParams logs the params of an option configuration that its builder ignores
+func warnUnknownParams(kind string, config OptionConfig, builder interface{}) {
+	if len(config.Params) == 0 || !logging.Enabled(logging.LevelWarn) {
+		return
+	}
+
+	schemaProvider, ok := builder.(options.ParamsSchemaProvider)
+	if !ok {
+		return
+	}
+	properties, _ := schemaProvider.ParamsSchema()["properties"].(map[string]interface{})
+
+	names := make([]string, 0, len(config.Params))
+	for name := range config.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, known := properties[name]; !known {
+			logging.Warn(fmt.Sprintf("ignoring unknown %s param", kind), map[string]interface{}{
+				"option": config.Type,
+				"param":  name,
+			})
+		}
+	}
+}
+
 // ListAvailableOptions returns the names of all options that support FromJSON
 func ListAvailableOptions() []string {
 	return options.DefaultRegistry.ListWithFromJSON()
 }
+
+// ListAvailableProgramOptions returns the names of all program options that support FromJSON
+func ListAvailableProgramOptions() []string {
+	return options.DefaultProgramRegistry.ListWithFromJSON()
+}
+
+// OptionDescription describes a JSON-configurable option and its parameters
+type OptionDescription struct {
+	Name        string
+	Description string
+	Schema      map[string]interface{}
+}
+
+// describableBuilder is the subset of option builder methods needed for descriptions
+type describableBuilder interface {
+	Description() string
+}
+
+// DescribeAvailableOptions returns descriptions of all options that support FromJSON, sorted by name
+func DescribeAvailableOptions() ([]OptionDescription, error) {
+	return describeOptions(options.DefaultRegistry.ListWithFromJSON(), func(name string) (describableBuilder, error) {
+		return options.DefaultRegistry.Create(name)
+	})
+}
+
+// DescribeAvailableProgramOptions returns descriptions of all program options that support FromJSON, sorted by name
+func DescribeAvailableProgramOptions() ([]OptionDescription, error) {
+	return describeOptions(options.DefaultProgramRegistry.ListWithFromJSON(), func(name string) (describableBuilder, error) {
+		return options.DefaultProgramRegistry.Create(name)
+	})
+}
+
+// describeOptions builds sorted descriptions for the named options using the given factory
+func describeOptions(names []string, create func(string) (describableBuilder, error)) ([]OptionDescription, error) {
+	sort.Strings(names)
+
+	descriptions := make([]OptionDescription, 0, len(names))
+	for _, name := range names {
+		builder, err := create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create option %s: %w", name, err)
+		}
+
+		// Options without an explicit schema accept an arbitrary params object
+		schema := map[string]interface{}{"type": "object"}
+		if provider, ok := builder.(options.ParamsSchemaProvider); ok {
+			schema = provider.ParamsSchema()
+		}
+
+		descriptions = append(descriptions, OptionDescription{
+			Name:        name,
+			Description: builder.Description(),
+			Schema:      schema,
+		})
+	}
+
+	return descriptions, nil
+}
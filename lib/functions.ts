@@ -6,6 +6,8 @@ import type {
   CELFunctionDefinition,
   CELFunctionParam,
   CELTypeDef,
+  EvalCallContext,
+  TaggedDecimal,
 } from "./types.js";
 
 /**
@@ -19,29 +21,33 @@ type CELTypeToTS<
   ? any // Limit recursion depth to 5 levels
   : T extends "bool"
     ? boolean
-    : T extends "int" | "uint" | "double"
+    : T extends "int" | "double"
       ? number
-      : T extends "string"
-        ? string
-        : T extends "bytes"
+      : T extends "uint"
+        ? bigint
+        : T extends "string"
           ? string
-          : T extends { kind: "list"; elementType: infer E }
-            ? E extends CELTypeDef
-              ? Array<CELTypeToTS<E, [...Depth, unknown]>>
-              : never
-            : T extends { kind: "map"; keyType: infer K; valueType: infer V }
-              ? V extends CELTypeDef
-                ? Record<string, CELTypeToTS<V, [...Depth, unknown]>>
+          : T extends "bytes"
+            ? Uint8Array
+            : T extends { kind: "list"; elementType: infer E }
+              ? E extends CELTypeDef
+                ? Array<CELTypeToTS<E, [...Depth, unknown]>>
                 : never
-              : T extends "dyn"
-                ? any
-                : T extends "null"
-                  ? null
-                  : T extends "timestamp"
-                    ? Date
-                    : T extends "duration"
-                      ? string
-                      : never;
+              : T extends { kind: "map"; keyType: infer K; valueType: infer V }
+                ? V extends CELTypeDef
+                  ? Record<string, CELTypeToTS<V, [...Depth, unknown]>>
+                  : never
+                : T extends "dyn"
+                  ? any
+                  : T extends "null"
+                    ? null
+                    : T extends "timestamp"
+                      ? Date
+                      : T extends "duration"
+                        ? string
+                        : T extends "decimal"
+                          ? TaggedDecimal
+                          : never;
 
 /**
  * Extracts TypeScript parameter types from a tuple of CEL function parameters
@@ -52,6 +58,14 @@ type ExtractParamTypes<P extends readonly CELFunctionParam[]> = {
     : never;
 };
 
+/**
+ * How a function's implementation may be called, beyond its signature
+ */
+type FunctionFlags = Pick<
+  CELFunctionDefinition,
+  "isPure" | "cacheable" | "cacheSize" | "batchImpl"
+>;
+
 /**
  * Builder class for creating type-safe CEL function definitions
  *
@@ -71,12 +85,14 @@ export class CELFunction<
   private name: string;
   private readonly params: CELFunctionParam[];
   private returnType: CELTypeDef;
+  private readonly flags: FunctionFlags;
   private overloads: CELFunctionDefinition[] = [];
 
   private constructor(
     name: string,
     params: CELFunctionParam[] = [],
     returnType: CELTypeDef = "dyn",
+    flags: FunctionFlags = {},
   ) {
     if (!/^[a-zA-Z_][a-zA-Z0-9_]*$/.test(name)) {
       throw new Error(
@@ -86,6 +102,7 @@ export class CELFunction<
     this.name = name;
     this.params = params;
     this.returnType = returnType;
+    this.flags = flags;
   }
 
   /**
@@ -121,21 +138,74 @@ export class CELFunction<
       ...this.params,
       { name, type, optional },
     ] as CELFunctionParam[];
-    return new CELFunction(this.name, newParams, this.returnType);
+    return new CELFunction(this.name, newParams, this.returnType, this.flags);
   }
 
   /**
    * Set the return type of the function
    */
   returns<T extends CELTypeDef>(type: T): CELFunction<Params, T> {
-    return new CELFunction(this.name, this.params, type);
+    return new CELFunction(this.name, this.params, type, this.flags);
+  }
+
+  /**
+   * Mark the implementation as pure: its result depends only on its arguments
+   * and calling it has no side effects. Calls to pure functions with constant
+   * arguments may be folded at compile time, and programs calling them may be
+   * memoized
+   */
+  pure(): CELFunction<Params, ReturnType> {
+    return new CELFunction(this.name, this.params, this.returnType, {
+      ...this.flags,
+      isPure: true,
+    });
+  }
+
+  /**
+   * Cache the results of calls within an evaluation, keyed by a hash of the
+   * arguments, so repeated calls with the same arguments, such as lookups
+   * inside comprehensions, call the implementation once
+   * @param cacheSize - Also keep up to this many results across evaluations,
+   * evicting the least recently used
+   */
+  cacheable(cacheSize?: number): CELFunction<Params, ReturnType> {
+    return new CELFunction(this.name, this.params, this.returnType, {
+      ...this.flags,
+      cacheable: true,
+      cacheSize,
+    });
+  }
+
+  /**
+   * Add a batch implementation, called once for all the calls a map() makes,
+   * such as `items.map(x, f(x))`, with the list of their argument lists. It
+   * returns the list of their results in the same order, so mapping the
+   * function over a list calls into JavaScript once rather than per element
+   */
+  batch(
+    impl: (
+      calls: Array<ExtractParamTypes<Params>>,
+      context: EvalCallContext | undefined,
+    ) =>
+      | Array<CELTypeToTS<ReturnType>>
+      | Promise<Array<CELTypeToTS<ReturnType>>>,
+  ): CELFunction<Params, ReturnType> {
+    return new CELFunction(this.name, this.params, this.returnType, {
+      ...this.flags,
+      batchImpl: impl as CELFunctionDefinition["batchImpl"],
+    });
   }
 
   /**
    * Set the implementation function and return the final definition
+   * In evaluations started by Program.evalConcurrent(), the implementation may
+   * return a Promise, and receives an {@link EvalCallContext} after its
+   * arguments
    */
   implement(
-    impl: (...args: ExtractParamTypes<Params>) => CELTypeToTS<ReturnType>,
+    impl: (
+      ...args: [...ExtractParamTypes<Params>, EvalCallContext | undefined]
+    ) => CELTypeToTS<ReturnType> | Promise<CELTypeToTS<ReturnType>>,
   ): CELFunctionDefinition {
     const definition: CELFunctionDefinition = {
       name: this.name,
@@ -144,6 +214,19 @@ export class CELFunction<
       impl: impl as (...args: any[]) => any,
     };
 
+    if (this.flags.isPure) {
+      definition.isPure = true;
+    }
+    if (this.flags.cacheable) {
+      definition.cacheable = true;
+      if (this.flags.cacheSize !== undefined) {
+        definition.cacheSize = this.flags.cacheSize;
+      }
+    }
+    if (this.flags.batchImpl) {
+      definition.batchImpl = this.flags.batchImpl;
+    }
+
     if (this.overloads.length > 0) {
       definition.overloads = this.overloads;
     }
@@ -2,21 +2,90 @@
  * Global type declarations for Go WASM integration
  */
 
+/**
+ * Errors are reported as strings, or as structured internal errors when the
+ * module recovers from a panic
+ */
+type ResultError = string | import("./types.js").WasmErrorInfo;
+
+/**
+ * The error fields of a result. Calls with the handle of a destroyed object, or
+ * of an object of another instance of the module, report "stale_handle" as the
+ * errorCode along with the stale handle
+ */
+type ErrorResult = {
+  error?: ResultError;
+  errorCode?: string;
+  handle?: string;
+};
+
 // Type aliases for reusable types
 type RegisterCELFunction = (
   implID: string,
   fn: (...args: any[]) => any,
 ) => {
   success?: boolean;
-  error?: string;
+  error?: ResultError;
+};
+
+type DefineGlobalFunctionFunction = (funcDef: {
+  name: string;
+  params: Array<{ name: string; type: any; optional?: boolean }>;
+  returnType: any;
+  implID: string;
+  isPure?: boolean;
+  cacheable?: boolean;
+  cacheSize?: number;
+  batchImplID?: string;
+}) => {
+  success?: boolean;
+  error?: ResultError;
+};
+
+type RegisterLibraryFunction = (
+  name: string,
+  library: {
+    varDecls?: Array<{ name: string; type: any }>;
+    funcDefs?: any;
+    options?: any[];
+  },
+) => {
+  success?: boolean;
+  error?: ResultError;
+};
+
+type CreateEnvConfig = {
+  varDecls?: Array<{ name: string; type: any; default?: any }>;
+  funcDefs?: any;
+  constants?: Array<{ name: string; type: any; value: any }>;
+  libraries?: string[];
+  absentVariables?: import("./types.js").AbsentVariablesPolicy;
+  apiVersion?: number;
+  sessionID?: string;
+};
+
+type CreateEnvFunction = {
+  (config: CreateEnvConfig): { envID?: string; error?: ResultError };
+  /** The form of earlier versions, with declarations and functions only */
+  (
+    varDecls: Array<{ name: string; type: any; default?: any }>,
+    funcDefs?: any,
+  ): { envID?: string; error?: ResultError };
+};
+
+type CreateEnvFromJSONSchemaFunction = (schema: string | object) => {
+  envID?: string;
+  typeName?: string;
+  variables?: string[];
+  error?: ResultError;
 };
 
-type CreateEnvFunction = (
-  varDecls: Array<{ name: string; type: any }>,
-  funcDefs?: any,
+type CreateEnvFromPresetFunction = (
+  name: string,
+  sessionID?: string,
 ) => {
   envID?: string;
-  error?: string;
+  error?: ResultError;
 };
 
 type ExtendEnvFunction = (
@@ -24,72 +93,570 @@ type ExtendEnvFunction = (
   options: string,
 ) => {
   success?: boolean;
-  error?: string;
+  error?: ResultError;
+};
+
+type LoadBundleFunction = (
+  bundle: Uint8Array | string | object,
+  sessionID?: string,
+  publicKey?: Uint8Array | string,
+  skipSignatureVerification?: boolean,
+) => {
+  envID?: string;
+  programs?: Record<string, string>;
+  error?: ResultError;
 };
 
 type CompileExprFunction = (
   envID: string,
   expr: string,
+  programOptions?: string,
+  flags?: boolean | import("./types.js").CompileFlags,
+) => {
+  programID?: string;
+  metrics?: import("./types.js").CompileMetrics;
+  optimizedSource?: string;
+  fieldMask?: string[][];
+  sourceMap?: import("./types.js").SourceMapping[];
+  error?: ResultError;
+};
+
+type InstantiateTemplateFunction = (
+  envID: string,
+  template: string,
+  bindings: Record<string, any>,
+  programOptions?: string,
+  flags?: import("./types.js").CompileFlags,
+) => {
+  programID?: string;
+  metrics?: import("./types.js").CompileMetrics;
+  optimizedSource?: string;
+  fieldMask?: string[][];
+  sourceMap?: import("./types.js").SourceMapping[];
+  error?: ResultError;
+};
+
+type RecompileProgramsFunction = (envID: string) => {
+  recompiled?: string[];
+  issues?: import("./types.js").RecompileIssue[];
+  error?: ResultError;
+};
+
+type ReplaceProgramFunction = (
+  programID: string,
+  expr: string,
+) => {
+  success?: boolean;
+  optimizedSource?: string;
+  fieldMask?: string[][];
+  sourceMap?: import("./types.js").SourceMapping[];
+  error?: ResultError;
+};
+
+type WarmupFunction = (programID: string) => {
+  success?: boolean;
+  error?: ResultError;
+};
+
+type ExportCheckedExprFunction = (
+  programID: string,
+  format?: import("./types.js").CheckedExprFormat,
+) => {
+  checkedExpr?: Uint8Array | string;
+  error?: ResultError;
+};
+
+type ProgramFromCheckedExprFunction = (
+  envID: string,
+  checkedExpr: Uint8Array | string | import("./types.js").SignedCheckedExpr,
+  format?: import("./types.js").CheckedExprFormat,
+  programOptions?: string,
+  flags?: import("./types.js").CompileFlags,
+  publicKey?: Uint8Array | string,
 ) => {
   programID?: string;
-  error?: string;
+  metrics?: import("./types.js").CompileMetrics;
+  optimizedSource?: string;
+  fieldMask?: string[][];
+  error?: ResultError;
+};
+
+type CompileExprDetailedFunction = (
+  envID: string,
+  expr: string,
+  programOptions?: string,
+  flags?: boolean | import("./types.js").CompileFlags,
+) => {
+  programID?: string | null;
+  metrics?: import("./types.js").CompileMetrics;
+  optimizedSource?: string;
+  fieldMask?: string[][];
+  sourceMap?: import("./types.js").SourceMapping[];
+  error?: ResultError | null;
+  issues?: any[];
 };
 
 type EvalProgramFunction = (
   programID: string,
   vars: Record<string, any>,
+  options?: boolean | import("./types.js").EvalOptions,
+) => {
+  result?: any;
+  cost?: number;
+  metrics?: import("./types.js").EvalMetrics;
+  errorValue?: import("./types.js").ErrorValue;
+  unknown?: string[];
+  functionTrace?: import("./types.js").FunctionCall[];
+  attributeTrace?: string[];
+  variableErrors?: import("./types.js").VariableError[];
+  memoryBudget?: import("./types.js").MemoryBudget;
+  error?: ResultError;
+};
+
+type EvalProgramAsyncFunction = (
+  programID: string,
+  vars: Record<string, any>,
+  callbackImplID: string,
+  options?: import("./types.js").EvalOptions,
+) => {
+  token?: string;
+  error?: ResultError;
+};
+
+type CancelEvalFunction = (token: string) => {
+  cancelled?: boolean;
+  error?: ResultError;
+};
+
+type CreateRuleSetFunction = (
+  envID: string,
+  rules: import("./types.js").Rule[],
+) => {
+  ruleSetID?: string;
+  error?: ResultError;
+};
+
+type EvalRuleSetFunction = (
+  ruleSetID: string,
+  vars: Record<string, any>,
+  options?: import("./types.js").RuleSetEvalOptions,
+) => Partial<import("./types.js").RuleSetResult> & {
+  error?: ResultError;
+};
+
+type DestroyRuleSetFunction = (ruleSetID: string) => {
+  success?: boolean;
+  error?: ResultError;
+};
+
+type CompilePolicyFunction = (
+  envID: string,
+  source: string,
+) => {
+  policyID?: string;
+  name?: string;
+  error?: ResultError;
+};
+
+type EvalPolicyFunction = (
+  policyID: string,
+  vars: Record<string, any>,
+  options?: Pick<import("./types.js").EvalOptions, "mapKeys" | "nonFinite">,
+) => Partial<import("./types.js").PolicyResult> & {
+  error?: ResultError;
+};
+
+type DestroyPolicyFunction = (policyID: string) => {
+  success?: boolean;
+  error?: ResultError;
+};
+
+type EvalProgramsFunction = (
+  programIDs: string[],
+  vars: Record<string, any>,
+  options?: import("./types.js").EvalOptions,
+) => {
+  results?: Array<ReturnType<EvalProgramFunction>>;
+  error?: ResultError;
+};
+
+type EvalRepeatedFunction = (
+  programID: string,
+  vars: Record<string, any>,
+  n: number,
+  options?: import("./types.js").EvalOptions,
+) => ReturnType<EvalProgramFunction> & {
+  stats?: import("./types.js").EvalStats;
+};
+
+type RunTestsFunction = (
+  programID: string,
+  cases: import("./types.js").TestCase[],
+  options?: import("./types.js").EvalOptions,
+) => {
+  passed?: number;
+  failed?: number;
+  results?: import("./types.js").TestCaseResult[];
+  error?: ResultError;
+};
+
+type ExplainFunction = (
+  programID: string,
+  vars: Record<string, any>,
+  options?: import("./types.js").ExplainOptions,
 ) => {
   result?: any;
-  error?: string;
+  errorValue?: import("./types.js").ErrorValue;
+  explanation?: import("./types.js").ExplanationNode;
+  error?: ResultError;
 };
 
+type EvalProgramWithContextMessageFunction = (
+  programID: string,
+  typeName: string,
+  message: Uint8Array | string | Record<string, any>,
+  options?: boolean | import("./types.js").EvalOptions,
+) => ReturnType<EvalProgramFunction>;
+
 type TypecheckExprFunction = (
   envID: string,
   expr: string,
+  options?: import("./types.js").TypecheckOptions,
 ) => {
   type?: any;
-  error?: string;
+  issues?: import("./types.js").CompilationIssue[];
+  undeclared?: string[];
+  error?: ResultError;
+};
+
+type DiffExprsFunction = (
+  envID: string,
+  exprA: string,
+  exprB: string,
+) => {
+  equal?: boolean;
+  changes?: import("./types.js").ExprChange[];
+  error?: ResultError;
+};
+
+type MutateFunction = (
+  envID: string,
+  expr: string,
+) => {
+  mutants?: Array<
+    Omit<import("./types.js").Mutant, "program"> & { programID: string }
+  >;
+  skipped?: number;
+  error?: ResultError;
+};
+
+type DescribeEnvFunction = (envID: string) => {
+  variables?: import("./types.js").DeclaredVariable[];
+  functions?: import("./types.js").DeclaredFunction[];
+  options?: import("./options/index.js").EnvOptionConfig[];
+  libraries?: string[];
+  container?: string;
+  error?: ResultError;
+};
+
+type ListFunctionsFunction = (
+  envID: string,
+  options?: import("./types.js").ListFunctionsOptions,
+) => {
+  functions?: import("./types.js").FunctionHelp[];
+  error?: ResultError;
+};
+
+type GenerateSampleVarsFunction = (
+  envID: string,
+  options?: import("./types.js").SampleVarsOptions,
+) => {
+  vars?: Record<string, any>;
+  error?: ResultError;
+};
+
+type EvaluateFunction = (
+  source: string,
+  vars: Record<string, any> | null,
+  options?: {
+    variables?: Array<{ name: string; type: any }>;
+    functions?: Array<{
+      name: string;
+      params: Array<{ name: string; type: any; optional?: boolean }>;
+      returnType: any;
+      implID: string;
+      isPure?: boolean;
+      cacheable?: boolean;
+      cacheSize?: number;
+      batchImplID?: string;
+  batchImplID?: string;
+    }>;
+    libraries?: string[];
+    options?: import("./options/index.js").EnvOptionConfig[];
+  },
+) => {
+  result?: any;
+  type?: import("./types.js").CELTypeDef;
+  issues?: import("./types.js").CompilationIssue[];
+  error?: ResultError;
+};
+
+type CanonicalHashFunction = (
+  envID: string,
+  expr: string,
+) => {
+  hash?: string;
+  canonical?: string;
+  sourceMap?: import("./types.js").SourceMapping[];
+  error?: ResultError;
 };
 
 type DestroyEnvFunction = (envID: string) => {
   success?: boolean;
-  error?: string;
+  error?: ResultError;
 };
 
 type DestroyProgramFunction = (programID: string) => {
   success?: boolean;
-  error?: string;
+  error?: ResultError;
+};
+
+type CreateSessionFunction = () => {
+  sessionID?: string;
+  error?: ResultError;
+};
+
+type DestroySessionFunction = (sessionID: string) => {
+  success?: boolean;
+  destroyedEnvs?: number;
+  destroyedPrograms?: number;
+  error?: ResultError;
+};
+
+type ConfigureFunction = (config: string) => {
+  config?: {
+    programTTLms: number;
+    envTTLms: number;
+    evalWorkers: number;
+    evalMemoryLimitBytes: number;
+  };
+  sweepIntervalms?: number;
+  error?: ResultError;
+};
+
+type SweepFunction = () => {
+  destroyedEnvs?: number;
+  destroyedPrograms?: number;
+  error?: ResultError;
+};
+
+type SetLoggerFunction = (
+  implID: string | null,
+  level?: string,
+) => {
+  success?: boolean;
+  error?: ResultError;
+};
+
+type SetLifecycleListenerFunction = (implID: string | null) => {
+  success?: boolean;
+  error?: ResultError;
+};
+
+type GetCapabilitiesFunction = () => {
+  version?: string;
+  celGoVersion?: string;
+  options?: string[];
+  jsonOptions?: string[];
+  programOptions?: string[];
+  typeKinds?: string[];
+  wireFormats?: string[];
+  presets?: string[];
+  error?: ResultError;
+};
+
+type DescribeOptionsFunction = () => {
+  options?: any[];
+  programOptions?: any[];
+  error?: ResultError;
+};
+
+type ShutdownFunction = () => {
+  success?: boolean;
+  destroyedEnvs?: number;
+  destroyedPrograms?: number;
+  error?: ResultError;
 };
 
 type GoConstructor = {
   new (): {
     importObject: WebAssembly.Imports;
-    run: (instance: WebAssembly.Instance) => void;
+    env: Record<string, string>;
+    exit: (code: number) => void;
+    run: (instance: WebAssembly.Instance) => Promise<void>;
   };
 };
 
 declare global {
+  /**
+   * The functions exported by the WASM module, attached to globalThis by
+   * default or to the object named by the WASM_CEL_EXPORTS environment variable
+   */
+  interface WasmCelExports {
+    registerCELFunction: RegisterCELFunction;
+    defineGlobalFunction: DefineGlobalFunctionFunction;
+    registerLibrary: RegisterLibraryFunction;
+    createEnv: CreateEnvFunction;
+    createEnvFromJSONSchema: CreateEnvFromJSONSchemaFunction;
+    createEnvFromPreset: CreateEnvFromPresetFunction;
+    loadBundle: LoadBundleFunction;
+    extendEnv: ExtendEnvFunction;
+    compileExpr: CompileExprFunction;
+    compileExprDetailed: CompileExprDetailedFunction;
+    instantiateTemplate: InstantiateTemplateFunction;
+    recompilePrograms: RecompileProgramsFunction;
+    replaceProgram: ReplaceProgramFunction;
+    warmup: WarmupFunction;
+    exportCheckedExpr: ExportCheckedExprFunction;
+    programFromCheckedExpr: ProgramFromCheckedExprFunction;
+    typecheckExpr: TypecheckExprFunction;
+    canonicalHash: CanonicalHashFunction;
+    diffExprs: DiffExprsFunction;
+    mutate: MutateFunction;
+    describeEnv: DescribeEnvFunction;
+    listFunctions: ListFunctionsFunction;
+    generateSampleVars: GenerateSampleVarsFunction;
+    evaluate: EvaluateFunction;
+    evalProgram: EvalProgramFunction;
+    evalPrograms: EvalProgramsFunction;
+    evalRepeated: EvalRepeatedFunction;
+    explain: ExplainFunction;
+    runTests: RunTestsFunction;
+    evalProgramAsync: EvalProgramAsyncFunction;
+    cancelEval: CancelEvalFunction;
+    evalProgramWithContextMessage: EvalProgramWithContextMessageFunction;
+    destroyEnv: DestroyEnvFunction;
+    destroyProgram: DestroyProgramFunction;
+    createRuleSet: CreateRuleSetFunction;
+    evalRuleSet: EvalRuleSetFunction;
+    destroyRuleSet: DestroyRuleSetFunction;
+    compilePolicy: CompilePolicyFunction;
+    evalPolicy: EvalPolicyFunction;
+    destroyPolicy: DestroyPolicyFunction;
+    createSession: CreateSessionFunction;
+    destroySession: DestroySessionFunction;
+    configure: ConfigureFunction;
+    sweep: SweepFunction;
+    setLogger: SetLoggerFunction;
+    setLifecycleListener: SetLifecycleListenerFunction;
+    getCapabilities: GetCapabilitiesFunction;
+    describeOptions: DescribeOptionsFunction;
+    shutdown: ShutdownFunction;
+  }
+
   interface Window {
     Go: typeof Go;
     registerCELFunction: RegisterCELFunction;
+    defineGlobalFunction: DefineGlobalFunctionFunction;
+    registerLibrary: RegisterLibraryFunction;
     createEnv: CreateEnvFunction;
+    createEnvFromJSONSchema: CreateEnvFromJSONSchemaFunction;
+    createEnvFromPreset: CreateEnvFromPresetFunction;
+    loadBundle: LoadBundleFunction;
     extendEnv: ExtendEnvFunction;
     compileExpr: CompileExprFunction;
+    compileExprDetailed: CompileExprDetailedFunction;
+    instantiateTemplate: InstantiateTemplateFunction;
+    recompilePrograms: RecompileProgramsFunction;
+    replaceProgram: ReplaceProgramFunction;
+    warmup: WarmupFunction;
+    exportCheckedExpr: ExportCheckedExprFunction;
+    programFromCheckedExpr: ProgramFromCheckedExprFunction;
     typecheckExpr: TypecheckExprFunction;
+    canonicalHash: CanonicalHashFunction;
+    diffExprs: DiffExprsFunction;
+    mutate: MutateFunction;
+    describeEnv: DescribeEnvFunction;
+    listFunctions: ListFunctionsFunction;
+    generateSampleVars: GenerateSampleVarsFunction;
+    evaluate: EvaluateFunction;
     evalProgram: EvalProgramFunction;
+    evalPrograms: EvalProgramsFunction;
+    evalRepeated: EvalRepeatedFunction;
+    explain: ExplainFunction;
+    runTests: RunTestsFunction;
+    evalProgramAsync: EvalProgramAsyncFunction;
+    cancelEval: CancelEvalFunction;
+    evalProgramWithContextMessage: EvalProgramWithContextMessageFunction;
     destroyEnv: DestroyEnvFunction;
     destroyProgram: DestroyProgramFunction;
+    createRuleSet: CreateRuleSetFunction;
+    evalRuleSet: EvalRuleSetFunction;
+    destroyRuleSet: DestroyRuleSetFunction;
+    compilePolicy: CompilePolicyFunction;
+    evalPolicy: EvalPolicyFunction;
+    destroyPolicy: DestroyPolicyFunction;
+    createSession: CreateSessionFunction;
+    destroySession: DestroySessionFunction;
+    configure: ConfigureFunction;
+    sweep: SweepFunction;
+    setLogger: SetLoggerFunction;
+    setLifecycleListener: SetLifecycleListenerFunction;
+    getCapabilities: GetCapabilitiesFunction;
+    describeOptions: DescribeOptionsFunction;
+    shutdown: ShutdownFunction;
   }
 
   var Go: GoConstructor;
   var registerCELFunction: RegisterCELFunction;
+  var defineGlobalFunction: DefineGlobalFunctionFunction;
+  var registerLibrary: RegisterLibraryFunction;
   var createEnv: CreateEnvFunction;
+  var createEnvFromJSONSchema: CreateEnvFromJSONSchemaFunction;
+  var createEnvFromPreset: CreateEnvFromPresetFunction;
+  var loadBundle: LoadBundleFunction;
   var extendEnv: ExtendEnvFunction;
   var compileExpr: CompileExprFunction;
+  var compileExprDetailed: CompileExprDetailedFunction;
+  var instantiateTemplate: InstantiateTemplateFunction;
+  var recompilePrograms: RecompileProgramsFunction;
+  var replaceProgram: ReplaceProgramFunction;
+  var warmup: WarmupFunction;
+  var exportCheckedExpr: ExportCheckedExprFunction;
+  var programFromCheckedExpr: ProgramFromCheckedExprFunction;
   var typecheckExpr: TypecheckExprFunction;
+  var canonicalHash: CanonicalHashFunction;
+  var diffExprs: DiffExprsFunction;
+  var mutate: MutateFunction;
+  var describeEnv: DescribeEnvFunction;
+  var listFunctions: ListFunctionsFunction;
+  var generateSampleVars: GenerateSampleVarsFunction;
+  var evaluate: EvaluateFunction;
   var evalProgram: EvalProgramFunction;
+  var evalPrograms: EvalProgramsFunction;
+  var evalRepeated: EvalRepeatedFunction;
+  var explain: ExplainFunction;
+  var runTests: RunTestsFunction;
+  var evalProgramAsync: EvalProgramAsyncFunction;
+  var cancelEval: CancelEvalFunction;
+  var evalProgramWithContextMessage: EvalProgramWithContextMessageFunction;
   var destroyEnv: DestroyEnvFunction;
   var destroyProgram: DestroyProgramFunction;
+  var createRuleSet: CreateRuleSetFunction;
+  var evalRuleSet: EvalRuleSetFunction;
+  var destroyRuleSet: DestroyRuleSetFunction;
+  var compilePolicy: CompilePolicyFunction;
+  var evalPolicy: EvalPolicyFunction;
+  var destroyPolicy: DestroyPolicyFunction;
+  var createSession: CreateSessionFunction;
+  var destroySession: DestroySessionFunction;
+  var configure: ConfigureFunction;
+  var sweep: SweepFunction;
+  var setLogger: SetLoggerFunction;
+  var setLifecycleListener: SetLifecycleListenerFunction;
+  var getCapabilities: GetCapabilitiesFunction;
+  var describeOptions: DescribeOptionsFunction;
+  var shutdown: ShutdownFunction;
 }
 
 export {};
@@ -4,10 +4,57 @@ import { fileURLToPath } from "node:url";
 import { execSync } from "node:child_process";
 import { createRequire } from "node:module";
 import type {
+  Capabilities,
   CELFunctionDefinition,
   CELTypeDef,
+  CheckedExprFormat,
+  CompileOptions,
+  ConcurrentEvalOptions,
   EnvOptions,
+  ErrorValue,
+  EvalOptions,
+  EvalResult,
+  EvalStats,
+  ExplainOptions,
+  Explanation,
+  ExplanationNode,
+  FunctionCall,
+  IntegerMode,
+  LibraryDefinition,
+  LoadBundleOptions,
+  LifecycleEvent,
+  LoadedBundle,
+  LogEntry,
+  LogLevel,
+  MemoryBudget,
+  OptionDescription,
+  PolicyBundle,
+  ProgramFromCheckedExprOptions,
+  SignedCheckedExpr,
+  SignedPolicyBundle,
+  Rule,
+  RuleSetEvalOptions,
+  RuleSetResult,
+  PolicyResult,
+  RepeatedEvalResult,
+  RuntimeConfig,
   TypeCheckResult,
+  TypecheckOptions,
+  CanonicalHashResult,
+  ExprDiff,
+  MutationResult,
+  EnvDescription,
+  FunctionHelp,
+  ListFunctionsOptions,
+  SampleVarsOptions,
+  EvaluateOptions,
+  EvaluateResult,
+  RecompileResult,
+  SourceMapping,
+  TestCase,
+  TestRunResult,
+  VariableError,
+  WasmErrorInfo,
 } from "./types.js";
 
 // Get __dirname equivalent in ESM
@@ -39,8 +86,176 @@ if (fs.existsSync(wasmExecPath)) {
   );
 }
 
+/**
+ * Error thrown when the WASM module fails internally, such as when it recovers
+ * from a Go panic or exits unexpectedly
+ */
+export class InternalError extends Error {
+  /** Error code, "internal" for recovered panics and "fatal" for exits */
+  readonly code: string;
+  /** Go stack trace of the failure, if available */
+  readonly goStack?: string;
+
+  constructor(message: string, code: string, goStack?: string) {
+    super(message);
+    this.name = "InternalError";
+    this.code = code;
+    this.goStack = goStack;
+  }
+}
+
+/**
+ * Error thrown when a call refers to an environment or program that was
+ * destroyed, such as a program ID kept after destroyProgram(), or to an object
+ * of another instance of the module, such as one loaded before a hot reload.
+ * Handles are never reused, so a stale handle is always reported rather than
+ * resolving to a newer object
+ */
+export class StaleHandleError extends Error {
+  /** Error code, always "stale_handle" */
+  readonly code: string;
+  /** The stale handle, such as a program ID */
+  readonly handle: string;
+
+  constructor(message: string, handle: string) {
+    super(message);
+    this.name = "StaleHandleError";
+    this.code = "stale_handle";
+    this.handle = handle;
+  }
+}
+
+/**
+ * Error thrown when an expression evaluates to a CEL error, such as a division by
+ * zero or a missing map key. Failures of the engine itself are reported with
+ * other errors
+ */
+export class EvaluationError extends Error {
+  /** The error value the expression evaluated to */
+  readonly errorValue: ErrorValue;
+  /** Custom function calls of the evaluation, with `traceFunctions: true` */
+  readonly functionTrace?: FunctionCall[];
+
+  constructor(errorValue: ErrorValue, functionTrace?: FunctionCall[]) {
+    super(`evaluation error: ${errorValue.message}`);
+    this.name = "EvaluationError";
+    this.errorValue = errorValue;
+    this.functionTrace = functionTrace;
+  }
+}
+
+/**
+ * Error thrown by strict evaluations when variables don't match their
+ * declarations
+ */
+export class InvalidVariablesError extends Error {
+  /** Each missing or mismatching value */
+  readonly variableErrors: VariableError[];
+
+  constructor(message: string, variableErrors: VariableError[]) {
+    super(message);
+    this.name = "InvalidVariablesError";
+    this.variableErrors = variableErrors;
+  }
+}
+
+/**
+ * Error thrown when an evaluation allocates more memory than its budget, set
+ * with `memoryLimitBytes` or configure()
+ */
+export class MemoryBudgetError extends Error {
+  /** The budget, in bytes */
+  readonly limitBytes: number;
+  /** The bytes allocated when the evaluation was stopped */
+  readonly allocatedBytes: number;
+
+  constructor(message: string, memoryBudget: MemoryBudget) {
+    super(message);
+    this.name = "MemoryBudgetError";
+    this.limitBytes = memoryBudget.limitBytes;
+    this.allocatedBytes = memoryBudget.allocatedBytes;
+  }
+}
+
+/**
+ * Convert the error of a result reported by the WASM module into an Error
+ */
+function toError(result: ErrorResult): Error {
+  const error = result.error ?? "unknown error";
+  if (result.errorCode === "stale_handle" && result.handle) {
+    return new StaleHandleError(errorMessage(error), result.handle);
+  }
+  if (typeof error === "string") {
+    return new Error(error);
+  }
+  return new InternalError(error.message, error.code, error.stack);
+}
+
+/**
+ * Convert the error of a result reported by an evaluation into an Error
+ */
+function toEvalError(
+  result: ErrorResult & {
+    variableErrors?: VariableError[];
+    memoryBudget?: MemoryBudget;
+  },
+): Error {
+  const error = result.error ?? "unknown error";
+  if (result.variableErrors) {
+    return new InvalidVariablesError(
+      errorMessage(error),
+      result.variableErrors,
+    );
+  }
+  if (result.memoryBudget) {
+    return new MemoryBudgetError(errorMessage(error), result.memoryBudget);
+  }
+  return toError(result);
+}
+
+/**
+ * Get the message of an error reported by the WASM module
+ */
+function errorMessage(error: string | WasmErrorInfo): string {
+  return typeof error === "string" ? error : error.message;
+}
+
+type FatalHandler = (error: InternalError) => void;
+
+const fatalHandlers = new Set<FatalHandler>();
+
+/**
+ * Register a callback for when the WASM module exits unexpectedly, such as on
+ * an unrecoverable Go runtime error. Existing Env, Program and Session
+ * instances can't be used afterwards; the next API call loads a fresh module
+ * instance.
+ *
+ * @param handler - Called with the error that describes the exit
+ * @returns Function that removes the handler
+ *
+ * @example
+ * ```typescript
+ * const off = onFatal((error) => {
+ *   console.error("CEL module crashed, rebuilding rules", error);
+ * });
+ * ```
+ */
+export function onFatal(handler: FatalHandler): () => void {
+  fatalHandlers.add(handler);
+  return () => {
+    fatalHandlers.delete(handler);
+  };
+}
+
 let isInitialized = false;
 let initPromise: Promise<void> | null = null;
+// Resolves when the Go program exits after shutdown()
+let exitPromise: Promise<void> | null = null;
+// Incremented by shutdown() so handles from a previous instance are never used
+// with the IDs of the next one
+let instanceGeneration = 0;
+// Periodically sweeps idle handles while a TTL is configured
+let sweepTimer: ReturnType<typeof setInterval> | null = null;
 
 /**
  * Initialize the WASM module
@@ -68,11 +283,23 @@ export async function init(): Promise<void> {
     }
 
     const go = new Go();
+    let exitCode = 0;
+    const defaultExit = go.exit.bind(go);
+    go.exit = (code: number) => {
+      exitCode = code;
+      defaultExit(code);
+    };
     const wasmBuffer = fs.readFileSync(wasmPath);
 
     WebAssembly.instantiate(wasmBuffer, go.importObject)
       .then((result: WebAssembly.WebAssemblyInstantiatedSource) => {
-        go.run(result.instance);
+        const generation = instanceGeneration;
+        exitPromise = go.run(result.instance).then(() => {
+          // shutdown() moves on to the next generation before the exit
+          if (generation === instanceGeneration) {
+            handleFatalExit(exitCode);
+          }
+        });
         isInitialized = true;
         resolve();
       })
@@ -87,6 +314,72 @@ export async function init(): Promise<void> {
   return initPromise;
 }
 
+/**
+ * Forget a module instance that exited on its own and notify the fatal handlers
+ */
+function handleFatalExit(code: number): void {
+  instanceGeneration++;
+  exitPromise = null;
+  isInitialized = false;
+  initPromise = null;
+  if (sweepTimer !== null) {
+    clearInterval(sweepTimer);
+    sweepTimer = null;
+  }
+
+  const error = new InternalError(
+    `WASM module exited unexpectedly with code ${code}`,
+    "fatal",
+  );
+  for (const handler of fatalHandlers) {
+    try {
+      handler(error);
+    } catch (err) {
+      console.error(`Error in fatal handler: ${err}`);
+    }
+  }
+}
+
+/**
+ * Shut down the WASM module, destroying every environment, program and session
+ * and unregistering all custom functions. Existing Env, Program and Session
+ * instances can't be used afterwards. The next API call loads a fresh instance,
+ * which makes this useful for hot reloading in development servers.
+ *
+ * @returns Promise resolving once the module has exited
+ * @throws Error if the module fails to shut down
+ */
+export async function shutdown(): Promise<void> {
+  if (!initPromise) {
+    return; // Never initialized, no-op
+  }
+
+  try {
+    await initPromise;
+  } catch {
+    // Failed to initialize, nothing to shut down
+    initPromise = null;
+    return;
+  }
+
+  if (sweepTimer !== null) {
+    clearInterval(sweepTimer);
+    sweepTimer = null;
+  }
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const result = globalObj.shutdown();
+  if (result.error) {
+    throw toError(result);
+  }
+
+  instanceGeneration++;
+  await exitPromise;
+  exitPromise = null;
+  isInitialized = false;
+  initPromise = null;
+}
+
 /**
  * Serialize a CEL type definition to a format that can be sent to Go
  */
@@ -109,6 +402,21 @@ function serializeTypeDef(type: CELTypeDef): any {
           valueType: serializeTypeDef(type.valueType),
         };
       }
+      if (type.kind === "object") {
+        const serialized: any = { kind: "object" };
+        if (type.fields) {
+          serialized.fields = Object.fromEntries(
+            Object.entries(type.fields).map(([name, fieldType]) => [
+              name,
+              serializeTypeDef(fieldType),
+            ]),
+          );
+        }
+        if (type.name !== undefined) {
+          serialized.name = type.name;
+        }
+        return serialized;
+      }
     }
   }
   return "dyn"; // Fallback to dynamic type
@@ -125,19 +433,36 @@ function serializeFunctionDefs(functions: CELFunctionDefinition[]): Array<{
   params: Array<{ name: string; type: any; optional?: boolean }>;
   returnType: any;
   implID: string;
+  isPure?: boolean;
+  cacheable?: boolean;
+  cacheSize?: number;
+  batchImplID?: string;
 }> {
   return functions.map((fn, index) => {
     // Generate a unique implementation ID
     const implID = `${fn.name}_${index}_${++implIDCounter}_${Date.now()}_${Math.random().toString(36).substring(2, 9)}`;
 
-    // Register the JavaScript function implementation
+    // Register the JavaScript function implementation, and its batch
+    // implementation if it has one
+    const batchImplID = fn.batchImpl ? `${implID}_batch` : undefined;
     const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
     if (typeof globalObj.registerCELFunction === "function") {
       const registerResult = globalObj.registerCELFunction(implID, fn.impl);
       if (registerResult.error) {
         throw new Error(
-          `Failed to register function ${fn.name}: ${registerResult.error}`,
+          `Failed to register function ${fn.name}: ${errorMessage(registerResult.error)}`,
+        );
+      }
+      if (batchImplID && fn.batchImpl) {
+        const batchResult = globalObj.registerCELFunction(
+          batchImplID,
+          fn.batchImpl,
         );
+        if (batchResult.error) {
+          throw new Error(
+            `Failed to register batch implementation of ${fn.name}: ${errorMessage(batchResult.error)}`,
+          );
+        }
       }
     } else {
       throw new Error(
@@ -154,21 +479,189 @@ function serializeFunctionDefs(functions: CELFunctionDefinition[]): Array<{
       })),
       returnType: serializeTypeDef(fn.returnType),
       implID,
+      isPure: fn.isPure === true,
+      cacheable: fn.cacheable === true,
+      cacheSize: fn.cacheSize,
+      batchImplID,
     };
   });
 }
 
+/**
+ * A field mask as a tree of field names, where null keeps the whole value
+ */
+type FieldMaskTree = Map<string, FieldMaskTree | null>;
+
+/**
+ * Build the tree of a field mask, letting shorter paths cover longer ones
+ */
+function fieldMaskTree(mask: string[][]): FieldMaskTree {
+  const root: FieldMaskTree = new Map();
+  for (const path of mask) {
+    let node = root;
+    for (const [i, field] of path.entries()) {
+      let child = node.get(field);
+      if (child === null) {
+        break;
+      }
+      if (i === path.length - 1) {
+        node.set(field, null);
+        break;
+      }
+      if (child === undefined) {
+        child = new Map();
+        node.set(field, child);
+      }
+      node = child;
+    }
+  }
+  return root;
+}
+
+/**
+ * Convert the clock an evaluation freezes now() at to an RFC 3339 string
+ */
+function evalNow(now: Date | string | undefined): string | undefined {
+  return now instanceof Date ? now.toISOString() : now;
+}
+
+/**
+ * Prepare the variables of an evaluation. Variables cross into the WASM module
+ * as JSON, which has no BigInts, so with the "string" and "bigint" integers
+ * modes, which read strings of digits back as integers, BigInts are replaced
+ * by their digits
+ */
+function evalVars(
+  vars: Record<string, any> | null,
+  integers: IntegerMode | undefined,
+): Record<string, any> {
+  if (!vars) {
+    return {};
+  }
+  if (integers !== "string" && integers !== "bigint") {
+    return vars;
+  }
+  return bigIntsToStrings(vars);
+}
+
+/**
+ * Replace the BigInts in arrays and plain objects with their digits
+ */
+function bigIntsToStrings(value: any): any {
+  if (typeof value === "bigint") {
+    return value.toString();
+  }
+  if (Array.isArray(value)) {
+    return value.map(bigIntsToStrings);
+  }
+  if (
+    value === null ||
+    typeof value !== "object" ||
+    Object.getPrototypeOf(value) !== Object.prototype
+  ) {
+    return value;
+  }
+
+  const result: Record<string, any> = {};
+  for (const [key, item] of Object.entries(value)) {
+    result[key] = bigIntsToStrings(item);
+  }
+  return result;
+}
+
+/**
+ * Keep the fields of a value covered by a field mask tree
+ */
+function pruneValue(value: any, tree: FieldMaskTree): any {
+  if (
+    value === null ||
+    typeof value !== "object" ||
+    Object.getPrototypeOf(value) !== Object.prototype
+  ) {
+    return value;
+  }
+
+  const pruned: Record<string, any> = {};
+  for (const [field, child] of tree) {
+    if (Object.prototype.hasOwnProperty.call(value, field)) {
+      pruned[field] =
+        child === null ? value[field] : pruneValue(value[field], child);
+    }
+  }
+  return pruned;
+}
+
+/**
+ * Convert the response of an evaluation to its result
+ * @throws EvaluationError if the expression evaluated to a CEL error that wasn't requested
+ * @throws Error if the evaluation failed
+ */
+function toEvalResult(
+  result: ReturnType<WasmCelExports["evalProgram"]>,
+  options: EvalOptions | undefined,
+): EvalResult {
+  if (result.error) {
+    throw toEvalError(result);
+  }
+  if (result.errorValue && options?.errorValues !== true) {
+    throw new EvaluationError(result.errorValue, result.functionTrace);
+  }
+
+  const evalResult: EvalResult = { result: result.result };
+  if (result.errorValue !== undefined) {
+    evalResult.errorValue = result.errorValue;
+  }
+  if (result.unknown !== undefined) {
+    evalResult.unknown = result.unknown;
+  }
+  if (result.cost !== undefined) {
+    evalResult.cost = result.cost;
+  }
+  if (result.metrics !== undefined) {
+    evalResult.metrics = result.metrics;
+  }
+  if (result.functionTrace !== undefined) {
+    evalResult.functionTrace = result.functionTrace;
+  }
+  if (result.attributeTrace !== undefined) {
+    evalResult.attributeTrace = result.attributeTrace;
+  }
+  return evalResult;
+}
+
+/**
+ * Serialize program options for transmission to Go
+ */
+function serializeProgramOptions(
+  options: CompileOptions | undefined,
+): string | undefined {
+  if (!options?.programOptions || options.programOptions.length === 0) {
+    return undefined;
+  }
+  return JSON.stringify(options.programOptions);
+}
+
 // FinalizationRegistry for automatic cleanup
 // This provides best-effort cleanup when objects are garbage collected
+
+// A handle ID along with the module instance it belongs to
+interface RegisteredHandle {
+  id: string;
+  generation: number;
+}
+
 const programRegistry =
   typeof FinalizationRegistry !== "undefined"
-    ? new FinalizationRegistry<string>((programID: string) => {
+    ? new FinalizationRegistry<RegisteredHandle>(({ id, generation }) => {
         // Best-effort cleanup when program is garbage collected
+        if (generation !== instanceGeneration) {
+          return; // Freed by shutdown() along with its instance
+        }
         try {
           const globalObj =
             typeof globalThis !== "undefined" ? globalThis : global;
           if (typeof globalObj.destroyProgram === "function") {
-            globalObj.destroyProgram(programID);
+            globalObj.destroyProgram(id);
           }
         } catch (err) {
           // Ignore errors during finalization - this is best-effort only
@@ -178,13 +671,16 @@ const programRegistry =
 
 const envRegistry =
   typeof FinalizationRegistry !== "undefined"
-    ? new FinalizationRegistry<string>((envID: string) => {
+    ? new FinalizationRegistry<RegisteredHandle>(({ id, generation }) => {
         // Best-effort cleanup when environment is garbage collected
+        if (generation !== instanceGeneration) {
+          return; // Freed by shutdown() along with its instance
+        }
         try {
           const globalObj =
             typeof globalThis !== "undefined" ? globalThis : global;
           if (typeof globalObj.destroyEnv === "function") {
-            globalObj.destroyEnv(envID);
+            globalObj.destroyEnv(id);
           }
         } catch (err) {
           // Ignore errors during finalization - this is best-effort only
@@ -192,29 +688,112 @@ const envRegistry =
       })
     : null;
 
+let evalCallbackCounter = 0;
+
 /**
  * A compiled CEL program that can be evaluated with variables
  */
 export class Program {
   private programID: string;
   private destroyed: boolean = false;
+  private generation: number = instanceGeneration;
+  private session?: Session;
+  /** Default integers mode of evaluations, from the environment */
+  private integers?: IntegerMode;
+
+  /**
+   * Source of the compiled expression after optimization, present when
+   * compiled with `optimizedSource: true`
+   */
+  readonly optimizedSource?: string;
+
+  /**
+   * Paths into the variables that the expression reads, present when compiled
+   * with `fieldMask: true`. Each path starts with a variable name and covers
+   * everything below it
+   */
+  readonly fieldMask?: string[][];
 
-  constructor(programID: string) {
+  /**
+   * Mapping of ranges of `optimizedSource` back to the ranges of the original
+   * expression they came from, present when compiled with `sourceMap: true`
+   */
+  readonly sourceMap?: SourceMapping[];
+
+  constructor(
+    programID: string,
+    session?: Session,
+    optimizedSource?: string,
+    fieldMask?: string[][],
+    sourceMap?: SourceMapping[],
+    integers?: IntegerMode,
+  ) {
     this.programID = programID;
+    this.session = session;
+    this.optimizedSource = optimizedSource;
+    this.fieldMask = fieldMask;
+    this.sourceMap = sourceMap;
+    this.integers = integers;
     // Register for automatic cleanup via FinalizationRegistry
     if (programRegistry) {
-      programRegistry.register(this, programID);
+      programRegistry.register(this, {
+        id: programID,
+        generation: this.generation,
+      });
+    }
+  }
+
+  /**
+   * Get the program ID (useful for debugging or advanced use cases)
+   */
+  getID(): string {
+    return this.programID;
+  }
+
+  /**
+   * Drop the parts of the variables that the expression doesn't read, so large
+   * context objects aren't serialized for nothing. Only plain objects are
+   * pruned; lists and other values are kept whole
+   * @param vars - Variables to prune, which are left unmodified
+   * @returns The variables the expression reads
+   * @throws Error if the program wasn't compiled with `fieldMask: true`
+   *
+   * @example
+   * ```typescript
+   * const program = await env.compile("request.user.id == owner", { fieldMask: true });
+   * const result = await program.eval(program.pruneVars(context));
+   * ```
+   */
+  pruneVars(vars: Record<string, any>): Record<string, any> {
+    if (!this.fieldMask) {
+      throw new Error("Program was not compiled with fieldMask: true");
     }
+    return pruneValue(vars, fieldMaskTree(this.fieldMask));
   }
 
   /**
    * Evaluate the compiled program with the given variables
    * @param vars - Variables to use in the evaluation
+   * @param options - Optional evaluation options such as `mapKeys` and `nonFinite`
    * @returns Promise resolving to the evaluation result
+   * @throws EvaluationError if the expression evaluates to a CEL error
+   * @throws InvalidVariablesError if `strict` is set and the variables don't
+   * match their declarations
    * @throws Error if evaluation fails or program has been destroyed
    */
-  async eval(vars: Record<string, any> | null = null): Promise<any> {
-    if (this.destroyed) {
+  async eval(
+    vars: Record<string, any> | null = null,
+    options?: Pick<
+      EvalOptions,
+      | "mapKeys"
+      | "nonFinite"
+      | "integers"
+      | "strict"
+      | "timeoutMs"
+      | "memoryLimitBytes"
+    >,
+  ): Promise<any> {
+    if (this.isReleased()) {
       throw new Error("Program has been destroyed");
     }
 
@@ -224,10 +803,27 @@ export class Program {
       try {
         const globalObj =
           typeof globalThis !== "undefined" ? globalThis : global;
-        const result = globalObj.evalProgram(this.programID, vars || {});
+        const integers = options?.integers ?? this.integers;
+        const result = globalObj.evalProgram(
+          this.programID,
+          evalVars(vars, integers),
+          {
+            mapKeys: options?.mapKeys,
+            nonFinite: options?.nonFinite,
+            integers,
+            errorValues: true,
+            strict: options?.strict === true,
+            timeoutMs: options?.timeoutMs,
+            memoryLimitBytes: options?.memoryLimitBytes,
+            now: evalNow(options?.now),
+            seed: options?.seed,
+          },
+        );
 
         if (result.error) {
-          reject(new Error(result.error));
+          reject(toEvalError(result));
+        } else if (result.errorValue) {
+          reject(new EvaluationError(result.errorValue));
         } else {
           resolve(result.result);
         }
@@ -239,277 +835,521 @@ export class Program {
   }
 
   /**
-   * Destroy this program and free associated WASM resources.
-   * After calling destroy(), this program instance should not be used.
-   * If FinalizationRegistry is available, resources will be automatically
-   * cleaned up when the object is garbage collected, but explicit cleanup
-   * is recommended.
+   * Evaluate the compiled program and return the result along with evaluation details
+   * @param vars - Variables to use in the evaluation
+   * @param options - Optional evaluation options such as `metrics`
+   * @returns Promise resolving to the evaluation result and details such as runtime cost
+   * @throws EvaluationError if the expression evaluates to a CEL error, unless
+   * `errorValues` is set
+   * @throws Error if evaluation fails or program has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const program = await env.compile("x.size()", {
+   *   programOptions: [ProgramOptions.costTracking()],
+   * });
+   * const { result, cost } = await program.evalDetailed({ x: [1, 2, 3] });
+   * ```
    */
-  destroy(): void {
-    if (this.destroyed) {
-      return; // Already destroyed, no-op
-    }
-
-    try {
-      const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
-      if (typeof globalObj.destroyProgram === "function") {
-        const result = globalObj.destroyProgram(this.programID);
-        if (result.error) {
-          // Log but don't throw - cleanup should be best-effort
-          console.warn(`Failed to destroy program: ${result.error}`);
-        }
-      }
-    } catch (err) {
-      // Log but don't throw - cleanup should be best-effort
-      console.warn(`Error destroying program: ${err}`);
-    } finally {
-      this.destroyed = true;
-      // Unregister from FinalizationRegistry since we've explicitly cleaned up
-      if (programRegistry) {
-        programRegistry.unregister(this);
-      }
+  async evalDetailed(
+    vars: Record<string, any> | null = null,
+    options?: EvalOptions,
+  ): Promise<EvalResult> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
     }
-  }
-}
 
-/**
- * A CEL environment that holds variable declarations and function definitions
- */
-export class Env {
-  private envID: string;
-  private destroyed: boolean = false;
+    await init();
 
-  private constructor(envID: string) {
-    this.envID = envID;
-    // Register for automatic cleanup via FinalizationRegistry
-    if (envRegistry) {
-      envRegistry.register(this, envID);
-    }
-  }
+    return new Promise<EvalResult>((resolve, reject) => {
+      let result: ReturnType<WasmCelExports["evalProgram"]>;
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const integers = options?.integers ?? this.integers;
+        result = globalObj.evalProgram(
+          this.programID,
+          evalVars(vars, integers),
+          {
+            metrics: options?.metrics === true,
+            mapKeys: options?.mapKeys,
+            nonFinite: options?.nonFinite,
+            integers,
+            errorValues: true,
+            unknowns: options?.unknowns,
+            traceFunctions: options?.traceFunctions === true,
+            traceAttributes: options?.traceAttributes === true,
+            strict: options?.strict === true,
+            timeoutMs: options?.timeoutMs,
+            memoryLimitBytes: options?.memoryLimitBytes,
+            now: evalNow(options?.now),
+            seed: options?.seed,
+          },
+        );
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+        return;
+      }
 
-  /**
-   * Get the environment ID (useful for debugging or advanced use cases)
-   */
-  getID(): string {
-    return this.envID;
+      try {
+        resolve(toEvalResult(result, options));
+      } catch (err) {
+        reject(err);
+      }
+    });
   }
 
   /**
-   * Create a new CEL environment
-   * @param options - Options including variable declarations, function definitions, and environment options
-   * @returns Promise resolving to a new Env instance
-   * @throws Error if environment creation fails
+   * Evaluate the compiled program on the module's worker pool, so custom
+   * functions may return Promises. While an evaluation waits for one, other
+   * evaluations on the pool keep running, so a slow function doesn't hold up
+   * the rest. At most `evalWorkers` evaluations (see configure()) run at once,
+   * and the others wait for a free worker
+   * @param vars - Variables to use in the evaluation
+   * @param options - Optional evaluation options, as for evalDetailed(), and a
+   * `signal` to cancel the evaluation with
+   * @returns Promise resolving to the evaluation result and details
+   * @throws EvaluationError if the expression evaluates to a CEL error, unless
+   * `errorValues` is set
+   * @throws The signal's reason if the evaluation is cancelled
+   * @throws Error if evaluation fails or program has been destroyed
    *
    * @example
    * ```typescript
    * const env = await Env.new({
-   *   variables: [
-   *     { name: "x", type: "int" },
-   *     { name: "y", type: "int" }
-   *   ],
+   *   variables: [{ name: "user", type: "string" }],
    *   functions: [
-   *     CELFunction.new("add")
-   *       .param("a", "int")
-   *       .param("b", "int")
-   *       .returns("int")
-   *       .implement((a, b) => a + b)
+   *     CELFunction.new("isMember")
+   *       .param("user", "string")
+   *       .returns("bool")
+   *       .implement(async (user) => (await fetchMembers()).includes(user)),
    *   ],
-   *   options: [
-   *     Options.optionalTypes()
-   *   ]
    * });
+   * const program = await env.compile("isMember(user)");
+   * const { result } = await program.evalConcurrent({ user: "alice" });
    * ```
    */
-  static async new(options?: EnvOptions): Promise<Env> {
-    await init();
-
-    // Serialize variable declarations
-    const varDecls = (options?.variables || []).map((v) => ({
-      name: v.name,
-      type: serializeTypeDef(v.type),
-    }));
-
-    // Serialize function definitions if provided
-    let serializedFuncDefs: any = null;
-    if (options?.functions && options.functions.length > 0) {
-      serializedFuncDefs = serializeFunctionDefs(options.functions);
+  async evalConcurrent(
+    vars: Record<string, any> | null = null,
+    options?: ConcurrentEvalOptions,
+  ): Promise<EvalResult> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
     }
 
-    // INTERNAL: Create environment first without options, then extend if needed
-    // This allows complex options to perform JavaScript-side setup before being applied
-    const env = await new Promise<Env>((resolve, reject) => {
+    const signal = options?.signal;
+    signal?.throwIfAborted();
+
+    await init();
+
+    return new Promise<EvalResult>((resolve, reject) => {
       try {
         const globalObj =
           typeof globalThis !== "undefined" ? globalThis : global;
-        const result = globalObj.createEnv(varDecls, serializedFuncDefs);
 
-        if (result.error) {
-          reject(new Error(result.error));
-        } else if (!result.envID) {
-          reject(new Error("Environment creation failed: no envID returned"));
-        } else {
-          resolve(new Env(result.envID));
+        let token: string | undefined;
+        const onAbort = () => {
+          if (token !== undefined) {
+            globalObj.cancelEval(token);
+          }
+        };
+
+        // The module calls the callback once, with the result of evalProgram()
+        let completed = false;
+        const callbackID = `evalConcurrent_${++evalCallbackCounter}`;
+        const registerResult = globalObj.registerCELFunction(
+          callbackID,
+          (result: ReturnType<WasmCelExports["evalProgram"]>) => {
+            completed = true;
+            signal?.removeEventListener("abort", onAbort);
+            if (signal?.aborted) {
+              reject(signal.reason);
+              return;
+            }
+            try {
+              resolve(toEvalResult(result, options));
+            } catch (err) {
+              reject(err);
+            }
+          },
+        );
+        if (registerResult.error) {
+          reject(
+            new Error(
+              `Failed to register callback: ${errorMessage(registerResult.error)}`,
+            ),
+          );
+          return;
+        }
+
+        const integers = options?.integers ?? this.integers;
+        const queued = globalObj.evalProgramAsync(
+          this.programID,
+          evalVars(vars, integers),
+          callbackID,
+          {
+            metrics: options?.metrics === true,
+            mapKeys: options?.mapKeys,
+            nonFinite: options?.nonFinite,
+            integers,
+            errorValues: true,
+            unknowns: options?.unknowns,
+            traceFunctions: options?.traceFunctions === true,
+            traceAttributes: options?.traceAttributes === true,
+            strict: options?.strict === true,
+            timeoutMs: options?.timeoutMs,
+            memoryLimitBytes: options?.memoryLimitBytes,
+            now: evalNow(options?.now),
+            seed: options?.seed,
+          },
+        );
+        if (queued.error) {
+          reject(toError(queued));
+          return;
+        }
+
+        // Evaluations that don't wait for a custom function complete right away
+        token = queued.token;
+        if (!completed) {
+          signal?.addEventListener("abort", onAbort, { once: true });
         }
       } catch (err) {
         const error = err instanceof Error ? err : new Error(String(err));
         reject(new Error(`WASM call failed: ${error.message}`));
       }
     });
+  }
 
-    // INTERNAL: If options were provided, extend the environment
-    // This allows options to perform JavaScript-side setup (like registering functions)
-    if (options?.options && options.options.length > 0) {
-      await env._extendWithOptions(options.options);
+  /**
+   * Evaluate several programs against the same variables, such as a set of
+   * policy rules against one request. The variables cross into the WASM module
+   * once, rather than once per program
+   * @param programs - The programs to evaluate
+   * @param vars - Variables to use in the evaluations
+   * @param options - Optional evaluation options, applied to every program
+   * @returns Promise resolving to the outcome of each program, in order, in
+   * the form of Promise.allSettled(), so one failing program doesn't hide the
+   * results of the others
+   * @throws Error if a program has been destroyed or the call fails
+   *
+   * @example
+   * ```typescript
+   * const outcomes = await Program.evalAll(rules, { request });
+   * const denied = outcomes.some(
+   *   (o) => o.status === "fulfilled" && o.value.result === true,
+   * );
+   * ```
+   */
+  static async evalAll(
+    programs: Program[],
+    vars: Record<string, any> | null = null,
+    options?: EvalOptions,
+  ): Promise<PromiseSettledResult<EvalResult>[]> {
+    for (const program of programs) {
+      if (program.isReleased()) {
+        throw new Error("Program has been destroyed");
+      }
     }
 
-    return env;
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.evalPrograms(
+      programs.map((program) => program.programID),
+      evalVars(vars, options?.integers),
+      {
+        metrics: options?.metrics === true,
+        mapKeys: options?.mapKeys,
+        nonFinite: options?.nonFinite,
+        integers: options?.integers,
+        errorValues: true,
+        unknowns: options?.unknowns,
+        traceFunctions: options?.traceFunctions === true,
+        traceAttributes: options?.traceAttributes === true,
+        strict: options?.strict === true,
+        timeoutMs: options?.timeoutMs,
+        memoryLimitBytes: options?.memoryLimitBytes,
+        now: evalNow(options?.now),
+        seed: options?.seed,
+      },
+    );
+    if (result.error) {
+      throw toError(result);
+    }
+
+    return (result.results ?? []).map(
+      (programResult): PromiseSettledResult<EvalResult> => {
+        try {
+          return {
+            status: "fulfilled",
+            value: toEvalResult(programResult, options),
+          };
+        } catch (err) {
+          return { status: "rejected", reason: err };
+        }
+      },
+    );
   }
 
   /**
-   * Compile a CEL expression in this environment
-   * @param expr - The CEL expression to compile
-   * @returns Promise resolving to a compiled Program
-   * @throws Error if compilation fails or environment has been destroyed
+   * Evaluate the compiled program several times against the same variables and
+   * report the timings, for benchmarking an expression without the cost of a
+   * call into the WASM module per evaluation. The variables are decoded once,
+   * and memoized programs are evaluated rather than looked up
+   * @param n - Number of evaluations
+   * @param vars - Variables to use in the evaluations
+   * @param options - Optional evaluation options, applied to every evaluation
+   * @returns Promise resolving to the result of the last evaluation and the
+   * timings of all of them
+   * @throws EvaluationError if the expression evaluates to a CEL error, unless
+   * `errorValues` is set
+   * @throws Error if an evaluation fails, n isn't positive or the program has
+   * been destroyed
    *
    * @example
    * ```typescript
-   * const env = await Env.new({
-   *   variables: [{ name: "x", type: "int" }]
-   * });
-   * const program = await env.compile("x + 10");
-   * const result = await program.eval({ x: 5 });
-   * console.log(result); // 15
+   * const { stats } = await program.evalRepeated(1000, { x: [1, 2, 3] });
+   * console.log(stats.p50Ms, stats.p95Ms);
    * ```
    */
-  async compile(expr: string): Promise<Program> {
-    if (this.destroyed) {
-      throw new Error("Environment has been destroyed");
+  async evalRepeated(
+    n: number,
+    vars: Record<string, any> | null = null,
+    options?: EvalOptions,
+  ): Promise<RepeatedEvalResult> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
     }
 
     await init();
 
-    if (typeof expr !== "string") {
-      throw new Error("Expression must be a string");
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const integers = options?.integers ?? this.integers;
+    const result = globalObj.evalRepeated(
+      this.programID,
+      evalVars(vars, integers),
+      n,
+      {
+        metrics: options?.metrics === true,
+        mapKeys: options?.mapKeys,
+        nonFinite: options?.nonFinite,
+        integers,
+        errorValues: true,
+        unknowns: options?.unknowns,
+        traceFunctions: options?.traceFunctions === true,
+        traceAttributes: options?.traceAttributes === true,
+        strict: options?.strict === true,
+        timeoutMs: options?.timeoutMs,
+        memoryLimitBytes: options?.memoryLimitBytes,
+        now: evalNow(options?.now),
+        seed: options?.seed,
+      },
+    );
+
+    return {
+      ...toEvalResult(result, options),
+      stats: result.stats as EvalStats,
+    };
+  }
+
+  /**
+   * Run test cases against the compiled program in one call into the WASM
+   * module, comparing each result with the expected one
+   * @param cases - Variables and the expected result or error of each case
+   * @param options - Optional evaluation options, applied to every case
+   * @returns Promise resolving to the number of cases that passed and failed,
+   * and the outcome of each case with the actual result and its differences
+   * from the expected one. Cases that fail don't reject
+   * @throws Error if the program has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const program = await env.compile("user.age >= 18");
+   * const { failed, results } = await program.runTests([
+   *   { name: "adult", vars: { user: { age: 30 } }, expect: true },
+   *   { name: "minor", vars: { user: { age: 17 } }, expect: false },
+   *   { name: "no age", vars: { user: {} }, expectError: "no such key" },
+   * ]);
+   * ```
+   */
+  async runTests(
+    cases: TestCase[],
+    options?: EvalOptions,
+  ): Promise<TestRunResult> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
     }
 
-    return new Promise<Program>((resolve, reject) => {
-      try {
-        const globalObj =
-          typeof globalThis !== "undefined" ? globalThis : global;
-        const result = globalObj.compileExpr(this.envID, expr);
+    await init();
 
-        if (result.error) {
-          reject(new Error(result.error));
-        } else if (!result.programID) {
-          reject(new Error("Compilation failed: no programID returned"));
-        } else {
-          resolve(new Program(result.programID));
-        }
-      } catch (err) {
-        const error = err instanceof Error ? err : new Error(String(err));
-        reject(new Error(`WASM call failed: ${error.message}`));
-      }
-    });
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const integers = options?.integers ?? this.integers;
+    const result = globalObj.runTests(
+      this.programID,
+      cases.map((testCase) => ({
+        ...testCase,
+        vars: evalVars(testCase.vars ?? null, integers),
+        expect: evalVars({ expect: testCase.expect }, integers).expect,
+      })),
+      {
+        mapKeys: options?.mapKeys,
+        nonFinite: options?.nonFinite,
+        integers,
+        unknowns: options?.unknowns,
+        strict: options?.strict === true,
+        timeoutMs: options?.timeoutMs,
+        memoryLimitBytes: options?.memoryLimitBytes,
+        now: evalNow(options?.now),
+        seed: options?.seed,
+      },
+    );
+    if (result.error) {
+      throw toError(result);
+    }
+
+    return {
+      passed: result.passed ?? 0,
+      failed: result.failed ?? 0,
+      results: result.results ?? [],
+    };
   }
 
   /**
-   * Compile a CEL expression with detailed results including warnings and issues
-   * @param expr - The CEL expression to compile
-   * @returns Promise resolving to detailed compilation results
-   * @throws Error if environment has been destroyed
+   * Evaluate the compiled program and explain how it arrived at its result,
+   * for policy debugging UIs. The explanation is a tree following the logical
+   * operators and conditionals of the expression down to the subexpressions
+   * they combine, each with its value, or whether it failed or was skipped by
+   * short-circuiting
+   * @param vars - Variables to use in the evaluation
+   * @param options - Optional options for converting values, such as `mapKeys`
+   * @returns Promise resolving to the result and its explanation. An
+   * expression that evaluates to a CEL error resolves with its `errorValue`
+   * @throws Error if the evaluation fails or the program has been destroyed
    *
    * @example
    * ```typescript
-   * const result = await env.compileDetailed("x + y");
-   * if (result.success) {
-   *   console.log("Compiled successfully");
-   *   if (result.issues.length > 0) {
-   *     console.log("Warnings:", result.issues);
-   *   }
-   *   const evalResult = await result.program.eval({ x: 10, y: 20 });
-   * } else {
-   *   console.log("Compilation failed:", result.error);
-   *   console.log("All issues:", result.issues);
-   * }
+   * const program = await env.compile("a && (b || c)");
+   * const { explanation } = await program.explain({
+   *   a: true,
+   *   b: false,
+   *   c: true,
+   * });
+   * // explanation.operands[1].operands[0]: { expr: "b", outcome: "false" }
    * ```
    */
-  async compileDetailed(
-    expr: string,
-  ): Promise<import("./types.js").CompilationResult> {
-    if (this.destroyed) {
-      throw new Error("Environment has been destroyed");
+  async explain(
+    vars: Record<string, any> | null = null,
+    options?: ExplainOptions,
+  ): Promise<Explanation> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
     }
 
     await init();
 
-    if (typeof expr !== "string") {
-      throw new Error("Expression must be a string");
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const integers = options?.integers ?? this.integers;
+    const result = globalObj.explain(this.programID, evalVars(vars, integers), {
+      mapKeys: options?.mapKeys,
+      nonFinite: options?.nonFinite,
+      integers,
+    });
+    if (result.error) {
+      throw toError(result);
     }
 
-    return new Promise<import("./types.js").CompilationResult>((resolve) => {
+    const explained: Explanation = {
+      result: result.result,
+      explanation: result.explanation as ExplanationNode,
+    };
+    if (result.errorValue !== undefined) {
+      explained.errorValue = result.errorValue;
+    }
+    return explained;
+  }
+
+  /**
+   * Evaluate the compiled program using the fields of a protobuf message as
+   * variables, for environments created with `Options.declareContextProto()`
+   * @param typeName - Fully-qualified name of the context message
+   * @param message - The message in the binary encoding, or its JSON mapping
+   * as a string or object
+   * @param options - Optional evaluation options such as `mapKeys` and `nonFinite`
+   * @returns Promise resolving to the evaluation result
+   * @throws EvaluationError if the expression evaluates to a CEL error
+   * @throws Error if the message can't be decoded, evaluation fails or the
+   * program has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const program = await env.compile('path.startsWith("/admin")');
+   * const allowed = await program.evalWithContextMessage(
+   *   "acme.CheckRequest",
+   *   requestBytes,
+   * );
+   * ```
+   */
+  async evalWithContextMessage(
+    typeName: string,
+    message: Uint8Array | string | Record<string, any>,
+    options?: Pick<EvalOptions, "mapKeys" | "nonFinite" | "integers">,
+  ): Promise<any> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
+    }
+
+    await init();
+
+    return new Promise<any>((resolve, reject) => {
       try {
         const globalObj =
           typeof globalThis !== "undefined" ? globalThis : global;
-        const result = (globalObj as any).compileExprDetailed(this.envID, expr);
+        const result = globalObj.evalProgramWithContextMessage(
+          this.programID,
+          typeName,
+          message,
+          {
+            mapKeys: options?.mapKeys,
+            nonFinite: options?.nonFinite,
+            integers: options?.integers ?? this.integers,
+            errorValues: true,
+          },
+        );
 
-        if (result.error && !result.programID) {
-          // Compilation failed completely
-          resolve({
-            success: false,
-            error: result.error,
-            issues: result.issues || [],
-            program: undefined,
-          });
-        } else if (result.programID) {
-          // Compilation succeeded (possibly with warnings)
-          resolve({
-            success: true,
-            error: undefined,
-            issues: result.issues || [],
-            program: new Program(result.programID),
-          });
+        if (result.error) {
+          reject(toError(result));
+        } else if (result.errorValue) {
+          reject(new EvaluationError(result.errorValue));
         } else {
-          // Unexpected state
-          resolve({
-            success: false,
-            error: "Compilation failed: no programID returned",
-            issues: result.issues || [],
-            program: undefined,
-          });
+          resolve(result.result);
         }
       } catch (err) {
         const error = err instanceof Error ? err : new Error(String(err));
-        resolve({
-          success: false,
-          error: `WASM call failed: ${error.message}`,
-          issues: [],
-          program: undefined,
-        });
+        reject(new Error(`WASM call failed: ${error.message}`));
       }
     });
   }
 
   /**
-   * Typecheck a CEL expression in this environment without compiling it
-   * @param expr - The CEL expression to typecheck
-   * @returns Promise resolving to the type information
-   * @throws Error if typechecking fails or environment has been destroyed
+   * Replace the expression of this program, compiling it in the program's
+   * environment with the options the program was compiled with. The program
+   * keeps its ID, so components holding it pick up the new expression on their
+   * next evaluation. If the expression doesn't compile, the program is left as
+   * it was
+   * @param expr - The CEL expression to compile
+   * @throws Error if compilation fails or the program has been destroyed
    *
    * @example
    * ```typescript
-   * const env = await Env.new({
-   *   variables: [{ name: "x", type: "int" }, { name: "y", type: "int" }]
-   * });
-   * const typeInfo = await env.typecheck("x + y");
-   * console.log(typeInfo.type); // "int"
-   *
-   * const listType = await env.typecheck("[1, 2, 3]");
-   * console.log(listType.type); // { kind: "list", elementType: "int" }
+   * const program = await env.compile("x > 10.0");
+   * await program.replace("x > 20.0");
+   * await program.eval({ x: 15 }); // false
    * ```
    */
-  async typecheck(expr: string): Promise<TypeCheckResult> {
-    if (this.destroyed) {
-      throw new Error("Environment has been destroyed");
+  async replace(expr: string): Promise<void> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
     }
 
     await init();
@@ -518,70 +1358,1258 @@ export class Env {
       throw new Error("Expression must be a string");
     }
 
-    return new Promise<TypeCheckResult>((resolve, reject) => {
-      try {
-        const globalObj =
-          typeof globalThis !== "undefined" ? globalThis : global;
-        const result = globalObj.typecheckExpr(this.envID, expr);
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.replaceProgram(this.programID, expr);
+    if (result.error) {
+      throw toError(result);
+    }
 
-        if (result.error) {
-          reject(new Error(result.error));
-        } else if (result.type === undefined) {
-          reject(new Error("Typecheck failed: no type returned"));
-        } else {
-          resolve({ type: result.type });
-        }
-      } catch (err) {
-        const error = err instanceof Error ? err : new Error(String(err));
-        reject(new Error(`WASM call failed: ${error.message}`));
-      }
+    // Flags are kept, so each field is present exactly when it was before
+    Object.assign(this, {
+      optimizedSource: result.optimizedSource,
+      fieldMask: result.fieldMask,
+      sourceMap: result.sourceMap,
     });
   }
 
   /**
-   * Extend this environment with additional CEL environment options
-   * @param options - Array of CEL environment option configurations or complex options with setup
-   * @returns Promise that resolves when the environment has been extended
-   * @throws Error if extension fails or environment has been destroyed
+   * Export the checked expression of the program as a
+   * google.api.expr.v1alpha1.CheckedExpr, so other CEL runtimes such as cel-cpp
+   * or cel-java can evaluate exactly what was checked here. Constants and
+   * optimizations are already folded into it, and the runtime has to provide
+   * the environment's custom functions
+   * @param format - The encoding, defaults to the protobuf wire format
+   * @returns Promise resolving to the encoded CheckedExpr, as bytes in the
+   * binary encoding and as a string otherwise
+   * @throws Error if the program has been destroyed
    *
    * @example
    * ```typescript
-   * const env = await Env.new({
-   *   variables: [{ name: "x", type: "int" }]
-   * });
-   *
-   * // Add options after creation
-   * await env.extend([Options.optionalTypes()]);
+   * const program = await env.compile("user.age >= 18");
+   * const checked = await program.exportCheckedExpr();
+   * await fetch("/rules", { method: "POST", body: checked });
    * ```
    */
-  async extend(
-    options: import("./options/index.js").EnvOptionInput[],
-  ): Promise<void> {
-    return this._extendWithOptions(options);
+  exportCheckedExpr(format?: "binary"): Promise<Uint8Array>;
+  exportCheckedExpr(format: "json" | "textproto"): Promise<string>;
+  async exportCheckedExpr(
+    format: CheckedExprFormat = "binary",
+  ): Promise<Uint8Array | string> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
+    }
+
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.exportCheckedExpr(this.programID, format);
+    if (result.error) {
+      throw toError(result);
+    }
+    return result.checkedExpr as Uint8Array | string;
   }
 
   /**
-   * Internal method to extend environment with options
-   * This method delegates to options that implement OptionWithSetup for complex operations
-   * @private
+   * Prepare the program for its first evaluation ahead of time, for
+   * latency-sensitive first uses. Programs are planned when they're compiled,
+   * so this computes what evaluations otherwise compute on first use, such as
+   * the variables the program reads for strict evaluations
+   * @throws Error if the program has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const program = await env.compile("request.user.role == 'admin'");
+   * await program.warmup();
+   * ```
    */
-  private async _extendWithOptions(
-    options: import("./options/index.js").EnvOptionInput[],
-  ): Promise<void> {
-    if (this.destroyed) {
-      throw new Error("Environment has been destroyed");
+  async warmup(): Promise<void> {
+    if (this.isReleased()) {
+      throw new Error("Program has been destroyed");
     }
 
     await init();
 
-    if (!options || options.length === 0) {
-      return; // Nothing to extend
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.warmup(this.programID);
+    if (result.error) {
+      throw toError(result);
     }
+  }
 
-    // Process options: delegate to options that can handle their own setup
-    const processedOptions: import("./options/index.js").EnvOptionConfig[] = [];
-
-    for (const option of options) {
+  /**
+   * Whether the WASM resources behind this program are gone, either through
+   * destroy(), its session or shutdown()
+   */
+  private isReleased(): boolean {
+    return (
+      this.destroyed ||
+      this.generation !== instanceGeneration ||
+      this.session?.isDestroyed() === true
+    );
+  }
+
+  /**
+   * Destroy this program and free associated WASM resources.
+   * After calling destroy(), this program instance should not be used.
+   * If FinalizationRegistry is available, resources will be automatically
+   * cleaned up when the object is garbage collected, but explicit cleanup
+   * is recommended.
+   */
+  destroy(): void {
+    if (this.destroyed) {
+      return; // Already destroyed, no-op
+    }
+
+    try {
+      const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+      // Already freed along with a destroyed session or by shutdown()
+      if (
+        !this.isReleased() &&
+        typeof globalObj.destroyProgram === "function"
+      ) {
+        const result = globalObj.destroyProgram(this.programID);
+        if (result.error) {
+          // Log but don't throw - cleanup should be best-effort
+          console.warn(
+            `Failed to destroy program: ${errorMessage(result.error)}`,
+          );
+        }
+      }
+    } catch (err) {
+      // Log but don't throw - cleanup should be best-effort
+      console.warn(`Error destroying program: ${err}`);
+    } finally {
+      this.destroyed = true;
+      // Unregister from FinalizationRegistry since we've explicitly cleaned up
+      if (programRegistry) {
+        programRegistry.unregister(this);
+      }
+    }
+  }
+}
+
+/**
+ * A CEL environment that holds variable declarations and function definitions
+ */
+export class Env {
+  private envID: string;
+  private destroyed: boolean = false;
+  private generation: number = instanceGeneration;
+  private session?: Session;
+  private integers?: IntegerMode;
+
+  private constructor(
+    envID: string,
+    session?: Session,
+    integers?: IntegerMode,
+  ) {
+    this.envID = envID;
+    this.session = session;
+    this.integers = integers;
+    // Register for automatic cleanup via FinalizationRegistry
+    if (envRegistry) {
+      envRegistry.register(this, { id: envID, generation: this.generation });
+    }
+  }
+
+  /**
+   * Get the environment ID (useful for debugging or advanced use cases)
+   */
+  getID(): string {
+    return this.envID;
+  }
+
+  /**
+   * Create a new CEL environment
+   * @param options - Options including variable declarations, function definitions, and environment options
+   * @returns Promise resolving to a new Env instance
+   * @throws Error if environment creation fails
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [
+   *     { name: "x", type: "int" },
+   *     { name: "y", type: "int" }
+   *   ],
+   *   functions: [
+   *     CELFunction.new("add")
+   *       .param("a", "int")
+   *       .param("b", "int")
+   *       .returns("int")
+   *       .implement((a, b) => a + b)
+   *   ],
+   *   options: [
+   *     Options.optionalTypes()
+   *   ]
+   * });
+   * ```
+   */
+  static async new(options?: EnvOptions): Promise<Env> {
+    await init();
+
+    const session = options?.session;
+    if (session?.isDestroyed()) {
+      throw new Error("Session has been destroyed");
+    }
+
+    // Serialize variable declarations
+    const varDecls = (options?.variables || []).map((v) => ({
+      name: v.name,
+      type: serializeTypeDef(v.type),
+      default: v.default,
+    }));
+
+    // Serialize constant declarations
+    const constants = (options?.constants || []).map((c) => ({
+      name: c.name,
+      type: serializeTypeDef(c.type),
+      value: c.value,
+    }));
+
+    // Serialize function definitions if provided
+    let serializedFuncDefs: any = null;
+    if (options?.functions && options.functions.length > 0) {
+      serializedFuncDefs = serializeFunctionDefs(options.functions);
+    }
+
+    // INTERNAL: Create environment first without options, then extend if needed
+    // This allows complex options to perform JavaScript-side setup before being applied
+    const env = await new Promise<Env>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.createEnv({
+          varDecls,
+          funcDefs: serializedFuncDefs,
+          constants,
+          libraries: options?.libraries,
+          absentVariables: options?.absentVariables,
+          apiVersion: options?.apiVersion,
+          sessionID: session?.getID(),
+        });
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (!result.envID) {
+          reject(new Error("Environment creation failed: no envID returned"));
+        } else {
+          resolve(new Env(result.envID, session, options?.integers));
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+
+    // INTERNAL: If options were provided, extend the environment
+    // This allows options to perform JavaScript-side setup (like registering functions)
+    if (options?.options && options.options.length > 0) {
+      await env._extendWithOptions(options.options);
+    }
+
+    return env;
+  }
+
+  /**
+   * Fully qualified name of the context message of environments created with
+   * {@link Env.fromJSONSchema}
+   */
+  static readonly JSON_SCHEMA_TYPE_NAME = "wasmcel.schema.Root";
+
+  /**
+   * Create a CEL environment declaring the properties of a JSON Schema object
+   * as variables. Objects with properties become message types, so their
+   * fields are type checked; arrays become lists, and strings with the
+   * `date-time` format or base64 content encoding become timestamps and bytes.
+   * @param schema - The JSON Schema, as an object or a JSON string
+   * @param options - Optional environment options to add to the environment
+   * @returns Promise resolving to a new Env instance
+   * @throws Error if the schema can't be converted
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.fromJSONSchema({
+   *   type: "object",
+   *   properties: {
+   *     user: {
+   *       type: "object",
+   *       properties: { name: { type: "string" }, age: { type: "integer" } },
+   *     },
+   *   },
+   * });
+   * const program = await env.compile("user.age >= 18");
+   * const result = await program.evalWithContextMessage(
+   *   Env.JSON_SCHEMA_TYPE_NAME,
+   *   { user: { name: "Ada", age: 36 } },
+   * );
+   * ```
+   */
+  static async fromJSONSchema(
+    schema: Record<string, any> | string,
+    options?: Pick<EnvOptions, "options">,
+  ): Promise<Env> {
+    await init();
+
+    const env = await new Promise<Env>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.createEnvFromJSONSchema(schema);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (!result.envID) {
+          reject(new Error("Environment creation failed: no envID returned"));
+        } else {
+          resolve(new Env(result.envID));
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+
+    if (options?.options && options.options.length > 0) {
+      await env._extendWithOptions(options.options);
+    }
+
+    return env;
+  }
+
+  /**
+   * Create the environment of a preset baked into the WASM binary at build
+   * time, so its configuration isn't shipped and parsed from JavaScript at
+   * startup. The names of the presets are listed by `getCapabilities()`
+   * @param name - The name of the preset
+   * @param options - Optional session, default integers mode and environment
+   * options to add to the environment
+   * @returns Promise resolving to a new Env instance
+   * @throws Error if there is no such preset or its environment can't be
+   * created
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.fromPreset("acme-default");
+   * const program = await env.compile("user.age >= MIN_AGE");
+   * ```
+   */
+  static async fromPreset(
+    name: string,
+    options?: Pick<EnvOptions, "session" | "integers" | "options">,
+  ): Promise<Env> {
+    await init();
+
+    const session = options?.session;
+    if (session?.isDestroyed()) {
+      throw new Error("Session has been destroyed");
+    }
+
+    const env = await new Promise<Env>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.createEnvFromPreset(name, session?.getID());
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (!result.envID) {
+          reject(new Error("Environment creation failed: no envID returned"));
+        } else {
+          resolve(new Env(result.envID, session, options?.integers));
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+
+    if (options?.options && options.options.length > 0) {
+      await env._extendWithOptions(options.options);
+    }
+
+    return env;
+  }
+
+  /**
+   * Create the environment and programs of a policy bundle in one call. The
+   * checked expressions of the bundle must match its environment, as with
+   * `env.programFromCheckedExpr()`, and if any program fails nothing is
+   * created
+   * @param bundle - The bundle or signed bundle, as its JSON in bytes or a
+   * string, or parsed
+   * @param options - Optional session and default integers mode of the
+   * environment, and the public key verifying the bundle's signature or the
+   * explicit opt-out of verifying it
+   * @returns Promise resolving to the environment and its programs by name
+   * @throws Error if the bundle is invalid, its signature doesn't verify or
+   * any program fails
+   *
+   * @example
+   * ```typescript
+   * const response = await fetch("/policies/bundle.json");
+   * const { env, programs } = await Env.loadBundle(await response.text());
+   * await programs.adult.eval({ user: { age: 21 } }); // true
+   * ```
+   */
+  static async loadBundle(
+    bundle: Uint8Array | string | PolicyBundle | SignedPolicyBundle,
+    options?: LoadBundleOptions,
+  ): Promise<LoadedBundle> {
+    await init();
+
+    const session = options?.session;
+    if (session?.isDestroyed()) {
+      throw new Error("Session has been destroyed");
+    }
+
+    return new Promise<LoadedBundle>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.loadBundle(
+          bundle,
+          session?.getID(),
+          options?.publicKey,
+          options?.skipSignatureVerification === true,
+        );
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (!result.envID || !result.programs) {
+          reject(new Error("Bundle loading failed: no envID returned"));
+        } else {
+          const programs: Record<string, Program> = {};
+          for (const [name, programID] of Object.entries(result.programs)) {
+            programs[name] = new Program(
+              programID,
+              session,
+              undefined,
+              undefined,
+              undefined,
+              options?.integers,
+            );
+          }
+          resolve({
+            env: new Env(result.envID, session, options?.integers),
+            programs,
+          });
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Compile a CEL expression in this environment
+   * @param expr - The CEL expression to compile
+   * @param options - Optional compile options such as program options
+   * @returns Promise resolving to a compiled Program
+   * @throws Error if compilation fails or environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "x", type: "int" }]
+   * });
+   * const program = await env.compile("x + 10");
+   * const result = await program.eval({ x: 5 });
+   * console.log(result); // 15
+   * ```
+   */
+  async compile(expr: string, options?: CompileOptions): Promise<Program> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (typeof expr !== "string") {
+      throw new Error("Expression must be a string");
+    }
+
+    return new Promise<Program>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.compileExpr(
+          this.envID,
+          expr,
+          serializeProgramOptions(options),
+          {
+            optimize: options?.optimize === true,
+            optimizedSource: options?.optimizedSource === true,
+            memoize: options?.memoize,
+            fieldMask: options?.fieldMask === true,
+            sourceMap: options?.sourceMap === true,
+            expectedType: options?.expectedType,
+          },
+        );
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (!result.programID) {
+          reject(new Error("Compilation failed: no programID returned"));
+        } else {
+          resolve(
+            new Program(
+              result.programID,
+              this.session,
+              result.optimizedSource,
+              result.fieldMask,
+              result.sourceMap,
+              this.integers,
+            ),
+          );
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Compile an expression template, replacing each `{{name}}` placeholder with
+   * the binding of that name rendered as a CEL literal. Strings are quoted and
+   * escaped, and lists and objects become list and map literals, so bindings
+   * can't change the structure of the expression
+   * @param template - The CEL expression with `{{name}}` placeholders
+   * @param bindings - Values of the placeholders
+   * @param options - Optional compile options such as program options
+   * @returns Promise resolving to a compiled Program
+   * @throws Error if a placeholder has no binding, compilation fails or the
+   * environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const program = await env.instantiateTemplate(
+   *   "user.name == {{name}} && user.role in {{roles}}",
+   *   { name: input, roles: ["admin", "owner"] },
+   * );
+   * ```
+   */
+  async instantiateTemplate(
+    template: string,
+    bindings: Record<string, any>,
+    options?: CompileOptions,
+  ): Promise<Program> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (typeof template !== "string") {
+      throw new Error("Template must be a string");
+    }
+
+    return new Promise<Program>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.instantiateTemplate(
+          this.envID,
+          template,
+          bindings ?? {},
+          serializeProgramOptions(options),
+          {
+            optimize: options?.optimize === true,
+            optimizedSource: options?.optimizedSource === true,
+            memoize: options?.memoize,
+            fieldMask: options?.fieldMask === true,
+            sourceMap: options?.sourceMap === true,
+            expectedType: options?.expectedType,
+          },
+        );
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (!result.programID) {
+          reject(new Error("Compilation failed: no programID returned"));
+        } else {
+          resolve(
+            new Program(
+              result.programID,
+              this.session,
+              result.optimizedSource,
+              result.fieldMask,
+              result.sourceMap,
+              this.integers,
+            ),
+          );
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Create a program from a checked expression, such as one checked by another
+   * CEL runtime on a backend or exported with `program.exportCheckedExpr()`.
+   * The expression is checked again, and the identifiers and overloads it
+   * references must resolve the same way in this environment
+   * @param checkedExpr - The `google.api.expr.v1alpha1.CheckedExpr`, as bytes
+   * in the binary encoding or as a string in the JSON or text encoding
+   * @param format - Encoding of the checked expression, "binary" by default
+   * @param options - Optional compile options such as program options, and
+   * the public key verifying the signature of a signed checked expression
+   * @returns Promise resolving to a compiled Program
+   * @throws Error if the checked expression can't be decoded, its signature
+   * doesn't verify, it doesn't match the environment or the environment has
+   * been destroyed
+   *
+   * @example
+   * ```typescript
+   * const response = await fetch("/rules/1");
+   * const checked = new Uint8Array(await response.arrayBuffer());
+   * const program = await env.programFromCheckedExpr(checked);
+   * ```
+   */
+  async programFromCheckedExpr(
+    checkedExpr: Uint8Array | string | SignedCheckedExpr,
+    format?: CheckedExprFormat,
+    options?: ProgramFromCheckedExprOptions,
+  ): Promise<Program> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    return new Promise<Program>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.programFromCheckedExpr(
+          this.envID,
+          checkedExpr,
+          format,
+          serializeProgramOptions(options),
+          {
+            optimize: options?.optimize === true,
+            optimizedSource: options?.optimizedSource === true,
+            memoize: options?.memoize,
+            fieldMask: options?.fieldMask === true,
+            expectedType: options?.expectedType,
+          },
+          options?.publicKey,
+        );
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (!result.programID) {
+          reject(new Error("Compilation failed: no programID returned"));
+        } else {
+          resolve(
+            new Program(
+              result.programID,
+              this.session,
+              result.optimizedSource,
+              result.fieldMask,
+              result.sourceMap,
+              this.integers,
+            ),
+          );
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Compile a CEL expression with detailed results including warnings and issues
+   * @param expr - The CEL expression to compile
+   * @param options - Optional compile options such as program options
+   * @returns Promise resolving to detailed compilation results
+   * @throws Error if environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const result = await env.compileDetailed("x + y");
+   * if (result.success) {
+   *   console.log("Compiled successfully");
+   *   if (result.issues.length > 0) {
+   *     console.log("Warnings:", result.issues);
+   *   }
+   *   const evalResult = await result.program.eval({ x: 10, y: 20 });
+   * } else {
+   *   console.log("Compilation failed:", result.error);
+   *   console.log("All issues:", result.issues);
+   * }
+   * ```
+   */
+  async compileDetailed(
+    expr: string,
+    options?: CompileOptions,
+  ): Promise<import("./types.js").CompilationResult> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (typeof expr !== "string") {
+      throw new Error("Expression must be a string");
+    }
+
+    return new Promise<import("./types.js").CompilationResult>((resolve) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.compileExprDetailed(
+          this.envID,
+          expr,
+          serializeProgramOptions(options),
+          {
+            metrics: options?.metrics === true,
+            optimize: options?.optimize === true,
+            optimizedSource: options?.optimizedSource === true,
+            memoize: options?.memoize,
+            fieldMask: options?.fieldMask === true,
+            sourceMap: options?.sourceMap === true,
+            expectedType: options?.expectedType,
+          },
+        );
+
+        if (result.error && !result.programID) {
+          // Compilation failed completely
+          resolve({
+            success: false,
+            error: errorMessage(result.error),
+            issues: result.issues || [],
+            program: undefined,
+          });
+        } else if (result.programID) {
+          // Compilation succeeded (possibly with warnings)
+          const compilationResult: import("./types.js").CompilationResult = {
+            success: true,
+            error: undefined,
+            issues: result.issues || [],
+            program: new Program(
+              result.programID,
+              this.session,
+              result.optimizedSource,
+              result.fieldMask,
+              result.sourceMap,
+              this.integers,
+            ),
+          };
+          if (result.metrics !== undefined) {
+            compilationResult.metrics = result.metrics;
+          }
+          if (result.optimizedSource !== undefined) {
+            compilationResult.optimizedSource = result.optimizedSource;
+          }
+          if (result.fieldMask !== undefined) {
+            compilationResult.fieldMask = result.fieldMask;
+          }
+          if (result.sourceMap !== undefined) {
+            compilationResult.sourceMap = result.sourceMap;
+          }
+          resolve(compilationResult);
+        } else {
+          // Unexpected state
+          resolve({
+            success: false,
+            error: "Compilation failed: no programID returned",
+            issues: result.issues || [],
+            program: undefined,
+          });
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        resolve({
+          success: false,
+          error: `WASM call failed: ${error.message}`,
+          issues: [],
+          program: undefined,
+        });
+      }
+    });
+  }
+
+  /**
+   * Typecheck a CEL expression in this environment without compiling it
+   * @param expr - The CEL expression to typecheck
+   * @param options - Whether undeclared identifiers are checked as dyn
+   * @returns Promise resolving to the type information
+   * @throws Error if typechecking fails or environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "x", type: "int" }, { name: "y", type: "int" }]
+   * });
+   * const typeInfo = await env.typecheck("x + y");
+   * console.log(typeInfo.type); // "int"
+   *
+   * const listType = await env.typecheck("[1, 2, 3]");
+   * console.log(listType.type); // { kind: "list", elementType: "int" }
+   *
+   * const partial = await env.typecheck("x + z", { allowUndeclared: true });
+   * console.log(partial.undeclared); // ["z"]
+   * ```
+   */
+  async typecheck(
+    expr: string,
+    options?: TypecheckOptions,
+  ): Promise<TypeCheckResult> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (typeof expr !== "string") {
+      throw new Error("Expression must be a string");
+    }
+
+    return new Promise<TypeCheckResult>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.typecheckExpr(this.envID, expr, options);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (result.type === undefined) {
+          reject(new Error("Typecheck failed: no type returned"));
+        } else if (result.issues !== undefined) {
+          resolve({
+            type: result.type,
+            issues: result.issues,
+            undeclared: result.undeclared ?? [],
+          });
+        } else {
+          resolve({ type: result.type });
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Compute a hash that identifies an expression regardless of formatting
+   *
+   * The expression is checked in this environment and unparsed to a canonical
+   * source, so expressions differing only in whitespace, comments or redundant
+   * parentheses share a hash. Use it to deduplicate rules or key caches of
+   * evaluation results.
+   * @param expr - The CEL expression to hash
+   * @returns Promise resolving to the hash, the canonical source and its
+   * mapping to the expression
+   * @throws Error if the expression doesn't compile or environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const a = await env.canonicalHash("x+1");
+   * const b = await env.canonicalHash("(x + 1) // increment");
+   * console.log(a.hash === b.hash); // true
+   * console.log(a.canonical); // "x + 1"
+   * ```
+   */
+  async canonicalHash(expr: string): Promise<CanonicalHashResult> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (typeof expr !== "string") {
+      throw new Error("Expression must be a string");
+    }
+
+    return new Promise<CanonicalHashResult>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.canonicalHash(this.envID, expr);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (result.hash === undefined || result.canonical === undefined) {
+          reject(new Error("Canonical hash failed: no hash returned"));
+        } else {
+          resolve({
+            hash: result.hash,
+            canonical: result.canonical,
+            sourceMap: result.sourceMap ?? [],
+          });
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Compare two expressions structurally
+   *
+   * Both expressions are checked in this environment and their ASTs compared,
+   * so review tools can show what changed in a rule's meaning rather than in
+   * its text. Formatting, comments and redundant parentheses aren't changes.
+   * @param exprA - The expression before the change
+   * @param exprB - The expression after the change
+   * @returns Promise resolving to the subexpressions that differ, with their
+   * ranges in each expression
+   * @throws Error if either expression doesn't compile or environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const diff = await env.diffExprs("x > 1 && y", "x >= 1 && y");
+   * console.log(diff.changes[0].kind); // "changed"
+   * console.log(diff.changes[0].before?.text); // "x > 1"
+   * console.log(diff.changes[0].after?.text); // "x >= 1"
+   * ```
+   */
+  async diffExprs(exprA: string, exprB: string): Promise<ExprDiff> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (typeof exprA !== "string" || typeof exprB !== "string") {
+      throw new Error("Expressions must be strings");
+    }
+
+    return new Promise<ExprDiff>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.diffExprs(this.envID, exprA, exprB);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (result.changes === undefined) {
+          reject(new Error("Diff failed: no changes returned"));
+        } else {
+          resolve({ equal: result.equal === true, changes: result.changes });
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Generate the mutants of an expression, compiled as programs
+   *
+   * Mutants swap operators, move the boundaries of comparisons and drop the
+   * operands of `&&` and `||`, so rule test suites can measure whether their
+   * inputs tell the rule apart from near misses: a mutant that gives the same
+   * results as the rule on every input of a suite survives it, showing a part
+   * of the rule the suite doesn't cover.
+   * @param expr - The CEL expression to mutate
+   * @returns Promise resolving to the mutants that compile, and the number of
+   * those that don't
+   * @throws Error if the expression doesn't compile or environment has been
+   * destroyed
+   *
+   * @example
+   * ```typescript
+   * const rule = await env.compile("user.age >= 18");
+   * const inputs = [{ user: { age: 17 } }, { user: { age: 30 } }];
+   * const { mutants } = await env.mutate("user.age >= 18");
+   * for (const mutant of mutants) {
+   *   let killed = false;
+   *   for (const vars of inputs) {
+   *     if ((await mutant.program.eval(vars)) !== (await rule.eval(vars))) {
+   *       killed = true;
+   *     }
+   *   }
+   *   if (!killed) console.log(`survived: ${mutant.expr}`); // user.age > 18
+   *   mutant.program.destroy();
+   * }
+   * ```
+   */
+  async mutate(expr: string): Promise<MutationResult> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (typeof expr !== "string") {
+      throw new Error("Expression must be a string");
+    }
+
+    return new Promise<MutationResult>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.mutate(this.envID, expr);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (result.mutants === undefined) {
+          reject(new Error("Mutation failed: no mutants returned"));
+        } else {
+          resolve({
+            mutants: result.mutants.map(({ programID, ...mutant }) => ({
+              ...mutant,
+              program: new Program(
+                programID,
+                this.session,
+                undefined,
+                undefined,
+                undefined,
+                this.integers,
+              ),
+            })),
+            skipped: result.skipped ?? 0,
+          });
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Describe what this environment declares
+   *
+   * Lists the variables and constants with their types, the functions with the
+   * signature of each overload, the options the environment was created and
+   * extended with, its libraries and its container, so editor tooling can
+   * render what's available without tracking it separately.
+   * @returns Promise resolving to the declarations and configuration
+   * @throws Error if environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const { variables, functions } = await env.describe();
+   * console.log(variables); // [{ name: "x", type: "int" }]
+   * const size = functions.find((f) => f.name === "size");
+   * console.log(size?.overloads.map((o) => o.signature));
+   * ```
+   */
+  async describe(): Promise<EnvDescription> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    return new Promise<EnvDescription>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.describeEnv(this.envID);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (
+          result.variables === undefined ||
+          result.functions === undefined
+        ) {
+          reject(new Error("Describe failed: no declarations returned"));
+        } else {
+          resolve({
+            variables: result.variables,
+            functions: result.functions,
+            options: result.options ?? [],
+            libraries: result.libraries ?? [],
+            container: result.container ?? "",
+          });
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * List the functions and macros this environment offers, with their
+   * documentation
+   *
+   * Functions of the standard library and extension libraries carry the
+   * descriptions and examples cel-go documents them with, for autocomplete
+   * popups and help panels. Operators are left out.
+   * @param options - `query` keeps the functions whose name or description
+   * contains it, ignoring case, with name matches first
+   * @returns Promise resolving to the matching functions and macros
+   * @throws Error if environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const [help] = await env.listFunctions({ query: "startsWith" });
+   * console.log(help.signatures); // ["string.startsWith(string) -> bool"]
+   * console.log(help.description);
+   * ```
+   */
+  async listFunctions(options?: ListFunctionsOptions): Promise<FunctionHelp[]> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    return new Promise<FunctionHelp[]>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.listFunctions(this.envID, options);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (result.functions === undefined) {
+          reject(new Error("Listing functions failed: no functions returned"));
+        } else {
+          resolve(result.functions);
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Generate plausible values of the variables this environment declares,
+   * matching their types
+   *
+   * Playgrounds can prefill their inputs with them, and rules can be fuzzed
+   * with inputs of the right shape by varying the seed. Timestamps, durations
+   * and bytes are generated as RFC 3339, Go duration and base64 strings, the
+   * forms variable defaults take. Constants are left out.
+   * @param options - `seed` of the generated values, 0 by default
+   * @returns Promise resolving to the values by variable name
+   * @throws Error if environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "tags", type: "list<string>" }],
+   * });
+   * const vars = await env.generateSampleVars({ seed: 42 });
+   * // { tags: ["delta", "echo", "golf"] }
+   * ```
+   */
+  async generateSampleVars(
+    options?: SampleVarsOptions,
+  ): Promise<Record<string, any>> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    return new Promise<Record<string, any>>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.generateSampleVars(this.envID, options);
+
+        if (result.error) {
+          reject(toError(result));
+        } else if (result.vars === undefined) {
+          reject(new Error("Generating samples failed: no values returned"));
+        } else {
+          resolve(result.vars);
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Extend this environment with additional CEL environment options
+   * @param options - Array of CEL environment option configurations or complex options with setup
+   * @returns Promise that resolves when the environment has been extended
+   * @throws Error if extension fails or environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "x", type: "int" }]
+   * });
+   *
+   * // Add options after creation
+   * await env.extend([Options.optionalTypes()]);
+   * ```
+   */
+  async extend(
+    options: import("./options/index.js").EnvOptionInput[],
+  ): Promise<void> {
+    return this._extendWithOptions(options);
+  }
+
+  /**
+   * Compile the programs of this environment again, against the declarations
+   * it has now. Use it after `extend()` so long-lived programs pick up new
+   * options such as AST validators, without tracking every program. Each
+   * program keeps its ID; one that no longer compiles keeps its previous
+   * compilation and is reported in `issues`. Programs of policies and
+   * programs created from checked expressions aren't compiled again
+   * @returns Promise resolving to the programs compiled again and the issues
+   * @throws Error if environment has been destroyed
+   *
+   * @example
+   * ```typescript
+   * await env.extend([Options.astValidators({ validators })]);
+   * const { issues } = await env.recompilePrograms();
+   * for (const issue of issues) {
+   *   console.warn(issue.programID, issue.error);
+   * }
+   * ```
+   */
+  async recompilePrograms(): Promise<RecompileResult> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.recompilePrograms(this.envID);
+    if (result.error) {
+      throw toError(result);
+    }
+
+    return {
+      recompiled: result.recompiled ?? [],
+      issues: result.issues ?? [],
+    };
+  }
+
+  /**
+   * Internal method to extend environment with options
+   * This method delegates to options that implement OptionWithSetup for complex operations
+   * @private
+   */
+  private async _extendWithOptions(
+    options: import("./options/index.js").EnvOptionInput[],
+  ): Promise<void> {
+    if (this.isReleased()) {
+      throw new Error("Environment has been destroyed");
+    }
+
+    await init();
+
+    if (!options || options.length === 0) {
+      return; // Nothing to extend
+    }
+
+    // Process options: delegate to options that can handle their own setup
+    const processedOptions: import("./options/index.js").EnvOptionConfig[] = [];
+
+    for (const option of options) {
       // Check if this option implements OptionWithSetup
       if (
         "setupAndProcess" in option &&
@@ -594,74 +2622,458 @@ export class Env {
             name: string,
             impl: (...args: any[]) => any,
           ): Promise<string> => {
-            if (this.destroyed) {
+            if (this.isReleased()) {
               throw new Error("Environment has been destroyed");
             }
 
-            // Generate a unique implementation ID for this function
-            const implID = `${name}_${this.envID}_${Date.now()}_${Math.random().toString(36).substring(2, 9)}`;
+            // Generate a unique implementation ID for this function
+            const implID = `${name}_${this.envID}_${Date.now()}_${Math.random().toString(36).substring(2, 9)}`;
+
+            // Register the JavaScript function implementation
+            const globalObj =
+              typeof globalThis !== "undefined" ? globalThis : global;
+            if (typeof globalObj.registerCELFunction === "function") {
+              const registerResult = globalObj.registerCELFunction(
+                implID,
+                impl,
+              );
+              if (registerResult.error) {
+                throw new Error(
+                  `Failed to register function ${name}: ${errorMessage(registerResult.error)}`,
+                );
+              }
+            } else {
+              throw new Error(
+                "registerCELFunction not available. Make sure WASM is initialized.",
+              );
+            }
+
+            // Return the actual implementation ID that was registered
+            return implID;
+          },
+        };
+        const processedOption = await option.setupAndProcess(setupEnv);
+        processedOptions.push(processedOption);
+      } else {
+        // Simple option configuration, pass through directly
+        processedOptions.push(
+          option as import("./options/index.js").EnvOptionConfig,
+        );
+      }
+    }
+
+    const serializedOptions = JSON.stringify(processedOptions);
+
+    return new Promise<void>((resolve, reject) => {
+      try {
+        const globalObj =
+          typeof globalThis !== "undefined" ? globalThis : global;
+        const result = globalObj.extendEnv(this.envID, serializedOptions);
+
+        if (result.error) {
+          reject(toError(result));
+        } else {
+          resolve();
+        }
+      } catch (err) {
+        const error = err instanceof Error ? err : new Error(String(err));
+        reject(new Error(`WASM call failed: ${error.message}`));
+      }
+    });
+  }
+
+  /**
+   * Whether the WASM resources behind this environment are gone, either through
+   * destroy(), its session or shutdown()
+   */
+  private isReleased(): boolean {
+    return (
+      this.destroyed ||
+      this.generation !== instanceGeneration ||
+      this.session?.isDestroyed() === true
+    );
+  }
+
+  /**
+   * Destroy this environment and free associated WASM resources.
+   * This will also clean up any registered JavaScript functions associated
+   * with this environment.
+   * After calling destroy(), this environment instance should not be used.
+   * If FinalizationRegistry is available, resources will be automatically
+   * cleaned up when the object is garbage collected, but explicit cleanup
+   * is recommended.
+   */
+  destroy(): void {
+    if (this.destroyed) {
+      return; // Already destroyed, no-op
+    }
+
+    try {
+      const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+      // Already freed along with a destroyed session or by shutdown()
+      if (
+        !this.isReleased() &&
+        typeof globalObj.destroyEnv === "function"
+      ) {
+        const result = globalObj.destroyEnv(this.envID);
+        if (result.error) {
+          // Log but don't throw - cleanup should be best-effort
+          console.warn(
+            `Failed to destroy environment: ${errorMessage(result.error)}`,
+          );
+        }
+      }
+    } catch (err) {
+      // Log but don't throw - cleanup should be best-effort
+      console.warn(`Error destroying environment: ${err}`);
+    } finally {
+      this.destroyed = true;
+      // Unregister from FinalizationRegistry since we've explicitly cleaned up
+      if (envRegistry) {
+        envRegistry.unregister(this);
+      }
+    }
+  }
+}
+
+/**
+ * A group of environments, and the programs compiled from them, that are
+ * destroyed together. Useful for tearing down everything created for a page
+ * or route at once instead of destroying each handle.
+ *
+ * @example
+ * ```typescript
+ * const session = await Session.new();
+ * const env = await Env.new({
+ *   variables: [{ name: "x", type: "double" }],
+ *   session,
+ * });
+ * const program = await env.compile("x * 2.0");
+ * await program.eval({ x: 21 }); // 42
+ *
+ * // Destroys env, program and the functions they registered
+ * session.destroy();
+ * ```
+ */
+export class Session {
+  private sessionID: string;
+  private destroyed: boolean = false;
+  private generation: number = instanceGeneration;
+
+  private constructor(sessionID: string) {
+    this.sessionID = sessionID;
+  }
+
+  /**
+   * Create a new session
+   * @returns Promise resolving to a new Session instance
+   * @throws Error if session creation fails
+   */
+  static async new(): Promise<Session> {
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.createSession();
+    if (result.error) {
+      throw toError(result);
+    }
+    if (!result.sessionID) {
+      throw new Error("Session creation failed: no sessionID returned");
+    }
+
+    return new Session(result.sessionID);
+  }
+
+  /**
+   * Get the session ID (useful for debugging or advanced use cases)
+   */
+  getID(): string {
+    return this.sessionID;
+  }
+
+  /**
+   * Whether the session has been destroyed, explicitly or by shutdown()
+   */
+  isDestroyed(): boolean {
+    return this.destroyed || this.generation !== instanceGeneration;
+  }
+
+  /**
+   * Destroy every environment and program created within this session and
+   * unregister their custom functions. Env and Program instances from the
+   * session can't be used afterwards; calling their destroy() is a no-op.
+   */
+  destroy(): void {
+    if (this.destroyed) {
+      return; // Already destroyed, no-op
+    }
+
+    try {
+      const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+      // Sessions of a previous module instance were freed by shutdown()
+      if (
+        this.generation === instanceGeneration &&
+        typeof globalObj.destroySession === "function"
+      ) {
+        const result = globalObj.destroySession(this.sessionID);
+        if (result.error) {
+          // Log but don't throw - cleanup should be best-effort
+          console.warn(
+            `Failed to destroy session: ${errorMessage(result.error)}`,
+          );
+        }
+      }
+    } catch (err) {
+      // Log but don't throw - cleanup should be best-effort
+      console.warn(`Error destroying session: ${err}`);
+    } finally {
+      this.destroyed = true;
+    }
+  }
+}
+
+/**
+ * A set of boolean rules compiled in an environment and evaluated against the
+ * same variables in a single call, reporting which rules matched
+ *
+ * @example
+ * ```typescript
+ * const rules = await RuleSet.new(env, [
+ *   { id: "blocked", expr: "request.ip in blocklist", mode: "stop", priority: 10 },
+ *   { id: "admin", expr: 'request.role == "admin"' },
+ * ]);
+ * const { matched } = await rules.eval({ request, blocklist });
+ * ```
+ */
+export class RuleSet {
+  private ruleSetID: string;
+  private destroyed: boolean = false;
+  private generation: number = instanceGeneration;
 
-            // Register the JavaScript function implementation
-            const globalObj =
-              typeof globalThis !== "undefined" ? globalThis : global;
-            if (typeof globalObj.registerCELFunction === "function") {
-              const registerResult = globalObj.registerCELFunction(
-                implID,
-                impl,
-              );
-              if (registerResult.error) {
-                throw new Error(
-                  `Failed to register function ${name}: ${registerResult.error}`,
-                );
-              }
-            } else {
-              throw new Error(
-                "registerCELFunction not available. Make sure WASM is initialized.",
-              );
-            }
+  private constructor(ruleSetID: string) {
+    this.ruleSetID = ruleSetID;
+  }
 
-            // Return the actual implementation ID that was registered
-            return implID;
-          },
-        };
-        const processedOption = await option.setupAndProcess(setupEnv);
-        processedOptions.push(processedOption);
-      } else {
-        // Simple option configuration, pass through directly
-        processedOptions.push(
-          option as import("./options/index.js").EnvOptionConfig,
-        );
-      }
+  /**
+   * Compile a rule set
+   * @param env - The environment to compile the rules in
+   * @param rules - The rules, each of which must evaluate to a bool
+   * @returns Promise resolving to a new RuleSet instance
+   * @throws Error if a rule is invalid or fails to compile
+   */
+  static async new(env: Env, rules: Rule[]): Promise<RuleSet> {
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.createRuleSet(env.getID(), rules);
+    if (result.error) {
+      throw toError(result);
+    }
+    if (!result.ruleSetID) {
+      throw new Error("Rule set creation failed: no ruleSetID returned");
     }
 
-    const serializedOptions = JSON.stringify(processedOptions);
+    return new RuleSet(result.ruleSetID);
+  }
 
-    return new Promise<void>((resolve, reject) => {
-      try {
-        const globalObj =
-          typeof globalThis !== "undefined" ? globalThis : global;
-        const result = globalObj.extendEnv(this.envID, serializedOptions);
+  /**
+   * Get the rule set ID (useful for debugging or advanced use cases)
+   */
+  getID(): string {
+    return this.ruleSetID;
+  }
+
+  /**
+   * Whether the rule set has been destroyed, explicitly or by shutdown()
+   */
+  isDestroyed(): boolean {
+    return this.destroyed || this.generation !== instanceGeneration;
+  }
+
+  /**
+   * Evaluate the rules in order against the given variables. All rules are
+   * evaluated unless a matching rule has mode "stop" or `stopOnFirstMatch` is set
+   * @param vars - Variables to use in the evaluation
+   * @param options - Optional evaluation options
+   * @returns Promise resolving to the matched rule ids and the rules that failed
+   * @throws Error if the rule set has been destroyed or the call fails
+   */
+  async eval(
+    vars: Record<string, any> | null = null,
+    options?: RuleSetEvalOptions,
+  ): Promise<RuleSetResult> {
+    if (this.isDestroyed()) {
+      throw new Error("Rule set has been destroyed");
+    }
+
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.evalRuleSet(this.ruleSetID, vars || {}, {
+      stopOnFirstMatch: options?.stopOnFirstMatch === true,
+    });
+    if (result.error) {
+      throw toError(result);
+    }
+
+    return {
+      matched: result.matched ?? [],
+      errors: result.errors ?? [],
+      evaluated: result.evaluated ?? 0,
+    };
+  }
+
+  /**
+   * Destroy the rule set and the programs compiled from its rules
+   */
+  destroy(): void {
+    if (this.destroyed) {
+      return; // Already destroyed, no-op
+    }
 
+    try {
+      const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+      // Rule sets of a previous module instance were freed by shutdown()
+      if (
+        this.generation === instanceGeneration &&
+        typeof globalObj.destroyRuleSet === "function"
+      ) {
+        const result = globalObj.destroyRuleSet(this.ruleSetID);
         if (result.error) {
-          reject(new Error(result.error));
-        } else {
-          resolve();
+          // Log but don't throw - cleanup should be best-effort
+          console.warn(
+            `Failed to destroy rule set: ${errorMessage(result.error)}`,
+          );
         }
-      } catch (err) {
-        const error = err instanceof Error ? err : new Error(String(err));
-        reject(new Error(`WASM call failed: ${error.message}`));
       }
+    } catch (err) {
+      // Log but don't throw - cleanup should be best-effort
+      console.warn(`Error destroying rule set: ${err}`);
+    } finally {
+      this.destroyed = true;
+    }
+  }
+}
+
+/**
+ * A CEL policy in the YAML format of cel.dev/policy, compiled in an
+ * environment. Rule variables are available to later expressions as
+ * `variables.<name>`, and the output of the first match whose condition holds
+ * is the result of the policy
+ *
+ * @example
+ * ```typescript
+ * const policy = await Policy.new(
+ *   env,
+ *   `
+ * name: admission
+ * rule:
+ *   variables:
+ *     - name: is_admin
+ *       expression: request.role == "admin"
+ *   match:
+ *     - condition: variables.is_admin
+ *       output: "true"
+ *     - output: "false"
+ *       explanation: "'only admins are allowed'"
+ * `,
+ * );
+ * const { result, explanation } = await policy.eval({ request });
+ * ```
+ */
+export class Policy {
+  private policyID: string;
+  private name: string;
+  private destroyed: boolean = false;
+  private generation: number = instanceGeneration;
+
+  private constructor(policyID: string, name: string) {
+    this.policyID = policyID;
+    this.name = name;
+  }
+
+  /**
+   * Compile a policy
+   * @param env - The environment to compile the policy's expressions in
+   * @param source - The policy document, in YAML
+   * @returns Promise resolving to a new Policy instance
+   * @throws Error if the document is invalid or an expression fails to compile
+   */
+  static async new(env: Env, source: string): Promise<Policy> {
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.compilePolicy(env.getID(), source);
+    if (result.error) {
+      throw toError(result);
+    }
+    if (!result.policyID) {
+      throw new Error("Policy compilation failed: no policyID returned");
+    }
+
+    return new Policy(result.policyID, result.name ?? "");
+  }
+
+  /**
+   * Get the policy ID (useful for debugging or advanced use cases)
+   */
+  getID(): string {
+    return this.policyID;
+  }
+
+  /**
+   * Get the name declared by the policy document, empty if it has none
+   */
+  getName(): string {
+    return this.name;
+  }
+
+  /**
+   * Whether the policy has been destroyed, explicitly or by shutdown()
+   */
+  isDestroyed(): boolean {
+    return this.destroyed || this.generation !== instanceGeneration;
+  }
+
+  /**
+   * Evaluate the policy against the given variables
+   * @param vars - Variables to use in the evaluation
+   * @param options - Optional evaluation options such as `mapKeys` and `nonFinite`
+   * @returns Promise resolving to the output of the first match that applies
+   * @throws Error if an expression fails to evaluate or the policy has been
+   * destroyed
+   */
+  async eval(
+    vars: Record<string, any> | null = null,
+    options?: Pick<EvalOptions, "mapKeys" | "nonFinite">,
+  ): Promise<PolicyResult> {
+    if (this.isDestroyed()) {
+      throw new Error("Policy has been destroyed");
+    }
+
+    await init();
+
+    const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+    const result = globalObj.evalPolicy(this.policyID, vars || {}, {
+      mapKeys: options?.mapKeys,
+      nonFinite: options?.nonFinite,
     });
+    if (result.error) {
+      throw toError(result);
+    }
+
+    const policyResult: PolicyResult = {
+      matched: result.matched === true,
+      result: result.result ?? null,
+    };
+    if (result.explanation !== undefined) {
+      policyResult.explanation = result.explanation;
+    }
+    return policyResult;
   }
 
   /**
-   * Destroy this environment and free associated WASM resources.
-   * This will also clean up any registered JavaScript functions associated
-   * with this environment.
-   * After calling destroy(), this environment instance should not be used.
-   * If FinalizationRegistry is available, resources will be automatically
-   * cleaned up when the object is garbage collected, but explicit cleanup
-   * is recommended.
+   * Destroy the policy and the programs compiled from its expressions
    */
   destroy(): void {
     if (this.destroyed) {
@@ -670,49 +3082,509 @@ export class Env {
 
     try {
       const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
-      if (typeof globalObj.destroyEnv === "function") {
-        const result = globalObj.destroyEnv(this.envID);
+      // Policies of a previous module instance were freed by shutdown()
+      if (
+        this.generation === instanceGeneration &&
+        typeof globalObj.destroyPolicy === "function"
+      ) {
+        const result = globalObj.destroyPolicy(this.policyID);
         if (result.error) {
           // Log but don't throw - cleanup should be best-effort
-          console.warn(`Failed to destroy environment: ${result.error}`);
+          console.warn(
+            `Failed to destroy policy: ${errorMessage(result.error)}`,
+          );
         }
       }
     } catch (err) {
       // Log but don't throw - cleanup should be best-effort
-      console.warn(`Error destroying environment: ${err}`);
+      console.warn(`Error destroying policy: ${err}`);
     } finally {
       this.destroyed = true;
-      // Unregister from FinalizationRegistry since we've explicitly cleaned up
-      if (envRegistry) {
-        envRegistry.unregister(this);
+    }
+  }
+}
+
+/**
+ * Configure the WASM module
+ *
+ * Setting `programTTLms` or `envTTLms` enables automatic cleanup of handles
+ * that haven't been used for that long, so programs and environments leaked by
+ * code that never reached `destroy()` don't accumulate. A periodic sweeper
+ * runs while a TTL is set; it doesn't keep Node.js processes alive.
+ * Fields that are omitted keep their current value.
+ *
+ * @param config - The configuration to apply
+ * @returns Promise resolving to the complete configuration
+ * @throws Error if the configuration is invalid
+ *
+ * @example
+ * ```typescript
+ * await configure({ programTTLms: 60_000, envTTLms: 300_000 });
+ * ```
+ */
+export async function configure(
+  config: RuntimeConfig,
+): Promise<Required<RuntimeConfig>> {
+  await init();
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const result = globalObj.configure(JSON.stringify(config));
+
+  if (result.error) {
+    throw toError(result);
+  }
+
+  if (sweepTimer !== null) {
+    clearInterval(sweepTimer);
+    sweepTimer = null;
+  }
+  if (result.sweepIntervalms) {
+    sweepTimer = setInterval(() => {
+      const sweepResult = globalObj.sweep();
+      if (sweepResult.error) {
+        // Log but don't throw - cleanup should be best-effort
+        console.warn(
+          `Failed to sweep idle handles: ${errorMessage(sweepResult.error)}`,
+        );
       }
+    }, result.sweepIntervalms);
+    // Don't keep the process alive just to clean up
+    if (typeof sweepTimer === "object" && "unref" in sweepTimer) {
+      sweepTimer.unref();
+    }
+  }
+
+  return {
+    programTTLms: result.config?.programTTLms ?? 0,
+    envTTLms: result.config?.envTTLms ?? 0,
+    evalWorkers: result.config?.evalWorkers ?? 4,
+    evalMemoryLimitBytes: result.config?.evalMemoryLimitBytes ?? 0,
+  };
+}
+
+let loggerCounter = 0;
+
+/**
+ * Route internal warnings of the WASM module, such as ignored option params
+ * and unknown type names, to a callback. Without a logger they are dropped.
+ *
+ * @param logger - Called with each entry at or above `level`, or null to remove the logger
+ * @param level - Minimum level of the entries passed to the logger, defaults to "warn"
+ * @throws Error if the level is invalid
+ *
+ * @example
+ * ```typescript
+ * await setLogger((entry) => console.warn(entry.message, entry.fields));
+ * ```
+ */
+export async function setLogger(
+  logger: ((entry: LogEntry) => void) | null,
+  level: LogLevel = "warn",
+): Promise<void> {
+  await init();
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+
+  const implID = logger ? `logger_${++loggerCounter}` : null;
+  const result = globalObj.setLogger(implID, level);
+  if (result.error) {
+    throw toError(result);
+  }
+
+  // Registered only once the level is accepted, so a rejected logger isn't kept
+  if (implID && logger) {
+    const registerResult = globalObj.registerCELFunction(implID, logger);
+    if (registerResult.error) {
+      throw new Error(
+        `Failed to register logger: ${errorMessage(registerResult.error)}`,
+      );
+    }
+  }
+}
+
+let lifecycleListenerCounter = 0;
+
+/**
+ * Send the lifecycle events of the WASM module to a callback: environments
+ * created and destroyed, programs compiled and destroyed, function
+ * implementations unregistered and cache entries evicted. Useful for debugging
+ * dashboards and leak detectors.
+ *
+ * @param listener - Called with each event, or null to remove the listener
+ *
+ * @example
+ * ```typescript
+ * const live = new Set<string>();
+ * await setLifecycleListener((event) => {
+ *   if (event.type === "programCompiled") live.add(event.programID);
+ *   if (event.type === "programDestroyed") live.delete(event.programID);
+ * });
+ * ```
+ */
+export async function setLifecycleListener(
+  listener: ((event: LifecycleEvent) => void) | null,
+): Promise<void> {
+  await init();
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+
+  // Registered before the module knows about it, so no event finds it missing
+  const implID = listener ? `lifecycle_${++lifecycleListenerCounter}` : null;
+  if (implID && listener) {
+    const registerResult = globalObj.registerCELFunction(implID, listener);
+    if (registerResult.error) {
+      throw new Error(
+        `Failed to register lifecycle listener: ${errorMessage(registerResult.error)}`,
+      );
+    }
+  }
+
+  const result = globalObj.setLifecycleListener(implID);
+  if (result.error) {
+    throw toError(result);
+  }
+}
+
+/**
+ * Define a custom function that is included in every environment created
+ * afterwards, so it doesn't have to be passed to each of them. Environments
+ * that already exist are unaffected, and shutdown() clears the definitions.
+ *
+ * @param fn - The function definition, as passed to Env.new
+ * @throws Error if the definition is invalid or conflicts with another global function
+ *
+ * @example
+ * ```typescript
+ * await defineGlobalFunction(
+ *   CELFunction.new("double").param("x", "double").returns("double").implement((x) => x * 2),
+ * );
+ * const env = await Env.new({ variables: [{ name: "x", type: "double" }] });
+ * const program = await env.compile("double(x)");
+ * ```
+ */
+export async function defineGlobalFunction(
+  fn: CELFunctionDefinition,
+): Promise<void> {
+  await init();
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const [funcDef] = serializeFunctionDefs([fn]);
+  const result = globalObj.defineGlobalFunction(funcDef);
+  if (result.error) {
+    throw toError(result);
+  }
+}
+
+/**
+ * Register a library of variables, functions and options that environments
+ * created afterwards can include by name with `EnvOptions.libraries`, so
+ * shared function packs are declared once. Libraries can't be redefined, and
+ * shutdown() removes them.
+ *
+ * @param name - The library's name, such as "acme.strings"
+ * @param library - The declarations the library adds to an environment
+ * @throws Error if the name is taken or the declarations are invalid
+ *
+ * @example
+ * ```typescript
+ * await registerLibrary("acme.strings", {
+ *   functions: [
+ *     CELFunction.new("shout").param("s", "string").returns("string").implement((s) => s.toUpperCase()),
+ *   ],
+ * });
+ * const env = await Env.new({ libraries: ["acme.strings"] });
+ * ```
+ */
+export async function registerLibrary(
+  name: string,
+  library: LibraryDefinition,
+): Promise<void> {
+  await init();
+
+  for (const option of library.options || []) {
+    if ("setupAndProcess" in option) {
+      throw new Error(
+        `Library ${name} can't include options that need setup in JavaScript`,
+      );
     }
   }
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const result = globalObj.registerLibrary(name, {
+    varDecls: (library.variables || []).map((v) => ({
+      name: v.name,
+      type: serializeTypeDef(v.type),
+    })),
+    funcDefs: serializeFunctionDefs(library.functions || []),
+    options: library.options,
+  });
+  if (result.error) {
+    throw toError(result);
+  }
+}
+
+/**
+ * Compile and evaluate an expression in a single call, for playgrounds and
+ * quick scripts. Each variable is declared with the type inferred from its
+ * value: whole numbers are ints, other numbers doubles, and arrays and objects
+ * lists and maps of the type their elements share, or `dyn` when they differ.
+ * The environment and program only live for the call.
+ *
+ * Compilation and evaluation failures don't reject: the result reports them
+ * along with the issues and the type found before.
+ *
+ * @param source - The CEL expression
+ * @param vars - The variables, whose values declare their types
+ * @param options - Explicit variable declarations, custom functions,
+ * libraries and environment options
+ * @returns Promise resolving to the issues, type and result of the expression
+ * @throws Error if the options are invalid
+ *
+ * @example
+ * ```typescript
+ * const { result, type } = await evaluate("items.size() > limit", {
+ *   items: [1, 2, 3],
+ *   limit: 2,
+ * });
+ * console.log(result, type); // true "bool"
+ * ```
+ */
+export async function evaluate(
+  source: string,
+  vars: Record<string, any> | null = null,
+  options?: EvaluateOptions,
+): Promise<EvaluateResult> {
+  await init();
+
+  if (typeof source !== "string") {
+    throw new Error("Expression must be a string");
+  }
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const result = globalObj.evaluate(source, vars, {
+    variables: options?.variables?.map((v) => ({
+      name: v.name,
+      type: serializeTypeDef(v.type),
+    })),
+    functions: serializeFunctionDefs(options?.functions || []),
+    libraries: options?.libraries,
+    options: options?.options,
+  });
+
+  if (result.issues === undefined) {
+    throw result.error
+      ? toError(result)
+      : new Error("Evaluation failed: no issues returned");
+  }
+  if (result.error) {
+    return {
+      success: false,
+      type: result.type,
+      issues: result.issues,
+      error: errorMessage(result.error),
+    };
+  }
+  return {
+    success: true,
+    result: result.result,
+    type: result.type,
+    issues: result.issues,
+  };
+}
+
+/**
+ * Get version and feature information about the WASM module
+ * @returns Promise resolving to the module capabilities
+ * @throws Error if the capabilities could not be retrieved
+ *
+ * @example
+ * ```typescript
+ * const caps = await getCapabilities();
+ * if (caps.jsonOptions.includes("OptionalTypes")) {
+ *   // Safe to use Options.optionalTypes()
+ * }
+ * ```
+ */
+export async function getCapabilities(): Promise<Capabilities> {
+  await init();
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const result = globalObj.getCapabilities();
+
+  if (result.error) {
+    throw toError(result);
+  }
+
+  return {
+    version: result.version ?? "unknown",
+    celGoVersion: result.celGoVersion ?? "unknown",
+    options: result.options ?? [],
+    jsonOptions: result.jsonOptions ?? [],
+    programOptions: result.programOptions ?? [],
+    typeKinds: result.typeKinds ?? [],
+    wireFormats: result.wireFormats ?? [],
+    presets: result.presets ?? [],
+  };
+}
+
+/**
+ * Describe all environment options that can be configured from JavaScript
+ * @returns Promise resolving to option metadata including a JSON Schema for each option's params
+ * @throws Error if the option metadata could not be retrieved
+ *
+ * @example
+ * ```typescript
+ * const options = await describeOptions();
+ * for (const option of options) {
+ *   console.log(option.name, option.params.map((p) => p.name));
+ * }
+ * ```
+ */
+export async function describeOptions(): Promise<OptionDescription[]> {
+  await init();
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const result = globalObj.describeOptions();
+
+  if (result.error) {
+    throw toError(result);
+  }
+
+  return (result.options ?? []) as OptionDescription[];
+}
+
+/**
+ * Describe all program options that can be configured from JavaScript
+ * @returns Promise resolving to program option metadata including a JSON Schema for each option's params
+ * @throws Error if the option metadata could not be retrieved
+ */
+export async function describeProgramOptions(): Promise<OptionDescription[]> {
+  await init();
+
+  const globalObj = typeof globalThis !== "undefined" ? globalThis : global;
+  const result = globalObj.describeOptions();
+
+  if (result.error) {
+    throw toError(result);
+  }
+
+  return (result.programOptions ?? []) as OptionDescription[];
 }
 
 // Re-export types and functions
 export type {
+  Capabilities,
+  RuntimeConfig,
+  WasmErrorInfo,
+  LogEntry,
+  LogLevel,
+  LifecycleEvent,
+  OptionDescription,
+  OptionParamDescription,
   CELType,
   CELTypeDef,
+  CELTypeExpression,
   CELListType,
   CELMapType,
+  CELObjectType,
   CELFunctionDefinition,
   CELFunctionParam,
   EnvOptions,
+  AbsentVariablesPolicy,
   VariableDeclaration,
+  ConstantDeclaration,
+  LibraryDefinition,
   TypeCheckResult,
+  TypecheckOptions,
+  CanonicalHashResult,
+  SourceMapping,
+  SourceRange,
+  ExprDiff,
+  ExprChange,
+  ExprExcerpt,
+  MutantKind,
+  Mutant,
+  MutationResult,
+  EnvDescription,
+  DeclaredVariable,
+  DeclaredFunction,
+  DeclaredOverload,
+  FunctionHelp,
+  ListFunctionsOptions,
+  SampleVarsOptions,
+  EvaluateOptions,
+  EvaluateResult,
+  CheckedExprFormat,
+  PolicyBundle,
+  PolicyBundleProgram,
+  SignedPolicyBundle,
+  SignedCheckedExpr,
+  ProgramFromCheckedExprOptions,
+  LoadBundleOptions,
+  LoadedBundle,
+  RecompileResult,
+  RecompileIssue,
   CompilationIssue,
   CompilationResult,
+  CompileOptions,
+  CompileMetrics,
+  CompileFlags,
+  EvalOptions,
+  ConcurrentEvalOptions,
+  EvalCallContext,
+  MemoryBudget,
+  EvalMetrics,
+  EvalResult,
+  RepeatedEvalResult,
+  EvalStats,
+  ExplainOptions,
+  Explanation,
+  ExplanationNode,
+  ExplanationOutcome,
+  TestCase,
+  TestCaseResult,
+  TestDiff,
+  TestRunResult,
+  MapKeysMode,
+  MapEntries,
+  NonFiniteMode,
+  IntegerMode,
+  TaggedDouble,
+  TaggedDecimal,
+  ErrorValue,
+  FunctionCall,
+  VariableError,
+  Rule,
+  RuleSetEvalOptions,
+  RuleSetResult,
+  PolicyResult,
 } from "./types.js";
 
 export { listType, mapType, CELFunction } from "./functions.js";
-export { Options } from "./options/index.js";
+export { Options, ProgramOptions } from "./options/index.js";
 export type {
   EnvOptionConfig,
+  SubOptionConfig,
   OptionalTypesConfig,
   ValidationIssue,
   ValidationContext,
   ValidatorResult,
   ASTValidatorFunction,
   ASTValidatorsConfig,
+  CrossTypeNumericComparisonsConfig,
+  DefaultUTCTimeZoneConfig,
+  EagerlyValidateDeclarationsConfig,
+  DeclareContextProtoConfig,
+  LibConfig,
+  K8sValidationPresetConfig,
+  AttributeContextPresetConfig,
+  LintConfig,
+  LintRule,
+  LintSeverity,
+  SystemFunctionsConfig,
+  HashConfig,
+  LocaleConfig,
+  SafeModePresetConfig,
+  EvalOptionName,
+  ProgramOptionConfig,
 } from "./options/index.js";
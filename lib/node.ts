@@ -0,0 +1,188 @@
+/**
+ * Node.js entrypoint for the WASM module
+ *
+ * Unlike the main entry, this loader doesn't install the API on globalThis.
+ * Each instance hands its functions back as a plain object, so several
+ * instances can live side by side and every `worker_threads` worker gets its
+ * own module. wasm_exec.cjs is loaded unmodified.
+ */
+
+import { readFile } from "node:fs/promises";
+import { createRequire } from "node:module";
+import { fileURLToPath } from "node:url";
+import { threadId } from "node:worker_threads";
+
+export type CELModule = WasmCelExports;
+
+/**
+ * Options for loading the WASM module in Node.js
+ */
+export interface NodeLoaderOptions {
+  /**
+   * Path to main.wasm, defaults to the copy shipped with the package
+   */
+  wasmPath?: string;
+  /**
+   * Path to wasm_exec.cjs, defaults to the copy shipped with the package
+   */
+  wasmExecPath?: string;
+}
+
+const exportNames: Array<keyof WasmCelExports> = [
+  "registerCELFunction",
+  "defineGlobalFunction",
+  "registerLibrary",
+  "createEnv",
+  "createEnvFromJSONSchema",
+  "createEnvFromPreset",
+  "loadBundle",
+  "extendEnv",
+  "compileExpr",
+  "compileExprDetailed",
+  "instantiateTemplate",
+  "recompilePrograms",
+  "replaceProgram",
+  "warmup",
+  "exportCheckedExpr",
+  "programFromCheckedExpr",
+  "typecheckExpr",
+  "canonicalHash",
+  "diffExprs",
+  "mutate",
+  "describeEnv",
+  "listFunctions",
+  "generateSampleVars",
+  "evaluate",
+  "evalProgram",
+  "evalPrograms",
+  "evalRepeated",
+  "explain",
+  "runTests",
+  "evalProgramAsync",
+  "cancelEval",
+  "evalProgramWithContextMessage",
+  "destroyEnv",
+  "destroyProgram",
+  "createRuleSet",
+  "evalRuleSet",
+  "destroyRuleSet",
+  "compilePolicy",
+  "evalPolicy",
+  "destroyPolicy",
+  "createSession",
+  "destroySession",
+  "configure",
+  "sweep",
+  "setLogger",
+  "setLifecycleListener",
+  "getCapabilities",
+  "describeOptions",
+  "shutdown",
+];
+
+let instanceCounter = 0;
+
+/**
+ * Load a new, independent instance of the WASM module
+ *
+ * @param options - Optional paths to the module files
+ * @returns Promise resolving to the module's functions
+ *
+ * @example
+ * ```typescript
+ * import { instantiate } from "wasm-cel/node";
+ *
+ * const cel = await instantiate();
+ * const { envID } = cel.createEnv({ varDecls: [{ name: "x", type: "double" }] });
+ * const { programID } = cel.compileExpr(envID!, "x * 2.0");
+ * console.log(cel.evalProgram(programID!, { x: 21 }).result); // 42
+ * ```
+ */
+export async function instantiate(
+  options: NodeLoaderOptions = {},
+): Promise<CELModule> {
+  const { module } = await load(options);
+  return module;
+}
+
+/**
+ * Load an instance and also return a promise that resolves once it has been
+ * shut down
+ */
+async function load(
+  options: NodeLoaderOptions,
+): Promise<{ module: CELModule; exited: Promise<void> }> {
+  const wasmExecPath =
+    options.wasmExecPath ??
+    fileURLToPath(new URL("../wasm_exec.cjs", import.meta.url));
+  const wasmPath =
+    options.wasmPath ??
+    fileURLToPath(new URL("../main.wasm", import.meta.url));
+
+  // wasm_exec.cjs defines the Go class on globalThis of the current thread
+  const require = createRequire(import.meta.url);
+  require(wasmExecPath);
+
+  const key = `__wasmCel_${threadId}_${++instanceCounter}`;
+  const go = new Go();
+  go.env = { ...go.env, WASM_CEL_EXPORTS: key };
+
+  const wasmBuffer = await readFile(wasmPath);
+  const { instance } = await WebAssembly.instantiate(
+    wasmBuffer,
+    go.importObject,
+  ).catch((err: unknown) => {
+    const error = err instanceof Error ? err : new Error(String(err));
+    throw new Error(`Failed to instantiate WASM module: ${error.message}`);
+  });
+
+  // The Go side attaches its exports to the global object named by
+  // WASM_CEL_EXPORTS while main() runs synchronously inside go.run(), so the
+  // slot is removed as soon as run() returns control
+  const target = {} as WasmCelExports;
+  const globalSlots = globalThis as unknown as Record<string, unknown>;
+  globalSlots[key] = target;
+  let exited: Promise<void>;
+  try {
+    exited = go.run(instance);
+  } finally {
+    delete globalSlots[key];
+  }
+
+  for (const name of exportNames) {
+    if (typeof target[name] !== "function") {
+      throw new Error(`WASM module did not export ${name}`);
+    }
+  }
+
+  return { module: target, exited };
+}
+
+let sharedInstance: Promise<CELModule> | null = null;
+
+/**
+ * Load the WASM module once per thread and return the shared instance
+ *
+ * @param options - Optional paths to the module files, only used on the first call
+ * @returns Promise resolving to the module's functions
+ */
+export function init(options: NodeLoaderOptions = {}): Promise<CELModule> {
+  if (!sharedInstance) {
+    const loading: Promise<CELModule> = load(options)
+      .then(({ module, exited }) => {
+        // After shutdown() the next call loads a fresh shared instance
+        exited.then(() => {
+          if (sharedInstance === loading) {
+            sharedInstance = null;
+          }
+        });
+        return module;
+      })
+      .catch((err: unknown) => {
+        sharedInstance = null;
+        throw err;
+      });
+    sharedInstance = loading;
+  }
+  return sharedInstance;
+}
@@ -0,0 +1,56 @@
+/**
+ * AttributeContextPreset CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for AttributeContextPreset CEL environment option
+ *
+ * AttributeContextPreset declares the standard attributes of a request as
+ * typed variables, so RBAC and ext_authz expressions can be authored and
+ * type-checked. The attributes are the fields of a context message, which
+ * programs can be evaluated against with `program.evalWithContextMessage()`:
+ *
+ * - `"envoy"` declares Envoy's attributes (`request`, `response`, `source`,
+ *   `destination`, `connection`, `upstream`, `metadata`, `filter_state` and
+ *   `xds`) as the fields of `wasmcel.envoy.Attributes`
+ * - `"google"` declares the fields of `google.rpc.context.AttributeContext`
+ *   (`origin`, `source`, `destination`, `request`, `response`, `resource`,
+ *   `api` and `extensions`)
+ */
+export interface AttributeContextPresetConfig {
+  /**
+   * Which attributes to declare.
+   * @default "envoy"
+   */
+  dialect?: "envoy" | "google";
+}
+
+/**
+ * Create an AttributeContextPreset option configuration
+ *
+ * @param config - Configuration for the preset
+ * @returns An option configuration declaring the request attributes
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   options: [Options.attributeContextPreset()]
+ * });
+ * const program = await env.compile('request.headers["x-user"] == "alice"');
+ * await program.evalWithContextMessage("wasmcel.envoy.Attributes", {
+ *   request: { headers: { "x-user": "alice" } },
+ * });
+ * ```
+ */
+export function attributeContextPreset(
+  config: AttributeContextPresetConfig = {},
+): EnvOptionConfig {
+  return {
+    type: "AttributeContextPreset",
+    params: {
+      dialect: config.dialect ?? "envoy",
+    },
+  };
+}
@@ -38,6 +38,21 @@ export interface OptionWithSetup {
   setupAndProcess(env: OptionSetupEnvironment): Promise<EnvOptionConfig>;
 }
 
+/**
+ * Configuration for a functional sub-option passed to an option that accepts them,
+ * such as `OptionalTypesVersion` for OptionalTypes or `StringsVersion` for ext.Strings
+ */
+export interface SubOptionConfig {
+  /**
+   * The name of the sub-option constructor
+   */
+  type: string;
+  /**
+   * Parameters for the sub-option, all of which are required
+   */
+  params?: Record<string, unknown>;
+}
+
 /**
  * Base option configuration that gets sent to WASM
  */
@@ -53,6 +68,74 @@ export type EnvOptionConfig =
   | {
       type: "CrossTypeNumericComparisons";
       params?: import("./crossTypeNumericComparisons.js").CrossTypeNumericComparisonsConfig;
+    }
+  | {
+      type: "DefaultUTCTimeZone";
+      params?: import("./defaultUTCTimeZone.js").DefaultUTCTimeZoneConfig;
+    }
+  | {
+      type: "EagerlyValidateDeclarations";
+      params?: import("./eagerlyValidateDeclarations.js").EagerlyValidateDeclarationsConfig;
+    }
+  | {
+      type: "Lib";
+      params: import("./lib.js").LibConfig;
+    }
+  | {
+      type: "K8sValidationPreset";
+      params?: import("./k8sValidationPreset.js").K8sValidationPresetConfig;
+    }
+  | {
+      type: "AttributeContextPreset";
+      params?: import("./attributeContextPreset.js").AttributeContextPresetConfig;
+    }
+  | {
+      type: "Lint";
+      params?: import("./lint.js").LintConfig;
+    }
+  | {
+      type: "SystemFunctions";
+      params?: import("./systemFunctions.js").SystemFunctionsConfig;
+    }
+  | {
+      type: "IP";
+    }
+  | {
+      type: "URL";
+    }
+  | {
+      type: "JWT";
+    }
+  | {
+      type: "Hash";
+      params?: import("./hash.js").HashConfig;
+    }
+  | {
+      type: "Locale";
+      params?: import("./locale.js").LocaleConfig;
+    }
+  | {
+      type: "Statistics";
+    }
+  | {
+      type: "SetOperations";
+    }
+  | {
+      type: "JSONPath";
+    }
+  | {
+      type: "ProtoJSON";
+    }
+  | {
+      type: "SafeModePreset";
+      params?: import("./safeModePreset.js").SafeModePresetConfig;
+    }
+  | {
+      /**
+       * Extension libraries from cel-go's ext package, such as "ext.Strings"
+       */
+      type: `ext.${string}`;
+      params?: { options?: SubOptionConfig[] };
     };
 
 /**
@@ -0,0 +1,78 @@
+/**
+ * DeclareContextProto CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for DeclareContextProto CEL environment option
+ *
+ * DeclareContextProto declares every top-level field of a protobuf message
+ * as a variable, so a whole request message can serve as the variable
+ * namespace. Evaluate programs against it with
+ * `program.evalWithContextMessage()`.
+ */
+export interface DeclareContextProtoConfig {
+  /**
+   * Fully-qualified name of the context message, e.g. `acme.Request`.
+   */
+  typeName: string;
+
+  /**
+   * Serialized `google.protobuf.FileDescriptorSet` containing the message
+   * and its dependencies, as bytes or a base64 string. Generate one with
+   * `protoc --include_imports --descriptor_set_out=...` or `buf build -o`.
+   */
+  descriptorSet: Uint8Array | string;
+}
+
+/**
+ * Create a DeclareContextProto option configuration
+ *
+ * @param config - The context message type and its descriptors
+ * @returns An option configuration declaring the message fields as variables
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   options: [
+ *     Options.declareContextProto({
+ *       typeName: "acme.Request",
+ *       descriptorSet: fs.readFileSync("request.binpb"),
+ *     }),
+ *   ],
+ * });
+ * ```
+ */
+export function declareContextProto(
+  config: DeclareContextProtoConfig,
+): EnvOptionConfig {
+  const descriptorSet =
+    typeof config.descriptorSet === "string"
+      ? config.descriptorSet
+      : toBase64(config.descriptorSet);
+
+  return {
+    type: "DeclareContextProto",
+    params: {
+      typeName: config.typeName,
+      descriptorSet,
+    },
+  };
+}
+
+/**
+ * Encode bytes as base64 in both Node.js and browsers
+ */
+function toBase64(bytes: Uint8Array): string {
+  if (typeof Buffer !== "undefined") {
+    return Buffer.from(bytes).toString("base64");
+  }
+
+  // Convert in chunks to stay under the argument limit of String.fromCharCode
+  let binary = "";
+  for (let i = 0; i < bytes.length; i += 0x8000) {
+    binary += String.fromCharCode(...bytes.subarray(i, i + 0x8000));
+  }
+  return btoa(binary);
+}
@@ -0,0 +1,56 @@
+/**
+ * DefaultUTCTimeZone CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for DefaultUTCTimeZone CEL environment option
+ *
+ * DefaultUTCTimeZone ensures that time-based operations (such as
+ * `timestamp.getHours()`) use the UTC timezone rather than the input time's
+ * local timezone when no explicit timezone argument is given.
+ *
+ * cel-go currently enables this behavior by default. Setting it explicitly
+ * pins the semantics so they don't change if the upstream default drifts.
+ */
+export interface DefaultUTCTimeZoneConfig {
+  /**
+   * Whether time-based operations default to the UTC timezone.
+   * @default true
+   */
+  enabled?: boolean;
+}
+
+/**
+ * Create a DefaultUTCTimeZone option configuration
+ *
+ * @param config - Configuration for the default timezone behavior
+ * @returns An option configuration for pinning the default timezone
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "ts", type: "timestamp" }],
+ *   options: [Options.defaultUTCTimeZone()]
+ * });
+ * ```
+ *
+ * @example
+ * ```typescript
+ * // Use the legacy behavior of the input time's local timezone
+ * const env = await Env.new({
+ *   options: [Options.defaultUTCTimeZone({ enabled: false })]
+ * });
+ * ```
+ */
+export function defaultUTCTimeZone(
+  config: DefaultUTCTimeZoneConfig = {},
+): EnvOptionConfig {
+  return {
+    type: "DefaultUTCTimeZone",
+    params: {
+      enabled: config.enabled ?? true,
+    },
+  };
+}
@@ -0,0 +1,45 @@
+/**
+ * EagerlyValidateDeclarations CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for EagerlyValidateDeclarations CEL environment option
+ *
+ * EagerlyValidateDeclarations ensures that any collisions between configured
+ * declarations are caught when the environment is created or extended, rather
+ * than lazily on the first compilation.
+ */
+export interface EagerlyValidateDeclarationsConfig {
+  /**
+   * Whether to validate declarations eagerly.
+   * @default true
+   */
+  enabled?: boolean;
+}
+
+/**
+ * Create an EagerlyValidateDeclarations option configuration
+ *
+ * @param config - Configuration for eager declaration validation
+ * @returns An option configuration for enabling eager declaration validation
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "x", type: "int" }],
+ *   options: [Options.eagerlyValidateDeclarations()]
+ * });
+ * ```
+ */
+export function eagerlyValidateDeclarations(
+  config: EagerlyValidateDeclarationsConfig = {},
+): EnvOptionConfig {
+  return {
+    type: "EagerlyValidateDeclarations",
+    params: {
+      enabled: config.enabled ?? true,
+    },
+  };
+}
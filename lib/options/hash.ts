@@ -0,0 +1,44 @@
+/**
+ * Hash CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for Hash CEL environment option
+ *
+ * Hash declares `hash.md5()`, `hash.sha1()`, `hash.sha256()` and
+ * `hash.sha512()`, returning the digest of a string or bytes, and
+ * `hmac.sha256()` and `hmac.sha512()`, returning the HMAC of a message with a
+ * key.
+ */
+export interface HashConfig {
+  /**
+   * Encoding of the digests the functions return
+   * @default "hex"
+   */
+  encoding?: "hex" | "base64";
+}
+
+/**
+ * Create a Hash option configuration
+ *
+ * @param config - Configuration for the encoding of digests
+ * @returns An option configuration declaring the hash and HMAC functions
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "user", type: "string" }],
+ *   options: [Options.hash()]
+ * });
+ * const bucket = await env.compile('hash.sha256(user) < "8"');
+ * ```
+ */
+export function hash(config: HashConfig = {}): EnvOptionConfig {
+  const params: Record<string, any> = {};
+  if (config.encoding !== undefined) {
+    params.encoding = config.encoding;
+  }
+  return { type: "Hash", params };
+}
@@ -10,6 +10,7 @@ export type {
   OptionWithSetup,
   EnvOptionConfig,
   EnvOptionInput,
+  SubOptionConfig,
 } from "./base.js";
 
 // Re-export specific option types
@@ -22,6 +23,23 @@ export type {
   ASTValidatorsConfig,
 } from "./astValidators.js";
 export type { CrossTypeNumericComparisonsConfig } from "./crossTypeNumericComparisons.js";
+export type { DefaultUTCTimeZoneConfig } from "./defaultUTCTimeZone.js";
+export type { EagerlyValidateDeclarationsConfig } from "./eagerlyValidateDeclarations.js";
+export type { DeclareContextProtoConfig } from "./declareContextProto.js";
+export type { LibConfig } from "./lib.js";
+export type { K8sValidationPresetConfig } from "./k8sValidationPreset.js";
+export type { AttributeContextPresetConfig } from "./attributeContextPreset.js";
+export type { LintConfig, LintRule, LintSeverity } from "./lint.js";
+export type { SystemFunctionsConfig } from "./systemFunctions.js";
+export type { HashConfig } from "./hash.js";
+export type { LocaleConfig } from "./locale.js";
+export type { SafeModePresetConfig } from "./safeModePreset.js";
 
-// Re-export the Options helper object
+export type {
+  EvalOptionName,
+  ProgramOptionConfig,
+} from "./programOptions.js";
+
+// Re-export the Options helper objects
 export { Options } from "./options.js";
+export { ProgramOptions } from "./programOptions.js";
@@ -0,0 +1,28 @@
+/**
+ * IP CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Create an IP option configuration
+ *
+ * IP declares the functions of the Kubernetes IP and CIDR libraries,
+ * implemented in Go: `ip()` and `cidr()` parse addresses and ranges, `isIP()`,
+ * `isCIDR()` and `ip.isCanonical()` validate strings, and ranges check
+ * whether they contain addresses with `containsIP()`.
+ *
+ * @returns An option configuration declaring the IP and CIDR functions
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "source", type: "string" }],
+ *   options: [Options.ip()]
+ * });
+ * const internal = await env.compile('cidr("10.0.0.0/8").containsIP(source)');
+ * ```
+ */
+export function ip(): EnvOptionConfig {
+  return { type: "IP" };
+}
@@ -0,0 +1,30 @@
+/**
+ * JSONPath CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Create a JSONPath option configuration
+ *
+ * JSONPath declares `jsonpath(value, path)`, implemented in Go, which returns
+ * the list of values a path selects. Paths support fields (`.name` or
+ * `['name']`), indexes (`[0]`, or `[-1]` from the end) and wildcards (`.*` or
+ * `[*]`).
+ *
+ * @returns An option configuration declaring `jsonpath()`
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "pod", type: "dyn" }],
+ *   options: [Options.jsonPath()]
+ * });
+ * const program = await env.compile(
+ *   'jsonpath(pod, "$.spec.containers[*].image").all(i, i != "latest")',
+ * );
+ * ```
+ */
+export function jsonPath(): EnvOptionConfig {
+  return { type: "JSONPath" };
+}
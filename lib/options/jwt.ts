@@ -0,0 +1,29 @@
+/**
+ * JWT CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Create a JWT option configuration
+ *
+ * JWT declares functions decoding JSON Web Tokens, implemented in Go:
+ * `jwt.decode()` returns the claims of a token, `jwt.header()` its header,
+ * `jwt.hasClaim()` checks whether it has a claim, and `jwt.expiresAt()` and
+ * `jwt.isExpired()` read its `exp` claim. Tokens are never verified, so the
+ * claims must not be trusted for anything but previews.
+ *
+ * @returns An option configuration declaring the JWT functions
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "token", type: "string" }],
+ *   options: [Options.jwt()]
+ * });
+ * const admin = await env.compile('"admin" in jwt.decode(token).roles');
+ * ```
+ */
+export function jwt(): EnvOptionConfig {
+  return { type: "JWT" };
+}
@@ -0,0 +1,48 @@
+/**
+ * K8sValidationPreset CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for K8sValidationPreset CEL environment option
+ *
+ * K8sValidationPreset configures the environment like the CEL environment of
+ * Kubernetes ValidatingAdmissionPolicy: the same cel-go extensions, literal
+ * validation and cost semantics, so rules can be previewed with matching
+ * behavior. The Kubernetes-specific libraries, such as quantity, IP and URL
+ * functions, are not included.
+ */
+export interface K8sValidationPresetConfig {
+  /**
+   * Maximum runtime cost of a single evaluation, after which it is aborted.
+   * @default 1000000
+   */
+  perCallLimit?: number;
+}
+
+/**
+ * Create a K8sValidationPreset option configuration
+ *
+ * @param config - Configuration for the preset
+ * @returns An option configuration for the Kubernetes validation preset
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "object", type: "dyn" }],
+ *   options: [Options.k8sValidationPreset()]
+ * });
+ * ```
+ */
+export function k8sValidationPreset(
+  config: K8sValidationPresetConfig = {},
+): EnvOptionConfig {
+  return {
+    type: "K8sValidationPreset",
+    params:
+      config.perCallLimit !== undefined
+        ? { perCallLimit: config.perCallLimit }
+        : {},
+  };
+}
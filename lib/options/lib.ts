@@ -0,0 +1,51 @@
+/**
+ * Lib CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for Lib CEL environment option
+ *
+ * Lib enables a library by name from cel-go's registry of versioned
+ * extensions, the one used by cel-go environment configuration files, so
+ * libraries added to cel-go are available without an option of their own.
+ * Known names include "strings", "lists", "math", "sets", "encoders",
+ * "bindings", "protos", "regex", "two-var-comprehensions" and "optional".
+ */
+export interface LibConfig {
+  /**
+   * Name of the library, such as "strings".
+   */
+  name: string;
+
+  /**
+   * Version of the library, which limits the functions it provides.
+   * @default "latest"
+   */
+  version?: number | "latest";
+}
+
+/**
+ * Create a Lib option configuration
+ *
+ * @param config - The name and version of the library
+ * @returns An option configuration enabling the library
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "s", type: "string" }],
+ *   options: [Options.lib({ name: "strings", version: 3 })]
+ * });
+ * ```
+ */
+export function lib(config: LibConfig): EnvOptionConfig {
+  return {
+    type: "Lib",
+    params: {
+      name: config.name,
+      version: config.version ?? "latest",
+    },
+  };
+}
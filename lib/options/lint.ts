@@ -0,0 +1,68 @@
+/**
+ * Lint CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Names of the built-in lint rules
+ *
+ * - `"constant-condition"`: conditions of `&&`, `||`, `!`, ternaries and macro
+ *   predicates that don't depend on any variable
+ * - `"tautological-comparison"`: comparisons that are always true or always
+ *   false, such as `x == x` or `size(l) >= 0`
+ * - `"duplicate-clause"`: clauses repeated in a chain of `&&` or `||`
+ * - `"timestamp-string-comparison"`: comparisons of timestamps to strings,
+ *   which compare text rather than instants
+ * - `"unused-binding"`: variables bound with `cel.bind` that its expression
+ *   never reads
+ */
+export type LintRule =
+  | "constant-condition"
+  | "tautological-comparison"
+  | "duplicate-clause"
+  | "timestamp-string-comparison"
+  | "unused-binding";
+
+/**
+ * Severity of a lint rule. Findings of `"error"` rules fail compilation, and
+ * `"off"` disables the rule
+ */
+export type LintSeverity = "off" | "info" | "warning" | "error";
+
+/**
+ * Configuration for Lint CEL environment option
+ *
+ * Lint runs the built-in lint rules over every expression compiled in the
+ * environment. Findings are returned as issues by `env.compileDetailed()`,
+ * with the name of the rule that reported them.
+ */
+export interface LintConfig {
+  /**
+   * Severity of each rule. Rules that aren't listed are warnings.
+   */
+  rules?: Partial<Record<LintRule, LintSeverity>>;
+}
+
+/**
+ * Create a Lint option configuration
+ *
+ * @param config - Configuration for the rules
+ * @returns An option configuration enabling the lint rules
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "x", type: "int" }],
+ *   options: [Options.lint({ rules: { "duplicate-clause": "error" } })]
+ * });
+ * const { issues } = await env.compileDetailed("x > 1 && (1 < 2)");
+ * // [{ severity: "warning", rule: "constant-condition", ... }]
+ * ```
+ */
+export function lint(config: LintConfig = {}): EnvOptionConfig {
+  return {
+    type: "Lint",
+    params: config.rules !== undefined ? { rules: config.rules } : {},
+  };
+}
@@ -0,0 +1,67 @@
+/**
+ * Locale CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for Locale CEL environment option
+ *
+ * Locale declares `locale.compare()`, `locale.equals()` and `locale.sort()`,
+ * which collate strings the way a locale does rather than byte by byte, and
+ * `locale.lower()`, `locale.upper()` and `locale.title()`, which change their
+ * case the way it does.
+ */
+export interface LocaleConfig {
+  /**
+   * BCP 47 language tag of the locale, such as "de" or "sv-SE"
+   * @default "und"
+   */
+  locale?: string;
+  /**
+   * Collate strings differing only in case equally
+   * @default false
+   */
+  ignoreCase?: boolean;
+  /**
+   * Collate strings differing only in diacritics equally
+   * @default false
+   */
+  ignoreDiacritics?: boolean;
+  /**
+   * Sort sequences of digits by their numeric value, so "item9" sorts before
+   * "item10"
+   * @default false
+   */
+  numeric?: boolean;
+}
+
+/**
+ * Create a Locale option configuration
+ *
+ * @param config - The locale and how strings collate
+ * @returns An option configuration declaring the locale functions
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "name", type: "string" }],
+ *   options: [Options.locale({ locale: "de", ignoreCase: true })]
+ * });
+ * const program = await env.compile('locale.compare(name, "m") < 0');
+ * ```
+ */
+export function locale(config: LocaleConfig = {}): EnvOptionConfig {
+  const params: Record<string, any> = {};
+  for (const key of [
+    "locale",
+    "ignoreCase",
+    "ignoreDiacritics",
+    "numeric",
+  ] as const) {
+    if (config[key] !== undefined) {
+      params[key] = config[key];
+    }
+  }
+  return { type: "Locale", params };
+}
@@ -2,7 +2,7 @@
  * OptionalTypes CEL environment option
  */
 
-import type { EnvOptionConfig } from "./base.js";
+import type { EnvOptionConfig, SubOptionConfig } from "./base.js";
 
 /**
  * Configuration for OptionalTypes CEL environment option
@@ -10,16 +10,20 @@ import type { EnvOptionConfig } from "./base.js";
  * OptionalTypes enable support for optional syntax and types in CEL.
  * This includes optional field access (obj.?field), optional indexing (list[?0]),
  * and optional value creation (optional.of(value)).
- *
- * This option takes no configuration parameters - it's simply enabled or disabled.
  */
 export interface OptionalTypesConfig {
-  // No configuration needed - OptionalTypes is simply enabled when used
+  /**
+   * Sub-options for the optional types library, e.g.
+   * `{ type: "OptionalTypesVersion", params: { version: 1 } }`
+   */
+  opts?: SubOptionConfig[];
 }
 
 /**
  * Create an OptionalTypes option configuration
  *
+ * @param config - Optional sub-options for the optional types library
+ *
  * @example
  * ```typescript
  * const env = await Env.new({
@@ -28,9 +32,9 @@ export interface OptionalTypesConfig {
  * });
  * ```
  */
-export function optionalTypes(): EnvOptionConfig {
+export function optionalTypes(config: OptionalTypesConfig = {}): EnvOptionConfig {
   return {
     type: "OptionalTypes",
-    params: {},
+    params: config,
   };
 }
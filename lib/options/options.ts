@@ -5,6 +5,24 @@
 import { optionalTypes } from "./optionalTypes.js";
 import { astValidators } from "./astValidators.js";
 import { crossTypeNumericComparisons } from "./crossTypeNumericComparisons.js";
+import { defaultUTCTimeZone } from "./defaultUTCTimeZone.js";
+import { eagerlyValidateDeclarations } from "./eagerlyValidateDeclarations.js";
+import { declareContextProto } from "./declareContextProto.js";
+import { lib } from "./lib.js";
+import { k8sValidationPreset } from "./k8sValidationPreset.js";
+import { attributeContextPreset } from "./attributeContextPreset.js";
+import { lint } from "./lint.js";
+import { systemFunctions } from "./systemFunctions.js";
+import { ip } from "./ip.js";
+import { url } from "./url.js";
+import { jwt } from "./jwt.js";
+import { hash } from "./hash.js";
+import { locale } from "./locale.js";
+import { statistics } from "./statistics.js";
+import { setOperations } from "./setOperations.js";
+import { jsonPath } from "./jsonPath.js";
+import { protoJSON } from "./protoJSON.js";
+import { safeModePreset } from "./safeModePreset.js";
 
 /**
  * Helper object containing functions for creating CEL environment option configurations
@@ -105,4 +123,442 @@ export const Options = {
    * ```
    */
   crossTypeNumericComparisons,
+
+  /**
+   * Create a DefaultUTCTimeZone option configuration
+   *
+   * This option pins whether time-based operations default to the UTC
+   * timezone, rather than relying on the default of the bundled cel-go
+   * version.
+   *
+   * @param config - Configuration for the default timezone behavior
+   * @returns An option configuration for pinning the default timezone
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "ts", type: "timestamp" }],
+   *   options: [Options.defaultUTCTimeZone()]
+   * });
+   * ```
+   */
+  defaultUTCTimeZone,
+
+  /**
+   * Create an EagerlyValidateDeclarations option configuration
+   *
+   * This option makes declaration collisions surface when the environment is
+   * created or extended instead of on the first compilation.
+   *
+   * @param config - Configuration for eager declaration validation
+   * @returns An option configuration for enabling eager declaration validation
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "x", type: "int" }],
+   *   options: [Options.eagerlyValidateDeclarations()]
+   * });
+   * ```
+   */
+  eagerlyValidateDeclarations,
+
+  /**
+   * Create a DeclareContextProto option configuration
+   *
+   * This option declares every top-level field of a protobuf message as a
+   * variable. Evaluate programs against a message of that type with
+   * `program.evalWithContextMessage()`.
+   *
+   * @param config - The context message type and its descriptors
+   * @returns An option configuration declaring the message fields as variables
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   options: [
+   *     Options.declareContextProto({
+   *       typeName: "acme.Request",
+   *       descriptorSet: fs.readFileSync("request.binpb"),
+   *     }),
+   *   ],
+   * });
+   * const program = await env.compile('user.name == "alice"');
+   * await program.evalWithContextMessage("acme.Request", { user: { name: "alice" } });
+   * ```
+   */
+  declareContextProto,
+
+  /**
+   * Create a Lib option configuration
+   *
+   * This option enables a library by name and version from cel-go's
+   * registry of versioned extensions, such as "strings" or "lists".
+   *
+   * @param config - The name and version of the library
+   * @returns An option configuration enabling the library
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "s", type: "string" }],
+   *   options: [
+   *     Options.lib({ name: "strings", version: 3 }),
+   *     Options.lib({ name: "lists" }),
+   *   ]
+   * });
+   * ```
+   */
+  lib,
+
+  /**
+   * Create a K8sValidationPreset option configuration
+   *
+   * This option configures the environment like Kubernetes
+   * ValidatingAdmissionPolicy, enabling the same extensions, literal
+   * validation and cost limits, so rules behave as they would in a cluster.
+   *
+   * @param config - Configuration for the preset, such as the per-call limit
+   * @returns An option configuration for the Kubernetes validation preset
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "object", type: "dyn" }],
+   *   options: [Options.k8sValidationPreset()]
+   * });
+   * const program = await env.compile("object.spec.replicas <= 5");
+   * const { cost } = await program.evalDetailed({ object });
+   * ```
+   */
+  k8sValidationPreset,
+
+  /**
+   * Create an AttributeContextPreset option configuration
+   *
+   * This option declares the standard attributes of a request, those of
+   * Envoy or of `google.rpc.context.AttributeContext`, as typed variables.
+   * Evaluate programs against the attributes with
+   * `program.evalWithContextMessage()`.
+   *
+   * @param config - Configuration for the preset, such as the dialect
+   * @returns An option configuration declaring the request attributes
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   options: [Options.attributeContextPreset({ dialect: "envoy" })]
+   * });
+   * const program = await env.compile(
+   *   'connection.mtls && request.headers["x-user"] == "alice"',
+   * );
+   * await program.evalWithContextMessage("wasmcel.envoy.Attributes", {
+   *   connection: { mtls: true },
+   *   request: { headers: { "x-user": "alice" } },
+   * });
+   * ```
+   */
+  attributeContextPreset,
+
+  /**
+   * Create a Lint option configuration
+   *
+   * This option runs the built-in lint rules, such as constant conditions and
+   * duplicate clauses, over every expression compiled in the environment.
+   * `env.compileDetailed()` returns their findings as issues, and rules set to
+   * `"error"` fail compilation.
+   *
+   * @param config - Severity of each rule, which defaults to `"warning"`
+   * @returns An option configuration enabling the lint rules
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "x", type: "int" }],
+   *   options: [Options.lint({ rules: { "unused-binding": "off" } })]
+   * });
+   * const { issues } = await env.compileDetailed("x > 1 || x > 1");
+   * console.log(issues[0].rule); // "duplicate-clause"
+   * ```
+   */
+  lint,
+
+  /**
+   * Create a SystemFunctions option configuration
+   *
+   * This option declares `uuid()`, returning a random version 4 UUID string,
+   * `random()`, returning a double in [0, 1), and `now()`, returning the
+   * current timestamp, which is the same for every call of an evaluation. In
+   * deterministic mode every evaluation seeds the random source and freezes
+   * the clock, so rules calling them are reproducible.
+   *
+   * @param config - Whether the functions are deterministic, with their seed
+   * and clock
+   * @returns An option configuration declaring the functions
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   options: [Options.systemFunctions({ deterministic: true, seed: 42 })]
+   * });
+   * const program = await env.compile("random()");
+   * await program.eval(); // the same double every time
+   * await program.eval(null, { seed: 7 }); // seeded for this evaluation
+   * ```
+   */
+  systemFunctions,
+
+  /**
+   * Create an IP option configuration
+   *
+   * This option declares the functions of the Kubernetes IP and CIDR
+   * libraries, implemented in Go, so network policies get the same functions
+   * as in a cluster without JavaScript callbacks. Addresses have methods such
+   * as `family()`, `isLoopback()` and `isPrivate()`, and ranges
+   * `containsIP()`, `containsCIDR()` and `prefixLength()`.
+   *
+   * @returns An option configuration declaring the IP and CIDR functions
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "source", type: "string" }],
+   *   options: [Options.ip()]
+   * });
+   * const program = await env.compile(
+   *   'cidr("10.0.0.0/8").containsIP(source) && !ip(source).isLoopback()',
+   * );
+   * await program.eval({ source: "10.1.2.3" }); // true
+   * ```
+   */
+  ip,
+
+  /**
+   * Create a URL option configuration
+   *
+   * This option declares the functions of the Kubernetes URL library,
+   * implemented in Go, for request routing and firewall rules. `url()` parses
+   * absolute URLs and absolute paths, whose `getScheme()`, `getHost()`,
+   * `getHostname()`, `getPort()`, `getEscapedPath()`, `getPath()` and
+   * `getQuery()` take them apart.
+   *
+   * @returns An option configuration declaring the URL functions
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "request", type: "string" }],
+   *   options: [Options.url()]
+   * });
+   * const program = await env.compile(
+   *   'url(request).getQuery()["debug"] == ["true"]',
+   * );
+   * await program.eval({ request: "/search?debug=true" }); // true
+   * ```
+   */
+  url,
+
+  /**
+   * Create a JWT option configuration
+   *
+   * This option declares functions decoding JSON Web Tokens, implemented in
+   * Go, for auth previews: `jwt.decode()`, `jwt.header()`, `jwt.hasClaim()`,
+   * `jwt.expiresAt()` and `jwt.isExpired()`. Tokens are decoded but never
+   * verified, so their claims must not be trusted for anything but previews.
+   *
+   * @returns An option configuration declaring the JWT functions
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "token", type: "string" }],
+   *   options: [Options.jwt(), Options.systemFunctions()]
+   * });
+   * const program = await env.compile(
+   *   '!jwt.isExpired(token, now()) && jwt.decode(token).sub == "alice"',
+   * );
+   * ```
+   */
+  jwt,
+
+  /**
+   * Create a Hash option configuration
+   *
+   * This option declares hash and HMAC functions, implemented in Go, for
+   * rules bucketing users or verifying signatures: `hash.md5()`,
+   * `hash.sha1()`, `hash.sha256()` and `hash.sha512()` of a string or bytes,
+   * and `hmac.sha256()` and `hmac.sha512()` of a message with a key.
+   *
+   * @param config - The encoding of digests, hex or base64
+   * @returns An option configuration declaring the functions
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [
+   *     { name: "body", type: "string" },
+   *     { name: "signature", type: "string" },
+   *   ],
+   *   options: [Options.hash({ encoding: "base64" })]
+   * });
+   * const program = await env.compile(
+   *   'hmac.sha256("secret", body) == signature',
+   * );
+   * ```
+   */
+  hash,
+
+  /**
+   * Create a Locale option configuration
+   *
+   * This option declares functions following the rules of a locale, so text
+   * rules agree with the collation of backends rather than comparing bytes:
+   * `locale.compare()`, `locale.equals()` and `locale.sort()` collate
+   * strings, and `locale.lower()`, `locale.upper()` and `locale.title()`
+   * change their case.
+   *
+   * @param config - The locale and whether collation ignores case and
+   * diacritics or sorts digits numerically
+   * @returns An option configuration declaring the locale functions
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   options: [Options.locale({ locale: "sv" })]
+   * });
+   * const program = await env.compile('locale.sort(["ä", "z", "a"])');
+   * await program.eval(); // ["a", "z", "ä"]
+   * ```
+   */
+  locale,
+
+  /**
+   * Create a Statistics option configuration
+   *
+   * This option declares functions aggregating lists of ints, uints or
+   * doubles, implemented in Go, so analytics expressions don't fold over
+   * lists with comprehensions: `math.sum()`, `math.min()` and `math.max()`
+   * return elements of the list's type, and `math.avg()`, `math.median()` and
+   * `math.percentile()` doubles.
+   *
+   * @returns An option configuration declaring the statistics functions
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [
+   *     { name: "latencies", type: { kind: "list", elementType: "double" } },
+   *   ],
+   *   options: [Options.statistics()]
+   * });
+   * const program = await env.compile(
+   *   "math.percentile(latencies, 99) > 2.0 * math.median(latencies)",
+   * );
+   * ```
+   */
+  statistics,
+
+  /**
+   * Create a SetOperations option configuration
+   *
+   * This option declares functions combining lists as sets, implemented in
+   * Go, to go with the predicates of `ext.Sets`: `sets.union()`,
+   * `sets.intersection()`, `sets.difference()` and `sets.distinct()`. Results
+   * keep the order elements first appear in and leave out duplicates.
+   *
+   * @returns An option configuration declaring the set operations
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [
+   *     { name: "granted", type: { kind: "list", elementType: "string" } },
+   *     { name: "required", type: { kind: "list", elementType: "string" } },
+   *   ],
+   *   options: [Options.setOperations()]
+   * });
+   * const missing = await env.compile("sets.difference(required, granted)");
+   * ```
+   */
+  setOperations,
+
+  /**
+   * Create a JSONPath option configuration
+   *
+   * This option declares `jsonpath(value, path)`, implemented in Go, for
+   * digging into dynamic data without long chains of index expressions. It
+   * returns the list of values a path selects, supporting the subset of
+   * JSONPath made of fields, indexes and wildcards.
+   *
+   * @returns An option configuration declaring `jsonpath()`
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "pod", type: "dyn" }],
+   *   options: [Options.jsonPath()]
+   * });
+   * const program = await env.compile(
+   *   'jsonpath(pod, "$.spec.containers[*].image")',
+   * );
+   * await program.eval({
+   *   pod: { spec: { containers: [{ image: "nginx" }, { image: "redis" }] } },
+   * }); // ["nginx", "redis"]
+   * ```
+   */
+  jsonPath,
+
+  /**
+   * Create a ProtoJSON option configuration
+   *
+   * This option declares `toJSON(value)` and `fromJSON(typeName, json)`,
+   * which convert messages to and from their protobuf JSON mapping, so rules
+   * can build typed messages with struct construction expressions, such as
+   * `acme.Policy{name: "x"}`, and emit them for the host application.
+   * `fromJSON()` only finds types registered along with or before the option.
+   *
+   * @returns An option configuration declaring `toJSON()` and `fromJSON()`
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "body", type: "string" }],
+   *   options: [
+   *     Options.declareContextProto({
+   *       typeName: "acme.Request",
+   *       descriptorSet,
+   *     }),
+   *     Options.protoJSON(),
+   *   ]
+   * });
+   * const program = await env.compile(
+   *   'fromJSON("acme.User", body).groups.exists(g, g == "admins")',
+   * );
+   * ```
+   */
+  protoJSON,
+
+  /**
+   * Create a SafeModePreset option configuration
+   *
+   * This option restricts the environment to boolean filters that are safe to
+   * compile from untrusted input: expressions must have type bool, can't
+   * concatenate strings, bytes or lists, build lists or maps with
+   * comprehensions, write large literals or call custom JavaScript functions,
+   * and evaluations are aborted once they exceed the cost limit.
+   *
+   * @param config - Configuration for the preset, such as the cost limit
+   * @returns An option configuration for the safe mode preset
+   *
+   * @example
+   * ```typescript
+   * const env = await Env.new({
+   *   variables: [{ name: "item", type: "map<string, dyn>" }],
+   *   options: [Options.safeModePreset()]
+   * });
+   * const program = await env.compile(userFilter);
+   * const matches = await program.eval({ item });
+   * ```
+   */
+  safeModePreset,
 } as const;
@@ -0,0 +1,114 @@
+/**
+ * CEL program options
+ *
+ * Program options configure how an individual compiled program is planned and
+ * evaluated, as opposed to environment options which affect every expression
+ * compiled in an environment.
+ */
+
+/**
+ * Evaluation flags accepted by the EvalOptions program option
+ */
+export type EvalOptionName =
+  | "OptTrackState"
+  | "OptExhaustiveEval"
+  | "OptOptimize"
+  | "OptPartialEval"
+  | "OptTrackCost"
+  | "OptCheckStringFormat";
+
+/**
+ * Program option configuration that gets sent to WASM
+ */
+export type ProgramOptionConfig =
+  | {
+      type: "EvalOptions";
+      params?: { opts?: EvalOptionName[] };
+    }
+  | {
+      type: "CostTracking";
+      params?: {};
+    }
+  | {
+      type: "CostLimit";
+      params: { costLimit: number };
+    }
+  | {
+      type: "InterruptCheckFrequency";
+      params: { checkFrequency: number };
+    };
+
+/**
+ * Create an EvalOptions program option configuration
+ *
+ * @param opts - Evaluation flags to enable for the program
+ * @returns A program option configuration
+ *
+ * @example
+ * ```typescript
+ * const program = await env.compile("x > 10", {
+ *   programOptions: [ProgramOptions.evalOptions(["OptOptimize"])],
+ * });
+ * ```
+ */
+export function evalOptions(opts: EvalOptionName[]): ProgramOptionConfig {
+  return {
+    type: "EvalOptions",
+    params: { opts },
+  };
+}
+
+/**
+ * Create a CostTracking program option configuration
+ *
+ * When enabled, `program.evalDetailed()` reports the runtime cost of each
+ * evaluation.
+ *
+ * @returns A program option configuration
+ */
+export function costTracking(): ProgramOptionConfig {
+  return {
+    type: "CostTracking",
+    params: {},
+  };
+}
+
+/**
+ * Create a CostLimit program option configuration
+ *
+ * Evaluation is aborted with an error when the runtime cost exceeds the limit.
+ *
+ * @param costLimit - Maximum runtime cost for a single evaluation
+ * @returns A program option configuration
+ */
+export function costLimit(costLimit: number): ProgramOptionConfig {
+  return {
+    type: "CostLimit",
+    params: { costLimit },
+  };
+}
+
+/**
+ * Create an InterruptCheckFrequency program option configuration
+ *
+ * @param checkFrequency - Number of comprehension iterations between interrupt checks
+ * @returns A program option configuration
+ */
+export function interruptCheckFrequency(
+  checkFrequency: number,
+): ProgramOptionConfig {
+  return {
+    type: "InterruptCheckFrequency",
+    params: { checkFrequency },
+  };
+}
+
+/**
+ * Helper object containing functions for creating CEL program option configurations
+ */
+export const ProgramOptions = {
+  evalOptions,
+  costTracking,
+  costLimit,
+  interruptCheckFrequency,
+} as const;
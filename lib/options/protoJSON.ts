@@ -0,0 +1,33 @@
+/**
+ * ProtoJSON CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Create a ProtoJSON option configuration
+ *
+ * ProtoJSON declares `toJSON(value)`, which returns the JSON of a value, using
+ * the protobuf JSON mapping for messages, and `fromJSON(typeName, json)`, which
+ * decodes a message of a registered type. `fromJSON()` only finds types
+ * registered along with or before the option, such as with
+ * `Options.declareContextProto()`.
+ *
+ * @returns An option configuration declaring `toJSON()` and `fromJSON()`
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   options: [
+ *     Options.declareContextProto({ typeName: "acme.Request", descriptorSet }),
+ *     Options.protoJSON(),
+ *   ]
+ * });
+ * const program = await env.compile(
+ *   'toJSON(acme.User{name: user.name, groups: ["reviewed"]})',
+ * );
+ * ```
+ */
+export function protoJSON(): EnvOptionConfig {
+  return { type: "ProtoJSON" };
+}
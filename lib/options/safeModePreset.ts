@@ -0,0 +1,55 @@
+/**
+ * SafeModePreset CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for SafeModePreset CEL environment option
+ *
+ * SafeModePreset restricts the environment to boolean filters that are safe to
+ * compile from untrusted input, such as filters typed into product UIs:
+ * expressions must have type bool, may not concatenate strings, bytes or
+ * lists, build lists or maps with comprehensions, write large literals or call
+ * custom JavaScript functions, and evaluations are aborted once they exceed a
+ * cost limit.
+ */
+export interface SafeModePresetConfig {
+  /**
+   * Maximum runtime cost of a single evaluation, after which it is aborted.
+   * @default 10000
+   */
+  costLimit?: number;
+  /**
+   * Maximum number of elements of list and map literals.
+   * @default 100
+   */
+  maxLiteralSize?: number;
+}
+
+/**
+ * Create a SafeModePreset option configuration
+ *
+ * @param config - Configuration for the preset
+ * @returns An option configuration for the safe mode preset
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "item", type: "map<string, dyn>" }],
+ *   options: [Options.safeModePreset({ costLimit: 1000 })]
+ * });
+ * ```
+ */
+export function safeModePreset(
+  config: SafeModePresetConfig = {},
+): EnvOptionConfig {
+  const params: Record<string, any> = {};
+  if (config.costLimit !== undefined) {
+    params.costLimit = config.costLimit;
+  }
+  if (config.maxLiteralSize !== undefined) {
+    params.maxLiteralSize = config.maxLiteralSize;
+  }
+  return { type: "SafeModePreset", params };
+}
@@ -0,0 +1,31 @@
+/**
+ * SetOperations CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Create a SetOperations option configuration
+ *
+ * SetOperations declares `sets.union()`, `sets.intersection()`,
+ * `sets.difference()` and `sets.distinct()`, which combine lists as sets and
+ * go with the predicates of the `ext.Sets` option.
+ *
+ * @returns An option configuration declaring the set operations
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [
+ *     { name: "roles", type: { kind: "list", elementType: "string" } },
+ *   ],
+ *   options: [Options.setOperations()]
+ * });
+ * const program = await env.compile(
+ *   'size(sets.intersection(roles, ["admin", "ops"])) > 0',
+ * );
+ * ```
+ */
+export function setOperations(): EnvOptionConfig {
+  return { type: "SetOperations" };
+}
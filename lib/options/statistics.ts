@@ -0,0 +1,29 @@
+/**
+ * Statistics CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Create a Statistics option configuration
+ *
+ * Statistics declares functions aggregating lists of ints, uints or doubles,
+ * implemented in Go: `math.sum()`, `math.min()`, `math.max()`, `math.avg()`,
+ * `math.median()` and `math.percentile()`.
+ *
+ * @returns An option configuration declaring the statistics functions
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [
+ *     { name: "latencies", type: { kind: "list", elementType: "double" } },
+ *   ],
+ *   options: [Options.statistics()]
+ * });
+ * const slow = await env.compile("math.percentile(latencies, 99) > 250.0");
+ * ```
+ */
+export function statistics(): EnvOptionConfig {
+  return { type: "Statistics" };
+}
@@ -0,0 +1,63 @@
+/**
+ * SystemFunctions CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Configuration for SystemFunctions CEL environment option
+ *
+ * SystemFunctions declares `uuid()`, `random()` and `now()`. In deterministic
+ * mode every evaluation seeds the random source with `seed` and freezes the
+ * clock at `now`, so rules calling them are reproducible. Evaluations can also
+ * inject their own with the `seed` and `now` of `EvalOptions`.
+ */
+export interface SystemFunctionsConfig {
+  /**
+   * Seed the random source and freeze the clock of every evaluation
+   * @default false
+   */
+  deterministic?: boolean;
+  /**
+   * Seed of the random source of `uuid()` and `random()` in deterministic mode
+   * @default 0
+   */
+  seed?: number;
+  /**
+   * Timestamp `now()` returns in deterministic mode, as a Date or an RFC 3339
+   * string
+   * @default "1970-01-01T00:00:00Z"
+   */
+  now?: Date | string;
+}
+
+/**
+ * Create a SystemFunctions option configuration
+ *
+ * @param config - Configuration for the deterministic mode
+ * @returns An option configuration declaring `uuid()`, `random()` and `now()`
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   options: [Options.systemFunctions()]
+ * });
+ * const weekend = await env.compile("now().getDayOfWeek() in [0, 6]");
+ * ```
+ */
+export function systemFunctions(
+  config: SystemFunctionsConfig = {},
+): EnvOptionConfig {
+  const params: Record<string, any> = {};
+  if (config.deterministic !== undefined) {
+    params.deterministic = config.deterministic;
+  }
+  if (config.seed !== undefined) {
+    params.seed = config.seed;
+  }
+  if (config.now !== undefined) {
+    params.now =
+      config.now instanceof Date ? config.now.toISOString() : config.now;
+  }
+  return { type: "SystemFunctions", params };
+}
@@ -0,0 +1,30 @@
+/**
+ * URL CEL environment option
+ */
+
+import type { EnvOptionConfig } from "./base.js";
+
+/**
+ * Create a URL option configuration
+ *
+ * URL declares the functions of the Kubernetes URL library, implemented in
+ * Go: `url()` parses absolute URLs and absolute paths, `isURL()` validates
+ * strings, and URLs have getters such as `getHost()`, `getPath()` and
+ * `getQuery()`.
+ *
+ * @returns An option configuration declaring the URL functions
+ *
+ * @example
+ * ```typescript
+ * const env = await Env.new({
+ *   variables: [{ name: "request", type: "string" }],
+ *   options: [Options.url()]
+ * });
+ * const admin = await env.compile(
+ *   'url(request).getPath().startsWith("/admin")',
+ * );
+ * ```
+ */
+export function url(): EnvOptionConfig {
+  return { type: "URL" };
+}
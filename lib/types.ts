@@ -18,14 +18,26 @@ export type CELType =
   | "dyn"
   | "null"
   | "timestamp"
-  | "duration";
+  | "duration"
+  | "decimal";
+
+/**
+ * Compact type expression such as "list<map<string,int>>" or
+ * "map<string, list<double>>"
+ */
+export type CELTypeExpression = `list<${string}>` | `map<${string}>`;
 
 /**
  * CEL list type with element type
  */
 export interface CELListType {
   kind: "list";
-  elementType: CELType | CELListType | CELMapType;
+  elementType:
+    | CELType
+    | CELTypeExpression
+    | CELListType
+    | CELMapType
+    | CELObjectType;
 }
 
 /**
@@ -34,13 +46,38 @@ export interface CELListType {
 export interface CELMapType {
   kind: "map";
   keyType: CELType;
-  valueType: CELType | CELListType | CELMapType;
+  valueType:
+    | CELType
+    | CELTypeExpression
+    | CELListType
+    | CELMapType
+    | CELObjectType;
+}
+
+/**
+ * Object type declared by a variable, whose fields are type checked while its
+ * values are plain objects
+ */
+export interface CELObjectType {
+  kind: "object";
+  fields?: Record<string, CELTypeDef>;
+  /**
+   * Name derived from the path of the object, such as
+   * `wasmcel.vars.request.auth` for the `auth` field of a `request` variable.
+   * Described types have a name and no fields
+   */
+  name?: string;
 }
 
 /**
  * Union of all possible CEL type representations
  */
-export type CELTypeDef = CELType | CELListType | CELMapType;
+export type CELTypeDef =
+  | CELType
+  | CELTypeExpression
+  | CELListType
+  | CELMapType
+  | CELObjectType;
 
 /**
  * Parameter definition for a CEL function
@@ -68,6 +105,28 @@ export interface CELFunctionDefinition {
   impl: (...args: any[]) => any;
   /** Whether the function accepts variable arguments (overloads) */
   overloads?: CELFunctionDefinition[];
+  /**
+   * Whether the implementation depends only on its arguments, so calls to it
+   * may be folded at compile time and programs calling it may be memoized
+   */
+  isPure?: boolean;
+  /**
+   * Cache the results of calls within an evaluation, keyed by a hash of the
+   * arguments, so repeated calls with the same arguments, such as inside
+   * comprehensions, call the implementation once. Failed calls aren't cached
+   */
+  cacheable?: boolean;
+  /**
+   * Also keep up to this many results of a cacheable function across
+   * evaluations, evicting the least recently used
+   */
+  cacheSize?: number;
+  /**
+   * Implementation called once for all the calls a map() makes, such as
+   * `list.map(x, f(x))`, with the list of their argument lists, returning the
+   * list of their results in the same order
+   */
+  batchImpl?: (calls: any[][], ...rest: any[]) => any[] | Promise<any[]>;
 }
 
 /**
@@ -78,6 +137,79 @@ export interface VariableDeclaration {
   name: string;
   /** Variable type */
   type: CELTypeDef;
+  /**
+   * Value of the variable when an evaluation doesn't provide it. Whole numbers
+   * are accepted for `int` and `uint` variables, and RFC 3339 and Go duration
+   * strings such as "1h30m" for `timestamp` and `duration` variables
+   */
+  default?: any;
+}
+
+/**
+ * Compile-time constant declaration for an environment
+ */
+export interface ConstantDeclaration {
+  /** Constant name */
+  name: string;
+  /** Constant type */
+  type: CELTypeDef;
+  /**
+   * Constant value. Numbers are converted to int or uint constants when they
+   * are integral, and strings to timestamp and duration constants
+   */
+  value: any;
+}
+
+/**
+ * A named set of declarations that environments include with
+ * `EnvOptions.libraries`, registered with registerLibrary()
+ */
+export interface LibraryDefinition {
+  /** Variable declarations */
+  variables?: VariableDeclaration[];
+  /** Custom functions */
+  functions?: CELFunctionDefinition[];
+  /**
+   * Environment options. Options that need setup in JavaScript, such as AST
+   * validators, can't be part of a library
+   */
+  options?: import("./options/index.js").EnvOptionConfig[];
+}
+
+/**
+ * Options of the one-off `evaluate()`
+ */
+export interface EvaluateOptions {
+  /**
+   * Variable declarations, in place of the types inferred from the values of
+   * the variables, such as timestamps that JSON can only hold as strings
+   */
+  variables?: VariableDeclaration[];
+  /** Custom functions */
+  functions?: CELFunctionDefinition[];
+  /** Names of registered libraries to include */
+  libraries?: string[];
+  /**
+   * Environment options. Options that need setup in JavaScript, such as AST
+   * validators, aren't supported
+   */
+  options?: import("./options/index.js").EnvOptionConfig[];
+}
+
+/**
+ * Result of the one-off `evaluate()`
+ */
+export interface EvaluateResult {
+  /** Whether the expression compiled and evaluated */
+  success: boolean;
+  /** The result of the expression, when it evaluated */
+  result?: any;
+  /** The type the expression was checked to, when it compiled */
+  type?: CELTypeDef;
+  /** All issues found while compiling the expression */
+  issues: CompilationIssue[];
+  /** Why compiling or evaluating the expression failed */
+  error?: string;
 }
 
 /**
@@ -86,10 +218,570 @@ export interface VariableDeclaration {
 export interface EnvOptions {
   /** Variable declarations (name and type) */
   variables?: VariableDeclaration[];
+  /**
+   * Compile-time constants, replaced by their values when expressions are
+   * compiled so that the parts of an expression that only depend on them are
+   * evaluated once
+   */
+  constants?: ConstantDeclaration[];
   /** Custom functions to register */
   functions?: CELFunctionDefinition[];
+  /** Names of libraries registered with registerLibrary() to include */
+  libraries?: string[];
   /** Environment options (like OptionalTypes) */
   options?: import("./options/index.js").EnvOptionInput[];
+  /** Session the environment belongs to, destroyed along with it */
+  session?: import("./index.js").Session;
+  /**
+   * What variables that an evaluation doesn't provide, and that have no
+   * default, evaluate to. With "error" (the default) reading them fails the
+   * evaluation, with "null" they are null, and with "unknown" they are unknown,
+   * as if listed in the `unknowns` of evalDetailed()
+   */
+  absentVariables?: AbsentVariablesPolicy;
+  /**
+   * The default `integers` mode of evaluations of the environment's programs,
+   * see {@link EvalOptions.integers}
+   */
+  integers?: IntegerMode;
+  /**
+   * The API version to pin the environment to, so the grammar of the types of
+   * its declarations, the default value encoding of its evaluations and the
+   * options it starts from stay those of that version when later versions of
+   * the library change them. Defaults to the latest version
+   */
+  apiVersion?: number;
+}
+
+/**
+ * What variables that an evaluation doesn't provide evaluate to
+ */
+export type AbsentVariablesPolicy = "error" | "null" | "unknown";
+
+/**
+ * Options for compiling a CEL expression into a program
+ */
+export interface CompileOptions {
+  /** Program options (like CostLimit) applied to the compiled program */
+  programOptions?: import("./options/index.js").ProgramOptionConfig[];
+  /** Include timing metrics in the result of compileDetailed() */
+  metrics?: boolean;
+  /**
+   * Fold constant subexpressions into literals and enable the planner's
+   * optimizations, which precompute constant lists, maps and regular
+   * expressions. Folding is skipped for expressions that would fail when
+   * folded, and calls to custom functions are only folded if they are pure
+   */
+  optimize?: boolean;
+  /**
+   * Expose the source of the compiled expression, after folding, as
+   * `program.optimizedSource`
+   */
+  optimizedSource?: boolean;
+  /**
+   * Cache up to this many evaluation results, keyed by a hash of the variables
+   * and evaluation options, so evaluating the same inputs again returns the
+   * cached result. Only programs that don't call impure custom functions can
+   * be memoized
+   */
+  memoize?: number;
+  /**
+   * Expose the paths into the variables that the expression reads as
+   * `program.fieldMask`, so `program.pruneVars()` can drop the rest of large
+   * variables before they are passed to the WASM module
+   */
+  fieldMask?: boolean;
+  /**
+   * Map the ranges of `program.optimizedSource` back to the ranges of the
+   * expression they came from, as `program.sourceMap`, so diagnostics on the
+   * optimized source can be shown on the original. Requires `optimizedSource`
+   */
+  sourceMap?: boolean;
+  /**
+   * Fail compilation unless the expression has this type, such as "bool" for
+   * conditions. Expressions of type dyn are accepted, as their type is only
+   * known once they are evaluated
+   */
+  expectedType?: CELTypeDef;
+}
+
+/**
+ * Compile flags sent to the WASM module
+ */
+export type CompileFlags = Pick<
+  CompileOptions,
+  | "metrics"
+  | "optimize"
+  | "optimizedSource"
+  | "memoize"
+  | "fieldMask"
+  | "sourceMap"
+  | "expectedType"
+>;
+
+/**
+ * Options for evaluating a compiled program
+ */
+export interface EvalOptions {
+  /** Include timing metrics in the result of evalDetailed() */
+  metrics?: boolean;
+  /**
+   * How maps with non-string keys are returned. With "string" (the default) all
+   * keys become strings, so `{1: "a"}` and `{"1": "a"}` look the same. With
+   * "entries" such maps are returned as {@link MapEntries}
+   */
+  mapKeys?: MapKeysMode;
+  /**
+   * How NaN and ±Infinity doubles are returned. With "number" (the default) they
+   * are plain numbers, which are lost if the result is sent through JSON. With
+   * "tagged" they are returned as {@link TaggedDouble}, and with "string" as
+   * "NaN", "Infinity" or "-Infinity"
+   */
+  nonFinite?: NonFiniteMode;
+  /**
+   * How ints and uints beyond 2^53, which numbers can't hold exactly, are
+   * returned. With "number" (the default) they are rounded, with "string" they
+   * are strings of digits and with "bigint" BigInts. With "string" or "bigint",
+   * variables declared as ints or uints may also be given as strings of digits
+   * or BigInts. Defaults to the `integers` of the program's environment
+   */
+  integers?: IntegerMode;
+  /**
+   * Resolve evalDetailed() with an `errorValue` instead of rejecting when the
+   * expression evaluates to a CEL error
+   */
+  errorValues?: boolean;
+  /**
+   * Attribute paths such as "request.auth" whose values are unknown, with "*"
+   * matching any field. Programs compiled with the OptPartialEval program option
+   * then return the attributes the outcome depends on in `unknown`
+   */
+  unknowns?: string[];
+  /**
+   * Record every custom function call of the evaluation in the `functionTrace`
+   * of evalDetailed(), with its arguments, result and duration
+   */
+  traceFunctions?: boolean;
+  /**
+   * Record the path of every variable, map entry and list element the
+   * evaluation reads, such as "user.email" or "items[0]", in the
+   * `attributeTrace` of evalDetailed()
+   */
+  traceAttributes?: boolean;
+  /**
+   * Check the variables the program reads against their declared types before
+   * evaluating it, rejecting with an `InvalidVariablesError` that lists every
+   * missing or mismatching value
+   */
+  strict?: boolean;
+  /**
+   * Fail the evaluation once it has run for this many milliseconds. Evaluations
+   * check the time between iterations of comprehensions, and those started by
+   * Program.evalConcurrent() also while waiting for custom functions
+   */
+  timeoutMs?: number;
+  /**
+   * Reject with a `MemoryBudgetError` once the strings, bytes, lists and maps
+   * the evaluation produces add up to this many bytes, estimated from their
+   * sizes. Defaults to the `evalMemoryLimitBytes` set with configure()
+   */
+  memoryLimitBytes?: number;
+  /**
+   * Freeze the clock of `now()` at this time for the evaluation, in
+   * environments with the SystemFunctions option
+   */
+  now?: Date | string;
+  /**
+   * Seed the random source of `uuid()` and `random()` for the evaluation, in
+   * environments with the SystemFunctions option
+   */
+  seed?: number;
+}
+
+/**
+ * Options for evaluating a compiled program with Program.evalConcurrent()
+ */
+export interface ConcurrentEvalOptions extends EvalOptions {
+  /**
+   * Cancels the evaluation when aborted, rejecting with the signal's reason. A
+   * queued evaluation is dropped right away, and a running one the next time it
+   * calls a custom function or between iterations of a comprehension
+   */
+  signal?: AbortSignal;
+}
+
+/**
+ * The allocations of an evaluation that exceeded its memory budget
+ */
+export interface MemoryBudget {
+  /** The budget, in bytes */
+  limitBytes: number;
+  /** The bytes allocated when the evaluation was stopped */
+  allocatedBytes: number;
+}
+
+/**
+ * Passed to custom functions after their arguments in evaluations started by
+ * Program.evalConcurrent()
+ */
+export interface EvalCallContext {
+  /**
+   * Aborts once the evaluation is cancelled or times out, such as to pass on to
+   * fetch(). The reason is a DOMException named "AbortError" or "TimeoutError"
+   */
+  signal: AbortSignal;
+  /** When the evaluation times out, in milliseconds since the epoch */
+  deadline?: number;
+  /** Milliseconds left until the evaluation times out */
+  remainingMs?: number;
+}
+
+/**
+ * How maps with non-string keys are returned
+ */
+export type MapKeysMode = "string" | "entries";
+
+/**
+ * How NaN and ±Infinity doubles are returned
+ */
+export type NonFiniteMode = "number" | "tagged" | "string";
+
+/**
+ * How ints and uints beyond 2^53 are returned
+ */
+export type IntegerMode = "number" | "string" | "bigint";
+
+/**
+ * A NaN or ±Infinity double, returned with `nonFinite: "tagged"`
+ * Variables and custom function results in this form are read as doubles
+ */
+export interface TaggedDouble {
+  "@type": "double";
+  value: "NaN" | "Infinity" | "-Infinity";
+}
+
+/**
+ * A map with non-string keys, returned with `mapKeys: "entries"`
+ * Entries are sorted by key: booleans, then ints, uints and strings
+ */
+export interface MapEntries {
+  "@type": "map";
+  entries: Array<[any, any]>;
+}
+
+/**
+ * An exact decimal number, the form `decimal` values are passed and returned
+ * in so they don't lose precision as numbers
+ * The value keeps its scale, so "1.10" + "2.20" is "3.30"
+ */
+export interface TaggedDecimal {
+  "@type": "decimal";
+  value: string;
+}
+
+/**
+ * Time spent in each phase of compiling an expression, in milliseconds
+ */
+export interface CompileMetrics {
+  /** Parsing the expression */
+  parseMs: number;
+  /** Type-checking the expression, including AST validators */
+  checkMs: number;
+  /** Folding constant subexpressions, when optimizing or the environment declares constants */
+  optimizeMs?: number;
+  /** Planning the program */
+  programMs: number;
+}
+
+/**
+ * Time spent evaluating a program, in milliseconds
+ */
+export interface EvalMetrics {
+  /** The whole evaluation, including custom function calls */
+  evalMs: number;
+  /** Time spent in JavaScript custom function implementations */
+  callbackMs: number;
+  /** Number of custom function calls */
+  callbackCount: number;
+  /** Time spent looking up the memo cache, present for memoized programs */
+  memoMs?: number;
+}
+
+/**
+ * A boolean expression in a rule set
+ */
+export interface Rule {
+  /** Identifier reported when the rule matches */
+  id: string;
+  /** CEL expression evaluating to a bool */
+  expr: string;
+  /**
+   * Whether evaluation continues after the rule matches, defaults to
+   * "continue". A matching "stop" rule ends the evaluation
+   */
+  mode?: "continue" | "stop";
+  /**
+   * Rules are evaluated highest priority first, and in the order they are
+   * listed when their priorities are equal. Defaults to 0
+   */
+  priority?: number;
+}
+
+/**
+ * Options for evaluating a rule set
+ */
+export interface RuleSetEvalOptions {
+  /** End the evaluation at the first matching rule, whatever its mode */
+  stopOnFirstMatch?: boolean;
+}
+
+/**
+ * Result of evaluating a rule set
+ */
+export interface RuleSetResult {
+  /** Ids of the rules that evaluated to true, in evaluation order */
+  matched: string[];
+  /** Rules that failed to evaluate, which don't match */
+  errors: Array<{ id: string; error: string }>;
+  /** Number of rules evaluated before the evaluation ended */
+  evaluated: number;
+}
+
+/**
+ * Result of evaluating a policy
+ */
+export interface PolicyResult {
+  /** Whether a match produced an output */
+  matched: boolean;
+  /** Output of the first match whose condition held, null if none did */
+  result: any;
+  /** Explanation of the matching output, present if the match has one */
+  explanation?: any;
+}
+
+/**
+ * Detailed result of evaluating a compiled program
+ */
+export interface EvalResult {
+  /** The evaluation result */
+  result: any;
+  /** Runtime cost of the evaluation, present when cost tracking is enabled */
+  cost?: number;
+  /** Timing metrics, present when requested with `metrics: true` */
+  metrics?: EvalMetrics;
+  /**
+   * The CEL error the expression evaluated to, present with `errorValues: true`.
+   * `result` is null in that case
+   */
+  errorValue?: ErrorValue;
+  /**
+   * Attribute paths the result depends on when it couldn't be determined because
+   * of `unknowns`. `result` is null in that case
+   */
+  unknown?: string[];
+  /** Custom function calls in call order, present with `traceFunctions: true` */
+  functionTrace?: FunctionCall[];
+  /**
+   * Sorted paths of the variables, map entries and list elements the evaluation
+   * read, present with `traceAttributes: true`
+   */
+  attributeTrace?: string[];
+}
+
+/**
+ * Result of evaluating a program several times with evalRepeated()
+ */
+export interface RepeatedEvalResult extends EvalResult {
+  /** Timings of the evaluations */
+  stats: EvalStats;
+}
+
+/**
+ * Timings of repeated evaluations, in milliseconds
+ */
+export interface EvalStats {
+  /** Number of evaluations */
+  iterations: number;
+  /** All evaluations together */
+  totalMs: number;
+  /** The fastest evaluation */
+  minMs: number;
+  /** The median evaluation */
+  p50Ms: number;
+  /** The 95th percentile evaluation */
+  p95Ms: number;
+  /** The slowest evaluation */
+  maxMs: number;
+}
+
+/**
+ * A custom function call recorded with `traceFunctions: true`
+ */
+export interface FunctionCall {
+  /** Function name */
+  function: string;
+  /** Arguments, converted like results */
+  args: any[];
+  /** The value the call returned, absent if it failed */
+  result?: any;
+  /** Error message if the call failed */
+  error?: string;
+  /** Code of the error, for errors reported by the function */
+  code?: string;
+  /** Milliseconds spent in the call, including argument and result conversion */
+  durationMs: number;
+  /** Whether the result came from the cache of a cacheable function */
+  cached?: boolean;
+}
+
+/**
+ * A case of runTests(): variables and the result the program should return
+ */
+export interface TestCase {
+  /** Name identifying the case in the results */
+  name?: string;
+  /** Variables of the evaluation */
+  vars?: Record<string, any>;
+  /**
+   * The expected result, compared like JSON values, so `1` and `1.0` are
+   * equal. Defaults to null
+   */
+  expect?: any;
+  /**
+   * Expect the evaluation to fail with an error containing this text instead
+   * of returning `expect`
+   */
+  expectError?: string;
+}
+
+/**
+ * A difference between the expected and the actual result of a test case
+ */
+export interface TestDiff {
+  /**
+   * Path of the difference within the result, such as `items[0].name`, empty
+   * for the result itself
+   */
+  path: string;
+  /** The expected value, absent if only the actual result has the entry */
+  expected?: any;
+  /** The actual value, absent if only the expected result has the entry */
+  actual?: any;
+}
+
+/**
+ * Outcome of a test case
+ */
+export interface TestCaseResult {
+  /** Name of the case, if it has one */
+  name?: string;
+  /** Whether the case passed */
+  passed: boolean;
+  /** The result the program returned, absent if the evaluation failed */
+  actual?: any;
+  /** Error the evaluation failed with, including CEL errors */
+  error?: string;
+  /** The differences from the expected result, if it differs */
+  diffs?: TestDiff[];
+}
+
+/**
+ * Outcome of running test cases with runTests()
+ */
+export interface TestRunResult {
+  /** Number of cases that passed */
+  passed: number;
+  /** Number of cases that failed */
+  failed: number;
+  /** Outcome of each case, in order */
+  results: TestCaseResult[];
+}
+
+/**
+ * Options for explaining the result of a program with explain()
+ */
+export type ExplainOptions = Pick<
+  EvalOptions,
+  "mapKeys" | "nonFinite" | "integers"
+>;
+
+/**
+ * Result of explaining the evaluation of a program with explain()
+ */
+export interface Explanation {
+  /** The evaluation result, null if the expression evaluated to an error */
+  result: any;
+  /** The CEL error the expression evaluated to, if it did */
+  errorValue?: ErrorValue;
+  /** How the expression arrived at its result */
+  explanation: ExplanationNode;
+}
+
+/**
+ * How a subexpression was evaluated: "true" or "false" for booleans, "value"
+ * for other values, "error" if it failed, or "skipped" if short-circuiting
+ * never evaluated it
+ */
+export type ExplanationOutcome =
+  | "true"
+  | "false"
+  | "value"
+  | "error"
+  | "skipped";
+
+/**
+ * A subexpression in the explanation of a result. Logical operators and
+ * conditionals are explained by their operands, down to the subexpressions
+ * they combine
+ */
+export interface ExplanationNode {
+  /** Source of the subexpression */
+  expr: string;
+  /** Position of the subexpression, with a 1-based line and 0-based column */
+  location?: { line: number; column: number };
+  /** How the subexpression was evaluated */
+  outcome: ExplanationOutcome;
+  /** The value of the subexpression, unless it failed or was skipped */
+  value?: any;
+  /** Error message, if the subexpression failed */
+  error?: string;
+  /**
+   * The operator, for logical operators and conditionals. Chains of the same
+   * operator, such as `a && b && c`, are one node
+   */
+  operator?: "&&" | "||" | "!" | "?:";
+  /** Explanations of the operands, with the operator */
+  operands?: ExplanationNode[];
+}
+
+/**
+ * A CEL error an expression evaluated to, such as a division by zero
+ */
+export interface ErrorValue {
+  /** Error message */
+  message: string;
+  /**
+   * Code of an error reported by a custom function, from the `code` of the
+   * error it threw or returned, or "function_error" if it had none
+   */
+  code?: string;
+  /** ID of the subexpression that produced the error, if known */
+  exprID?: number;
+  /** Position of that subexpression, with a 1-based line and 0-based column */
+  location?: { line: number; column: number };
+}
+
+/**
+ * A variable that doesn't match its declaration, reported by strict evaluations
+ */
+export interface VariableError {
+  /**
+   * Variable name followed by the index or key of the mismatching value, such
+   * as `items[2]` or `labels["app"]`
+   */
+  path: string;
+  /** What is wrong, such as "expected int, got double" or "missing variable" */
+  message: string;
 }
 
 /**
@@ -98,6 +790,354 @@ export interface EnvOptions {
 export interface TypeCheckResult {
   /** The inferred type of the expression */
   type: CELTypeDef;
+  /**
+   * With `allowUndeclared`, a warning with the location of each reference to
+   * an undeclared identifier
+   */
+  issues?: CompilationIssue[];
+  /** With `allowUndeclared`, the names of the undeclared identifiers, sorted */
+  undeclared?: string[];
+}
+
+/**
+ * Options of `env.typecheck()`
+ */
+export interface TypecheckOptions {
+  /**
+   * Check undeclared identifiers as `dyn` and report them as warnings rather
+   * than failing, so editors can show the type of expressions whose variables
+   * aren't declared yet. Undeclared functions still fail
+   */
+  allowUndeclared?: boolean;
+}
+
+/**
+ * Canonical form of a CEL expression and its hash
+ */
+export interface CanonicalHashResult {
+  /** Hex-encoded SHA-256 digest of the canonical source */
+  hash: string;
+  /** The expression unparsed from its checked AST */
+  canonical: string;
+  /** Mapping of the canonical source to the expression */
+  sourceMap: SourceMapping[];
+}
+
+/**
+ * A subexpression that differs between two expressions, as reported by
+ * `env.diffExprs()`
+ */
+export interface ExprChange {
+  /**
+   * `"changed"` when the subexpression was replaced, `"added"` or `"removed"`
+   * for an argument or element only one of the expressions has
+   */
+  kind: "changed" | "added" | "removed";
+  /** The subexpression in the first expression, absent when added */
+  before?: ExprExcerpt;
+  /** The subexpression in the second expression, absent when removed */
+  after?: ExprExcerpt;
+}
+
+/**
+ * The range of a subexpression, its text and the type it was checked to
+ */
+export interface ExprExcerpt extends SourceRange {
+  text: string;
+  type?: string;
+}
+
+/**
+ * Structural differences between two expressions
+ */
+export interface ExprDiff {
+  /** Whether the expressions are the same apart from formatting */
+  equal: boolean;
+  /** The subexpressions that differ, outermost first */
+  changes: ExprChange[];
+}
+
+/**
+ * What a mutant changes: "operator" swaps an operator such as `&&` for `||` or
+ * removes a `!`, "boundary" moves a comparison by one, in its operator or its
+ * constant, and "clause" drops an operand of `&&` or `||`
+ */
+export type MutantKind = "operator" | "boundary" | "clause";
+
+/**
+ * A mutant of an expression, compiled as a program
+ */
+export interface Mutant {
+  /** The compiled mutant, destroyed like any other program */
+  program: import("./index.js").Program;
+  /** Source of the mutant */
+  expr: string;
+  /** What the mutant changes */
+  kind: MutantKind;
+  /** The change, such as "replaced >= with >" */
+  description: string;
+  /** Range of the original expression the mutant changes */
+  range: SourceRange;
+}
+
+/**
+ * Mutants generated from an expression with mutate()
+ */
+export interface MutationResult {
+  /** The mutants that compiled, in the order of the expression */
+  mutants: Mutant[];
+  /** Number of mutants left out because they don't compile */
+  skipped: number;
+}
+
+/**
+ * A variable or constant declared in an environment
+ */
+export interface DeclaredVariable {
+  name: string;
+  /** The declared type, `"dyn"` for types the JSON form can't express */
+  type: CELTypeDef;
+  /** Whether the name is a constant, folded into expressions */
+  constant?: boolean;
+}
+
+/**
+ * An overload of a function declared in an environment
+ */
+export interface DeclaredOverload {
+  /** The overload ID */
+  id: string;
+  /** Whether the overload is called as a method of its first parameter */
+  member: boolean;
+  /** Types of the parameters, including the receiver of methods */
+  params: CELTypeDef[];
+  resultType: CELTypeDef;
+  /** The overload as it's called, such as `"list(<A>).size() -> int"` */
+  signature: string;
+}
+
+/**
+ * A function declared in an environment, including operators such as `_+_`
+ */
+export interface DeclaredFunction {
+  name: string;
+  overloads: DeclaredOverload[];
+}
+
+/**
+ * The declarations and configuration of an environment, as returned by
+ * `env.describe()`
+ */
+export interface EnvDescription {
+  /** Variables and constants, sorted by name */
+  variables: DeclaredVariable[];
+  /** Functions, sorted by name */
+  functions: DeclaredFunction[];
+  /** Options the environment was created and extended with, in order */
+  options: import("./options/index.js").EnvOptionConfig[];
+  /** Names of the libraries the environment includes */
+  libraries: string[];
+  /** The container names are resolved in, empty by default */
+  container: string;
+  /** The API version the environment is pinned to */
+  apiVersion: number;
+}
+
+/**
+ * Options of `env.listFunctions()`
+ */
+export interface ListFunctionsOptions {
+  /**
+   * Keeps the functions and macros whose name or description contains it,
+   * ignoring case
+   */
+  query?: string;
+}
+
+/**
+ * Options of `env.generateSampleVars()`
+ */
+export interface SampleVarsOptions {
+  /**
+   * Seed of the generated values, so the same seed generates the same values
+   * for the same declarations. Defaults to 0
+   */
+  seed?: number;
+}
+
+/**
+ * Documentation of a function or macro, as listed by `env.listFunctions()`
+ */
+export interface FunctionHelp {
+  name: string;
+  /** Documentation of the function, empty for undocumented functions */
+  description: string;
+  /** Signatures of the overloads, such as `"string.size() -> int"` */
+  signatures: string[];
+  /** Example expressions calling the function */
+  examples: string[];
+  /** Whether this is a macro, expanded when parsed, rather than a function */
+  macro?: boolean;
+}
+
+/**
+ * A range of source, in UTF-16 code units like string indexes, end excluded
+ */
+export interface SourceRange {
+  start: number;
+  end: number;
+}
+
+/**
+ * A range of generated source, such as the optimized or canonical source of an
+ * expression, and the range of the original expression it came from
+ *
+ * Every subexpression of the generated source is mapped, from the outermost to
+ * the innermost, so the last mapping containing a position is the most
+ * precise. Folded subexpressions map to the whole subexpression they replaced
+ */
+export interface SourceMapping {
+  generated: SourceRange;
+  original: SourceRange;
+}
+
+/**
+ * Encoding of a checked expression exported with Program.exportCheckedExpr():
+ * the protobuf wire format, its JSON mapping or its text format
+ */
+export type CheckedExprFormat = "binary" | "json" | "textproto";
+
+/**
+ * A checked expression signed with Ed25519 by the backend that checked it,
+ * carrying the expression as it was signed, in any {@link CheckedExprFormat}.
+ * Both fields are base64
+ */
+export interface SignedCheckedExpr {
+  /** The checked expression */
+  checkedExpr: string;
+  /** Ed25519 signature of the checked expression */
+  signature: string;
+}
+
+/**
+ * Options for creating a program from a checked expression
+ */
+export interface ProgramFromCheckedExprOptions extends CompileOptions {
+  /**
+   * Ed25519 public key, as its 32 bytes or in base64. When set, the checked
+   * expression must be a {@link SignedCheckedExpr} signed with its private key
+   */
+  publicKey?: Uint8Array | string;
+}
+
+/**
+ * A policy bundle: the configuration of an environment and the programs
+ * checked in it, in one JSON artifact a rules backend can hand to
+ * {@link import("./index.js").Env.loadBundle}. Bytes are encoded in base64
+ */
+export interface PolicyBundle {
+  /** Version of the bundle format */
+  version: 1;
+  /**
+   * Configuration of the environment. Custom functions are implemented in
+   * JavaScript, so a bundle can only use them through libraries registered
+   * before it's loaded
+   */
+  env: {
+    variables?: VariableDeclaration[];
+    constants?: ConstantDeclaration[];
+    /** Names of libraries registered with registerLibrary() */
+    libraries?: string[];
+    /** Environment options, which can't need setup in JavaScript */
+    options?: import("./options/index.js").EnvOptionConfig[];
+    absentVariables?: AbsentVariablesPolicy;
+    /** The API version to pin the environment to, the latest by default */
+    apiVersion?: number;
+  };
+  /**
+   * Serialized `google.protobuf.FileDescriptorSet` in base64, used by a
+   * DeclareContextProto option of the environment without a descriptor set
+   */
+  descriptorSet?: string;
+  /** The programs of the bundle */
+  programs: PolicyBundleProgram[];
+}
+
+/**
+ * A named program of a {@link PolicyBundle}, either a checked expression or
+ * the source of an expression to compile
+ */
+export interface PolicyBundleProgram {
+  /** Name of the program in {@link LoadedBundle.programs} */
+  name: string;
+  /**
+   * `google.api.expr.v1alpha1.CheckedExpr` in the binary encoding, in base64,
+   * as exported by `program.exportCheckedExpr()`
+   */
+  checkedExpr?: string;
+  /** Source of the expression, when the bundle doesn't carry it checked */
+  expr?: string;
+  /** Program options (like CostLimit) applied to the program */
+  programOptions?: import("./options/index.js").ProgramOptionConfig[];
+}
+
+/**
+ * A {@link PolicyBundle} signed with Ed25519 by the backend that built it,
+ * carrying the JSON of the bundle as it was signed. Both fields are base64
+ */
+export interface SignedPolicyBundle {
+  /** JSON of the bundle */
+  bundle: string;
+  /** Ed25519 signature of the JSON of the bundle */
+  signature: string;
+}
+
+/**
+ * Options for loading a policy bundle
+ */
+export interface LoadBundleOptions
+  extends Pick<EnvOptions, "session" | "integers"> {
+  /**
+   * Ed25519 public key, as its 32 bytes or in base64. When set, the bundle
+   * must be a {@link SignedPolicyBundle} signed with its private key, so
+   * tampered bundles, such as ones cached in local storage, are rejected
+   */
+  publicKey?: Uint8Array | string;
+  /**
+   * Load a {@link SignedPolicyBundle} without a `publicKey`, skipping the
+   * verification of its signature. Signed bundles are rejected without a key
+   * otherwise
+   */
+  skipSignatureVerification?: boolean;
+}
+
+/**
+ * The environment and programs created from a {@link PolicyBundle}
+ */
+export interface LoadedBundle {
+  env: import("./index.js").Env;
+  /** Programs by name */
+  programs: Record<string, import("./index.js").Program>;
+}
+
+/**
+ * Programs compiled again by Env.recompilePrograms()
+ */
+export interface RecompileResult {
+  /** IDs of the programs compiled again, as returned by Program.getID() */
+  recompiled: string[];
+  /** Programs that no longer compile, which keep their previous compilation */
+  issues: RecompileIssue[];
+}
+
+/**
+ * A program that failed to compile again
+ */
+export interface RecompileIssue {
+  /** ID of the program, as returned by Program.getID() */
+  programID: string;
+  /** Why the program doesn't compile */
+  error: string;
 }
 
 /**
@@ -117,6 +1157,10 @@ export interface CompilationIssue {
     /** Character offset in the source */
     offset?: number;
   };
+  /** Lint rule that reported the issue, for issues of the Lint option */
+  rule?: import("./options/lint.js").LintRule;
+  /** Declared names close to an undeclared identifier or function, nearest first */
+  didYouMean?: string[];
 }
 
 /**
@@ -131,4 +1175,134 @@ export interface CompilationResult {
   issues: CompilationIssue[];
   /** The compiled program if compilation succeeded */
   program?: import("./index.js").Program;
+  /** Timing metrics, present when requested with `metrics: true` */
+  metrics?: CompileMetrics;
+  /** Source of the compiled expression, present when requested with `optimizedSource: true` */
+  optimizedSource?: string;
+  /** Paths into the variables the expression reads, present when requested with `fieldMask: true` */
+  fieldMask?: string[][];
+  /** Mapping of `optimizedSource` to the expression, present when requested with `sourceMap: true` */
+  sourceMap?: SourceMapping[];
+}
+
+/**
+ * A structured error reported by the WASM module
+ */
+export interface WasmErrorInfo {
+  /** Error code, "internal" for recovered panics */
+  code: string;
+  /** Human-readable error message */
+  message: string;
+  /** Go stack trace of the failure */
+  stack?: string;
+}
+
+/**
+ * Severity of a log entry reported by the WASM module
+ */
+export type LogLevel = "debug" | "info" | "warn" | "error";
+
+/**
+ * An internal warning or diagnostic reported by the WASM module
+ */
+export interface LogEntry {
+  /** Severity of the entry */
+  level: LogLevel;
+  /** Human-readable description of what happened */
+  message: string;
+  /** Additional context, such as the option or type name involved */
+  fields?: Record<string, any>;
+}
+
+/**
+ * A lifecycle event of the WASM module, sent to the lifecycle listener
+ */
+export type LifecycleEvent =
+  | { type: "envCreated"; envID: string }
+  | { type: "envDestroyed"; envID: string }
+  | { type: "programCompiled"; programID: string; envID: string }
+  | { type: "programDestroyed"; programID: string; envID: string }
+  | { type: "functionUnregistered"; implID: string }
+  | {
+      type: "cacheEvicted";
+      cache: "memo" | "envPool" | "typeRegistry" | "function";
+    };
+
+/**
+ * Runtime configuration of the WASM module
+ */
+export interface RuntimeConfig {
+  /**
+   * Destroy programs that haven't been evaluated for this many milliseconds.
+   * 0 disables the cleanup.
+   */
+  programTTLms?: number;
+  /**
+   * Destroy environments that haven't been used for this many milliseconds.
+   * Compiling, typechecking and evaluating a program of the environment count
+   * as use. 0 disables the cleanup.
+   */
+  envTTLms?: number;
+  /**
+   * The most evaluations started by Program.evalConcurrent() that run at once.
+   * Defaults to 4.
+   */
+  evalWorkers?: number;
+  /**
+   * The memory budget of evaluations that don't set `memoryLimitBytes`, in
+   * bytes. 0 (the default) leaves them unlimited.
+   */
+  evalMemoryLimitBytes?: number;
+}
+
+/**
+ * Version and feature information reported by the WASM module
+ */
+export interface Capabilities {
+  /** The wasm-cel version the module was built from */
+  version: string;
+  /** The cel-go version embedded in the module */
+  celGoVersion: string;
+  /** All registered CEL environment options */
+  options: string[];
+  /** Options that can be configured from JavaScript */
+  jsonOptions: string[];
+  /** Program options that can be configured from JavaScript */
+  programOptions: string[];
+  /** Type names and kinds accepted in type definitions */
+  typeKinds: string[];
+  /** Wire formats supported for values and configuration */
+  wireFormats: string[];
+  /** Names of the environment presets baked into the module */
+  presets: string[];
+}
+
+/**
+ * A single parameter accepted by a JSON-configurable option
+ */
+export interface OptionParamDescription {
+  /** Parameter name as used in the option's `params` object */
+  name: string;
+  /** JSON Schema type of the parameter */
+  type?: string;
+  /** Value used when the parameter is omitted */
+  default?: any;
+  /** Human-readable description of the parameter */
+  description?: string;
+  /** Whether the parameter must be provided */
+  required: boolean;
+}
+
+/**
+ * Metadata describing a JSON-configurable CEL environment option
+ */
+export interface OptionDescription {
+  /** The option type name (the `type` field of an option configuration) */
+  name: string;
+  /** Description of what the option does */
+  description: string;
+  /** JSON Schema describing the option's `params` object */
+  schema: Record<string, any>;
+  /** Flattened list of the top-level parameters from the schema */
+  params: OptionParamDescription[];
 }
@@ -0,0 +1,76 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// LatestAPIVersion is the API version of environments created without one
+const LatestAPIVersion = 1
+
+// apiSemantics holds the behaviors that an API version pins, so environments created
+// with a version keep converting types and values the same way when later versions
+// change them
+type apiSemantics struct {
+	version int
+	// parseType converts JSON type expressions of declarations to CEL types
+	parseType func(typeDef interface{}) *exprpb.Type
+	// encoding fills in the value encoding modes an evaluation leaves unset
+	encoding ValueEncoding
+	// envOptions are the options every environment starts from
+	envOptions func() []cel.EnvOption
+}
+
+// apiVersions maps each supported API version to its semantics
+// Versions are only added, never changed, since stored rules rely on them
+var apiVersions = map[int]*apiSemantics{
+	1: {
+		version:   1,
+		parseType: parseTypeDef,
+		encoding: ValueEncoding{
+			MapKeys:   MapKeysString,
+			NonFinite: NonFiniteNumber,
+			Integers:  IntegersNumber,
+		},
+		envOptions: func() []cel.EnvOption {
+			// Macro calls are tracked so checked expressions, including comprehensions,
+			// can be unparsed
+			return append([]cel.EnvOption{cel.EnableMacroCallTracking()}, codecOptions()...)
+		},
+	},
+}
+
+// lookupAPIVersion returns the semantics of an API version, where 0 is the latest
+func lookupAPIVersion(version int) (*apiSemantics, error) {
+	if version == 0 {
+		version = LatestAPIVersion
+	}
+	semantics, ok := apiVersions[version]
+	if !ok {
+		supported := make([]int, 0, len(apiVersions))
+		for v := range apiVersions {
+			supported = append(supported, v)
+		}
+		sort.Ints(supported)
+		return nil, fmt.Errorf("unsupported API version %d: expected one of %v", version, supported)
+	}
+	return semantics, nil
+}
+
+// withDefaults fills in the modes of a value encoding that are unset with the ones of
+// the API version
+func (s *apiSemantics) withDefaults(encoding ValueEncoding) ValueEncoding {
+	if encoding.MapKeys == "" {
+		encoding.MapKeys = s.encoding.MapKeys
+	}
+	if encoding.NonFinite == "" {
+		encoding.NonFinite = s.encoding.NonFinite
+	}
+	if encoding.Integers == "" {
+		encoding.Integers = s.encoding.Integers
+	}
+	return encoding
+}
@@ -0,0 +1,199 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/interpreter"
+)
+
+// attributeTrace records the attribute paths an evaluation resolves, such as "user" and
+// "user.email", for reports of the data a rule reads
+// A nil *attributeTrace records nothing, so call sites don't need to check whether
+// tracing is enabled
+type attributeTrace struct {
+	adapter   types.Adapter
+	paths     map[string]bool
+	recording bool
+}
+
+// newAttributeTrace returns a trace adapting variables with adapter, or nil if tracing is
+// disabled
+func newAttributeTrace(enabled bool, adapter types.Adapter) *attributeTrace {
+	if !enabled {
+		return nil
+	}
+	return &attributeTrace{adapter: adapter, paths: make(map[string]bool), recording: true}
+}
+
+// wrap decorates the variables of an evaluation so the variables it resolves, and the
+// map entries and list elements read from them, are recorded
+func (t *attributeTrace) wrap(activation interface{}) (interface{}, error) {
+	if t == nil {
+		return activation, nil
+	}
+	parent, ok := activation.(interpreter.Activation)
+	if !ok {
+		var err error
+		if parent, err = interpreter.NewActivation(activation); err != nil {
+			return nil, err
+		}
+	}
+	return &tracedActivation{parent: parent, trace: t}, nil
+}
+
+// stop ends the recording, so converting the result doesn't count as reading it
+func (t *attributeTrace) stop() {
+	if t != nil {
+		t.recording = false
+	}
+}
+
+// track records that the value at path was read, and wraps maps and lists so the values
+// read from them are recorded too
+func (t *attributeTrace) track(path string, val ref.Val) ref.Val {
+	if !t.recording {
+		return val
+	}
+	t.paths[path] = true
+	switch v := val.(type) {
+	case traits.Mapper:
+		return &tracedMap{Mapper: v, path: path, trace: t}
+	case traits.Lister:
+		return &tracedList{Lister: v, path: path, trace: t}
+	}
+	return val
+}
+
+// addTo adds the recorded paths to a response under the "attributeTrace" key, sorted
+func (t *attributeTrace) addTo(response map[string]interface{}) map[string]interface{} {
+	if t == nil {
+		return response
+	}
+	paths := make([]string, 0, len(t.paths))
+	for path := range t.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	response["attributeTrace"] = stringsToInterfaces(paths)
+	return response
+}
+
+// adapter returns the type adapter of the program's environment, which variables are
+// adapted with
+func (p *ProgramState) adapter() types.Adapter {
+	if envState, ok := envs.get(p.envID); ok {
+		return envState.env.CELTypeAdapter()
+	}
+	return types.DefaultTypeAdapter
+}
+
+// tracedActivation records the variables an evaluation resolves
+type tracedActivation struct {
+	parent interpreter.Activation
+	trace  *attributeTrace
+}
+
+// ResolveName implements interpreter.Activation
+func (a *tracedActivation) ResolveName(name string) (any, bool) {
+	value, ok := a.parent.ResolveName(name)
+	if !ok {
+		return value, ok
+	}
+	val, isVal := value.(ref.Val)
+	if !isVal {
+		val = a.trace.adapter.NativeToValue(value)
+	}
+	if types.IsError(val) || types.IsUnknown(val) {
+		return value, ok
+	}
+	return a.trace.track(name, val), true
+}
+
+// Parent returns the variables, which also makes partial activations visible to partial
+// evaluation
+func (a *tracedActivation) Parent() interpreter.Activation {
+	return a.parent
+}
+
+// tracedMap records the entries read from a map
+type tracedMap struct {
+	traits.Mapper
+	path  string
+	trace *attributeTrace
+}
+
+// Get implements traits.Indexer
+func (m *tracedMap) Get(key ref.Val) ref.Val {
+	val := m.Mapper.Get(key)
+	if types.IsError(val) || types.IsUnknown(val) {
+		return val
+	}
+	return m.trace.track(entryPath(m.path, key), val)
+}
+
+// Find implements traits.Mapper
+func (m *tracedMap) Find(key ref.Val) (ref.Val, bool) {
+	val, found := m.Mapper.Find(key)
+	if !found || types.IsError(val) || types.IsUnknown(val) {
+		return val, found
+	}
+	return m.trace.track(entryPath(m.path, key), val), true
+}
+
+// tracedList records the elements read from a list
+type tracedList struct {
+	traits.Lister
+	path  string
+	trace *attributeTrace
+}
+
+// Get implements traits.Indexer
+func (l *tracedList) Get(index ref.Val) ref.Val {
+	val := l.Lister.Get(index)
+	if types.IsError(val) || types.IsUnknown(val) {
+		return val
+	}
+	return l.trace.track(entryPath(l.path, index), val)
+}
+
+// Iterator implements traits.Iterable, recording the elements that comprehensions visit
+func (l *tracedList) Iterator() traits.Iterator {
+	return &tracedIterator{Iterator: l.Lister.Iterator(), list: l}
+}
+
+// tracedIterator records the elements of a list as they are visited
+type tracedIterator struct {
+	traits.Iterator
+	list  *tracedList
+	index int64
+}
+
+// Next implements traits.Iterator
+func (it *tracedIterator) Next() ref.Val {
+	val := it.Iterator.Next()
+	path := entryPath(it.list.path, types.Int(it.index))
+	it.index++
+	if types.IsError(val) || types.IsUnknown(val) {
+		return val
+	}
+	return it.list.trace.track(path, val)
+}
+
+// entryPath returns the path of a map entry or list element, selecting keys that are
+// identifiers as fields
+func entryPath(path string, key ref.Val) string {
+	switch k := key.(type) {
+	case types.String:
+		if objectFieldName.MatchString(string(k)) {
+			return path + "." + string(k)
+		}
+		return fmt.Sprintf("%s[%q]", path, string(k))
+	case types.Int, types.Uint, types.Double, types.Bool:
+		return fmt.Sprintf("%s[%v]", path, k.Value())
+	}
+	return fmt.Sprintf("%s[%v]", path, ValueToJSON(key))
+}
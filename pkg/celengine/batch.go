@@ -0,0 +1,189 @@
+package celengine
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// batchFunctionPrefix starts the names of the functions calling batch implementations,
+// which can't be written in expressions
+const batchFunctionPrefix = "@batch:"
+
+// batchFunctionName returns the name of the function calling the batch implementation
+// of an overload
+func batchFunctionName(overloadID string) string {
+	return batchFunctionPrefix + overloadID
+}
+
+// isBatchFunction reports whether a function calls a batch implementation, so it is
+// left out of descriptions and suggestions
+func isBatchFunction(name string) bool {
+	return strings.HasPrefix(name, batchFunctionPrefix)
+}
+
+// batchDeclaration declares the function calling the batch implementation of an
+// overload, which takes the list of argument lists of the calls and returns the list of
+// their results
+// Its overload ID ends with the implID of the overload, so it is as pure as the overload
+func batchDeclaration(name string, overloadID string, batchImplID string, returnType *cel.Type) cel.EnvOption {
+	return cel.Function(batchFunctionName(overloadID),
+		cel.Overload("batch_"+overloadID, []*cel.Type{cel.ListType(cel.ListType(cel.DynType))}, cel.ListType(returnType),
+			cel.UnaryBinding(func(calls ref.Val) ref.Val {
+				if jsFunctionCaller == nil {
+					return types.NewErr("JavaScript function caller not set")
+				}
+				goCalls := functionArg(calls).([]interface{})
+				if len(goCalls) == 0 {
+					return types.NewRefValList(types.DefaultTypeAdapter, []ref.Val{})
+				}
+
+				start := time.Now()
+				result, err := jsFunctionCaller.CallJSFunction(batchImplID, []interface{}{goCalls})
+				evalMetrics.trackCallback(start)
+				val := batchResults(name, returnType, len(goCalls), result, err)
+				evalTrace.record(name, []ref.Val{calls}, val, start, false)
+				return val
+			}),
+		),
+	)
+}
+
+// batchResults converts the results returned by a batch implementation for a number of
+// calls to a list of CEL values of the function's return type, or to the first error
+func batchResults(name string, returnType *cel.Type, calls int, result interface{}, err error) ref.Val {
+	if err != nil {
+		return functionError(name, err)
+	}
+	if fnErr, ok := returnedError(result); ok {
+		return functionError(name, fnErr)
+	}
+	results, ok := result.([]interface{})
+	if !ok || len(results) != calls {
+		return types.NewErr("function %s must return a list of %d results from its batch implementation", name, calls)
+	}
+	vals := make([]ref.Val, len(results))
+	for i, result := range results {
+		vals[i] = functionResult(name, returnType, result)
+		if types.IsUnknownOrError(vals[i]) {
+			return vals[i]
+		}
+	}
+	return types.NewRefValList(types.DefaultTypeAdapter, vals)
+}
+
+// batchCalls rewrites the map() comprehensions of a checked expression whose transform
+// is a call to a function with a batch implementation, such as list.map(x, f(x)), to
+// collect the arguments of the calls and call the batch implementation once
+// The rewritten expression is only planned: callers keep the checked one for the
+// optimized source, field masks and explanations. Like folding, batching is best-effort,
+// and the checked expression is returned unchanged if it fails
+func batchCalls(env *cel.Env, checked *cel.Ast) *cel.Ast {
+	if len(batchedMaps(env, checked.NativeRep())) == 0 {
+		return checked
+	}
+	batched, issues := cel.NewStaticOptimizer(batchOptimizer{}).Optimize(env, checked)
+	if issues != nil && issues.Err() != nil {
+		return checked
+	}
+	return batched
+}
+
+// batchOptimizer rewrites map() comprehensions to call batch implementations
+type batchOptimizer struct{}
+
+// Optimize implements cel.ASTOptimizer
+// list.map(x, f(x)) becomes @batch:f(list.map(x, [dyn(x)])), and the form filtering the
+// list the same way. Arguments are wrapped with dyn() so the argument lists type check
+// in environments requiring homogeneous literals
+func (batchOptimizer) Optimize(ctx *cel.OptimizerContext, a *ast.AST) *ast.AST {
+	for _, comprehension := range batchedMaps(ctx.Env, a) {
+		call := mappedCall(comprehension)
+		overloadID := a.ReferenceMap()[call.ID()].OverloadIDs[0]
+
+		args := make([]ast.Expr, 0, len(call.AsCall().Args()))
+		for _, arg := range call.AsCall().Args() {
+			args = append(args, ctx.NewCall("dyn", arg))
+		}
+		call.SetKindCase(ctx.NewList(args, []int32{}))
+
+		// The comprehension moves into the argument of the call replacing it
+		collected := ctx.NewLiteral(types.NullValue)
+		collected.SetKindCase(comprehension)
+		comprehension.SetKindCase(ctx.NewCall(batchFunctionName(overloadID), collected))
+	}
+	// The macro calls would refer to the rewritten comprehensions, and the rewritten
+	// expression is never unparsed
+	for id := range ctx.MacroCalls() {
+		ctx.ClearMacroCall(id)
+	}
+	return a
+}
+
+// batchedMaps returns the map() comprehensions of a checked expression whose transform
+// is a call to an overload with a batch implementation
+func batchedMaps(env *cel.Env, a *ast.AST) []ast.Expr {
+	functions := env.Functions()
+	references := a.ReferenceMap()
+	var matches []ast.Expr
+	ast.PostOrderVisit(a.Expr(), ast.NewExprVisitor(func(e ast.Expr) {
+		call := mappedCall(e)
+		if call == nil {
+			return
+		}
+		reference, ok := references[call.ID()]
+		if !ok || len(reference.OverloadIDs) != 1 {
+			return
+		}
+		if _, ok := functions[batchFunctionName(reference.OverloadIDs[0])]; ok {
+			matches = append(matches, e)
+		}
+	}))
+	return matches
+}
+
+// mappedCall returns the global function call a map() comprehension adds to its result
+// for each element, or nil if the expression isn't a map() comprehension transforming
+// elements with a call
+func mappedCall(e ast.Expr) ast.Expr {
+	if e.Kind() != ast.ComprehensionKind {
+		return nil
+	}
+	c := e.AsComprehension()
+	if c.HasIterVar2() || c.AccuInit().Kind() != ast.ListKind || len(c.AccuInit().AsList().Elements()) != 0 ||
+		!isIdent(c.Result(), c.AccuVar()) {
+		return nil
+	}
+
+	// The step is @result + [f(x)], or p ? @result + [f(x)] : @result when filtering
+	step := c.LoopStep()
+	if step.Kind() == ast.CallKind && step.AsCall().FunctionName() == operators.Conditional {
+		args := step.AsCall().Args()
+		if !isIdent(args[2], c.AccuVar()) {
+			return nil
+		}
+		step = args[1]
+	}
+	if step.Kind() != ast.CallKind || step.AsCall().FunctionName() != operators.Add {
+		return nil
+	}
+	args := step.AsCall().Args()
+	if !isIdent(args[0], c.AccuVar()) || args[1].Kind() != ast.ListKind || len(args[1].AsList().Elements()) != 1 {
+		return nil
+	}
+	call := args[1].AsList().Elements()[0]
+	if call.Kind() != ast.CallKind || call.AsCall().IsMemberFunction() {
+		return nil
+	}
+	return call
+}
+
+// isIdent reports whether an expression is a reference to an identifier
+func isIdent(e ast.Expr, name string) bool {
+	return e.Kind() == ast.IdentKind && e.AsIdent() == name
+}
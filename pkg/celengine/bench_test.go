@@ -0,0 +1,28 @@
+package celengine_test
+
+import (
+	"testing"
+
+	"github.com/invakid404/wasm-cel/internal/bench"
+)
+
+// BenchmarkWorkloads runs the workloads shared with cmd/bench
+func BenchmarkWorkloads(b *testing.B) {
+	for _, workload := range bench.Workloads {
+		b.Run(workload.Name, func(b *testing.B) {
+			op, cleanup, err := workload.Setup()
+			if err != nil {
+				b.Fatalf("setup failed: %v", err)
+			}
+			defer cleanup()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := op(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package celengine
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/invakid404/wasm-cel/internal/wasmenv"
+)
+
+// BundleVersion is the version of the bundle format LoadBundle reads
+const BundleVersion = 1
+
+// Bundle is a policy bundle: the configuration of an environment and the programs checked
+// in it, in one artifact a rules backend can hand to this runtime
+// Bundles are encoded as JSON, with bytes in base64
+type Bundle struct {
+	Version int       `json:"version"`
+	Env     BundleEnv `json:"env"`
+	// DescriptorSet is a serialized google.protobuf.FileDescriptorSet, used by the
+	// DeclareContextProto option of the environment when it has no descriptor set of its own
+	DescriptorSet []byte          `json:"descriptorSet,omitempty"`
+	Programs      []BundleProgram `json:"programs,omitempty"`
+}
+
+// BundleEnv is the configuration of the environment of a bundle
+// Custom functions are implemented in JavaScript, so a bundle can only reach them through
+// libraries registered before it's loaded
+type BundleEnv struct {
+	Variables       []VarDecl              `json:"variables,omitempty"`
+	Constants       []ConstantDecl         `json:"constants,omitempty"`
+	Libraries       []string               `json:"libraries,omitempty"`
+	Options         []wasmenv.OptionConfig `json:"options,omitempty"`
+	AbsentVariables string                 `json:"absentVariables,omitempty"`
+	APIVersion      int                    `json:"apiVersion,omitempty"` // 0 is LatestAPIVersion
+}
+
+// BundleProgram is a named program of a bundle, either a checked expression in the binary
+// encoding or the source of an expression to compile
+type BundleProgram struct {
+	Name           string          `json:"name"`
+	CheckedExpr    []byte          `json:"checkedExpr,omitempty"`
+	Expr           string          `json:"expr,omitempty"`
+	ProgramOptions json.RawMessage `json:"programOptions,omitempty"`
+}
+
+// SignedBundle is a bundle signed with Ed25519 by the backend that built it
+// It carries the encoded bundle as it was signed, so verifying doesn't depend on how JSON
+// is formatted
+type SignedBundle struct {
+	Bundle    []byte `json:"bundle"`
+	Signature []byte `json:"signature"`
+}
+
+// SignBundle signs an encoded bundle with an Ed25519 private key and returns the encoded
+// SignedBundle, for Go backends producing bundles for LoadBundleWithKey
+func SignBundle(data []byte, privateKey ed25519.PrivateKey) ([]byte, error) {
+	signature, err := sign(data, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SignedBundle{
+		Bundle:    data,
+		Signature: signature,
+	})
+}
+
+// LoadBundle creates the environment of a bundle and its programs in one call
+// The environment is created in the session if sessionID isn't empty. Checked expressions
+// are imported like ProgramFromCheckedExpr does, so they must match the environment
+// The program IDs are returned under "programs" by name. If any program fails, everything
+// the bundle created is destroyed
+// A SignedBundle is rejected, since its signature can only be verified by
+// LoadBundleWithKey, unless LoadUnverifiedBundle explicitly skips verifying it
+func LoadBundle(data []byte, sessionID string) map[string]interface{} {
+	return LoadBundleWithKey(data, sessionID, nil)
+}
+
+// LoadBundleWithKey loads a bundle like LoadBundle, requiring it to be a SignedBundle
+// signed with the private key of publicKey when publicKey isn't nil, so the rules that
+// run are the ones the backend signed
+func LoadBundleWithKey(data []byte, sessionID string, publicKey []byte) map[string]interface{} {
+	return loadBundle(data, sessionID, publicKey, false)
+}
+
+// LoadUnverifiedBundle loads a bundle like LoadBundle, loading a SignedBundle without
+// verifying its signature, for bundles whose integrity is checked some other way
+func LoadUnverifiedBundle(data []byte, sessionID string) map[string]interface{} {
+	return loadBundle(data, sessionID, nil, true)
+}
+
+// loadBundle loads a bundle, verifying its signature with publicKey unless it's nil
+// A SignedBundle without a key is only loaded when skipVerification is set
+func loadBundle(data []byte, sessionID string, publicKey []byte, skipVerification bool) map[string]interface{} {
+	data, err := verifiedBundle(data, publicKey, skipVerification)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse bundle: %v", err),
+		}
+	}
+	if bundle.Version != BundleVersion {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("unsupported bundle version %d: expected %d", bundle.Version, BundleVersion),
+		}
+	}
+
+	names := make(map[string]bool, len(bundle.Programs))
+	for i, program := range bundle.Programs {
+		if program.Name == "" {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("bundle program %d has no name", i),
+			}
+		}
+		if names[program.Name] {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("duplicate bundle program %s", program.Name),
+			}
+		}
+		names[program.Name] = true
+		if (program.CheckedExpr == nil) == (program.Expr == "") {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("bundle program %s: expected one of checkedExpr or expr", program.Name),
+			}
+		}
+	}
+
+	created := bundle.createEnv(sessionID)
+	if created["error"] != nil {
+		return created
+	}
+	envID := created["envID"].(string)
+
+	programIDs := make(map[string]interface{}, len(bundle.Programs))
+	for _, program := range bundle.Programs {
+		programOptionsJSON := optionalRawJSON(program.ProgramOptions)
+
+		var result map[string]interface{}
+		if program.CheckedExpr != nil {
+			result = ProgramFromCheckedExpr(envID, program.CheckedExpr, CheckedExprBinary, programOptionsJSON, CompileFlags{})
+		} else {
+			result = CompileWithFlags(envID, program.Expr, programOptionsJSON, CompileFlags{})
+		}
+		if result["error"] != nil {
+			for _, programID := range programIDs {
+				DestroyProgram(programID.(string))
+			}
+			DestroyEnv(envID)
+			leaveSession(sessionID, envID)
+			return map[string]interface{}{
+				"error": fmt.Sprintf("bundle program %s: %v", program.Name, result["error"]),
+			}
+		}
+		programIDs[program.Name] = result["programID"]
+	}
+
+	return map[string]interface{}{
+		"envID":    envID,
+		"programs": programIDs,
+		"error":    nil,
+	}
+}
+
+// createEnv creates the environment of a bundle, in the session if sessionID isn't empty
+func (bundle Bundle) createEnv(sessionID string) map[string]interface{} {
+	options, err := bundle.envOptions()
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	env := bundle.Env
+	return CreateEnvWithConfig(EnvConfig{
+		VarDecls:        env.Variables,
+		Constants:       env.Constants,
+		Libraries:       env.Libraries,
+		Options:         options,
+		AbsentVariables: env.AbsentVariables,
+		APIVersion:      env.APIVersion,
+		SessionID:       sessionID,
+	})
+}
+
+// verifiedBundle returns the encoded bundle of a SignedBundle, verifying its signature
+// when publicKey isn't nil. Data that isn't a SignedBundle is returned as is, unless a
+// signature is required, and a SignedBundle without a key is rejected unless
+// skipVerification is set
+func verifiedBundle(data []byte, publicKey []byte, skipVerification bool) ([]byte, error) {
+	if err := checkPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	var signed SignedBundle
+	if err := json.Unmarshal(data, &signed); err != nil || signed.Signature == nil {
+		if publicKey != nil {
+			return nil, fmt.Errorf("bundle isn't signed")
+		}
+		return data, nil
+	}
+
+	switch {
+	case publicKey != nil:
+		if !ed25519.Verify(publicKey, signed.Bundle, signed.Signature) {
+			return nil, fmt.Errorf("bundle signature verification failed")
+		}
+	case !skipVerification:
+		return nil, fmt.Errorf("bundle is signed: load it with a public key to verify its signature, or skip verification explicitly")
+	}
+	return signed.Bundle, nil
+}
+
+// sign signs data with an Ed25519 private key
+func sign(data []byte, privateKey ed25519.PrivateKey) ([]byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key: expected %d bytes, got %d", ed25519.PrivateKeySize, len(privateKey))
+	}
+	return ed25519.Sign(privateKey, data), nil
+}
+
+// checkPublicKey checks that a public key, if any, is an Ed25519 public key
+func checkPublicKey(publicKey []byte) error {
+	if publicKey != nil && len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+	return nil
+}
+
+// envOptions encodes the options of the environment of a bundle, giving the bundle's
+// descriptor set to a DeclareContextProto option without one
+func (bundle Bundle) envOptions() (json.RawMessage, error) {
+	if len(bundle.Env.Options) == 0 {
+		return nil, nil
+	}
+
+	configs := make([]wasmenv.OptionConfig, len(bundle.Env.Options))
+	for i, config := range bundle.Env.Options {
+		if config.Type == "DeclareContextProto" && config.Params["descriptorSet"] == nil {
+			if bundle.DescriptorSet == nil {
+				return nil, fmt.Errorf("bundle option DeclareContextProto has no descriptor set, and neither does the bundle")
+			}
+			params := make(map[string]interface{}, len(config.Params)+1)
+			for name, value := range config.Params {
+				params[name] = value
+			}
+			params["descriptorSet"] = base64.StdEncoding.EncodeToString(bundle.DescriptorSet)
+			config.Params = params
+		}
+		configs[i] = config
+	}
+
+	encoded, err := json.Marshal(configs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle options: %v", err)
+	}
+	return encoded, nil
+}
+
+// optionalRawJSON returns raw JSON as a string, or nil if it's absent
+func optionalRawJSON(raw json.RawMessage) *string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	value := string(raw)
+	return &value
+}
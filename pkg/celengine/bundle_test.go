@@ -0,0 +1,31 @@
+package celengine
+
+import "testing"
+
+func TestLoadBundleFailureLeavesSession(t *testing.T) {
+	sessionID := CreateSession()["sessionID"].(string)
+	defer DestroySession(sessionID)
+
+	loaded := LoadBundle([]byte(`{"version": 1, "env": {}, "programs": [{"name": "answer", "expr": "42"}]}`), sessionID)
+	if loaded["error"] != nil {
+		t.Fatalf("failed to load the bundle: %v", loaded["error"])
+	}
+
+	failed := LoadBundle([]byte(`{"version": 1, "env": {}, "programs": [
+		{"name": "answer", "expr": "42"},
+		{"name": "broken", "expr": "x +"}
+	]}`), sessionID)
+	if failed["error"] == nil {
+		t.Fatalf("expected the bundle with a broken program to fail, got %v", failed)
+	}
+
+	// Only the environment of the bundle that loaded is left in the session
+	envIDs := sessions[sessionID].envIDs
+	if len(envIDs) != 1 || envIDs[0] != loaded["envID"] {
+		t.Fatalf("expected the session to hold only %v, got %v", loaded["envID"], envIDs)
+	}
+	destroyed := DestroySession(sessionID)
+	if destroyed["destroyedEnvs"] != 1 || destroyed["destroyedPrograms"] != 1 {
+		t.Errorf("expected 1 environment and 1 program destroyed, got %v", destroyed)
+	}
+}
@@ -0,0 +1,142 @@
+package celengine
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// callCache holds the results of the calls to cacheable functions made by a single
+// evaluation, keyed by callKey
+// A nil *callCache caches nothing, so call sites don't need to check whether an
+// evaluation is running
+type callCache struct {
+	results map[string]ref.Val
+}
+
+// evalCalls caches the calls of the running evaluation
+var evalCalls *callCache
+
+// get returns the cached result of a call
+func (c *callCache) get(key string) (ref.Val, bool) {
+	if c == nil {
+		return nil, false
+	}
+	val, ok := c.results[key]
+	return val, ok
+}
+
+// put caches the result of a call
+func (c *callCache) put(key string, val ref.Val) {
+	if c == nil {
+		return
+	}
+	if c.results == nil {
+		c.results = make(map[string]ref.Val)
+	}
+	c.results[key] = val
+}
+
+// functionCache is a bounded cache of the results of a cacheable function shared by
+// evaluations, evicting the least recently used entry
+// A nil *functionCache caches nothing, like memoCache. Like the other caches it needs no
+// locking: evaluations run on a single thread and only interleave while suspended
+type functionCache struct {
+	limit   int
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+}
+
+// functionCacheEntry is a cached result
+type functionCacheEntry struct {
+	key string
+	val ref.Val
+}
+
+// newFunctionCache creates the cache of a function, or nil if limit is not positive
+func newFunctionCache(limit int) *functionCache {
+	if limit <= 0 {
+		return nil
+	}
+	return &functionCache{
+		limit:   limit,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached result of a call
+func (c *functionCache) get(key string) (ref.Val, bool) {
+	if c == nil {
+		return nil, false
+	}
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*functionCacheEntry).val, true
+}
+
+// put caches the result of a call, evicting the least recently used entry if the cache
+// is full
+func (c *functionCache) put(key string, val ref.Val) {
+	if c == nil {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*functionCacheEntry).val = val
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&functionCacheEntry{key: key, val: val})
+	if c.order.Len() > c.limit {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*functionCacheEntry).key)
+		emitLifecycleEvent(LifecycleCacheEvicted, map[string]interface{}{"cache": "function"})
+	}
+}
+
+// callKey hashes a call to a function implementation with its arguments, as passed to
+// JavaScript. It returns "" if the arguments can't be serialized, so the call isn't cached
+func callKey(implID string, goArgs []interface{}) string {
+	data, err := json.Marshal(goArgs)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(implID+"\x00"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedCall returns the result of a call cached by the running evaluation or, failing
+// that, shared by the function across evaluations
+func cachedCall(key string, shared *functionCache) (ref.Val, bool) {
+	if key == "" {
+		return nil, false
+	}
+	if val, ok := evalCalls.get(key); ok {
+		return val, true
+	}
+	if val, ok := shared.get(key); ok {
+		evalCalls.put(key, val)
+		return val, true
+	}
+	return nil, false
+}
+
+// cacheCall caches the result of a call for the running evaluation and, if the function
+// shares its results, for later evaluations
+// Errors aren't cached, so failed calls are retried
+func cacheCall(key string, shared *functionCache, val ref.Val) {
+	if types.IsUnknownOrError(val) {
+		return
+	}
+	evalCalls.put(key, val)
+	shared.put(key, val)
+}
@@ -0,0 +1,50 @@
+package celengine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/cel-go/common/types"
+)
+
+// lifecycleRecorder records the lifecycle events sent to its listener
+type lifecycleRecorder struct {
+	events *[]map[string]interface{}
+}
+
+func (r lifecycleRecorder) CallJSFunction(implID string, args []interface{}) (interface{}, error) {
+	*r.events = append(*r.events, args[0].(map[string]interface{}))
+	return nil, nil
+}
+
+func TestFunctionCacheEviction(t *testing.T) {
+	var events []map[string]interface{}
+	SetJSFunctionCaller(lifecycleRecorder{events: &events})
+	SetLifecycleListener("listener")
+	defer SetJSFunctionCaller(nil)
+	defer SetLifecycleListener("")
+
+	cache := newFunctionCache(2)
+	cache.put("a", types.String("A"))
+	cache.put("b", types.String("B"))
+	cache.get("a")
+	if len(events) != 0 {
+		t.Fatalf("expected no events before the cache is full, got %v", events)
+	}
+
+	// "b" is the least recently used entry
+	cache.put("c", types.String("C"))
+	if _, ok := cache.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+
+	expected := []map[string]interface{}{{"type": LifecycleCacheEvicted, "cache": "function"}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
@@ -0,0 +1,57 @@
+package celengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// CanonicalHash returns a hash identifying an expression regardless of its formatting
+// The expression is parsed and checked in the environment, then unparsed to a canonical
+// source, so expressions that differ only in whitespace, comments or redundant parentheses
+// share a hash. The canonical source is returned under "canonical" and its SHA-256 digest,
+// hex-encoded, under "hash", with a map from ranges of the canonical source back to ranges
+// of the expression under "sourceMap"
+func CanonicalHash(envID string, exprStr string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	ast, issues := envState.env.Compile(exprStr)
+	if issues != nil && issues.Err() != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("compilation error: %v", issues.Err()),
+		}
+	}
+
+	canonical, err := cel.AstToString(ast)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to unparse expression: %v", err),
+		}
+	}
+
+	mappings, ok := sourceMap(envState.env, exprStr, canonical)
+	if !ok {
+		mappings = []interface{}{}
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return map[string]interface{}{
+		"hash":      hex.EncodeToString(sum[:]),
+		"canonical": canonical,
+		"sourceMap": mappings,
+		"error":     nil,
+	}
+}
@@ -0,0 +1,161 @@
+package celengine
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+
+	"github.com/invakid404/wasm-cel/internal/options"
+	"github.com/invakid404/wasm-cel/internal/wasmenv"
+)
+
+// Version is the wasm-cel version embedded in the module
+// It is set at build time via -ldflags "-X github.com/invakid404/wasm-cel/pkg/celengine.Version=..."
+var Version = "dev"
+
+const celGoModulePath = "github.com/google/cel-go"
+
+// supportedTypeKinds lists the type names and kinds understood by parseTypeDef: the
+// built-in type names, the kinds of object type definitions, the forms of compact type
+// expressions and the type names of registered codecs
+func supportedTypeKinds() []string {
+	names := make([]string, 0, len(typeNames))
+	for name := range typeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	kinds := append(names, typeDefKinds...)
+	kinds = append(kinds, typeExpressionForms...)
+	return append(kinds, codecTypeNames()...)
+}
+
+// supportedWireFormats lists the encodings accepted for values and configuration
+var supportedWireFormats = []string{"json", "protobuf"}
+
+// celGoVersion returns the version of cel-go compiled into the module
+func celGoVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == celGoModulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// GetCapabilities returns the module and cel-go versions along with the supported
+// options, type kinds, wire formats and baked-in presets so clients can feature-detect
+func GetCapabilities() map[string]interface{} {
+	allOptions := options.DefaultRegistry.List()
+	sort.Strings(allOptions)
+
+	jsonOptions := options.DefaultRegistry.ListWithFromJSON()
+	sort.Strings(jsonOptions)
+
+	programOptions := options.DefaultProgramRegistry.ListWithFromJSON()
+	sort.Strings(programOptions)
+
+	return map[string]interface{}{
+		"version":        Version,
+		"celGoVersion":   celGoVersion(),
+		"options":        stringsToInterfaces(allOptions),
+		"jsonOptions":    stringsToInterfaces(jsonOptions),
+		"programOptions": stringsToInterfaces(programOptions),
+		"typeKinds":      stringsToInterfaces(supportedTypeKinds()),
+		"wireFormats":    stringsToInterfaces(supportedWireFormats),
+		"presets":        stringsToInterfaces(PresetNames()),
+		"error":          nil,
+	}
+}
+
+// stringsToInterfaces converts a string slice into a slice that syscall/js can marshal
+func stringsToInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+// DescribeOptions returns the parameter schema and description of every JSON-configurable
+// environment and program option
+func DescribeOptions() map[string]interface{} {
+	envDescriptions, err := wasmenv.DescribeAvailableOptions()
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to describe options: %v", err),
+		}
+	}
+
+	programDescriptions, err := wasmenv.DescribeAvailableProgramOptions()
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to describe program options: %v", err),
+		}
+	}
+
+	return map[string]interface{}{
+		"options":        optionDescriptionsToJSON(envDescriptions),
+		"programOptions": optionDescriptionsToJSON(programDescriptions),
+		"error":          nil,
+	}
+}
+
+// optionDescriptionsToJSON converts option descriptions to a JS-compatible format
+func optionDescriptionsToJSON(descriptions []wasmenv.OptionDescription) []interface{} {
+	result := make([]interface{}, 0, len(descriptions))
+	for _, description := range descriptions {
+		result = append(result, map[string]interface{}{
+			"name":        description.Name,
+			"description": description.Description,
+			"schema":      description.Schema,
+			"params":      schemaParams(description.Schema),
+		})
+	}
+	return result
+}
+
+// schemaParams flattens the top-level properties of a JSON Schema object into a parameter list
+func schemaParams(schema map[string]interface{}) []interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	required := make(map[string]bool)
+	if requiredList, ok := schema["required"].([]interface{}); ok {
+		for _, name := range requiredList {
+			if nameStr, ok := name.(string); ok {
+				required[nameStr] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		param := map[string]interface{}{
+			"name":     name,
+			"required": required[name],
+		}
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			for _, key := range []string{"type", "default", "description"} {
+				if value, exists := property[key]; exists {
+					param[key] = value
+				}
+			}
+		}
+		params = append(params, param)
+	}
+
+	return params
+}
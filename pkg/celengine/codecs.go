@@ -0,0 +1,120 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// ValueCodec converts the values of a custom CEL type to and from tagged objects such as
+// {"@type": "decimal", "value": "12.30"}, so they cross into and out of the module without
+// passing through a lossy JSON type. Variables, constants, function arguments and results,
+// and evaluation results all use the codecs
+type ValueCodec interface {
+	// Type is the CEL type of the values. Its name is the "@type" of their tagged objects
+	// and the name variables and functions declare it by
+	Type() *types.Type
+	// Declarations declares the functions and operators of the type, and is added to every
+	// environment created after the codec is registered
+	Declarations() []cel.EnvOption
+	// Decode converts a tagged object to a value of the type
+	Decode(object map[string]interface{}) (ref.Val, error)
+	// Encode converts a value of the type to a tagged object
+	Encode(val ref.Val) map[string]interface{}
+}
+
+// valueCodecs maps type names to registered codecs
+var valueCodecs = make(map[string]ValueCodec)
+
+// reservedTypeNames are the type names with a meaning of their own in declarations and
+// tagged objects
+var reservedTypeNames = map[string]bool{
+	"bool": true, "int": true, "uint": true, "double": true, "string": true, "bytes": true,
+	"timestamp": true, "duration": true, "null": true, "dyn": true, "any": true,
+	"list": true, "map": true, "type": true,
+}
+
+func init() {
+	if err := RegisterValueCodec(decimalCodec{}); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterValueCodec registers a codec for a custom type, which environments created
+// afterwards declare
+// A codec can't be replaced, so values already decoded keep their meaning
+func RegisterValueCodec(codec ValueCodec) error {
+	name := codec.Type().TypeName()
+	if reservedTypeNames[name] {
+		return fmt.Errorf("type name is reserved: %s", name)
+	}
+	if _, ok := valueCodecs[name]; ok {
+		return fmt.Errorf("value codec already registered: %s", name)
+	}
+	valueCodecs[name] = codec
+	return nil
+}
+
+// codecTypeNames returns the type names of the registered codecs, sorted
+func codecTypeNames() []string {
+	names := make([]string, 0, len(valueCodecs))
+	for name := range valueCodecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// codecOptions returns the declarations of every registered codec, in order of type name
+func codecOptions() []cel.EnvOption {
+	var opts []cel.EnvOption
+	for _, name := range codecTypeNames() {
+		opts = append(opts, valueCodecs[name].Declarations()...)
+	}
+	return opts
+}
+
+// codecExprType returns the declaration type of a registered codec's type
+func codecExprType(typeName string) (*exprpb.Type, bool) {
+	codec, ok := valueCodecs[typeName]
+	if !ok {
+		return nil, false
+	}
+	exprType, err := cel.TypeToExprType(codec.Type())
+	if err != nil {
+		return nil, false
+	}
+	return exprType, true
+}
+
+// decodeCodecValue decodes an object tagged with the type of a registered codec
+// An object the codec rejects decodes to an error value, which fails the evaluations
+// that use it
+func decodeCodecValue(object map[string]interface{}) (ref.Val, bool) {
+	typeName, ok := object["@type"].(string)
+	if !ok {
+		return nil, false
+	}
+	codec, ok := valueCodecs[typeName]
+	if !ok {
+		return nil, false
+	}
+	val, err := codec.Decode(object)
+	if err != nil {
+		return types.NewErr("failed to decode %s: %v", typeName, err), true
+	}
+	return val, true
+}
+
+// encodeCodecValue encodes a value whose type has a registered codec
+func encodeCodecValue(val ref.Val) (map[string]interface{}, bool) {
+	codec, ok := valueCodecs[val.Type().TypeName()]
+	if !ok {
+		return nil, false
+	}
+	return codec.Encode(val), true
+}
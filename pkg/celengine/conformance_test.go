@@ -0,0 +1,399 @@
+package celengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	celpb "cel.dev/expr"
+	conformancepb "cel.dev/expr/conformance/test"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"github.com/invakid404/wasm-cel/internal/options"
+
+	// Registers the message types referenced by the test files
+	_ "cel.dev/expr/conformance/proto2"
+	_ "cel.dev/expr/conformance/proto3"
+)
+
+// conformanceFiles lists the cel-spec simple test files that apply to this package,
+// with the environment options each one needs. Files about protobuf messages, enums
+// and unknowns are left out since values cross the JavaScript boundary as JSON
+var conformanceFiles = map[string]string{
+	"basic":          "",
+	"bindings_ext":   `[{"type": "ext.Bindings"}]`,
+	"comparisons":    "",
+	"conversions":    "",
+	"encoders_ext":   `[{"type": "ext.Encoders"}]`,
+	"fp_math":        "",
+	"integer_math":   "",
+	"lists":          "",
+	"logic":          "",
+	"macros":         "",
+	"macros2":        `[{"type": "ext.TwoVarComprehensions"}]`,
+	"math_ext":       `[{"type": "ext.Math"}]`,
+	"optionals":      `[{"type": "OptionalTypes"}]`,
+	"parse":          "",
+	"plumbing":       "",
+	"string":         "",
+	"string_ext":     `[{"type": "ext.Strings"}]`,
+	"timestamps":     "",
+	"type_deduction": `[{"type": "OptionalTypes"}]`,
+}
+
+// conformanceSkips lists tests that are known to differ from cel-spec, keyed by
+// file/section/test, with the reason
+var conformanceSkips = map[string]string{
+	"optionals/optionals/map_null_entry_no_such_key":    "cel-go returns optional.none() for fields of absent values",
+	"optionals/optionals/map_present_key_invalid_field": "cel-go returns optional.none() for fields of absent values",
+	"string_ext/value_errors/indexof_out_of_range":      "cel-go returns -1 for out of range offsets",
+	"string_ext/value_errors/lastindexof_out_of_range":  "cel-go returns -1 for out of range offsets",
+}
+
+// roundTripImplID is the implementation ID of the roundTrip function every conformance
+// environment declares. It passes its argument through the mock function caller, so
+// results also cover the conversion of function arguments and return values
+const roundTripImplID = "conformance_roundTrip"
+
+type conformanceCaller struct{}
+
+func (conformanceCaller) CallJSFunction(implID string, args []interface{}) (interface{}, error) {
+	if implID != roundTripImplID || len(args) != 1 {
+		return nil, fmt.Errorf("function implementation not found: %s", implID)
+	}
+	return args[0], nil
+}
+
+func (conformanceCaller) UnregisterFunction(implID string) {}
+
+// conformanceTestdata returns the directory holding the simple test files of the
+// cel.dev/expr module in the module cache
+func conformanceTestdata(t *testing.T) string {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "cel.dev/expr").Output()
+	dir := strings.TrimSpace(string(out))
+	if err != nil || dir == "" {
+		t.Skipf("cel.dev/expr module not available: %v", err)
+	}
+	return filepath.Join(dir, "tests", "simple", "testdata")
+}
+
+func TestConformance(t *testing.T) {
+	testdata := conformanceTestdata(t)
+
+	SetJSFunctionCaller(conformanceCaller{})
+	SetUnregisterFunctionCaller(conformanceCaller{})
+	defer SetJSFunctionCaller(nil)
+	defer SetUnregisterFunctionCaller(nil)
+
+	names := make([]string, 0, len(conformanceFiles))
+	for name := range conformanceFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(testdata, name+".textproto"))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		var file conformancepb.SimpleTestFile
+		if err := prototext.Unmarshal(data, &file); err != nil {
+			t.Fatalf("failed to parse %s: %v", name, err)
+		}
+
+		optionsJSON := conformanceFiles[name]
+		t.Run(name, func(t *testing.T) {
+			if missing := missingConformanceOption(t, optionsJSON); missing != "" {
+				t.Skipf("option %s isn't built in", missing)
+			}
+			for _, section := range file.GetSection() {
+				t.Run(section.GetName(), func(t *testing.T) {
+					for _, test := range section.GetTest() {
+						t.Run(test.GetName(), func(t *testing.T) {
+							key := strings.Join([]string{name, section.GetName(), test.GetName()}, "/")
+							if reason, ok := conformanceSkips[key]; ok {
+								t.Skip(reason)
+							}
+							runConformanceTest(t, test, optionsJSON)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+// missingConformanceOption returns the first environment option of a file that this build
+// leaves out, such as the ext options under the wasmcel_noext tag, or "" if all are built in
+func missingConformanceOption(t *testing.T, optionsJSON string) string {
+	if optionsJSON == "" {
+		return ""
+	}
+	var configs []struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(optionsJSON), &configs); err != nil {
+		t.Fatalf("failed to parse options %s: %v", optionsJSON, err)
+	}
+	for _, config := range configs {
+		if _, err := options.DefaultRegistry.Create(config.Type); err != nil {
+			return config.Type
+		}
+	}
+	return ""
+}
+
+func runConformanceTest(t *testing.T, test *conformancepb.SimpleTest, options string) {
+	switch {
+	case test.GetDisableMacros():
+		t.Skip("macros can't be disabled")
+	case test.GetContainer() != "":
+		t.Skip("containers aren't supported")
+	case test.GetLocale() != "":
+		t.Skip("locales aren't supported")
+	case test.GetUnknown() != nil, test.GetAnyUnknowns() != nil:
+		t.Skip("unknowns aren't supported")
+	case strings.Contains(test.GetExpr(), "TestAllTypes"):
+		t.Skip("protobuf messages aren't supported")
+	}
+
+	varDecls := []VarDecl{}
+	for _, decl := range test.GetTypeEnv() {
+		if decl.GetIdent() == nil {
+			t.Skipf("function declarations aren't supported: %s", decl.GetName())
+		}
+		typeDef, ok := conformanceTypeDef(decl.GetIdent().GetType())
+		if !ok {
+			t.Skipf("no type definition for variable %s", decl.GetName())
+		}
+		varDecls = append(varDecls, VarDecl{Name: decl.GetName(), Type: typeDef})
+	}
+
+	vars := make(map[string]interface{})
+	for varName, binding := range test.GetBindings() {
+		if binding.GetValue() == nil {
+			t.Skipf("binding %s isn't a value", varName)
+		}
+		value, ok := conformanceJSON(binding.GetValue())
+		if !ok {
+			t.Skipf("no JSON representation for binding %s", varName)
+		}
+		vars[varName] = value
+	}
+
+	funcDefs := []FunctionDef{{
+		Name:       "roundTrip",
+		Params:     []ParamDef{{Name: "value", Type: "dyn"}},
+		ReturnType: "dyn",
+		ImplID:     roundTripImplID,
+	}}
+	env := CreateEnvWithOptions(varDecls, funcDefs, &options)
+	if env["error"] != nil {
+		t.Fatalf("failed to create environment: %v", env["error"])
+	}
+	envID := env["envID"].(string)
+	defer DestroyEnv(envID)
+
+	compiled := Compile(envID, test.GetExpr())
+	if test.GetDisableCheck() && compiled["error"] != nil {
+		t.Skip("expressions are always type-checked")
+	}
+	if test.GetCheckOnly() {
+		if compiled["error"] != nil {
+			t.Fatalf("failed to compile %q: %v", test.GetExpr(), compiled["error"])
+		}
+		DestroyProgram(compiled["programID"].(string))
+		return
+	}
+
+	var expected interface{}
+	switch {
+	case test.GetValue() != nil:
+		value, ok := conformanceJSON(test.GetValue())
+		if !ok {
+			t.Skip("no JSON representation for the expected value")
+		}
+		expected = value
+	case test.GetTypedResult() != nil:
+		value, ok := conformanceJSON(test.GetTypedResult().GetResult())
+		if !ok {
+			t.Skip("no JSON representation for the expected value")
+		}
+		expected = value
+	case test.GetEvalError() != nil, test.GetAnyEvalErrors() != nil:
+		// Errors are reported by either the checker or the evaluation
+		if compiled["error"] != nil {
+			return
+		}
+		programID := compiled["programID"].(string)
+		defer DestroyProgram(programID)
+		if result := Eval(programID, vars); result["error"] == nil {
+			t.Fatalf("expected an error from %q, got %#v", test.GetExpr(), result["result"])
+		}
+		return
+	default:
+		// Tests without a matcher expect true
+		expected = true
+	}
+
+	// The expression is evaluated as is, and once more with its result passed
+	// through a function implementation. The newline keeps trailing comments from
+	// swallowing the closing parenthesis
+	for _, expr := range []string{test.GetExpr(), "roundTrip(" + test.GetExpr() + "\n)"} {
+		compiled := Compile(envID, expr)
+		if compiled["error"] != nil && test.GetDisableCheck() {
+			t.Skip("expressions are always type-checked")
+		}
+		if compiled["error"] != nil {
+			t.Fatalf("failed to compile %q: %v", expr, compiled["error"])
+		}
+		programID := compiled["programID"].(string)
+
+		result := Eval(programID, vars)
+		DestroyProgram(programID)
+		if result["error"] != nil {
+			t.Fatalf("failed to evaluate %q: %v", expr, result["error"])
+		}
+		if !conformanceEqual(result["result"], expected) {
+			t.Fatalf("%q evaluated to %#v, expected %#v", expr, result["result"], expected)
+		}
+	}
+}
+
+// conformanceTypeDef converts a checked type to the type definitions accepted by CreateEnv
+func conformanceTypeDef(exprType *celpb.Type) (interface{}, bool) {
+	switch kind := exprType.GetTypeKind().(type) {
+	case *celpb.Type_Dyn:
+		return "dyn", true
+	case *celpb.Type_Null:
+		return "null", true
+	case *celpb.Type_Primitive:
+		switch kind.Primitive {
+		case celpb.Type_BOOL:
+			return "bool", true
+		case celpb.Type_INT64:
+			return "int", true
+		case celpb.Type_UINT64:
+			return "uint", true
+		case celpb.Type_DOUBLE:
+			return "double", true
+		case celpb.Type_STRING:
+			return "string", true
+		case celpb.Type_BYTES:
+			return "bytes", true
+		}
+	case *celpb.Type_WellKnown:
+		switch kind.WellKnown {
+		case celpb.Type_TIMESTAMP:
+			return "timestamp", true
+		case celpb.Type_DURATION:
+			return "duration", true
+		}
+	case *celpb.Type_ListType_:
+		elemType, ok := conformanceTypeDef(kind.ListType.GetElemType())
+		if !ok {
+			return nil, false
+		}
+		return map[string]interface{}{"kind": "list", "elementType": elemType}, true
+	case *celpb.Type_MapType_:
+		keyType, ok := conformanceTypeDef(kind.MapType.GetKeyType())
+		if !ok {
+			return nil, false
+		}
+		valueType, ok := conformanceTypeDef(kind.MapType.GetValueType())
+		if !ok {
+			return nil, false
+		}
+		return map[string]interface{}{"kind": "map", "keyType": keyType, "valueType": valueType}, true
+	}
+	return nil, false
+}
+
+// conformanceJSON converts a conformance value to the form ValueToJSON produces for it
+// Values without a JSON form, like messages and types, are reported as not ok
+func conformanceJSON(value *celpb.Value) (interface{}, bool) {
+	switch kind := value.GetKind().(type) {
+	case *celpb.Value_NullValue:
+		return nil, true
+	case *celpb.Value_BoolValue:
+		return kind.BoolValue, true
+	case *celpb.Value_Int64Value:
+		return kind.Int64Value, true
+	case *celpb.Value_Uint64Value:
+		return kind.Uint64Value, true
+	case *celpb.Value_DoubleValue:
+		return kind.DoubleValue, true
+	case *celpb.Value_StringValue:
+		return kind.StringValue, true
+	case *celpb.Value_BytesValue:
+		return kind.BytesValue, true
+	case *celpb.Value_ListValue:
+		result := make([]interface{}, 0, len(kind.ListValue.GetValues()))
+		for _, item := range kind.ListValue.GetValues() {
+			converted, ok := conformanceJSON(item)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, converted)
+		}
+		return result, true
+	case *celpb.Value_MapValue:
+		result := make(map[string]interface{})
+		for _, entry := range kind.MapValue.GetEntries() {
+			key, ok := conformanceJSON(entry.GetKey())
+			if !ok {
+				return nil, false
+			}
+			converted, ok := conformanceJSON(entry.GetValue())
+			if !ok {
+				return nil, false
+			}
+			result[fmt.Sprintf("%v", key)] = converted
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// conformanceEqual compares JSON values, treating NaN as equal to itself
+func conformanceEqual(actual, expected interface{}) bool {
+	switch expected := expected.(type) {
+	case float64:
+		actual, ok := actual.(float64)
+		return ok && (actual == expected || math.IsNaN(actual) && math.IsNaN(expected))
+	case []byte:
+		actual, ok := actual.([]byte)
+		return ok && bytes.Equal(actual, expected)
+	case []interface{}:
+		actual, ok := actual.([]interface{})
+		if !ok || len(actual) != len(expected) {
+			return false
+		}
+		for i := range expected {
+			if !conformanceEqual(actual[i], expected[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		actual, ok := actual.(map[string]interface{})
+		if !ok || len(actual) != len(expected) {
+			return false
+		}
+		for key, value := range expected {
+			if !conformanceEqual(actual[key], value) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(actual, expected)
+}
@@ -0,0 +1,231 @@
+package celengine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/overloads"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/invakid404/wasm-cel/internal/options"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// ConstantDecl represents a compile-time constant declaration from JavaScript
+// References to constants are replaced by their values when expressions are compiled
+type ConstantDecl struct {
+	Name  string      `json:"name"`
+	Type  interface{} `json:"type"` // Can be string or map[string]interface{}
+	Value interface{} `json:"value"`
+}
+
+// constantOptions converts constant declarations to CEL environment options, parsing
+// their types with parseType
+func constantOptions(constants []ConstantDecl, parseType func(interface{}) *exprpb.Type) ([]cel.EnvOption, error) {
+	opts := make([]cel.EnvOption, 0, len(constants))
+	for _, constant := range constants {
+		celType, err := cel.ExprTypeToType(parseType(constant.Type))
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert type of constant %s: %v", constant.Name, err)
+		}
+
+		value, err := typedValue(celType, constant.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for constant %s: %v", constant.Name, err)
+		}
+
+		opts = append(opts, cel.Constant(constant.Name, celType, value))
+	}
+	return opts, nil
+}
+
+// typedValue converts a JSON value to a CEL value of the given type
+// JSON has no integer, timestamp or duration types, so numbers and strings are
+// converted to the declared type where they represent it exactly
+func typedValue(t *cel.Type, value interface{}) (ref.Val, error) {
+	switch t.Kind() {
+	case types.ListKind:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a list, got %T", value)
+		}
+		elems := make([]ref.Val, len(items))
+		for i, item := range items {
+			elem, err := typedValue(t.Parameters()[0], item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			elems[i] = elem
+		}
+		return types.NewRefValList(types.DefaultTypeAdapter, elems), nil
+	case types.MapKind:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a map, got %T", value)
+		}
+		result := make(map[ref.Val]ref.Val, len(entries))
+		for key, entry := range entries {
+			k, err := typedValue(t.Parameters()[0], key)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %v", key, err)
+			}
+			v, err := typedValue(t.Parameters()[1], entry)
+			if err != nil {
+				return nil, fmt.Errorf("[%q]: %v", key, err)
+			}
+			result[k] = v
+		}
+		return types.NewRefValMap(types.DefaultTypeAdapter, result), nil
+	case types.DynKind, types.AnyKind:
+		return JSONToValue(value), nil
+	}
+
+	val := JSONToValue(value)
+	if val.Type() == t {
+		return val, nil
+	}
+
+	// Only integral doubles convert to integers, rather than being truncated
+	if d, ok := val.(types.Double); ok && (t.Kind() == types.IntKind || t.Kind() == types.UintKind) {
+		if math.Trunc(float64(d)) != float64(d) {
+			return nil, fmt.Errorf("%v is not an integer", float64(d))
+		}
+	}
+
+	switch t.Kind() {
+	case types.IntKind, types.UintKind, types.DoubleKind, types.BytesKind,
+		types.TimestampKind, types.DurationKind:
+		converted := val.ConvertToType(t)
+		if types.IsError(converted) {
+			return nil, fmt.Errorf("%v", converted)
+		}
+		return converted, nil
+	case types.OpaqueKind:
+		// Types with a value codec are decoded from their tagged objects by JSONToValue
+		if types.IsError(val) {
+			return nil, fmt.Errorf("%v", val)
+		}
+		if val.Type().TypeName() == t.TypeName() {
+			return val, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, got %s", t, val.Type().TypeName())
+}
+
+// hasConstants reports whether an environment declares any constants
+func hasConstants(env *cel.Env) bool {
+	for _, variable := range env.Variables() {
+		if variable.Value() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// foldConstants replaces references to constants with their values and folds the
+// subexpressions that are constant as a result into literals
+// Calls to JavaScript functions are only folded if they are marked pure, so expressions
+// calling impure ones only have their constants inlined. Folding is best-effort:
+// if it fails, such as on a division by zero, the checked AST is returned unchanged so
+// the error surfaces at evaluation time as usual
+func foldConstants(envState *EnvState, checked *cel.Ast) *cel.Ast {
+	optimizers := []cel.ASTOptimizer{constantInliner{}}
+	if !callsImpureFunction(envState, checked) {
+		folder, err := cel.NewConstantFoldingOptimizer()
+		if err != nil {
+			return checked
+		}
+		optimizers = append(optimizers, folder)
+	}
+
+	optimized, issues := cel.NewStaticOptimizer(optimizers...).Optimize(envState.env, checked)
+	if issues != nil && issues.Err() != nil {
+		return checked
+	}
+	return optimized
+}
+
+// callsImpureFunction reports whether a checked expression calls one of the environment's
+// JavaScript function implementations that isn't marked pure, or a system function such
+// as now()
+func callsImpureFunction(envState *EnvState, checked *cel.Ast) bool {
+	for _, reference := range checked.NativeRep().ReferenceMap() {
+		for _, overloadID := range reference.OverloadIDs {
+			if options.IsSystemOverload(overloadID) {
+				return true
+			}
+			for implID, pure := range envState.purity {
+				if !pure && strings.HasSuffix(overloadID, "_"+implID) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// constantInliner replaces identifiers that resolve to constants with their values
+type constantInliner struct{}
+
+// Optimize implements cel.ASTOptimizer
+func (constantInliner) Optimize(ctx *cel.OptimizerContext, a *ast.AST) *ast.AST {
+	references := a.ReferenceMap()
+	idents := ast.MatchDescendants(ast.NavigateAST(a), func(e ast.NavigableExpr) bool {
+		reference, ok := references[e.ID()]
+		return e.Kind() == ast.IdentKind && ok && reference.Value != nil
+	})
+	for _, ident := range idents {
+		if expr, ok := constantExpr(ctx, references[ident.ID()].Value); ok {
+			ctx.UpdateExpr(ident, expr)
+		}
+	}
+	return a
+}
+
+// constantExpr builds the expression for a constant value
+// Values without a literal form in CEL syntax are left as references
+func constantExpr(ctx *cel.OptimizerContext, val ref.Val) (ast.Expr, bool) {
+	switch val.Type() {
+	case types.BoolType, types.BytesType, types.DoubleType, types.IntType,
+		types.NullType, types.StringType, types.UintType:
+		return ctx.NewLiteral(val), true
+	case types.TimestampType, types.DurationType:
+		function := overloads.TypeConvertTimestamp
+		if val.Type() == types.DurationType {
+			function = overloads.TypeConvertDuration
+		}
+		return ctx.NewCall(function, ctx.NewLiteral(val.ConvertToType(types.StringType))), true
+	case types.ListType:
+		list := val.(traits.Lister)
+		elems := make([]ast.Expr, 0, int64(list.Size().(types.Int)))
+		for it := list.Iterator(); it.HasNext() == types.True; {
+			elem, ok := constantExpr(ctx, it.Next())
+			if !ok {
+				return nil, false
+			}
+			elems = append(elems, elem)
+		}
+		return ctx.NewList(elems, []int32{}), true
+	case types.MapType:
+		m := val.(traits.Mapper)
+		entries := make([]ast.EntryExpr, 0, int64(m.Size().(types.Int)))
+		for it := m.Iterator(); it.HasNext() == types.True; {
+			key := it.Next()
+			keyExpr, ok := constantExpr(ctx, key)
+			if !ok {
+				return nil, false
+			}
+			valueExpr, ok := constantExpr(ctx, m.Get(key))
+			if !ok {
+				return nil, false
+			}
+			entries = append(entries, ctx.NewMapEntry(keyExpr, valueExpr, false))
+		}
+		return ctx.NewMap(entries), true
+	}
+	return nil, false
+}
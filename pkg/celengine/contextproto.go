@@ -0,0 +1,99 @@
+package celengine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encodings accepted by EvalWithContextMessage
+const (
+	ContextMessageBinary = "binary" // Protobuf wire format
+	ContextMessageJSON   = "json"   // Protobuf JSON mapping
+)
+
+// EvalWithContextMessage evaluates a program using the fields of a protobuf message as its
+// variables. The program's environment should declare typeName with the DeclareContextProto
+// option, so each top-level field of the message is available as a variable.
+func EvalWithContextMessage(programID string, typeName string, message []byte, encoding string, options EvalOptions) map[string]interface{} {
+	if err := options.ValueEncoding.validate(); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+	touchProgram(programState)
+
+	envState, ok := envs.get(programState.envID)
+	if !ok {
+		return envs.notFound(programState.envID)
+	}
+
+	timings := newCallMetrics(options.Metrics)
+	evalMetrics = timings
+	defer func() { evalMetrics = nil }()
+
+	msg, err := newContextMessage(envState.env, typeName)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	switch encoding {
+	case ContextMessageBinary:
+		err = proto.Unmarshal(message, msg)
+	case ContextMessageJSON:
+		err = protojson.Unmarshal(message, msg)
+	default:
+		return map[string]interface{}{
+			"error": fmt.Sprintf("invalid context message encoding %q: expected %q or %q", encoding, ContextMessageBinary, ContextMessageJSON),
+		}
+	}
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to decode context message %s: %v", typeName, err),
+		}
+	}
+
+	activation, err := cel.ContextProtoVars(msg)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to create context variables: %v", err),
+		}
+	}
+
+	options.ValueEncoding = programState.inputs.semantics.withDefaults(options.ValueEncoding)
+	return evalActivation(programState, activation, options, timings)
+}
+
+// newContextMessage creates an empty message of a type known to the environment
+func newContextMessage(env *cel.Env, typeName string) (proto.Message, error) {
+	zero := env.CELTypeProvider().NewValue(typeName, nil)
+	msg, ok := zero.Value().(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("unknown context message type: %s", typeName)
+	}
+	return msg.ProtoReflect().New().Interface(), nil
+}
+
+// messageToJSON converts a protobuf message to its JSON mapping
+func messageToJSON(msg proto.Message) (interface{}, error) {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,363 @@
+package celengine
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// DecimalType is the CEL type of exact decimal numbers, for amounts like money that doubles
+// can't represent exactly
+var DecimalType = types.NewOpaqueType("decimal")
+
+// decimalRuntimeType is the type of Decimal values during evaluation. Its traits let the
+// standard operators dispatch to Decimal's methods, which an opaque type can't declare
+var decimalRuntimeType = types.NewObjectType(DecimalType.TypeName(),
+	traits.AdderType, traits.SubtractorType, traits.MultiplierType, traits.DividerType,
+	traits.NegatorType, traits.ComparerType)
+
+// decimalDivisionDigits is how many more fractional digits than its operands a quotient
+// keeps before it's rounded
+const decimalDivisionDigits = 18
+
+// decimalMaxDigits bounds the exponents of parsed decimals and the places of round(), so
+// a short input can't expand to an enormous number
+const decimalMaxDigits = 1000
+
+// Decimal is an exact decimal number, an unscaled integer divided by 10^scale
+// The scale is kept through arithmetic like fixed-point amounts, so 1.10 + 2.20 is 3.30
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+var bigTen = big.NewInt(10)
+
+// ParseDecimal parses a decimal number such as "-12.30" or "1.5e3"
+func ParseDecimal(text string) (Decimal, error) {
+	mantissa, exponent := text, int64(0)
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		var err error
+		if exponent, err = strconv.ParseInt(text[i+1:], 10, 32); err != nil {
+			return Decimal{}, fmt.Errorf("invalid exponent in %q", text)
+		}
+		mantissa = text[:i]
+	}
+
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") || strings.HasPrefix(mantissa, "+") {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	digits := whole + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", text)
+	}
+
+	scale := int64(len(fraction)) - exponent
+	if scale < -decimalMaxDigits || scale > decimalMaxDigits+int64(len(fraction)) {
+		return Decimal{}, fmt.Errorf("decimal %q is out of range", text)
+	}
+	unscaled, _ := new(big.Int).SetString(sign+digits, 10)
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+// pow10 returns 10^n
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(n), nil)
+}
+
+// String formats the decimal with its scale's number of fractional digits
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	sign := ""
+	if d.unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// rescale returns the unscaled value of d at a larger scale
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale == d.scale {
+		return d.unscaled
+	}
+	return new(big.Int).Mul(d.unscaled, pow10(int64(scale-d.scale)))
+}
+
+// aligned returns the unscaled values of two decimals at their larger scale
+func aligned(a, b Decimal) (*big.Int, *big.Int, int32) {
+	scale := max(a.scale, b.scale)
+	return a.rescale(scale), b.rescale(scale), scale
+}
+
+// Round rounds the decimal to a number of fractional digits, half to even
+func (d Decimal) Round(places int32) Decimal {
+	if places >= d.scale {
+		return Decimal{unscaled: d.rescale(places), scale: places}
+	}
+	divisor := pow10(int64(d.scale - places))
+	quotient, remainder := new(big.Int).QuoRem(d.unscaled, divisor, new(big.Int))
+	roundHalfEven(quotient, remainder, divisor, d.unscaled.Sign())
+	return Decimal{unscaled: quotient, scale: places}
+}
+
+// roundHalfEven adjusts a truncated quotient for its remainder, rounding half to even
+func roundHalfEven(quotient, remainder, divisor *big.Int, sign int) {
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	cmp := twice.CmpAbs(divisor)
+	if cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1) {
+		if sign < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+}
+
+// trimmed drops trailing fractional zeros down to a minimum scale
+func (d Decimal) trimmed(minScale int32) Decimal {
+	unscaled, scale := new(big.Int).Set(d.unscaled), d.scale
+	remainder := new(big.Int)
+	for scale > minScale {
+		quotient, rem := new(big.Int).QuoRem(unscaled, bigTen, remainder)
+		if rem.Sign() != 0 {
+			break
+		}
+		unscaled, scale = quotient, scale-1
+	}
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+// Add implements traits.Adder
+func (d Decimal) Add(other ref.Val) ref.Val {
+	o, ok := other.(Decimal)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(other)
+	}
+	a, b, scale := aligned(d, o)
+	return Decimal{unscaled: new(big.Int).Add(a, b), scale: scale}
+}
+
+// Subtract implements traits.Subtractor
+func (d Decimal) Subtract(other ref.Val) ref.Val {
+	o, ok := other.(Decimal)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(other)
+	}
+	a, b, scale := aligned(d, o)
+	return Decimal{unscaled: new(big.Int).Sub(a, b), scale: scale}
+}
+
+// Multiply implements traits.Multiplier
+func (d Decimal) Multiply(other ref.Val) ref.Val {
+	o, ok := other.(Decimal)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(other)
+	}
+	return Decimal{unscaled: new(big.Int).Mul(d.unscaled, o.unscaled), scale: d.scale + o.scale}
+}
+
+// Divide implements traits.Divider
+// Quotients keep decimalDivisionDigits more fractional digits than the operands, rounded
+// half to even, without trailing zeros beyond the operands' scale
+func (d Decimal) Divide(other ref.Val) ref.Val {
+	o, ok := other.(Decimal)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(other)
+	}
+	if o.unscaled.Sign() == 0 {
+		return types.NewErr("division by zero")
+	}
+
+	// d / o at scale s is d.unscaled * 10^(s - d.scale + o.scale) / o.unscaled
+	minScale := max(d.scale, o.scale)
+	scale := minScale + decimalDivisionDigits
+	numerator := new(big.Int).Mul(d.unscaled, pow10(int64(scale-d.scale+o.scale)))
+	quotient, remainder := new(big.Int).QuoRem(numerator, o.unscaled, new(big.Int))
+	roundHalfEven(quotient, remainder, o.unscaled, numerator.Sign()*o.unscaled.Sign())
+	return Decimal{unscaled: quotient, scale: scale}.trimmed(minScale)
+}
+
+// Negate implements traits.Negater
+func (d Decimal) Negate() ref.Val {
+	return Decimal{unscaled: new(big.Int).Neg(d.unscaled), scale: d.scale}
+}
+
+// Compare implements traits.Comparer
+func (d Decimal) Compare(other ref.Val) ref.Val {
+	o, ok := other.(Decimal)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(other)
+	}
+	a, b, _ := aligned(d, o)
+	return types.Int(a.Cmp(b))
+}
+
+// ConvertToNative implements ref.Val, converting to a string or a Decimal
+func (d Decimal) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	switch {
+	case typeDesc == reflect.TypeOf(d):
+		return d, nil
+	case typeDesc.Kind() == reflect.String:
+		return d.String(), nil
+	case typeDesc.Kind() == reflect.Interface && reflect.TypeOf(d).Implements(typeDesc):
+		return d, nil
+	}
+	return nil, fmt.Errorf("type conversion error from decimal to '%v'", typeDesc)
+}
+
+// ConvertToType implements ref.Val
+func (d Decimal) ConvertToType(typeVal ref.Type) ref.Val {
+	switch typeVal.TypeName() {
+	case DecimalType.TypeName():
+		return d
+	case types.StringType.TypeName():
+		return types.String(d.String())
+	case types.DoubleType.TypeName():
+		value, _ := strconv.ParseFloat(d.String(), 64)
+		return types.Double(value)
+	case types.TypeType.TypeName():
+		return decimalRuntimeType
+	}
+	return types.NewErr("type conversion error from '%s' to '%s'", DecimalType, typeVal)
+}
+
+// Equal implements ref.Val, comparing by value so 1.0 equals 1.00
+func (d Decimal) Equal(other ref.Val) ref.Val {
+	o, ok := other.(Decimal)
+	if !ok {
+		return types.False
+	}
+	a, b, _ := aligned(d, o)
+	return types.Bool(a.Cmp(b) == 0)
+}
+
+// Type implements ref.Val
+func (d Decimal) Type() ref.Type {
+	return decimalRuntimeType
+}
+
+// Value implements ref.Val, returning the decimal's text
+func (d Decimal) Value() any {
+	return d.String()
+}
+
+// decimalCodec encodes decimals as {"@type": "decimal", "value": "12.30"}
+type decimalCodec struct{}
+
+// Type implements ValueCodec
+func (decimalCodec) Type() *types.Type {
+	return DecimalType
+}
+
+// Decode implements ValueCodec
+func (decimalCodec) Decode(object map[string]interface{}) (ref.Val, error) {
+	text, ok := object["value"].(string)
+	if !ok || len(object) != 2 {
+		return nil, fmt.Errorf(`expected {"@type": "decimal", "value": "<digits>"}`)
+	}
+	d, err := ParseDecimal(text)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Encode implements ValueCodec
+func (decimalCodec) Encode(val ref.Val) map[string]interface{} {
+	return map[string]interface{}{
+		"@type": "decimal",
+		"value": val.(Decimal).String(),
+	}
+}
+
+// Declarations implements ValueCodec: conversions from strings, ints and doubles and back,
+// arithmetic, comparisons and round()
+func (decimalCodec) Declarations() []cel.EnvOption {
+	decimal := DecimalType
+	binary := func(op func(Decimal, ref.Val) ref.Val) cel.OverloadOpt {
+		return cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+			d, ok := lhs.(Decimal)
+			if !ok {
+				return types.MaybeNoSuchOverloadErr(lhs)
+			}
+			return op(d, rhs)
+		})
+	}
+
+	return []cel.EnvOption{
+		cel.Function("decimal",
+			cel.Overload("string_to_decimal", []*cel.Type{cel.StringType}, decimal,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					d, err := ParseDecimal(string(val.(types.String)))
+					if err != nil {
+						return types.NewErr("%v", err)
+					}
+					return d
+				})),
+			cel.Overload("int_to_decimal", []*cel.Type{cel.IntType}, decimal,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					return Decimal{unscaled: big.NewInt(int64(val.(types.Int)))}
+				})),
+			cel.Overload("double_to_decimal", []*cel.Type{cel.DoubleType}, decimal,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					value := float64(val.(types.Double))
+					if math.IsNaN(value) || math.IsInf(value, 0) {
+						return types.NewErr("can't convert %v to decimal", value)
+					}
+					d, _ := ParseDecimal(strconv.FormatFloat(value, 'f', -1, 64))
+					return d
+				})),
+		),
+		cel.Function("string",
+			cel.Overload("decimal_to_string", []*cel.Type{decimal}, cel.StringType,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					return val.ConvertToType(types.StringType)
+				}))),
+		cel.Function("double",
+			cel.Overload("decimal_to_double", []*cel.Type{decimal}, cel.DoubleType,
+				cel.UnaryBinding(func(val ref.Val) ref.Val {
+					return val.ConvertToType(types.DoubleType)
+				}))),
+		cel.Function("round",
+			cel.MemberOverload("decimal_round_int", []*cel.Type{decimal, cel.IntType}, decimal,
+				binary(func(d Decimal, places ref.Val) ref.Val {
+					n := int64(places.(types.Int))
+					if n < 0 || n > decimalMaxDigits {
+						return types.NewErr("round() places out of range: %d", n)
+					}
+					return d.Round(int32(n))
+				}))),
+		// The standard operators dispatch to Decimal's traits, so these only declare them
+		cel.Function("_+_", cel.Overload("add_decimal", []*cel.Type{decimal, decimal}, decimal)),
+		cel.Function("_-_", cel.Overload("subtract_decimal", []*cel.Type{decimal, decimal}, decimal)),
+		cel.Function("_*_", cel.Overload("multiply_decimal", []*cel.Type{decimal, decimal}, decimal)),
+		cel.Function("_/_", cel.Overload("divide_decimal", []*cel.Type{decimal, decimal}, decimal)),
+		cel.Function("-_", cel.Overload("negate_decimal", []*cel.Type{decimal}, decimal)),
+		cel.Function("_<_", cel.Overload("less_decimal", []*cel.Type{decimal, decimal}, cel.BoolType)),
+		cel.Function("_<=_", cel.Overload("less_equals_decimal", []*cel.Type{decimal, decimal}, cel.BoolType)),
+		cel.Function("_>_", cel.Overload("greater_decimal", []*cel.Type{decimal, decimal}, cel.BoolType)),
+		cel.Function("_>=_", cel.Overload("greater_equals_decimal", []*cel.Type{decimal, decimal}, cel.BoolType)),
+	}
+}
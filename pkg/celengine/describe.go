@@ -0,0 +1,112 @@
+package celengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// DescribeEnv describes what an environment declares: its variables and constants with
+// their types, its functions with the signature of each overload, the options it was
+// created and extended with, its libraries and its container
+// Editor tooling can render what's available from it without tracking the declarations
+// separately
+func DescribeEnv(envID string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	variables := make([]interface{}, 0)
+	declared := envState.env.Variables()
+	sort.Slice(declared, func(i, j int) bool { return declared[i].Name() < declared[j].Name() })
+	for _, variable := range declared {
+		// The standard library declares type names such as int as variables of type type
+		if variable.Type().Kind() == types.TypeKind {
+			continue
+		}
+		description := map[string]interface{}{
+			"name": variable.Name(),
+			"type": typeDescription(variable.Type()),
+		}
+		if variable.Value() != nil {
+			description["constant"] = true
+		}
+		variables = append(variables, description)
+	}
+
+	functions := make([]interface{}, 0)
+	funcs := envState.env.Functions()
+	names := make([]string, 0, len(funcs))
+	for name, function := range funcs {
+		if !function.IsDeclarationDisabled() && !isBatchFunction(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		overloads := make([]interface{}, 0)
+		signatures := overloadSignatures(funcs[name])
+		for _, overload := range funcs[name].OverloadDecls() {
+			params := make([]interface{}, 0, len(overload.ArgTypes()))
+			for _, arg := range overload.ArgTypes() {
+				params = append(params, typeDescription(arg))
+			}
+			overloads = append(overloads, map[string]interface{}{
+				"id":         overload.ID(),
+				"member":     overload.IsMemberFunction(),
+				"params":     params,
+				"resultType": typeDescription(overload.ResultType()),
+				"signature":  signatures[overload.ID()],
+			})
+		}
+		functions = append(functions, map[string]interface{}{
+			"name":      name,
+			"overloads": overloads,
+		})
+	}
+
+	return map[string]interface{}{
+		"variables":  variables,
+		"functions":  functions,
+		"options":    append(make([]interface{}, 0, len(envState.options)), envState.options...),
+		"libraries":  stringsToInterfaces(envState.libraries),
+		"container":  envState.env.Container.Name(),
+		"apiVersion": envState.inputs.semantics.version,
+		"error":      nil,
+	}
+}
+
+// typeDescription converts a type to the JSON form of variable declarations, or "dyn"
+// for types that form can't express
+func typeDescription(t *types.Type) interface{} {
+	exprType, err := cel.TypeToExprType(t)
+	if err != nil {
+		return "dyn"
+	}
+	return typeToJSON(exprType)
+}
+
+// optionConfigs decodes the options JSON an environment was created or extended with,
+// or nil if there is none
+func optionConfigs(optionsJSON *string) []interface{} {
+	if optionsJSON == nil || *optionsJSON == "" {
+		return nil
+	}
+	var configs []interface{}
+	if err := json.Unmarshal([]byte(*optionsJSON), &configs); err != nil {
+		return nil
+	}
+	return configs
+}
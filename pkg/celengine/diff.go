@@ -0,0 +1,208 @@
+package celengine
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+)
+
+// DiffExprs compares two expressions structurally, so reviews of rules can show what
+// changed in their meaning rather than in their text
+// Both expressions are checked in the environment and their ASTs walked together.
+// Subexpressions that differ are reported under "changes", outermost first, as "changed"
+// with their range in both expressions, or as "added" or "removed" arguments or elements
+// with their range in the expression that has them. Each range comes with its text and
+// the type the subexpression was checked to. Formatting, comments and redundant
+// parentheses aren't changes, and "equal" is true when there are none
+func DiffExprs(envID string, exprA string, exprB string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	astA, issues := envState.env.Compile(exprA)
+	if issues != nil && issues.Err() != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("compilation error in exprA: %v", issues.Err()),
+		}
+	}
+	astB, issues := envState.env.Compile(exprB)
+	if issues != nil && issues.Err() != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("compilation error in exprB: %v", issues.Err()),
+		}
+	}
+
+	d := &exprDiff{
+		before:  newParsedSource(exprA, astA),
+		after:   newParsedSource(exprB, astB),
+		changes: make([]interface{}, 0),
+	}
+	d.compare(d.before.expr, d.after.expr)
+
+	return map[string]interface{}{
+		"equal":   len(d.changes) == 0,
+		"changes": d.changes,
+		"error":   nil,
+	}
+}
+
+// exprDiff collects the changes between two expressions
+type exprDiff struct {
+	before, after *parsedSource
+	changes       []interface{}
+}
+
+// compare reports the changes between two corresponding subexpressions
+func (d *exprDiff) compare(a, b ast.Expr) {
+	if !sameNode(d.before.written(a), d.after.written(b)) {
+		d.report("changed", a, b)
+		return
+	}
+
+	subsA, subsB := d.before.subexpressions(a), d.after.subexpressions(b)
+	if len(subsA) == len(subsB) {
+		for i := range subsA {
+			d.compare(subsA[i], subsB[i])
+		}
+		return
+	}
+
+	// Subexpressions common to both are matched first, and those left between two matches
+	// are compared in order, so an argument inserted in a call is reported as added
+	// rather than as a change of every argument after it
+	i, j := 0, 0
+	for _, match := range d.commonSubexpressions(subsA, subsB) {
+		d.compareRun(subsA[i:match[0]], subsB[j:match[1]])
+		i, j = match[0]+1, match[1]+1
+	}
+	d.compareRun(subsA[i:], subsB[j:])
+}
+
+// compareRun compares runs of subexpressions between two matches in order, reporting
+// those left over in one run as removed or added
+func (d *exprDiff) compareRun(subsA, subsB []ast.Expr) {
+	for i := 0; i < len(subsA) || i < len(subsB); i++ {
+		switch {
+		case i >= len(subsB):
+			d.report("removed", subsA[i], nil)
+		case i >= len(subsA):
+			d.report("added", nil, subsB[i])
+		default:
+			d.compare(subsA[i], subsB[i])
+		}
+	}
+}
+
+// commonSubexpressions returns the indexes of the longest common subsequence of equal
+// subexpressions of two nodes
+func (d *exprDiff) commonSubexpressions(subsA, subsB []ast.Expr) [][2]int {
+	lengths := make([][]int, len(subsA)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(subsB)+1)
+	}
+	for i := len(subsA) - 1; i >= 0; i-- {
+		for j := len(subsB) - 1; j >= 0; j-- {
+			if d.equal(subsA[i], subsB[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var matches [][2]int
+	for i, j := 0, 0; i < len(subsA) && j < len(subsB); {
+		switch {
+		case d.equal(subsA[i], subsB[j]):
+			matches = append(matches, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// equal reports whether two subexpressions are structurally the same
+func (d *exprDiff) equal(a, b ast.Expr) bool {
+	if !sameNode(d.before.written(a), d.after.written(b)) {
+		return false
+	}
+	subsA, subsB := d.before.subexpressions(a), d.after.subexpressions(b)
+	if len(subsA) != len(subsB) {
+		return false
+	}
+	for i := range subsA {
+		if !d.equal(subsA[i], subsB[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// report adds a change, with the range of each side that has the subexpression
+func (d *exprDiff) report(kind string, a, b ast.Expr) {
+	change := map[string]interface{}{
+		"kind": kind,
+	}
+	if a != nil {
+		if span, ok := d.before.span(a); ok {
+			change["before"] = d.before.excerpt(a, span)
+		}
+	}
+	if b != nil {
+		if span, ok := d.after.span(b); ok {
+			change["after"] = d.after.excerpt(b, span)
+		}
+	}
+	d.changes = append(d.changes, change)
+}
+
+// excerpt returns the range of a subexpression in UTF-16 offsets along with its text and
+// its type
+func (s *parsedSource) excerpt(e ast.Expr, span sourceSpan) map[string]interface{} {
+	excerpt := s.rangeOf(span)
+	excerpt["text"] = string(s.runes[span.start:span.end])
+	if t, ok := s.types[e.ID()]; ok {
+		excerpt["type"] = t.String()
+	}
+	return excerpt
+}
+
+// sameNode reports whether two nodes are the same apart from their subexpressions: the
+// same call or field, identifier, literal value or type and fields of a message
+func sameNode(a, b ast.Expr) bool {
+	if !sameShape(a, b) {
+		return false
+	}
+	switch a.Kind() {
+	case ast.IdentKind:
+		return a.AsIdent() == b.AsIdent()
+	case ast.LiteralKind:
+		return a.AsLiteral().Type() == b.AsLiteral().Type() && a.AsLiteral().Equal(b.AsLiteral()) == types.True
+	case ast.StructKind:
+		fieldsA, fieldsB := a.AsStruct().Fields(), b.AsStruct().Fields()
+		if a.AsStruct().TypeName() != b.AsStruct().TypeName() || len(fieldsA) != len(fieldsB) {
+			return false
+		}
+		for i := range fieldsA {
+			if fieldsA[i].AsStructField().Name() != fieldsB[i].AsStructField().Name() {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+// Package celengine is the CEL engine behind the wasm-cel WebAssembly module.
+//
+// It exposes the same environment and program lifecycle as the JavaScript API
+// (CreateEnvWithConfig, ExtendEnv, Compile, Eval, DestroyProgram, DestroyEnv)
+// along with the same JSON type grammar, value conversion and option
+// configuration, so server-side Go code evaluates expressions with exactly the
+// semantics seen in the browser.
+//
+// Functions return the same result maps the WASM exports hand to JavaScript,
+// with an "error" key that is nil on success:
+//
+//	env := celengine.CreateEnv([]celengine.VarDecl{{Name: "x", Type: "double"}}, nil)
+//	if env["error"] != nil {
+//		return fmt.Errorf("%v", env["error"])
+//	}
+//	envID := env["envID"].(string)
+//	defer celengine.DestroyEnv(envID)
+//
+//	program := celengine.Compile(envID, "x * 2.0")
+//	result := celengine.Eval(program["programID"].(string), map[string]interface{}{"x": 21.0})
+//
+// Custom functions are declared with FunctionDef and dispatched by ImplID to
+// the JSFunctionCaller set with SetJSFunctionCaller. In the WASM build this
+// calls into JavaScript; host programs provide a Go implementation instead.
+package celengine
@@ -0,0 +1,357 @@
+package celengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// Map key modes for ValueEncoding.MapKeys
+const (
+	// MapKeysString stringifies all map keys, so {1: "a"} and {"1": "a"} both become {"1": "a"}
+	MapKeysString = "string"
+	// MapKeysEntries returns maps with non-string keys as {"@type": "map", "entries": [[key, value], ...]}
+	// with the entries sorted by key
+	MapKeysEntries = "entries"
+)
+
+// Non-finite double modes for ValueEncoding.NonFinite
+const (
+	// NonFiniteNumber returns NaN and ±Inf as plain numbers, which JavaScript handles but
+	// JSON can't represent
+	NonFiniteNumber = "number"
+	// NonFiniteTagged returns NaN and ±Inf as {"@type": "double", "value": "NaN"}, with
+	// "Infinity" and "-Infinity" for the infinities. Variables and function results in
+	// this form are decoded back to doubles
+	NonFiniteTagged = "tagged"
+	// NonFiniteString returns NaN and ±Inf as the strings "NaN", "Infinity" and "-Infinity"
+	NonFiniteString = "string"
+)
+
+// Integer modes for ValueEncoding.Integers
+const (
+	// IntegersNumber returns ints and uints as plain numbers, which JavaScript rounds
+	// beyond 2^53
+	IntegersNumber = "number"
+	// IntegersString returns ints and uints beyond 2^53 as decimal strings, and reads
+	// strings of digits in variables declared as ints or uints back as integers
+	IntegersString = "string"
+	// IntegersBigInt returns ints and uints beyond 2^53 as json.Number, which the WASM
+	// module passes to JavaScript as BigInts and JSON encodes as exact numbers. Variables
+	// are read like IntegersString
+	IntegersBigInt = "bigint"
+)
+
+// maxSafeInteger is the largest integer that JavaScript numbers represent exactly
+const maxSafeInteger = 1<<53 - 1
+
+// ValueEncoding controls how ValueToJSONWithEncoding represents values that JSON
+// objects and arrays can't express directly. The zero value matches ValueToJSON
+type ValueEncoding struct {
+	MapKeys   string `json:"mapKeys,omitempty"`
+	NonFinite string `json:"nonFinite,omitempty"`
+	Integers  string `json:"integers,omitempty"`
+}
+
+// validate reports unknown encoding modes
+func (e ValueEncoding) validate() error {
+	switch e.MapKeys {
+	case "", MapKeysString, MapKeysEntries:
+	default:
+		return fmt.Errorf("unknown mapKeys mode %q, expected %q or %q", e.MapKeys, MapKeysString, MapKeysEntries)
+	}
+	switch e.NonFinite {
+	case "", NonFiniteNumber, NonFiniteTagged, NonFiniteString:
+	default:
+		return fmt.Errorf("unknown nonFinite mode %q, expected %q, %q or %q", e.NonFinite, NonFiniteNumber, NonFiniteTagged, NonFiniteString)
+	}
+	switch e.Integers {
+	case "", IntegersNumber, IntegersString, IntegersBigInt:
+	default:
+		return fmt.Errorf("unknown integers mode %q, expected %q, %q or %q", e.Integers, IntegersNumber, IntegersString, IntegersBigInt)
+	}
+	return nil
+}
+
+// integerStrings reports whether variables declared as ints or uints may be strings
+func (e ValueEncoding) integerStrings() bool {
+	return e.Integers == IntegersString || e.Integers == IntegersBigInt
+}
+
+// encodeInt converts an int, representing integers beyond 2^53 as configured by encoding
+func encodeInt(value int64, encoding ValueEncoding) interface{} {
+	if value >= -maxSafeInteger && value <= maxSafeInteger {
+		return value
+	}
+	return encodeUnsafeInteger(value, strconv.FormatInt(value, 10), encoding)
+}
+
+// encodeUint converts a uint like encodeInt
+func encodeUint(value uint64, encoding ValueEncoding) interface{} {
+	if value <= maxSafeInteger {
+		return value
+	}
+	return encodeUnsafeInteger(value, strconv.FormatUint(value, 10), encoding)
+}
+
+// encodeUnsafeInteger represents an integer beyond 2^53, given with its decimal digits
+func encodeUnsafeInteger(value interface{}, digits string, encoding ValueEncoding) interface{} {
+	switch encoding.Integers {
+	case IntegersString:
+		return digits
+	case IntegersBigInt:
+		return json.Number(digits)
+	}
+	return value
+}
+
+// encodeDouble converts a double, representing NaN and ±Inf as configured by encoding
+func encodeDouble(value float64, encoding ValueEncoding) interface{} {
+	if !math.IsNaN(value) && !math.IsInf(value, 0) {
+		return value
+	}
+
+	// FormatFloat writes "+Inf" and "-Inf", JavaScript's String() gives "Infinity" and "-Infinity"
+	token := "NaN"
+	if math.IsInf(value, 1) {
+		token = "Infinity"
+	} else if math.IsInf(value, -1) {
+		token = "-Infinity"
+	}
+
+	switch encoding.NonFinite {
+	case NonFiniteTagged:
+		return map[string]interface{}{
+			"@type": "double",
+			"value": token,
+		}
+	case NonFiniteString:
+		return token
+	}
+	return value
+}
+
+// decodeTaggedDouble returns the double held by a {"@type": "double", "value": "..."} object
+func decodeTaggedDouble(object map[string]interface{}) (float64, bool) {
+	if len(object) != 2 || object["@type"] != "double" {
+		return 0, false
+	}
+	token, ok := object["value"].(string)
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// decodeIntegerStrings replaces strings of digits in variables declared as ints or uints,
+// including inside declared lists and maps, with the integers they hold
+// Like decodeTaggedValues, the variables are only copied if something changed. Strings
+// that aren't integers are kept, for strict evaluations to report
+func decodeIntegerStrings(vars map[string]interface{}, declared map[string]*types.Type) (map[string]interface{}, bool) {
+	var result map[string]interface{}
+	for name, declaredType := range declared {
+		value, ok := vars[name]
+		if !ok {
+			continue
+		}
+		decoded, changed := decodeIntegerString(value, declaredType)
+		if !changed {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]interface{}, len(vars))
+			for k, original := range vars {
+				result[k] = original
+			}
+		}
+		result[name] = decoded
+	}
+	if result == nil {
+		return vars, false
+	}
+	return result, true
+}
+
+// decodeIntegerString applies decodeIntegerStrings to a value of a declared type
+func decodeIntegerString(value interface{}, declared *types.Type) (interface{}, bool) {
+	switch declared.Kind() {
+	case types.IntKind:
+		if text, ok := value.(string); ok {
+			if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return i, true
+			}
+		}
+	case types.UintKind:
+		if text, ok := value.(string); ok {
+			if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+				return u, true
+			}
+		}
+	case types.ListKind:
+		items, ok := value.([]interface{})
+		if !ok {
+			break
+		}
+		var result []interface{}
+		for i, item := range items {
+			decoded, changed := decodeIntegerString(item, declared.Parameters()[0])
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = append([]interface{}(nil), items...)
+			}
+			result[i] = decoded
+		}
+		if result != nil {
+			return result, true
+		}
+	case types.MapKind:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		elemTypes := make(map[string]*types.Type, len(entries))
+		for key := range entries {
+			elemTypes[key] = declared.Parameters()[1]
+		}
+		return decodeIntegerStrings(entries, elemTypes)
+	}
+	return value, false
+}
+
+// hasNonStringKeys reports whether any key of the map is not a string
+func hasNonStringKeys(mapper traits.Mapper) bool {
+	it := mapper.Iterator()
+	for it.HasNext() == types.True {
+		if _, ok := it.Next().(types.String); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mapEntries converts a map to a tagged list of [key, value] pairs that keeps the key types
+func mapEntries(mapper traits.Mapper, encoding ValueEncoding) map[string]interface{} {
+	keys := make([]ref.Val, 0)
+	it := mapper.Iterator()
+	for it.HasNext() == types.True {
+		keys = append(keys, it.Next())
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return mapKeyLess(keys[i], keys[j])
+	})
+
+	entries := make([]interface{}, len(keys))
+	for i, key := range keys {
+		entries[i] = []interface{}{
+			ValueToJSONWithEncoding(key, encoding),
+			ValueToJSONWithEncoding(mapper.Get(key), encoding),
+		}
+	}
+	return map[string]interface{}{
+		"@type":   "map",
+		"entries": entries,
+	}
+}
+
+// mapKeyLess orders map keys by type (bool, int, uint, string) and then by value
+// so entries come out the same on every evaluation
+func mapKeyLess(a, b ref.Val) bool {
+	rankA, rankB := mapKeyRank(a), mapKeyRank(b)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+	switch a := a.(type) {
+	case types.Bool:
+		return !bool(a) && bool(b.(types.Bool))
+	case types.Int:
+		return a < b.(types.Int)
+	case types.Uint:
+		return a < b.(types.Uint)
+	case types.String:
+		return a < b.(types.String)
+	}
+	return fmt.Sprintf("%v", a.Value()) < fmt.Sprintf("%v", b.Value())
+}
+
+// mapKeyRank returns the position of a key's type in the entry order
+func mapKeyRank(key ref.Val) int {
+	switch key.(type) {
+	case types.Bool:
+		return 0
+	case types.Int:
+		return 1
+	case types.Uint:
+		return 2
+	case types.String:
+		return 3
+	}
+	return 4
+}
+
+// decodeTaggedValues replaces tagged doubles in a JSON value with float64s, and objects
+// tagged with the type of a registered value codec with their values
+// Containers are only copied when something inside them changed, so the caller's
+// values are never modified and untagged input isn't copied at all
+func decodeTaggedValues(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if decoded, ok := decodeTaggedDouble(v); ok {
+			return decoded, true
+		}
+		if decoded, ok := decodeCodecValue(v); ok {
+			return decoded, true
+		}
+		return decodeTaggedMap(v)
+	case []interface{}:
+		var result []interface{}
+		for i, item := range v {
+			decoded, changed := decodeTaggedValues(item)
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = append([]interface{}(nil), v...)
+			}
+			result[i] = decoded
+		}
+		if result == nil {
+			return value, false
+		}
+		return result, true
+	}
+	return value, false
+}
+
+// decodeTaggedMap applies decodeTaggedValues to the values of a map
+func decodeTaggedMap(object map[string]interface{}) (map[string]interface{}, bool) {
+	var result map[string]interface{}
+	for key, item := range object {
+		decoded, changed := decodeTaggedValues(item)
+		if !changed {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]interface{}, len(object))
+			for k, original := range object {
+				result[k] = original
+			}
+		}
+		result[key] = decoded
+	}
+	if result == nil {
+		return object, false
+	}
+	return result, true
+}
@@ -0,0 +1,101 @@
+package celengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/google/cel-go/cel"
+)
+
+// pooledEnv is a CEL environment shared by every env created with the same configuration
+type pooledEnv struct {
+	env      *cel.Env
+	refCount int
+}
+
+// envPool maps configuration keys to shared environments
+// cel.Env is immutable once built, so sharing is safe; ExtendEnv never modifies a
+// shared env but moves the extended environment to the env for the extended configuration
+// (copy-on-extend)
+var envPool = make(map[string]*pooledEnv)
+
+// envPoolKey hashes an environment configuration
+// Function definitions include their implementation IDs, so envs only share
+// bindings when they call the same JavaScript functions
+func envPoolKey(varDecls []VarDecl, constants []ConstantDecl, funcDefs []FunctionDef, libraryNames []string, optionsJSON *string, absentVariables string, apiVersion int) (string, bool) {
+	config := struct {
+		VarDecls  []VarDecl      `json:"varDecls"`
+		Constants []ConstantDecl `json:"constants,omitempty"`
+		FuncDefs  []FunctionDef  `json:"funcDefs"`
+		Libraries []string       `json:"libraries,omitempty"`
+		Options   string         `json:"options"`
+		Absent    string         `json:"absent"`
+		Version   int            `json:"apiVersion"`
+	}{
+		VarDecls:  varDecls,
+		Constants: constants,
+		FuncDefs:  funcDefs,
+		Libraries: libraryNames,
+		Absent:    absentVariables,
+		Version:   apiVersion,
+	}
+	if optionsJSON != nil {
+		config.Options = *optionsJSON
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// extendedPoolKey derives the key of a shared environment extended with options
+func extendedPoolKey(parentKey string, optionsJSON string) string {
+	sum := sha256.Sum256([]byte(parentKey + "\x00" + optionsJSON))
+	return hex.EncodeToString(sum[:])
+}
+
+// acquirePooledEnv returns the shared environment for a key and takes a reference to it
+func acquirePooledEnv(key string) (*cel.Env, bool) {
+	pooled, ok := envPool[key]
+	if !ok {
+		return nil, false
+	}
+	pooled.refCount++
+	return pooled.env, true
+}
+
+// addPooledEnv stores a newly built environment under its key with one reference
+func addPooledEnv(key string, env *cel.Env) {
+	envPool[key] = &pooledEnv{env: env, refCount: 1}
+}
+
+// releasePooledEnv drops a reference to a shared environment, evicting it when unused
+func releasePooledEnv(key string) {
+	if key == "" {
+		return
+	}
+	pooled, ok := envPool[key]
+	if !ok {
+		return
+	}
+	pooled.refCount--
+	if pooled.refCount <= 0 {
+		delete(envPool, key)
+		emitLifecycleEvent(LifecycleCacheEvicted, map[string]interface{}{"cache": "envPool"})
+	}
+}
+
+// removeEnv deletes an environment from the registry and releases its pooled env and
+// shared type registry
+func removeEnv(envID string, envState *EnvState) {
+	releasePooledEnv(envState.poolKey)
+	envState.poolKey = ""
+	releaseTypeRegistry(envState.typesKey)
+	envState.typesKey = ""
+	envs.remove(envID)
+	emitLifecycleEvent(LifecycleEnvDestroyed, map[string]interface{}{"envID": envID})
+}
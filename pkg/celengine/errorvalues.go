@@ -0,0 +1,72 @@
+package celengine
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// unknownPatterns converts attribute paths such as "request.auth" into attribute patterns
+// A "*" segment matches any field or index
+func unknownPatterns(paths []string) []*cel.AttributePatternType {
+	patterns := make([]*cel.AttributePatternType, 0, len(paths))
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		pattern := cel.AttributePattern(segments[0])
+		for _, segment := range segments[1:] {
+			if segment == "*" {
+				pattern = pattern.Wildcard()
+			} else {
+				pattern = pattern.QualString(segment)
+			}
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// errorValueToJSON describes an error value, with the location of the expression that
+// produced it when the program's AST knows it, and its code if a custom function reported it
+func errorValueToJSON(errVal *types.Err, ast *cel.Ast) map[string]interface{} {
+	result := map[string]interface{}{
+		"message": errVal.Error(),
+	}
+	var fnErr *FunctionError
+	if errors.As(errVal, &fnErr) {
+		result["code"] = fnErr.Code
+	}
+	if id := errVal.NodeID(); id != 0 {
+		result["exprID"] = id
+		if ast != nil {
+			if location := ast.NativeRep().SourceInfo().GetStartLocation(id); location.Line() > 0 {
+				result["location"] = map[string]interface{}{
+					"line":   location.Line(),
+					"column": location.Column(),
+				}
+			}
+		}
+	}
+	return result
+}
+
+// unknownAttributes lists the attribute paths an unknown result depends on, sorted and
+// without duplicates
+func unknownAttributes(unknown *types.Unknown) []interface{} {
+	seen := make(map[string]bool)
+	for _, id := range unknown.IDs() {
+		trails, _ := unknown.GetAttributeTrails(id)
+		for _, trail := range trails {
+			seen[trail.String()] = true
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return stringsToInterfaces(paths)
+}
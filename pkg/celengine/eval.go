@@ -0,0 +1,1775 @@
+package celengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	commonTypes "github.com/invakid404/wasm-cel/internal/common"
+	"github.com/invakid404/wasm-cel/internal/logging"
+	"github.com/invakid404/wasm-cel/internal/wasmenv"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+// FunctionDef represents a custom function definition from JavaScript
+type FunctionDef struct {
+	Name       string      `json:"name"`
+	Params     []ParamDef  `json:"params"`
+	ReturnType interface{} `json:"returnType"`       // Can be string or map[string]interface{}
+	ImplID     string      `json:"implID"`           // ID to identify the JS function implementation
+	IsPure     bool        `json:"isPure,omitempty"` // Whether the implementation depends only on its arguments, so it may be folded and memoized
+	// Cacheable caches the results of calls within an evaluation, keyed by a hash of the
+	// arguments, so repeated calls with the same arguments call the implementation once
+	Cacheable bool `json:"cacheable,omitempty"`
+	// CacheSize also keeps up to this many results of a Cacheable function across
+	// evaluations, evicting the least recently used
+	CacheSize int `json:"cacheSize,omitempty"`
+	// BatchImplID identifies an implementation called once with the argument lists of
+	// the calls a map() makes, such as list.map(x, f(x)), returning their results
+	BatchImplID string `json:"batchImplID,omitempty"`
+}
+
+// ParamDef represents a function parameter definition
+type ParamDef struct {
+	Name     string      `json:"name"`
+	Type     interface{} `json:"type"` // Can be string or map[string]interface{}
+	Optional bool        `json:"optional,omitempty"`
+}
+
+// JSFunctionCaller is an interface for calling JavaScript functions from Go
+// This allows the cel package to be testable without syscall/js dependency
+type JSFunctionCaller interface {
+	CallJSFunction(implID string, args []interface{}) (interface{}, error)
+}
+
+// Global registry to store JavaScript function callers
+// This is set by the WASM layer
+var jsFunctionCaller JSFunctionCaller
+
+// SetJSFunctionCaller sets the JavaScript function caller
+// This is called from the WASM layer
+func SetJSFunctionCaller(caller JSFunctionCaller) {
+	jsFunctionCaller = caller
+}
+
+// Global registry for compilation contexts using the "Filename Side-Channel" pattern
+// Maps unique compilation ID -> issue collector
+var compilationRegistry sync.Map
+
+// Use common types to avoid duplication
+type CompilationIssueCollector = commonTypes.CompilationIssueCollector
+
+// CompilationIssueCollectorImpl implements CompilationIssueCollector
+type CompilationIssueCollectorImpl struct {
+	issues []ValidatorIssue
+}
+
+func (c *CompilationIssueCollectorImpl) AddValidatorIssue(issue ValidatorIssue) {
+	c.issues = append(c.issues, issue)
+}
+
+func (c *CompilationIssueCollectorImpl) GetValidatorIssues() []ValidatorIssue {
+	return c.issues
+}
+
+// NewCompilationIssueCollector creates a new compilation-scoped issue collector
+func NewCompilationIssueCollector() CompilationIssueCollector {
+	return &CompilationIssueCollectorImpl{
+		issues: make([]ValidatorIssue, 0),
+	}
+}
+
+// RegisterCompilationContext registers a compilation context with a unique ID
+func RegisterCompilationContext(compilationID string, collector CompilationIssueCollector) {
+	compilationRegistry.Store(compilationID, collector)
+}
+
+// GetCompilationContext retrieves a compilation context by ID
+func GetCompilationContext(compilationID string) CompilationIssueCollector {
+	if val, ok := compilationRegistry.Load(compilationID); ok {
+		return val.(CompilationIssueCollector)
+	}
+	return nil
+}
+
+// GetCompilationContextAdder retrieves a compilation context by ID as an adder interface
+// This is used by the options package which only needs to add issues
+func GetCompilationContextAdder(compilationID string) commonTypes.CompilationIssueAdder {
+	return GetCompilationContext(compilationID)
+}
+
+// UnregisterCompilationContext removes a compilation context (important for cleanup)
+func UnregisterCompilationContext(compilationID string) {
+	compilationRegistry.Delete(compilationID)
+}
+
+type ValidatorIssue = commonTypes.ValidatorIssue
+
+// EnvState holds a CEL environment
+type EnvState struct {
+	env       *cel.Env
+	implIDs   []string        // Track function implementation IDs for cleanup
+	purity    map[string]bool // Whether each function implementation, including global ones, is pure
+	destroyed bool            // Track if environment has been destroyed
+	poolKey   string          // Key of the shared pooled env, empty once extended
+	typesKey  string          // Key of the shared type registry of the env's context proto, if any
+	inputs    *envInputs      // Defaults and absent variable policy of evaluations
+	options   []interface{}   // Options the environment was created and extended with, as configured
+	libraries []string        // Names of the libraries the environment includes
+	lastUsed  time.Time       // Last time the environment was used, for TTL cleanup
+}
+
+// ProgramState holds a compiled CEL program
+type ProgramState struct {
+	prg       cel.Program
+	ast       *cel.Ast               // Checked AST, used to locate error values
+	envID     string                 // Track which environment created this program
+	memo      *memoCache             // Cached responses, if the program was compiled with memoization
+	inputs    *envInputs             // Defaults and absent variable policy of the environment
+	source    *programSource         // How the program was compiled, to compile it again
+	variables map[string]*types.Type // Types of the variables the program reads, computed for strict evaluations
+	patterns  *unknownPatternSet     // Attribute patterns of the unknowns of the last evaluation with unknowns
+	lastUsed  time.Time              // Last time the program was used, for TTL cleanup
+}
+
+// FunctionRefCount tracks reference counts for function implementations
+type FunctionRefCount struct {
+	refCount int    // Number of live environments declaring this function and programs that might use it
+	envID    string // Which environment first declared this function
+}
+
+// Global registries for environments and programs
+var (
+	envs                 = newHandleTable[EnvState]("environment", "env")
+	programs             = newHandleTable[ProgramState]("program", "prg")
+	functionRefs         = make(map[string]*FunctionRefCount) // Track function reference counts
+	compilationIDCounter int64
+)
+
+// VarDecl represents a variable declaration with a name and type
+type VarDecl struct {
+	Name string      `json:"name"`
+	Type interface{} `json:"type"` // Can be string or map[string]interface{}
+	// Default is the value of the variable when an evaluation doesn't provide it
+	Default interface{} `json:"default,omitempty"`
+}
+
+// CreateEnv creates a new CEL environment with variable declarations and function definitions
+// Returns an environment ID that can be used for compilation
+func CreateEnv(varDecls []VarDecl, funcDefs []FunctionDef) map[string]interface{} {
+	return CreateEnvWithOptions(varDecls, funcDefs, nil)
+}
+
+// ExtendEnv extends an existing environment with additional options
+// This allows adding options that require JavaScript functions after the environment is created
+func ExtendEnv(envID string, optionsJSON string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	// Environments extended the same way from the same shared env share the result
+	var poolKey string
+	if envState.poolKey != "" {
+		poolKey = extendedPoolKey(envState.poolKey, optionsJSON)
+		if env, ok := acquirePooledEnv(poolKey); ok {
+			releasePooledEnv(envState.poolKey)
+			envState.env = env
+			envState.poolKey = poolKey
+			envState.options = append(envState.options, optionConfigs(&optionsJSON)...)
+			if encoded, ok := contextDescriptorSet(&optionsJSON); ok && retainTypeRegistry(typeRegistryKey(encoded)) {
+				releaseTypeRegistry(envState.typesKey)
+				envState.typesKey = typeRegistryKey(encoded)
+			}
+
+			return map[string]interface{}{
+				"success": true,
+				"error":   nil,
+			}
+		}
+	}
+
+	// Parse and create the new options with environment ID
+	envOptions, err := wasmenv.CreateOptionsFromJSONWithEnvID(optionsJSON, envID)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to create environment options: %v", err),
+		}
+	}
+
+	// A context proto declared from a descriptor set shares its type registry
+	sharedOpt, typesKey := contextTypes(&optionsJSON)
+	if keepOpt := keepObjectTypes(envState.env); keepOpt != nil {
+		envOptions = append([]cel.EnvOption{keepOpt}, envOptions...)
+	}
+	if sharedOpt != nil {
+		envOptions = append([]cel.EnvOption{sharedOpt}, envOptions...)
+	}
+
+	// Extend the existing environment with new options
+	newEnv, err := envState.env.Extend(envOptions...)
+	if err != nil {
+		releaseTypeRegistry(typesKey)
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to extend environment: %v", err),
+		}
+	}
+
+	// Replace the environment pointer with the extended environment
+	// The env it was extended from is left untouched for the other envs sharing it
+	envState.env = newEnv
+	envState.options = append(envState.options, optionConfigs(&optionsJSON)...)
+	releasePooledEnv(envState.poolKey)
+	envState.poolKey = poolKey
+	if typesKey != "" {
+		releaseTypeRegistry(envState.typesKey)
+		envState.typesKey = typesKey
+	}
+	if poolKey != "" {
+		addPooledEnv(poolKey, newEnv)
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// CreateEnvWithOptions creates a new CEL environment with variable declarations, function definitions, and environment options
+// Returns an environment ID that can be used for compilation
+func CreateEnvWithOptions(varDecls []VarDecl, funcDefs []FunctionDef, optionsJSON *string) map[string]interface{} {
+	config := EnvConfig{VarDecls: varDecls, FuncDefs: funcDefs}
+	if optionsJSON != nil {
+		config.Options = json.RawMessage(*optionsJSON)
+	}
+	return CreateEnvWithConfig(config)
+}
+
+// EnvConfig configures a new CEL environment
+type EnvConfig struct {
+	// VarDecls declares the variables of the environment
+	VarDecls []VarDecl `json:"varDecls"`
+	// Constants are compile-time constants folded into expressions when they are compiled
+	Constants []ConstantDecl `json:"constants"`
+	// FuncDefs declares the custom functions of the environment
+	FuncDefs []FunctionDef `json:"funcDefs"`
+	// Libraries are the names of registered libraries the environment includes
+	Libraries []string `json:"libraries"`
+	// Options are the environment options, as a JSON array
+	Options json.RawMessage `json:"options"`
+	// AbsentVariables is the policy for variables that an evaluation doesn't provide and
+	// that have no default: AbsentVariablesError (the default), AbsentVariablesNull or
+	// AbsentVariablesUnknown
+	AbsentVariables string `json:"absentVariables"`
+	// APIVersion pins the environment to an API version: the grammar of the types in its
+	// declarations, the default value encoding of its evaluations and the options it
+	// starts from stay those of the version, whatever later versions change. 0 is
+	// LatestAPIVersion
+	APIVersion int `json:"apiVersion"`
+	// SessionID is the session the environment is destroyed along with, if not empty
+	SessionID string `json:"sessionID"`
+}
+
+// CreateEnvWithConfig creates a new CEL environment configured by config
+// Returns an environment ID that can be used for compilation
+func CreateEnvWithConfig(config EnvConfig) map[string]interface{} {
+	var session *SessionState
+	if config.SessionID != "" {
+		var ok bool
+		if session, ok = sessions[config.SessionID]; !ok {
+			return idNotFound("session", "session", config.SessionID)
+		}
+	}
+
+	result := createEnv(config)
+	if envID, ok := result["envID"].(string); ok && session != nil {
+		session.envIDs = append(session.envIDs, envID)
+	}
+	return result
+}
+
+// createEnv creates the environment of a configuration
+func createEnv(config EnvConfig) map[string]interface{} {
+	varDecls, constants, funcDefs, libraryNames := config.VarDecls, config.Constants, config.FuncDefs, config.Libraries
+	optionsJSON := optionalRawJSON(config.Options)
+
+	semantics, err := lookupAPIVersion(config.APIVersion)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	varDecls, objects, err := declareObjectTypes(varDecls, semantics.parseType)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	inputs, err := newEnvInputs(varDecls, config.AbsentVariables, semantics)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	// Global functions are declared in every environment, ahead of its own functions
+	allFuncDefs := append(append([]FunctionDef{}, globalFunctions...), funcDefs...)
+
+	// Identical configurations share one cel.Env
+	// Libraries can't be redefined, so their names identify their declarations
+	poolKey, poolable := envPoolKey(varDecls, constants, allFuncDefs, libraryNames, optionsJSON, inputs.absent, semantics.version)
+	if poolable {
+		if env, ok := acquirePooledEnv(poolKey); ok {
+			envID := envs.reserve()
+			_, libraryFuncDefs, err := libraryOptions(libraryNames, envID)
+			if err != nil {
+				envs.remove(envID)
+				releasePooledEnv(poolKey)
+				return map[string]interface{}{
+					"error": err.Error(),
+				}
+			}
+			var typesKey string
+			if encoded, ok := contextDescriptorSet(optionsJSON); ok && retainTypeRegistry(typeRegistryKey(encoded)) {
+				typesKey = typeRegistryKey(encoded)
+			}
+			registerEnv(envID, env, libraryFuncDefs, funcDefs, poolKey, typesKey, inputs, libraryNames, optionsJSON)
+
+			return map[string]interface{}{
+				"envID": envID,
+				"error": nil,
+			}
+		}
+	}
+
+	// Convert variable declarations to CEL declarations
+	var celVarDecls []*exprpb.Decl
+	for _, varDecl := range varDecls {
+		celType := semantics.parseType(varDecl.Type)
+		celVarDecls = append(celVarDecls, decls.NewVar(varDecl.Name, celType))
+	}
+
+	// Convert function definitions to CEL function declarations and implementations
+	var funcDecls []*exprpb.Decl
+	var funcImpls []cel.EnvOption
+	for _, funcDef := range allFuncDefs {
+		funcDecl, funcImpl, err := functionDeclaration(funcDef, semantics.parseType)
+		if err != nil {
+			return map[string]interface{}{
+				"error": err.Error(),
+			}
+		}
+		funcDecls = append(funcDecls, funcDecl)
+		funcImpls = append(funcImpls, funcImpl)
+	}
+
+	// Create CEL environment with variable declarations, function declarations, and options
+	var env *cel.Env
+	opts := semantics.envOptions()
+
+	// Omitted variables can only be unknown in programs evaluated partially
+	if inputs.absent == AbsentVariablesUnknown {
+		opts = append(opts, cel.Lib(partialEvalLibrary{}))
+	}
+
+	// Object types have to be known before the variables declared with them
+	if len(objects) > 0 {
+		opts = append(opts, objectTypes(objects))
+	}
+
+	// Add variable declarations
+	if len(celVarDecls) > 0 {
+		opts = append(opts, cel.Declarations(celVarDecls...))
+	}
+
+	// Add constant declarations
+	if len(constants) > 0 {
+		constantOpts, err := constantOptions(constants, semantics.parseType)
+		if err != nil {
+			return map[string]interface{}{
+				"error": err.Error(),
+			}
+		}
+		opts = append(opts, constantOpts...)
+	}
+
+	// Add function declarations
+	if len(funcDecls) > 0 {
+		opts = append(opts, cel.Declarations(funcDecls...))
+	}
+
+	// Add function implementations
+	if len(funcImpls) > 0 {
+		opts = append(opts, funcImpls...)
+	}
+
+	// Reserve the environment ID first (needed for options creation)
+	envID := envs.reserve()
+
+	// Add libraries, ahead of the options so they can refer to library declarations
+	libraryOpts, libraryFuncDefs, err := libraryOptions(libraryNames, envID)
+	if err != nil {
+		envs.remove(envID)
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	opts = append(opts, libraryOpts...)
+
+	// Add environment options from configuration
+	if optionsJSON != nil && *optionsJSON != "" {
+		envOptions, err := wasmenv.CreateOptionsFromJSONWithEnvID(*optionsJSON, envID)
+		if err != nil {
+			envs.remove(envID)
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to create environment options: %v", err),
+			}
+		}
+		opts = append(opts, envOptions...)
+	}
+
+	// Environments are built directly rather than by extending a shared standard
+	// environment: cel-go already caches the standard library declarations, so Env.Extend
+	// saves nothing over cel.NewEnv (see the env/new and env/extend benchmark workloads)
+	// Envs whose context proto comes from the same descriptor set share its type registry,
+	// which has to be in place before any option registers types
+	sharedOpt, typesKey := contextTypes(optionsJSON)
+	if sharedOpt != nil {
+		opts = append([]cel.EnvOption{sharedOpt}, opts...)
+	}
+	env, err = cel.NewEnv(opts...)
+	if err != nil {
+		envs.remove(envID)
+		releaseTypeRegistry(typesKey)
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to create CEL environment: %v", err),
+		}
+	}
+
+	if poolable {
+		addPooledEnv(poolKey, env)
+	} else {
+		poolKey = ""
+	}
+	registerEnv(envID, env, libraryFuncDefs, funcDefs, poolKey, typesKey, inputs, libraryNames, optionsJSON)
+
+	return map[string]interface{}{
+		"envID": envID,
+		"error": nil,
+	}
+}
+
+// registerEnv records a new environment and the function implementations it uses
+// Global and library functions are not tracked for cleanup, since they outlive every environment
+func registerEnv(envID string, env *cel.Env, libraryFuncDefs []FunctionDef, funcDefs []FunctionDef, poolKey string, typesKey string, inputs *envInputs, libraryNames []string, optionsJSON *string) {
+	purity := make(map[string]bool, len(globalFunctions)+len(libraryFuncDefs)+len(funcDefs))
+	for _, funcDef := range globalFunctions {
+		purity[funcDef.ImplID] = funcDef.IsPure
+	}
+	for _, funcDef := range libraryFuncDefs {
+		purity[funcDef.ImplID] = funcDef.IsPure
+	}
+
+	// Collect function implementation IDs for cleanup tracking
+	implIDs := make([]string, 0, len(funcDefs))
+	for _, funcDef := range funcDefs {
+		implIDs = append(implIDs, funcDef.ImplID)
+		purity[funcDef.ImplID] = funcDef.IsPure
+		if funcDef.BatchImplID != "" {
+			implIDs = append(implIDs, funcDef.BatchImplID)
+		}
+	}
+	for _, implID := range implIDs {
+		// The environment holds a reference until it's destroyed, and programs hold theirs
+		// Environments sharing an implementation, such as pooled ones, each add their own
+		if ref, ok := functionRefs[implID]; ok {
+			ref.refCount++
+			continue
+		}
+		functionRefs[implID] = &FunctionRefCount{
+			refCount: 1,
+			envID:    envID,
+		}
+	}
+
+	envs.set(envID, &EnvState{
+		env:       env,
+		implIDs:   implIDs,
+		purity:    purity,
+		destroyed: false,
+		poolKey:   poolKey,
+		typesKey:  typesKey,
+		inputs:    inputs,
+		options:   optionConfigs(optionsJSON),
+		libraries: libraryNames,
+		lastUsed:  time.Now(),
+	})
+	emitLifecycleEvent(LifecycleEnvCreated, map[string]interface{}{"envID": envID})
+}
+
+// functionDeclaration converts a function definition to a CEL function declaration and
+// an implementation that calls back to JavaScript, parsing the types of its parameters
+// and result with parseType
+func functionDeclaration(funcDef FunctionDef, parseType func(interface{}) *exprpb.Type) (*exprpb.Decl, cel.EnvOption, error) {
+	// Convert parameter types from exprpb.Type to cel.Type
+	paramTypesExpr := make([]*exprpb.Type, 0, len(funcDef.Params))
+	paramTypesCel := make([]*cel.Type, 0, len(funcDef.Params))
+	for _, param := range funcDef.Params {
+		paramTypeExpr := parseType(param.Type)
+		paramTypesExpr = append(paramTypesExpr, paramTypeExpr)
+		// Convert to cel.Type
+		paramTypeCel, err := cel.ExprTypeToType(paramTypeExpr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert parameter type: %v", err)
+		}
+		paramTypesCel = append(paramTypesCel, paramTypeCel)
+	}
+
+	// Convert return type
+	returnTypeExpr := parseType(funcDef.ReturnType)
+	returnTypeCel, err := cel.ExprTypeToType(returnTypeExpr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert return type: %v", err)
+	}
+
+	overloadID := fmt.Sprintf("%s_%s", funcDef.Name, funcDef.ImplID)
+
+	// Create function declaration (using exprpb types)
+	funcDecl := decls.NewFunction(funcDef.Name,
+		decls.NewOverload(
+			overloadID,
+			paramTypesExpr,
+			returnTypeExpr,
+		),
+	)
+
+	// Create function implementation that calls back to JavaScript (using cel types)
+	implID := funcDef.ImplID
+	name := funcDef.Name
+	cacheable := funcDef.Cacheable
+	shared := newFunctionCache(funcDef.CacheSize)
+	funcImpl := cel.Function(funcDef.Name,
+		cel.Overload(overloadID, paramTypesCel, returnTypeCel,
+			cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+				// Convert CEL values to Go values
+				goArgs := make([]interface{}, len(args))
+				for i, arg := range args {
+					goArgs[i] = functionArg(arg)
+				}
+
+				// Call the registered JavaScript function
+				if jsFunctionCaller != nil {
+					start := time.Now()
+					var key string
+					if cacheable {
+						key = callKey(implID, goArgs)
+						if val, ok := cachedCall(key, shared); ok {
+							evalTrace.record(name, args, val, start, true)
+							return val
+						}
+					}
+					result, err := jsFunctionCaller.CallJSFunction(implID, goArgs)
+					evalMetrics.trackCallback(start)
+
+					// Convert result back to a CEL value of the declared return type
+					var val ref.Val
+					if err != nil {
+						val = functionError(name, err)
+					} else {
+						val = functionResult(name, returnTypeCel, result)
+					}
+					if key != "" {
+						cacheCall(key, shared, val)
+					}
+					evalTrace.record(name, args, val, start, false)
+					return val
+				}
+
+				return types.NewErr("JavaScript function caller not set")
+			}),
+		),
+	)
+	if funcDef.BatchImplID != "" {
+		batchImpl := batchDeclaration(name, overloadID, funcDef.BatchImplID, returnTypeCel)
+		callImpl := funcImpl
+		funcImpl = func(e *cel.Env) (*cel.Env, error) {
+			e, err := callImpl(e)
+			if err != nil {
+				return nil, err
+			}
+			return batchImpl(e)
+		}
+	}
+	return funcDecl, funcImpl, nil
+}
+
+// Compile compiles a CEL expression using the specified environment
+// Returns a program ID that can be used for evaluation
+func Compile(envID string, exprStr string) map[string]interface{} {
+	return CompileWithOptions(envID, exprStr, nil)
+}
+
+// CompileWithOptions compiles a CEL expression using the specified environment and program options
+// Returns a program ID that can be used for evaluation
+func CompileWithOptions(envID string, exprStr string, programOptionsJSON *string) map[string]interface{} {
+	return CompileWithMetrics(envID, exprStr, programOptionsJSON, false)
+}
+
+// CompileWithMetrics compiles a CEL expression like CompileWithOptions
+// When metrics is true, the result includes the time spent parsing, checking and planning
+// the program in milliseconds under the "metrics" key
+func CompileWithMetrics(envID string, exprStr string, programOptionsJSON *string, metrics bool) map[string]interface{} {
+	return CompileWithFlags(envID, exprStr, programOptionsJSON, CompileFlags{Metrics: metrics})
+}
+
+// CompileWithFlags compiles a CEL expression like CompileWithOptions, configured by flags
+func CompileWithFlags(envID string, exprStr string, programOptionsJSON *string, flags CompileFlags) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	timings := newCallMetrics(flags.Metrics)
+	ast, prg, memo, err := buildProgram(envState, exprStr, programOptionsJSON, flags, timings)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	programID := addProgram(envID, envState, prg, ast, memo, &programSource{expr: exprStr, programOptionsJSON: programOptionsJSON, flags: flags})
+
+	response := map[string]interface{}{
+		"programID": programID,
+		"error":     nil,
+	}
+	if flags.OptimizedSource {
+		addOptimizedSource(response, ast)
+		if flags.SourceMap {
+			addSourceMap(response, envState, exprStr)
+		}
+	}
+	if flags.FieldMask {
+		addFieldMask(response, ast)
+	}
+	return timings.addTo(response, false)
+}
+
+// buildProgram parses, checks and plans an expression in an environment, the way
+// CompileWithFlags does, tracking the time of each phase in timings
+func buildProgram(envState *EnvState, exprStr string, programOptionsJSON *string, flags CompileFlags, timings *callMetrics) (*cel.Ast, cel.Program, *memoCache, error) {
+	// Parse and check the expression, the same as env.Compile
+	start := time.Now()
+	ast, issues := envState.env.Parse(exprStr)
+	timings.track("parseMs", start)
+	if issues.Err() == nil {
+		start = time.Now()
+		ast, issues = envState.env.Check(ast)
+		timings.track("checkMs", start)
+	}
+	if issues != nil && issues.Err() != nil {
+		return nil, nil, nil, fmt.Errorf("compilation error: %v", issues.Err())
+	}
+
+	// Check for compilation errors
+	if !ast.IsChecked() {
+		return nil, nil, nil, fmt.Errorf("expression compilation failed: not checked")
+	}
+
+	return planProgram(envState, ast, programOptionsJSON, flags, timings)
+}
+
+// planProgram folds the constants of a checked expression and plans it in an environment,
+// the way CompileWithFlags does
+func planProgram(envState *EnvState, ast *cel.Ast, programOptionsJSON *string, flags CompileFlags, timings *callMetrics) (*cel.Ast, cel.Program, *memoCache, error) {
+	if err := checkResultType(envState, ast, flags); err != nil {
+		return nil, nil, nil, fmt.Errorf("compilation error: %w", err)
+	}
+
+	// Fold the environment's constants, and any other constant subexpressions when optimizing
+	if flags.Optimize || hasConstants(envState.env) {
+		start := time.Now()
+		ast = foldConstants(envState, ast)
+		timings.track("optimizeMs", start)
+	}
+
+	memo, err := newProgramMemo(envState, ast, flags.Memoize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Parse program options from configuration
+	programOptions, err := parseProgramOptions(programOptionsJSON)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create program options: %v", err)
+	}
+	// Charge the values the program produces to the memory budget of its evaluations
+	programOptions = append(programOptions, chargeMemoryBudget(flags.Optimize))
+	if flags.Optimize {
+		programOptions = append(programOptions, cel.EvalOptions(cel.OptOptimize))
+	}
+
+	// Create program, calling the batch implementations of functions mapped over lists
+	start := time.Now()
+	prg, err := envState.env.Program(batchCalls(envState.env, ast), programOptions...)
+	timings.track("programMs", start)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create program: %v", err)
+	}
+
+	return ast, prg, memo, nil
+}
+
+// addProgram registers a compiled program of an environment and returns its ID
+// source is how the program was compiled, nil for programs recompilePrograms can't rebuild
+func addProgram(envID string, envState *EnvState, prg cel.Program, checked *cel.Ast, memo *memoCache, source *programSource) string {
+	programID := programs.add(&ProgramState{
+		prg:      prg,
+		ast:      checked,
+		envID:    envID,
+		memo:     memo,
+		inputs:   envState.inputs,
+		source:   source,
+		lastUsed: time.Now(),
+	})
+
+	// Increment reference counts for all functions in this environment
+	// Programs can potentially use any function from their environment
+	for _, implID := range envState.implIDs {
+		if ref, ok := functionRefs[implID]; ok {
+			ref.refCount++
+		}
+	}
+	emitLifecycleEvent(LifecycleProgramCompiled, map[string]interface{}{"programID": programID, "envID": envID})
+	return programID
+}
+
+// CompileDetailed compiles a CEL expression and returns detailed results including all issues
+func CompileDetailed(envID string, exprStr string) map[string]interface{} {
+	return CompileDetailedWithOptions(envID, exprStr, nil)
+}
+
+// CompileDetailedWithOptions compiles a CEL expression with program options and returns detailed
+// results including all issues
+func CompileDetailedWithOptions(envID string, exprStr string, programOptionsJSON *string) map[string]interface{} {
+	return CompileDetailedWithMetrics(envID, exprStr, programOptionsJSON, false)
+}
+
+// CompileDetailedWithMetrics compiles a CEL expression like CompileDetailedWithOptions
+// When metrics is true, successful results include the time spent parsing, checking and
+// planning the program in milliseconds under the "metrics" key
+func CompileDetailedWithMetrics(envID string, exprStr string, programOptionsJSON *string, metrics bool) map[string]interface{} {
+	return CompileDetailedWithFlags(envID, exprStr, programOptionsJSON, CompileFlags{Metrics: metrics})
+}
+
+// CompileDetailedWithFlags compiles a CEL expression like CompileDetailedWithOptions, configured by flags
+func CompileDetailedWithFlags(envID string, exprStr string, programOptionsJSON *string, flags CompileFlags) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		result := envs.notFound(envID)
+		result["issues"] = []interface{}{}
+		return result
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error":  fmt.Sprintf("environment has been destroyed: %s", envID),
+			"issues": []interface{}{},
+		}
+	}
+	touchEnv(envState)
+
+	// Create a compilation-scoped issue collector
+	compilationCollector := NewCompilationIssueCollector()
+
+	// Generate a unique compilation ID (using the filename side-channel pattern)
+	compilationIDCounter++
+	compilationID := fmt.Sprintf("comp_%d_%p", compilationIDCounter, &compilationCollector)
+
+	// Register the compilation context
+	RegisterCompilationContext(compilationID, compilationCollector)
+	defer UnregisterCompilationContext(compilationID) // Important: cleanup to prevent memory leaks
+
+	// Create source with compilation ID as the description (filename side-channel)
+	source := common.NewStringSource(exprStr, compilationID)
+
+	timings := newCallMetrics(flags.Metrics)
+
+	// Use ParseSource + Check with the compilation ID embedded in the source description
+	start := time.Now()
+	ast, issues := envState.env.ParseSource(source)
+	timings.track("parseMs", start)
+	parsed := ast
+	if issues.Err() == nil {
+		start = time.Now()
+		ast, issues = envState.env.Check(ast)
+		timings.track("checkMs", start)
+	}
+
+	// Convert all issues to JavaScript-compatible format
+	var jsIssues []interface{}
+
+	// Add CEL built-in issues first
+	if issues != nil {
+		for _, err := range issues.Errors() {
+			jsIssue := map[string]interface{}{
+				"severity": "error",
+				"message":  err.Message,
+				"location": map[string]interface{}{
+					"line":   int(err.Location.Line()),
+					"column": int(err.Location.Column()),
+				},
+			}
+			if suggestions := didYouMean(envState.env, parsed, err); len(suggestions) > 0 {
+				jsIssue["didYouMean"] = suggestions
+			}
+			jsIssues = append(jsIssues, jsIssue)
+		}
+	}
+
+	// Add custom validator issues from this compilation
+	for _, validatorIssue := range compilationCollector.GetValidatorIssues() {
+		jsIssue := map[string]interface{}{
+			"severity": validatorIssue.Severity,
+			"message":  validatorIssue.Message,
+		}
+		if validatorIssue.Location != nil {
+			jsIssue["location"] = validatorIssue.Location
+		}
+		if validatorIssue.Rule != "" {
+			jsIssue["rule"] = validatorIssue.Rule
+		}
+		jsIssues = append(jsIssues, jsIssue)
+	}
+
+	// Check if compilation failed completely
+	if issues != nil && issues.Err() != nil {
+		// The errors name the source after its description, which is only a side-channel
+		// here, so they name it <input> like the errors of Compile
+		message := strings.ReplaceAll(issues.Err().Error(), compilationID, "<input>")
+		return map[string]interface{}{
+			"error":     "compilation error: " + message,
+			"issues":    jsIssues,
+			"programID": nil,
+		}
+	}
+
+	// Check for compilation errors
+	if !ast.IsChecked() {
+		return map[string]interface{}{
+			"error":     "expression compilation failed: not checked",
+			"issues":    jsIssues,
+			"programID": nil,
+		}
+	}
+
+	ast, prg, memo, err := planProgram(envState, ast, programOptionsJSON, flags, timings)
+	if err != nil {
+		// Report a mismatched result type as an issue at the expression, like a check error
+		var typeErr *resultTypeError
+		if errors.As(err, &typeErr) {
+			jsIssues = append(jsIssues, map[string]interface{}{
+				"severity": "error",
+				"message":  typeErr.message,
+				"location": map[string]interface{}{
+					"line":   typeErr.location.Line(),
+					"column": typeErr.location.Column(),
+				},
+			})
+		}
+		return map[string]interface{}{
+			"error":     err.Error(),
+			"issues":    jsIssues,
+			"programID": nil,
+		}
+	}
+
+	programID := addProgram(envID, envState, prg, ast, memo, &programSource{expr: exprStr, programOptionsJSON: programOptionsJSON, flags: flags})
+
+	response := map[string]interface{}{
+		"programID": programID,
+		"error":     nil,
+		"issues":    jsIssues,
+	}
+	if flags.OptimizedSource {
+		addOptimizedSource(response, ast)
+		if flags.SourceMap {
+			addSourceMap(response, envState, exprStr)
+		}
+	}
+	if flags.FieldMask {
+		addFieldMask(response, ast)
+	}
+	return timings.addTo(response, false)
+}
+
+// Typecheck typechecks a CEL expression using the specified environment
+// Returns the type of the expression without compiling it
+func Typecheck(envID string, exprStr string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	// Parse and compile the expression (this performs typechecking)
+	ast, issues := envState.env.Compile(exprStr)
+	if issues != nil && issues.Err() != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("typecheck error: %v", issues.Err()),
+		}
+	}
+
+	// Check for compilation errors
+	if !ast.IsChecked() {
+		return map[string]interface{}{
+			"error": "expression typecheck failed: not checked",
+		}
+	}
+
+	// Get the type of the expression
+	exprType := ast.OutputType()
+	if exprType == nil {
+		return map[string]interface{}{
+			"error": "expression has no type information",
+		}
+	}
+
+	// Convert cel.Type to exprpb.Type
+	exprTypeExpr, err := cel.TypeToExprType(exprType)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to convert type: %v", err),
+		}
+	}
+
+	// Convert the type to JSON-serializable format
+	typeInfo := typeToJSON(exprTypeExpr)
+
+	return map[string]interface{}{
+		"type":  typeInfo,
+		"error": nil,
+	}
+}
+
+// Eval evaluates a compiled program with the given variables
+func Eval(programID string, vars map[string]interface{}) map[string]interface{} {
+	return EvalWithMetrics(programID, vars, false)
+}
+
+// EvalWithMetrics evaluates a compiled program like Eval
+// When metrics is true, the result includes the evaluation time and the time spent in
+// JavaScript function implementations under the "metrics" key
+func EvalWithMetrics(programID string, vars map[string]interface{}, metrics bool) map[string]interface{} {
+	return EvalWithOptions(programID, vars, EvalOptions{Metrics: metrics})
+}
+
+// EvalOptions configures a single evaluation
+// The value encoding fields are inlined, so the JSON form is a flat object
+type EvalOptions struct {
+	Metrics bool `json:"metrics"`
+	// ErrorValues returns an expression that evaluates to a CEL error as an "errorValue"
+	// result instead of failing the evaluation
+	ErrorValues bool `json:"errorValues"`
+	// Unknowns lists attribute paths such as "request.auth" whose values are unknown
+	// Programs compiled with OptPartialEval return an "unknown" result listing the
+	// attributes the outcome depends on
+	Unknowns []string `json:"unknowns,omitempty"`
+	// TraceFunctions records every custom function call of the evaluation, with its
+	// arguments, result and duration, under the "functionTrace" key
+	TraceFunctions bool `json:"traceFunctions"`
+	// TraceAttributes records the path of every variable, map entry and list element the
+	// evaluation reads, such as "user.email" or "items[0]", under the "attributeTrace" key
+	TraceAttributes bool `json:"traceAttributes"`
+	// Strict checks the variables the program reads against their declared types before
+	// evaluating it, failing with a "variableErrors" entry per mismatching or missing value
+	Strict bool `json:"strict"`
+	// TimeoutMs fails the evaluation once it has run this long, checked like Context
+	TimeoutMs float64 `json:"timeoutMs,omitempty"`
+	// MemoryLimitBytes fails the evaluation once the strings, bytes, lists and maps it
+	// produces add up to this many bytes, estimated from their sizes. 0 uses the
+	// runtime's EvalMemoryLimitBytes
+	MemoryLimitBytes float64 `json:"memoryLimitBytes,omitempty"`
+	// Now freezes the clock of now() for the evaluation, as an RFC 3339 timestamp, in
+	// environments with the SystemFunctions option
+	Now string `json:"now,omitempty"`
+	// Seed seeds the random source of uuid() and random() for the evaluation, in
+	// environments with the SystemFunctions option
+	Seed *int64 `json:"seed,omitempty"`
+	// Context cancels the evaluation once it's done, checked between the iterations of
+	// comprehensions and while custom functions wait for their results
+	Context context.Context `json:"-"`
+	ValueEncoding
+}
+
+// EvalWithOptions evaluates a compiled program like Eval, converting the result
+// with the given value encoding
+func EvalWithOptions(programID string, vars map[string]interface{}, options EvalOptions) map[string]interface{} {
+	if err := options.ValueEncoding.validate(); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+	touchProgram(programState)
+	options.ValueEncoding = programState.inputs.semantics.withDefaults(options.ValueEncoding)
+
+	// Tagged doubles stand in for NaN and ±Inf, which JSON can't represent
+	decoded, _ := decodeTaggedMap(vars)
+	if options.integerStrings() {
+		decoded, _ = decodeIntegerStrings(decoded, programState.readVariables())
+	}
+	if options.Strict {
+		if errs := validateVariables(programState, decoded, options.Unknowns); len(errs) > 0 {
+			return invalidVariablesResponse(errs)
+		}
+	}
+	return evalCached(programState, decoded, options, func() (string, bool) {
+		return programState.memo.key(vars, options)
+	})
+}
+
+// evalCached evaluates a program against an activation like evalActivation, answering
+// repeated inputs from the program's memo cache
+// memoKey hashes the inputs, and is only called for memoized programs
+func evalCached(programState *ProgramState, activation interface{}, options EvalOptions, memoKey func() (string, bool)) map[string]interface{} {
+	timings := newCallMetrics(options.Metrics)
+	evalMetrics = timings
+	defer func() { evalMetrics = nil }()
+
+	// A hit is the whole evaluation, so its lookup is also tracked as evalMs
+	start := time.Now()
+	var key string
+	memoized := false
+	if programState.memo != nil {
+		key, memoized = memoKey()
+	}
+	if memoized {
+		cached, ok := programState.memo.get(key)
+		timings.track("memoMs", start)
+		if ok {
+			timings.track("evalMs", start)
+			trace := newFunctionTrace(options.TraceFunctions, options.ValueEncoding)
+			return timings.addTo(trace.addTo(cached), true)
+		}
+	}
+
+	response := evalActivation(programState, activation, options, timings)
+	if memoized && response["error"] == nil {
+		programState.memo.put(key, response)
+	}
+	return response
+}
+
+// evalActivation evaluates a program against an activation and builds the response
+func evalActivation(programState *ProgramState, activation interface{}, options EvalOptions, timings *callMetrics) map[string]interface{} {
+	if options.TimeoutMs < 0 {
+		return map[string]interface{}{
+			"error": "timeoutMs must not be negative",
+		}
+	}
+	if options.MemoryLimitBytes < 0 {
+		return map[string]interface{}{
+			"error": "memoryLimitBytes must not be negative",
+		}
+	}
+	now, err := options.clock()
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	activation, options, err = programState.inputs.bind(activation, options)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	if len(options.Unknowns) > 0 {
+		partialVars, err := cel.PartialVars(activation, programState.unknownPatterns(options.Unknowns)...)
+		if err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to create partial variables: %v", err),
+			}
+		}
+		activation = partialVars
+	}
+
+	attributes := newAttributeTrace(options.TraceAttributes, programState.adapter())
+	if activation, err = attributes.wrap(activation); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to create activation: %v", err),
+		}
+	}
+
+	// Evaluate the program with variables
+	trace := newFunctionTrace(options.TraceFunctions, options.ValueEncoding)
+	evalTrace = trace
+	evalCalls = &callCache{}
+	stopSystem := startSystemEvaluation(now, options.Seed)
+	start := time.Now()
+	ctx, cancel := options.interruptContext()
+	defer cancel()
+	budget := options.memoryBudget()
+	evalBudget = budget
+	if ctx != nil || budget != nil {
+		if activation, err = newInterruptActivation(activation, ctx, budget, options); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to create activation: %v", err),
+			}
+		}
+	}
+	out, details, err := programState.prg.Eval(activation)
+	timings.track("evalMs", start)
+	evalTrace = nil
+	evalCalls = nil
+	evalBudget = nil
+	stopSystem()
+	attributes.stop()
+
+	// An interrupted evaluation fails however it ended, even as an error value
+	if ctx != nil {
+		if err := Interrupted(ctx, options); err != nil {
+			return attributes.addTo(trace.addTo(map[string]interface{}{
+				"error": err.Error(),
+			}))
+		}
+	}
+	if budget.exceeded() {
+		return attributes.addTo(trace.addTo(budget.response()))
+	}
+
+	var response map[string]interface{}
+	switch out := out.(type) {
+	case *types.Err:
+		if !options.ErrorValues {
+			return attributes.addTo(trace.addTo(map[string]interface{}{
+				"error": fmt.Sprintf("evaluation error: %v", err),
+			}))
+		}
+		response = map[string]interface{}{
+			"result":     nil,
+			"errorValue": errorValueToJSON(out, programState.ast),
+			"error":      nil,
+		}
+	case *types.Unknown:
+		response = map[string]interface{}{
+			"result":  nil,
+			"unknown": unknownAttributes(out),
+			"error":   nil,
+		}
+	default:
+		if err != nil {
+			return attributes.addTo(trace.addTo(map[string]interface{}{
+				"error": fmt.Sprintf("evaluation error: %v", err),
+			}))
+		}
+
+		// Convert CEL value to JSON-serializable value
+		response = map[string]interface{}{
+			"result": ValueToJSONWithEncoding(out, options.ValueEncoding),
+			"error":  nil,
+		}
+	}
+
+	// Include the runtime cost when cost tracking is enabled for the program
+	if details != nil {
+		if cost := details.ActualCost(); cost != nil {
+			response["cost"] = *cost
+		}
+	}
+
+	return timings.addTo(attributes.addTo(trace.addTo(response)), true)
+}
+
+// parseProgramOptions creates CEL program options from an optional JSON configuration
+// Comprehensions check whether the evaluation was cancelled every interruptCheckFrequency
+// iterations, unless the configuration sets its own InterruptCheckFrequency
+func parseProgramOptions(programOptionsJSON *string) ([]cel.ProgramOption, error) {
+	programOptions := []cel.ProgramOption{cel.InterruptCheckFrequency(interruptCheckFrequency)}
+	if programOptionsJSON == nil || *programOptionsJSON == "" {
+		return programOptions, nil
+	}
+	configured, err := wasmenv.CreateProgramOptionsFromJSON(*programOptionsJSON)
+	if err != nil {
+		return nil, err
+	}
+	return append(programOptions, configured...), nil
+}
+
+// typeNames maps the built-in type names of type definitions to their CEL types
+var typeNames = map[string]*exprpb.Type{
+	"bool":      decls.Bool,
+	"int":       decls.Int,
+	"uint":      decls.Uint,
+	"double":    decls.Double,
+	"string":    decls.String,
+	"bytes":     decls.Bytes,
+	"timestamp": decls.Timestamp,
+	"duration":  decls.Duration,
+	"null":      decls.Null,
+	"dyn":       decls.Dyn,
+	"any":       decls.Dyn,
+}
+
+// Kinds of the type definitions given as objects, {"kind": ...}
+const (
+	typeDefKindList   = "list"
+	typeDefKindMap    = "map"
+	typeDefKindObject = "object"
+)
+
+// typeDefKinds lists the kinds parseTypeDef understands
+var typeDefKinds = []string{typeDefKindList, typeDefKindMap, typeDefKindObject}
+
+// typeExpressionForms lists the parameterized types of compact type expressions
+var typeExpressionForms = []string{"list<T>", "map<K, V>"}
+
+// parseTypeDef parses a type definition from JSON into a CEL type
+// typeDef can be a string (type name) or a map[string]interface{} (complex type)
+func parseTypeDef(typeDef interface{}) *exprpb.Type {
+	// If it's a string, treat it as a simple type name
+	if typeName, ok := typeDef.(string); ok {
+		return parseTypeName(typeName)
+	}
+
+	// Otherwise, it should be a map
+	typeDefMap, ok := typeDef.(map[string]interface{})
+	if !ok {
+		logging.Warn("unsupported type definition, using dyn", map[string]interface{}{
+			"typeDef": fmt.Sprintf("%v", typeDef),
+		})
+		return decls.Dyn
+	}
+
+	if kind, ok := typeDefMap["kind"].(string); ok {
+		switch kind {
+		case typeDefKindList:
+			if elemType, ok := typeDefMap["elementType"].(map[string]interface{}); ok {
+				return decls.NewListType(parseTypeDef(elemType))
+			}
+			// Fallback to string type name
+			if elemTypeStr, ok := typeDefMap["elementType"].(string); ok {
+				return decls.NewListType(parseTypeDef(elemTypeStr))
+			}
+			return decls.NewListType(decls.Dyn)
+		case typeDefKindMap:
+			keyType := decls.String
+			valueType := decls.Dyn
+			if kt, ok := typeDefMap["keyType"].(string); ok {
+				keyType = parseTypeName(kt)
+			} else if ktMap, ok := typeDefMap["keyType"].(map[string]interface{}); ok {
+				keyType = parseTypeDef(ktMap)
+			}
+			if vt, ok := typeDefMap["valueType"].(map[string]interface{}); ok {
+				valueType = parseTypeDef(vt)
+			} else if vt, ok := typeDefMap["valueType"].(string); ok {
+				valueType = parseTypeDef(vt)
+			}
+			return decls.NewMapType(keyType, valueType)
+		case typeDefKindObject:
+			// Object types are named when the variables declaring them are
+			if name, ok := typeDefMap["name"].(string); ok {
+				return decls.NewObjectType(name)
+			}
+			logging.Warn("object type outside a variable declaration, using map<string, dyn>", map[string]interface{}{
+				"typeDef": fmt.Sprintf("%v", typeDefMap),
+			})
+			return decls.NewMapType(decls.String, decls.Dyn)
+		}
+	}
+
+	// Try as string type name in map
+	if typeName, ok := typeDefMap["type"].(string); ok {
+		return parseTypeName(typeName)
+	}
+	if typeName, ok := typeDefMap["name"].(string); ok {
+		return parseTypeName(typeName)
+	}
+
+	logging.Warn("unsupported type definition, using dyn", map[string]interface{}{
+		"typeDef": fmt.Sprintf("%v", typeDefMap),
+	})
+	return decls.Dyn
+}
+
+// parseTypeName parses a type name string into a CEL type
+// Compact type expressions such as "map<string, list<int>>" are parsed into the
+// corresponding list and map types
+func parseTypeName(typeName string) *exprpb.Type {
+	if isTypeExpression(typeName) {
+		exprType, err := parseTypeExpression(typeName)
+		if err != nil {
+			logging.Warn("invalid type expression, using dyn", map[string]interface{}{
+				"typeName": typeName,
+				"error":    err.Error(),
+			})
+			return decls.Dyn
+		}
+		return exprType
+	}
+
+	if exprType, ok := typeNames[typeName]; ok {
+		return exprType
+	}
+	if exprType, ok := codecExprType(typeName); ok {
+		return exprType
+	}
+	logging.Warn("unknown type name, using dyn", map[string]interface{}{
+		"typeName": typeName,
+	})
+	return decls.Dyn
+}
+
+// typeToJSON converts a CEL exprpb.Type to a JSON-serializable format
+// This is the inverse of parseTypeDef
+func typeToJSON(exprType *exprpb.Type) interface{} {
+	if exprType == nil {
+		return "dyn"
+	}
+
+	switch exprType.GetTypeKind().(type) {
+	case *exprpb.Type_Primitive:
+		switch exprType.GetPrimitive() {
+		case exprpb.Type_BOOL:
+			return "bool"
+		case exprpb.Type_INT64:
+			return "int"
+		case exprpb.Type_UINT64:
+			return "uint"
+		case exprpb.Type_DOUBLE:
+			return "double"
+		case exprpb.Type_STRING:
+			return "string"
+		case exprpb.Type_BYTES:
+			return "bytes"
+		}
+	case *exprpb.Type_WellKnown:
+		switch exprType.GetWellKnown() {
+		case exprpb.Type_TIMESTAMP:
+			return "timestamp"
+		case exprpb.Type_DURATION:
+			return "duration"
+		}
+	case *exprpb.Type_ListType_:
+		elemType := exprType.GetListType().GetElemType()
+		return map[string]interface{}{
+			"kind":        "list",
+			"elementType": typeToJSON(elemType),
+		}
+	case *exprpb.Type_MapType_:
+		mapType := exprType.GetMapType()
+		return map[string]interface{}{
+			"kind":      "map",
+			"keyType":   typeToJSON(mapType.GetKeyType()),
+			"valueType": typeToJSON(mapType.GetValueType()),
+		}
+	case *exprpb.Type_Null:
+		return "null"
+	case *exprpb.Type_Dyn:
+		return "dyn"
+	case *exprpb.Type_AbstractType_:
+		if name := exprType.GetAbstractType().GetName(); valueCodecs[name] != nil {
+			return name
+		}
+	case *exprpb.Type_MessageType:
+		if name := exprType.GetMessageType(); strings.HasPrefix(name, objectTypePackage) {
+			return map[string]interface{}{
+				"kind": "object",
+				"name": name,
+			}
+		}
+	}
+
+	// Fallback to dynamic type
+	return "dyn"
+}
+
+// ValueToJSON converts a CEL ref.Val to a JSON-serializable value
+func ValueToJSON(val ref.Val) interface{} {
+	return ValueToJSONWithEncoding(val, ValueEncoding{})
+}
+
+// ValueToJSONWithEncoding converts a CEL ref.Val like ValueToJSON, representing values
+// that JSON can't express directly as configured by encoding
+func ValueToJSONWithEncoding(val ref.Val, encoding ValueEncoding) interface{} {
+	if val == nil {
+		return nil
+	}
+
+	// Handle null values explicitly
+	if val == types.NullValue {
+		return nil
+	}
+
+	switch v := val.(type) {
+	case *types.Optional:
+		// Handle CEL optional types properly
+		if v.HasValue() {
+			// Recursively convert the wrapped value
+			return ValueToJSONWithEncoding(v.GetValue(), encoding)
+		} else {
+			// Optional with no value (optional.none())
+			return nil
+		}
+	case types.Bool:
+		return bool(v)
+	case types.Int:
+		return encodeInt(int64(v), encoding)
+	case types.Uint:
+		return encodeUint(uint64(v), encoding)
+	case types.Double:
+		return encodeDouble(float64(v), encoding)
+	case types.String:
+		return string(v)
+	case types.Bytes:
+		return []byte(v)
+	case traits.Lister:
+		size := v.Size().Value().(int64)
+		result := make([]interface{}, size)
+		for i := int64(0); i < size; i++ {
+			result[i] = ValueToJSONWithEncoding(v.Get(types.Int(i)), encoding)
+		}
+		return result
+	case traits.Mapper:
+		if encoding.MapKeys == MapKeysEntries && hasNonStringKeys(v) {
+			return mapEntries(v, encoding)
+		}
+		result := make(map[string]interface{})
+		it := v.Iterator()
+		for it.HasNext() == types.True {
+			key := it.Next()
+			val := v.Get(key)
+			keyStr := fmt.Sprintf("%v", ValueToJSONWithEncoding(key, encoding))
+			result[keyStr] = ValueToJSONWithEncoding(val, encoding)
+		}
+		return result
+	default:
+		// Values with a registered codec use their tagged objects
+		if object, ok := encodeCodecValue(val); ok {
+			return object
+		}
+
+		// Protobuf messages use their JSON mapping
+		if msg, ok := val.Value().(proto.Message); ok {
+			if result, err := messageToJSON(msg); err == nil {
+				return result
+			}
+		}
+
+		// Values that convert to strings, such as IP addresses, use their string form
+		if text, ok := val.ConvertToType(types.StringType).(types.String); ok {
+			return string(text)
+		}
+
+		// For other unknown types, convert to string
+		logging.Warn("no JSON conversion for value, using its string form", map[string]interface{}{
+			"type": val.Type().TypeName(),
+		})
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// JSONToValue converts a JSON-serializable value to a CEL ref.Val
+func JSONToValue(val interface{}) ref.Val {
+	if val == nil {
+		return types.NullValue
+	}
+
+	switch v := val.(type) {
+	case ref.Val:
+		// Already decoded, such as by a value codec
+		return v
+	case bool:
+		return types.Bool(v)
+	case int:
+		return types.Int(v)
+	case int8:
+		return types.Int(v)
+	case int16:
+		return types.Int(v)
+	case int32:
+		return types.Int(v)
+	case int64:
+		return types.Int(v)
+	case uint:
+		return types.Uint(v)
+	case uint8:
+		return types.Uint(v)
+	case uint16:
+		return types.Uint(v)
+	case uint32:
+		return types.Uint(v)
+	case uint64:
+		return types.Uint(v)
+	case float32:
+		return types.Double(v)
+	case float64:
+		return types.Double(v)
+	case string:
+		return types.String(v)
+	case []byte:
+		return types.Bytes(v)
+	case time.Time:
+		return types.Timestamp{Time: v}
+	case []interface{}:
+		items := make([]ref.Val, len(v))
+		for i, item := range v {
+			items[i] = JSONToValue(item)
+		}
+		return types.NewDynamicList(types.DefaultTypeAdapter, items)
+	case map[string]interface{}:
+		if value, ok := decodeTaggedDouble(v); ok {
+			return types.Double(value)
+		}
+		if value, ok := decodeCodecValue(v); ok {
+			return value
+		}
+		result := make(map[ref.Val]ref.Val)
+		for k, v := range v {
+			result[types.String(k)] = JSONToValue(v)
+		}
+		return types.NewDynamicMap(types.DefaultTypeAdapter, result)
+	default:
+		// Try to convert via JSON marshaling/unmarshaling
+		logging.Debug("converting value through JSON", map[string]interface{}{
+			"type": fmt.Sprintf("%T", val),
+		})
+		jsonBytes, err := json.Marshal(val)
+		if err != nil {
+			return types.NewErr("failed to convert value: %v", err)
+		}
+		var jsonVal interface{}
+		if err := json.Unmarshal(jsonBytes, &jsonVal); err != nil {
+			return types.NewErr("failed to unmarshal value: %v", err)
+		}
+		return JSONToValue(jsonVal)
+	}
+}
+
+// UnregisterFunctionCaller is an interface for unregistering functions
+// This allows the cel package to clean up function registrations
+type UnregisterFunctionCaller interface {
+	UnregisterFunction(implID string)
+}
+
+// Global variable to hold the unregister function caller
+// This is set by the WASM layer
+var unregisterFunctionCaller UnregisterFunctionCaller
+
+// SetUnregisterFunctionCaller sets the unregister function caller
+// This is called from the WASM layer
+func SetUnregisterFunctionCaller(caller UnregisterFunctionCaller) {
+	unregisterFunctionCaller = caller
+}
+
+// unregisterFunctionIfUnused unregisters a function if its reference count reaches 0
+func unregisterFunctionIfUnused(implID string) {
+	ref, ok := functionRefs[implID]
+	if !ok {
+		return
+	}
+
+	if ref.refCount <= 0 {
+		// Unregister the function
+		if unregisterFunctionCaller != nil {
+			unregisterFunctionCaller.UnregisterFunction(implID)
+		}
+		// Remove from function refs tracking
+		delete(functionRefs, implID)
+		emitLifecycleEvent(LifecycleFunctionUnregistered, map[string]interface{}{"implID": implID})
+	}
+}
+
+// DestroyEnv destroys an environment and marks it as destroyed
+// Functions are not immediately unregistered - they will be unregistered
+// when all programs and other environments using them are destroyed (reference counting)
+// However, if nothing else references them, cleanup happens immediately
+func DestroyEnv(envID string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+	if envState.destroyed {
+		return map[string]interface{}{
+			"success": true,
+			"error":   nil,
+		}
+	}
+
+	// Mark environment as destroyed (prevents new programs from being created)
+	envState.destroyed = true
+
+	// Release the environment's own references, unregistering the functions nothing else uses
+	for _, implID := range envState.implIDs {
+		if ref, ok := functionRefs[implID]; ok {
+			ref.refCount--
+			unregisterFunctionIfUnused(implID)
+		}
+	}
+
+	// The environment entry is kept until its last program is destroyed
+	if !hasPrograms(envID) {
+		removeEnv(envID, envState)
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// DestroyProgram destroys a compiled program
+// This should be called when a program is no longer needed
+// Decrements reference counts for functions and unregisters them if no longer needed
+func DestroyProgram(programID string) map[string]interface{} {
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+
+	// Store envID before deleting the program
+	envID := programState.envID
+
+	// Remove program from registry FIRST (before checking for remaining programs)
+	programs.remove(programID)
+	emitLifecycleEvent(LifecycleProgramDestroyed, map[string]interface{}{"programID": programID, "envID": envID})
+
+	// Get the environment that created this program
+	envState, envExists := envs.get(envID)
+	if envExists {
+		// Decrement reference counts for all functions in the environment
+		for _, implID := range envState.implIDs {
+			if ref, ok := functionRefs[implID]; ok {
+				ref.refCount--
+				if ref.refCount < 0 {
+					logging.Warn("function reference count dropped below zero", map[string]interface{}{
+						"implID":    implID,
+						"programID": programID,
+						"refCount":  ref.refCount,
+					})
+				}
+				// Unregister function if no longer needed
+				unregisterFunctionIfUnused(implID)
+			}
+		}
+
+		// If environment is destroyed and no programs remain, remove it
+		if envState.destroyed && !hasPrograms(envID) {
+			removeEnv(envID, envState)
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// hasPrograms reports whether any program compiled in an environment remains
+func hasPrograms(envID string) bool {
+	for _, prog := range programs.all() {
+		if prog.envID == envID {
+			return true
+		}
+	}
+	return false
+}
+
+// Shutdown destroys every program, rule set, environment and session, unregisters all
+// function implementations, forgets global functions and libraries and resets the runtime configuration
+// ID counters are kept and handles become stale, so handles from before the shutdown are
+// never mistaken for new ones
+func Shutdown() map[string]interface{} {
+	destroyedPrograms := programs.len()
+	destroyedEnvs := 0
+	for _, envState := range envs.all() {
+		if !envState.destroyed {
+			destroyedEnvs++
+		}
+	}
+
+	implIDs := make([]string, 0, len(functionRefs))
+	for implID := range functionRefs {
+		implIDs = append(implIDs, implID)
+	}
+	for _, funcDef := range globalFunctions {
+		implIDs = append(implIDs, funcDef.ImplID)
+	}
+	implIDs = append(implIDs, libraryImplIDs()...)
+	sort.Strings(implIDs)
+	if loggerImplID != "" {
+		implIDs = append(implIDs, loggerImplID)
+	}
+	if lifecycleListenerImplID != "" {
+		implIDs = append(implIDs, lifecycleListenerImplID)
+	}
+	if unregisterFunctionCaller != nil {
+		for _, implID := range implIDs {
+			unregisterFunctionCaller.UnregisterFunction(implID)
+		}
+	}
+	loggerImplID = ""
+	lifecycleListenerImplID = ""
+	logging.SetSink(nil, logging.LevelWarn)
+
+	programs.reset()
+	ruleSets = make(map[string]*ruleSetState)
+	policies = make(map[string]*policyState)
+	envs.reset()
+	functionRefs = make(map[string]*FunctionRefCount)
+	globalFunctions = nil
+	libraries = make(map[string]*Library)
+	envPool = make(map[string]*pooledEnv)
+	typeRegistries = make(map[string]*sharedRegistry)
+	sessions = make(map[string]*SessionState)
+	runtimeConfig = RuntimeConfig{EvalWorkers: defaultEvalWorkers}
+
+	return map[string]interface{}{
+		"success":           true,
+		"destroyedEnvs":     destroyedEnvs,
+		"destroyedPrograms": destroyedPrograms,
+		"error":             nil,
+	}
+}
@@ -0,0 +1,156 @@
+package celengine
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+)
+
+// EvaluateOptions configures a one-off evaluation with Evaluate
+type EvaluateOptions struct {
+	// Variables declares variables explicitly, in place of the types inferred from their
+	// values, such as timestamps that JSON can only hold as strings
+	Variables []VarDecl `json:"variables,omitempty"`
+	// Functions are custom functions whose implementations were already registered
+	Functions []FunctionDef `json:"functions,omitempty"`
+	// Libraries are names of registered libraries to include
+	Libraries []string `json:"libraries,omitempty"`
+	// Options are environment option configurations, as passed to ExtendEnv
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// Evaluate compiles and evaluates an expression in a single call, declaring each variable
+// with the type inferred from its value, for playgrounds and quick scripts
+// The environment and program only live for the call, and the response holds the
+// compilation issues, the type of the expression and its result. When compilation or
+// evaluation fails, "error" holds why along with whatever was computed before, while
+// invalid declarations and options fail without issues
+func Evaluate(source string, vars map[string]interface{}, options EvaluateOptions) map[string]interface{} {
+	varDecls := inferVarDecls(vars, options.Variables)
+	created := CreateEnvWithConfig(EnvConfig{
+		VarDecls:  varDecls,
+		FuncDefs:  options.Functions,
+		Libraries: options.Libraries,
+		Options:   options.Options,
+	})
+	if created["error"] != nil {
+		return map[string]interface{}{
+			"error": created["error"],
+		}
+	}
+	envID := created["envID"].(string)
+	defer DestroyEnv(envID)
+
+	compiled := CompileDetailedWithFlags(envID, source, nil, CompileFlags{})
+	issues, _ := compiled["issues"].([]interface{})
+	response := map[string]interface{}{
+		"issues": append(make([]interface{}, 0, len(issues)), issues...),
+		"error":  nil,
+	}
+	if compiled["error"] != nil {
+		response["error"] = compiled["error"]
+		return response
+	}
+	programID := compiled["programID"].(string)
+	defer DestroyProgram(programID)
+	programState, _ := programs.get(programID)
+	response["type"] = typeDescription(programState.ast.OutputType())
+
+	// Values are converted to the types they were declared with, so whole numbers are ints
+	// rather than the doubles JSON holds them as
+	activation := make(map[string]interface{}, len(vars))
+	for name, value := range vars {
+		activation[name] = value
+	}
+	for _, varDecl := range varDecls {
+		value, ok := vars[varDecl.Name]
+		if !ok {
+			continue
+		}
+		if declaredType, err := cel.ExprTypeToType(parseTypeDef(varDecl.Type)); err == nil {
+			activation[varDecl.Name] = coerceValue(value, declaredType)
+		}
+	}
+
+	evaluated := Eval(programID, activation)
+	if evaluated["error"] != nil {
+		response["error"] = evaluated["error"]
+		return response
+	}
+	response["result"] = evaluated["result"]
+	return response
+}
+
+// inferVarDecls declares every variable with the type inferred from its value, unless it
+// is declared explicitly
+func inferVarDecls(vars map[string]interface{}, declared []VarDecl) []VarDecl {
+	varDecls := append([]VarDecl{}, declared...)
+	explicit := make(map[string]bool, len(declared))
+	for _, varDecl := range declared {
+		explicit[varDecl.Name] = true
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		if !explicit[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		varDecls = append(varDecls, VarDecl{Name: name, Type: inferType(vars[name])})
+	}
+	return varDecls
+}
+
+// inferType returns the type expression of a JSON value
+// Whole numbers are ints, lists and maps have the common type of their elements, or dyn
+// when they differ, and objects tagged with a registered codec's type have that type
+func inferType(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return "bool"
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= maxSafeInteger {
+			return "int"
+		}
+		return "double"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list<" + commonType(v) + ">"
+	case map[string]interface{}:
+		if tag, ok := v["@type"].(string); ok && (tag == "double" || valueCodecs[tag] != nil) {
+			return tag
+		}
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, item)
+		}
+		return "map<string, " + commonType(values) + ">"
+	}
+	return "dyn"
+}
+
+// commonType returns the type shared by the elements of a list or the values of a map
+// Ints and doubles mixed together are doubles, and any other mix is dyn
+func commonType(values []interface{}) string {
+	common := ""
+	for _, value := range values {
+		t := inferType(value)
+		switch {
+		case common == "" || common == t:
+			common = t
+		case (common == "int" || common == "double") && (t == "int" || t == "double"):
+			common = "double"
+		default:
+			return "dyn"
+		}
+	}
+	if common == "" {
+		return "dyn"
+	}
+	return common
+}
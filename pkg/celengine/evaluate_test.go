@@ -0,0 +1,33 @@
+package celengine
+
+import "testing"
+
+func TestCompilationErrorMessages(t *testing.T) {
+	const expected = "compilation error: ERROR: <input>:1:5: undeclared reference to 'y' (in container '')\n | x + y\n | ....^"
+
+	created := CreateEnv([]VarDecl{{Name: "x", Type: "int"}}, nil)
+	envID, _ := created["envID"].(string)
+	if envID == "" {
+		t.Fatalf("failed to create the environment: %v", created["error"])
+	}
+	defer DestroyEnv(envID)
+
+	tests := []struct {
+		name    string
+		compile func() map[string]interface{}
+	}{
+		{"Evaluate", func() map[string]interface{} {
+			return Evaluate("x + y", map[string]interface{}{"x": 1}, EvaluateOptions{})
+		}},
+		{"Compile", func() map[string]interface{} { return Compile(envID, "x + y") }},
+		{"CompileDetailed", func() map[string]interface{} { return CompileDetailed(envID, "x + y") }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if message := test.compile()["error"]; message != expected {
+				t.Errorf("expected %q, got %q", expected, message)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package celengine
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+	"github.com/google/cel-go/parser"
+)
+
+// explainedOperators are the operators whose operands are explained as the branches of
+// explanation trees, by the operator they're written with
+var explainedOperators = map[string]string{
+	operators.LogicalAnd:  "&&",
+	operators.LogicalOr:   "||",
+	operators.LogicalNot:  "!",
+	operators.Conditional: "?:",
+}
+
+// Explain evaluates a program and explains its result as a tree, for debugging policies
+// The tree follows the logical operators and conditionals of the expression down to the
+// subexpressions they combine. Each node has the source of its subexpression under "expr"
+// and its "outcome": "true" or "false" for booleans, "value" for other values, "error",
+// or "skipped" for subexpressions that short-circuiting never evaluated. Evaluated nodes
+// have their "value" and failed ones their "error". Nodes of the operators also have their
+// "operator", such as "&&" or "?:", and their "operands". Chains of the same operator,
+// such as a && b && c, are flattened into one node
+// The response holds the "result" and the tree under "explanation", or the error value
+// the expression evaluated to under "errorValue"
+func Explain(programID string, vars map[string]interface{}, encoding ValueEncoding) map[string]interface{} {
+	if err := encoding.validate(); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+	touchProgram(programState)
+	envState, ok := envs.get(programState.envID)
+	if !ok {
+		return envs.notFound(programState.envID)
+	}
+	encoding = programState.inputs.semantics.withDefaults(encoding)
+
+	// The program is planned again to track the value of each subexpression
+	programOptions := []cel.ProgramOption{}
+	if programState.source != nil {
+		var err error
+		if programOptions, err = parseProgramOptions(programState.source.programOptionsJSON); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to create program options: %v", err),
+			}
+		}
+	}
+	programOptions = append(programOptions, cel.EvalOptions(cel.OptTrackState))
+	prg, err := envState.env.Program(programState.ast, programOptions...)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to create program: %v", err),
+		}
+	}
+
+	// Tagged doubles stand in for NaN and ±Inf, which JSON can't represent
+	decoded, _ := decodeTaggedMap(vars)
+	if encoding.integerStrings() {
+		decoded, _ = decodeIntegerStrings(decoded, programState.readVariables())
+	}
+	activation, _, err := programState.inputs.bind(decoded, EvalOptions{})
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	evalCalls = &callCache{}
+	out, details, err := prg.Eval(activation)
+	evalCalls = nil
+	response := map[string]interface{}{
+		"result": nil,
+		"error":  nil,
+	}
+	switch out := out.(type) {
+	case *types.Err:
+		response["errorValue"] = errorValueToJSON(out, programState.ast)
+	default:
+		if err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("evaluation error: %v", err),
+			}
+		}
+		response["result"] = ValueToJSONWithEncoding(out, encoding)
+	}
+
+	e := &explainer{
+		info:     programState.ast.NativeRep().SourceInfo(),
+		state:    details.State(),
+		encoding: encoding,
+	}
+	response["explanation"] = e.explain(programState.ast.NativeRep().Expr())
+	return response
+}
+
+// explainer builds the explanation tree of an evaluation from its tracked state
+type explainer struct {
+	info     *ast.SourceInfo
+	state    interpreter.EvalState
+	encoding ValueEncoding
+}
+
+// explain explains a subexpression and, for the operators explained as branches, its
+// operands
+func (e *explainer) explain(expr ast.Expr) map[string]interface{} {
+	node := map[string]interface{}{
+		"expr": e.source(expr),
+	}
+	if location := e.info.GetStartLocation(expr.ID()); location.Line() > 0 {
+		node["location"] = map[string]interface{}{
+			"line":   location.Line(),
+			"column": location.Column(),
+		}
+	}
+
+	val, evaluated := e.state.Value(expr.ID())
+	switch {
+	case !evaluated:
+		node["outcome"] = "skipped"
+	case types.IsError(val):
+		node["outcome"] = "error"
+		node["error"] = fmt.Sprintf("%v", val)
+	case val.Type() == types.BoolType:
+		node["outcome"] = fmt.Sprintf("%v", val.Value())
+		node["value"] = val.Value()
+	default:
+		node["outcome"] = "value"
+		node["value"] = ValueToJSONWithEncoding(val, e.encoding)
+	}
+
+	if expr.Kind() != ast.CallKind {
+		return node
+	}
+	function := expr.AsCall().FunctionName()
+	operator, ok := explainedOperators[function]
+	if !ok {
+		return node
+	}
+	node["operator"] = operator
+	operands := make([]interface{}, 0)
+	for _, operand := range e.operands(expr, function) {
+		operands = append(operands, e.explain(operand))
+	}
+	node["operands"] = operands
+	return node
+}
+
+// operands returns the operands of a call, flattening the operands that are calls of the
+// same logical operator
+func (e *explainer) operands(expr ast.Expr, function string) []ast.Expr {
+	var operands []ast.Expr
+	for _, arg := range expr.AsCall().Args() {
+		if (function == operators.LogicalAnd || function == operators.LogicalOr) &&
+			arg.Kind() == ast.CallKind && arg.AsCall().FunctionName() == function {
+			operands = append(operands, e.operands(arg, function)...)
+			continue
+		}
+		operands = append(operands, arg)
+	}
+	return operands
+}
+
+// source returns the source of a subexpression, as unparsed from the AST
+func (e *explainer) source(expr ast.Expr) string {
+	source, err := parser.Unparse(expr, e.info)
+	if err != nil {
+		return ""
+	}
+	return source
+}
@@ -0,0 +1,251 @@
+package celengine
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encodings accepted by ExportCheckedExpr and ProgramFromCheckedExpr
+const (
+	CheckedExprBinary    = "binary"    // Protobuf wire format
+	CheckedExprJSON      = "json"      // Protobuf JSON mapping
+	CheckedExprTextproto = "textproto" // Protobuf text format
+)
+
+// ExportCheckedExpr encodes the checked expression of a program as a
+// google.api.expr.v1alpha1.CheckedExpr, so other CEL runtimes such as cel-cpp or cel-java
+// can evaluate exactly what was checked here. Constants and optimizations are already
+// folded into it, and the runtime has to provide the environment's custom functions
+// The expression is returned under "checkedExpr", as bytes in the binary encoding and as
+// a string otherwise. The encoding defaults to binary
+func ExportCheckedExpr(programID string, format string) map[string]interface{} {
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+	touchProgram(programState)
+
+	checked, err := cel.AstToCheckedExpr(programState.ast)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to convert checked expression: %v", err),
+		}
+	}
+
+	var exported interface{}
+	switch format {
+	case "", CheckedExprBinary:
+		exported, err = proto.Marshal(checked)
+	case CheckedExprJSON:
+		var data []byte
+		data, err = protojson.Marshal(checked)
+		exported = string(data)
+	case CheckedExprTextproto:
+		var data []byte
+		data, err = prototext.Marshal(checked)
+		exported = string(data)
+	default:
+		return map[string]interface{}{
+			"error": fmt.Sprintf("invalid checked expression format %q: expected %q, %q or %q", format, CheckedExprBinary, CheckedExprJSON, CheckedExprTextproto),
+		}
+	}
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to encode checked expression: %v", err),
+		}
+	}
+
+	return map[string]interface{}{
+		"checkedExpr": exported,
+		"error":       nil,
+	}
+}
+
+// SignedCheckedExpr is a checked expression signed with Ed25519 by the backend that
+// checked it, in any of the encodings of ExportCheckedExpr
+// It carries the encoded expression as it was signed, so verifying doesn't depend on how
+// it's re-encoded
+type SignedCheckedExpr struct {
+	CheckedExpr []byte `json:"checkedExpr"`
+	Signature   []byte `json:"signature"`
+}
+
+// SignCheckedExpr signs an encoded checked expression with an Ed25519 private key and
+// returns the encoded SignedCheckedExpr, for Go backends producing checked expressions
+// for ProgramFromCheckedExprWithKey
+func SignCheckedExpr(data []byte, privateKey ed25519.PrivateKey) ([]byte, error) {
+	signature, err := sign(data, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SignedCheckedExpr{
+		CheckedExpr: data,
+		Signature:   signature,
+	})
+}
+
+// ProgramFromCheckedExprWithKey compiles a checked expression like ProgramFromCheckedExpr,
+// requiring data to be a SignedCheckedExpr signed with the private key of publicKey, so
+// the rules that run are the ones the backend signed
+func ProgramFromCheckedExprWithKey(envID string, data []byte, format string, programOptionsJSON *string, flags CompileFlags, publicKey []byte) map[string]interface{} {
+	if publicKey == nil {
+		return map[string]interface{}{
+			"error": "a public key is required to verify a signed checked expression",
+		}
+	}
+	if err := checkPublicKey(publicKey); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	var signed SignedCheckedExpr
+	if err := json.Unmarshal(data, &signed); err != nil || signed.Signature == nil {
+		return map[string]interface{}{
+			"error": "checked expression isn't signed",
+		}
+	}
+	if !ed25519.Verify(publicKey, signed.CheckedExpr, signed.Signature) {
+		return map[string]interface{}{
+			"error": "checked expression signature verification failed",
+		}
+	}
+	return ProgramFromCheckedExpr(envID, signed.CheckedExpr, format, programOptionsJSON, flags)
+}
+
+// ProgramFromCheckedExpr compiles a google.api.expr.v1alpha1.CheckedExpr, such as one
+// checked by another CEL runtime on a backend, into a program of an environment
+// The expression is checked again against the environment, and the identifiers and
+// overloads it references must resolve here the way they did where it was checked, so
+// the program can't silently read a variable of another type or call another function.
+// The program options and flags are those of CompileWithFlags. Imported programs have no
+// source, so they can't be recompiled or replaced
+// The expression isn't verified, which ProgramFromCheckedExprWithKey does
+func ProgramFromCheckedExpr(envID string, data []byte, format string, programOptionsJSON *string, flags CompileFlags) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	checked := &exprpb.CheckedExpr{}
+	var err error
+	switch format {
+	case "", CheckedExprBinary:
+		err = proto.Unmarshal(data, checked)
+	case CheckedExprJSON:
+		err = protojson.Unmarshal(data, checked)
+	case CheckedExprTextproto:
+		err = prototext.Unmarshal(data, checked)
+	default:
+		return map[string]interface{}{
+			"error": fmt.Sprintf("invalid checked expression format %q: expected %q, %q or %q", format, CheckedExprBinary, CheckedExprJSON, CheckedExprTextproto),
+		}
+	}
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to decode checked expression: %v", err),
+		}
+	}
+	if checked.GetExpr() == nil {
+		return map[string]interface{}{
+			"error": "failed to decode checked expression: no expression",
+		}
+	}
+
+	imported, err := cel.CheckedExprToAstWithSource(checked, nil)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("invalid checked expression: %v", err),
+		}
+	}
+
+	timings := newCallMetrics(flags.Metrics)
+	parsed := cel.ParsedExprToAst(&exprpb.ParsedExpr{Expr: checked.GetExpr(), SourceInfo: checked.GetSourceInfo()})
+	start := time.Now()
+	ast, issues := envState.env.Check(parsed)
+	timings.track("checkMs", start)
+	if issues != nil && issues.Err() != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("compilation error: %v", issues.Err()),
+		}
+	}
+	if err := matchReferences(imported, ast); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("checked expression doesn't match the environment: %v", err),
+		}
+	}
+
+	ast, prg, memo, err := planProgram(envState, ast, programOptionsJSON, flags, timings)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	response := map[string]interface{}{
+		"programID": addProgram(envID, envState, prg, ast, memo, nil),
+		"error":     nil,
+	}
+	if flags.OptimizedSource {
+		addOptimizedSource(response, ast)
+	}
+	if flags.FieldMask {
+		addFieldMask(response, ast)
+	}
+	return timings.addTo(response, false)
+}
+
+// matchReferences reports the first reference of an imported checked expression that
+// resolves differently in the expression checked again
+// An overload of the imported expression must be among the overloads a call resolves to
+// here, since a checker with less type information may leave more of them to runtime
+func matchReferences(imported, checked *cel.Ast) error {
+	want := imported.NativeRep().ReferenceMap()
+	got := checked.NativeRep().ReferenceMap()
+
+	ids := make([]int64, 0, len(want))
+	for id := range want {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		reference, resolved := want[id], got[id]
+		if len(reference.OverloadIDs) > 0 {
+			for _, overloadID := range reference.OverloadIDs {
+				if resolved == nil || !slices.Contains(resolved.OverloadIDs, overloadID) {
+					return fmt.Errorf("overload %s doesn't apply here", overloadID)
+				}
+			}
+			continue
+		}
+
+		if resolved == nil || resolved.Name != reference.Name {
+			return fmt.Errorf("identifier %s isn't declared", reference.Name)
+		}
+		wantType := imported.NativeRep().GetType(id)
+		gotType := checked.NativeRep().GetType(id)
+		if !wantType.IsExactType(gotType) {
+			return fmt.Errorf("identifier %s is %s here, not %s", reference.Name, gotType, wantType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package celengine
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+)
+
+// fieldMask lists the paths into the variables that a checked expression reads
+// Each path starts with a variable name followed by the fields selected from it. A path
+// covers everything below it, so callers can drop the rest of the variables before
+// evaluation without changing the result
+func fieldMask(checked *cel.Ast) [][]string {
+	native := checked.NativeRep()
+	collector := &fieldMaskCollector{
+		native:     native,
+		references: native.ReferenceMap(),
+		paths:      make(map[string][]string),
+	}
+	collector.visit(native.Expr(), nil)
+
+	paths := make([][]string, 0, len(collector.paths))
+	for _, path := range collector.paths {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return strings.Join(paths[i], "\x00") < strings.Join(paths[j], "\x00")
+	})
+
+	// Drop paths that are already covered by a shorter one
+	mask := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		if len(mask) > 0 && hasPathPrefix(path, mask[len(mask)-1]) {
+			continue
+		}
+		mask = append(mask, path)
+	}
+	return mask
+}
+
+// addFieldMask adds the field mask of a compiled expression to a compilation result
+// Paths are converted to generic slices, which the WASM bridge can pass to JavaScript
+func addFieldMask(response map[string]interface{}, compiled *cel.Ast) {
+	mask := fieldMask(compiled)
+	paths := make([]interface{}, len(mask))
+	for i, path := range mask {
+		elems := make([]interface{}, len(path))
+		for j, elem := range path {
+			elems[j] = elem
+		}
+		paths[i] = elems
+	}
+	response["fieldMask"] = paths
+}
+
+// hasPathPrefix reports whether a path starts with a prefix
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, elem := range prefix {
+		if path[i] != elem {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldMaskCollector walks an expression collecting the variable paths it reads
+type fieldMaskCollector struct {
+	native     *ast.AST
+	references map[int64]*ast.ReferenceInfo
+	paths      map[string][]string
+	variables  map[string]*types.Type // Types of the variables read, if requested
+}
+
+// visit records the paths read by an expression, where locals are the names bound by
+// enclosing comprehensions, which shadow variables of the same name
+func (c *fieldMaskCollector) visit(e ast.Expr, locals map[string]bool) {
+	if path, ok := c.selectPath(e, locals); ok {
+		c.paths[strings.Join(path, "\x00")] = path
+		return
+	}
+
+	switch e.Kind() {
+	case ast.SelectKind:
+		c.visit(e.AsSelect().Operand(), locals)
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.IsMemberFunction() {
+			c.visit(call.Target(), locals)
+		}
+		for _, arg := range call.Args() {
+			c.visit(arg, locals)
+		}
+	case ast.ListKind:
+		for _, elem := range e.AsList().Elements() {
+			c.visit(elem, locals)
+		}
+	case ast.MapKind:
+		for _, entry := range e.AsMap().Entries() {
+			c.visit(entry.AsMapEntry().Key(), locals)
+			c.visit(entry.AsMapEntry().Value(), locals)
+		}
+	case ast.StructKind:
+		for _, field := range e.AsStruct().Fields() {
+			c.visit(field.AsStructField().Value(), locals)
+		}
+	case ast.ComprehensionKind:
+		comp := e.AsComprehension()
+		c.visit(comp.IterRange(), locals)
+		c.visit(comp.AccuInit(), locals)
+
+		scoped := make(map[string]bool, len(locals)+3)
+		for name := range locals {
+			scoped[name] = true
+		}
+		scoped[comp.IterVar()] = true
+		if comp.HasIterVar2() {
+			scoped[comp.IterVar2()] = true
+		}
+		scoped[comp.AccuVar()] = true
+		c.visit(comp.LoopCondition(), scoped)
+		c.visit(comp.LoopStep(), scoped)
+		c.visit(comp.Result(), scoped)
+	}
+}
+
+// selectPath returns the path of a variable reference followed by field selections
+// and indexes with constant string keys
+func (c *fieldMaskCollector) selectPath(e ast.Expr, locals map[string]bool) ([]string, bool) {
+	// Qualified variable names are resolved to a single reference by the checker
+	// Type names, such as in `type(x) == int`, are references too but aren't variables
+	if reference, ok := c.references[e.ID()]; ok && reference.Value == nil && len(reference.OverloadIDs) == 0 {
+		if e.Kind() == ast.IdentKind && locals[e.AsIdent()] || c.native.GetType(e.ID()).Kind() == types.TypeKind {
+			return nil, false
+		}
+		if c.variables != nil {
+			c.variables[reference.Name] = c.native.GetType(e.ID())
+		}
+		return []string{reference.Name}, true
+	}
+
+	switch e.Kind() {
+	case ast.SelectKind:
+		sel := e.AsSelect()
+		if path, ok := c.selectPath(sel.Operand(), locals); ok {
+			return append(path, sel.FieldName()), true
+		}
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.FunctionName() != operators.Index || len(call.Args()) != 2 {
+			return nil, false
+		}
+		key := call.Args()[1]
+		if key.Kind() != ast.LiteralKind {
+			return nil, false
+		}
+		name, ok := key.AsLiteral().(types.String)
+		if !ok {
+			return nil, false
+		}
+		if path, ok := c.selectPath(call.Args()[0], locals); ok {
+			return append(path, string(name)), true
+		}
+	}
+	return nil, false
+}
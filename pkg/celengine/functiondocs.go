@@ -0,0 +1,145 @@
+package celengine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/decls"
+)
+
+// ListFunctionsOptions filters the functions listed by ListFunctions
+type ListFunctionsOptions struct {
+	// Query keeps the functions and macros whose name or description contains it,
+	// ignoring case. Empty keeps all of them
+	Query string `json:"query,omitempty"`
+}
+
+// callableName matches the names of functions that can be written in expressions, as
+// opposed to operators such as _+_ and internal functions such as @in
+var callableName = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*(\.[_a-zA-Z][_a-zA-Z0-9]*)*$`)
+
+// ListFunctions lists the functions and macros an environment offers, with the signature
+// and examples of each overload and their documentation, for autocomplete and help panels
+// The standard library and extension libraries document their functions in cel-go, so
+// those carry descriptions and examples. Operators are left out
+// Functions whose name starts with the query come first, then those whose name contains
+// it, then those whose description does
+func ListFunctions(envID string, options ListFunctionsOptions) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	var docs []*common.Doc
+	for name, function := range envState.env.Functions() {
+		if callableName.MatchString(name) && !function.IsDeclarationDisabled() {
+			docs = append(docs, function.Documentation())
+		}
+	}
+	// Forms of a macro with different numbers of arguments, such as map, are documented
+	// separately, so their documentation is merged
+	macros := make(map[string]*common.Doc)
+	for _, macro := range envState.env.Macros() {
+		documentor, ok := macro.(common.Documentor)
+		if !ok {
+			continue
+		}
+		doc := documentor.Documentation()
+		merged, ok := macros[doc.Name]
+		if !ok {
+			merged = common.NewMacroDoc(doc.Name, "")
+			macros[doc.Name] = merged
+			docs = append(docs, merged)
+		}
+		if doc.Description != "" && merged.Description != "" {
+			merged.Description += "\n\n"
+		}
+		merged.Description += doc.Description
+		merged.Children = append(merged.Children, doc.Children...)
+	}
+
+	query := strings.ToLower(options.Query)
+	ranks := make(map[*common.Doc]int, len(docs))
+	matching := docs[:0]
+	for _, doc := range docs {
+		name := strings.ToLower(doc.Name)
+		switch {
+		case strings.HasPrefix(name, query):
+			ranks[doc] = 0
+		case strings.Contains(name, query):
+			ranks[doc] = 1
+		case strings.Contains(strings.ToLower(doc.Description), query):
+			ranks[doc] = 2
+		default:
+			continue
+		}
+		matching = append(matching, doc)
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if ranks[matching[i]] != ranks[matching[j]] {
+			return ranks[matching[i]] < ranks[matching[j]]
+		}
+		if matching[i].Name != matching[j].Name {
+			return matching[i].Name < matching[j].Name
+		}
+		return matching[i].Kind < matching[j].Kind
+	})
+
+	functions := make([]interface{}, 0, len(matching))
+	for _, doc := range matching {
+		functions = append(functions, functionHelp(doc))
+	}
+	return map[string]interface{}{
+		"functions": functions,
+		"error":     nil,
+	}
+}
+
+// functionHelp converts the documentation of a function or macro to its JSON form
+func functionHelp(doc *common.Doc) map[string]interface{} {
+	signatures := make([]interface{}, 0)
+	examples := make([]interface{}, 0)
+	for _, child := range doc.Children {
+		switch child.Kind {
+		case common.DocOverload:
+			signatures = append(signatures, child.Signature)
+			for _, example := range child.Children {
+				examples = append(examples, example.Description)
+			}
+		case common.DocExample:
+			examples = append(examples, child.Description)
+		}
+	}
+
+	help := map[string]interface{}{
+		"name":        doc.Name,
+		"description": doc.Description,
+		"signatures":  signatures,
+		"examples":    examples,
+	}
+	if doc.Kind == common.DocMacro {
+		help["macro"] = true
+	}
+	return help
+}
+
+// overloadSignatures returns the signature cel-go documents for each overload of a
+// function, by overload ID
+func overloadSignatures(function *decls.FunctionDecl) map[string]string {
+	signatures := make(map[string]string)
+	for _, child := range function.Documentation().Children {
+		signatures[child.Name] = child.Signature
+	}
+	return signatures
+}
@@ -0,0 +1,155 @@
+package celengine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Seeds taken from the shapes of real-world rule sets: Kubernetes validation rules,
+// Envoy RBAC conditions and feature flag targeting
+var (
+	typeDefSeeds = []string{
+		`"string"`,
+		`"timestamp"`,
+		`{"kind": "list", "elementType": "string"}`,
+		`{"kind": "list", "elementType": {"kind": "map", "keyType": "string", "valueType": "dyn"}}`,
+		`{"kind": "map", "keyType": "string", "valueType": {"kind": "list", "elementType": "int"}}`,
+		`{"kind": "map", "keyType": {"kind": "list"}, "valueType": 5}`,
+		`{"type": "double"}`,
+		`{"name": "bytes"}`,
+		`"list<map<string,int>>"`,
+		`"map<string, list<double>>"`,
+		`{"kind": "list", "elementType": "map<string, list<"}`,
+		`[]`,
+		`null`,
+	}
+
+	valueSeeds = []string{
+		`{"metadata": {"name": "web", "labels": {"app": "web", "tier": "frontend"}}, "spec": {"replicas": 3}}`,
+		`{"request": {"path": "/admin", "headers": {"x-user": "alice"}}, "source": {"address": "10.0.0.1"}}`,
+		`{"user": {"id": "u-1", "country": "DE", "plan": "pro", "signupDays": 42}, "flags": ["beta", "dark"]}`,
+		`[1, 2.5, -3e10, "x", true, null, [], {}]`,
+		`1.7976931348623157e308`,
+		`""`,
+	}
+
+	optionsSeeds = []string{
+		`[{"type": "OptionalTypes"}]`,
+		`[{"type": "OptionalTypes", "params": {"opts": [{"type": "OptionalTypesVersion", "params": {"version": 1}}]}}]`,
+		`[{"type": "ext.Strings", "params": {"options": [{"type": "StringsVersion", "params": {"version": 2}}]}}]`,
+		`[{"type": "CrossTypeNumericComparisons", "params": {"enabled": true}}]`,
+		`[{"type": "DefaultUTCTimeZone", "params": {"enabled": false}}]`,
+		`[{"type": "ASTValidators", "params": {"validators": [], "options": {"failOnWarning": true}}}]`,
+		`[{"type": "Unknown"}]`,
+		`[{"type": "OptionalTypes", "params": {"opts": "nope"}}]`,
+		`{}`,
+	}
+
+	expressionSeeds = []string{
+		`self.spec.replicas <= 10 && self.metadata.name.startsWith("web")`,
+		`has(self.metadata.labels) && self.metadata.labels.all(k, k.size() < 64)`,
+		`request.path.startsWith("/admin") && request.headers["x-user"] in ["alice", "bob"]`,
+		`user.country in ["DE", "FR"] && user.plan == "pro" ? "enabled" : "disabled"`,
+		`flags.exists(f, f == "beta") || user.signupDays > 30`,
+		`[1, 2, 3].map(x, x * 2).filter(x, x > 2).size() == 2`,
+		`duration("1h") < duration("2h") && timestamp("2024-01-01T00:00:00Z").getFullYear() == 2024`,
+		`{"a": 1}.?b.orValue(0)`,
+		`1 / 0`,
+		`((((`,
+	}
+)
+
+func FuzzParseTypeDef(f *testing.F) {
+	for _, seed := range typeDefSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var typeDef interface{}
+		if err := json.Unmarshal([]byte(data), &typeDef); err != nil {
+			return
+		}
+
+		exprType := parseTypeDef(typeDef)
+		if exprType == nil {
+			t.Fatalf("parseTypeDef(%s) returned nil", data)
+		}
+		typeToJSON(exprType)
+	})
+}
+
+func FuzzJSONToValue(f *testing.F) {
+	for _, seed := range valueSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var value interface{}
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			return
+		}
+
+		converted := JSONToValue(value)
+		if converted == nil {
+			t.Fatalf("JSONToValue(%s) returned nil", data)
+		}
+		ValueToJSON(converted)
+	})
+}
+
+func FuzzOptionsJSON(f *testing.F) {
+	for _, seed := range optionsSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, optionsJSON string) {
+		result := CreateEnvWithOptions(nil, nil, &optionsJSON)
+		if envID, ok := result["envID"].(string); ok {
+			DestroyEnv(envID)
+		}
+
+		parseProgramOptions(&optionsJSON)
+	})
+}
+
+func FuzzCompile(f *testing.F) {
+	for _, seed := range expressionSeeds {
+		f.Add(seed)
+	}
+
+	varDecls := []VarDecl{
+		{Name: "self", Type: "dyn"},
+		{Name: "request", Type: "dyn"},
+		{Name: "user", Type: "dyn"},
+		{Name: "flags", Type: map[string]interface{}{"kind": "list", "elementType": "string"}},
+	}
+	options := `[{"type": "OptionalTypes"}]`
+	env := CreateEnvWithOptions(varDecls, nil, &options)
+	if env["error"] != nil {
+		f.Fatalf("failed to create environment: %v", env["error"])
+	}
+	envID := env["envID"].(string)
+
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"self": {"metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 3}},
+		"request": {"path": "/admin", "headers": {"x-user": "alice"}},
+		"user": {"country": "DE", "plan": "pro", "signupDays": 42},
+		"flags": ["beta"]
+	}`), &vars); err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, expr string) {
+		Typecheck(envID, expr)
+
+		result := CompileDetailed(envID, expr)
+		programID, ok := result["programID"].(string)
+		if !ok {
+			return
+		}
+		defer DestroyProgram(programID)
+
+		Eval(programID, vars)
+	})
+}
@@ -0,0 +1,179 @@
+package celengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RuntimeConfig configures automatic cleanup of idle environments and programs, how
+// many asynchronous evaluations run at once, and the default memory budget of evaluations
+// A TTL or memory budget of 0 disables cleanup for that kind of handle or the budget
+type RuntimeConfig struct {
+	ProgramTTLms         int64 `json:"programTTLms"`
+	EnvTTLms             int64 `json:"envTTLms"`
+	EvalWorkers          int   `json:"evalWorkers"`
+	EvalMemoryLimitBytes int64 `json:"evalMemoryLimitBytes"`
+}
+
+// minSweepInterval bounds how often the sweeper runs for very short TTLs
+const minSweepInterval = 10 * time.Millisecond
+
+// defaultEvalWorkers is the number of asynchronous evaluations that run at once by default
+const defaultEvalWorkers = 4
+
+var (
+	runtimeConfig = RuntimeConfig{EvalWorkers: defaultEvalWorkers}
+	lastSweep     time.Time
+)
+
+// Configure updates the runtime configuration from a JSON object
+// Fields that are omitted keep their current value. The result includes the
+// interval the host should call Sweep at, which is 0 when no TTL is set
+func Configure(configJSON string) map[string]interface{} {
+	var update struct {
+		ProgramTTLms         *int64 `json:"programTTLms"`
+		EnvTTLms             *int64 `json:"envTTLms"`
+		EvalWorkers          *int   `json:"evalWorkers"`
+		EvalMemoryLimitBytes *int64 `json:"evalMemoryLimitBytes"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &update); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse configuration: %v", err),
+		}
+	}
+
+	config := runtimeConfig
+	if update.ProgramTTLms != nil {
+		config.ProgramTTLms = *update.ProgramTTLms
+	}
+	if update.EnvTTLms != nil {
+		config.EnvTTLms = *update.EnvTTLms
+	}
+	if update.EvalWorkers != nil {
+		config.EvalWorkers = *update.EvalWorkers
+	}
+	if update.EvalMemoryLimitBytes != nil {
+		config.EvalMemoryLimitBytes = *update.EvalMemoryLimitBytes
+	}
+	if config.ProgramTTLms < 0 || config.EnvTTLms < 0 {
+		return map[string]interface{}{
+			"error": "TTLs must not be negative",
+		}
+	}
+	if config.EvalWorkers < 1 {
+		return map[string]interface{}{
+			"error": "evalWorkers must be at least 1",
+		}
+	}
+	if config.EvalMemoryLimitBytes < 0 {
+		return map[string]interface{}{
+			"error": "evalMemoryLimitBytes must not be negative",
+		}
+	}
+	runtimeConfig = config
+
+	return map[string]interface{}{
+		"config": map[string]interface{}{
+			"programTTLms":         config.ProgramTTLms,
+			"envTTLms":             config.EnvTTLms,
+			"evalWorkers":          config.EvalWorkers,
+			"evalMemoryLimitBytes": config.EvalMemoryLimitBytes,
+		},
+		"sweepIntervalms": SweepInterval().Milliseconds(),
+		"error":           nil,
+	}
+}
+
+// EvalWorkers returns how many asynchronous evaluations may run at once
+func EvalWorkers() int {
+	return runtimeConfig.EvalWorkers
+}
+
+// SweepInterval returns how often Sweep should run for the current configuration,
+// or 0 if no TTL is set
+func SweepInterval() time.Duration {
+	var ttl int64
+	for _, value := range []int64{runtimeConfig.ProgramTTLms, runtimeConfig.EnvTTLms} {
+		if value > 0 && (ttl == 0 || value < ttl) {
+			ttl = value
+		}
+	}
+	if ttl == 0 {
+		return 0
+	}
+
+	// Sweeping at half the TTL keeps handles from outliving it by more than 50%
+	interval := time.Duration(ttl) * time.Millisecond / 2
+	if interval < minSweepInterval {
+		interval = minSweepInterval
+	}
+	return interval
+}
+
+// touchEnv records that an environment was just used
+func touchEnv(envState *EnvState) {
+	envState.lastUsed = time.Now()
+}
+
+// touchProgram records that a program was just used
+// Using a program also keeps the environment that created it alive
+func touchProgram(programState *ProgramState) {
+	programState.lastUsed = time.Now()
+	if envState, ok := envs.get(programState.envID); ok {
+		envState.lastUsed = programState.lastUsed
+	}
+}
+
+// Sweep destroys programs and environments that have been idle for longer than their TTL
+// Destroyed environments follow the same rules as DestroyEnv, so they stay around
+// until their remaining programs are destroyed
+func Sweep() map[string]interface{} {
+	now := time.Now()
+	lastSweep = now
+
+	var programIDs []string
+	if runtimeConfig.ProgramTTLms > 0 {
+		ttl := time.Duration(runtimeConfig.ProgramTTLms) * time.Millisecond
+		for programID, programState := range programs.all() {
+			if now.Sub(programState.lastUsed) > ttl {
+				programIDs = append(programIDs, programID)
+			}
+		}
+		sort.Strings(programIDs)
+		for _, programID := range programIDs {
+			DestroyProgram(programID)
+		}
+	}
+
+	var envIDs []string
+	if runtimeConfig.EnvTTLms > 0 {
+		ttl := time.Duration(runtimeConfig.EnvTTLms) * time.Millisecond
+		for envID, envState := range envs.all() {
+			if !envState.destroyed && now.Sub(envState.lastUsed) > ttl {
+				envIDs = append(envIDs, envID)
+			}
+		}
+		sort.Strings(envIDs)
+		for _, envID := range envIDs {
+			DestroyEnv(envID)
+		}
+	}
+
+	return map[string]interface{}{
+		"destroyedEnvs":     len(envIDs),
+		"destroyedPrograms": len(programIDs),
+		"error":             nil,
+	}
+}
+
+// SweepIfDue runs Sweep if a sweep interval has passed since the last one
+// Hosts that can't run a timer call this before handling each request
+func SweepIfDue() {
+	interval := SweepInterval()
+	if interval == 0 || time.Since(lastSweep) < interval {
+		return
+	}
+	Sweep()
+}
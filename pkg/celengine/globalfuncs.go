@@ -0,0 +1,57 @@
+package celengine
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// globalFunctions are declared in every environment created after they were defined
+var globalFunctions []FunctionDef
+
+// DefineGlobalFunction registers a custom function that is declared in every environment
+// created afterwards, in addition to the environment's own functions
+// Its implementation is never unregistered by environment cleanup, only by Shutdown
+func DefineGlobalFunction(funcDef FunctionDef) map[string]interface{} {
+	if funcDef.Name == "" || funcDef.ImplID == "" {
+		return map[string]interface{}{
+			"error": "global function requires a name and an implID",
+		}
+	}
+	for _, existing := range globalFunctions {
+		if existing.ImplID == funcDef.ImplID {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("global function implementation already defined: %s", funcDef.ImplID),
+			}
+		}
+	}
+
+	// Declare the global functions together, so conflicting overloads are reported now
+	// rather than when the next environment is created
+	defs := append(append([]FunctionDef{}, globalFunctions...), funcDef)
+	funcDecls := make([]*exprpb.Decl, 0, len(defs))
+	opts := make([]cel.EnvOption, 0, len(defs)+1)
+	for _, def := range defs {
+		funcDecl, funcImpl, err := functionDeclaration(def, parseTypeDef)
+		if err != nil {
+			return map[string]interface{}{
+				"error": err.Error(),
+			}
+		}
+		funcDecls = append(funcDecls, funcDecl)
+		opts = append(opts, funcImpl)
+	}
+	opts = append(opts, cel.Declarations(funcDecls...))
+	if _, err := cel.NewEnv(opts...); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("invalid global function %s: %v", funcDef.Name, err),
+		}
+	}
+
+	globalFunctions = defs
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
@@ -0,0 +1,259 @@
+package celengine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// StaleHandleErrorCode is the error code of results for handles whose object was
+// destroyed, such as the ID of a destroyed program, or that were issued by another
+// instance of the module
+const StaleHandleErrorCode = "stale_handle"
+
+// instanceNonce namespaces the IDs issued by this instance of the module, so the IDs of
+// an instance loaded before a reload are rejected rather than mistaken for the IDs of
+// the objects of this one
+var instanceNonce = newInstanceNonce()
+
+// newInstanceNonce returns a random nonce of 8 hex digits
+func newInstanceNonce() string {
+	var nonce [4]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate the instance nonce: %v", err))
+	}
+	return hex.EncodeToString(nonce[:])
+}
+
+// InstanceNonce returns the nonce the IDs issued by this instance of the module start
+// with, after their prefix
+func InstanceNonce() string {
+	return instanceNonce
+}
+
+// newID returns the ID of the nth object of a kind not held in a handleTable, such as
+// policy_<nonce>_3 for the third policy
+func newID(prefix string, n int64) string {
+	return prefix + "_" + instanceNonce + "_" + strconv.FormatInt(n, 10)
+}
+
+// idNotFound returns the result of a call with an ID without an object: a stale handle
+// error for the IDs of another instance of the module, or a not found error otherwise
+func idNotFound(kind string, prefix string, id string) map[string]interface{} {
+	rest, ok := strings.CutPrefix(id, prefix+"_")
+	if !ok {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("%s not found: %s", kind, id),
+		}
+	}
+	nonce, _, _ := strings.Cut(rest, "_")
+	if len(nonce) == len(instanceNonce) && nonce != instanceNonce {
+		return staleHandleResult(id, fmt.Sprintf("stale %s handle: %s was issued by another instance of the module, such as one loaded before a reload", kind, id))
+	}
+	return map[string]interface{}{
+		"error": fmt.Sprintf("%s not found: %s", kind, id),
+	}
+}
+
+// staleHandleResult returns the result of a call with a stale handle, with the error
+// code and the handle next to the error message
+func staleHandleResult(id string, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"error":     message,
+		"errorCode": StaleHandleErrorCode,
+		"handle":    id,
+	}
+}
+
+// handleTable holds the objects of one kind, such as environments, in a slab of slots
+// addressed by integer handles
+// A handle is the index of a slot and the generation of the slot when the handle was
+// issued. Freeing a slot moves it to the next generation, so the handles of destroyed
+// objects are told apart from the handles of the objects reusing their slots, and
+// reported as stale rather than resolving to the wrong object.
+// Handles are passed to JavaScript as IDs of the form <prefix>_<nonce>_<index>_<generation>,
+// with the nonce of the instance.
+type handleTable[T any] struct {
+	kind     string // What the objects are, for errors
+	prefix   string // Prefix of the IDs
+	idPrefix string // Start of the IDs issued by this instance, <prefix>_<nonce>_
+	slots    []handleSlot[T]
+	free     []uint32 // Indexes of the free slots, reused last freed first
+	count    int
+}
+
+// handleSlot is a slot of a handleTable
+type handleSlot[T any] struct {
+	generation uint32
+	value      *T   // nil when the slot is free or reserved
+	used       bool // Whether the slot is taken, even if its value isn't set yet
+}
+
+// newHandleTable creates an empty table of objects
+func newHandleTable[T any](kind string, prefix string) *handleTable[T] {
+	return &handleTable[T]{kind: kind, prefix: prefix, idPrefix: prefix + "_" + instanceNonce + "_"}
+}
+
+// reserve takes a free slot and returns its ID, for objects that need their ID before
+// they are created. The slot holds no object until set is called
+func (t *handleTable[T]) reserve() string {
+	var index uint32
+	if n := len(t.free); n > 0 {
+		index = t.free[n-1]
+		t.free = t.free[:n-1]
+	} else {
+		index = uint32(len(t.slots))
+		t.slots = append(t.slots, handleSlot[T]{generation: 1})
+	}
+	t.slots[index].used = true
+	return t.format(index, t.slots[index].generation)
+}
+
+// add stores an object in a free slot and returns its ID
+func (t *handleTable[T]) add(value *T) string {
+	id := t.reserve()
+	t.set(id, value)
+	return id
+}
+
+// set stores the object of a reserved slot
+func (t *handleTable[T]) set(id string, value *T) {
+	if slot := t.slot(id); slot != nil && slot.used {
+		if slot.value == nil {
+			t.count++
+		}
+		slot.value = value
+	}
+}
+
+// get returns the object of a handle, or false if the handle is unknown, stale or
+// reserved
+func (t *handleTable[T]) get(id string) (*T, bool) {
+	index, generation, ok := t.parse(id)
+	if !ok || int(index) >= len(t.slots) {
+		return nil, false
+	}
+	slot := &t.slots[index]
+	if slot.generation != generation || slot.value == nil {
+		return nil, false
+	}
+	return slot.value, true
+}
+
+// remove frees the slot of a handle, so the handle becomes stale
+func (t *handleTable[T]) remove(id string) {
+	slot := t.slot(id)
+	if slot == nil || !slot.used {
+		return
+	}
+	if slot.value != nil {
+		t.count--
+	}
+	index, _, _ := t.parse(id)
+	t.release(index)
+}
+
+// release frees a slot and moves it to its next generation
+func (t *handleTable[T]) release(index uint32) {
+	slot := &t.slots[index]
+	slot.value = nil
+	slot.used = false
+	slot.generation++
+	t.free = append(t.free, index)
+}
+
+// reset frees every slot, so every handle issued before becomes stale
+func (t *handleTable[T]) reset() {
+	t.free = t.free[:0]
+	for index := len(t.slots) - 1; index >= 0; index-- {
+		if t.slots[index].used {
+			t.release(uint32(index))
+		} else {
+			t.free = append(t.free, uint32(index))
+		}
+	}
+	t.count = 0
+}
+
+// len returns the number of objects in the table
+func (t *handleTable[T]) len() int {
+	return t.count
+}
+
+// all iterates over the IDs and objects of the table, in slot order. Objects can be
+// removed while iterating
+func (t *handleTable[T]) all() iter.Seq2[string, *T] {
+	return func(yield func(string, *T) bool) {
+		for index := range t.slots {
+			slot := &t.slots[index]
+			if slot.value == nil {
+				continue
+			}
+			if !yield(t.format(uint32(index), slot.generation), slot.value) {
+				return
+			}
+		}
+	}
+}
+
+// notFound returns the result of a call with a handle without an object: a stale
+// handle error for the handles of destroyed objects and of other instances, or a not
+// found error otherwise
+func (t *handleTable[T]) notFound(id string) map[string]interface{} {
+	index, generation, ok := t.parse(id)
+	if ok && int(index) < len(t.slots) && generation < t.slots[index].generation {
+		return staleHandleResult(id, fmt.Sprintf("stale %s handle: %s was destroyed", t.kind, id))
+	}
+	return idNotFound(t.kind, t.prefix, id)
+}
+
+// slot returns the slot a handle refers to, or nil if the handle is malformed, unknown
+// or stale
+func (t *handleTable[T]) slot(id string) *handleSlot[T] {
+	index, generation, ok := t.parse(id)
+	if !ok || int(index) >= len(t.slots) || t.slots[index].generation != generation {
+		return nil
+	}
+	return &t.slots[index]
+}
+
+// format returns the ID of a handle
+func (t *handleTable[T]) format(index uint32, generation uint32) string {
+	return t.idPrefix + strconv.FormatUint(uint64(index), 10) + "_" + strconv.FormatUint(uint64(generation), 10)
+}
+
+// parse returns the slot index and generation of an ID issued by this instance
+// It runs on every call taking an ID, so it scans the ID in place instead of splitting it
+func (t *handleTable[T]) parse(id string) (uint32, uint32, bool) {
+	if !strings.HasPrefix(id, t.idPrefix) {
+		return 0, 0, false
+	}
+	index, i := parseHandleNumber(id, len(t.idPrefix))
+	if i == len(t.idPrefix) || i >= len(id) || id[i] != '_' {
+		return 0, 0, false
+	}
+	generation, end := parseHandleNumber(id, i+1)
+	if end == i+1 || end != len(id) {
+		return 0, 0, false
+	}
+	return index, generation, true
+}
+
+// parseHandleNumber parses the decimal number starting at an offset of an ID, and
+// returns it with the offset of the first byte after it
+// Numbers are at most 9 digits long, so they can't overflow
+func parseHandleNumber(id string, start int) (uint32, int) {
+	var n uint32
+	i := start
+	for ; i < len(id) && i-start < 9; i++ {
+		digit := id[i] - '0'
+		if digit > 9 {
+			break
+		}
+		n = n*10 + uint32(digit)
+	}
+	return n, i
+}
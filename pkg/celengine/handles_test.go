@@ -0,0 +1,46 @@
+package celengine
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// BenchmarkHandleLookup compares resolving IDs through a handleTable with the map of
+// counter IDs (prg_1, prg_2...) it replaced
+func BenchmarkHandleLookup(b *testing.B) {
+	for _, size := range []int{10, 1000, 100000} {
+		b.Run(fmt.Sprintf("handleTable/%d", size), func(b *testing.B) {
+			table := newHandleTable[ProgramState]("program", "prg")
+			ids := make([]string, size)
+			for i := range ids {
+				ids[i] = table.add(&ProgramState{})
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok := table.get(ids[i%size]); !ok {
+					b.Fatal("handle not found")
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("map/%d", size), func(b *testing.B) {
+			table := make(map[string]*ProgramState, size)
+			ids := make([]string, size)
+			for i := range ids {
+				ids[i] = "prg_" + strconv.Itoa(i+1)
+				table[ids[i]] = &ProgramState{}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok := table[ids[i%size]]; !ok {
+					b.Fatal("handle not found")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package celengine
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+)
+
+// Policies for variables that an evaluation doesn't provide and that have no default
+const (
+	AbsentVariablesError   = "error"   // Reading the variable fails the evaluation, the default
+	AbsentVariablesNull    = "null"    // The variable is null
+	AbsentVariablesUnknown = "unknown" // The variable is unknown, as if listed in EvalOptions.Unknowns
+)
+
+// envInputs holds how the evaluations of an environment's programs treat their variables
+type envInputs struct {
+	declared  map[string]bool        // Names of the declared variables
+	defaults  map[string]interface{} // Values of variables that evaluations may omit
+	absent    string                 // Policy for other omitted variables
+	semantics *apiSemantics          // Behaviors pinned by the API version of the environment
+}
+
+// newEnvInputs checks the default values of the variable declarations and the absent
+// variable policy of an environment
+// Defaults are converted to their declared type where JSON can't express it, so whole
+// numbers become ints or uints and strings become timestamps or durations
+func newEnvInputs(varDecls []VarDecl, absent string, semantics *apiSemantics) (*envInputs, error) {
+	switch absent {
+	case "":
+		absent = AbsentVariablesError
+	case AbsentVariablesError, AbsentVariablesNull, AbsentVariablesUnknown:
+	default:
+		return nil, fmt.Errorf("invalid absent variables policy %q: expected %q, %q or %q", absent, AbsentVariablesError, AbsentVariablesNull, AbsentVariablesUnknown)
+	}
+
+	inputs := &envInputs{
+		declared:  make(map[string]bool, len(varDecls)),
+		defaults:  make(map[string]interface{}),
+		absent:    absent,
+		semantics: semantics,
+	}
+	for _, varDecl := range varDecls {
+		inputs.declared[varDecl.Name] = true
+		if varDecl.Default == nil {
+			continue
+		}
+
+		declaredType, err := cel.ExprTypeToType(semantics.parseType(varDecl.Type))
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %v", varDecl.Name, err)
+		}
+		value, _ := decodeTaggedValues(varDecl.Default)
+		value = coerceValue(value, declaredType)
+		if errs := validateValue(value, declaredType, varDecl.Name, nil); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid default of variable %s: %s: %s", varDecl.Name, errs[0].Path, errs[0].Message)
+		}
+		inputs.defaults[varDecl.Name] = value
+	}
+	return inputs, nil
+}
+
+// mayOmit reports whether evaluations may omit a variable, because it has a default or
+// the absent variable policy gives it a value
+func (in *envInputs) mayOmit(name string) bool {
+	_, ok := in.defaults[name]
+	return ok || in.absent != AbsentVariablesError
+}
+
+// bind applies the defaults and the absent variable policy to the activation of an evaluation
+// Under the unknown policy, the omitted variables are added to the evaluation's unknowns
+func (in *envInputs) bind(activation interface{}, options EvalOptions) (interface{}, EvalOptions, error) {
+	if len(in.defaults) == 0 && in.absent == AbsentVariablesError {
+		return activation, options, nil
+	}
+
+	vars, ok := activation.(interpreter.Activation)
+	if !ok {
+		var err error
+		if vars, err = cel.NewActivation(activation); err != nil {
+			return nil, options, fmt.Errorf("failed to create activation: %v", err)
+		}
+	}
+
+	if in.absent == AbsentVariablesUnknown {
+		unknowns := options.Unknowns
+		for name := range in.declared {
+			if _, provided := vars.ResolveName(name); provided {
+				continue
+			}
+			if _, hasDefault := in.defaults[name]; !hasDefault {
+				unknowns = append(unknowns, name)
+			}
+		}
+		// Copy rather than append to the caller's slice, which is shared across programs
+		options.Unknowns = append([]string(nil), unknowns...)
+	}
+
+	return &inputActivation{vars: vars, inputs: in}, options, nil
+}
+
+// inputActivation resolves variables that an evaluation doesn't provide from the defaults,
+// or as null under the null policy
+type inputActivation struct {
+	vars   interpreter.Activation
+	inputs *envInputs
+}
+
+// ResolveName implements interpreter.Activation
+func (a *inputActivation) ResolveName(name string) (any, bool) {
+	if value, ok := a.vars.ResolveName(name); ok {
+		return value, true
+	}
+	if value, ok := a.inputs.defaults[name]; ok {
+		return value, true
+	}
+	if a.inputs.absent == AbsentVariablesNull && a.inputs.declared[name] {
+		return types.NullValue, true
+	}
+	return nil, false
+}
+
+// Parent implements interpreter.Activation
+func (a *inputActivation) Parent() interpreter.Activation {
+	return nil
+}
+
+// partialEvalLibrary enables partial evaluation for every program of an environment, which
+// the unknown policy needs to report the variables an outcome depends on
+type partialEvalLibrary struct{}
+
+// LibraryName implements cel.SingletonLibrary
+func (partialEvalLibrary) LibraryName() string {
+	return "wasm-cel.inputs.unknown"
+}
+
+// CompileOptions implements cel.Library
+func (partialEvalLibrary) CompileOptions() []cel.EnvOption {
+	return nil
+}
+
+// ProgramOptions implements cel.Library
+func (partialEvalLibrary) ProgramOptions() []cel.ProgramOption {
+	return []cel.ProgramOption{cel.EvalOptions(cel.OptPartialEval)}
+}
+
+// coerceValue converts a decoded JSON value to the declared type where JSON can't express
+// it. Values that can't be converted are returned as they are
+func coerceValue(value interface{}, declared *types.Type) interface{} {
+	switch declared.Kind() {
+	case types.IntKind:
+		if number, ok := value.(float64); ok && number == math.Trunc(number) && math.Abs(number) <= 1<<53 {
+			return int64(number)
+		}
+	case types.UintKind:
+		if number, ok := value.(float64); ok && number == math.Trunc(number) && number >= 0 && number <= 1<<53 {
+			return uint64(number)
+		}
+	case types.TimestampKind:
+		if text, ok := value.(string); ok {
+			if timestamp, err := time.Parse(time.RFC3339Nano, text); err == nil {
+				return timestamp
+			}
+		}
+	case types.DurationKind:
+		if text, ok := value.(string); ok {
+			if duration, err := time.ParseDuration(text); err == nil {
+				return duration
+			}
+		}
+	case types.ListKind:
+		if items, ok := value.([]interface{}); ok {
+			coerced := make([]interface{}, len(items))
+			for i, item := range items {
+				coerced[i] = coerceValue(item, declared.Parameters()[0])
+			}
+			return coerced
+		}
+	case types.MapKind:
+		if entries, ok := value.(map[string]interface{}); ok {
+			coerced := make(map[string]interface{}, len(entries))
+			for key, entry := range entries {
+				coerced[key] = coerceValue(entry, declared.Parameters()[1])
+			}
+			return coerced
+		}
+	}
+	return value
+}
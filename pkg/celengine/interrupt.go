@@ -0,0 +1,101 @@
+package celengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/interpreter"
+)
+
+// interruptCheckFrequency is how many iterations of a comprehension run between checks
+// whether the evaluation was cancelled or timed out
+const interruptCheckFrequency = 100
+
+// Timeout returns how long an evaluation may run, or 0 if it may run indefinitely
+func (options EvalOptions) Timeout() time.Duration {
+	if options.TimeoutMs <= 0 {
+		return 0
+	}
+	return time.Duration(options.TimeoutMs * float64(time.Millisecond))
+}
+
+// interruptContext returns the context that interrupts an evaluation, bounded by its
+// timeout, or nil if nothing interrupts it
+func (options EvalOptions) interruptContext() (context.Context, context.CancelFunc) {
+	timeout := options.Timeout()
+	if timeout == 0 {
+		return options.Context, func() {}
+	}
+	parent := options.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// Interrupted returns why the context of an evaluation interrupts it, or nil if it doesn't
+// The deadline is checked against the clock, since timers don't fire while an evaluation
+// keeps the module's only thread busy
+func Interrupted(ctx context.Context, options EvalOptions) error {
+	err := ctx.Err()
+	if deadline, ok := ctx.Deadline(); ok && err == nil && !time.Now().Before(deadline) {
+		err = context.DeadlineExceeded
+	}
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("evaluation timed out after %gms", options.TimeoutMs)
+	default:
+		return fmt.Errorf("evaluation cancelled")
+	}
+}
+
+// interruptActivation stops comprehensions once the evaluation is interrupted or exceeds
+// its memory budget, checking every interruptCheckFrequency iterations
+// An interruption is sticky, so enclosing comprehensions stop at their next iteration
+// rather than absorbing the error of the interrupted one
+type interruptActivation struct {
+	parent      interpreter.Activation
+	ctx         context.Context // Nil if only the budget interrupts the evaluation
+	budget      *memoryBudget
+	options     EvalOptions
+	checks      uint
+	interrupted bool
+}
+
+// newInterruptActivation wraps the variables of an evaluation to check ctx and budget
+func newInterruptActivation(activation interface{}, ctx context.Context, budget *memoryBudget, options EvalOptions) (*interruptActivation, error) {
+	parent, ok := activation.(interpreter.Activation)
+	if !ok {
+		var err error
+		if parent, err = interpreter.NewActivation(activation); err != nil {
+			return nil, err
+		}
+	}
+	return &interruptActivation{parent: parent, ctx: ctx, budget: budget, options: options}, nil
+}
+
+// ResolveName answers the "#interrupted" checks of comprehensions, and looks up other
+// names in the variables
+func (a *interruptActivation) ResolveName(name string) (any, bool) {
+	if name != "#interrupted" {
+		return a.parent.ResolveName(name)
+	}
+	a.checks++
+	if !a.interrupted && a.checks%interruptCheckFrequency == 0 {
+		a.interrupted = (a.ctx != nil && Interrupted(a.ctx, a.options) != nil) || a.budget.exceeded()
+	}
+	if a.interrupted {
+		return true, true
+	}
+	return nil, false
+}
+
+// Parent returns the variables, which also makes partial activations visible to partial
+// evaluation
+func (a *interruptActivation) Parent() interpreter.Activation {
+	return a.parent
+}
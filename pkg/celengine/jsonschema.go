@@ -0,0 +1,377 @@
+package celengine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// jsonSchemaPackage is the protobuf package of the messages converted from a JSON Schema
+const jsonSchemaPackage = "wasmcel.schema"
+
+// jsonSchemaRootType is the message whose fields are the variables of a JSON Schema
+const jsonSchemaRootType = jsonSchemaPackage + ".Root"
+
+// CreateEnvFromJSONSchema creates a CEL environment declaring the properties of a JSON Schema
+// object as variables. Objects with properties become messages, so their fields are type
+// checked; arrays become lists, objects with only additionalProperties become maps, and
+// strings with the date-time format or base64 content encoding become timestamps and bytes.
+// The variables are the fields of a context message, "typeName" in the response, which
+// programs can be evaluated against with EvalWithContextMessage
+func CreateEnvFromJSONSchema(schemaJSON string) map[string]interface{} {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse JSON schema: %v", err),
+		}
+	}
+
+	file, variables, err := jsonSchemaFile(schema)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("invalid JSON schema: %v", err),
+		}
+	}
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to encode JSON schema types: %v", err),
+		}
+	}
+
+	// The message is declared like any other context proto, so identical schemas share an env
+	options, _ := json.Marshal([]map[string]interface{}{{
+		"type": "DeclareContextProto",
+		"params": map[string]interface{}{
+			"typeName":      jsonSchemaRootType,
+			"descriptorSet": base64.StdEncoding.EncodeToString(set),
+		},
+	}})
+	response := CreateEnvWithConfig(EnvConfig{Options: options})
+	if response["error"] != nil {
+		return response
+	}
+	response["typeName"] = jsonSchemaRootType
+	response["variables"] = variables
+	return response
+}
+
+// jsonSchemaFile converts a JSON Schema object to a file of messages, returning the names of
+// the root message's fields
+func jsonSchemaFile(schema map[string]interface{}) (*descriptorpb.FileDescriptorProto, []interface{}, error) {
+	if _, ok := schema["properties"].(map[string]interface{}); !ok || schemaTypeOf(schema) != "object" {
+		return nil, nil, fmt.Errorf("the root schema must be an object with properties")
+	}
+
+	converter := &jsonSchemaConverter{
+		document: schema,
+		names:    make(map[string]bool),
+		refs:     make(map[string]string),
+		imports:  make(map[string]bool),
+	}
+	if _, err := converter.message(schema, "Root", "#"); err != nil {
+		return nil, nil, err
+	}
+
+	imports := make([]string, 0, len(converter.imports))
+	for file := range converter.imports {
+		imports = append(imports, file)
+	}
+	sort.Strings(imports)
+
+	root := converter.messages[0]
+	variables := make([]interface{}, len(root.Field))
+	for i, field := range root.Field {
+		variables[i] = field.GetName()
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("wasmcel/schema/schema.proto"),
+		Package:     proto.String(jsonSchemaPackage),
+		Syntax:      proto.String("proto3"),
+		Dependency:  imports,
+		MessageType: converter.messages,
+	}, variables, nil
+}
+
+// jsonSchemaConverter converts the schemas of a JSON Schema document to messages
+type jsonSchemaConverter struct {
+	document map[string]interface{}
+	messages []*descriptorpb.DescriptorProto
+	names    map[string]bool   // Message names in use
+	refs     map[string]string // Message of each referenced definition, by reference
+	imports  map[string]bool   // Well-known type files the messages depend on
+}
+
+// schemaField is the protobuf type of a schema
+type schemaField struct {
+	fieldType descriptorpb.FieldDescriptorProto_Type
+	typeName  string       // Fully qualified name of a message type
+	repeated  bool         // Arrays are repeated fields
+	mapValue  *schemaField // Value of a map with string keys
+}
+
+// message converts an object schema with properties to a message, returning its full name
+// path locates the schema in the document for error messages
+func (c *jsonSchemaConverter) message(schema map[string]interface{}, name string, path string) (string, error) {
+	message := &descriptorpb.DescriptorProto{Name: proto.String(c.uniqueName(name))}
+	c.messages = append(c.messages, message)
+	fullName := "." + jsonSchemaPackage + "." + message.GetName()
+	if ref, ok := c.refs[path]; ok && ref == "" {
+		c.refs[path] = fullName
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fieldNames := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		propertySchema, ok := properties[key].(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("%s/properties/%s: expected a schema object", path, key)
+		}
+
+		field, err := c.field(propertySchema, message.GetName()+camelCase(key), path+"/properties/"+key)
+		if err != nil {
+			return "", err
+		}
+
+		fieldName := identifier(key)
+		for fieldNames[fieldName] {
+			fieldName += "_"
+		}
+		fieldNames[fieldName] = true
+
+		descriptor := field.descriptor(fieldName, int32(i+1))
+		descriptor.JsonName = proto.String(key)
+		if field.mapValue != nil {
+			entry := &descriptorpb.DescriptorProto{
+				Name: proto.String(camelCase(fieldName) + "Entry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					(&schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_STRING}).descriptor("key", 1),
+					field.mapValue.descriptor("value", 2),
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}
+			message.NestedType = append(message.NestedType, entry)
+			descriptor.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			descriptor.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			descriptor.TypeName = proto.String(fullName + "." + entry.GetName())
+		}
+		message.Field = append(message.Field, descriptor)
+	}
+	return fullName, nil
+}
+
+// field converts a schema to the type of a field, where name is the name of the message
+// created for an object schema
+func (c *jsonSchemaConverter) field(schema map[string]interface{}, name string, path string) (*schemaField, error) {
+	if ref, ok := schema["$ref"].(string); ok {
+		return c.ref(ref, path)
+	}
+
+	switch schemaTypeOf(schema) {
+	case "string":
+		if schema["format"] == "date-time" {
+			return c.wellKnown("google.protobuf.Timestamp", "google/protobuf/timestamp.proto"), nil
+		}
+		if schema["format"] == "byte" || schema["contentEncoding"] == "base64" {
+			return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_BYTES}, nil
+		}
+		return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_STRING}, nil
+	case "integer":
+		return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_INT64}, nil
+	case "number":
+		return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE}, nil
+	case "boolean":
+		return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_BOOL}, nil
+	case "array":
+		element := c.dyn()
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			itemField, err := c.field(items, name, path+"/items")
+			if err != nil {
+				return nil, err
+			}
+			// Repeated fields can't nest, so lists of lists or maps hold dyn values
+			if !itemField.repeated && itemField.mapValue == nil {
+				element = itemField
+			}
+		}
+		element.repeated = true
+		return element, nil
+	case "object":
+		if _, ok := schema["properties"].(map[string]interface{}); ok {
+			typeName, err := c.message(schema, name, path)
+			if err != nil {
+				return nil, err
+			}
+			return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, typeName: typeName}, nil
+		}
+		if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			value, err := c.field(additional, name+"Value", path+"/additionalProperties")
+			if err != nil {
+				return nil, err
+			}
+			if value.repeated || value.mapValue != nil {
+				value = c.dyn()
+			}
+			return &schemaField{mapValue: value}, nil
+		}
+		return c.wellKnown("google.protobuf.Struct", "google/protobuf/struct.proto"), nil
+	default:
+		// Schemas without a single type, such as unions, are dyn
+		return c.dyn(), nil
+	}
+}
+
+// ref converts a reference to a definition of the document, such as "#/$defs/Address"
+// Object definitions become one message however often they are referenced, so they can
+// refer to themselves
+func (c *jsonSchemaConverter) ref(ref string, path string) (*schemaField, error) {
+	if typeName := c.refs[ref]; typeName != "" {
+		return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, typeName: typeName}, nil
+	}
+	if _, converting := c.refs[ref]; converting {
+		return nil, fmt.Errorf("%s: unsupported circular reference %s", path, ref)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	if !strings.HasPrefix(ref, "#/") || len(segments) != 2 || (segments[0] != "$defs" && segments[0] != "definitions") {
+		return nil, fmt.Errorf("%s: unsupported reference %s, expected #/$defs/<name> or #/definitions/<name>", path, ref)
+	}
+	definitions, _ := c.document[segments[0]].(map[string]interface{})
+	definition, ok := definitions[segments[1]].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: reference %s not found", path, ref)
+	}
+
+	// Mark the reference so the message converted from it registers itself under it
+	c.refs[ref] = ""
+	field, err := c.field(definition, camelCase(segments[1]), ref)
+	if err != nil {
+		return nil, err
+	}
+	if field.typeName == "" || c.refs[ref] == "" {
+		delete(c.refs, ref)
+	}
+	return field, nil
+}
+
+// wellKnown returns a field of a well-known message type, importing its file
+func (c *jsonSchemaConverter) wellKnown(typeName string, file string) *schemaField {
+	c.imports[file] = true
+	return &schemaField{fieldType: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, typeName: "." + typeName}
+}
+
+// dyn returns a field holding any JSON value
+func (c *jsonSchemaConverter) dyn() *schemaField {
+	return c.wellKnown("google.protobuf.Value", "google/protobuf/struct.proto")
+}
+
+// uniqueName returns a message name that isn't in use yet
+func (c *jsonSchemaConverter) uniqueName(name string) string {
+	unique := name
+	for i := 2; c.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	c.names[unique] = true
+	return unique
+}
+
+// descriptor builds the descriptor of a field with the given name and number
+func (f *schemaField) descriptor(name string, number int32) *descriptorpb.FieldDescriptorProto {
+	descriptor := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   f.fieldType.Enum(),
+	}
+	if f.repeated {
+		descriptor.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+	if f.typeName != "" {
+		descriptor.TypeName = proto.String(f.typeName)
+	}
+	return descriptor
+}
+
+// schemaTypeOf returns the type of a schema, ignoring "null" in a list of types since
+// absent fields read as their default. It is empty when the schema allows several types
+func schemaTypeOf(schema map[string]interface{}) string {
+	switch schemaType := schema["type"].(type) {
+	case string:
+		return schemaType
+	case []interface{}:
+		var result string
+		for _, t := range schemaType {
+			if name, ok := t.(string); ok && name != "null" {
+				if result != "" {
+					return ""
+				}
+				result = name
+			}
+		}
+		return result
+	}
+
+	// Untyped schemas are inferred from their keywords
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
+
+// identifier converts a property name to a valid protobuf and CEL identifier
+func identifier(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// camelCase converts a property name to the UpperCamelCase of message names
+func camelCase(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range identifier(name) {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 || b.String()[0] >= '0' && b.String()[0] <= '9' {
+		return "T" + b.String()
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package celengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/invakid404/wasm-cel/internal/wasmenv"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Library is a named set of variables, functions and environment options that
+// environments include by name rather than repeating its declarations
+type Library struct {
+	VarDecls []VarDecl       `json:"varDecls"`
+	FuncDefs []FunctionDef   `json:"funcDefs"`
+	Options  json.RawMessage `json:"options,omitempty"` // Environment options, as passed to ExtendEnv
+}
+
+// libraries maps names to registered libraries
+// A library can't be redefined, so environments pooled under its name stay valid
+var libraries = make(map[string]*Library)
+
+// RegisterLibrary registers a library that environments created afterwards can include by name
+// Like global functions, its function implementations are only unregistered by Shutdown
+func RegisterLibrary(name string, library Library) map[string]interface{} {
+	if name == "" {
+		return map[string]interface{}{
+			"error": "library requires a name",
+		}
+	}
+	if _, ok := libraries[name]; ok {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("library already registered: %s", name),
+		}
+	}
+
+	// Build an environment with just the library so invalid declarations are reported now
+	lib, err := newEnvLibrary(name, &library, "")
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	if _, err := cel.NewEnv(cel.Lib(lib)); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("invalid library %s: %v", name, err),
+		}
+	}
+
+	libraries[name] = &library
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// libraryOptions resolves library names to the options including them in an environment,
+// along with the function definitions they declare
+func libraryOptions(names []string, envID string) ([]cel.EnvOption, []FunctionDef, error) {
+	opts := make([]cel.EnvOption, 0, len(names))
+	var funcDefs []FunctionDef
+	for _, name := range names {
+		library, ok := libraries[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("library not found: %s", name)
+		}
+		lib, err := newEnvLibrary(name, library, envID)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, cel.Lib(lib))
+		funcDefs = append(funcDefs, library.FuncDefs...)
+	}
+	return opts, funcDefs, nil
+}
+
+// libraryImplIDs returns the function implementation IDs of every registered library
+func libraryImplIDs() []string {
+	var implIDs []string
+	for _, library := range libraries {
+		for _, funcDef := range library.FuncDefs {
+			implIDs = append(implIDs, funcDef.ImplID)
+		}
+	}
+	sort.Strings(implIDs)
+	return implIDs
+}
+
+// envLibrary is a library's declarations as a cel.Library for one environment
+// Its options are created with the environment's ID, like those passed to ExtendEnv
+type envLibrary struct {
+	name string
+	opts []cel.EnvOption
+}
+
+// newEnvLibrary converts a library's declarations to environment options
+func newEnvLibrary(name string, library *Library, envID string) (*envLibrary, error) {
+	var declarations []*exprpb.Decl
+	for _, varDecl := range library.VarDecls {
+		declarations = append(declarations, decls.NewVar(varDecl.Name, parseTypeDef(varDecl.Type)))
+	}
+
+	var opts []cel.EnvOption
+	for _, funcDef := range library.FuncDefs {
+		funcDecl, funcImpl, err := functionDeclaration(funcDef, parseTypeDef)
+		if err != nil {
+			return nil, fmt.Errorf("library %s: %v", name, err)
+		}
+		declarations = append(declarations, funcDecl)
+		opts = append(opts, funcImpl)
+	}
+	if len(declarations) > 0 {
+		opts = append(opts, cel.Declarations(declarations...))
+	}
+
+	if len(library.Options) > 0 {
+		envOptions, err := wasmenv.CreateOptionsFromJSONWithEnvID(string(library.Options), envID)
+		if err != nil {
+			return nil, fmt.Errorf("library %s: failed to create environment options: %v", name, err)
+		}
+		opts = append(opts, envOptions...)
+	}
+
+	return &envLibrary{name: name, opts: opts}, nil
+}
+
+// LibraryName implements cel.SingletonLibrary, so a library is only included once
+func (l *envLibrary) LibraryName() string {
+	return "wasm-cel.library." + l.name
+}
+
+// CompileOptions implements cel.Library
+func (l *envLibrary) CompileOptions() []cel.EnvOption {
+	return l.opts
+}
+
+// ProgramOptions implements cel.Library
+func (l *envLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
@@ -0,0 +1,47 @@
+package celengine
+
+// Lifecycle events sent to the lifecycle listener
+const (
+	LifecycleEnvCreated           = "envCreated"
+	LifecycleEnvDestroyed         = "envDestroyed"
+	LifecycleProgramCompiled      = "programCompiled"
+	LifecycleProgramDestroyed     = "programDestroyed"
+	LifecycleFunctionUnregistered = "functionUnregistered"
+	LifecycleCacheEvicted         = "cacheEvicted"
+)
+
+// lifecycleListenerImplID is the function implementation lifecycle events are sent to
+var lifecycleListenerImplID string
+
+// SetLifecycleListener sends the lifecycle events of environments, programs, function
+// implementations and caches to a JavaScript function, for debugging dashboards and
+// leak detectors
+// The function is called with an event object {type, ...} for each event. An empty
+// implID removes the listener.
+func SetLifecycleListener(implID string) map[string]interface{} {
+	// The previous listener isn't referenced by any environment, so it's released right away
+	if lifecycleListenerImplID != "" && lifecycleListenerImplID != implID && unregisterFunctionCaller != nil {
+		unregisterFunctionCaller.UnregisterFunction(lifecycleListenerImplID)
+	}
+	lifecycleListenerImplID = implID
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// emitLifecycleEvent sends an event with its fields to the lifecycle listener, if any
+func emitLifecycleEvent(eventType string, fields map[string]interface{}) {
+	if lifecycleListenerImplID == "" || jsFunctionCaller == nil {
+		return
+	}
+
+	event := make(map[string]interface{}, len(fields)+1)
+	for key, value := range fields {
+		event[key] = value
+	}
+	event["type"] = eventType
+	// Like a failing logger, a failing listener has nowhere to report to
+	_, _ = jsFunctionCaller.CallJSFunction(lifecycleListenerImplID, []interface{}{event})
+}
@@ -0,0 +1,62 @@
+package celengine
+
+import (
+	"github.com/invakid404/wasm-cel/internal/logging"
+)
+
+// loggerImplID is the function implementation log entries are sent to
+var loggerImplID string
+
+// SetLogger routes internal warnings, such as ignored option params, type and value
+// conversion fallbacks and reference counting anomalies, to a JavaScript function
+// The function is called with an entry object {level, message, fields}. Entries below
+// level ("debug", "info", "warn" or "error", default "warn") are dropped.
+// An empty implID removes the logger.
+func SetLogger(implID string, level string) map[string]interface{} {
+	minLevel := logging.LevelWarn
+	if level != "" {
+		parsed, err := logging.ParseLevel(level)
+		if err != nil {
+			return map[string]interface{}{
+				"error": err.Error(),
+			}
+		}
+		minLevel = parsed
+	}
+
+	// The previous logger isn't referenced by any environment, so it's released right away
+	if loggerImplID != "" && loggerImplID != implID && unregisterFunctionCaller != nil {
+		unregisterFunctionCaller.UnregisterFunction(loggerImplID)
+	}
+	loggerImplID = implID
+
+	if implID == "" {
+		logging.SetSink(nil, minLevel)
+	} else {
+		logging.SetSink(logToJS(implID), minLevel)
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// logToJS returns a sink that calls the given function implementation
+func logToJS(implID string) logging.Sink {
+	return func(level logging.Level, message string, fields map[string]interface{}) {
+		if jsFunctionCaller == nil {
+			return
+		}
+
+		entry := map[string]interface{}{
+			"level":   level.String(),
+			"message": message,
+		}
+		if fields != nil {
+			entry["fields"] = fields
+		}
+		// A failing logger has nowhere to report to
+		_, _ = jsFunctionCaller.CallJSFunction(implID, []interface{}{entry})
+	}
+}
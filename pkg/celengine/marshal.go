@@ -0,0 +1,125 @@
+package celengine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// FunctionErrorCode is the code of errors thrown by function implementations that don't
+// set one
+const FunctionErrorCode = "function_error"
+
+// FunctionError is an error reported by a custom function implementation, either by
+// throwing or by returning {"__celError": {"code": ..., "message": ...}}
+// Callers return it from CallJSFunction for thrown errors, and the code is reported in
+// the error value of the evaluation
+type FunctionError struct {
+	Code    string
+	Message string
+}
+
+func (e *FunctionError) Error() string {
+	return e.Message
+}
+
+// functionError converts an error calling a JavaScript function to a CEL error value
+func functionError(name string, err error) ref.Val {
+	var fnErr *FunctionError
+	if errors.As(err, &fnErr) {
+		return types.WrapErr(&FunctionError{
+			Code:    fnErr.Code,
+			Message: fmt.Sprintf("function %s: %s", name, fnErr.Message),
+		})
+	}
+	return types.NewErr("function call error: %v", err)
+}
+
+// returnedError returns the error a function result reports with a __celError object
+func returnedError(result interface{}) (*FunctionError, bool) {
+	object, ok := result.(map[string]interface{})
+	if !ok || len(object) != 1 {
+		return nil, false
+	}
+	details, ok := object["__celError"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	fnErr := &FunctionError{Code: FunctionErrorCode}
+	if code, ok := details["code"].(string); ok && code != "" {
+		fnErr.Code = code
+	}
+	fnErr.Message, _ = details["message"].(string)
+	return fnErr, true
+}
+
+// functionArg converts a CEL value to the Go value passed to a JavaScript function
+// Unlike ValueToJSON, timestamps are passed as time.Time and non-finite doubles as is, so
+// callers can hand them to JavaScript as Dates and numbers. Uints and bytes are passed as
+// uint64 and []byte, which the WASM caller converts to BigInts and Uint8Arrays
+func functionArg(val ref.Val) interface{} {
+	switch v := val.(type) {
+	case types.Double:
+		return float64(v)
+	case types.Timestamp:
+		return v.Time
+	case traits.Lister:
+		size := v.Size().Value().(int64)
+		result := make([]interface{}, size)
+		for i := int64(0); i < size; i++ {
+			result[i] = functionArg(v.Get(types.Int(i)))
+		}
+		return result
+	case traits.Mapper:
+		result := make(map[string]interface{})
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			key := it.Next()
+			result[fmt.Sprintf("%v", ValueToJSON(key))] = functionArg(v.Get(key))
+		}
+		return result
+	}
+	return ValueToJSON(val)
+}
+
+// functionResult converts the value returned by a JavaScript function to a CEL value of
+// the function's declared return type, or an error value if it doesn't match or the
+// function returned an error
+// Types JSON has no equivalent for, like messages, are converted without validation
+func functionResult(name string, returnType *cel.Type, result interface{}) ref.Val {
+	if fnErr, ok := returnedError(result); ok {
+		return functionError(name, fnErr)
+	}
+	if !validatedKind(returnType) {
+		return JSONToValue(result)
+	}
+	val, err := typedValue(returnType, result)
+	if err != nil {
+		return types.NewErr("function %s returned an invalid %s: %v", name, returnType, err)
+	}
+	return val
+}
+
+// validatedKind reports whether values of a type can be validated by typedValue
+func validatedKind(t *cel.Type) bool {
+	switch t.Kind() {
+	case types.ListKind, types.MapKind:
+		for _, param := range t.Parameters() {
+			if !validatedKind(param) {
+				return false
+			}
+		}
+		return true
+	case types.BoolKind, types.BytesKind, types.DoubleKind, types.DurationKind,
+		types.DynKind, types.AnyKind, types.IntKind, types.NullTypeKind,
+		types.StringKind, types.TimestampKind, types.UintKind:
+		return true
+	case types.OpaqueKind:
+		return valueCodecs[t.TypeName()] != nil
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package celengine
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// memoCache is a bounded cache of a program's evaluation responses keyed by a hash of
+// the variables and evaluation options, evicting the least recently used entry
+// A nil *memoCache caches nothing, so call sites don't need to check whether memoization
+// is enabled
+type memoCache struct {
+	limit   int
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+}
+
+// memoEntry is a cached evaluation response
+type memoEntry struct {
+	key      string
+	response map[string]interface{}
+}
+
+// newProgramMemo creates the memo cache of a program, or nil if limit is not positive
+// Only programs that can't call impure JavaScript functions are memoized, since their
+// results depend on nothing but their inputs
+func newProgramMemo(envState *EnvState, checked *cel.Ast, limit int) (*memoCache, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	if callsImpureFunction(envState, checked) {
+		return nil, fmt.Errorf("memoization requires a program that doesn't call impure custom functions")
+	}
+	return &memoCache{
+		limit:   limit,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}, nil
+}
+
+// key hashes the inputs of an evaluation
+// It reports false if memoization is disabled or the inputs can't be serialized
+func (c *memoCache) key(vars map[string]interface{}, options EvalOptions) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	return hashEvalInputs(vars, options)
+}
+
+// hashEvalInputs hashes the variables and options of an evaluation
+// It reports false if they can't be serialized
+func hashEvalInputs(vars map[string]interface{}, options EvalOptions) (string, bool) {
+	// Metrics, timeouts and memory budgets don't change the result, so they don't split
+	// the cache
+	options.Metrics = false
+	options.TimeoutMs = 0
+	options.MemoryLimitBytes = 0
+	data, err := json.Marshal(struct {
+		Vars    map[string]interface{} `json:"vars"`
+		Options EvalOptions            `json:"options"`
+	}{vars, options})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// get returns a copy of the cached response for a key
+func (c *memoCache) get(key string) (map[string]interface{}, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return copyResponse(element.Value.(*memoEntry).response), true
+}
+
+// put caches a response, evicting the least recently used entry if the cache is full
+func (c *memoCache) put(key string, response map[string]interface{}) {
+	// Cache hits make no function calls, so they have no trace
+	cached := copyResponse(response)
+	delete(cached, "metrics")
+	delete(cached, "functionTrace")
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*memoEntry).response = cached
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoEntry{key: key, response: cached})
+	if c.order.Len() > c.limit {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoEntry).key)
+		emitLifecycleEvent(LifecycleCacheEvicted, map[string]interface{}{"cache": "memo"})
+	}
+}
+
+// copyResponse makes a shallow copy of a response so callers can add to it
+func copyResponse(response map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(response)+1)
+	for k, v := range response {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package celengine
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/interpreter"
+)
+
+// Estimated sizes of the values an evaluation produces, after Go's representation of them
+const (
+	valueHeaderSize = 16 // A string or bytes header, or an interface holding a list element
+	listHeaderSize  = 24 // A slice header
+	mapHeaderSize   = 48 // A map header
+	mapEntrySize    = 48 // A key and a value, with the map's per-entry overhead
+)
+
+// evalBudget is the memory budget of the running evaluation, if it has one
+var evalBudget *memoryBudget
+
+// memoryBudget tracks the bytes an evaluation allocates against its limit
+// The allocations are the estimated sizes of the strings, bytes, lists and maps produced
+// by function calls and by list and map literals. Only the evaluation's own values are
+// counted, so evaluations running alongside it on the worker pool don't count towards
+// its budget, and no statistics of the runtime are read while it runs
+type memoryBudget struct {
+	limit     uint64
+	allocated uint64
+}
+
+// memoryBudget returns the budget of an evaluation, or nil if its memory isn't limited
+func (options EvalOptions) memoryBudget() *memoryBudget {
+	limit := options.MemoryLimitBytes
+	if limit <= 0 {
+		limit = float64(runtimeConfig.EvalMemoryLimitBytes)
+	}
+	if limit <= 0 {
+		return nil
+	}
+	return &memoryBudget{limit: uint64(limit)}
+}
+
+// charge counts the size of a value the evaluation produced
+// Mutable lists and maps are the accumulators of comprehensions, which grow in place by
+// the elements charged as they were created, so they aren't charged again
+func (b *memoryBudget) charge(val ref.Val) {
+	switch val := val.(type) {
+	case types.String:
+		b.allocated += valueHeaderSize + uint64(len(val))
+	case types.Bytes:
+		b.allocated += valueHeaderSize + uint64(len(val))
+	case traits.MutableLister, traits.MutableMapper:
+	case traits.Lister:
+		if size, ok := val.Size().(types.Int); ok {
+			b.allocated += listHeaderSize + valueHeaderSize*uint64(size)
+		}
+	case traits.Mapper:
+		if size, ok := val.Size().(types.Int); ok {
+			b.allocated += mapHeaderSize + mapEntrySize*uint64(size)
+		}
+	}
+}
+
+// exceeded reports whether the allocations so far exceed the limit
+func (b *memoryBudget) exceeded() bool {
+	return b != nil && b.allocated > b.limit
+}
+
+// response reports an evaluation that exceeded its budget, with the measured allocations
+// under "memoryBudget"
+func (b *memoryBudget) response() map[string]interface{} {
+	return map[string]interface{}{
+		"error": fmt.Sprintf("evaluation exceeded its memory budget: allocated %d of %d bytes", b.allocated, b.limit),
+		"memoryBudget": map[string]interface{}{
+			"limitBytes":     b.limit,
+			"allocatedBytes": b.allocated,
+		},
+	}
+}
+
+// chargeMemoryBudget is a program option charging the values produced by function calls
+// and list and map literals to the budget of the running evaluation
+// cel-go's optimizer decorates the program after it, building literals of constants once
+// when planning and turning `in` over them into set lookups, which it can't do through
+// a wrapper. Optimized programs leave those literals unwrapped, since evaluations don't
+// build them
+func chargeMemoryBudget(optimized bool) cel.ProgramOption {
+	return cel.CustomDecorator(func(i interpreter.Interpretable) (interpreter.Interpretable, error) {
+		switch i := i.(type) {
+		case interpreter.InterpretableCall:
+			return &budgetCall{i}, nil
+		case interpreter.InterpretableConstructor:
+			if optimized && constantLiteral(i) {
+				return i, nil
+			}
+			return &budgetConstructor{i}, nil
+		}
+		return i, nil
+	})
+}
+
+// constantLiteral reports whether a constructor is a list or map literal of constants
+func constantLiteral(i interpreter.InterpretableConstructor) bool {
+	if i.Type() != types.ListType && i.Type() != types.MapType {
+		return false
+	}
+	for _, val := range i.InitVals() {
+		if _, ok := val.(interpreter.InterpretableConst); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// budgetCall charges the results of a function call
+type budgetCall struct {
+	interpreter.InterpretableCall
+}
+
+// Eval calls the function and charges its result
+func (c *budgetCall) Eval(activation interpreter.Activation) ref.Val {
+	return chargeEvalBudget(c.InterpretableCall.Eval(activation))
+}
+
+// budgetConstructor charges the lists and maps a literal creates
+type budgetConstructor struct {
+	interpreter.InterpretableConstructor
+}
+
+// Eval creates the value and charges it
+func (c *budgetConstructor) Eval(activation interpreter.Activation) ref.Val {
+	return chargeEvalBudget(c.InterpretableConstructor.Eval(activation))
+}
+
+// chargeEvalBudget charges a value to the budget of the running evaluation, if any, and
+// returns it, or an error once the budget is exceeded so the evaluation stops early
+func chargeEvalBudget(val ref.Val) ref.Val {
+	if evalBudget == nil {
+		return val
+	}
+	evalBudget.charge(val)
+	if evalBudget.exceeded() {
+		return types.NewErr("evaluation exceeded its memory budget")
+	}
+	return val
+}
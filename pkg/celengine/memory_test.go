@@ -0,0 +1,81 @@
+package celengine
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+)
+
+// plannedRoot returns the type of the root interpretable cel-go planned for a program
+func plannedRoot(t *testing.T, prg cel.Program) string {
+	t.Helper()
+	root := reflect.ValueOf(prg).Elem().FieldByName("interpretable")
+	if !root.IsValid() || root.IsNil() {
+		t.Fatalf("no planned interpretable in %T", prg)
+	}
+	return root.Elem().Type().String()
+}
+
+// compileForBudget compiles an expression over an int x and returns its program ID
+func compileForBudget(t *testing.T, expr string, flags CompileFlags) string {
+	t.Helper()
+	created := CreateEnv([]VarDecl{{Name: "x", Type: "int"}}, nil)
+	envID, _ := created["envID"].(string)
+	if envID == "" {
+		t.Fatalf("failed to create the environment: %v", created["error"])
+	}
+	t.Cleanup(func() { DestroyEnv(envID) })
+
+	compiled := CompileWithFlags(envID, expr, nil, flags)
+	programID, _ := compiled["programID"].(string)
+	if programID == "" {
+		t.Fatalf("failed to compile %s: %v", expr, compiled["error"])
+	}
+	t.Cleanup(func() { DestroyProgram(programID) })
+	return programID
+}
+
+func TestMemoryBudgetKeepsSetMembership(t *testing.T) {
+	elems := make([]string, 100)
+	for i := range elems {
+		elems[i] = strconv.Itoa(i)
+	}
+	expr := "x in [" + strings.Join(elems, ", ") + "]"
+
+	programID := compileForBudget(t, expr, CompileFlags{Optimize: true})
+	programState, _ := programs.get(programID)
+	if root := plannedRoot(t, programState.prg); root != "*interpreter.evalSetMembership" {
+		t.Errorf("expected an optimized `in` to be a set lookup, got %s", root)
+	}
+
+	result := EvalWithOptions(programID, map[string]interface{}{"x": 42}, EvalOptions{MemoryLimitBytes: 64})
+	if result["result"] != true {
+		t.Errorf("expected true within the budget, got %v", result)
+	}
+}
+
+func TestMemoryBudgetConstantLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    CompileFlags
+		exceeded bool
+	}{
+		// Built by every evaluation: a list header and 3 elements, 72 bytes
+		{name: "planned", exceeded: true},
+		// Built once when planning
+		{name: "optimized", flags: CompileFlags{Optimize: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			programID := compileForBudget(t, "size([x, 2, 3]) + size([1, 2, 3])", test.flags)
+			result := EvalWithOptions(programID, map[string]interface{}{"x": 1}, EvalOptions{MemoryLimitBytes: 100})
+			if _, exceeded := result["memoryBudget"]; exceeded != test.exceeded {
+				t.Errorf("expected the budget to be exceeded: %v, got %v", test.exceeded, result)
+			}
+		})
+	}
+}
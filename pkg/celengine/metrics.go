@@ -0,0 +1,62 @@
+package celengine
+
+import "time"
+
+// callMetrics collects timings of a single compile or eval call
+// A nil *callMetrics records nothing, so call sites don't need to check whether metrics are enabled
+type callMetrics struct {
+	phases        map[string]float64
+	callbackTime  time.Duration
+	callbackCount int
+}
+
+// evalMetrics collects the JavaScript callback time of the running evaluation, if enabled
+var evalMetrics *callMetrics
+
+// newCallMetrics returns a collector, or nil if metrics are disabled
+func newCallMetrics(enabled bool) *callMetrics {
+	if !enabled {
+		return nil
+	}
+	return &callMetrics{phases: make(map[string]float64)}
+}
+
+// track records the time elapsed since start under the given phase name
+func (m *callMetrics) track(phase string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.phases[phase] += milliseconds(time.Since(start))
+}
+
+// trackCallback records a call to a JavaScript function implementation
+func (m *callMetrics) trackCallback(start time.Time) {
+	if m == nil {
+		return
+	}
+	m.callbackTime += time.Since(start)
+	m.callbackCount++
+}
+
+// addTo adds the collected metrics to a result under the "metrics" key
+func (m *callMetrics) addTo(result map[string]interface{}, withCallbacks bool) map[string]interface{} {
+	if m == nil {
+		return result
+	}
+
+	metrics := make(map[string]interface{}, len(m.phases)+2)
+	for phase, ms := range m.phases {
+		metrics[phase] = ms
+	}
+	if withCallbacks {
+		metrics["callbackMs"] = milliseconds(m.callbackTime)
+		metrics["callbackCount"] = m.callbackCount
+	}
+	result["metrics"] = metrics
+	return result
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
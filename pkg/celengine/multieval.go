@@ -0,0 +1,95 @@
+package celengine
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// EvalPrograms evaluates several programs against the same variables, such as a set of
+// policy rules against one request. The variables are decoded and bound to an activation
+// once, rather than once per program
+// Each entry of "results" is the response EvalWithOptions returns for the program at the
+// same index, so a missing program or failed evaluation doesn't fail the others
+func EvalPrograms(programIDs []string, vars map[string]interface{}, options EvalOptions) map[string]interface{} {
+	if err := options.ValueEncoding.validate(); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	inputs, err := newSharedInputs(vars, options)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	results := make([]interface{}, len(programIDs))
+	for i, programID := range programIDs {
+		results[i] = inputs.eval(programID, options)
+	}
+
+	return map[string]interface{}{
+		"results": results,
+		"error":   nil,
+	}
+}
+
+// sharedInputs are the variables of several evaluations, decoded and bound to an
+// activation once
+type sharedInputs struct {
+	vars       map[string]interface{} // Decoded variables, validated by strict evaluations
+	activation cel.Activation
+	memoKey    func() (string, bool)
+}
+
+// newSharedInputs binds variables to an activation for evaluations with the given options
+func newSharedInputs(vars map[string]interface{}, options EvalOptions) (*sharedInputs, error) {
+	// Tagged doubles stand in for NaN and ±Inf, which JSON can't represent
+	decoded, _ := decodeTaggedMap(vars)
+	activation, err := cel.NewActivation(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create activation: %v", err)
+	}
+
+	// The memo key only depends on the inputs, so memoized programs share it
+	var key string
+	keyed, hashed := false, false
+	return &sharedInputs{
+		vars:       decoded,
+		activation: activation,
+		memoKey: func() (string, bool) {
+			if !hashed {
+				key, keyed = hashEvalInputs(vars, options)
+				hashed = true
+			}
+			return key, keyed
+		},
+	}, nil
+}
+
+// eval evaluates a program against the inputs like EvalWithOptions
+func (in *sharedInputs) eval(programID string, options EvalOptions) map[string]interface{} {
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+	touchProgram(programState)
+	options.ValueEncoding = programState.inputs.semantics.withDefaults(options.ValueEncoding)
+
+	// Integer strings are read by type, so only programs declaring them as ints get a
+	// copy of the variables with the strings replaced
+	vars, activation := in.vars, interface{}(in.activation)
+	if options.integerStrings() {
+		if decoded, changed := decodeIntegerStrings(in.vars, programState.readVariables()); changed {
+			vars, activation = decoded, decoded
+		}
+	}
+	if options.Strict {
+		if errs := validateVariables(programState, vars, options.Unknowns); len(errs) > 0 {
+			return invalidVariablesResponse(errs)
+		}
+	}
+	return evalCached(programState, activation, options, in.memoKey)
+}
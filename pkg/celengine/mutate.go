@@ -0,0 +1,298 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+)
+
+// Kinds of mutants generated by Mutate
+const (
+	MutantOperator = "operator" // An operator replaced by another, or a negation removed
+	MutantBoundary = "boundary" // A comparison moved by one, in its operator or its constant
+	MutantClause   = "clause"   // An operand of && or || dropped
+)
+
+// operatorMutation is an operator a mutant replaces another with
+type operatorMutation struct {
+	function string
+	kind     string
+}
+
+// operatorMutations are the operators each operator is replaced with
+var operatorMutations = map[string][]operatorMutation{
+	operators.Less:          {{operators.LessEquals, MutantBoundary}, {operators.GreaterEquals, MutantOperator}},
+	operators.LessEquals:    {{operators.Less, MutantBoundary}, {operators.Greater, MutantOperator}},
+	operators.Greater:       {{operators.GreaterEquals, MutantBoundary}, {operators.LessEquals, MutantOperator}},
+	operators.GreaterEquals: {{operators.Greater, MutantBoundary}, {operators.Less, MutantOperator}},
+	operators.Equals:        {{operators.NotEquals, MutantOperator}},
+	operators.NotEquals:     {{operators.Equals, MutantOperator}},
+	operators.LogicalAnd:    {{operators.LogicalOr, MutantOperator}},
+	operators.LogicalOr:     {{operators.LogicalAnd, MutantOperator}},
+	operators.Add:           {{operators.Subtract, MutantOperator}},
+	operators.Subtract:      {{operators.Add, MutantOperator}},
+	operators.Multiply:      {{operators.Divide, MutantOperator}},
+	operators.Divide:        {{operators.Multiply, MutantOperator}},
+}
+
+// Mutate generates the mutants of an expression and compiles each as a program of the
+// environment, so rule test suites can check that their inputs tell the rule apart from
+// near misses: a suite whose inputs give a mutant the same results as the rule doesn't
+// cover the part of the rule the mutant changed
+// Mutants swap operators, such as && for || or == for !=, move the boundaries of
+// comparisons by changing < to <= or a constant compared against by one, and drop the
+// operands of && and ||, including within macros. Mutants that don't compile, such as
+// + swapped for - on strings, are left out and counted under "skipped"
+// Each mutant under "mutants" has its "programID", its source "expr", its "kind", a
+// "description" of the change and the "range" of the expression it changed, in UTF-16
+// offsets. The programs are destroyed like any other
+func Mutate(envID string, exprStr string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	if _, issues := envState.env.Compile(exprStr); issues != nil && issues.Err() != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("compilation error: %v", issues.Err()),
+		}
+	}
+	source, ok := parseSource(envState.env, exprStr)
+	if !ok {
+		return map[string]interface{}{
+			"error": "failed to parse the expression",
+		}
+	}
+
+	m := &mutator{source: source, seen: map[string]bool{exprStr: true}}
+	m.visit(source.expr, nil)
+
+	mutants := make([]interface{}, 0, len(m.mutants))
+	skipped := 0
+	for _, mutant := range m.mutants {
+		checked, prg, memo, err := buildProgram(envState, mutant.expr, nil, CompileFlags{}, nil)
+		if err != nil {
+			skipped++
+			continue
+		}
+		programID := addProgram(envID, envState, prg, checked, memo, &programSource{expr: mutant.expr})
+		mutants = append(mutants, map[string]interface{}{
+			"programID":   programID,
+			"expr":        mutant.expr,
+			"kind":        mutant.kind,
+			"description": mutant.description,
+			"range":       source.rangeOf(mutant.span),
+		})
+	}
+
+	return map[string]interface{}{
+		"mutants": mutants,
+		"skipped": skipped,
+		"error":   nil,
+	}
+}
+
+// mutant is the source of a mutant and the change it makes to the expression
+type mutant struct {
+	expr        string
+	kind        string
+	description string
+	span        sourceSpan // The part of the expression that was changed
+}
+
+// mutator generates the mutants of an expression by rewriting its source, so mutants keep
+// the formatting and macros of the expression
+type mutator struct {
+	source  *parsedSource
+	seen    map[string]bool // Sources generated so far, starting with the expression
+	mutants []mutant
+}
+
+// visit generates the mutants of a subexpression as written and of its subexpressions
+// parent is the node the subexpression is an operand of, or nil for the expression
+func (m *mutator) visit(e ast.Expr, parent ast.Expr) {
+	e = m.source.written(e)
+	if e.Kind() == ast.CallKind {
+		m.mutateCall(e, parent)
+	}
+	for _, sub := range m.source.subexpressions(e) {
+		m.visit(sub, e)
+	}
+}
+
+// mutateCall generates the mutants of an operator call
+func (m *mutator) mutateCall(e ast.Expr, parent ast.Expr) {
+	function := e.AsCall().FunctionName()
+	args := e.AsCall().Args()
+	symbol, _ := operators.FindReverse(function)
+
+	// The token of an operator call is its operator
+	token, ok := m.source.token(e)
+	if !ok || symbol == "" || m.source.text(token) != symbol {
+		return
+	}
+	for _, mutation := range operatorMutations[function] {
+		replacement, _ := operators.FindReverse(mutation.function)
+		edits := append(m.regroup(e, parent, mutation.function), sourceEdit{token, replacement})
+		m.add(token, mutation.kind, fmt.Sprintf("replaced %s with %s", symbol, replacement), edits...)
+	}
+
+	switch function {
+	case operators.LogicalNot:
+		m.add(token, MutantOperator, "removed !", sourceEdit{token, ""})
+	case operators.LogicalAnd, operators.LogicalOr:
+		span, ok := m.source.span(e)
+		if !ok {
+			return
+		}
+		for i, side := range []string{"left", "right"} {
+			kept := args[1-i]
+			keptSpan, ok := m.source.span(kept)
+			if !ok {
+				continue
+			}
+			replacement := m.source.text(keptSpan)
+			if operatorPrecedence(kept) > operators.Precedence(function) {
+				replacement = "(" + replacement + ")"
+			}
+			m.add(span, MutantClause, fmt.Sprintf("dropped the %s operand of %s", side, symbol), sourceEdit{span, replacement})
+		}
+	case operators.Less, operators.LessEquals, operators.Greater, operators.GreaterEquals:
+		for _, arg := range args {
+			m.mutateBoundary(arg)
+		}
+	}
+}
+
+// regroup returns the parentheses keeping the operands of an operator call, and the call
+// within its parent, grouped as they were once its operator is replaced with function
+// That matters for && and ||, which bind differently, and whose chains such as a || b || c
+// the parser balances rather than grouping them to the left
+func (m *mutator) regroup(e ast.Expr, parent ast.Expr, function string) []sourceEdit {
+	precedence := operators.Precedence(function)
+	var edits []sourceEdit
+	if args := e.AsCall().Args(); len(args) == 2 {
+		if operatorPrecedence(args[0]) > precedence {
+			edits = append(edits, m.parenthesize(args[0])...)
+		}
+		if operatorPrecedence(args[1]) >= precedence {
+			edits = append(edits, m.parenthesize(args[1])...)
+		}
+	}
+	if parent != nil && operatorPrecedence(parent) > 0 && precedence > operatorPrecedence(parent) {
+		edits = append(edits, m.parenthesize(e)...)
+	}
+	return edits
+}
+
+// parenthesize returns the parentheses grouping a subexpression, unless it's grouped already
+func (m *mutator) parenthesize(e ast.Expr) []sourceEdit {
+	span, ok := m.source.span(e)
+	if !ok {
+		return nil
+	}
+	runes := m.source.runes
+	before := span.start
+	for before > 0 && unicode.IsSpace(runes[before-1]) {
+		before--
+	}
+	after := m.source.skipSpace(span.end, false)
+	if before > 0 && runes[before-1] == '(' && after < int32(len(runes)) && runes[after] == ')' {
+		return nil
+	}
+	return []sourceEdit{
+		{sourceSpan{span.start, span.start}, "("},
+		{sourceSpan{span.end, span.end}, ")"},
+	}
+}
+
+// operatorPrecedence returns the precedence of an operator call, higher for operators that
+// bind more loosely, or 0 for other nodes
+func operatorPrecedence(e ast.Expr) int {
+	if e.Kind() != ast.CallKind {
+		return 0
+	}
+	function := e.AsCall().FunctionName()
+	if symbol, ok := operators.FindReverse(function); !ok || symbol == "" && function != operators.Conditional {
+		return 0
+	}
+	return operators.Precedence(function)
+}
+
+// mutateBoundary generates the mutants moving a numeric constant compared against by one
+func (m *mutator) mutateBoundary(e ast.Expr) {
+	if e.Kind() != ast.LiteralKind {
+		return
+	}
+	span, ok := m.source.span(e)
+	if !ok {
+		return
+	}
+	text := m.source.text(span)
+
+	var lower, higher string
+	switch value := e.AsLiteral().(type) {
+	case types.Int:
+		if strconv.FormatInt(int64(value), 10) != text {
+			return
+		}
+		lower, higher = strconv.FormatInt(int64(value)-1, 10), strconv.FormatInt(int64(value)+1, 10)
+	case types.Uint:
+		if strconv.FormatUint(uint64(value), 10)+"u" != strings.ToLower(text) || value == 0 {
+			return
+		}
+		lower, higher = strconv.FormatUint(uint64(value)-1, 10)+"u", strconv.FormatUint(uint64(value)+1, 10)+"u"
+	case types.Double:
+		if parsed, err := strconv.ParseFloat(text, 64); err != nil || parsed != float64(value) {
+			return
+		}
+		lower, higher = doubleLiteral(float64(value)-1), doubleLiteral(float64(value)+1)
+	default:
+		return
+	}
+	m.add(span, MutantBoundary, fmt.Sprintf("replaced %s with %s", text, lower), sourceEdit{span, lower})
+	m.add(span, MutantBoundary, fmt.Sprintf("replaced %s with %s", text, higher), sourceEdit{span, higher})
+}
+
+// sourceEdit replaces a span of the expression
+type sourceEdit struct {
+	span        sourceSpan
+	replacement string
+}
+
+// add records the mutant making edits to the expression, which don't overlap, unless an
+// earlier mutant has the same source. changed is the span the mutant is reported to change
+func (m *mutator) add(changed sourceSpan, kind string, description string, edits ...sourceEdit) {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].span.start < edits[j].span.start
+	})
+	var expr strings.Builder
+	runes := m.source.runes
+	offset := int32(0)
+	for _, edit := range edits {
+		expr.WriteString(string(runes[offset:edit.span.start]))
+		expr.WriteString(edit.replacement)
+		offset = edit.span.end
+	}
+	expr.WriteString(string(runes[offset:]))
+
+	if m.seen[expr.String()] {
+		return
+	}
+	m.seen[expr.String()] = true
+	m.mutants = append(m.mutants, mutant{expr: expr.String(), kind: kind, description: description, span: changed})
+}
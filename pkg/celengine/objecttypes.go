@@ -0,0 +1,199 @@
+package celengine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// objectTypePackage prefixes the names of the object types declared by variables
+const objectTypePackage = "wasmcel.vars."
+
+// objectFieldName matches the field names of object types, which expressions select
+var objectFieldName = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// objectType is a struct type declared by a variable, whose values are JSON objects
+// Fields are type checked like the fields of a message, while evaluations read them from
+// the objects like map entries, so has() tests whether an object has a field
+type objectType struct {
+	fields map[string]*types.Type
+	names  []string // Field names, sorted
+}
+
+// declareObjectTypes names the object types in the types of variable declarations after
+// their paths, so the object of request.auth is wasmcel.vars.request.auth. It returns
+// copies of the declarations whose object types carry their names, along with the object
+// types by name
+// Objects that are list elements or map values are named after the list or map
+func declareObjectTypes(varDecls []VarDecl, parseType func(interface{}) *exprpb.Type) ([]VarDecl, map[string]*objectType, error) {
+	objects := make(map[string]*objectType)
+	declared := make([]VarDecl, len(varDecls))
+	for i, varDecl := range varDecls {
+		typeDef, err := nameObjectTypes(varDecl.Type, varDecl.Name, objects, parseType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("variable %s: %v", varDecl.Name, err)
+		}
+		declared[i] = varDecl
+		declared[i].Type = typeDef
+	}
+	return declared, objects, nil
+}
+
+// nameObjectTypes names the object types within a type definition found at path, and
+// records their field types
+func nameObjectTypes(typeDef interface{}, path string, objects map[string]*objectType, parseType func(interface{}) *exprpb.Type) (interface{}, error) {
+	typeDefMap, ok := typeDef.(map[string]interface{})
+	if !ok {
+		return typeDef, nil
+	}
+
+	named := make(map[string]interface{}, len(typeDefMap)+1)
+	for key, value := range typeDefMap {
+		named[key] = value
+	}
+	switch typeDefMap["kind"] {
+	case "list":
+		elemType, err := nameObjectTypes(typeDefMap["elementType"], path, objects, parseType)
+		if err != nil {
+			return nil, err
+		}
+		named["elementType"] = elemType
+		return named, nil
+	case "map":
+		valueType, err := nameObjectTypes(typeDefMap["valueType"], path, objects, parseType)
+		if err != nil {
+			return nil, err
+		}
+		named["valueType"] = valueType
+		return named, nil
+	case "object":
+	default:
+		return typeDef, nil
+	}
+
+	// An object without fields refers to an object type declared elsewhere by its name
+	fieldDefs, ok := typeDefMap["fields"].(map[string]interface{})
+	if !ok {
+		return typeDef, nil
+	}
+	name := objectTypePackage + path
+	object := &objectType{
+		fields: make(map[string]*types.Type, len(fieldDefs)),
+		names:  make([]string, 0, len(fieldDefs)),
+	}
+	for fieldName := range fieldDefs {
+		object.names = append(object.names, fieldName)
+	}
+	sort.Strings(object.names)
+
+	objects[name] = object
+	namedFields := make(map[string]interface{}, len(fieldDefs))
+	for _, fieldName := range object.names {
+		if !objectFieldName.MatchString(fieldName) {
+			return nil, fmt.Errorf("invalid field name %q of %s", fieldName, path)
+		}
+		fieldDef, err := nameObjectTypes(fieldDefs[fieldName], path+"."+fieldName, objects, parseType)
+		if err != nil {
+			return nil, err
+		}
+		fieldType, err := cel.ExprTypeToType(parseType(fieldDef))
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %v", path, fieldName, err)
+		}
+		object.fields[fieldName] = fieldType
+		namedFields[fieldName] = fieldDef
+	}
+	named["fields"] = namedFields
+	named["name"] = name
+	return named, nil
+}
+
+// objectTypes makes the object types of an environment known to its type checker,
+// wrapping the type provider of the environment
+// An environment extended from one with object types shares its provider, so the
+// provider is copied rather than wrapped twice
+func objectTypes(objects map[string]*objectType) cel.EnvOption {
+	return func(e *cel.Env) (*cel.Env, error) {
+		var registry *types.Registry
+		switch provider := e.CELTypeProvider().(type) {
+		case *types.Registry:
+			registry = provider
+		case *objectTypeProvider:
+			registry = provider.Registry.Copy()
+		default:
+			return nil, fmt.Errorf("object types are not supported by type provider %T", provider)
+		}
+
+		provider := &objectTypeProvider{Registry: registry, objects: objects}
+		e, err := cel.CustomTypeProvider(provider)(e)
+		if err != nil {
+			return nil, err
+		}
+		return cel.CustomTypeAdapter(provider)(e)
+	}
+}
+
+// keepObjectTypes returns the option declaring the object types of an environment in
+// the environments extended from it, or nil when it has none
+// It must come after any option replacing the type provider
+func keepObjectTypes(env *cel.Env) cel.EnvOption {
+	provider, ok := env.CELTypeProvider().(*objectTypeProvider)
+	if !ok {
+		return nil
+	}
+	return objectTypes(provider.objects)
+}
+
+// objectTypeProvider is a type registry that also provides the object types declared by
+// variables
+type objectTypeProvider struct {
+	*types.Registry
+	objects map[string]*objectType
+}
+
+// FindStructType implements types.Provider
+func (p *objectTypeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, ok := p.objects[structType]; ok {
+		return types.NewTypeTypeWithParam(types.NewObjectType(structType)), true
+	}
+	return p.Registry.FindStructType(structType)
+}
+
+// FindStructFieldNames implements types.Provider
+func (p *objectTypeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	if object, ok := p.objects[structType]; ok {
+		return append([]string(nil), object.names...), true
+	}
+	return p.Registry.FindStructFieldNames(structType)
+}
+
+// FindStructFieldType implements types.Provider
+// Object fields have no accessors, so evaluations select them from objects like map keys
+func (p *objectTypeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	object, ok := p.objects[structType]
+	if !ok {
+		return p.Registry.FindStructFieldType(structType, fieldName)
+	}
+	fieldType, ok := object.fields[fieldName]
+	if !ok {
+		return nil, false
+	}
+	return &types.FieldType{Type: fieldType}, true
+}
+
+// NewValue implements types.Provider, creating objects as maps
+func (p *objectTypeProvider) NewValue(structType string, fields map[string]ref.Val) ref.Val {
+	if _, ok := p.objects[structType]; !ok {
+		return p.Registry.NewValue(structType, fields)
+	}
+	entries := make(map[ref.Val]ref.Val, len(fields))
+	for name, value := range fields {
+		entries[types.String(name)] = value
+	}
+	return types.NewRefValMap(p, entries)
+}
@@ -0,0 +1,56 @@
+package celengine
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/invakid404/wasm-cel/internal/logging"
+)
+
+// CompileFlags configures how an expression is compiled
+type CompileFlags struct {
+	Metrics bool `json:"metrics"`
+	// Optimize folds constant subexpressions into literals and enables the planner's
+	// optimizations, which precompute constant lists, maps and regular expressions
+	Optimize bool `json:"optimize"`
+	// OptimizedSource includes the source of the compiled expression, after folding,
+	// under the "optimizedSource" key
+	OptimizedSource bool `json:"optimizedSource"`
+	// Memoize caches up to this many evaluation responses, keyed by the variables and
+	// evaluation options, so repeated evaluations with identical inputs are not recomputed
+	Memoize int `json:"memoize"`
+	// FieldMask includes the paths into the variables that the expression reads under the
+	// "fieldMask" key, so callers can drop the rest of large variables before evaluating it
+	FieldMask bool `json:"fieldMask"`
+	// SourceMap includes a map from ranges of the optimized source back to ranges of the
+	// expression under the "sourceMap" key, along with OptimizedSource
+	SourceMap bool `json:"sourceMap"`
+	// ExpectedType fails compilation unless the expression has this type, given in the
+	// type grammar of declarations, such as "bool" for the conditions of policies
+	ExpectedType interface{} `json:"expectedType,omitempty"`
+}
+
+// addOptimizedSource adds the source of a compiled expression to a compilation result
+func addOptimizedSource(response map[string]interface{}, compiled *cel.Ast) {
+	source, err := cel.AstToString(compiled)
+	if err != nil {
+		logging.Warn("failed to unparse the optimized expression", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	response["optimizedSource"] = source
+}
+
+// addSourceMap adds the map from the optimized source of a compilation result back to the
+// expression it was compiled from
+func addSourceMap(response map[string]interface{}, envState *EnvState, exprStr string) {
+	optimized, ok := response["optimizedSource"].(string)
+	if !ok {
+		return
+	}
+	mappings, ok := sourceMap(envState.env, exprStr, optimized)
+	if !ok {
+		logging.Warn("failed to map the optimized expression to its source", nil)
+		return
+	}
+	response["sourceMap"] = mappings
+}
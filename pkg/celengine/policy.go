@@ -0,0 +1,394 @@
+package celengine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+	"gopkg.in/yaml.v3"
+)
+
+// policyDocument is a CEL policy in the YAML format of cel.dev/policy
+type policyDocument struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Rule        *policyRule `yaml:"rule"`
+}
+
+// policyRule declares variables and the matches that decide the rule's output
+type policyRule struct {
+	ID          string           `yaml:"id"`
+	Description string           `yaml:"description"`
+	Variables   []policyVariable `yaml:"variables"`
+	Match       []policyMatch    `yaml:"match"`
+}
+
+// policyVariable is an expression available to later expressions as variables.<name>
+type policyVariable struct {
+	Name        string `yaml:"name"`
+	Expression  string `yaml:"expression"`
+	Description string `yaml:"description"`
+}
+
+// policyMatch produces an output, or defers to a nested rule, when its condition holds
+// A match without a condition always holds
+type policyMatch struct {
+	Condition   string      `yaml:"condition"`
+	Output      string      `yaml:"output"`
+	Explanation string      `yaml:"explanation"`
+	Rule        *policyRule `yaml:"rule"`
+}
+
+// policyState holds a compiled policy
+// Each expression of the policy is compiled as a program of the environment, and
+// evaluating the policy walks the graph of programs
+type policyState struct {
+	root       *compiledPolicyRule
+	programIDs []string
+	inputs     *envInputs // Defaults and absent variable policy of the environment
+}
+
+// compiledPolicyRule is a rule whose expressions are compiled to programs
+type compiledPolicyRule struct {
+	variables []compiledPolicyVariable
+	matches   []compiledPolicyMatch
+}
+
+// compiledPolicyVariable is a variable and the program computing its value
+type compiledPolicyVariable struct {
+	name      string
+	programID string
+}
+
+// compiledPolicyMatch is a match whose expressions are compiled to programs
+// An empty condition always holds; output is empty when the match defers to a rule
+type compiledPolicyMatch struct {
+	condition   string
+	output      string
+	explanation string
+	rule        *compiledPolicyRule
+}
+
+var (
+	policies        = make(map[string]*policyState)
+	policyIDCounter int64
+)
+
+// CompilePolicy compiles a policy in the YAML format of cel.dev/policy, so it can be
+// evaluated with a single call
+// Variables are evaluated lazily, when an expression first refers to them, and the
+// output of the first match whose condition holds is the output of the rule
+func CompilePolicy(envID string, source string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	var document policyDocument
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(source)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&document); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("failed to parse policy: %v", err),
+		}
+	}
+	if document.Rule == nil {
+		return map[string]interface{}{
+			"error": "policy requires a rule",
+		}
+	}
+
+	compiler := &policyCompiler{envID: envID, envState: envState}
+	root, err := compiler.compileRule(envState.env, document.Rule, "rule")
+	if err != nil {
+		destroyPrograms(compiler.programIDs)
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	policyIDCounter++
+	policyID := newID("policy", policyIDCounter)
+	policies[policyID] = &policyState{root: root, programIDs: compiler.programIDs, inputs: envState.inputs}
+
+	return map[string]interface{}{
+		"policyID": policyID,
+		"name":     document.Name,
+		"error":    nil,
+	}
+}
+
+// EvalPolicy evaluates a compiled policy
+// "matched" reports whether any match produced an output, which is the "result"
+// along with its "explanation", if the match has one
+func EvalPolicy(policyID string, vars map[string]interface{}, encoding ValueEncoding) map[string]interface{} {
+	if err := encoding.validate(); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	state, ok := policies[policyID]
+	if !ok {
+		return idNotFound("policy", "policy", policyID)
+	}
+
+	encoding = state.inputs.semantics.withDefaults(encoding)
+
+	// Tagged doubles stand in for NaN and ±Inf, which JSON can't represent
+	decoded, _ := decodeTaggedMap(vars)
+	activation, err := policyActivation(state, decoded)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	// The programs of the policy share the calls to cacheable functions they make
+	evalCalls = &callCache{}
+	output, explanation, matched, err := state.root.eval(activation)
+	evalCalls = nil
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("evaluation error: %v", err),
+		}
+	}
+
+	response := map[string]interface{}{
+		"result":  nil,
+		"matched": matched,
+		"error":   nil,
+	}
+	if matched {
+		response["result"] = ValueToJSONWithEncoding(output, encoding)
+	}
+	if explanation != nil {
+		response["explanation"] = ValueToJSONWithEncoding(explanation, encoding)
+	}
+	return response
+}
+
+// DestroyPolicy destroys a compiled policy along with the programs compiled from it
+func DestroyPolicy(policyID string) map[string]interface{} {
+	state, ok := policies[policyID]
+	if !ok {
+		return idNotFound("policy", "policy", policyID)
+	}
+	delete(policies, policyID)
+	destroyPrograms(state.programIDs)
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// destroyPrograms destroys the programs that still exist among programIDs
+// Programs destroyed on their own, such as by a session or sweep, are skipped
+func destroyPrograms(programIDs []string) {
+	for _, programID := range programIDs {
+		if _, ok := programs.get(programID); ok {
+			DestroyProgram(programID)
+		}
+	}
+}
+
+// policyCompiler compiles the expressions of a policy as programs of an environment
+type policyCompiler struct {
+	envID      string
+	envState   *EnvState
+	programIDs []string
+}
+
+// compileRule compiles a rule, declaring its variables for the expressions after them
+// path locates the rule in the policy for error messages
+func (c *policyCompiler) compileRule(env *cel.Env, rule *policyRule, path string) (*compiledPolicyRule, error) {
+	compiled := &compiledPolicyRule{}
+	for i, variable := range rule.Variables {
+		variablePath := fmt.Sprintf("%s.variables[%d]", path, i)
+		if variable.Name == "" {
+			return nil, fmt.Errorf("%s: variable requires a name", variablePath)
+		}
+
+		checked, programID, err := c.compile(env, variable.Expression, variablePath+".expression")
+		if err != nil {
+			return nil, err
+		}
+		compiled.variables = append(compiled.variables, compiledPolicyVariable{name: variable.Name, programID: programID})
+
+		env, err = env.Extend(cel.Variable("variables."+variable.Name, checked.OutputType()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", variablePath, err)
+		}
+	}
+
+	if len(rule.Match) == 0 {
+		return nil, fmt.Errorf("%s: rule requires at least one match", path)
+	}
+	for i, match := range rule.Match {
+		matchPath := fmt.Sprintf("%s.match[%d]", path, i)
+		if (match.Output == "") == (match.Rule == nil) {
+			return nil, fmt.Errorf("%s: match requires either an output or a rule", matchPath)
+		}
+
+		var compiledMatch compiledPolicyMatch
+		if match.Condition != "" {
+			checked, programID, err := c.compile(env, match.Condition, matchPath+".condition")
+			if err != nil {
+				return nil, err
+			}
+			if kind := checked.OutputType().Kind(); kind != types.BoolKind && kind != types.DynKind {
+				return nil, fmt.Errorf("%s.condition: expected a bool expression, got %s", matchPath, checked.OutputType())
+			}
+			compiledMatch.condition = programID
+		}
+
+		if match.Rule != nil {
+			if match.Explanation != "" {
+				return nil, fmt.Errorf("%s: match with a rule can't have an explanation", matchPath)
+			}
+			nested, err := c.compileRule(env, match.Rule, matchPath+".rule")
+			if err != nil {
+				return nil, err
+			}
+			compiledMatch.rule = nested
+		} else {
+			_, programID, err := c.compile(env, match.Output, matchPath+".output")
+			if err != nil {
+				return nil, err
+			}
+			compiledMatch.output = programID
+
+			if match.Explanation != "" {
+				_, programID, err := c.compile(env, match.Explanation, matchPath+".explanation")
+				if err != nil {
+					return nil, err
+				}
+				compiledMatch.explanation = programID
+			}
+		}
+		compiled.matches = append(compiled.matches, compiledMatch)
+	}
+	return compiled, nil
+}
+
+// compile compiles an expression of the policy as a program of the environment
+func (c *policyCompiler) compile(env *cel.Env, expr string, path string) (*cel.Ast, string, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, "", fmt.Errorf("%s: expression is empty", path)
+	}
+
+	checked, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, "", fmt.Errorf("%s: compilation error: %v", path, issues.Err())
+	}
+	prg, err := env.Program(batchCalls(env, checked))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: failed to create program: %v", path, err)
+	}
+
+	programID := addProgram(c.envID, c.envState, prg, checked, nil, nil)
+	c.programIDs = append(c.programIDs, programID)
+	return checked, programID, nil
+}
+
+// eval evaluates a rule, returning the output and explanation of the first match whose
+// condition holds, and whether there was one
+func (r *compiledPolicyRule) eval(parent interpreter.Activation) (ref.Val, ref.Val, bool, error) {
+	// Variables are bound lazily, and cel-go replaces each binding with its value once used
+	var activation interpreter.Activation
+	bindings := make(map[string]interface{}, len(r.variables))
+	for _, variable := range r.variables {
+		programID := variable.programID
+		bindings["variables."+variable.name] = func() ref.Val {
+			val, err := evalPolicyProgram(programID, activation)
+			if err != nil {
+				return types.WrapErr(err)
+			}
+			return val
+		}
+	}
+	variables, err := interpreter.NewActivation(bindings)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	activation = interpreter.NewHierarchicalActivation(parent, variables)
+
+	for _, match := range r.matches {
+		if match.condition != "" {
+			holds, err := evalPolicyProgram(match.condition, activation)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if holds != types.True {
+				continue
+			}
+		}
+
+		if match.rule != nil {
+			output, explanation, matched, err := match.rule.eval(activation)
+			if err != nil || matched {
+				return output, explanation, matched, err
+			}
+			continue
+		}
+
+		output, err := evalPolicyProgram(match.output, activation)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		var explanation ref.Val
+		if match.explanation != "" {
+			explanation, err = evalPolicyProgram(match.explanation, activation)
+			if err != nil {
+				return nil, nil, false, err
+			}
+		}
+		return output, explanation, true, nil
+	}
+	return nil, nil, false, nil
+}
+
+// policyActivation binds the variables of a policy evaluation, applying the defaults and
+// absent variable policy of the environment
+func policyActivation(state *policyState, vars map[string]interface{}) (interpreter.Activation, error) {
+	bound, options, err := state.inputs.bind(vars, EvalOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(options.Unknowns) > 0 {
+		return cel.PartialVars(bound, unknownPatterns(options.Unknowns)...)
+	}
+	activation, err := cel.NewActivation(bound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create activation: %v", err)
+	}
+	return activation, nil
+}
+
+// evalPolicyProgram evaluates one of the programs of a policy
+func evalPolicyProgram(programID string, activation interpreter.Activation) (ref.Val, error) {
+	programState, ok := programs.get(programID)
+	if !ok {
+		return nil, fmt.Errorf("policy program was destroyed: %s", programID)
+	}
+	touchProgram(programState)
+
+	val, _, err := programState.prg.Eval(activation)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
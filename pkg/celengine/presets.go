@@ -0,0 +1,109 @@
+package celengine
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// presetFiles holds the environment presets baked into the module, one bundle per file
+// named after the preset, generated by presetsgen from the presets directory at the root
+// of the repository
+//
+//go:generate go run ../../cmd/presetsgen ../../presets presets
+//go:embed presets
+var presetFiles embed.FS
+
+// presetDir is the directory of presetFiles holding the presets
+const presetDir = "presets"
+
+// presets caches the presets already decoded, by name
+var presets = make(map[string]*Bundle)
+
+// PresetNames returns the names of the environment presets baked into the module, sorted
+func PresetNames() []string {
+	entries, err := fs.ReadDir(presetFiles, presetDir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// preset returns the environment preset baked into the module under a name, decoding it
+// the first time it's used
+func preset(name string) (*Bundle, error) {
+	if bundle, ok := presets[name]; ok {
+		return bundle, nil
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("unknown preset: %s", name)
+	}
+	data, err := presetFiles.ReadFile(path.Join(presetDir, name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown preset: %s", name)
+	}
+	bundle, err := decodePreset(data)
+	if err != nil {
+		return nil, fmt.Errorf("preset %s: %v", name, err)
+	}
+	presets[name] = bundle
+	return bundle, nil
+}
+
+// decodePreset decodes an environment preset: a bundle without programs, whose
+// environment is created by name rather than shipped at startup
+func decodePreset(data []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse preset: %v", err)
+	}
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported preset version %d: expected %d", bundle.Version, BundleVersion)
+	}
+	if len(bundle.Programs) > 0 {
+		return nil, fmt.Errorf("presets can't have programs")
+	}
+	return &bundle, nil
+}
+
+// CreateEnvFromPreset creates the environment of a preset baked into the module, in the
+// session if sessionID isn't empty
+func CreateEnvFromPreset(name string, sessionID string) map[string]interface{} {
+	bundle, err := preset(name)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	return bundle.createEnv(sessionID)
+}
+
+// CheckPreset decodes an environment preset and creates its environment, returning the
+// error creating it from the module would fail with
+// Presets using libraries are only decoded, as libraries are registered at runtime
+func CheckPreset(data []byte) error {
+	bundle, err := decodePreset(data)
+	if err != nil {
+		return err
+	}
+	if len(bundle.Env.Libraries) > 0 {
+		return nil
+	}
+	created := bundle.createEnv("")
+	if created["error"] != nil {
+		return fmt.Errorf("%v", created["error"])
+	}
+	DestroyEnv(created["envID"].(string))
+	return nil
+}
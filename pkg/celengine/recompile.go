@@ -0,0 +1,133 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// programSource is how a program was compiled from an expression, so it can be compiled
+// again after its environment changed
+type programSource struct {
+	expr               string
+	programOptionsJSON *string
+	flags              CompileFlags
+}
+
+// RecompilePrograms compiles the live programs of an environment again, against the
+// declarations the environment has now, such as those added by ExtendEnv
+// Each program keeps its ID. A program that no longer compiles keeps its previous
+// compilation and is reported under "issues". Programs compiled from policies aren't
+// compiled again, since their expressions are compiled in an extension of the environment,
+// and neither are programs imported from checked expressions, which have no source
+func RecompilePrograms(envID string) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	var programIDs []string
+	for programID, programState := range programs.all() {
+		if programState.envID == envID && programState.source != nil {
+			programIDs = append(programIDs, programID)
+		}
+	}
+	sort.Strings(programIDs)
+
+	recompiled := make([]interface{}, 0, len(programIDs))
+	issues := make([]interface{}, 0)
+	for _, programID := range programIDs {
+		programState, _ := programs.get(programID)
+		source := programState.source
+		ast, prg, memo, err := buildProgram(envState, source.expr, source.programOptionsJSON, source.flags, nil)
+		if err != nil {
+			issues = append(issues, map[string]interface{}{
+				"programID": programID,
+				"error":     err.Error(),
+			})
+			continue
+		}
+
+		programState.prg = prg
+		programState.ast = ast
+		programState.memo = memo
+		programState.variables = nil
+		recompiled = append(recompiled, programID)
+	}
+
+	return map[string]interface{}{
+		"recompiled": recompiled,
+		"issues":     issues,
+		"error":      nil,
+	}
+}
+
+// ReplaceProgram compiles an expression in the environment of a program and swaps it in
+// under the program's ID, with the program options and flags the program was compiled with
+// If the expression doesn't compile, the program is left as it was
+func ReplaceProgram(programID string, exprStr string) map[string]interface{} {
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+	if programState.source == nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("program %s wasn't compiled from an expression and can't be replaced", programID),
+		}
+	}
+
+	touchProgram(programState)
+
+	envState, ok := envs.get(programState.envID)
+	if !ok {
+		return envs.notFound(programState.envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", programState.envID),
+		}
+	}
+	touchEnv(envState)
+
+	source := &programSource{
+		expr:               exprStr,
+		programOptionsJSON: programState.source.programOptionsJSON,
+		flags:              programState.source.flags,
+	}
+	ast, prg, memo, err := buildProgram(envState, source.expr, source.programOptionsJSON, source.flags, nil)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	programState.prg = prg
+	programState.ast = ast
+	programState.memo = memo
+	programState.source = source
+	programState.variables = nil
+
+	response := map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+	if source.flags.OptimizedSource {
+		addOptimizedSource(response, ast)
+		if source.flags.SourceMap {
+			addSourceMap(response, envState, source.expr)
+		}
+	}
+	if source.flags.FieldMask {
+		addFieldMask(response, ast)
+	}
+	return response
+}
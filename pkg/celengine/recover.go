@@ -0,0 +1,37 @@
+package celengine
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// InternalErrorCode is the error code of results returned for recovered panics
+const InternalErrorCode = "internal"
+
+// RecoverPanic turns a panic in an entrypoint into a result with a structured error,
+// so a single bad call doesn't abort the whole module
+// It must be deferred directly: defer celengine.RecoverPanic(&result)
+func RecoverPanic(result *interface{}) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	*result = map[string]interface{}{
+		"error": internalError(recovered, debug.Stack()),
+	}
+}
+
+// internalError describes a recovered panic
+func internalError(recovered interface{}, stack []byte) map[string]interface{} {
+	message := fmt.Sprint(recovered)
+	if err, ok := recovered.(error); ok {
+		message = err.Error()
+	}
+
+	return map[string]interface{}{
+		"code":    InternalErrorCode,
+		"message": fmt.Sprintf("internal error: %s", message),
+		"stack":   string(stack),
+	}
+}
@@ -0,0 +1,76 @@
+package celengine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// EvalRepeated evaluates a program n times against the same variables and reports the
+// timing of the evaluations, so benchmarks measure the expression rather than n crossings
+// into the module. The variables are decoded and bound to an activation once
+// The response is that of the last evaluation, with the timings under "stats". A failed
+// evaluation stops the loop and its response is returned as is
+func EvalRepeated(programID string, vars map[string]interface{}, n int, options EvalOptions) map[string]interface{} {
+	if n < 1 {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("n must be positive, got %d", n),
+		}
+	}
+	if err := options.ValueEncoding.validate(); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	inputs, err := newSharedInputs(vars, options)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	// A memo hit would time the cache rather than the expression
+	inputs.memoKey = func() (string, bool) { return "", false }
+
+	durations := make([]time.Duration, n)
+	var response map[string]interface{}
+	for i := range durations {
+		start := time.Now()
+		response = inputs.eval(programID, options)
+		durations[i] = time.Since(start)
+		if response["error"] != nil {
+			return response
+		}
+	}
+
+	response["stats"] = repeatStats(durations)
+	return response
+}
+
+// repeatStats summarizes the durations of repeated evaluations in milliseconds
+func repeatStats(durations []time.Duration) map[string]interface{} {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return map[string]interface{}{
+		"iterations": len(durations),
+		"totalMs":    milliseconds(total),
+		"minMs":      milliseconds(durations[0]),
+		"p50Ms":      milliseconds(percentile(durations, 0.50)),
+		"p95Ms":      milliseconds(percentile(durations, 0.95)),
+		"maxMs":      milliseconds(durations[len(durations)-1]),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
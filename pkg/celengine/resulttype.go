@@ -0,0 +1,55 @@
+package celengine
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+)
+
+// resultTypeError is a checked expression whose type doesn't match the expected type
+type resultTypeError struct {
+	message  string
+	location common.Location // Start of the expression
+}
+
+// Error implements error
+func (e *resultTypeError) Error() string {
+	return e.message
+}
+
+// checkResultType checks the type of a checked expression against the type expected by
+// flags, parsed with the type grammar of the environment. Expressions of type dyn match
+// any type, as their type is only known once they are evaluated
+func checkResultType(envState *EnvState, checked *cel.Ast, flags CompileFlags) error {
+	if flags.ExpectedType == nil {
+		return nil
+	}
+	expected, err := cel.ExprTypeToType(envState.inputs.semantics.parseType(flags.ExpectedType))
+	if err != nil {
+		return fmt.Errorf("invalid expected type: %v", err)
+	}
+	actual := checked.OutputType()
+	if actual.Kind() == types.DynKind || expected.IsAssignableType(actual) {
+		return nil
+	}
+	return &resultTypeError{
+		message:  fmt.Sprintf("expression has type %s, expected %s", cel.FormatCELType(actual), cel.FormatCELType(expected)),
+		location: expressionStart(checked),
+	}
+}
+
+// expressionStart returns the location of the start of an expression
+// The parser records the operator of calls as their location, so when the source is
+// known the start is that of the span covering the whole expression
+func expressionStart(checked *cel.Ast) common.Location {
+	native := checked.NativeRep()
+	if checked.Source() != nil {
+		parsed := newParsedSource(checked.Source().Content(), checked)
+		if span, ok := parsed.span(parsed.expr); ok {
+			return native.SourceInfo().GetLocationByOffset(span.start)
+		}
+	}
+	return native.SourceInfo().GetStartLocation(native.Expr().ID())
+}
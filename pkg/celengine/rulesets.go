@@ -0,0 +1,182 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/common/types"
+)
+
+// Modes of a rule, which decide whether evaluation continues after it matches
+const (
+	RuleModeContinue = "continue" // Later rules are evaluated too, unless stopping on the first match
+	RuleModeStop     = "stop"     // No rules are evaluated after this one matches
+)
+
+// Rule is a boolean expression in a rule set
+type Rule struct {
+	ID   string `json:"id"`
+	Expr string `json:"expr"`
+	// Mode is RuleModeContinue or RuleModeStop, defaulting to RuleModeContinue
+	Mode string `json:"mode,omitempty"`
+	// Priority orders the rules, highest first; rules of equal priority keep their order
+	Priority int `json:"priority,omitempty"`
+}
+
+// RuleSetEvalOptions configures the evaluation of a rule set
+type RuleSetEvalOptions struct {
+	// StopOnFirstMatch ends the evaluation at the first matching rule, whatever its mode
+	StopOnFirstMatch bool `json:"stopOnFirstMatch"`
+}
+
+// ruleSetState holds the compiled rules of a rule set, in evaluation order
+type ruleSetState struct {
+	rules []compiledRule
+}
+
+// compiledRule is a rule along with the program compiled from its expression
+type compiledRule struct {
+	id        string
+	mode      string
+	programID string
+}
+
+var (
+	ruleSets         = make(map[string]*ruleSetState)
+	ruleSetIDCounter int64
+)
+
+// CreateRuleSet compiles rules into a rule set that is evaluated in a single call
+// Each rule must evaluate to a bool. Rules are compiled as programs of the environment,
+// so they keep it alive and follow the same cleanup rules until DestroyRuleSet
+func CreateRuleSet(envID string, rules []Rule) map[string]interface{} {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return map[string]interface{}{
+				"error": "rule requires an id",
+			}
+		}
+		if seen[rule.ID] {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("duplicate rule id: %s", rule.ID),
+			}
+		}
+		seen[rule.ID] = true
+
+		if rule.Mode != "" && rule.Mode != RuleModeContinue && rule.Mode != RuleModeStop {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("invalid mode %q of rule %s: expected %q or %q", rule.Mode, rule.ID, RuleModeContinue, RuleModeStop),
+			}
+		}
+	}
+
+	ordered := append([]Rule{}, rules...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+
+	state := &ruleSetState{rules: make([]compiledRule, 0, len(ordered))}
+	for _, rule := range ordered {
+		compiled := CompileWithFlags(envID, rule.Expr, nil, CompileFlags{})
+		programID, ok := compiled["programID"].(string)
+		if !ok {
+			state.destroy()
+			return map[string]interface{}{
+				"error": fmt.Sprintf("rule %s: %v", rule.ID, compiled["error"]),
+			}
+		}
+		state.rules = append(state.rules, compiledRule{id: rule.ID, mode: rule.Mode, programID: programID})
+
+		programState, _ := programs.get(programID)
+		outputType := programState.ast.OutputType()
+		if outputType.Kind() != types.BoolKind && outputType.Kind() != types.DynKind {
+			state.destroy()
+			return map[string]interface{}{
+				"error": fmt.Sprintf("rule %s: expected a bool expression, got %s", rule.ID, outputType),
+			}
+		}
+	}
+
+	ruleSetIDCounter++
+	ruleSetID := newID("ruleset", ruleSetIDCounter)
+	ruleSets[ruleSetID] = state
+
+	return map[string]interface{}{
+		"ruleSetID": ruleSetID,
+		"error":     nil,
+	}
+}
+
+// EvalRuleSet evaluates the rules of a rule set against the same variables, in order
+// "matched" lists the ids of the rules that evaluated to true. A rule that fails to
+// evaluate doesn't match, and is listed in "errors" with its error instead
+func EvalRuleSet(ruleSetID string, vars map[string]interface{}, options RuleSetEvalOptions) map[string]interface{} {
+	state, ok := ruleSets[ruleSetID]
+	if !ok {
+		return idNotFound("rule set", "ruleset", ruleSetID)
+	}
+
+	evalOptions := EvalOptions{}
+	inputs, err := newSharedInputs(vars, evalOptions)
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	matched := make([]interface{}, 0)
+	errs := make([]interface{}, 0)
+	evaluated := 0
+	for _, rule := range state.rules {
+		response := inputs.eval(rule.programID, evalOptions)
+		evaluated++
+
+		if response["error"] != nil {
+			errs = append(errs, map[string]interface{}{
+				"id":    rule.id,
+				"error": response["error"],
+			})
+			continue
+		}
+		if response["result"] != true {
+			continue
+		}
+
+		matched = append(matched, rule.id)
+		if options.StopOnFirstMatch || rule.mode == RuleModeStop {
+			break
+		}
+	}
+
+	return map[string]interface{}{
+		"matched":   matched,
+		"errors":    errs,
+		"evaluated": evaluated,
+		"error":     nil,
+	}
+}
+
+// DestroyRuleSet destroys a rule set along with the programs compiled from its rules
+func DestroyRuleSet(ruleSetID string) map[string]interface{} {
+	state, ok := ruleSets[ruleSetID]
+	if !ok {
+		return idNotFound("rule set", "ruleset", ruleSetID)
+	}
+	delete(ruleSets, ruleSetID)
+	state.destroy()
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
+
+// destroy destroys the programs compiled from the rules
+func (s *ruleSetState) destroy() {
+	programIDs := make([]string, len(s.rules))
+	for i, rule := range s.rules {
+		programIDs[i] = rule.programID
+	}
+	destroyPrograms(programIDs)
+}
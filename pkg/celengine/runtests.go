@@ -0,0 +1,249 @@
+package celengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TestCase is a case of RunTests: variables and the result a program should return for them
+type TestCase struct {
+	// Name identifies the case in the results
+	Name string `json:"name,omitempty"`
+	// Vars are the variables of the evaluation
+	Vars map[string]interface{} `json:"vars"`
+	// Expect is the expected result, compared like JSON values, so 1 and 1.0 are equal
+	Expect interface{} `json:"expect"`
+	// ExpectError expects the evaluation to fail with an error containing this text,
+	// instead of returning Expect
+	ExpectError string `json:"expectError,omitempty"`
+}
+
+// RunTests evaluates a program against each test case in one call, so rule authors can
+// run a suite without crossing into the module per case
+// Each case is evaluated like EvalWithOptions, with CEL errors as values. The response
+// holds the number of cases "passed" and "failed", and under "results" the outcome of
+// each case in order: its "name", whether it "passed", the "actual" result or the "error"
+// it failed with, and for results that differ from the expected one, "diffs" listing the
+// "path" of each difference within the result along with the "expected" and "actual"
+// values there, either absent where a map or list has no such entry
+func RunTests(programID string, cases []TestCase, options EvalOptions) map[string]interface{} {
+	if err := options.ValueEncoding.validate(); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	if _, ok := programs.get(programID); !ok {
+		return programs.notFound(programID)
+	}
+	options.ErrorValues = true
+
+	passed, failed := 0, 0
+	results := make([]interface{}, len(cases))
+	for i, testCase := range cases {
+		if testCase.Vars == nil {
+			testCase.Vars = make(map[string]interface{})
+		}
+		result := runTestCase(programID, testCase, options)
+		if result["passed"] == true {
+			passed++
+		} else {
+			failed++
+		}
+		results[i] = result
+	}
+
+	return map[string]interface{}{
+		"passed":  passed,
+		"failed":  failed,
+		"results": results,
+		"error":   nil,
+	}
+}
+
+// runTestCase evaluates a program against a test case and compares the outcome
+func runTestCase(programID string, testCase TestCase, options EvalOptions) map[string]interface{} {
+	response := EvalWithOptions(programID, testCase.Vars, options)
+	result := map[string]interface{}{}
+	if testCase.Name != "" {
+		result["name"] = testCase.Name
+	}
+
+	// Failures of the evaluation and the CEL errors it returned are both errors of the case
+	var evalErr string
+	switch {
+	case response["error"] != nil:
+		evalErr = fmt.Sprintf("%v", response["error"])
+	case response["errorValue"] != nil:
+		evalErr = fmt.Sprintf("%v", response["errorValue"].(map[string]interface{})["message"])
+	}
+	if evalErr != "" {
+		result["error"] = evalErr
+		result["passed"] = testCase.ExpectError != "" && strings.Contains(evalErr, testCase.ExpectError)
+		return result
+	}
+
+	actual := response["result"]
+	result["actual"] = actual
+	if testCase.ExpectError != "" {
+		result["passed"] = false
+		return result
+	}
+	diffs := diffTestValues("", testCase.Expect, actual, make([]interface{}, 0))
+	result["passed"] = len(diffs) == 0
+	if len(diffs) > 0 {
+		result["diffs"] = diffs
+	}
+	return result
+}
+
+// diffTestValues appends the differences between an expected and an actual value at path
+func diffTestValues(path string, expected, actual interface{}, diffs []interface{}) []interface{} {
+	if sameTestValue(expected, actual) {
+		return diffs
+	}
+
+	expectedMap, expectedIsMap := testMap(expected)
+	actualMap, actualIsMap := testMap(actual)
+	if expectedIsMap && actualIsMap {
+		keys := make([]string, 0, len(expectedMap)+len(actualMap))
+		for key := range expectedMap {
+			keys = append(keys, key)
+		}
+		for key := range actualMap {
+			if _, ok := expectedMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := testEntryPath(path, key)
+			expectedEntry, inExpected := expectedMap[key]
+			actualEntry, inActual := actualMap[key]
+			switch {
+			case !inActual:
+				diffs = append(diffs, map[string]interface{}{"path": keyPath, "expected": expectedEntry})
+			case !inExpected:
+				diffs = append(diffs, map[string]interface{}{"path": keyPath, "actual": actualEntry})
+			default:
+				diffs = diffTestValues(keyPath, expectedEntry, actualEntry, diffs)
+			}
+		}
+		return diffs
+	}
+
+	expectedList, expectedIsList := expected.([]interface{})
+	actualList, actualIsList := actual.([]interface{})
+	if expectedIsList && actualIsList {
+		for i := 0; i < len(expectedList) || i < len(actualList); i++ {
+			indexPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(actualList):
+				diffs = append(diffs, map[string]interface{}{"path": indexPath, "expected": expectedList[i]})
+			case i >= len(expectedList):
+				diffs = append(diffs, map[string]interface{}{"path": indexPath, "actual": actualList[i]})
+			default:
+				diffs = diffTestValues(indexPath, expectedList[i], actualList[i], diffs)
+			}
+		}
+		return diffs
+	}
+
+	return append(diffs, map[string]interface{}{
+		"path":     path,
+		"expected": expected,
+		"actual":   actual,
+	})
+}
+
+// sameTestValue reports whether an expected and an actual value are equal as JSON values
+// Numbers are compared by value, including tagged doubles, and integers beyond 2^53 by
+// their digits
+func sameTestValue(expected, actual interface{}) bool {
+	if expectedDigits, ok := testDigits(expected); ok {
+		if actualDigits, ok := testDigits(actual); ok {
+			return expectedDigits == actualDigits
+		}
+	}
+	expectedNumber, expectedIsNumber := testNumber(expected)
+	actualNumber, actualIsNumber := testNumber(actual)
+	if expectedIsNumber || actualIsNumber {
+		return expectedIsNumber && actualIsNumber &&
+			(expectedNumber == actualNumber || math.IsNaN(expectedNumber) && math.IsNaN(actualNumber))
+	}
+	if _, ok := testMap(expected); ok {
+		return false
+	}
+	if _, ok := expected.([]interface{}); ok {
+		return false
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// testNumber returns the value of a number, or of a tagged double
+func testNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case map[string]interface{}:
+		return decodeTaggedDouble(v)
+	}
+	return 0, false
+}
+
+// testDigits returns the digits of an integer beyond 2^53, which results encode as strings
+// of digits or json.Numbers, and expectations as strings of digits
+func testDigits(value interface{}) (string, bool) {
+	var digits string
+	switch v := value.(type) {
+	case string:
+		digits = v
+	case json.Number:
+		digits = v.String()
+	default:
+		return "", false
+	}
+	if _, err := strconv.ParseInt(digits, 10, 64); err != nil {
+		if _, err := strconv.ParseUint(digits, 10, 64); err != nil {
+			return "", false
+		}
+	}
+	return digits, true
+}
+
+// testMap returns the entries of a map value, other than a tagged double
+func testMap(value interface{}) (map[string]interface{}, bool) {
+	entries, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if _, tagged := decodeTaggedDouble(entries); tagged {
+		return nil, false
+	}
+	return entries, true
+}
+
+// testEntryPath returns the path of a map entry within a result, selecting keys that are
+// identifiers as fields
+func testEntryPath(path string, key string) string {
+	switch {
+	case !objectFieldName.MatchString(key):
+		return fmt.Sprintf("%s[%q]", path, key)
+	case path == "":
+		return key
+	}
+	return path + "." + key
+}
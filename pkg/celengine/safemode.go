@@ -0,0 +1,29 @@
+package celengine
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/invakid404/wasm-cel/internal/options"
+)
+
+func init() {
+	options.SetJSOverloadFunc(isJSOverload)
+}
+
+// isJSOverload reports whether an overload of an environment is implemented by one of
+// its JavaScript functions, including global and library functions
+func isJSOverload(env *cel.Env, overloadID string) bool {
+	for _, envState := range envs.all() {
+		if envState.env != env {
+			continue
+		}
+		for implID := range envState.purity {
+			if strings.HasSuffix(overloadID, "_"+implID) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package celengine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+)
+
+// sampleDepth is how deeply samples nest lists, maps and objects, so recursive message
+// types end
+const sampleDepth = 4
+
+// sampleWords are the strings samples are made of
+var sampleWords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel"}
+
+// sampleEpoch is the start of the year timestamps are sampled from
+var sampleEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SampleOptions configures GenerateSampleVars
+type SampleOptions struct {
+	// Seed of the generated values, so the same seed generates the same values for the
+	// same declarations. Defaults to 0
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// GenerateSampleVars generates plausible values of the variables an environment declares,
+// matching their types, so playgrounds can prefill inputs and rules can be fuzzed with
+// inputs of the right shape
+// Values are in the JSON form evaluations and defaults take: timestamps are RFC 3339
+// strings within 2024, durations strings such as "1h30m0s" and bytes base64. Lists and
+// maps have up to three elements, and objects and messages every field. Dynamic values
+// are booleans, numbers or strings. Constants are left out
+func GenerateSampleVars(envID string, options SampleOptions) map[string]interface{} {
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	s := &sampler{
+		rand:     rand.New(rand.NewSource(options.Seed)),
+		provider: envState.env.CELTypeProvider(),
+	}
+	declared := envState.env.Variables()
+	sort.Slice(declared, func(i, j int) bool { return declared[i].Name() < declared[j].Name() })
+	vars := make(map[string]interface{}, len(declared))
+	for _, variable := range declared {
+		// The standard library declares type names such as int as variables of type type
+		if variable.Type().Kind() == types.TypeKind || variable.Value() != nil {
+			continue
+		}
+		vars[variable.Name()] = s.sample(variable.Type(), 0)
+	}
+
+	return map[string]interface{}{
+		"vars":  vars,
+		"error": nil,
+	}
+}
+
+// sampler generates the values of types
+type sampler struct {
+	rand     *rand.Rand
+	provider types.Provider // Provides the fields of objects and messages
+}
+
+// sample generates a value of a type, nested depth lists, maps and objects deep
+func (s *sampler) sample(t *types.Type, depth int) interface{} {
+	switch t.Kind() {
+	case types.BoolKind:
+		return s.rand.Intn(2) == 1
+	case types.IntKind:
+		return int64(s.rand.Intn(100))
+	case types.UintKind:
+		return uint64(s.rand.Intn(100))
+	case types.DoubleKind:
+		return math.Round(s.rand.Float64()*10000) / 100
+	case types.StringKind:
+		return s.word()
+	case types.BytesKind:
+		return base64.StdEncoding.EncodeToString([]byte(s.word()))
+	case types.TimestampKind:
+		offset := time.Duration(s.rand.Int63n(int64(365 * 24 * time.Hour / time.Second)))
+		return sampleEpoch.Add(offset * time.Second).Format(time.RFC3339)
+	case types.DurationKind:
+		return (time.Duration(1+s.rand.Intn(24*60)) * time.Minute).String()
+	case types.ListKind:
+		items := make([]interface{}, 0)
+		if depth < sampleDepth {
+			for i := s.size(); i > 0; i-- {
+				items = append(items, s.sample(t.Parameters()[0], depth+1))
+			}
+		}
+		return items
+	case types.MapKind:
+		entries := make(map[string]interface{})
+		if depth < sampleDepth {
+			for i := s.size(); i > 0; i-- {
+				entries[s.key(t.Parameters()[0])] = s.sample(t.Parameters()[1], depth+1)
+			}
+		}
+		return entries
+	case types.StructKind:
+		fields := make(map[string]interface{})
+		names, ok := s.provider.FindStructFieldNames(t.TypeName())
+		if !ok || depth >= sampleDepth {
+			return fields
+		}
+		for _, name := range names {
+			if field, ok := s.provider.FindStructFieldType(t.TypeName(), name); ok {
+				fields[name] = s.sample(field.Type, depth+1)
+			}
+		}
+		return fields
+	case types.DynKind, types.AnyKind:
+		switch s.rand.Intn(3) {
+		case 0:
+			return s.sample(types.BoolType, depth)
+		case 1:
+			return s.sample(types.IntType, depth)
+		}
+		return s.sample(types.StringType, depth)
+	}
+	return nil
+}
+
+// size returns the number of elements of a list or map
+func (s *sampler) size() int {
+	return 1 + s.rand.Intn(3)
+}
+
+// word returns a string sample
+func (s *sampler) word() string {
+	return sampleWords[s.rand.Intn(len(sampleWords))]
+}
+
+// key returns a map key of a type, as the string JSON objects hold it as
+func (s *sampler) key(t *types.Type) string {
+	switch t.Kind() {
+	case types.IntKind, types.UintKind:
+		return strconv.Itoa(s.rand.Intn(100))
+	case types.BoolKind:
+		return strconv.FormatBool(s.rand.Intn(2) == 1)
+	}
+	return s.word()
+}
@@ -0,0 +1,90 @@
+package celengine
+
+import "sort"
+
+// SessionState groups environments so they can be torn down together
+// Programs and function implementations belong to the environment that created them,
+// so tracking environments is enough to reach everything created within a session
+type SessionState struct {
+	envIDs []string
+}
+
+var (
+	sessions         = make(map[string]*SessionState)
+	sessionIDCounter int64
+)
+
+// CreateSession creates a new session
+// Returns a session ID that environments can be created in
+func CreateSession() map[string]interface{} {
+	sessionIDCounter++
+	sessionID := newID("session", sessionIDCounter)
+	sessions[sessionID] = &SessionState{}
+
+	return map[string]interface{}{
+		"sessionID": sessionID,
+		"error":     nil,
+	}
+}
+
+// leaveSession removes an environment from its session, for environments destroyed
+// before whatever they were created for succeeded, so the session doesn't list them
+func leaveSession(sessionID string, envID string) {
+	session, ok := sessions[sessionID]
+	if !ok {
+		return
+	}
+	for i, id := range session.envIDs {
+		if id == envID {
+			session.envIDs = append(session.envIDs[:i], session.envIDs[i+1:]...)
+			return
+		}
+	}
+}
+
+// DestroySession destroys every program and environment created within a session
+// and unregisters the function implementations they used
+func DestroySession(sessionID string) map[string]interface{} {
+	session, ok := sessions[sessionID]
+	if !ok {
+		return idNotFound("session", "session", sessionID)
+	}
+	delete(sessions, sessionID)
+
+	inSession := make(map[string]bool, len(session.envIDs))
+	for _, envID := range session.envIDs {
+		inSession[envID] = true
+	}
+
+	// Destroy programs first so function reference counts drop to zero
+	var programIDs []string
+	for programID, program := range programs.all() {
+		if inSession[program.envID] {
+			programIDs = append(programIDs, programID)
+		}
+	}
+	sort.Strings(programIDs)
+	for _, programID := range programIDs {
+		DestroyProgram(programID)
+	}
+
+	// Environments destroyed individually before are already gone or pending cleanup
+	destroyedEnvs := 0
+	for _, envID := range session.envIDs {
+		envState, ok := envs.get(envID)
+		if !ok {
+			continue
+		}
+		if !envState.destroyed {
+			destroyedEnvs++
+		}
+		DestroyEnv(envID)
+	}
+
+	return map[string]interface{}{
+		"success":           true,
+		"destroyedEnvs":     destroyedEnvs,
+		"destroyedPrograms": len(programIDs),
+		"error":             nil,
+	}
+}
@@ -0,0 +1,369 @@
+package celengine
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+)
+
+// sourceSpan is a range of a source in code points, end excluded
+type sourceSpan struct {
+	start, end int32
+}
+
+// parsedSource is a source and its parsed expression, for locating the span of each
+// subexpression
+type parsedSource struct {
+	runes []rune
+	units []int32 // UTF-16 offset of each code point, and of the end of the source
+	info  *ast.SourceInfo
+	expr  ast.Expr
+	exprs map[int64]ast.Expr    // Subexpressions by ID, for resolving macro call arguments
+	types map[int64]*types.Type // Types of the subexpressions, when the expression is checked
+	spans map[int64]sourceSpan  // Spans of the subexpressions located so far
+}
+
+// parseSource parses a source in an environment without checking it
+func parseSource(env *cel.Env, source string) (*parsedSource, bool) {
+	parsed, issues := env.Parse(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, false
+	}
+	return newParsedSource(source, parsed), true
+}
+
+// newParsedSource locates the subexpressions of an expression parsed or checked from a
+// source
+func newParsedSource(source string, parsed *cel.Ast) *parsedSource {
+	runes := []rune(source)
+	units := make([]int32, len(runes)+1)
+	for i, r := range runes {
+		units[i+1] = units[i] + int32(utf16.RuneLen(r))
+	}
+
+	expr := parsed.NativeRep().Expr()
+	exprs := make(map[int64]ast.Expr)
+	ast.PostOrderVisit(expr, ast.NewExprVisitor(func(e ast.Expr) {
+		exprs[e.ID()] = e
+	}))
+	return &parsedSource{
+		runes: runes,
+		units: units,
+		info:  parsed.NativeRep().SourceInfo(),
+		expr:  expr,
+		exprs: exprs,
+		types: parsed.NativeRep().TypeMap(),
+		spans: make(map[int64]sourceSpan),
+	}
+}
+
+// sourceMap maps the ranges of source generated from an expression, such as its optimized
+// or canonical source, back to the ranges of the original source they came from
+// Both sources are parsed and their expressions walked together. Where they differ, such
+// as where constants were folded, the generated subexpression maps to the whole original
+// subexpression it replaced. Macros are walked as they were written rather than as they
+// expand. Offsets are in UTF-16 code units, as JavaScript indexes strings
+func sourceMap(env *cel.Env, original string, generated string) ([]interface{}, bool) {
+	from, ok := parseSource(env, original)
+	if !ok {
+		return nil, false
+	}
+	to, ok := parseSource(env, generated)
+	if !ok {
+		return nil, false
+	}
+
+	mappings := make([]interface{}, 0)
+	seen := make(map[[2]sourceSpan]bool)
+	var walk func(out, in ast.Expr)
+	walk = func(out, in ast.Expr) {
+		outSpan, outOK := to.span(out)
+		inSpan, inOK := from.span(in)
+		if outOK && inOK && outSpan.start < outSpan.end && !seen[[2]sourceSpan{outSpan, inSpan}] {
+			seen[[2]sourceSpan{outSpan, inSpan}] = true
+			mappings = append(mappings, map[string]interface{}{
+				"generated": to.rangeOf(outSpan),
+				"original":  from.rangeOf(inSpan),
+			})
+		}
+
+		outNode, inNode := to.written(out), from.written(in)
+		outChildren, inChildren := to.subexpressions(out), from.subexpressions(in)
+		if !sameShape(outNode, inNode) || len(outChildren) != len(inChildren) {
+			return
+		}
+		for i := range outChildren {
+			walk(outChildren[i], inChildren[i])
+		}
+	}
+	walk(to.expr, from.expr)
+
+	return mappings, true
+}
+
+// rangeOf converts a span to UTF-16 offsets
+func (s *parsedSource) rangeOf(span sourceSpan) map[string]interface{} {
+	return map[string]interface{}{
+		"start": s.units[span.start],
+		"end":   s.units[span.end],
+	}
+}
+
+// text returns the source of a span
+func (s *parsedSource) text(span sourceSpan) string {
+	return string(s.runes[span.start:span.end])
+}
+
+// written returns a node as it was written: the call of a macro, or the node itself
+func (s *parsedSource) written(e ast.Expr) ast.Expr {
+	if call, ok := s.info.GetMacroCall(e.ID()); ok {
+		return call
+	}
+	return e
+}
+
+// subexpressions returns the direct subexpressions of a node as it was written
+// Macro calls refer to arguments that are macros themselves by ID, so those are resolved
+func (s *parsedSource) subexpressions(e ast.Expr) []ast.Expr {
+	subs := subexpressions(s.written(e))
+	for i, sub := range subs {
+		if resolved, ok := s.exprs[sub.ID()]; ok && sub.Kind() == ast.UnspecifiedExprKind {
+			subs[i] = resolved
+		}
+	}
+	return subs
+}
+
+// span returns the span of a subexpression
+// The parser only records the token each node was created from, such as the operator of
+// a call, so the span covers the tokens of the node and its subexpressions, along with
+// the function name, type name and brackets of nodes that have them, and the parentheses
+// grouping parts of it
+func (s *parsedSource) span(e ast.Expr) (sourceSpan, bool) {
+	if span, ok := s.spans[e.ID()]; ok {
+		return span, span.start >= 0
+	}
+
+	span, _ := s.token(e)
+	for _, sub := range s.subexpressions(e) {
+		subSpan, ok := s.span(sub)
+		if !ok {
+			continue
+		}
+		if span.start < 0 || subSpan.start < span.start {
+			span.start = subSpan.start
+		}
+		if subSpan.end > span.end {
+			span.end = subSpan.end
+		}
+	}
+	if span.start >= 0 {
+		span = s.balance(s.extend(s.written(e), span))
+	}
+
+	s.spans[e.ID()] = span
+	return span, span.start >= 0
+}
+
+// token returns the span of the token a node was created from, such as the operator of a
+// call
+func (s *parsedSource) token(e ast.Expr) (sourceSpan, bool) {
+	r, ok := s.info.GetOffsetRange(e.ID())
+	if !ok || r.Start < 0 || r.Start >= int32(len(s.runes)) {
+		return sourceSpan{start: -1}, false
+	}
+	// The parser ends the range of a token at its length in bytes rather than code points
+	span := sourceSpan{start: r.Start, end: r.Start}
+	for size := r.Stop - r.Start; size > 0 && span.end < int32(len(s.runes)); span.end++ {
+		size -= int32(utf8.RuneLen(s.runes[span.end]))
+	}
+	return span, true
+}
+
+// extend widens the span of a node to its field name, its closing bracket and the name
+// before it
+func (s *parsedSource) extend(e ast.Expr, span sourceSpan) sourceSpan {
+	if e.Kind() == ast.SelectKind {
+		field := []rune(e.AsSelect().FieldName())
+		if i := s.skipSpace(span.end, false); i+int32(len(field)) <= int32(len(s.runes)) && string(s.runes[i:i+int32(len(field))]) == string(field) {
+			span.end = i + int32(len(field))
+		}
+	}
+	if closer := closingBracket(e); closer != 0 {
+		if i := s.skipSpace(span.end, true); i < int32(len(s.runes)) && s.runes[i] == closer {
+			span.end = i + 1
+		}
+	}
+
+	// The checker resolves selections of a qualified name into an identifier located at
+	// the last dot
+	if e.Kind() == ast.IdentKind && strings.Contains(e.AsIdent(), ".") {
+		name := []rune(e.AsIdent())
+		last := int32(len(name)) - 1
+		for name[last] != '.' {
+			last--
+		}
+		if start, end := span.start-last, span.start+int32(len(name))-last; start >= 0 && end <= int32(len(s.runes)) && string(s.runes[start:end]) == string(name) {
+			span = sourceSpan{start: start, end: end}
+		}
+	}
+
+	var name string
+	switch {
+	case e.Kind() == ast.StructKind:
+		name = e.AsStruct().TypeName()
+	case e.Kind() == ast.CallKind && !e.AsCall().IsMemberFunction() && closingBracket(e) == ')':
+		name = e.AsCall().FunctionName()
+	}
+	if name != "" {
+		i := span.start
+		for i > 0 && unicode.IsSpace(s.runes[i-1]) {
+			i--
+		}
+		if start := i - int32(len([]rune(name))); start >= 0 && string(s.runes[start:i]) == name {
+			span.start = start
+		}
+	}
+	return span
+}
+
+// balance widens a span to the parentheses it opens or closes but leaves out, which group
+// subexpressions without being part of the parsed expression
+func (s *parsedSource) balance(span sourceSpan) sourceSpan {
+	open, unopened := 0, 0
+	var quote rune
+	for i := span.start; i < span.end; i++ {
+		switch r := s.runes[i]; {
+		case quote != 0:
+			if r == '\\' {
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			open++
+		case r == ')' && open > 0:
+			open--
+		case r == ')':
+			unopened++
+		}
+	}
+
+	for ; open > 0; open-- {
+		i := s.skipSpace(span.end, false)
+		if i >= int32(len(s.runes)) || s.runes[i] != ')' {
+			break
+		}
+		span.end = i + 1
+	}
+	for ; unopened > 0; unopened-- {
+		i := span.start
+		for i > 0 && unicode.IsSpace(s.runes[i-1]) {
+			i--
+		}
+		if i == 0 || s.runes[i-1] != '(' {
+			break
+		}
+		span.start = i - 1
+	}
+	return span
+}
+
+// skipSpace returns the offset of the first code point from offset i that isn't
+// whitespace or part of a comment, or a trailing comma if commas are skipped
+func (s *parsedSource) skipSpace(i int32, commas bool) int32 {
+	for i < int32(len(s.runes)) {
+		switch {
+		case unicode.IsSpace(s.runes[i]) || commas && s.runes[i] == ',':
+			i++
+		case strings.HasPrefix(string(s.runes[i:min(i+2, int32(len(s.runes)))]), "//"):
+			for i < int32(len(s.runes)) && s.runes[i] != '\n' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// sameShape reports whether two nodes are the same kind of node, so their subexpressions
+// correspond
+func sameShape(a, b ast.Expr) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case ast.CallKind:
+		return a.AsCall().FunctionName() == b.AsCall().FunctionName() &&
+			a.AsCall().IsMemberFunction() == b.AsCall().IsMemberFunction()
+	case ast.SelectKind:
+		return a.AsSelect().FieldName() == b.AsSelect().FieldName()
+	}
+	return true
+}
+
+// closingBracket returns the bracket that closes a node, or 0 for nodes without one,
+// such as operators
+func closingBracket(e ast.Expr) rune {
+	switch e.Kind() {
+	case ast.CallKind:
+		switch name := e.AsCall().FunctionName(); {
+		case name == "_[_]" || name == "_[?_]":
+			return ']'
+		case strings.HasPrefix(name, "_") || strings.HasPrefix(name, "@") || name == "!_" || name == "-_":
+			return 0
+		}
+		return ')'
+	case ast.ComprehensionKind:
+		return ')'
+	case ast.SelectKind:
+		if e.AsSelect().IsTestOnly() {
+			return ')'
+		}
+	case ast.ListKind:
+		return ']'
+	case ast.MapKind, ast.StructKind:
+		return '}'
+	}
+	return 0
+}
+
+// subexpressions returns the direct subexpressions of a node in a fixed order
+func subexpressions(e ast.Expr) []ast.Expr {
+	switch e.Kind() {
+	case ast.CallKind:
+		call := e.AsCall()
+		if call.IsMemberFunction() {
+			return append([]ast.Expr{call.Target()}, call.Args()...)
+		}
+		return call.Args()
+	case ast.ComprehensionKind:
+		comp := e.AsComprehension()
+		return []ast.Expr{comp.IterRange(), comp.AccuInit(), comp.LoopCondition(), comp.LoopStep(), comp.Result()}
+	case ast.SelectKind:
+		return []ast.Expr{e.AsSelect().Operand()}
+	case ast.ListKind:
+		return e.AsList().Elements()
+	case ast.MapKind:
+		var subs []ast.Expr
+		for _, entry := range e.AsMap().Entries() {
+			subs = append(subs, entry.AsMapEntry().Key(), entry.AsMapEntry().Value())
+		}
+		return subs
+	case ast.StructKind:
+		var subs []ast.Expr
+		for _, field := range e.AsStruct().Fields() {
+			subs = append(subs, field.AsStructField().Value())
+		}
+		return subs
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// variableError is a variable that doesn't match its declaration
+type variableError struct {
+	Path    string // Variable name followed by the index or key of the mismatching value
+	Message string
+}
+
+// validateVariables checks the variables read by a program against their declared types
+// Variables covered by an unknown pattern, or given a value by the environment, may be absent
+func validateVariables(programState *ProgramState, vars map[string]interface{}, unknowns []string) []variableError {
+	variables := programState.readVariables()
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []variableError
+	for _, name := range names {
+		value, ok := vars[name]
+		if !ok {
+			if !programState.inputs.mayOmit(name) && !coveredByUnknowns(name, unknowns) {
+				errs = append(errs, variableError{Path: name, Message: "missing variable"})
+			}
+			continue
+		}
+		errs = validateValue(value, variables[name], name, errs)
+	}
+	return errs
+}
+
+// readVariables returns the declared types of the variables the program reads, computed
+// on first use
+func (programState *ProgramState) readVariables() map[string]*types.Type {
+	if programState.variables == nil {
+		programState.variables = referencedVariables(programState.ast)
+	}
+	return programState.variables
+}
+
+// referencedVariables returns the declared types of the variables a checked program reads
+func referencedVariables(checked *cel.Ast) map[string]*types.Type {
+	native := checked.NativeRep()
+	collector := &fieldMaskCollector{
+		native:     native,
+		references: native.ReferenceMap(),
+		paths:      make(map[string][]string),
+		variables:  make(map[string]*types.Type),
+	}
+	collector.visit(native.Expr(), nil)
+	return collector.variables
+}
+
+// coveredByUnknowns reports whether an unknown pattern makes a whole variable unknown
+func coveredByUnknowns(name string, unknowns []string) bool {
+	for _, pattern := range unknowns {
+		if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateValue appends an error for each part of a decoded JSON value that doesn't
+// match the expected type. Dynamic and message types accept any value
+func validateValue(value interface{}, expected *types.Type, path string, errs []variableError) []variableError {
+	if value == nil {
+		if expected.Kind() == types.DynKind || expected.Kind() == types.AnyKind || expected.IsAssignableType(types.NullType) {
+			return errs
+		}
+		return append(errs, mismatch(path, expected, value))
+	}
+
+	switch expected.Kind() {
+	case types.BoolKind:
+		if _, ok := value.(bool); !ok {
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.IntKind:
+		switch value.(type) {
+		case int, int8, int16, int32, int64:
+		default:
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.UintKind:
+		switch value.(type) {
+		case uint, uint8, uint16, uint32, uint64:
+		default:
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.DoubleKind:
+		switch value.(type) {
+		case float32, float64:
+		default:
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.StringKind:
+		if _, ok := value.(string); !ok {
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.BytesKind:
+		if _, ok := value.([]byte); !ok {
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.TimestampKind:
+		if _, ok := value.(time.Time); !ok {
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.DurationKind:
+		if _, ok := value.(time.Duration); !ok {
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.NullTypeKind:
+		return append(errs, mismatch(path, expected, value))
+	case types.OpaqueKind:
+		// Tagged objects of value codecs are decoded before validation
+		switch v := value.(type) {
+		case *types.Err:
+			return append(errs, variableError{Path: path, Message: v.Error()})
+		case ref.Val:
+			if v.Type().TypeName() != expected.TypeName() {
+				return append(errs, mismatch(path, expected, value))
+			}
+		default:
+			return append(errs, mismatch(path, expected, value))
+		}
+	case types.ListKind:
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(errs, mismatch(path, expected, value))
+		}
+		for i, item := range items {
+			errs = validateValue(item, expected.Parameters()[0], fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case types.MapKind:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return append(errs, mismatch(path, expected, value))
+		}
+		keyType := expected.Parameters()[0]
+		if keyType.Kind() != types.StringKind && keyType.Kind() != types.DynKind {
+			return append(errs, variableError{
+				Path:    path,
+				Message: fmt.Sprintf("expected %s, got map with string keys", expected),
+			})
+		}
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			errs = validateValue(entries[key], expected.Parameters()[1], fmt.Sprintf("%s[%q]", path, key), errs)
+		}
+	}
+	return errs
+}
+
+// mismatch describes a value of the wrong kind
+func mismatch(path string, expected *types.Type, value interface{}) variableError {
+	return variableError{
+		Path:    path,
+		Message: fmt.Sprintf("expected %s, got %s", expected, valueKind(value)),
+	}
+}
+
+// valueKind names the CEL type a decoded JSON value would have
+// JavaScript numbers are always doubles, which is the usual cause of a mismatch with an
+// int variable
+func valueKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int, int8, int16, int32, int64:
+		return "int"
+	case uint, uint8, uint16, uint32, uint64:
+		return "uint"
+	case float32, float64:
+		return "double"
+	case string:
+		return "string"
+	case []byte:
+		return "bytes"
+	case time.Time:
+		return "timestamp"
+	case time.Duration:
+		return "duration"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	case ref.Val:
+		return value.(ref.Val).Type().TypeName()
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// invalidVariablesResponse reports variables that don't match their declarations
+func invalidVariablesResponse(errs []variableError) map[string]interface{} {
+	messages := make([]string, len(errs))
+	details := make([]interface{}, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Path + ": " + err.Message
+		details[i] = map[string]interface{}{
+			"path":    err.Path,
+			"message": err.Message,
+		}
+	}
+	return map[string]interface{}{
+		"error":          "invalid variables: " + strings.Join(messages, "; "),
+		"variableErrors": details,
+	}
+}
@@ -0,0 +1,121 @@
+package celengine
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+)
+
+// maxSuggestions is the most names suggested for an undeclared reference
+const maxSuggestions = 3
+
+// undeclaredReference matches the message of a check error for a name that isn't declared
+var undeclaredReference = regexp.MustCompile(`^undeclared reference to '([^']+)'`)
+
+// didYouMean returns the declared names closest to the name a check error reports as
+// undeclared, nearest first, or nil for other errors
+// Calls are compared with the functions and macros of the environment, and other
+// references with its variables and constants
+func didYouMean(env *cel.Env, parsed *cel.Ast, err *common.Error) []interface{} {
+	match := undeclaredReference.FindStringSubmatch(err.Message)
+	if match == nil {
+		return nil
+	}
+	name := match[1]
+
+	isCall := false
+	if parsed != nil {
+		ast.PostOrderVisit(parsed.NativeRep().Expr(), ast.NewExprVisitor(func(e ast.Expr) {
+			if e.ID() == err.ExprID && e.Kind() == ast.CallKind {
+				isCall = true
+			}
+		}))
+	}
+
+	var candidates []string
+	if isCall {
+		for function := range env.Functions() {
+			if !isBatchFunction(function) {
+				candidates = append(candidates, function)
+			}
+		}
+		for _, macro := range env.Macros() {
+			candidates = append(candidates, macro.Function())
+		}
+	} else {
+		for _, variable := range env.Variables() {
+			candidates = append(candidates, variable.Name())
+		}
+	}
+	var suggestions []interface{}
+	for _, suggestion := range nearestNames(name, candidates) {
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// nearestNames returns the candidates within a small edit distance of a name, nearest
+// first and then in alphabetical order
+// The allowed distance grows with the length of the name, and never reaches it, so
+// one-letter names aren't suggested for each other
+func nearestNames(name string, candidates []string) []string {
+	maxDistance := max(1, len([]rune(name))/3)
+
+	distances := make(map[string]int)
+	for _, candidate := range candidates {
+		// Operators and internal functions can't be written as names
+		if candidate == name || strings.ContainsAny(candidate[:1], "_@!-") {
+			continue
+		}
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance <= maxDistance && distance < len([]rune(name)) {
+			distances[candidate] = distance
+		}
+	}
+
+	names := make([]string, 0, len(distances))
+	for candidate := range distances {
+		names = append(names, candidate)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if distances[names[i]] != distances[names[j]] {
+			return distances[names[i]] < distances[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxSuggestions {
+		names = names[:maxSuggestions]
+	}
+	return names
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and swaps of
+// adjacent letters that turn a into b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
@@ -0,0 +1,41 @@
+package celengine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/invakid404/wasm-cel/internal/options"
+)
+
+// SuspendEvaluation detaches the state of the running evaluation, such as its metrics,
+// function trace, cached calls and memory budget, so other evaluations can run while a
+// custom function waits for its result
+// Call the returned function to reattach it before the evaluation continues
+func SuspendEvaluation() (resume func()) {
+	metrics, trace, calls, budget := evalMetrics, evalTrace, evalCalls, evalBudget
+	evalMetrics, evalTrace, evalCalls, evalBudget = nil, nil, nil, nil
+	resumeSystem := options.SuspendSystemEvaluation()
+	return func() {
+		evalMetrics, evalTrace, evalCalls, evalBudget = metrics, trace, calls, budget
+		resumeSystem()
+	}
+}
+
+// startSystemEvaluation gives the system functions of the evaluation that's starting their
+// own clock and random source, frozen at now and seeded with seed unless they're nil
+// Call the returned function once the evaluation ends
+func startSystemEvaluation(now *time.Time, seed *int64) (stop func()) {
+	return options.StartSystemEvaluation(now, seed)
+}
+
+// clock returns the time an evaluation freezes the clock of now() at, or nil if it doesn't
+func (options EvalOptions) clock() (*time.Time, error) {
+	if options.Now == "" {
+		return nil, nil
+	}
+	now, err := time.Parse(time.RFC3339Nano, options.Now)
+	if err != nil {
+		return nil, fmt.Errorf("now must be an RFC 3339 timestamp: %v", err)
+	}
+	return &now, nil
+}
@@ -0,0 +1,118 @@
+package celengine
+
+import (
+	"strings"
+	"testing"
+)
+
+// interleavingCaller implements step(name) by suspending the evaluation on its first
+// call until the test wakes it, the way the worker pool suspends evaluations while
+// custom functions wait for their Promises. Later calls return right away
+type interleavingCaller struct {
+	suspended chan string
+	wake      map[string]chan struct{}
+	called    map[string]bool
+}
+
+func (c interleavingCaller) CallJSFunction(implID string, args []interface{}) (interface{}, error) {
+	name := args[0].(string)
+	if c.called[name] {
+		return name, nil
+	}
+	c.called[name] = true
+	resume := SuspendEvaluation()
+	c.suspended <- name
+	<-c.wake[name]
+	resume()
+	return name, nil
+}
+
+func (interleavingCaller) UnregisterFunction(implID string) {}
+
+// TestSuspendEvaluationInterleaved runs two evaluations that suspend in turn and resume
+// in the order they suspended, checking that each keeps its own trace, cached calls,
+// metrics and memory budget
+func TestSuspendEvaluationInterleaved(t *testing.T) {
+	caller := interleavingCaller{
+		suspended: make(chan string),
+		wake:      map[string]chan struct{}{"a": make(chan struct{}), "bbbb": make(chan struct{})},
+		called:    make(map[string]bool),
+	}
+	SetJSFunctionCaller(caller)
+	defer SetJSFunctionCaller(nil)
+
+	created := CreateEnv([]VarDecl{{Name: "name", Type: "string"}}, []FunctionDef{{
+		Name:       "step",
+		Params:     []ParamDef{{Name: "name", Type: "string"}},
+		ReturnType: "string",
+		ImplID:     "interleave_step",
+		Cacheable:  true,
+	}})
+	envID, _ := created["envID"].(string)
+	if envID == "" {
+		t.Fatalf("failed to create the environment: %v", created["error"])
+	}
+	defer DestroyEnv(envID)
+	compiled := Compile(envID, "step(name) + step(name)")
+	programID, _ := compiled["programID"].(string)
+	if programID == "" {
+		t.Fatalf("failed to compile: %v", compiled["error"])
+	}
+
+	evaluate := func(name string, options EvalOptions) chan map[string]interface{} {
+		done := make(chan map[string]interface{})
+		go func() {
+			done <- EvalWithOptions(programID, map[string]interface{}{"name": name}, options)
+		}()
+		if suspended := <-caller.suspended; suspended != name {
+			t.Fatalf("expected the evaluation of %s to suspend, got %s", name, suspended)
+		}
+		if evalTrace != nil || evalCalls != nil || evalMetrics != nil || evalBudget != nil {
+			t.Fatalf("expected the state of the evaluation of %s to be detached", name)
+		}
+		return done
+	}
+
+	// The budget of a is exceeded by its own values only: "a" twice and "aa"
+	a := evaluate("a", EvalOptions{TraceFunctions: true, MemoryLimitBytes: 40})
+	b := evaluate("bbbb", EvalOptions{TraceFunctions: true, Metrics: true, MemoryLimitBytes: 1000})
+
+	close(caller.wake["a"])
+	resultA := <-a
+	close(caller.wake["bbbb"])
+	resultB := <-b
+
+	if budget, ok := resultA["memoryBudget"].(map[string]interface{}); !ok || budget["allocatedBytes"] != uint64(52) {
+		t.Errorf("expected a to exceed its budget with 52 bytes allocated, got %v", resultA)
+	}
+	if message, _ := resultA["error"].(string); !strings.Contains(message, "memory budget") {
+		t.Errorf("expected a memory budget error for a, got %v", resultA["error"])
+	}
+	if resultB["result"] != "bbbbbbbb" {
+		t.Fatalf("expected bbbbbbbb, got %v", resultB)
+	}
+	if metrics, _ := resultB["metrics"].(map[string]interface{}); metrics["callbackCount"] != 1 {
+		t.Errorf("expected b to call its implementation once, got metrics %v", resultB["metrics"])
+	}
+
+	// Each trace holds its own two calls, the second answered from its own cache
+	for name, result := range map[string]map[string]interface{}{"a": resultA, "bbbb": resultB} {
+		calls, _ := result["functionTrace"].([]interface{})
+		if len(calls) != 2 {
+			t.Fatalf("expected 2 traced calls for %s, got %v", name, result["functionTrace"])
+		}
+		for i, call := range calls {
+			call := call.(map[string]interface{})
+			if args := call["args"].([]interface{}); len(args) != 1 || args[0] != name {
+				t.Errorf("expected the calls of %s, got %v", name, call)
+			}
+			if cached := call["cached"] == true; cached != (i == 1) {
+				t.Errorf("expected only the second call of %s to be cached, got %v", name, calls)
+			}
+		}
+	}
+
+	if evalTrace != nil || evalCalls != nil || evalMetrics != nil || evalBudget != nil {
+		t.Error("expected no evaluation state once both evaluations ended")
+	}
+}
@@ -0,0 +1,213 @@
+package celengine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InstantiateTemplate compiles an expression template, replacing each {{name}} placeholder
+// with the binding of that name rendered as a CEL literal
+func InstantiateTemplate(envID string, templateSource string, bindings map[string]interface{}) map[string]interface{} {
+	return InstantiateTemplateWithFlags(envID, templateSource, bindings, nil, CompileFlags{})
+}
+
+// InstantiateTemplateWithFlags instantiates a template like InstantiateTemplate and compiles
+// the result like CompileWithFlags
+// Bindings are values rather than source, so a string binding can't change the structure
+// of the expression the way concatenating it into the source could
+func InstantiateTemplateWithFlags(envID string, templateSource string, bindings map[string]interface{}, programOptionsJSON *string, flags CompileFlags) map[string]interface{} {
+	exprStr, err := renderTemplate(templateSource, bindings)
+	if err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("template error: %v", err),
+		}
+	}
+	return CompileWithFlags(envID, exprStr, programOptionsJSON, flags)
+}
+
+// renderTemplate replaces the placeholders of a template with CEL literals
+// Placeholders inside string literals and comments are left as they are, since they
+// can't stand for a value there
+func renderTemplate(source string, bindings map[string]interface{}) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(source); {
+		switch {
+		case source[i] == '"' || source[i] == '\'':
+			end := stringLiteralEnd(source, i)
+			out.WriteString(source[i:end])
+			i = end
+		case strings.HasPrefix(source[i:], "//"):
+			end := strings.IndexByte(source[i:], '\n')
+			if end < 0 {
+				end = len(source) - i
+			}
+			out.WriteString(source[i : i+end])
+			i += end
+		case strings.HasPrefix(source[i:], "{{"):
+			name, end, ok := placeholderAt(source, i)
+			if !ok {
+				return "", fmt.Errorf("malformed placeholder at offset %d: expected {{name}}", i)
+			}
+			binding, bound := bindings[name]
+			if !bound {
+				return "", fmt.Errorf("no binding for placeholder {{%s}}", name)
+			}
+			binding, _ = decodeTaggedValues(binding)
+			literal, err := celLiteral(binding)
+			if err != nil {
+				return "", fmt.Errorf("binding %s: %v", name, err)
+			}
+			out.WriteString(literal)
+			i = end
+		default:
+			out.WriteByte(source[i])
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// placeholderAt parses the {{name}} placeholder starting at offset start, returning the
+// name and the offset just past it
+func placeholderAt(source string, start int) (string, int, bool) {
+	i := start + len("{{")
+	for i < len(source) && (source[i] == ' ' || source[i] == '\t') {
+		i++
+	}
+	nameStart := i
+	for i < len(source) && isIdentifierByte(source[i], i > nameStart) {
+		i++
+	}
+	name := source[nameStart:i]
+	for i < len(source) && (source[i] == ' ' || source[i] == '\t') {
+		i++
+	}
+	if name == "" || !strings.HasPrefix(source[i:], "}}") {
+		return "", 0, false
+	}
+	return name, i + len("}}"), true
+}
+
+// isIdentifierByte reports whether c may appear in an identifier, digits only after the
+// first character
+func isIdentifierByte(c byte, inner bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (inner && c >= '0' && c <= '9')
+}
+
+// stringLiteralEnd returns the offset just past the string literal whose opening quote is at
+// offset start, or the end of the source if the literal isn't closed
+// Raw strings, prefixed with r or R, don't treat backslashes as escapes
+func stringLiteralEnd(source string, start int) int {
+	raw := false
+	for j := start - 1; j >= 0 && j >= start-2; j-- {
+		c := source[j]
+		if c == 'r' || c == 'R' {
+			raw = true
+		} else if c != 'b' && c != 'B' {
+			break
+		}
+	}
+
+	quote := source[start : start+1]
+	if strings.HasPrefix(source[start:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	for i := start + len(quote); i < len(source); i++ {
+		if source[i] == '\\' && !raw {
+			i++
+			continue
+		}
+		if strings.HasPrefix(source[i:], quote) {
+			return i + len(quote)
+		}
+		if len(quote) == 1 && source[i] == '\n' {
+			break
+		}
+	}
+	return len(source)
+}
+
+// celLiteral renders a decoded JSON value as CEL source
+// Integral numbers are ints, so they compare to ints without a conversion, other numbers
+// are doubles, and map keys are sorted so the same bindings always render the same
+// expression
+func celLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return intLiteral(int64(v)), nil
+		}
+		return doubleLiteral(v), nil
+	case string:
+		// Go's escapes are a subset of CEL's
+		return strconv.Quote(v), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			literal, err := celLiteral(item)
+			if err != nil {
+				return "", fmt.Errorf("[%d]: %v", i, err)
+			}
+			items[i] = literal
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		entries := make([]string, len(keys))
+		for i, key := range keys {
+			literal, err := celLiteral(v[key])
+			if err != nil {
+				return "", fmt.Errorf("[%q]: %v", key, err)
+			}
+			entries[i] = strconv.Quote(key) + ": " + literal
+		}
+		return "{" + strings.Join(entries, ", ") + "}", nil
+	case Decimal:
+		return "decimal(" + strconv.Quote(v.String()) + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", value)
+	}
+}
+
+// intLiteral renders an int, parenthesizing negative values so a literal always parses
+// as a single operand
+func intLiteral(value int64) string {
+	literal := strconv.FormatInt(value, 10)
+	if value < 0 {
+		return "(" + literal + ")"
+	}
+	return literal
+}
+
+// doubleLiteral renders a double, parenthesizing negative values so a literal always
+// parses as a single operand
+func doubleLiteral(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return `double("NaN")`
+	case math.IsInf(value, 1):
+		return `double("Infinity")`
+	case math.IsInf(value, -1):
+		return `double("-Infinity")`
+	}
+
+	literal := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(literal, ".e") {
+		literal += ".0"
+	}
+	if value < 0 || (value == 0 && math.Signbit(value)) {
+		return "(" + literal + ")"
+	}
+	return literal
+}
@@ -0,0 +1,70 @@
+package celengine
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// functionTrace records the custom function calls of a single evaluation
+// A nil *functionTrace records nothing, so call sites don't need to check whether tracing
+// is enabled
+type functionTrace struct {
+	encoding ValueEncoding
+	calls    []interface{}
+}
+
+// evalTrace records the custom function calls of the running evaluation, if enabled
+var evalTrace *functionTrace
+
+// newFunctionTrace returns a trace converting values with encoding, or nil if tracing is
+// disabled
+func newFunctionTrace(enabled bool, encoding ValueEncoding) *functionTrace {
+	if !enabled {
+		return nil
+	}
+	return &functionTrace{encoding: encoding, calls: make([]interface{}, 0)}
+}
+
+// record adds a call that started at start to the trace, cached if its result came from
+// the cache of a cacheable function rather than from calling it
+func (t *functionTrace) record(name string, args []ref.Val, result ref.Val, start time.Time, cached bool) {
+	if t == nil {
+		return
+	}
+
+	jsonArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		jsonArgs[i] = ValueToJSONWithEncoding(arg, t.encoding)
+	}
+	call := map[string]interface{}{
+		"function":   name,
+		"args":       jsonArgs,
+		"durationMs": milliseconds(time.Since(start)),
+	}
+	if cached {
+		call["cached"] = true
+	}
+
+	if errVal, ok := result.(*types.Err); ok {
+		call["error"] = errVal.Error()
+		var fnErr *FunctionError
+		if errors.As(errVal, &fnErr) {
+			call["code"] = fnErr.Code
+		}
+	} else {
+		call["result"] = ValueToJSONWithEncoding(result, t.encoding)
+	}
+	t.calls = append(t.calls, call)
+}
+
+// addTo adds the recorded calls to a response under the "functionTrace" key
+func (t *functionTrace) addTo(response map[string]interface{}) map[string]interface{} {
+	if t == nil {
+		return response
+	}
+	response["functionTrace"] = t.calls
+	return response
+}
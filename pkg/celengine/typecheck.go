@@ -0,0 +1,128 @@
+package celengine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+)
+
+// TypecheckOptions configures TypecheckWithOptions
+type TypecheckOptions struct {
+	// AllowUndeclared checks undeclared identifiers as dyn, reporting them as warnings
+	// rather than failing, so editors can show the type of expressions whose variables
+	// aren't declared yet. Undeclared functions still fail
+	AllowUndeclared bool `json:"allowUndeclared,omitempty"`
+}
+
+// TypecheckWithOptions typechecks an expression like Typecheck
+// With AllowUndeclared, the response also holds "issues", a warning with the location
+// of each reference to an undeclared identifier, and "undeclared", their sorted names
+func TypecheckWithOptions(envID string, exprStr string, options TypecheckOptions) map[string]interface{} {
+	if !options.AllowUndeclared {
+		return Typecheck(envID, exprStr)
+	}
+
+	envState, ok := envs.get(envID)
+	if !ok {
+		return envs.notFound(envID)
+	}
+
+	// Check if environment has been destroyed
+	if envState.destroyed {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("environment has been destroyed: %s", envID),
+		}
+	}
+	touchEnv(envState)
+
+	parsed, issues := envState.env.Parse(exprStr)
+	if issues != nil && issues.Err() != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("typecheck error: %v", issues.Err()),
+		}
+	}
+
+	// Undeclared identifiers are declared as dyn in a throwaway extension of the
+	// environment, and the expression is checked again until none are left, since
+	// the checker may only report some of them at a time
+	env := envState.env
+	warnings := make([]interface{}, 0)
+	undeclared := make(map[string]bool)
+	for {
+		checked, issues := env.Check(parsed)
+		if issues == nil || issues.Err() == nil {
+			exprType, err := cel.TypeToExprType(checked.OutputType())
+			if err != nil {
+				return map[string]interface{}{
+					"error": fmt.Sprintf("failed to convert type: %v", err),
+				}
+			}
+			names := make([]string, 0, len(undeclared))
+			for name := range undeclared {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return map[string]interface{}{
+				"type":       typeToJSON(exprType),
+				"issues":     warnings,
+				"undeclared": stringsToInterfaces(names),
+				"error":      nil,
+			}
+		}
+
+		var declarations []cel.EnvOption
+		for _, err := range issues.Errors() {
+			name, ok := undeclaredIdentifier(parsed, err)
+			if !ok {
+				continue
+			}
+			warning := map[string]interface{}{
+				"severity": "warning",
+				"message":  fmt.Sprintf("undeclared reference to '%s', checked as dyn", name),
+				"location": map[string]interface{}{
+					"line":   int(err.Location.Line()),
+					"column": int(err.Location.Column()),
+				},
+			}
+			if suggestions := didYouMean(envState.env, parsed, err); len(suggestions) > 0 {
+				warning["didYouMean"] = suggestions
+			}
+			warnings = append(warnings, warning)
+			if !undeclared[name] {
+				undeclared[name] = true
+				declarations = append(declarations, cel.Variable(name, cel.DynType))
+			}
+		}
+		if len(declarations) == 0 {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("typecheck error: %v", issues.Err()),
+			}
+		}
+
+		var err error
+		if env, err = env.Extend(declarations...); err != nil {
+			return map[string]interface{}{
+				"error": fmt.Sprintf("failed to declare undeclared identifiers: %v", err),
+			}
+		}
+	}
+}
+
+// undeclaredIdentifier returns the name of the identifier a check error reports as
+// undeclared, as opposed to an undeclared function or another error
+func undeclaredIdentifier(parsed *cel.Ast, err *common.Error) (string, bool) {
+	match := undeclaredReference.FindStringSubmatch(err.Message)
+	if match == nil {
+		return "", false
+	}
+	isCall := false
+	ast.PostOrderVisit(parsed.NativeRep().Expr(), ast.NewExprVisitor(func(e ast.Expr) {
+		if e.ID() == err.ExprID && e.Kind() == ast.CallKind {
+			isCall = true
+		}
+	}))
+	return match[1], !isCall
+}
@@ -0,0 +1,111 @@
+package celengine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/checker/decls"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// isTypeExpression reports whether a type name is a compact type expression such as
+// "list<map<string,int>>" rather than a plain type name
+func isTypeExpression(typeName string) bool {
+	return strings.ContainsAny(typeName, "<>, \t")
+}
+
+// parseTypeExpression parses a compact type expression into a CEL type
+// The grammar is name or name<type, ...>, where list takes an element type and map
+// takes a key and a value type, mirroring how CEL prints types
+func parseTypeExpression(expr string) (*exprpb.Type, error) {
+	p := &typeExpressionParser{input: expr}
+	exprType, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.input[p.pos:], p.pos)
+	}
+	return exprType, nil
+}
+
+// typeExpressionParser is a recursive descent parser over a type expression
+type typeExpressionParser struct {
+	input string
+	pos   int
+}
+
+// parseType parses a type name with its optional type parameters
+func (p *typeExpressionParser) parseType() (*exprpb.Type, error) {
+	p.skipSpace()
+	start := p.pos
+	name := p.parseName()
+	if name == "" {
+		if p.pos < len(p.input) {
+			return nil, fmt.Errorf("expected a type name at offset %d, got %q", start, p.input[p.pos])
+		}
+		return nil, fmt.Errorf("expected a type name at offset %d", start)
+	}
+
+	p.skipSpace()
+	if p.pos >= len(p.input) || p.input[p.pos] != '<' {
+		return parseTypeName(name), nil
+	}
+	p.pos++
+
+	var params []*exprpb.Type
+	for {
+		param, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("expected ',' or '>' at offset %d", p.pos)
+		}
+		separator := p.input[p.pos]
+		p.pos++
+		if separator == '>' {
+			break
+		}
+		if separator != ',' {
+			return nil, fmt.Errorf("expected ',' or '>' at offset %d, got %q", p.pos-1, separator)
+		}
+	}
+
+	switch {
+	case name == "list" && len(params) == 1:
+		return decls.NewListType(params[0]), nil
+	case name == "map" && len(params) == 2:
+		return decls.NewMapType(params[0], params[1]), nil
+	case name == "list":
+		return nil, fmt.Errorf("list takes 1 type parameter, got %d", len(params))
+	case name == "map":
+		return nil, fmt.Errorf("map takes 2 type parameters, got %d", len(params))
+	default:
+		return nil, fmt.Errorf("type %s takes no type parameters", name)
+	}
+}
+
+// parseName consumes a possibly qualified type name
+func (p *typeExpressionParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c != '_' && c != '.' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// skipSpace consumes whitespace
+func (p *typeExpressionParser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
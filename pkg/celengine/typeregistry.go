@@ -0,0 +1,140 @@
+package celengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/invakid404/wasm-cel/internal/options"
+	"github.com/invakid404/wasm-cel/internal/wasmenv"
+)
+
+// sharedRegistry is a type registry shared by every env whose context proto comes from
+// the same descriptor set
+type sharedRegistry struct {
+	registry *types.Registry
+	refCount int
+}
+
+// typeRegistries maps descriptor set keys to shared registries
+// Registries are mutable, so each env gets a copy. Copies share the descriptions of the
+// messages and their fields, which make up most of a registry built from descriptors
+var typeRegistries = make(map[string]*sharedRegistry)
+
+// contextDescriptorSet returns the base64-encoded descriptor set of the DeclareContextProto
+// option among environment options, if there is one
+func contextDescriptorSet(optionsJSON *string) (string, bool) {
+	if optionsJSON == nil || *optionsJSON == "" {
+		return "", false
+	}
+	var configs []wasmenv.OptionConfig
+	if err := json.Unmarshal([]byte(*optionsJSON), &configs); err != nil {
+		return "", false
+	}
+	for _, config := range configs {
+		if config.Type != "DeclareContextProto" {
+			continue
+		}
+		encoded, ok := config.Params["descriptorSet"].(string)
+		return encoded, ok
+	}
+	return "", false
+}
+
+// typeRegistryKey hashes a base64-encoded descriptor set
+func typeRegistryKey(encoded string) string {
+	sum := sha256.Sum256([]byte(encoded))
+	return hex.EncodeToString(sum[:])
+}
+
+// acquireTypeRegistry returns the shared registry of a descriptor set, building it on first
+// use, and takes a reference to it
+// A set that fails to build has no registry, and the DeclareContextProto option reports why
+func acquireTypeRegistry(encoded string) (*types.Registry, string, bool) {
+	key := typeRegistryKey(encoded)
+	if shared, ok := typeRegistries[key]; ok {
+		shared.refCount++
+		return shared.registry, key, true
+	}
+
+	files, err := options.DescriptorSetFiles(encoded)
+	if err != nil {
+		return nil, "", false
+	}
+	registry, err := types.NewRegistry()
+	if err != nil {
+		return nil, "", false
+	}
+	for _, file := range files {
+		if err := registry.RegisterDescriptor(file); err != nil {
+			return nil, "", false
+		}
+	}
+
+	typeRegistries[key] = &sharedRegistry{registry: registry, refCount: 1}
+	return registry, key, true
+}
+
+// retainTypeRegistry takes another reference to a shared registry, for an env that shares
+// a pooled cel.Env built with it
+func retainTypeRegistry(key string) bool {
+	shared, ok := typeRegistries[key]
+	if ok {
+		shared.refCount++
+	}
+	return ok
+}
+
+// releaseTypeRegistry drops a reference to a shared registry, evicting it when unused
+// Envs built with it keep their copies
+func releaseTypeRegistry(key string) {
+	if key == "" {
+		return
+	}
+	shared, ok := typeRegistries[key]
+	if !ok {
+		return
+	}
+	shared.refCount--
+	if shared.refCount <= 0 {
+		delete(typeRegistries, key)
+		emitLifecycleEvent(LifecycleCacheEvicted, map[string]interface{}{"cache": "typeRegistry"})
+	}
+}
+
+// sharedTypes makes a copy of a shared registry the type provider and adapter of an env
+// It must come before the options that register types, which would otherwise be dropped.
+// The only other such option is OptionalTypes, so an optional type registered by an
+// earlier option or the env being extended is carried over
+func sharedTypes(registry *types.Registry) cel.EnvOption {
+	return func(e *cel.Env) (*cel.Env, error) {
+		copied := registry.Copy()
+		if _, ok := e.CELTypeProvider().FindIdent(types.OptionalType.TypeName()); ok {
+			if err := copied.RegisterType(types.OptionalType); err != nil {
+				return nil, err
+			}
+		}
+		e, err := cel.CustomTypeProvider(copied)(e)
+		if err != nil {
+			return nil, err
+		}
+		return cel.CustomTypeAdapter(copied)(e)
+	}
+}
+
+// contextTypes returns the option sharing the type registry of the context proto that
+// environment options declare from a descriptor set, and the key of the reference taken
+// to the registry. Without such a context proto, the option is nil
+func contextTypes(optionsJSON *string) (cel.EnvOption, string) {
+	encoded, ok := contextDescriptorSet(optionsJSON)
+	if !ok {
+		return nil, ""
+	}
+	registry, key, ok := acquireTypeRegistry(encoded)
+	if !ok {
+		return nil, ""
+	}
+	return sharedTypes(registry), key
+}
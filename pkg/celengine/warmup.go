@@ -0,0 +1,44 @@
+package celengine
+
+import (
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// unknownPatternSet holds the attribute patterns of a list of unknowns, so evaluations
+// with the same unknowns as the previous one don't parse them again
+type unknownPatternSet struct {
+	key      string
+	patterns []*cel.AttributePatternType
+}
+
+// unknownPatterns returns the attribute patterns of unknowns, reusing those of the
+// program's previous evaluation when it had the same unknowns
+func (programState *ProgramState) unknownPatterns(unknowns []string) []*cel.AttributePatternType {
+	key := strings.Join(unknowns, "\n")
+	if programState.patterns == nil || programState.patterns.key != key {
+		programState.patterns = &unknownPatternSet{key: key, patterns: unknownPatterns(unknowns)}
+	}
+	return programState.patterns.patterns
+}
+
+// Warmup prepares a program for its first evaluation, for callers that can't afford the
+// extra latency of that evaluation
+// Programs are planned when they're compiled, and the plan is kept for every evaluation,
+// so this computes the state evaluations otherwise compute on first use: the variables
+// the program reads and their types, which strict evaluations and integer strings need
+func Warmup(programID string) map[string]interface{} {
+	programState, ok := programs.get(programID)
+	if !ok {
+		return programs.notFound(programID)
+	}
+	touchProgram(programState)
+
+	programState.readVariables()
+
+	return map[string]interface{}{
+		"success": true,
+		"error":   nil,
+	}
+}
@@ -0,0 +1,33 @@
+import { Env } from "../dist/index.js";
+
+describe("API versions", () => {
+  test("should pin environments to the latest version by default", async () => {
+    const env = await Env.new({ variables: [{ name: "x", type: "int" }] });
+
+    const { apiVersion } = await env.describe();
+    expect(apiVersion).toBe(1);
+
+    env.destroy();
+  });
+
+  test("should evaluate the same way when pinned to version 1", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+      apiVersion: 1,
+    });
+    const program = await env.compile("{1: x / 0.0, 2: 1.0 / 0.0}");
+
+    const result = await program.eval({ x: 0 });
+    expect(result["2"]).toBe(Infinity);
+    expect(Number.isNaN(result["1"])).toBe(true);
+    expect((await env.describe()).apiVersion).toBe(1);
+
+    env.destroy();
+  });
+
+  test("should reject unsupported versions", async () => {
+    await expect(Env.new({ apiVersion: 2 })).rejects.toThrow(
+      "unsupported API version 2: expected one of [1]",
+    );
+  });
+});
@@ -0,0 +1,84 @@
+import { Env } from "../dist/index.js";
+
+describe("Attribute tracing", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "user", type: "map<string, dyn>" },
+        { name: "items", type: "list<dyn>" },
+        { name: "headers", type: "map<string, string>" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  const user = { age: 21, email: "alice@example.com", phone: "555-0100" };
+
+  test("should record the attributes the evaluation read", async () => {
+    const program = await env.compile(
+      'user.age < 18 || user.email.endsWith("@example.com")',
+    );
+    const { result, attributeTrace } = await program.evalDetailed(
+      { user },
+      { traceAttributes: true },
+    );
+
+    expect(result).toBe(true);
+    expect(attributeTrace).toEqual(["user", "user.age", "user.email"]);
+  });
+
+  test("should not record branches that weren't taken", async () => {
+    const program = await env.compile(
+      'user.age >= 18 || user.email.endsWith("@example.com")',
+    );
+    const { attributeTrace } = await program.evalDetailed(
+      { user },
+      { traceAttributes: true },
+    );
+
+    expect(attributeTrace).toEqual(["user", "user.age"]);
+  });
+
+  test("should record list elements and map keys", async () => {
+    const program = await env.compile('items[1].name == headers["x-id"]');
+    const { attributeTrace } = await program.evalDetailed(
+      { items: [{ name: "a" }, { name: "b" }], headers: { "x-id": "b" } },
+      { traceAttributes: true },
+    );
+
+    expect(attributeTrace).toEqual([
+      "headers",
+      'headers["x-id"]',
+      "items",
+      "items[1]",
+      "items[1].name",
+    ]);
+  });
+
+  test("should record the elements comprehensions visit", async () => {
+    const program = await env.compile("items.all(i, i.name != '')");
+    const { attributeTrace } = await program.evalDetailed(
+      { items: [{ name: "a" }, { name: "b" }] },
+      { traceAttributes: true },
+    );
+
+    expect(attributeTrace).toEqual([
+      "items",
+      "items[0]",
+      "items[0].name",
+      "items[1]",
+      "items[1].name",
+    ]);
+  });
+
+  test("should not trace without traceAttributes", async () => {
+    const program = await env.compile("user.age");
+    const { attributeTrace } = await program.evalDetailed({ user });
+    expect(attributeTrace).toBeUndefined();
+  });
+});
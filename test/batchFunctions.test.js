@@ -0,0 +1,127 @@
+import { Env, CELFunction } from "../dist/index.js";
+
+describe("Batch implementations", () => {
+  const scale = (calls) =>
+    CELFunction.new("scale")
+      .param("x", "int")
+      .param("factor", "int")
+      .returns("int")
+      .batch((batch) => {
+        calls.push(batch);
+        return batch.map(([x, factor]) => x * factor);
+      })
+      .implement((x, factor) => {
+        calls.push([x, factor]);
+        return x * factor;
+      });
+
+  test("should call the batch implementation once for a map()", async () => {
+    const calls = [];
+    const env = await Env.new({
+      variables: [{ name: "xs", type: "list<int>" }],
+      functions: [scale(calls)],
+    });
+
+    const program = await env.compile("xs.map(x, scale(x, 10))");
+    expect(await program.eval({ xs: [1, 2, 3] })).toEqual([10, 20, 30]);
+    expect(calls).toEqual([
+      [
+        [1, 10],
+        [2, 10],
+        [3, 10],
+      ],
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should only pass the elements kept by a filtering map()", async () => {
+    const calls = [];
+    const env = await Env.new({
+      variables: [{ name: "xs", type: "list<int>" }],
+      functions: [scale(calls)],
+    });
+
+    const program = await env.compile("xs.map(x, x > 1, scale(x, 2))");
+    expect(await program.eval({ xs: [1, 2, 3] })).toEqual([4, 6]);
+    expect(calls).toEqual([
+      [
+        [2, 2],
+        [3, 2],
+      ],
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should call the implementation outside of map()", async () => {
+    const calls = [];
+    const env = await Env.new({
+      variables: [{ name: "xs", type: "list<int>" }],
+      functions: [scale(calls)],
+    });
+
+    const program = await env.compile("xs.exists(x, scale(x, 2) == 4)");
+    expect(await program.eval({ xs: [1, 2, 3] })).toBe(true);
+    expect(calls).toEqual([
+      [1, 2],
+      [2, 2],
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should not call the batch implementation for empty lists", async () => {
+    const calls = [];
+    const env = await Env.new({
+      variables: [{ name: "xs", type: "list<int>" }],
+      functions: [scale(calls)],
+    });
+
+    const program = await env.compile("xs.map(x, scale(x, 2))");
+    expect(await program.eval({ xs: [] })).toEqual([]);
+    expect(calls).toEqual([]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should fail when the batch returns the wrong number of results", async () => {
+    const env = await Env.new({
+      variables: [{ name: "xs", type: "list<int>" }],
+      functions: [
+        CELFunction.new("half")
+          .param("x", "int")
+          .returns("int")
+          .batch(() => [1])
+          .implement((x) => x / 2),
+      ],
+    });
+
+    const program = await env.compile("xs.map(x, half(x))");
+    await expect(program.eval({ xs: [2, 4] })).rejects.toThrow(
+      "function half must return a list of 2 results from its batch implementation",
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should keep the source of the expression unchanged", async () => {
+    const env = await Env.new({
+      variables: [{ name: "xs", type: "list<int>" }],
+      functions: [scale([])],
+    });
+
+    const program = await env.compile("xs.map(x, scale(x, 2))", {
+      optimizedSource: true,
+    });
+    expect(program.optimizedSource).toBe("xs.map(x, scale(x, 2))");
+
+    program.destroy();
+    env.destroy();
+  });
+});
@@ -0,0 +1,184 @@
+import { Env, Session } from "../dist/index.js";
+import { signingKeys } from "./helpers.js";
+
+// FileDescriptorSet for:
+//
+//   syntax = "proto3";
+//   package acme;
+//   message User { string name = 1; repeated string groups = 2; }
+//   message Request { string path = 1; int64 size = 2; User user = 3; }
+const descriptorSet =
+  "CqkBChJhY21lL3JlcXVlc3QucHJvdG8SBGFjbWUiMgoEVXNlchISCgRuYW1lGAEgASgJUgRuYW1lEhYKBmdyb3VwcxgCIAMoCVIGZ3JvdXBzIlEKB1JlcXVlc3QSEgoEcGF0aBgBIAEoCVIEcGF0aBISCgRzaXplGAIgASgDUgRzaXplEh4KBHVzZXIYAyABKAsyCi5hY21lLlVzZXJSBHVzZXJiBnByb3RvMw==";
+
+const variables = [{ name: "user", type: "map<string, dyn>" }];
+
+async function checkedExpr(expr) {
+  const env = await Env.new({ variables });
+  const program = await env.compile(expr);
+  const checked = await program.exportCheckedExpr();
+  program.destroy();
+  env.destroy();
+  return Buffer.from(checked).toString("base64");
+}
+
+describe("Policy bundles", () => {
+  test("should load the environment and programs of a bundle", async () => {
+    const bundle = {
+      version: 1,
+      env: {
+        variables,
+        constants: [{ name: "MAX_NAME", type: "int", value: 3 }],
+      },
+      programs: [
+        { name: "adult", checkedExpr: await checkedExpr("user.age >= 18") },
+        { name: "short", expr: "size(user.name) <= MAX_NAME" },
+      ],
+    };
+
+    for (const encoded of [
+      bundle,
+      JSON.stringify(bundle),
+      new TextEncoder().encode(JSON.stringify(bundle)),
+    ]) {
+      const { env, programs } = await Env.loadBundle(encoded);
+      expect(Object.keys(programs).sort()).toEqual(["adult", "short"]);
+      expect(await programs.adult.eval({ user: { age: 21 } })).toBe(true);
+      expect(await programs.short.eval({ user: { name: "Ada" } })).toBe(true);
+
+      programs.adult.destroy();
+      programs.short.destroy();
+      env.destroy();
+    }
+  });
+
+  test("should use the descriptor set of the bundle", async () => {
+    const { env, programs } = await Env.loadBundle({
+      version: 1,
+      descriptorSet,
+      env: {
+        options: [
+          { type: "DeclareContextProto", params: { typeName: "acme.Request" } },
+        ],
+      },
+      programs: [{ name: "admin", expr: '"admins" in user.groups' }],
+    });
+
+    const result = await programs.admin.evalWithContextMessage("acme.Request", {
+      user: { groups: ["admins"] },
+    });
+    expect(result).toBe(true);
+
+    programs.admin.destroy();
+    env.destroy();
+  });
+
+  test("should create the environment in a session", async () => {
+    const session = await Session.new();
+    const { env, programs } = await Env.loadBundle(
+      { version: 1, env: {}, programs: [{ name: "one", expr: "1" }] },
+      { session },
+    );
+
+    expect(await programs.one.eval()).toBe(1);
+
+    session.destroy();
+    await expect(programs.one.eval()).rejects.toThrow(
+      "Program has been destroyed",
+    );
+    await expect(env.compile("1")).rejects.toThrow(
+      "Environment has been destroyed",
+    );
+  });
+
+  test("should reject invalid bundles", async () => {
+    await expect(
+      Env.loadBundle({ version: 2, env: {}, programs: [] }),
+    ).rejects.toThrow("unsupported bundle version 2");
+
+    await expect(
+      Env.loadBundle({
+        version: 1,
+        env: {},
+        programs: [
+          { name: "a", expr: "1" },
+          { name: "a", expr: "2" },
+        ],
+      }),
+    ).rejects.toThrow("duplicate bundle program a");
+
+    await expect(
+      Env.loadBundle({
+        version: 1,
+        env: {},
+        programs: [{ name: "adult", checkedExpr: await checkedExpr("user") }],
+      }),
+    ).rejects.toThrow("bundle program adult: compilation error");
+  });
+
+  describe("signatures", () => {
+    const { rawKey, sign } = signingKeys();
+
+    function signed(bundle) {
+      const data = Buffer.from(JSON.stringify(bundle));
+      return {
+        bundle: data.toString("base64"),
+        signature: sign(data),
+      };
+    }
+
+    const bundle = {
+      version: 1,
+      env: {},
+      programs: [{ name: "answer", expr: "42" }],
+    };
+
+    test("should load bundles signed with the key", async () => {
+      for (const key of [rawKey, Buffer.from(rawKey).toString("base64")]) {
+        const { env, programs } = await Env.loadBundle(signed(bundle), {
+          publicKey: key,
+        });
+        expect(await programs.answer.eval()).toBe(42);
+
+        programs.answer.destroy();
+        env.destroy();
+      }
+    });
+
+    test("should reject tampered and unsigned bundles", async () => {
+      const tampered = {
+        ...signed(bundle),
+        bundle: Buffer.from(
+          JSON.stringify({
+            ...bundle,
+            programs: [{ name: "answer", expr: "0" }],
+          }),
+        ).toString("base64"),
+      };
+      await expect(
+        Env.loadBundle(tampered, { publicKey: rawKey }),
+      ).rejects.toThrow("bundle signature verification failed");
+
+      await expect(
+        Env.loadBundle(bundle, { publicKey: rawKey }),
+      ).rejects.toThrow("bundle isn't signed");
+
+      await expect(
+        Env.loadBundle(signed(bundle), { publicKey: rawKey.subarray(1) }),
+      ).rejects.toThrow("invalid public key: expected 32 bytes, got 31");
+    });
+
+    test("should only load signed bundles without a key when verification is skipped", async () => {
+      await expect(Env.loadBundle(signed(bundle))).rejects.toThrow(
+        "bundle is signed",
+      );
+
+      const { env, programs } = await Env.loadBundle(signed(bundle), {
+        skipSignatureVerification: true,
+      });
+      expect(await programs.answer.eval()).toBe(42);
+
+      programs.answer.destroy();
+      env.destroy();
+    });
+  });
+});
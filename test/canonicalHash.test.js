@@ -0,0 +1,44 @@
+import { Env } from "../dist/index.js";
+
+describe("Canonical hashing", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "x", type: "int" },
+        { name: "items", type: { kind: "list", elementType: "int" } },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should hash formatting variants identically", async () => {
+    const a = await env.canonicalHash("x+1");
+    const b = await env.canonicalHash("  (x + 1) // increment");
+    expect(a.canonical).toBe("x + 1");
+    expect(b).toEqual(a);
+    expect(a.hash).toMatch(/^[0-9a-f]{64}$/);
+  });
+
+  test("should distinguish different expressions", async () => {
+    const a = await env.canonicalHash("x + 1");
+    const b = await env.canonicalHash("x + 2");
+    expect(a.hash).not.toBe(b.hash);
+  });
+
+  test("should unparse macros", async () => {
+    const result = await env.canonicalHash("items.exists(i,i>x)");
+    expect(result.canonical).toBe("items.exists(i, i > x)");
+  });
+
+  test("should reject expressions that don't compile", async () => {
+    await expect(env.canonicalHash("y + 1")).rejects.toThrow(
+      "undeclared reference",
+    );
+    await expect(env.canonicalHash("x +")).rejects.toThrow("Syntax error");
+  });
+});
@@ -0,0 +1,157 @@
+import { Env, CELFunction, configure } from "../dist/index.js";
+
+const sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms));
+
+describe("Concurrent evaluation", () => {
+  let env;
+  let calls;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+      functions: [
+        CELFunction.new("slow")
+          .param("x", "double")
+          .returns("double")
+          .implement(async (x) => {
+            await sleep(50);
+            calls.push(`slow ${x}`);
+            return x * 2;
+          }),
+        CELFunction.new("fast")
+          .param("x", "double")
+          .returns("double")
+          .implement((x) => {
+            calls.push(`fast ${x}`);
+            return x + 1;
+          }),
+        CELFunction.new("failing")
+          .param("x", "double")
+          .returns("double")
+          .implement(() => Promise.reject(new Error("lookup failed"))),
+      ],
+    });
+  });
+
+  beforeEach(() => {
+    calls = [];
+  });
+
+  afterEach(async () => {
+    await configure({ evalWorkers: 4 });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should wait for Promises returned by functions", async () => {
+    const program = await env.compile("slow(x) + 1.0");
+    const { result } = await program.evalConcurrent({ x: 2 });
+    expect(result).toBe(5);
+    program.destroy();
+  });
+
+  test("should keep evaluating while an evaluation waits", async () => {
+    const slow = await env.compile("slow(x)");
+    const fast = await env.compile("fast(x)");
+
+    const results = await Promise.all([
+      slow.evalConcurrent({ x: 1 }),
+      fast.evalConcurrent({ x: 2 }),
+      slow.evalConcurrent({ x: 3 }),
+    ]);
+    expect(results.map(({ result }) => result)).toEqual([2, 3, 6]);
+    expect(calls[0]).toBe("fast 2");
+
+    slow.destroy();
+    fast.destroy();
+  });
+
+  test("should run at most evalWorkers evaluations at once", async () => {
+    const program = await env.compile("slow(x)");
+    await configure({ evalWorkers: 1 });
+
+    const start = Date.now();
+    await Promise.all([
+      program.evalConcurrent({ x: 1 }),
+      program.evalConcurrent({ x: 2 }),
+    ]);
+    expect(Date.now() - start).toBeGreaterThanOrEqual(95);
+    program.destroy();
+  });
+
+  test("should reject when a returned Promise rejects", async () => {
+    const program = await env.compile("failing(x)");
+    await expect(program.evalConcurrent({ x: 1 })).rejects.toThrow(
+      "lookup failed",
+    );
+    program.destroy();
+  });
+
+  test("should support evaluation options", async () => {
+    const program = await env.compile("slow(x)");
+    const { result, metrics } = await program.evalConcurrent(
+      { x: 1 },
+      { metrics: true },
+    );
+    expect(result).toBe(2);
+    expect(metrics.callbackCount).toBe(1);
+    program.destroy();
+  });
+
+  test("should reject Promises in synchronous evaluations", async () => {
+    const program = await env.compile("slow(x)");
+    await expect(program.eval({ x: 1 })).rejects.toThrow(
+      "must return their result synchronously",
+    );
+    program.destroy();
+  });
+
+  test("should cancel running evaluations when aborted", async () => {
+    const program = await env.compile("slow(x) + slow(x + 1.0)");
+    const controller = new AbortController();
+
+    const evaluation = program.evalConcurrent(
+      { x: 1 },
+      { signal: controller.signal },
+    );
+    setTimeout(() => controller.abort(), 10);
+    await expect(evaluation).rejects.toThrow("aborted");
+    await sleep(100);
+    expect(calls).toEqual(["slow 1"]);
+    program.destroy();
+  });
+
+  test("should drop queued evaluations when aborted", async () => {
+    const program = await env.compile("slow(x)");
+    await configure({ evalWorkers: 1 });
+    const controller = new AbortController();
+
+    const first = program.evalConcurrent({ x: 1 });
+    const second = program.evalConcurrent(
+      { x: 2 },
+      { signal: controller.signal },
+    );
+    controller.abort(new Error("superseded"));
+    await expect(second).rejects.toThrow("superseded");
+    await expect(first).resolves.toMatchObject({ result: 2 });
+    expect(calls).toEqual(["slow 1"]);
+    program.destroy();
+  });
+
+  test("should reject right away when already aborted", async () => {
+    const program = await env.compile("fast(x)");
+    await expect(
+      program.evalConcurrent({ x: 1 }, { signal: AbortSignal.abort() }),
+    ).rejects.toThrow("aborted");
+    expect(calls).toEqual([]);
+    program.destroy();
+  });
+
+  test("should reject fewer than one worker", async () => {
+    await expect(configure({ evalWorkers: 0 })).rejects.toThrow(
+      "evalWorkers must be at least 1",
+    );
+  });
+});
@@ -0,0 +1,60 @@
+import { Env, configure } from "../dist/index.js";
+
+const sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms));
+
+describe("configure", () => {
+  afterEach(async () => {
+    await configure({ programTTLms: 0, envTTLms: 0 });
+  });
+
+  test("should merge partial configurations", async () => {
+    expect(await configure({ programTTLms: 1000 })).toEqual({
+      programTTLms: 1000,
+      envTTLms: 0,
+      evalWorkers: 4,
+      evalMemoryLimitBytes: 0,
+    });
+    expect(await configure({ envTTLms: 2000 })).toEqual({
+      programTTLms: 1000,
+      envTTLms: 2000,
+      evalWorkers: 4,
+      evalMemoryLimitBytes: 0,
+    });
+  });
+
+  test("should reject negative TTLs", async () => {
+    await expect(configure({ programTTLms: -1 })).rejects.toThrow(
+      "TTLs must not be negative",
+    );
+  });
+
+  test("should destroy idle programs", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const idle = await env.compile("x + 1.0");
+    const active = await env.compile("x + 2.0");
+
+    await configure({ programTTLms: 100 });
+    for (let i = 0; i < 6; i++) {
+      await sleep(50);
+      expect(await active.eval({ x: 1 })).toBe(3);
+    }
+
+    await expect(idle.eval({ x: 1 })).rejects.toThrow("program not found");
+
+    active.destroy();
+    env.destroy();
+  });
+
+  test("should destroy idle environments", async () => {
+    const env = await Env.new();
+
+    await configure({ envTTLms: 50 });
+    await sleep(200);
+
+    await expect(env.compile("1 + 1")).rejects.toThrow(
+      "environment not found",
+    );
+  });
+});
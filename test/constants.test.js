@@ -0,0 +1,106 @@
+import { Env, CELFunction } from "../dist/index.js";
+
+describe("Constants", () => {
+  test("should fold constants into expressions", async () => {
+    const env = await Env.new({
+      variables: [{ name: "size", type: "double" }],
+      constants: [
+        { name: "MAX_SIZE", type: "int", value: 1024 },
+        {
+          name: "LIMITS",
+          type: { kind: "map", keyType: "string", valueType: "int" },
+          value: { free: 10, pro: 100 },
+        },
+        { name: "ROLES", type: { kind: "list", elementType: "string" }, value: ["admin"] },
+      ],
+    });
+
+    const program = await env.compile(
+      "size <= double(MAX_SIZE * LIMITS['free']) && 'admin' in ROLES",
+    );
+    expect(await program.eval({ size: 2048 })).toBe(true);
+    expect(await program.eval({ size: 20480 })).toBe(false);
+
+    const typeResult = await env.typecheck("MAX_SIZE");
+    expect(typeResult.type).toBe("int");
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should report folding time in compile metrics", async () => {
+    const env = await Env.new({
+      constants: [{ name: "A", type: "int", value: 2 }],
+    });
+
+    const result = await env.compileDetailed("A * 21", { metrics: true });
+    expect(result.success).toBe(true);
+    expect(result.metrics.optimizeMs).toEqual(expect.any(Number));
+    expect(await result.program.eval()).toBe(42);
+
+    result.program.destroy();
+    env.destroy();
+  });
+
+  test("should convert strings to timestamps and durations", async () => {
+    const env = await Env.new({
+      constants: [
+        { name: "START", type: "timestamp", value: "2024-01-01T00:00:00Z" },
+        { name: "GRACE", type: "duration", value: "1h" },
+      ],
+    });
+
+    const program = await env.compile("(START + GRACE).getHours()");
+    expect(await program.eval()).toBe(1);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should compile expressions that fail to fold as written", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "bool" }],
+      constants: [{ name: "ZERO", type: "int", value: 0 }],
+    });
+
+    const program = await env.compile("x ? 1 : 1 / ZERO");
+    expect(await program.eval({ x: true })).toBe(1);
+    await expect(program.eval({ x: false })).rejects.toThrow("division by zero");
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should not call custom functions at compile time", async () => {
+    let calls = 0;
+    const env = await Env.new({
+      constants: [{ name: "NAME", type: "string", value: "world" }],
+      functions: [
+        CELFunction.new("greet")
+          .param("name", "string")
+          .returns("string")
+          .implement((name) => {
+            calls++;
+            return `hello ${name}`;
+          }),
+      ],
+    });
+
+    const program = await env.compile("greet(NAME)");
+    expect(calls).toBe(0);
+    expect(await program.eval()).toBe("hello world");
+    expect(calls).toBe(1);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject values that don't match the declared type", async () => {
+    await expect(
+      Env.new({ constants: [{ name: "A", type: "int", value: 1.5 }] }),
+    ).rejects.toThrow("invalid value for constant A: 1.5 is not an integer");
+    await expect(
+      Env.new({ constants: [{ name: "B", type: "string", value: 1 }] }),
+    ).rejects.toThrow("invalid value for constant B");
+  });
+});
@@ -0,0 +1,214 @@
+import { Env, Options, EvaluationError } from "../dist/index.js";
+
+// FileDescriptorSet for:
+//
+//   syntax = "proto3";
+//   package acme;
+//   message User { string name = 1; repeated string groups = 2; }
+//   message Request { string path = 1; int64 size = 2; User user = 3; }
+const descriptorSet =
+  "CqkBChJhY21lL3JlcXVlc3QucHJvdG8SBGFjbWUiMgoEVXNlchISCgRuYW1lGAEgASgJUgRuYW1lEhYKBmdyb3VwcxgCIAMoCVIGZ3JvdXBzIlEKB1JlcXVlc3QSEgoEcGF0aBgBIAEoCVIEcGF0aBISCgRzaXplGAIgASgDUgRzaXplEh4KBHVzZXIYAyABKAsyCi5hY21lLlVzZXJSBHVzZXJiBnByb3RvMw==";
+
+// Binary encoding of
+//   { path: "/admin", size: 42, user: { name: "alice", groups: ["admins"] } }
+const requestBytes = new Uint8Array([
+  10, 6, 47, 97, 100, 109, 105, 110, 16, 42, 26, 15, 10, 5, 97, 108, 105, 99,
+  101, 18, 6, 97, 100, 109, 105, 110, 115,
+]);
+
+describe("Context messages", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      options: [
+        Options.declareContextProto({
+          typeName: "acme.Request",
+          descriptorSet,
+        }),
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should declare message fields as variables", async () => {
+    const result = await env.typecheck("user.groups");
+    expect(result.type).toEqual({ kind: "list", elementType: "string" });
+  });
+
+  test("should evaluate against the binary encoding", async () => {
+    const program = await env.compile(
+      'path.startsWith("/admin") && "admins" in user.groups && size > 10',
+    );
+    expect(
+      await program.evalWithContextMessage("acme.Request", requestBytes),
+    ).toBe(true);
+    program.destroy();
+  });
+
+  test("should evaluate against the JSON mapping", async () => {
+    const program = await env.compile('path + ":" + user.name');
+    expect(
+      await program.evalWithContextMessage("acme.Request", {
+        path: "/x",
+        user: { name: "bob" },
+      }),
+    ).toBe("/x:bob");
+    expect(
+      await program.evalWithContextMessage(
+        "acme.Request",
+        JSON.stringify({ path: "/y" }),
+      ),
+    ).toBe("/y:");
+    program.destroy();
+  });
+
+  test("should return messages in their JSON mapping", async () => {
+    const program = await env.compile("user");
+    expect(
+      await program.evalWithContextMessage("acme.Request", requestBytes),
+    ).toEqual({ name: "alice", groups: ["admins"] });
+    program.destroy();
+  });
+
+  test("should accept the descriptor set as bytes", async () => {
+    const bytesEnv = await Env.new({
+      options: [
+        Options.declareContextProto({
+          typeName: "acme.Request",
+          descriptorSet: Buffer.from(descriptorSet, "base64"),
+        }),
+      ],
+    });
+    const program = await bytesEnv.compile("size * 2");
+    expect(
+      await program.evalWithContextMessage("acme.Request", requestBytes),
+    ).toBe(84);
+    program.destroy();
+    bytesEnv.destroy();
+  });
+
+  test("should reject invalid messages and unknown types", async () => {
+    const program = await env.compile("path");
+    await expect(
+      program.evalWithContextMessage("acme.Request", { bogus: 1 }),
+    ).rejects.toThrow("failed to decode context message");
+    await expect(
+      program.evalWithContextMessage("acme.Missing", {}),
+    ).rejects.toThrow("unknown context message type: acme.Missing");
+    program.destroy();
+  });
+
+  test("should report evaluation errors", async () => {
+    const program = await env.compile("size / 0");
+    await expect(
+      program.evalWithContextMessage("acme.Request", requestBytes),
+    ).rejects.toBeInstanceOf(EvaluationError);
+    program.destroy();
+  });
+
+  test("should share descriptor sets between environments", async () => {
+    const tenants = await Promise.all(
+      ["alice", "bob"].map((tenant) =>
+        Env.new({
+          constants: [{ name: "tenant", type: "string", value: tenant }],
+          options: [
+            Options.optionalTypes(),
+            Options.declareContextProto({
+              typeName: "acme.Request",
+              descriptorSet,
+            }),
+          ],
+        }),
+      ),
+    );
+
+    const [alice, bob] = await Promise.all(
+      tenants.map((tenant) =>
+        tenant.compile("user.name == tenant && optional.of(size).hasValue()"),
+      ),
+    );
+    tenants[1].destroy();
+    bob.destroy();
+
+    expect(
+      await alice.evalWithContextMessage("acme.Request", requestBytes),
+    ).toBe(true);
+    alice.destroy();
+    tenants[0].destroy();
+  });
+
+  test("should reject unknown message types in the option", async () => {
+    await expect(
+      Env.new({
+        options: [
+          Options.declareContextProto({
+            typeName: "acme.Missing",
+            descriptorSet,
+          }),
+        ],
+      }),
+    ).rejects.toThrow("acme.Missing not found");
+  });
+});
+
+describe("ProtoJSON option", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "body", type: "string" }],
+      options: [
+        Options.declareContextProto({
+          typeName: "acme.Request",
+          descriptorSet,
+        }),
+        Options.protoJSON(),
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should build messages and encode them as JSON", async () => {
+    const program = await env.compile(
+      'toJSON(acme.Request{path: "/x", size: 5, user: acme.User{name: "bob"}})',
+    );
+    expect(await program.eval()).toBe(
+      '{"path":"/x","size":"5","user":{"name":"bob"}}',
+    );
+    program.destroy();
+  });
+
+  test("should encode other values as JSON", async () => {
+    const program = await env.compile('toJSON({"b": [1, 2.5], "a": true})');
+    expect(await program.eval()).toBe('{"a":true,"b":[1,2.5]}');
+    program.destroy();
+  });
+
+  test("should decode messages from JSON", async () => {
+    const program = await env.compile(
+      'fromJSON("acme.User", body) == acme.User{name: "bob", groups: ["x"]}',
+    );
+    expect(await program.eval({ body: '{"name":"bob","groups":["x"]}' })).toBe(
+      true,
+    );
+    await expect(program.eval({ body: '{"bogus":1}' })).rejects.toThrow(
+      "failed to decode acme.User",
+    );
+    program.destroy();
+  });
+
+  test("should reject unknown message types", async () => {
+    const program = await env.compile('fromJSON("acme.Missing", "{}")');
+    await expect(program.eval()).rejects.toThrow(
+      "unknown message type: acme.Missing",
+    );
+    program.destroy();
+  });
+});
@@ -417,4 +417,126 @@ describe("Custom Functions", () => {
       await expect(env.compile("undefinedFunc(1)")).rejects.toThrow();
     });
   });
+
+  describe("Typed arguments and results", () => {
+    test("should pass uints as BigInts, bytes as Uint8Arrays and timestamps as Dates", async () => {
+      const received = [];
+      const inspect = CELFunction.new("inspect")
+        .param("u", "uint")
+        .param("b", "bytes")
+        .param("t", "timestamp")
+        .returns("bool")
+        .implement((u, b, t) => {
+          received.push(u, b, t);
+          return true;
+        });
+
+      const env = await Env.new({ functions: [inspect] });
+      const program = await env.compile(
+        "inspect(18446744073709551615u, b'hi', timestamp('2024-01-02T03:04:05Z'))",
+      );
+      expect(await program.eval()).toBe(true);
+
+      const [u, b, t] = received;
+      expect(u).toBe(18446744073709551615n);
+      expect(b).toBeInstanceOf(Uint8Array);
+      expect(Array.from(b)).toEqual([104, 105]);
+      expect(t).toBeInstanceOf(Date);
+      expect(t.toISOString()).toBe("2024-01-02T03:04:05.000Z");
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should convert results to the declared return type", async () => {
+      const maxUint = CELFunction.new("maxUint")
+        .returns("uint")
+        .implement(() => 18446744073709551615n);
+      const epoch = CELFunction.new("epoch")
+        .returns("timestamp")
+        .implement(() => new Date(0));
+      const greeting = CELFunction.new("greeting")
+        .returns("bytes")
+        .implement(() => new TextEncoder().encode("hi"));
+
+      const env = await Env.new({ functions: [maxUint, epoch, greeting] });
+      const program = await env.compile(
+        "maxUint() == 18446744073709551615u && epoch() == timestamp(0) && greeting() == b'hi'",
+      );
+      expect(await program.eval()).toBe(true);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should fail when the result doesn't match the return type", async () => {
+      const bad = CELFunction.new("bad")
+        .returns("int")
+        .implement(() => "not a number");
+
+      const env = await Env.new({ functions: [bad] });
+      const program = await env.compile("bad()");
+      await expect(program.eval()).rejects.toThrow(
+        "function bad returned an invalid int",
+      );
+
+      program.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("Errors", () => {
+    test("should report thrown and returned errors with their codes", async () => {
+      const email = CELFunction.new("email")
+        .param("s", "string")
+        .returns("string")
+        .implement((s) => {
+          if (!s.includes("@")) {
+            return {
+              __celError: { code: "invalid_email", message: `not an email: ${s}` },
+            };
+          }
+          return s.toLowerCase();
+        });
+      const fail = CELFunction.new("fail")
+        .returns("bool")
+        .implement(() => {
+          const error = new Error("quota exceeded");
+          error.code = "quota";
+          throw error;
+        });
+      const crash = CELFunction.new("crash")
+        .returns("bool")
+        .implement(() => {
+          throw new TypeError("oops");
+        });
+
+      const env = await Env.new({
+        variables: [{ name: "input", type: "string" }],
+        functions: [email, fail, crash],
+      });
+
+      const program = await env.compile("email(input)");
+      expect(await program.eval({ input: "A@B.C" })).toBe("a@b.c");
+      const { errorValue } = await program.evalDetailed(
+        { input: "nobody" },
+        { errorValues: true },
+      );
+      expect(errorValue.code).toBe("invalid_email");
+      expect(errorValue.message).toBe("function email: not an email: nobody");
+
+      const failing = await env.compile("fail()");
+      await expect(failing.eval()).rejects.toMatchObject({
+        name: "EvaluationError",
+        errorValue: { code: "quota", message: "function fail: quota exceeded" },
+      });
+
+      const crashing = await env.compile("crash()");
+      await expect(crashing.eval()).rejects.toMatchObject({
+        errorValue: { code: "function_error" },
+      });
+
+      env.destroy();
+    });
+  });
 });
@@ -0,0 +1,88 @@
+import { Env, CELFunction } from "../dist/index.js";
+
+const decimal = (value) => ({ "@type": "decimal", value });
+
+describe("Decimals", () => {
+  test("should add decimals exactly", async () => {
+    const env = await Env.new({
+      variables: [
+        { name: "a", type: "decimal" },
+        { name: "b", type: "decimal" },
+      ],
+    });
+    const program = await env.compile("a + b");
+
+    const result = await program.eval({
+      a: decimal("0.10"),
+      b: decimal("0.20"),
+    });
+    expect(result).toEqual(decimal("0.30"));
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should convert, compare and round decimals", async () => {
+    const env = await Env.new();
+    const program = await env.compile(
+      '[decimal("1") / decimal("4"), decimal(1.5) < decimal(2), decimal("2.345").round(2), string(-decimal("1.50"))]',
+    );
+
+    expect(await program.eval()).toEqual([
+      decimal("0.25"),
+      true,
+      decimal("2.34"),
+      "-1.50",
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should fail on division by zero", async () => {
+    const env = await Env.new();
+    const program = await env.compile('decimal("1") / decimal("0")');
+
+    await expect(program.eval()).rejects.toThrow("division by zero");
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should pass decimals to and from custom functions", async () => {
+    const env = await Env.new({
+      variables: [{ name: "price", type: "decimal" }],
+      functions: [
+        CELFunction.new("withCents")
+          .param("price", "decimal")
+          .returns("decimal")
+          .implement((price) => decimal(`${price.value}5`)),
+      ],
+    });
+    const program = await env.compile("withCents(price)");
+
+    expect(await program.eval({ price: decimal("1.2") })).toEqual(
+      decimal("1.25"),
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject invalid decimal variables in strict mode", async () => {
+    const env = await Env.new({
+      variables: [{ name: "price", type: "decimal" }],
+    });
+    const program = await env.compile("price");
+
+    await expect(
+      program.eval({ price: decimal("abc") }, { strict: true }),
+    ).rejects.toThrow('invalid decimal "abc"');
+    await expect(
+      program.eval({ price: 1.5 }, { strict: true }),
+    ).rejects.toThrow("expected decimal, got double");
+
+    program.destroy();
+    env.destroy();
+  });
+});
@@ -0,0 +1,96 @@
+import { Env } from "../dist/index.js";
+
+describe("Variable defaults", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "limit", type: "int", default: 10 },
+        { name: "tags", type: "list<string>", default: ["a", "b"] },
+        { name: "since", type: "timestamp", default: "2024-01-01T00:00:00Z" },
+        { name: "name", type: "string" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should use defaults for omitted variables", async () => {
+    const program = await env.compile(
+      "[limit + 1, size(tags), since.getFullYear()]",
+    );
+    await expect(program.eval({})).resolves.toEqual([11, 2, 2024]);
+    program.destroy();
+  });
+
+  test("should prefer provided values over defaults", async () => {
+    const program = await env.compile("size(tags)");
+    await expect(program.eval({ tags: [] })).resolves.toBe(0);
+    program.destroy();
+  });
+
+  test("should still fail on omitted variables without a default", async () => {
+    const program = await env.compile("name + '!'");
+    await expect(program.eval({})).rejects.toThrow("no such attribute");
+    program.destroy();
+  });
+
+  test("should accept omitted variables with defaults in strict mode", async () => {
+    const program = await env.compile("limit > 5");
+    await expect(program.eval({}, { strict: true })).resolves.toBe(true);
+    program.destroy();
+  });
+
+  test("should reject defaults that don't match the declared type", async () => {
+    await expect(
+      Env.new({ variables: [{ name: "n", type: "int", default: 1.5 }] }),
+    ).rejects.toThrow("invalid default of variable n: n: expected int");
+    await expect(
+      Env.new({
+        variables: [{ name: "l", type: "list<int>", default: [1, "x"] }],
+      }),
+    ).rejects.toThrow("l[1]: expected int, got string");
+  });
+});
+
+describe("Absent variables", () => {
+  test("should evaluate omitted variables as null", async () => {
+    const env = await Env.new({
+      variables: [{ name: "user", type: "dyn" }],
+      absentVariables: "null",
+    });
+    const program = await env.compile("user == null");
+    await expect(program.eval({})).resolves.toBe(true);
+    await expect(program.eval({ user: "x" })).resolves.toBe(false);
+    env.destroy();
+  });
+
+  test("should report omitted variables as unknown", async () => {
+    const env = await Env.new({
+      variables: [
+        { name: "plan", type: "string", default: "free" },
+        { name: "user", type: "dyn" },
+      ],
+      absentVariables: "unknown",
+    });
+    const program = await env.compile("plan == 'pro' || user.admin");
+    await expect(program.evalDetailed({})).resolves.toEqual({
+      result: null,
+      unknown: ["user"],
+    });
+    await expect(program.eval({ user: { admin: true } })).resolves.toBe(true);
+    env.destroy();
+  });
+
+  test("should reject an invalid policy", async () => {
+    await expect(
+      Env.new({
+        variables: [{ name: "x", type: "int" }],
+        absentVariables: "zero",
+      }),
+    ).rejects.toThrow('invalid absent variables policy "zero"');
+  });
+});
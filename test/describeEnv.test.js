@@ -0,0 +1,75 @@
+import { CELFunction, Env, Options } from "../dist/index.js";
+
+describe("Environment descriptions", () => {
+  test("should list variables and constants with their types", async () => {
+    const env = await Env.new({
+      variables: [
+        { name: "x", type: "int" },
+        { name: "scores", type: "map<string, int>" },
+      ],
+      constants: [{ name: "LIMIT", type: "int", value: 10 }],
+    });
+
+    const { variables } = await env.describe();
+    expect(variables).toEqual([
+      { name: "LIMIT", type: "int", constant: true },
+      {
+        name: "scores",
+        type: { kind: "map", keyType: "string", valueType: "int" },
+      },
+      { name: "x", type: "int" },
+    ]);
+
+    env.destroy();
+  });
+
+  test("should list functions with their overloads", async () => {
+    const double = CELFunction.new("double")
+      .param("n", "int")
+      .returns("int")
+      .implement((n) => n * 2);
+    const env = await Env.new({ functions: [double] });
+
+    const { functions } = await env.describe();
+    const declared = functions.find((f) => f.name === "double");
+    expect(declared.overloads).toEqual([
+      expect.objectContaining({
+        member: false,
+        params: ["int"],
+        resultType: "int",
+        signature: "double(int) -> int",
+      }),
+    ]);
+
+    const size = functions.find((f) => f.name === "size");
+    expect(size.overloads.map((o) => o.signature)).toContain(
+      "string.size() -> int",
+    );
+
+    env.destroy();
+  });
+
+  test("should list the options the environment was built with", async () => {
+    const env = await Env.new({ options: [Options.optionalTypes()] });
+    await env.extend([Options.crossTypeNumericComparisons()]);
+
+    const description = await env.describe();
+    expect(description.options.map((option) => option.type)).toEqual([
+      "OptionalTypes",
+      "CrossTypeNumericComparisons",
+    ]);
+    expect(description.libraries).toEqual([]);
+    expect(description.container).toBe("");
+
+    env.destroy();
+  });
+
+  test("should reject destroyed environments", async () => {
+    const env = await Env.new();
+    env.destroy();
+
+    await expect(env.describe()).rejects.toThrow(
+      "Environment has been destroyed",
+    );
+  });
+});
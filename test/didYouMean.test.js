@@ -0,0 +1,43 @@
+import { Env } from "../dist/index.js";
+
+describe("Suggestions for undeclared names", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "userName", type: "string" },
+        { name: "items", type: "list<int>" },
+        { name: "x", type: "int" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should suggest variables for undeclared identifiers", async () => {
+    const result = await env.compileDetailed("usrName == 'a'");
+
+    expect(result.success).toBe(false);
+    expect(result.issues).toHaveLength(1);
+    expect(result.issues[0].didYouMean).toEqual(["userName"]);
+  });
+
+  test("should suggest functions and macros for calls", async () => {
+    const call = await env.compileDetailed("sizee(items) > 0");
+    expect(call.issues[0].didYouMean).toEqual(["size"]);
+
+    const macro = await env.compileDetailed("items.exist(i, i > 0)");
+    const issue = macro.issues.find((i) => i.message.includes("'exist'"));
+    expect(issue.didYouMean).toEqual(["exists"]);
+  });
+
+  test("should leave out suggestions when no name is close", async () => {
+    const result = await env.compileDetailed("y > 1");
+
+    expect(result.issues).toHaveLength(1);
+    expect(result.issues[0].didYouMean).toBeUndefined();
+  });
+});
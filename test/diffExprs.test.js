@@ -0,0 +1,88 @@
+import { Env } from "../dist/index.js";
+
+describe("Expression diffs", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "x", type: "int" },
+        { name: "s", type: "string" },
+        { name: "l", type: "list<int>" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should ignore formatting, comments and parentheses", async () => {
+    const diff = await env.diffExprs(
+      "x > 1 && s == 'a'",
+      "(x >  1) && s == 'a' // same",
+    );
+
+    expect(diff).toEqual({ equal: true, changes: [] });
+  });
+
+  test("should report changed subexpressions with their ranges", async () => {
+    const diff = await env.diffExprs("x > 1 && s == 'a'", "x >= 1 && s == 'b'");
+
+    expect(diff.equal).toBe(false);
+    expect(diff.changes).toEqual([
+      {
+        kind: "changed",
+        before: { start: 0, end: 5, text: "x > 1", type: "bool" },
+        after: { start: 0, end: 6, text: "x >= 1", type: "bool" },
+      },
+      {
+        kind: "changed",
+        before: { start: 14, end: 17, text: "'a'", type: "string" },
+        after: { start: 15, end: 18, text: "'b'", type: "string" },
+      },
+    ]);
+  });
+
+  test("should report added and removed elements", async () => {
+    const added = await env.diffExprs(
+      "s in ['a', 'b']",
+      "s in ['a', 'c', 'b']",
+    );
+    expect(added.changes).toEqual([
+      {
+        kind: "added",
+        after: { start: 11, end: 14, text: "'c'", type: "string" },
+      },
+    ]);
+
+    const removed = await env.diffExprs(
+      "s in ['a', 'c', 'b']",
+      "s in ['a', 'b']",
+    );
+    expect(removed.changes).toEqual([
+      {
+        kind: "removed",
+        before: { start: 11, end: 14, text: "'c'", type: "string" },
+      },
+    ]);
+  });
+
+  test("should compare macros as they were written", async () => {
+    const body = await env.diffExprs("l.all(i, i > 0)", "l.all(i, i > x)");
+    expect(body.changes).toHaveLength(1);
+    expect(body.changes[0].before.text).toBe("0");
+    expect(body.changes[0].after.text).toBe("x");
+
+    const macro = await env.diffExprs("l.all(i, i > 0)", "l.exists(i, i > 0)");
+    expect(macro.changes).toHaveLength(1);
+    expect(macro.changes[0].before.text).toBe("l.all(i, i > 0)");
+    expect(macro.changes[0].after.text).toBe("l.exists(i, i > 0)");
+  });
+
+  test("should reject expressions that don't compile", async () => {
+    await expect(env.diffExprs("x > 1", "x > 'a'")).rejects.toThrow(
+      "compilation error in exprB",
+    );
+  });
+});
@@ -0,0 +1,93 @@
+import {
+  Env,
+  EvaluationError,
+  InternalError,
+  ProgramOptions,
+  mapType,
+} from "../dist/index.js";
+
+describe("Error values", () => {
+  const variables = [
+    { name: "limits", type: mapType("string", "int") },
+    { name: "tier", type: "string" },
+  ];
+  const vars = { limits: { gold: 20 }, tier: "silver" };
+
+  test("should reject with an EvaluationError when the expression errors", async () => {
+    const env = await Env.new({ variables });
+    const program = await env.compile("limits[tier] > 10");
+
+    const error = await program.eval(vars).catch((err) => err);
+    expect(error).toBeInstanceOf(EvaluationError);
+    expect(error).not.toBeInstanceOf(InternalError);
+    expect(error.message).toBe("evaluation error: no such key: silver");
+    expect(error.errorValue).toEqual({
+      message: "no such key: silver",
+      exprID: 2,
+      location: { line: 1, column: 6 },
+    });
+
+    await expect(program.evalDetailed(vars)).rejects.toBeInstanceOf(
+      EvaluationError,
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should return error values with errorValues", async () => {
+    const env = await Env.new({ variables });
+    const program = await env.compile("limits[tier] > 10");
+
+    const { result, errorValue } = await program.evalDetailed(vars, {
+      errorValues: true,
+    });
+    expect(result).toBeNull();
+    expect(errorValue.message).toBe("no such key: silver");
+
+    const ok = await program.evalDetailed(
+      { ...vars, tier: "gold" },
+      { errorValues: true },
+    );
+    expect(ok).toEqual({ result: true });
+
+    program.destroy();
+    env.destroy();
+  });
+});
+
+describe("Unknowns", () => {
+  test("should list the unknown attributes a result depends on", async () => {
+    const env = await Env.new({
+      variables: [
+        { name: "user", type: "dyn" },
+        { name: "request", type: "dyn" },
+      ],
+    });
+    const program = await env.compile("user.age >= 18 && request.approved", {
+      programOptions: [ProgramOptions.evalOptions(["OptPartialEval"])],
+    });
+
+    const unknown = await program.evalDetailed(
+      { user: { age: 21 } },
+      { unknowns: ["request.approved"] },
+    );
+    expect(unknown).toEqual({ result: null, unknown: ["request.approved"] });
+
+    // The outcome doesn't depend on the unknown attribute
+    const known = await program.evalDetailed(
+      { user: { age: 12 } },
+      { unknowns: ["request.approved"] },
+    );
+    expect(known).toEqual({ result: false });
+
+    const wildcard = await program.evalDetailed(
+      {},
+      { unknowns: ["request.*", "user"] },
+    );
+    expect(wildcard.unknown).toEqual(["request.approved", "user"]);
+
+    program.destroy();
+    env.destroy();
+  });
+});
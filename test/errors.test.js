@@ -0,0 +1,35 @@
+import { Env, InternalError } from "../dist/index.js";
+
+describe("Internal errors", () => {
+  test("should report panics as internal errors and keep working", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "int" }],
+    });
+    const program = await env.compile("x + 1");
+
+    // BigInt values can't be serialized, which panics inside the module
+    const error = await program.eval({ x: 10n }).catch((err) => err);
+    expect(error).toBeInstanceOf(InternalError);
+    expect(error.code).toBe("internal");
+    expect(error.message).toContain("BigInt");
+    expect(error.goStack).toContain("goroutine");
+
+    // Other calls are unaffected
+    const other = await env.compile("2 * 21");
+    expect(await other.eval()).toBe(42);
+
+    other.destroy();
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should report invalid input as a plain Error", async () => {
+    const env = await Env.new();
+
+    const error = await env.compile("1 +").catch((err) => err);
+    expect(error).toBeInstanceOf(Error);
+    expect(error).not.toBeInstanceOf(InternalError);
+
+    env.destroy();
+  });
+});
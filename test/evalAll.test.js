@@ -0,0 +1,63 @@
+import { Env, Program, EvaluationError } from "../dist/index.js";
+
+describe("Program.evalAll", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "request", type: "dyn" }],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should evaluate every program against the same variables", async () => {
+    const programs = await Promise.all(
+      [
+        'request.user.role == "admin"',
+        'request.path.startsWith("/public")',
+        "request.size * 2.0",
+      ].map((expr) => env.compile(expr)),
+    );
+
+    const outcomes = await Program.evalAll(programs, {
+      request: { user: { role: "viewer" }, path: "/public/a", size: 2 },
+    });
+    expect(outcomes).toEqual([
+      { status: "fulfilled", value: { result: false } },
+      { status: "fulfilled", value: { result: true } },
+      { status: "fulfilled", value: { result: 4 } },
+    ]);
+  });
+
+  test("should settle failing programs on their own", async () => {
+    const programs = await Promise.all(
+      ["request.missing", "request.path"].map((expr) => env.compile(expr)),
+    );
+
+    const outcomes = await Program.evalAll(programs, {
+      request: { path: "/" },
+    });
+    expect(outcomes[0].status).toBe("rejected");
+    expect(outcomes[0].reason).toBeInstanceOf(EvaluationError);
+    expect(outcomes[1]).toEqual({
+      status: "fulfilled",
+      value: { result: "/" },
+    });
+
+    const withErrorValues = await Program.evalAll(
+      programs,
+      { request: { path: "/" } },
+      { errorValues: true },
+    );
+    expect(withErrorValues[0].value.errorValue.message).toMatch(/no such key/);
+  });
+
+  test("should reject destroyed programs", async () => {
+    const program = await env.compile("true");
+    program.destroy();
+    await expect(Program.evalAll([program], {})).rejects.toThrow(/destroyed/);
+  });
+});
@@ -0,0 +1,45 @@
+import { Env } from "../dist/index.js";
+
+describe("Repeated evaluation", () => {
+  test("should evaluate a program n times and report timings", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "list<double>" }],
+    });
+    const program = await env.compile("x.map(i, i * 2.0)");
+
+    const { result, stats } = await program.evalRepeated(100, {
+      x: [1, 2, 3],
+    });
+    expect(result).toEqual([2, 4, 6]);
+    expect(stats.iterations).toBe(100);
+    expect(stats.minMs).toBeLessThanOrEqual(stats.p50Ms);
+    expect(stats.p50Ms).toBeLessThanOrEqual(stats.p95Ms);
+    expect(stats.p95Ms).toBeLessThanOrEqual(stats.maxMs);
+    expect(stats.totalMs).toBeGreaterThanOrEqual(stats.maxMs);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should stop at the first failed evaluation", async () => {
+    const env = await Env.new();
+    const program = await env.compile("1 / 0");
+
+    await expect(program.evalRepeated(10)).rejects.toThrow("division by zero");
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject a non-positive count", async () => {
+    const env = await Env.new();
+    const program = await env.compile("1");
+
+    await expect(program.evalRepeated(0)).rejects.toThrow(
+      "n must be positive",
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+});
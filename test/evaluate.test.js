@@ -0,0 +1,67 @@
+import { Options, evaluate } from "../dist/index.js";
+import { shout } from "./helpers.js";
+
+describe("One-off evaluation", () => {
+  test("should infer declarations from the variables", async () => {
+    const result = await evaluate(
+      "user.age >= limit && user.name.startsWith('A') && tags.size() == 2",
+      { user: { age: 30, name: "Alice" }, limit: 18, tags: ["a", "b"] },
+    );
+
+    expect(result).toEqual({
+      success: true,
+      result: true,
+      type: "bool",
+      issues: [],
+    });
+  });
+
+  test("should infer ints and doubles from numbers", async () => {
+    const ints = await evaluate("count / 2", { count: 5 });
+    expect(ints.result).toBe(2);
+    expect(ints.type).toBe("int");
+
+    const doubles = await evaluate("values[0] + values[1]", {
+      values: [1, 2.5],
+    });
+    expect(doubles.result).toBe(3.5);
+    expect(doubles.type).toBe("double");
+  });
+
+  test("should report compilation issues without rejecting", async () => {
+    const result = await evaluate("usr.age > 1", { user: { age: 1 } });
+
+    expect(result.success).toBe(false);
+    expect(result.error).toContain("undeclared reference to 'usr'");
+    expect(result.issues[0].didYouMean).toEqual(["user"]);
+  });
+
+  test("should report evaluation errors with the type", async () => {
+    const result = await evaluate("1 / x", { x: 0 });
+
+    expect(result.success).toBe(false);
+    expect(result.type).toBe("int");
+    expect(result.error).toContain("division by zero");
+  });
+
+  test("should accept declarations, functions and options", async () => {
+    const result = await evaluate(
+      "t > timestamp('2020-01-01T00:00:00Z') ? shout(m.?name.orValue('x')) : ''",
+      { t: "2024-01-01T00:00:00Z", m: {} },
+      {
+        variables: [{ name: "t", type: "timestamp" }],
+        functions: [shout()],
+        options: [Options.optionalTypes()],
+      },
+    );
+
+    expect(result.success).toBe(true);
+    expect(result.result).toBe("X");
+  });
+
+  test("should reject invalid options", async () => {
+    await expect(
+      evaluate("1", null, { libraries: ["no.such.library"] }),
+    ).rejects.toThrow();
+  });
+});
@@ -0,0 +1,62 @@
+import { Env } from "../dist/index.js";
+
+describe("Expected types", () => {
+  let env;
+
+  beforeEach(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "x", type: "int" },
+        { name: "m", type: "map<string, dyn>" },
+      ],
+    });
+  });
+
+  afterEach(() => {
+    env.destroy();
+  });
+
+  test("should compile expressions of the expected type", async () => {
+    const program = await env.compile("x > 10", { expectedType: "bool" });
+    expect(await program.eval({ x: 11 })).toBe(true);
+    program.destroy();
+  });
+
+  test("should fail on expressions of another type", async () => {
+    await expect(
+      env.compile("x + 10", { expectedType: "bool" }),
+    ).rejects.toThrow("expression has type int, expected bool");
+  });
+
+  test("should compare the parameters of list and map types", async () => {
+    const program = await env.compile("[x]", {
+      expectedType: { kind: "list", elementType: "int" },
+    });
+    program.destroy();
+
+    await expect(
+      env.compile("[x]", { expectedType: "list<string>" }),
+    ).rejects.toThrow("expression has type list(int), expected list(string)");
+  });
+
+  test("should accept expressions of type dyn", async () => {
+    const program = await env.compile("m.enabled", { expectedType: "bool" });
+    expect(await program.eval({ m: { enabled: true } })).toBe(true);
+    program.destroy();
+  });
+
+  test("should report an issue at the start of the expression", async () => {
+    const result = await env.compileDetailed("\n  (x) +\n 1", {
+      expectedType: "bool",
+    });
+
+    expect(result.success).toBe(false);
+    expect(result.issues).toEqual([
+      {
+        severity: "error",
+        message: "expression has type int, expected bool",
+        location: { line: 2, column: 2 },
+      },
+    ]);
+  });
+});
@@ -0,0 +1,93 @@
+import { Env } from "../dist/index.js";
+
+describe("Explain", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "a", type: "bool" },
+        { name: "b", type: "bool" },
+        { name: "c", type: "bool" },
+        { name: "user", type: "map<string, dyn>" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should explain the operands of logical operators", async () => {
+    const program = await env.compile("a && (b || c)");
+    const { result, explanation } = await program.explain({
+      a: true,
+      b: false,
+      c: true,
+    });
+
+    expect(result).toBe(true);
+    expect(explanation).toMatchObject({
+      expr: "a && (b || c)",
+      outcome: "true",
+      operator: "&&",
+      operands: [
+        { expr: "a", outcome: "true", value: true },
+        {
+          expr: "b || c",
+          outcome: "true",
+          operator: "||",
+          operands: [
+            { expr: "b", outcome: "false", value: false },
+            { expr: "c", outcome: "true", value: true },
+          ],
+        },
+      ],
+    });
+  });
+
+  test("should flatten chains and mark skipped operands", async () => {
+    const program = await env.compile("a && b && c");
+    const { explanation } = await program.explain({
+      a: false,
+      b: true,
+      c: true,
+    });
+
+    expect(explanation.operands.map((operand) => operand.outcome)).toEqual([
+      "false",
+      "skipped",
+      "skipped",
+    ]);
+  });
+
+  test("should explain conditionals with their values", async () => {
+    const program = await env.compile('user.age >= 18 ? "adult" : "minor"');
+    const { result, explanation } = await program.explain({
+      user: { age: 12 },
+    });
+
+    expect(result).toBe("minor");
+    expect(explanation.operator).toBe("?:");
+    expect(explanation.operands).toMatchObject([
+      { expr: "user.age >= 18", outcome: "false" },
+      { expr: '"adult"', outcome: "skipped" },
+      { expr: '"minor"', outcome: "value", value: "minor" },
+    ]);
+  });
+
+  test("should explain errors", async () => {
+    const program = await env.compile("user.missing || a");
+    const { result, explanation } = await program.explain({
+      user: {},
+      a: true,
+    });
+
+    expect(result).toBe(true);
+    expect(explanation.operands[0]).toMatchObject({
+      expr: "user.missing",
+      outcome: "error",
+    });
+    expect(explanation.operands[0].error).toContain("no such key");
+  });
+});
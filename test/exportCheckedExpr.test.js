@@ -0,0 +1,52 @@
+import { Env } from "../dist/index.js";
+
+describe("Checked expression export", () => {
+  test("should export the checked expression in every format", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const program = await env.compile("x > 10.0");
+
+    const binary = await program.exportCheckedExpr();
+    expect(binary).toBeInstanceOf(Uint8Array);
+    expect(binary.length).toBeGreaterThan(0);
+
+    const json = JSON.parse(await program.exportCheckedExpr("json"));
+    expect(Object.values(json.referenceMap)).toContainEqual({
+      overloadId: ["greater_double"],
+    });
+    expect(json.expr.callExpr.function).toBe("_>_");
+
+    const textproto = await program.exportCheckedExpr("textproto");
+    expect(textproto).toContain('overload_id:"greater_double"');
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should fold constants into the exported expression", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+      constants: [{ name: "LIMIT", type: "double", value: 10 }],
+    });
+    const program = await env.compile("x > LIMIT");
+
+    const json = JSON.parse(await program.exportCheckedExpr("json"));
+    expect(json.expr.callExpr.args[1].constExpr).toEqual({ doubleValue: 10 });
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject unknown formats", async () => {
+    const env = await Env.new();
+    const program = await env.compile("1");
+
+    await expect(program.exportCheckedExpr("yaml")).rejects.toThrow(
+      'invalid checked expression format "yaml"',
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+});
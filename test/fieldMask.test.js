@@ -0,0 +1,74 @@
+import { Env } from "../dist/index.js";
+
+describe("Field masks", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "request", type: "dyn" },
+        { name: "items", type: { kind: "list", elementType: "dyn" } },
+        { name: "owner", type: "string" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should list the paths an expression reads", async () => {
+    const program = await env.compile(
+      'request.user.id == owner && request.headers["x-tenant"] == "acme"',
+      { fieldMask: true },
+    );
+    expect(program.fieldMask).toEqual([
+      ["owner"],
+      ["request", "headers", "x-tenant"],
+      ["request", "user", "id"],
+    ]);
+  });
+
+  test("should keep variables used whole", async () => {
+    const program = await env.compile(
+      "items.all(request, request.ok) && size(request) > 1 && request.user.id != ''",
+      { fieldMask: true },
+    );
+    expect(program.fieldMask).toEqual([["items"], ["request"]]);
+  });
+
+  test("should prune variables without changing the result", async () => {
+    const program = await env.compile(
+      'request.user.id == owner && request.headers["x-tenant"] == "acme"',
+      { fieldMask: true },
+    );
+    const vars = {
+      owner: "u1",
+      request: {
+        user: { id: "u1", name: "Ada", roles: ["admin"] },
+        headers: { "x-tenant": "acme", accept: "*/*" },
+        body: "x".repeat(1000),
+      },
+      items: [1, 2, 3],
+    };
+
+    const pruned = program.pruneVars(vars);
+    expect(pruned).toEqual({
+      owner: "u1",
+      request: {
+        user: { id: "u1" },
+        headers: { "x-tenant": "acme" },
+      },
+    });
+    expect(vars.request.body).toHaveLength(1000);
+    expect(await program.eval(pruned)).toBe(await program.eval(vars));
+  });
+
+  test("should require a field mask to prune", async () => {
+    const program = await env.compile("owner == 'u1'");
+    expect(program.fieldMask).toBeUndefined();
+    expect(() => program.pruneVars({ owner: "u1" })).toThrow(
+      /not compiled with fieldMask/,
+    );
+  });
+});
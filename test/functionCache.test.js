@@ -0,0 +1,116 @@
+import { Env, CELFunction, setLifecycleListener } from "../dist/index.js";
+import { shout } from "./helpers.js";
+
+describe("Cacheable functions", () => {
+  test("should call a cacheable function once per distinct arguments", async () => {
+    const calls = [];
+    const env = await Env.new({
+      variables: [{ name: "ids", type: "list<string>" }],
+      functions: [
+        CELFunction.new("getUser")
+          .param("id", "string")
+          .returns("dyn")
+          .cacheable()
+          .implement((id) => {
+            calls.push(id);
+            return { id, active: id !== "u3" };
+          }),
+      ],
+    });
+
+    const program = await env.compile(
+      "ids.all(i, getUser(i).active && getUser(i).id == i)",
+    );
+    expect(await program.eval({ ids: ["u1", "u2", "u1", "u2"] })).toBe(true);
+    expect(calls).toEqual(["u1", "u2"]);
+
+    // Results aren't kept across evaluations without a cache size
+    expect(await program.eval({ ids: ["u1", "u3"] })).toBe(false);
+    expect(calls).toEqual(["u1", "u2", "u1", "u3"]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should keep results across evaluations up to the cache size", async () => {
+    let calls = 0;
+    const env = await Env.new({
+      variables: [{ name: "ids", type: "list<string>" }],
+      functions: [shout({ cacheable: 2, onCall: () => calls++ })],
+    });
+
+    const program = await env.compile("ids.map(i, shout(i))");
+    expect(await program.eval({ ids: ["a", "b", "a"] })).toEqual([
+      "A",
+      "B",
+      "A",
+    ]);
+    expect(calls).toBe(2);
+    expect(await program.eval({ ids: ["a", "b"] })).toEqual(["A", "B"]);
+    expect(calls).toBe(2);
+
+    // "c" evicts "a", the least recently used result
+    const events = [];
+    await setLifecycleListener((event) => events.push(event));
+    expect(await program.eval({ ids: ["c", "a"] })).toEqual(["C", "A"]);
+    await setLifecycleListener(null);
+    expect(calls).toBe(4);
+    expect(events).toContainEqual({ type: "cacheEvicted", cache: "function" });
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should not cache failed calls", async () => {
+    let calls = 0;
+    const env = await Env.new({
+      variables: [{ name: "ids", type: "list<string>" }],
+      functions: [
+        CELFunction.new("check")
+          .param("id", "string")
+          .returns("bool")
+          .cacheable(10)
+          .implement(() => {
+            calls++;
+            throw new Error("unavailable");
+          }),
+      ],
+    });
+
+    const program = await env.compile("ids.exists(i, check(i))");
+    await expect(program.eval({ ids: ["a", "a"] })).rejects.toThrow(
+      "unavailable",
+    );
+    expect(calls).toBe(2);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should trace calls answered from the cache", async () => {
+    const env = await Env.new({
+      variables: [{ name: "id", type: "string" }],
+      functions: [
+        CELFunction.new("lookup")
+          .param("id", "string")
+          .returns("string")
+          .cacheable()
+          .implement((id) => `user:${id}`),
+      ],
+    });
+
+    const program = await env.compile("lookup(id) + lookup(id)");
+    const { result, functionTrace } = await program.evalDetailed(
+      { id: "a" },
+      { traceFunctions: true },
+    );
+    expect(result).toBe("user:auser:a");
+    expect(functionTrace.map((call) => call.cached === true)).toEqual([
+      false,
+      true,
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+});
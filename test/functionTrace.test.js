@@ -0,0 +1,68 @@
+import { Env, CELFunction } from "../dist/index.js";
+
+describe("Function tracing", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "id", type: "string" }],
+      functions: [
+        CELFunction.new("lookupUser")
+          .param("id", "string")
+          .returns("dyn")
+          .implement((id) =>
+            id === "u1"
+              ? { role: "viewer" }
+              : { __celError: { code: "not_found", message: `no user ${id}` } },
+          ),
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should record custom function calls", async () => {
+    const program = await env.compile('lookupUser(id).role == "admin"');
+    const { result, functionTrace } = await program.evalDetailed(
+      { id: "u1" },
+      { traceFunctions: true },
+    );
+    expect(result).toBe(false);
+    expect(functionTrace).toEqual([
+      {
+        function: "lookupUser",
+        args: ["u1"],
+        result: { role: "viewer" },
+        durationMs: expect.any(Number),
+      },
+    ]);
+    program.destroy();
+  });
+
+  test("should not trace without traceFunctions", async () => {
+    const program = await env.compile("lookupUser(id)");
+    const { functionTrace } = await program.evalDetailed({ id: "u1" });
+    expect(functionTrace).toBeUndefined();
+    program.destroy();
+  });
+
+  test("should attach the trace to evaluation errors", async () => {
+    const program = await env.compile('lookupUser(id).role == "admin"');
+    await expect(
+      program.evalDetailed({ id: "u2" }, { traceFunctions: true }),
+    ).rejects.toMatchObject({
+      name: "EvaluationError",
+      functionTrace: [
+        {
+          function: "lookupUser",
+          args: ["u2"],
+          error: "function lookupUser: no user u2",
+          code: "not_found",
+        },
+      ],
+    });
+    program.destroy();
+  });
+});
@@ -0,0 +1,57 @@
+import { Env, CELFunction, defineGlobalFunction } from "../dist/index.js";
+import { shout } from "./helpers.js";
+
+describe("Global functions", () => {
+  test("should be available in environments created afterwards", async () => {
+    await defineGlobalFunction(
+      CELFunction.new("triple")
+        .param("x", "double")
+        .returns("double")
+        .implement((x) => x * 3),
+    );
+
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const program = await env.compile("triple(x)");
+    expect(await program.eval({ x: 2 })).toBe(6);
+    env.destroy();
+  });
+
+  test("should combine with environment functions", async () => {
+    await defineGlobalFunction(shout());
+
+    const env = await Env.new({
+      variables: [{ name: "s", type: "string" }],
+      functions: [
+        CELFunction.new("exclaim")
+          .param("s", "string")
+          .returns("string")
+          .implement((s) => `${s}!`),
+      ],
+    });
+    const program = await env.compile("exclaim(shout(s))");
+    expect(await program.eval({ s: "hi" })).toBe("HI!");
+
+    // Destroying the environment keeps global implementations registered
+    env.destroy();
+    const other = await Env.new({
+      variables: [{ name: "s", type: "string" }],
+    });
+    const again = await other.compile("shout(s)");
+    expect(await again.eval({ s: "ok" })).toBe("OK");
+    other.destroy();
+  });
+
+  test("should reject conflicting definitions", async () => {
+    const halve = CELFunction.new("halve")
+      .param("x", "double")
+      .returns("double")
+      .implement((x) => x / 2);
+
+    await defineGlobalFunction(halve);
+    await expect(defineGlobalFunction(halve)).rejects.toThrow(
+      /invalid global function halve/,
+    );
+  });
+});
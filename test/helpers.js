@@ -0,0 +1,48 @@
+import { generateKeyPairSync, sign } from "node:crypto";
+import { CELFunction } from "../dist/index.js";
+
+/**
+ * Defines a custom function taking and returning a value of one type, made
+ * pure or cacheable (with an optional cache size) when asked, and calling
+ * onCall with the argument of every call it receives
+ */
+function unaryFunction(name, type, impl, { pure, cacheable, onCall } = {}) {
+  let fn = CELFunction.new(name).param("value", type).returns(type);
+  if (pure) {
+    fn = fn.pure();
+  }
+  if (cacheable) {
+    fn = fn.cacheable(cacheable === true ? undefined : cacheable);
+  }
+  return fn.implement((value) => {
+    onCall?.(value);
+    return impl(value);
+  });
+}
+
+/** Defines twice(value), doubling a number of the given type */
+export function twice(type = "int", options) {
+  return unaryFunction("twice", type, (n) => n * 2, options);
+}
+
+/** Defines shout(value), upper-casing a string */
+export function shout(options) {
+  return unaryFunction("shout", "string", (s) => s.toUpperCase(), options);
+}
+
+/**
+ * Generates an Ed25519 key pair, returning the raw public key the module
+ * verifies signatures with and a function signing data with the private key
+ * into a base64 signature
+ */
+export function signingKeys() {
+  const { publicKey, privateKey } = generateKeyPairSync("ed25519");
+  // The raw key is the end of its SPKI encoding
+  const rawKey = new Uint8Array(
+    publicKey.export({ format: "der", type: "spki" }).subarray(-32),
+  );
+  return {
+    rawKey,
+    sign: (data) => sign(null, data, privateKey).toString("base64"),
+  };
+}
@@ -0,0 +1,73 @@
+import { Env } from "../dist/index.js";
+
+describe("Large integers", () => {
+  test("should round integers beyond 2^53 by default", async () => {
+    const env = await Env.new();
+    const program = await env.compile("9007199254740993");
+
+    expect(await program.eval()).toBe(9007199254740992);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should return integers beyond 2^53 as strings or BigInts", async () => {
+    const env = await Env.new();
+    const program = await env.compile(
+      "[9007199254740993, 18446744073709551615u, 1]",
+    );
+
+    expect(await program.eval(null, { integers: "string" })).toEqual([
+      "9007199254740993",
+      "18446744073709551615",
+      1,
+    ]);
+    expect(await program.eval(null, { integers: "bigint" })).toEqual([
+      9007199254740993n,
+      18446744073709551615n,
+      1,
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should read integer strings and BigInts in variables", async () => {
+    const env = await Env.new({
+      variables: [
+        { name: "id", type: "int" },
+        { name: "ids", type: "list<uint>" },
+      ],
+      integers: "bigint",
+    });
+    const program = await env.compile("[id + 1, ids[0]]");
+
+    expect(
+      await program.eval({
+        id: 9007199254740993n,
+        ids: ["18446744073709551615"],
+      }),
+    ).toEqual([9007199254740994n, 18446744073709551615n]);
+    expect(
+      await program.eval(
+        { id: "9007199254740993", ids: [1n] },
+        { integers: "string" },
+      ),
+    ).toEqual(["9007199254740994", 1]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject unknown integers modes", async () => {
+    const env = await Env.new();
+    const program = await env.compile("1");
+
+    await expect(program.eval(null, { integers: "hex" })).rejects.toThrow(
+      'unknown integers mode "hex"',
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+});
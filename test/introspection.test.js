@@ -0,0 +1,76 @@
+import { describeOptions, getCapabilities } from "../dist/index.js";
+
+describe("Module introspection", () => {
+  describe("getCapabilities", () => {
+    test("should report module and cel-go versions", async () => {
+      const caps = await getCapabilities();
+      expect(typeof caps.version).toBe("string");
+      expect(caps.celGoVersion).toMatch(/^v\d+\.\d+\.\d+/);
+    });
+
+    test("should list JSON-configurable options as a subset of all options", async () => {
+      const caps = await getCapabilities();
+      expect(caps.jsonOptions).toContain("OptionalTypes");
+      expect(caps.jsonOptions).toContain("CrossTypeNumericComparisons");
+      for (const name of caps.jsonOptions) {
+        expect(caps.options).toContain(name);
+      }
+    });
+
+    test("should report supported type kinds and wire formats", async () => {
+      const caps = await getCapabilities();
+      expect(caps.typeKinds).toEqual(
+        expect.arrayContaining([
+          "int",
+          "string",
+          "list",
+          "map",
+          "object",
+          "list<T>",
+          "map<K, V>",
+          "decimal",
+        ]),
+      );
+      expect(caps.wireFormats).toContain("json");
+    });
+  });
+
+  describe("describeOptions", () => {
+    test("should describe every JSON-configurable option", async () => {
+      const caps = await getCapabilities();
+      const options = await describeOptions();
+      expect(options.map((option) => option.name)).toEqual(caps.jsonOptions);
+    });
+
+    test("should expose parameter schemas with defaults", async () => {
+      const options = await describeOptions();
+      const crossType = options.find(
+        (option) => option.name === "CrossTypeNumericComparisons",
+      );
+
+      expect(crossType.description).toContain("CrossTypeNumericComparisons");
+      expect(crossType.schema.type).toBe("object");
+      expect(crossType.params).toEqual([
+        expect.objectContaining({
+          name: "enabled",
+          type: "boolean",
+          default: true,
+          required: false,
+        }),
+      ]);
+    });
+
+    test("should mark required parameters", async () => {
+      const options = await describeOptions();
+      const validators = options.find(
+        (option) => option.name === "ASTValidators",
+      );
+      const ids = validators.params.find(
+        (param) => param.name === "validatorFunctionIds",
+      );
+
+      expect(ids.required).toBe(true);
+      expect(validators.schema.required).toEqual(["validatorFunctionIds"]);
+    });
+  });
+});
@@ -0,0 +1,130 @@
+import { Env, Options } from "../dist/index.js";
+
+const schema = {
+  type: "object",
+  $defs: {
+    Node: {
+      type: "object",
+      properties: {
+        name: { type: "string" },
+        children: { type: "array", items: { $ref: "#/$defs/Node" } },
+      },
+    },
+  },
+  properties: {
+    user: {
+      type: "object",
+      properties: {
+        name: { type: "string" },
+        "x-id": { type: "integer" },
+        created: { type: "string", format: "date-time" },
+        avatar: { type: "string", contentEncoding: "base64" },
+        tags: { type: "array", items: { type: "string" } },
+        scores: { type: "object", additionalProperties: { type: "number" } },
+      },
+    },
+    tree: { $ref: "#/$defs/Node" },
+    count: { type: ["integer", "null"] },
+    extra: { oneOf: [{ type: "string" }, { type: "integer" }] },
+  },
+};
+
+const data = {
+  user: {
+    name: "Ada",
+    "x-id": 7,
+    created: "2024-01-02T03:04:05Z",
+    avatar: "aGk=",
+    tags: ["admin"],
+    scores: { math: 1.5 },
+  },
+  tree: { name: "root", children: [{ name: "leaf" }] },
+  count: 2,
+  extra: "anything",
+};
+
+describe("JSON Schema environments", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.fromJSONSchema(schema);
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should declare properties as typed variables", async () => {
+    const result = await env.typecheck(
+      "user.created.getFullYear() + user.x_id + count",
+    );
+    expect(result.type).toBe("int");
+  });
+
+  test("should reject fields missing from the schema", async () => {
+    await expect(env.compile("user.missing")).rejects.toThrow(
+      "undefined field 'missing'",
+    );
+  });
+
+  test("should evaluate against data matching the schema", async () => {
+    const program = await env.compile(
+      "[user.name, string(user.avatar), user.tags[0], " +
+        "string(user.scores['math']), tree.children[0].name, " +
+        "string(user.created.getMonth()), string(extra)]",
+    );
+    const result = await program.evalWithContextMessage(
+      Env.JSON_SCHEMA_TYPE_NAME,
+      data,
+    );
+    expect(result).toEqual([
+      "Ada",
+      "hi",
+      "admin",
+      "1.5",
+      "leaf",
+      "0",
+      "anything",
+    ]);
+    program.destroy();
+  });
+
+  test("should accept the schema as a JSON string", async () => {
+    const fromString = await Env.fromJSONSchema(JSON.stringify(schema));
+    const result = await fromString.typecheck("tree.children[0].children");
+    expect(result.type).toEqual({ kind: "list", elementType: "dyn" });
+    await expect(fromString.compile("tree.children[0].size")).rejects.toThrow(
+      "undefined field 'size'",
+    );
+    fromString.destroy();
+  });
+
+  test("should apply environment options", async () => {
+    const withOptions = await Env.fromJSONSchema(schema, {
+      options: [Options.optionalTypes()],
+    });
+    const program = await withOptions.compile("user.?name.orValue('none')");
+    const result = await program.evalWithContextMessage(
+      Env.JSON_SCHEMA_TYPE_NAME,
+      data,
+    );
+    expect(result).toBe("Ada");
+    program.destroy();
+    withOptions.destroy();
+  });
+
+  test("should reject schemas that don't describe an object", async () => {
+    await expect(Env.fromJSONSchema({ type: "string" })).rejects.toThrow(
+      "the root schema must be an object with properties",
+    );
+  });
+
+  test("should report unresolved references", async () => {
+    await expect(
+      Env.fromJSONSchema({
+        type: "object",
+        properties: { a: { $ref: "#/$defs/Missing" } },
+      }),
+    ).rejects.toThrow("#/properties/a: reference #/$defs/Missing not found");
+  });
+});
@@ -0,0 +1,43 @@
+import { Env, Options, registerLibrary } from "../dist/index.js";
+import { shout } from "./helpers.js";
+
+describe("Libraries", () => {
+  beforeAll(async () => {
+    await registerLibrary("acme.strings", {
+      variables: [{ name: "region", type: "string" }],
+      functions: [shout()],
+      options: [Options.optionalTypes()],
+    });
+  });
+
+  test("should include a library's declarations by name", async () => {
+    const env = await Env.new({
+      variables: [{ name: "name", type: "string" }],
+      libraries: ["acme.strings"],
+    });
+    const program = await env.compile(
+      'shout(name) + "@" + region + optional.of("!").orValue("")',
+    );
+    expect(await program.eval({ name: "ada", region: "eu" })).toBe("ADA@eu!");
+    env.destroy();
+  });
+
+  test("should keep library functions after an environment is destroyed", async () => {
+    const first = await Env.new({ libraries: ["acme.strings"] });
+    first.destroy();
+
+    const second = await Env.new({ libraries: ["acme.strings"] });
+    const program = await second.compile('shout("ok")');
+    expect(await program.eval()).toBe("OK");
+    second.destroy();
+  });
+
+  test("should reject unknown and duplicate libraries", async () => {
+    await expect(Env.new({ libraries: ["acme.missing"] })).rejects.toThrow(
+      /library not found: acme.missing/,
+    );
+    await expect(registerLibrary("acme.strings", {})).rejects.toThrow(
+      /library already registered: acme.strings/,
+    );
+  });
+});
@@ -0,0 +1,73 @@
+import { Env, setLifecycleListener } from "../dist/index.js";
+import { twice } from "./helpers.js";
+
+describe("setLifecycleListener", () => {
+  afterEach(async () => {
+    await setLifecycleListener(null);
+  });
+
+  test("should report environments and programs", async () => {
+    const events = [];
+    await setLifecycleListener((event) => events.push(event));
+
+    const env = await Env.new({
+      variables: [{ name: "x", type: "int" }],
+    });
+    const program = await env.compile("x + 1");
+    program.destroy();
+    env.destroy();
+
+    const envID = events[0].envID;
+    const programID = events[1].programID;
+    expect(events).toContainEqual({ type: "envCreated", envID });
+    expect(events).toContainEqual({
+      type: "programCompiled",
+      programID,
+      envID,
+    });
+    expect(events).toContainEqual({
+      type: "programDestroyed",
+      programID,
+      envID,
+    });
+    expect(events).toContainEqual({ type: "envDestroyed", envID });
+  });
+
+  test("should report unregistered functions", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "int" }],
+      functions: [twice()],
+    });
+
+    const events = [];
+    await setLifecycleListener((event) => events.push(event));
+    env.destroy();
+
+    expect(
+      events.some((event) => event.type === "functionUnregistered"),
+    ).toBe(true);
+  });
+
+  test("should report evicted shared environments", async () => {
+    const events = [];
+    await setLifecycleListener((event) => events.push(event));
+
+    const env = await Env.new({
+      variables: [{ name: "lifecycle", type: "string" }],
+    });
+    env.destroy();
+
+    expect(events).toContainEqual({ type: "cacheEvicted", cache: "envPool" });
+  });
+
+  test("should stop reporting once removed", async () => {
+    const events = [];
+    await setLifecycleListener((event) => events.push(event));
+    await setLifecycleListener(null);
+
+    const env = await Env.new({});
+    env.destroy();
+
+    expect(events).toEqual([]);
+  });
+});
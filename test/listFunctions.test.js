@@ -0,0 +1,47 @@
+import { Env } from "../dist/index.js";
+import { shout } from "./helpers.js";
+
+describe("Function documentation", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({ functions: [shout()] });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should document standard functions", async () => {
+    const functions = await env.listFunctions({ query: "startsWith" });
+
+    expect(functions).toHaveLength(1);
+    expect(functions[0].name).toBe("startsWith");
+    expect(functions[0].description).toContain("starts with");
+    expect(functions[0].signatures).toEqual([
+      "string.startsWith(string) -> bool",
+    ]);
+    expect(functions[0].examples.length).toBeGreaterThan(0);
+  });
+
+  test("should list macros and custom functions, not operators", async () => {
+    const functions = await env.listFunctions();
+    const names = functions.map((f) => f.name);
+
+    expect(names).toEqual(expect.arrayContaining(["size", "shout", "all"]));
+    expect(names).not.toContain("_+_");
+    expect(functions.find((f) => f.name === "all").macro).toBe(true);
+    expect(functions.find((f) => f.name === "shout")).toEqual({
+      name: "shout",
+      description: "",
+      signatures: ["shout(string) -> string"],
+      examples: [],
+    });
+  });
+
+  test("should rank name matches before description matches", async () => {
+    const functions = await env.listFunctions({ query: "SIZE" });
+
+    expect(functions[0].name).toBe("size");
+  });
+});
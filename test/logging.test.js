@@ -0,0 +1,75 @@
+import { Env, Options, setLogger } from "../dist/index.js";
+
+describe("setLogger", () => {
+  afterEach(async () => {
+    await setLogger(null);
+  });
+
+  test("should report unknown type names", async () => {
+    const entries = [];
+    await setLogger((entry) => entries.push(entry));
+
+    const env = await Env.new({
+      variables: [{ name: "x", type: "strng" }],
+    });
+
+    expect(entries).toContainEqual({
+      level: "warn",
+      message: "unknown type name, using dyn",
+      fields: { typeName: "strng" },
+    });
+
+    env.destroy();
+  });
+
+  test("should report ignored option params", async () => {
+    const entries = [];
+    await setLogger((entry) => entries.push(entry));
+
+    const env = await Env.new({
+      options: [{ type: "OptionalTypes", params: { bogus: true } }],
+    });
+
+    expect(entries).toContainEqual({
+      level: "warn",
+      message: "ignoring unknown option param",
+      fields: { option: "OptionalTypes", param: "bogus" },
+    });
+
+    env.destroy();
+  });
+
+  test("should skip entries below the level", async () => {
+    const entries = [];
+    await setLogger((entry) => entries.push(entry), "error");
+
+    const env = await Env.new({
+      variables: [{ name: "x", type: "strng" }],
+      options: [Options.optionalTypes()],
+    });
+
+    expect(entries).toEqual([]);
+
+    env.destroy();
+  });
+
+  test("should reject unknown levels", async () => {
+    await expect(setLogger(() => {}, "verbose")).rejects.toThrow(
+      "unknown log level",
+    );
+  });
+
+  test("should stop logging once removed", async () => {
+    const entries = [];
+    await setLogger((entry) => entries.push(entry));
+    await setLogger(null);
+
+    const env = await Env.new({
+      variables: [{ name: "x", type: "strng" }],
+    });
+
+    expect(entries).toEqual([]);
+
+    env.destroy();
+  });
+});
@@ -0,0 +1,60 @@
+import { Env } from "../dist/index.js";
+
+describe("Map keys", () => {
+  test("should stringify map keys by default", async () => {
+    const env = await Env.new();
+    const program = await env.compile('{1: "a", 2: "b"}');
+
+    expect(await program.eval()).toEqual({ 1: "a", 2: "b" });
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should return maps with non-string keys as entries", async () => {
+    const env = await Env.new();
+    const program = await env.compile(
+      '{"nested": {2u: true, 1u: false}, "list": [{true: 1, false: 0}]}',
+    );
+
+    const result = await program.eval(null, { mapKeys: "entries" });
+    expect(result).toEqual({
+      nested: {
+        "@type": "map",
+        entries: [
+          [1, false],
+          [2, true],
+        ],
+      },
+      list: [
+        {
+          "@type": "map",
+          entries: [
+            [false, 0],
+            [true, 1],
+          ],
+        },
+      ],
+    });
+
+    const { result: detailed } = await program.evalDetailed(null, {
+      mapKeys: "entries",
+    });
+    expect(detailed).toEqual(result);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject unknown map key modes", async () => {
+    const env = await Env.new();
+    const program = await env.compile("{1: 1}");
+
+    await expect(program.eval(null, { mapKeys: "typed" })).rejects.toThrow(
+      "unknown mapKeys mode",
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+});
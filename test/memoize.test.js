@@ -0,0 +1,86 @@
+import { Env } from "../dist/index.js";
+import { twice } from "./helpers.js";
+
+describe("Memoization", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "price", type: "double" },
+        { name: "qty", type: "double" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should return cached results for repeated inputs", async () => {
+    const program = await env.compile("price * qty", { memoize: 10 });
+
+    const first = await program.evalDetailed(
+      { price: 2.5, qty: 4 },
+      { metrics: true },
+    );
+    expect(first.result).toBe(10);
+    expect(first.metrics.memoMs).toEqual(expect.any(Number));
+
+    const second = await program.evalDetailed(
+      { qty: 4, price: 2.5 },
+      { metrics: true },
+    );
+    expect(second.result).toBe(10);
+    expect(second.metrics.evalMs).toBeGreaterThanOrEqual(0);
+
+    expect(await program.eval({ price: 3, qty: 4 })).toBe(12);
+    program.destroy();
+  });
+
+  test("should evict the least recently used result", async () => {
+    const program = await env.compile("price + qty", { memoize: 1 });
+    expect(await program.eval({ price: 1, qty: 1 })).toBe(2);
+    expect(await program.eval({ price: 2, qty: 2 })).toBe(4);
+    expect(await program.eval({ price: 1, qty: 1 })).toBe(2);
+    program.destroy();
+  });
+
+  test("should not cache evaluation errors", async () => {
+    const program = await env.compile("int(price) > 0", { memoize: 10 });
+    await expect(program.eval({ price: 1e300 })).rejects.toThrow();
+    await expect(program.eval({ price: 1e300 })).rejects.toThrow();
+    program.destroy();
+  });
+
+  test("should reject memoizing programs that call custom functions", async () => {
+    const fnEnv = await Env.new({
+      functions: [twice()],
+    });
+
+    await expect(fnEnv.compile("twice(2)", { memoize: 10 })).rejects.toThrow(
+      "memoization requires a program that doesn't call impure custom functions",
+    );
+    const program = await fnEnv.compile("1 + 2", { memoize: 10 });
+    expect(await program.eval()).toBe(3);
+
+    program.destroy();
+    fnEnv.destroy();
+  });
+
+  test("should memoize programs that call pure custom functions", async () => {
+    let calls = 0;
+    const fnEnv = await Env.new({
+      variables: [{ name: "n", type: "double" }],
+      functions: [twice("double", { pure: true, onCall: () => calls++ })],
+    });
+
+    const program = await fnEnv.compile("twice(n)", { memoize: 10 });
+    expect(await program.eval({ n: 2 })).toBe(4);
+    expect(await program.eval({ n: 2 })).toBe(4);
+    expect(calls).toBe(1);
+
+    program.destroy();
+    fnEnv.destroy();
+  });
+});
@@ -0,0 +1,51 @@
+import { Env, configure, MemoryBudgetError } from "../dist/index.js";
+
+const digits = "[1, 2, 3, 4, 5, 6, 7, 8, 9, 10]";
+const expensive = `${digits}.map(a, ${digits}.map(b, ${digits}.map(c, string(a) + string(b) + string(c))))`;
+
+describe("Memory budgets", () => {
+  afterEach(async () => {
+    await configure({ evalMemoryLimitBytes: 0 });
+  });
+
+  test("should reject evaluations that exceed their budget", async () => {
+    const env = await Env.new();
+    const program = await env.compile(expensive);
+
+    const error = await program
+      .eval(null, { memoryLimitBytes: 10_000 })
+      .catch((err) => err);
+    expect(error).toBeInstanceOf(MemoryBudgetError);
+    expect(error.limitBytes).toBe(10_000);
+    expect(error.allocatedBytes).toBeGreaterThan(10_000);
+    expect(error.message).toContain("exceeded its memory budget");
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should apply the configured budget by default", async () => {
+    const env = await Env.new();
+    const program = await env.compile(expensive);
+    const cheap = await env.compile("1 + 1");
+
+    await configure({ evalMemoryLimitBytes: 10_000 });
+    await expect(program.eval()).rejects.toThrow(MemoryBudgetError);
+    expect(await cheap.eval()).toBe(2);
+
+    const { result } = await program.evalDetailed(null, {
+      memoryLimitBytes: 100_000_000,
+    });
+    expect(result).toHaveLength(10);
+
+    program.destroy();
+    cheap.destroy();
+    env.destroy();
+  });
+
+  test("should reject negative budgets", async () => {
+    await expect(configure({ evalMemoryLimitBytes: -1 })).rejects.toThrow(
+      "evalMemoryLimitBytes must not be negative",
+    );
+  });
+});
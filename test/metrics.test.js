@@ -0,0 +1,46 @@
+import { Env } from "../dist/index.js";
+import { twice } from "./helpers.js";
+
+describe("Metrics", () => {
+  test("should report compile phase timings when requested", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+
+    const result = await env.compileDetailed("x * 2.0", { metrics: true });
+    expect(result.success).toBe(true);
+    expect(result.metrics.parseMs).toBeGreaterThanOrEqual(0);
+    expect(result.metrics.checkMs).toBeGreaterThanOrEqual(0);
+    expect(result.metrics.programMs).toBeGreaterThanOrEqual(0);
+
+    const plain = await env.compileDetailed("x * 2.0");
+    expect(plain.metrics).toBeUndefined();
+
+    result.program.destroy();
+    plain.program.destroy();
+    env.destroy();
+  });
+
+  test("should report evaluation and callback timings", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+      functions: [twice("double")],
+    });
+    const program = await env.compile("twice(x) + twice(x)");
+
+    const { result, metrics } = await program.evalDetailed(
+      { x: 1 },
+      { metrics: true },
+    );
+    expect(result).toBe(4);
+    expect(metrics.callbackCount).toBe(2);
+    expect(metrics.callbackMs).toBeGreaterThanOrEqual(0);
+    expect(metrics.evalMs).toBeGreaterThanOrEqual(metrics.callbackMs);
+
+    const plain = await program.evalDetailed({ x: 1 });
+    expect(plain.metrics).toBeUndefined();
+
+    program.destroy();
+    env.destroy();
+  });
+});
@@ -0,0 +1,121 @@
+import { Env } from "../dist/index.js";
+
+describe("Mutation testing", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "user", type: "map<string, dyn>" },
+        { name: "a", type: "bool" },
+        { name: "b", type: "bool" },
+        { name: "c", type: "bool" },
+        { name: "name", type: "string" },
+      ],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should generate compiled mutants", async () => {
+    const { mutants, skipped } = await env.mutate("user.age >= 18 && !a");
+
+    expect(skipped).toBe(0);
+    expect(
+      mutants.map(({ expr, kind, description }) => ({
+        expr,
+        kind,
+        description,
+      })),
+    ).toEqual([
+      {
+        expr: "user.age >= 18 || !a",
+        kind: "operator",
+        description: "replaced && with ||",
+      },
+      {
+        expr: "!a",
+        kind: "clause",
+        description: "dropped the left operand of &&",
+      },
+      {
+        expr: "user.age >= 18",
+        kind: "clause",
+        description: "dropped the right operand of &&",
+      },
+      {
+        expr: "user.age > 18 && !a",
+        kind: "boundary",
+        description: "replaced >= with >",
+      },
+      {
+        expr: "user.age < 18 && !a",
+        kind: "operator",
+        description: "replaced >= with <",
+      },
+      {
+        expr: "user.age >= 17 && !a",
+        kind: "boundary",
+        description: "replaced 18 with 17",
+      },
+      {
+        expr: "user.age >= 19 && !a",
+        kind: "boundary",
+        description: "replaced 18 with 19",
+      },
+      {
+        expr: "user.age >= 18 && a",
+        kind: "operator",
+        description: "removed !",
+      },
+    ]);
+    expect(mutants[3].range).toEqual({ start: 9, end: 11 });
+
+    const vars = { user: { age: 18 }, a: false };
+    expect(await mutants[0].program.eval(vars)).toBe(true);
+    expect(await mutants[3].program.eval(vars)).toBe(false);
+
+    for (const mutant of mutants) {
+      mutant.program.destroy();
+    }
+  });
+
+  test("should keep the grouping of swapped operators", async () => {
+    const { mutants } = await env.mutate("a || b || c");
+
+    expect(mutants.map((mutant) => mutant.expr)).toContain("(a || b) && c");
+    for (const mutant of mutants) {
+      mutant.program.destroy();
+    }
+  });
+
+  test("should mutate within macros", async () => {
+    const { mutants } = await env.mutate("[1, 2].all(i, i > user.min)");
+
+    expect(mutants.map((mutant) => mutant.expr)).toEqual([
+      "[1, 2].all(i, i >= user.min)",
+      "[1, 2].all(i, i <= user.min)",
+    ]);
+    for (const mutant of mutants) {
+      mutant.program.destroy();
+    }
+  });
+
+  test("should skip mutants that don't compile", async () => {
+    const { mutants, skipped } = await env.mutate('name + "!" == "a!"');
+
+    expect(skipped).toBe(1);
+    expect(mutants.map((mutant) => mutant.expr)).toEqual([
+      'name + "!" != "a!"',
+    ]);
+    for (const mutant of mutants) {
+      mutant.program.destroy();
+    }
+  });
+
+  test("should reject expressions that don't compile", async () => {
+    await expect(env.mutate("a &&")).rejects.toThrow("compilation error");
+  });
+});
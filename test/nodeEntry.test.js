@@ -0,0 +1,55 @@
+import { init, instantiate } from "../dist/node.js";
+
+describe("Node.js entry", () => {
+  test("should evaluate without installing globals", async () => {
+    const cel = await instantiate();
+
+    const { envID, error } = cel.createEnv([{ name: "x", type: "double" }]);
+    expect(error).toBeNull();
+
+    const { programID } = cel.compileExpr(envID, "x * 2.0");
+    expect(cel.evalProgram(programID, { x: 21 }).result).toBe(42);
+
+    cel.destroyProgram(programID);
+    cel.destroyEnv(envID);
+  });
+
+  test("should share the instance returned by init", async () => {
+    const first = await init();
+    const second = await init();
+    expect(first).toBe(second);
+  });
+
+  test("should keep functions shared by environments until all are destroyed", async () => {
+    const cel = await instantiate();
+    cel.registerCELFunction("twice", (x) => x * 2);
+    const funcDefs = [
+      {
+        name: "twice",
+        params: [{ name: "x", type: "double" }],
+        returnType: "double",
+        implID: "twice",
+      },
+    ];
+
+    const first = cel.createEnv([{ name: "x", type: "double" }], funcDefs);
+    const { programID } = cel.compileExpr(first.envID, "twice(x)");
+    const second = cel.createEnv([{ name: "x", type: "double" }], funcDefs);
+    cel.destroyEnv(second.envID);
+    cel.destroyEnv(first.envID);
+
+    expect(cel.evalProgram(programID, { x: 21 }).result).toBe(42);
+
+    cel.destroyProgram(programID);
+  });
+
+  test("should keep instances independent", async () => {
+    const a = await instantiate();
+    const b = await instantiate();
+
+    const { envID } = a.createEnv([]);
+    expect(b.compileExpr(envID, "1 + 1").errorCode).toBe("stale_handle");
+
+    a.destroyEnv(envID);
+  });
+});
@@ -0,0 +1,61 @@
+import { Env, CELFunction } from "../dist/index.js";
+
+describe("Non-finite doubles", () => {
+  test("should return NaN and infinities as numbers by default", async () => {
+    const env = await Env.new();
+    const program = await env.compile("[0.0 / 0.0, 1.0 / 0.0, -1.0 / 0.0]");
+
+    const [nan, inf, negInf] = await program.eval();
+    expect(nan).toBeNaN();
+    expect(inf).toBe(Infinity);
+    expect(negInf).toBe(-Infinity);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should encode non-finite doubles as tagged values or strings", async () => {
+    const env = await Env.new();
+    const program = await env.compile("[0.0 / 0.0, 1.0 / 0.0, -1.0 / 0.0, 1.5]");
+
+    expect(await program.eval(null, { nonFinite: "tagged" })).toEqual([
+      { "@type": "double", value: "NaN" },
+      { "@type": "double", value: "Infinity" },
+      { "@type": "double", value: "-Infinity" },
+      1.5,
+    ]);
+    expect(await program.eval(null, { nonFinite: "string" })).toEqual([
+      "NaN",
+      "Infinity",
+      "-Infinity",
+      1.5,
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should decode tagged doubles in variables and function results", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+      functions: [
+        CELFunction.new("infinity")
+          .returns("double")
+          .implement(() => ({ "@type": "double", value: "-Infinity" })),
+      ],
+    });
+    const program = await env.compile("[x * 2.0, infinity()]");
+
+    const result = await program.eval(
+      { x: { "@type": "double", value: "Infinity" } },
+      { nonFinite: "tagged" },
+    );
+    expect(result).toEqual([
+      { "@type": "double", value: "Infinity" },
+      { "@type": "double", value: "-Infinity" },
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+});
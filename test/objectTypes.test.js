@@ -0,0 +1,100 @@
+import { Env, Options } from "../dist/index.js";
+
+const requestType = {
+  kind: "object",
+  fields: {
+    method: "string",
+    auth: {
+      kind: "object",
+      fields: {
+        claims: {
+          kind: "object",
+          fields: { sub: "string", roles: "list<string>" },
+        },
+      },
+    },
+    items: {
+      kind: "list",
+      elementType: { kind: "object", fields: { name: "string" } },
+    },
+  },
+};
+
+describe("Object types", () => {
+  test("should type check nested fields", async () => {
+    const env = await Env.new({
+      variables: [{ name: "request", type: requestType }],
+    });
+
+    await expect(env.compile("request.auth.claim")).rejects.toThrow(
+      "undefined field 'claim'",
+    );
+    const result = await env.typecheck("request.items[0].name");
+    expect(result.type).toBe("string");
+
+    env.destroy();
+  });
+
+  test("should test the presence of fields", async () => {
+    const env = await Env.new({
+      variables: [{ name: "request", type: requestType }],
+    });
+    const program = await env.compile(
+      "has(request.auth.claims.sub) ? request.auth.claims.sub : 'anonymous'",
+    );
+
+    const claims = { roles: [] };
+    expect(await program.eval({ request: { auth: { claims } } })).toBe(
+      "anonymous",
+    );
+    claims.sub = "alice";
+    expect(await program.eval({ request: { auth: { claims } } })).toBe(
+      "alice",
+    );
+
+    env.destroy();
+  });
+
+  test("should keep object types when extended", async () => {
+    const env = await Env.new({
+      variables: [{ name: "request", type: requestType }],
+    });
+    await env.extend([Options.optionalTypes()]);
+    const program = await env.compile(
+      "request.?auth.?claims.?sub.orValue('anonymous')",
+    );
+
+    expect(await program.eval({ request: { auth: {} } })).toBe("anonymous");
+
+    env.destroy();
+  });
+
+  test("should describe object types by name", async () => {
+    const env = await Env.new({
+      variables: [{ name: "request", type: requestType }],
+    });
+
+    const { variables } = await env.describe();
+    expect(variables).toEqual([
+      {
+        name: "request",
+        type: { kind: "object", name: "wasmcel.vars.request" },
+      },
+    ]);
+
+    env.destroy();
+  });
+
+  test("should reject fields that aren't identifiers", async () => {
+    await expect(
+      Env.new({
+        variables: [
+          {
+            name: "headers",
+            type: { kind: "object", fields: { "x-id": "int" } },
+          },
+        ],
+      }),
+    ).rejects.toThrow('invalid field name "x-id" of headers');
+  });
+});
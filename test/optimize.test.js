@@ -0,0 +1,98 @@
+import { Env } from "../dist/index.js";
+import { twice } from "./helpers.js";
+
+describe("Optimization", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({ variables: [{ name: "x", type: "double" }] });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should fold constant subexpressions", async () => {
+    const program = await env.compile(
+      "x + (2.0 * 3.0) > 10.0 && [1, 2, 3].size() == 3",
+      { optimize: true, optimizedSource: true },
+    );
+    expect(program.optimizedSource).toBe("x + 6.0 > 10.0");
+    expect(await program.eval({ x: 5 })).toBe(true);
+    expect(await program.eval({ x: 4 })).toBe(false);
+    program.destroy();
+  });
+
+  test("should return the source unchanged without optimize", async () => {
+    const program = await env.compile("x + 2.0 * 3.0", {
+      optimizedSource: true,
+    });
+    expect(program.optimizedSource).toBe("x + 2.0 * 3.0");
+    program.destroy();
+
+    const plain = await env.compile("x + 1.0", { optimize: true });
+    expect(plain.optimizedSource).toBeUndefined();
+    plain.destroy();
+  });
+
+  test("should include the optimized source and metrics in detailed results", async () => {
+    const result = await env.compileDetailed("1 + 2 + 3", {
+      optimize: true,
+      optimizedSource: true,
+      metrics: true,
+    });
+    expect(result.success).toBe(true);
+    expect(result.optimizedSource).toBe("6");
+    expect(result.metrics.optimizeMs).toEqual(expect.any(Number));
+    expect(await result.program.eval()).toBe(6);
+    result.program.destroy();
+  });
+
+  test("should compile expressions that fail to fold as written", async () => {
+    const program = await env.compile("x > 0.0 ? 1 : 1 / 0", {
+      optimize: true,
+      optimizedSource: true,
+    });
+    expect(program.optimizedSource).toBe("(x > 0.0) ? 1 : (1 / 0)");
+    expect(await program.eval({ x: 1 })).toBe(1);
+    await expect(program.eval({ x: -1 })).rejects.toThrow("division by zero");
+    program.destroy();
+  });
+
+  test("should not call custom functions at compile time", async () => {
+    let calls = 0;
+    const fnEnv = await Env.new({
+      functions: [twice("int", { onCall: () => calls++ })],
+    });
+
+    const program = await fnEnv.compile("twice(1 + 2)", {
+      optimize: true,
+      optimizedSource: true,
+    });
+    expect(calls).toBe(0);
+    expect(program.optimizedSource).toBe("twice(1 + 2)");
+    expect(await program.eval()).toBe(6);
+
+    program.destroy();
+    fnEnv.destroy();
+  });
+
+  test("should fold calls to pure custom functions", async () => {
+    let calls = 0;
+    const fnEnv = await Env.new({
+      functions: [twice("int", { pure: true, onCall: () => calls++ })],
+    });
+
+    const program = await fnEnv.compile("twice(1 + 2)", {
+      optimize: true,
+      optimizedSource: true,
+    });
+    expect(calls).toBe(1);
+    expect(program.optimizedSource).toBe("6");
+    expect(await program.eval()).toBe(6);
+    expect(calls).toBe(1);
+
+    program.destroy();
+    fnEnv.destroy();
+  });
+});
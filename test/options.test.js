@@ -1,4 +1,5 @@
-import { Env, Options } from "../dist/index.js";
+import { Env, Options, ProgramOptions } from "../dist/index.js";
+import { shout } from "./helpers.js";
 
 describe("CEL Environment Options", () => {
   describe("Simple options", () => {
@@ -1263,4 +1264,930 @@ describe("CEL Environment Options", () => {
       env2.destroy();
     });
   });
+
+  describe("Semantics pinning options", () => {
+    test("should evaluate time accessors in UTC with DefaultUTCTimeZone", async () => {
+      const env = await Env.new({
+        options: [Options.defaultUTCTimeZone()],
+      });
+
+      const program = await env.compile(
+        'timestamp("2024-01-01T23:30:00Z").getHours()',
+      );
+      const result = await program.eval();
+      expect(result).toBe(23);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should accept EagerlyValidateDeclarations during construction and extend", async () => {
+      const env = await Env.new({
+        variables: [{ name: "name", type: "string" }],
+        options: [Options.eagerlyValidateDeclarations()],
+      });
+      await env.extend([
+        Options.eagerlyValidateDeclarations({ enabled: false }),
+      ]);
+
+      const program = await env.compile('name + "!"');
+      const result = await program.eval({ name: "hello" });
+      expect(result).toBe("hello!");
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should always apply heterogeneous equality", async () => {
+      const env = await Env.new({
+        options: [
+          Options.defaultUTCTimeZone(),
+          Options.eagerlyValidateDeclarations(),
+        ],
+      });
+
+      const program = await env.compile("dyn(1) == 1.0 && dyn(2u) == 2");
+      const result = await program.eval();
+      expect(result).toBe(true);
+
+      program.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("Lib option", () => {
+    test("should enable libraries by name and version", async () => {
+      const env = await Env.new({
+        variables: [{ name: "s", type: "string" }],
+        options: [
+          Options.lib({ name: "strings", version: 3 }),
+          Options.lib({ name: "lists" }),
+          { type: "Lib", params: { name: "optional", version: "latest" } },
+        ],
+      });
+
+      const program = await env.compile(
+        's.reverse() == "cba" && [3, 1, 2].sort() == [1, 2, 3] && ' +
+          "optional.of(1).hasValue()",
+      );
+      expect(await program.eval({ s: "abc" })).toBe(true);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should limit functions to the requested version", async () => {
+      const env = await Env.new({
+        variables: [{ name: "s", type: "string" }],
+        options: [Options.lib({ name: "strings", version: 1 })],
+      });
+
+      await expect(env.compile("s.reverse()")).rejects.toThrow(
+        "undeclared reference to 'reverse'",
+      );
+
+      env.destroy();
+    });
+
+    test("should reject unknown libraries", async () => {
+      await expect(
+        Env.new({ options: [Options.lib({ name: "nope" })] }),
+      ).rejects.toThrow('unknown library "nope"');
+    });
+  });
+
+  describe("K8sValidationPreset option", () => {
+    test("should enable the Kubernetes extensions and track cost", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "tags", type: { kind: "list", elementType: "string" } },
+        ],
+        options: [Options.k8sValidationPreset()],
+      });
+
+      const program = await env.compile(
+        'sets.contains(tags, ["prod"]) && tags.all(i, t, t.lowerAscii() == t)',
+      );
+      const { result, cost } = await program.evalDetailed({
+        tags: ["prod", "eu"],
+      });
+      expect(result).toBe(true);
+      expect(cost).toBeGreaterThan(0);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should validate literals at compile time", async () => {
+      const env = await Env.new({
+        options: [Options.k8sValidationPreset()],
+      });
+
+      await expect(
+        env.compile('duration("1x") > duration("0s")'),
+      ).rejects.toThrow("invalid duration argument");
+      await expect(env.compile('[1, "a"]')).rejects.toThrow(
+        "expected type 'int' but found 'string'",
+      );
+
+      env.destroy();
+    });
+
+    test("should abort evaluations over the per-call limit", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "items", type: { kind: "list", elementType: "string" } },
+        ],
+        options: [Options.k8sValidationPreset({ perCallLimit: 50 })],
+      });
+
+      const program = await env.compile("items.map(x, x + x).size() > 0");
+      await expect(
+        program.eval({ items: Array(100).fill("a") }),
+      ).rejects.toThrow("cost limit exceeded");
+
+      program.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("AttributeContextPreset option", () => {
+    test("should declare the Envoy attributes", async () => {
+      const env = await Env.new({
+        options: [Options.attributeContextPreset()],
+      });
+
+      const program = await env.compile(
+        'connection.mtls && request.headers["x-user"] == "alice" && ' +
+          'request.time > timestamp("2020-01-01T00:00:00Z")',
+      );
+      const result = await program.evalWithContextMessage(
+        "wasmcel.envoy.Attributes",
+        {
+          request: {
+            headers: { "x-user": "alice" },
+            time: "2024-01-01T00:00:00Z",
+          },
+          connection: { mtls: true },
+        },
+      );
+      expect(result).toBe(true);
+
+      await expect(env.compile("request.nope")).rejects.toThrow(
+        "undefined field 'nope'",
+      );
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should declare the fields of AttributeContext", async () => {
+      const env = await Env.new({
+        options: [Options.attributeContextPreset({ dialect: "google" })],
+      });
+
+      const program = await env.compile(
+        'request.auth.principal == "alice" && source.ip == "10.0.0.1"',
+      );
+      const result = await program.evalWithContextMessage(
+        "google.rpc.context.AttributeContext",
+        {
+          request: { auth: { principal: "alice" } },
+          source: { ip: "10.0.0.1" },
+        },
+      );
+      expect(result).toBe(true);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should reject unknown dialects", async () => {
+      await expect(
+        Env.new({
+          options: [
+            { type: "AttributeContextPreset", params: { dialect: "x" } },
+          ],
+        }),
+      ).rejects.toThrow('invalid dialect "x"');
+    });
+  });
+
+  describe("Lint option", () => {
+    test("should report findings as warnings with their rule", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "x", type: "int" },
+          { name: "t", type: "timestamp" },
+        ],
+        options: [Options.lint()],
+      });
+
+      const result = await env.compileDetailed(
+        "x > 1 && (1 < 2) && string(t) < '2024-01-01T00:00:00Z'",
+      );
+      expect(result.success).toBe(true);
+      expect(
+        result.issues.map((issue) => [issue.severity, issue.rule]),
+      ).toEqual([
+        ["warning", "constant-condition"],
+        ["warning", "timestamp-string-comparison"],
+      ]);
+      expect(result.issues[0].message).toBe(
+        "condition 1 < 2 doesn't depend on any variable",
+      );
+
+      result.program.destroy();
+      env.destroy();
+    });
+
+    test("should report duplicate clauses and tautologies", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "x", type: "int" },
+          { name: "l", type: { kind: "list", elementType: "int" } },
+        ],
+        options: [Options.lint()],
+      });
+
+      const result = await env.compileDetailed(
+        "x > 1 || size(l) >= 0 || x > 1",
+      );
+      expect(result.issues.map((issue) => issue.message)).toEqual([
+        "clause x > 1 is repeated",
+        "comparison size(l) >= 0 is always true",
+      ]);
+
+      result.program.destroy();
+      env.destroy();
+    });
+
+    test("should report unused cel.bind variables", async () => {
+      const env = await Env.new({
+        variables: [{ name: "x", type: "int" }],
+        options: [Options.lint(), { type: "ext.Bindings" }],
+      });
+
+      const unused = await env.compileDetailed("cel.bind(y, x + 1, x > 2)");
+      expect(unused.issues).toHaveLength(1);
+      expect(unused.issues[0].rule).toBe("unused-binding");
+
+      const used = await env.compileDetailed("cel.bind(y, x + 1, y > 2)");
+      expect(used.issues).toEqual([]);
+
+      unused.program.destroy();
+      used.program.destroy();
+      env.destroy();
+    });
+
+    test("should apply the severity of each rule", async () => {
+      const env = await Env.new({
+        variables: [{ name: "x", type: "int" }],
+        options: [
+          Options.lint({
+            rules: { "duplicate-clause": "error", "constant-condition": "off" },
+          }),
+        ],
+      });
+
+      await expect(env.compile("x > 1 || x > 1")).rejects.toThrow(
+        "clause x > 1 is repeated (duplicate-clause)",
+      );
+      const result = await env.compileDetailed("x > 1 || true");
+      expect(result.issues).toEqual([]);
+
+      result.program.destroy();
+      env.destroy();
+    });
+
+    test("should reject unknown rules", async () => {
+      await expect(
+        Env.new({ options: [Options.lint({ rules: { nope: "error" } })] }),
+      ).rejects.toThrow('unknown lint rule "nope"');
+    });
+  });
+
+  describe("Sub-options", () => {
+    test("should pin the strings extension version", async () => {
+      const env = await Env.new({
+        variables: [{ name: "s", type: "string" }],
+        options: [
+          {
+            type: "ext.Strings",
+            params: {
+              options: [{ type: "StringsVersion", params: { version: 0 } }],
+            },
+          },
+        ],
+      });
+
+      await expect(env.compile("s.reverse()")).rejects.toThrow(
+        "undeclared reference to 'reverse'",
+      );
+
+      const program = await env.compile("s.upperAscii()");
+      const result = await program.eval({ s: "abc" });
+      expect(result).toBe("ABC");
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should accept OptionalTypes sub-options", async () => {
+      const env = await Env.new({
+        options: [
+          Options.optionalTypes({
+            opts: [{ type: "OptionalTypesVersion", params: { version: 1 } }],
+          }),
+        ],
+      });
+
+      const program = await env.compile("optional.of(1).orValue(2)");
+      const result = await program.eval();
+      expect(result).toBe(1);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should reject unknown sub-options", async () => {
+      await expect(
+        Env.new({
+          options: [
+            { type: "ext.Strings", params: { options: [{ type: "Nope" }] } },
+          ],
+        }),
+      ).rejects.toThrow('unknown sub-option "Nope"');
+    });
+  });
+
+  describe("Program options", () => {
+    test("should report runtime cost with CostTracking", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "items", type: { kind: "list", elementType: "string" } },
+        ],
+      });
+
+      const program = await env.compile("items.exists(i, i == 'admin')", {
+        programOptions: [ProgramOptions.costTracking()],
+      });
+      const { result, cost } = await program.evalDetailed({
+        items: ["user", "admin"],
+      });
+
+      expect(result).toBe(true);
+      expect(typeof cost).toBe("number");
+      expect(cost).toBeGreaterThan(0);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should omit cost when cost tracking is not enabled", async () => {
+      const env = await Env.new();
+      const program = await env.compile("1 + 2");
+      const details = await program.evalDetailed();
+
+      expect(details.result).toBe(3);
+      expect(details.cost).toBeUndefined();
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should abort evaluation when CostLimit is exceeded", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "items", type: { kind: "list", elementType: "string" } },
+        ],
+      });
+
+      const program = await env.compile("items.map(i, i + i).size()", {
+        programOptions: [ProgramOptions.costLimit(1)],
+      });
+
+      await expect(program.eval({ items: ["a", "b", "c"] })).rejects.toThrow(
+        "cost limit exceeded",
+      );
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should accept EvalOptions and InterruptCheckFrequency", async () => {
+      const env = await Env.new({
+        variables: [{ name: "x", type: "string" }],
+      });
+
+      const result = await env.compileDetailed('x == "a" || x == "b"', {
+        programOptions: [
+          ProgramOptions.evalOptions(["OptOptimize", "OptExhaustiveEval"]),
+          ProgramOptions.interruptCheckFrequency(100),
+        ],
+      });
+
+      expect(result.success).toBe(true);
+      expect(await result.program.eval({ x: "b" })).toBe(true);
+
+      result.program.destroy();
+      env.destroy();
+    });
+
+    test("should reject unknown program options", async () => {
+      const env = await Env.new();
+
+      await expect(
+        env.compile("1 + 1", {
+          programOptions: [{ type: "DoesNotExist", params: {} }],
+        }),
+      ).rejects.toThrow('program option "DoesNotExist" not found');
+
+      env.destroy();
+    });
+  });
+
+  describe("SystemFunctions option", () => {
+    test("should declare uuid(), random() and now()", async () => {
+      const env = await Env.new({
+        options: [Options.systemFunctions()],
+      });
+
+      const program = await env.compile(
+        "uuid().matches('^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-') && " +
+          "random() >= 0.0 && random() < 1.0 && now() == now()",
+      );
+      expect(await program.eval()).toBe(true);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should repeat results in deterministic mode", async () => {
+      const env = await Env.new({
+        options: [
+          Options.systemFunctions({
+            deterministic: true,
+            seed: 42,
+            now: "2024-06-01T12:00:00Z",
+          }),
+        ],
+      });
+
+      const program = await env.compile("[uuid(), random()]");
+      const first = await program.eval();
+      expect(await program.eval()).toEqual(first);
+      expect(await program.eval(null, { seed: 7 })).not.toEqual(first);
+
+      const clock = await env.compile(
+        "now() == timestamp('2024-06-01T12:00:00Z')",
+      );
+      expect(await clock.eval()).toBe(true);
+
+      program.destroy();
+      clock.destroy();
+      env.destroy();
+    });
+
+    test("should freeze the clock of an evaluation", async () => {
+      const env = await Env.new({
+        options: [Options.systemFunctions()],
+      });
+
+      const weekend = await env.compile("now().getDayOfWeek() in [0, 6]");
+      expect(
+        await weekend.eval(null, { now: new Date("2024-06-01T00:00:00Z") }),
+      ).toBe(true);
+      expect(
+        await weekend.eval(null, { now: "2024-06-03T00:00:00Z" }),
+      ).toBe(false);
+
+      weekend.destroy();
+      env.destroy();
+    });
+
+    test("should not memoize programs calling them", async () => {
+      const env = await Env.new({
+        options: [Options.systemFunctions()],
+      });
+
+      await expect(env.compile("random()", { memoize: 4 })).rejects.toThrow(
+        "memoization requires",
+      );
+
+      env.destroy();
+    });
+  });
+
+  describe("IP option", () => {
+    test("should check addresses against ranges", async () => {
+      const env = await Env.new({
+        variables: [{ name: "source", type: "string" }],
+        options: [Options.ip()],
+      });
+
+      const program = await env.compile(
+        'cidr("10.0.0.0/8").containsIP(source) && !ip(source).isLoopback()',
+      );
+      expect(await program.eval({ source: "10.1.2.3" })).toBe(true);
+      expect(await program.eval({ source: "11.1.2.3" })).toBe(false);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should return addresses and ranges as strings", async () => {
+      const env = await Env.new({
+        options: [Options.ip()],
+      });
+
+      const program = await env.compile(
+        '[cidr("10.1.2.3/8").masked(), cidr("10.1.2.3/8").ip()]',
+      );
+      expect(await program.eval()).toEqual(["10.0.0.0/8", "10.1.2.3"]);
+
+      program.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("URL option", () => {
+    test("should take URLs apart", async () => {
+      const env = await Env.new({
+        variables: [{ name: "request", type: "string" }],
+        options: [Options.url()],
+      });
+
+      const program = await env.compile(
+        "[url(request).getScheme(), url(request).getHostname(), " +
+          "url(request).getPort(), url(request).getPath(), " +
+          "url(request).getQuery()]",
+      );
+      expect(
+        await program.eval({
+          request: "https://example.com:8443/a%20b?x=1&x=2",
+        }),
+      ).toEqual(["https", "example.com", "8443", "/a b", { x: ["1", "2"] }]);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should validate URLs", async () => {
+      const env = await Env.new({
+        options: [Options.url()],
+      });
+
+      const program = await env.compile(
+        '[isURL("/admin"), isURL("admin"), string(url("/a?b=c"))]',
+      );
+      expect(await program.eval()).toEqual([true, false, "/a?b=c"]);
+
+      const invalid = await env.compile('url("admin")');
+      await expect(invalid.eval()).rejects.toThrow("URL parse error");
+
+      program.destroy();
+      invalid.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("JWT option", () => {
+    const encode = (object) =>
+      Buffer.from(JSON.stringify(object)).toString("base64url");
+    const token = [
+      encode({ alg: "HS256", typ: "JWT" }),
+      encode({ sub: "alice", roles: ["admin"], exp: 1717243200 }),
+      "signature",
+    ].join(".");
+
+    test("should decode claims without verifying them", async () => {
+      const env = await Env.new({
+        variables: [{ name: "token", type: "string" }],
+        options: [Options.jwt()],
+      });
+
+      const program = await env.compile(
+        '[jwt.decode(token).sub, jwt.header(token).alg, ' +
+          'jwt.hasClaim(token, "roles"), jwt.hasClaim(token, "aud")]',
+      );
+      expect(await program.eval({ token })).toEqual([
+        "alice",
+        "HS256",
+        true,
+        false,
+      ]);
+
+      const expiry = await env.compile("jwt.expiresAt(token)");
+      expect(await expiry.eval({ token })).toBe("2024-06-01T12:00:00Z");
+
+      program.destroy();
+      expiry.destroy();
+      env.destroy();
+    });
+
+    test("should check expiry at a timestamp", async () => {
+      const env = await Env.new({
+        variables: [{ name: "token", type: "string" }],
+        options: [Options.jwt(), Options.systemFunctions()],
+      });
+
+      const program = await env.compile("jwt.isExpired(token, now())");
+      expect(
+        await program.eval({ token }, { now: "2024-06-01T11:59:59Z" }),
+      ).toBe(false);
+      expect(
+        await program.eval({ token }, { now: "2024-06-01T12:00:00Z" }),
+      ).toBe(true);
+
+      program.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("Hash option", () => {
+    test("should return hex digests", async () => {
+      const env = await Env.new({
+        variables: [{ name: "body", type: "string" }],
+        options: [Options.hash()],
+      });
+
+      const program = await env.compile(
+        '[hash.md5("abc"), hash.sha256(b"abc"), hmac.sha256("key", body)]',
+      );
+      expect(await program.eval({ body: "msg" })).toEqual([
+        "900150983cd24fb0d6963f7d28e17f72",
+        "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+        "2d93cbc1be167bcb1637a4a23cbff01a7878f0c50ee833954ea5221bb1b8c628",
+      ]);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should return base64 digests", async () => {
+      const env = await Env.new({
+        options: [Options.hash({ encoding: "base64" })],
+      });
+
+      const program = await env.compile('hash.sha256("abc")');
+      expect(await program.eval()).toBe(
+        "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=",
+      );
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should reject unknown encodings", async () => {
+      await expect(
+        Env.new({ options: [Options.hash({ encoding: "base32" })] }),
+      ).rejects.toThrow("unknown digest encoding");
+    });
+  });
+
+  describe("Locale option", () => {
+    test("should collate strings the way a locale does", async () => {
+      const env = await Env.new({
+        options: [Options.locale({ locale: "sv" })],
+      });
+
+      const program = await env.compile(
+        '[locale.sort(["ä", "z", "a"]), locale.compare("ä", "z"), "ä" < "z"]',
+      );
+      expect(await program.eval()).toEqual([["a", "z", "ä"], 1, true]);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should ignore case and sort digits numerically", async () => {
+      const env = await Env.new({
+        options: [Options.locale({ ignoreCase: true, numeric: true })],
+      });
+
+      const program = await env.compile(
+        '[locale.equals("Alice", "alice"), ' +
+          'locale.sort(["item10", "item9", "item1"])]',
+      );
+      expect(await program.eval()).toEqual([
+        true,
+        ["item1", "item9", "item10"],
+      ]);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should change case the way a locale does", async () => {
+      const env = await Env.new({
+        variables: [{ name: "city", type: "string" }],
+        options: [Options.locale({ locale: "tr" })],
+      });
+
+      const program = await env.compile("locale.upper(city)");
+      expect(await program.eval({ city: "istanbul" })).toBe("İSTANBUL");
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should reject invalid locales", async () => {
+      await expect(
+        Env.new({ options: [Options.locale({ locale: "??" })] }),
+      ).rejects.toThrow("invalid locale");
+    });
+  });
+
+  describe("Statistics option", () => {
+    test("should aggregate lists of numbers", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "xs", type: { kind: "list", elementType: "double" } },
+        ],
+        options: [Options.statistics()],
+      });
+
+      const program = await env.compile(
+        "[math.sum(xs), math.min(xs), math.max(xs), math.avg(xs), " +
+          "math.median(xs), math.percentile(xs, 90)]",
+      );
+      expect(await program.eval({ xs: [3.5, 1, 10, 2] })).toEqual([
+        16.5, 1, 10, 4.125, 2.75, 8.05,
+      ]);
+
+      const ints = await env.compile("[math.sum([1, 2, 3]), math.sum([])]");
+      expect(await ints.eval()).toEqual([6, 0]);
+
+      program.destroy();
+      ints.destroy();
+      env.destroy();
+    });
+
+    test("should fail on empty lists and overflow", async () => {
+      const env = await Env.new({
+        options: [Options.statistics()],
+      });
+
+      const empty = await env.compile("math.avg([0.0].filter(x, x > 0.0))");
+      await expect(empty.eval()).rejects.toThrow("of an empty list");
+
+      const overflow = await env.compile(
+        "math.sum([9223372036854775807, 1])",
+      );
+      await expect(overflow.eval()).rejects.toThrow("overflow");
+
+      empty.destroy();
+      overflow.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("SetOperations option", () => {
+    test("should combine lists as sets", async () => {
+      const env = await Env.new({
+        options: [Options.setOperations()],
+      });
+
+      const program = await env.compile(
+        "[sets.union([1, 2], [2, 3]), sets.intersection([1, 2, 2], [2, 3]), " +
+          "sets.difference([1, 2, 3], [dyn(2.0)]), sets.distinct([1, 1u, 2])]",
+      );
+      expect(await program.eval()).toEqual([[1, 2, 3], [2], [1, 3], [1, 2]]);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should keep the types of lists", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "roles", type: { kind: "list", elementType: "string" } },
+        ],
+        options: [Options.setOperations()],
+      });
+
+      const program = await env.compile(
+        'sets.distinct(roles).exists(r, r.startsWith("admin"))',
+      );
+      expect(await program.eval({ roles: ["admin", "dev", "admin"] })).toBe(
+        true,
+      );
+      await expect(env.compile('sets.union([1], ["a"])')).rejects.toThrow(
+        "found no matching overload",
+      );
+
+      program.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("JSONPath option", () => {
+    const pod = {
+      metadata: { labels: { "app.kubernetes.io/name": "web", tier: "front" } },
+      spec: {
+        containers: [
+          { name: "app", image: "nginx" },
+          { name: "sidecar", image: "envoy" },
+        ],
+      },
+    };
+
+    test("should select fields, indexes and wildcards", async () => {
+      const env = await Env.new({
+        variables: [{ name: "pod", type: "dyn" }],
+        options: [Options.jsonPath()],
+      });
+
+      const program = await env.compile(
+        '[jsonpath(pod, "$.spec.containers[*].image"), ' +
+          'jsonpath(pod, "spec.containers[-1].name"), ' +
+          "jsonpath(pod, \"$.metadata.labels['app.kubernetes.io/name']\"), " +
+          'jsonpath(pod, "$.metadata.labels.*"), ' +
+          'jsonpath(pod, "$.spec.volumes[0]")]',
+      );
+      expect(await program.eval({ pod })).toEqual([
+        ["nginx", "envoy"],
+        ["sidecar"],
+        ["web"],
+        ["web", "front"],
+        [],
+      ]);
+
+      program.destroy();
+      env.destroy();
+    });
+  });
+
+  describe("SafeModePreset option", () => {
+    const variables = [
+      { name: "item", type: "map<string, dyn>" },
+      { name: "tags", type: "list<string>" },
+    ];
+
+    test("should compile boolean filters", async () => {
+      const env = await Env.new({
+        variables,
+        options: [Options.safeModePreset()],
+      });
+
+      const program = await env.compile(
+        'item.status in ["open", "blocked"] && tags.exists(t, t == "urgent")',
+      );
+      expect(
+        await program.eval({ item: { status: "open" }, tags: ["urgent"] }),
+      ).toBe(true);
+
+      program.destroy();
+      env.destroy();
+    });
+
+    test("should reject expressions the safe mode doesn't allow", async () => {
+      const env = await Env.new({
+        variables,
+        functions: [shout()],
+        options: [Options.safeModePreset({ maxLiteralSize: 2 })],
+      });
+
+      for (const [expr, message] of [
+        ["tags.size()", "expression has type int, expected bool"],
+        ["item.done", "expression has type dyn, expected bool"],
+        ["tags[0] + 'x' == 'ax'", "concatenation of string values"],
+        ["tags + ['x'] == []", "concatenation of list(string) values"],
+        ["item.a + item.b == 2", "addition of dyn values"],
+        ["tags.map(t, t).size() > 0", "comprehensions building lists or maps"],
+        ["tags.filter(t, t == 'a') == []", "comprehensions building lists"],
+        ["item.status in ['a', 'b', 'c']", "list literal of 3 elements"],
+        ["shout(tags[0]) == 'A'", "custom function shout is not allowed"],
+      ]) {
+        await expect(env.compile(expr)).rejects.toThrow(message);
+      }
+
+      const result = await env.compileDetailed("1 < 2 && tags + tags == []");
+      expect(result.issues).toEqual([
+        expect.objectContaining({
+          severity: "error",
+          location: { line: 1, column: 14 },
+        }),
+      ]);
+
+      env.destroy();
+    });
+
+    test("should abort evaluations over the cost limit", async () => {
+      const env = await Env.new({
+        variables,
+        options: [Options.safeModePreset({ costLimit: 50 })],
+      });
+
+      const program = await env.compile("tags.all(t, t != 'b')");
+      expect(await program.eval({ item: {}, tags: ["a"] })).toBe(true);
+      await expect(
+        program.eval({ item: {}, tags: Array(100).fill("a") }),
+      ).rejects.toThrow("cost limit exceeded");
+
+      program.destroy();
+      env.destroy();
+    });
+  });
 });
@@ -0,0 +1,127 @@
+import { Env, Policy } from "../dist/index.js";
+
+const source = `
+name: admission
+rule:
+  variables:
+    - name: is_admin
+      expression: request.role == "admin"
+  match:
+    - condition: variables.is_admin
+      output: "true"
+    - condition: request.path.startsWith("/public")
+      rule:
+        match:
+          - condition: request.method == "GET"
+            output: "true"
+    - output: "false"
+      explanation: "'only admins may access ' + request.path"
+`;
+
+describe("Policies", () => {
+  let env;
+  let policy;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "request", type: "dyn" }],
+    });
+    policy = await Policy.new(env, source);
+  });
+
+  afterAll(() => {
+    policy.destroy();
+    env.destroy();
+  });
+
+  test("should report the name of the policy", () => {
+    expect(policy.getName()).toBe("admission");
+  });
+
+  test("should return the output of the first matching match", async () => {
+    const result = await policy.eval({
+      request: { role: "admin", path: "/admin", method: "POST" },
+    });
+    expect(result).toEqual({ matched: true, result: true });
+  });
+
+  test("should evaluate nested rules", async () => {
+    const result = await policy.eval({
+      request: { role: "user", path: "/public/a", method: "GET" },
+    });
+    expect(result).toEqual({ matched: true, result: true });
+  });
+
+  test("should fall through nested rules that don't match", async () => {
+    const result = await policy.eval({
+      request: { role: "user", path: "/public/a", method: "POST" },
+    });
+    expect(result).toEqual({
+      matched: true,
+      result: false,
+      explanation: "only admins may access /public/a",
+    });
+  });
+
+  test("should report when no match applies", async () => {
+    const partial = await Policy.new(
+      env,
+      `
+rule:
+  match:
+    - condition: request.role == "admin"
+      output: "'allow'"
+`,
+    );
+    const result = await partial.eval({ request: { role: "user" } });
+    expect(result).toEqual({ matched: false, result: null });
+    partial.destroy();
+  });
+
+  test("should report errors with their location", async () => {
+    await expect(
+      Policy.new(
+        env,
+        `
+rule:
+  match:
+    - condition: request.role == "admin"
+      output: "true"
+    - condition: "'not a bool'"
+      output: "false"
+`,
+      ),
+    ).rejects.toThrow(/rule\.match\[1\]\.condition: expected a bool/);
+  });
+
+  test("should reject matches with both an output and a rule", async () => {
+    await expect(
+      Policy.new(
+        env,
+        `
+rule:
+  match:
+    - output: "true"
+      rule:
+        match:
+          - output: "false"
+`,
+      ),
+    ).rejects.toThrow(/match requires either an output or a rule/);
+  });
+
+  test("should reject unknown fields", async () => {
+    await expect(Policy.new(env, "rule:\n  matches: []\n")).rejects.toThrow(
+      /failed to parse policy/,
+    );
+  });
+
+  test("should reject evaluation after destroy", async () => {
+    const destroyed = await Policy.new(env, source);
+    destroyed.destroy();
+    expect(destroyed.isDestroyed()).toBe(true);
+    await expect(destroyed.eval({})).rejects.toThrow(
+      /Policy has been destroyed/,
+    );
+  });
+});
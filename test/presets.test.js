@@ -0,0 +1,17 @@
+import { Env, getCapabilities } from "../dist/index.js";
+
+describe("Env.fromPreset", () => {
+  test("should list the presets baked into the module", async () => {
+    const caps = await getCapabilities();
+    expect(Array.isArray(caps.presets)).toBe(true);
+  });
+
+  test("should reject presets that aren't baked into the module", async () => {
+    await expect(Env.fromPreset("no-such-preset")).rejects.toThrow(
+      "unknown preset: no-such-preset",
+    );
+    await expect(Env.fromPreset("../bundle")).rejects.toThrow(
+      "unknown preset: ../bundle",
+    );
+  });
+});
@@ -0,0 +1,140 @@
+import { Env } from "../dist/index.js";
+import { signingKeys } from "./helpers.js";
+
+describe("Programs from checked expressions", () => {
+  test("should evaluate an exported checked expression", async () => {
+    const env = await Env.new({
+      variables: [
+        { name: "x", type: "double" },
+        { name: "s", type: "string" },
+      ],
+    });
+    const program = await env.compile('x > 10.0 && s.startsWith("a")');
+
+    for (const format of ["binary", "json", "textproto"]) {
+      const checked = await program.exportCheckedExpr(format);
+      const imported = await env.programFromCheckedExpr(checked, format);
+      expect(await imported.eval({ x: 11, s: "ab" })).toBe(true);
+      expect(await imported.eval({ x: 9, s: "ab" })).toBe(false);
+      imported.destroy();
+    }
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should apply compile options", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const program = await env.compile("x > 1.0 + 1.0");
+    const checked = await program.exportCheckedExpr();
+
+    const imported = await env.programFromCheckedExpr(checked, "binary", {
+      optimize: true,
+      optimizedSource: true,
+      fieldMask: true,
+    });
+    expect(imported.optimizedSource).toBe("x > 2.0");
+    expect(imported.fieldMask).toEqual([["x"]]);
+
+    imported.destroy();
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject expressions that don't match the environment", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const program = await env.compile("x > 10.0");
+    const checked = await program.exportCheckedExpr();
+
+    const undeclared = await Env.new();
+    await expect(undeclared.programFromCheckedExpr(checked)).rejects.toThrow(
+      "undeclared reference to 'x'",
+    );
+
+    const dynamic = await Env.new({
+      variables: [{ name: "x", type: "dyn" }],
+    });
+    await expect(dynamic.programFromCheckedExpr(checked)).rejects.toThrow(
+      "identifier x is dyn here, not double",
+    );
+
+    program.destroy();
+    env.destroy();
+    undeclared.destroy();
+    dynamic.destroy();
+  });
+
+  test("should reject malformed checked expressions", async () => {
+    const env = await Env.new();
+
+    await expect(env.programFromCheckedExpr("{", "json")).rejects.toThrow(
+      "failed to decode checked expression",
+    );
+    await expect(env.programFromCheckedExpr("", "textproto")).rejects.toThrow(
+      "no expression",
+    );
+
+    env.destroy();
+  });
+
+  test("should not replace imported programs", async () => {
+    const env = await Env.new();
+    const program = await env.compile("1 + 1");
+    const imported = await env.programFromCheckedExpr(
+      await program.exportCheckedExpr(),
+    );
+
+    await expect(imported.replace("2")).rejects.toThrow(
+      "wasn't compiled from an expression",
+    );
+
+    imported.destroy();
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should verify signed checked expressions", async () => {
+    const { rawKey, sign } = signingKeys();
+
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const program = await env.compile("x > 10.0");
+    const checked = Buffer.from(await program.exportCheckedExpr());
+    const signed = {
+      checkedExpr: checked.toString("base64"),
+      signature: sign(checked),
+    };
+
+    const imported = await env.programFromCheckedExpr(signed, "binary", {
+      publicKey: rawKey,
+    });
+    expect(await imported.eval({ x: 11 })).toBe(true);
+
+    const tampered = await env.compile("x < 10.0");
+    await expect(
+      env.programFromCheckedExpr(
+        {
+          ...signed,
+          checkedExpr: Buffer.from(await tampered.exportCheckedExpr()).toString(
+            "base64",
+          ),
+        },
+        "binary",
+        { publicKey: rawKey },
+      ),
+    ).rejects.toThrow("checked expression signature verification failed");
+    await expect(
+      env.programFromCheckedExpr(checked, "binary", { publicKey: rawKey }),
+    ).rejects.toThrow("checked expression isn't signed");
+
+    imported.destroy();
+    tampered.destroy();
+    program.destroy();
+    env.destroy();
+  });
+});
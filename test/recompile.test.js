@@ -0,0 +1,55 @@
+import { Env, Options } from "../dist/index.js";
+
+describe("Recompiling programs", () => {
+  const rejectSecrets = (nodeType, nodeData) => {
+    if (nodeType === "select" && nodeData.field === "secret") {
+      return { issues: [{ severity: "error", message: "no secrets" }] };
+    }
+    return { issues: [] };
+  };
+
+  test("should recompile the programs of an extended environment", async () => {
+    const env = await Env.new({
+      variables: [{ name: "config", type: "map<string, string>" }],
+    });
+    const secret = await env.compile("config.secret");
+    const size = await env.compile("size(config)", { memoize: 4 });
+
+    await env.extend([Options.astValidators({ validators: [rejectSecrets] })]);
+    const result = await env.recompilePrograms();
+
+    expect(result.recompiled).toEqual([size.getID()]);
+    expect(result.issues).toHaveLength(1);
+    expect(result.issues[0].programID).toBe(secret.getID());
+    expect(result.issues[0].error).toContain("no secrets");
+
+    // Both programs stay usable under the same handles
+    const vars = { config: { secret: "x" } };
+    await expect(secret.eval(vars)).resolves.toBe("x");
+    await expect(size.eval(vars)).resolves.toBe(1);
+
+    env.destroy();
+  });
+
+  test("should only recompile programs of the environment", async () => {
+    const env = await Env.new({ variables: [{ name: "x", type: "double" }] });
+    const other = await Env.new({ variables: [{ name: "x", type: "double" }] });
+    const program = await env.compile("x * 2.0");
+    await other.compile("x + 1.0");
+
+    const result = await env.recompilePrograms();
+    expect(result).toEqual({ recompiled: [program.getID()], issues: [] });
+    await expect(program.eval({ x: 2 })).resolves.toBe(4);
+
+    env.destroy();
+    other.destroy();
+  });
+
+  test("should reject destroyed environments", async () => {
+    const env = await Env.new();
+    env.destroy();
+    await expect(env.recompilePrograms()).rejects.toThrow(
+      "Environment has been destroyed",
+    );
+  });
+});
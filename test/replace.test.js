@@ -0,0 +1,53 @@
+import { Env } from "../dist/index.js";
+
+describe("Replacing programs", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({ variables: [{ name: "x", type: "double" }] });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should swap the expression under the same handle", async () => {
+    const program = await env.compile("x > 10.0");
+    const id = program.getID();
+    await expect(program.eval({ x: 15 })).resolves.toBe(true);
+
+    await program.replace("x > 20.0");
+    expect(program.getID()).toBe(id);
+    await expect(program.eval({ x: 15 })).resolves.toBe(false);
+    program.destroy();
+  });
+
+  test("should keep the program when the expression doesn't compile", async () => {
+    const program = await env.compile("x * 2.0");
+    await expect(program.replace("x *")).rejects.toThrow("compilation error");
+    await expect(program.replace("y")).rejects.toThrow("undeclared reference");
+    await expect(program.eval({ x: 2 })).resolves.toBe(4);
+    program.destroy();
+  });
+
+  test("should keep compile options and drop memoized results", async () => {
+    const program = await env.compile("x + 1.0", {
+      memoize: 8,
+      fieldMask: true,
+    });
+    await expect(program.eval({ x: 1 })).resolves.toBe(2);
+
+    await program.replace("x + 2.0");
+    await expect(program.eval({ x: 1 })).resolves.toBe(3);
+    expect(program.fieldMask).toEqual([["x"]]);
+    program.destroy();
+  });
+
+  test("should reject destroyed programs", async () => {
+    const program = await env.compile("x");
+    program.destroy();
+    await expect(program.replace("x + 1.0")).rejects.toThrow(
+      "Program has been destroyed",
+    );
+  });
+});
@@ -0,0 +1,83 @@
+import { Env, RuleSet } from "../dist/index.js";
+
+describe("Rule sets", () => {
+  let env;
+  let rules;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "request", type: "dyn" },
+        { name: "blocklist", type: { kind: "list", elementType: "string" } },
+      ],
+    });
+    rules = await RuleSet.new(env, [
+      { id: "public", expr: 'request.path.startsWith("/public")' },
+      {
+        id: "blocked",
+        expr: "request.ip in blocklist",
+        mode: "stop",
+        priority: 10,
+      },
+      { id: "admin", expr: 'request.role == "admin"', priority: 5 },
+      { id: "trusted", expr: "request.trusted" },
+    ]);
+  });
+
+  afterAll(() => {
+    rules.destroy();
+    env.destroy();
+  });
+
+  test("should report every matching rule in priority order", async () => {
+    const result = await rules.eval({
+      request: { path: "/public/a", role: "admin", ip: "10.0.0.1" },
+      blocklist: [],
+    });
+    expect(result.matched).toEqual(["admin", "public"]);
+    expect(result.evaluated).toBe(4);
+    expect(result.errors).toEqual([
+      { id: "trusted", error: expect.stringMatching(/no such key/) },
+    ]);
+  });
+
+  test("should stop on the first match when requested", async () => {
+    const result = await rules.eval(
+      {
+        request: { path: "/public/a", role: "admin", ip: "10.0.0.1" },
+        blocklist: [],
+      },
+      { stopOnFirstMatch: true },
+    );
+    expect(result.matched).toEqual(["admin"]);
+    expect(result.evaluated).toBe(2);
+  });
+
+  test("should stop after a matching stop rule", async () => {
+    const result = await rules.eval({
+      request: { path: "/public/a", role: "admin", ip: "10.0.0.1" },
+      blocklist: ["10.0.0.1"],
+    });
+    expect(result.matched).toEqual(["blocked"]);
+    expect(result.evaluated).toBe(1);
+  });
+
+  test("should reject invalid rules", async () => {
+    await expect(
+      RuleSet.new(env, [{ id: "size", expr: "size(blocklist)" }]),
+    ).rejects.toThrow(/rule size: expected a bool expression/);
+    await expect(
+      RuleSet.new(env, [
+        { id: "a", expr: "true" },
+        { id: "a", expr: "false" },
+      ]),
+    ).rejects.toThrow(/duplicate rule id: a/);
+  });
+
+  test("should not evaluate after being destroyed", async () => {
+    const ruleSet = await RuleSet.new(env, [{ id: "always", expr: "true" }]);
+    ruleSet.destroy();
+    expect(ruleSet.isDestroyed()).toBe(true);
+    await expect(ruleSet.eval()).rejects.toThrow(/destroyed/);
+  });
+});
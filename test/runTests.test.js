@@ -0,0 +1,66 @@
+import { Env } from "../dist/index.js";
+
+describe("Test runner", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "user", type: "map<string, dyn>" }],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should report passing and failing cases", async () => {
+    const program = await env.compile("user.age >= 18");
+    const { passed, failed, results } = await program.runTests([
+      { name: "adult", vars: { user: { age: 30 } }, expect: true },
+      { name: "minor", vars: { user: { age: 17 } }, expect: true },
+      { name: "no age", vars: { user: {} }, expectError: "no such key" },
+    ]);
+
+    expect(passed).toBe(2);
+    expect(failed).toBe(1);
+    expect(results[0]).toEqual({ name: "adult", passed: true, actual: true });
+    expect(results[1]).toEqual({
+      name: "minor",
+      passed: false,
+      actual: false,
+      diffs: [{ path: "", expected: true, actual: false }],
+    });
+    expect(results[2].passed).toBe(true);
+    expect(results[2].error).toContain("no such key");
+  });
+
+  test("should diff maps and lists", async () => {
+    const program = await env.compile(
+      '{"name": user.name, "tags": [1, 2.0], "x-id": 1}',
+    );
+    const { results } = await program.runTests([
+      {
+        vars: { user: { name: "a" } },
+        expect: { name: "b", tags: [1, 2, 3], "x-id": 1 },
+      },
+    ]);
+
+    expect(results[0].passed).toBe(false);
+    expect(results[0].diffs).toEqual([
+      { path: "name", expected: "b", actual: "a" },
+      { path: "tags[2]", expected: 3 },
+    ]);
+  });
+
+  test("should fail cases that don't error as expected", async () => {
+    const program = await env.compile("user.age");
+    const { results } = await program.runTests([
+      { vars: { user: { age: 1 } }, expectError: "no such key" },
+      { vars: { user: {} }, expect: 1 },
+    ]);
+
+    expect(results[0]).toEqual({ passed: false, actual: 1 });
+    expect(results[1].passed).toBe(false);
+    expect(results[1].error).toContain("no such key");
+  });
+});
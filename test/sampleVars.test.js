@@ -0,0 +1,55 @@
+import { Env } from "../dist/index.js";
+
+describe("Sample variables", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        {
+          name: "user",
+          type: {
+            kind: "object",
+            fields: { name: "string", age: "int", roles: "list<string>" },
+          },
+        },
+        { name: "created", type: "timestamp" },
+        { name: "limits", type: "map<string, double>" },
+      ],
+      constants: [{ name: "max", type: "int", value: 10 }],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should generate values matching the declared types", async () => {
+    const vars = await env.generateSampleVars();
+
+    expect(Object.keys(vars).sort()).toEqual(["created", "limits", "user"]);
+    expect(typeof vars.user.name).toBe("string");
+    expect(Number.isInteger(vars.user.age)).toBe(true);
+    expect(vars.user.roles.length).toBeGreaterThan(0);
+    expect(vars.created).toMatch(/^2024-\d\d-\d\dT\d\d:\d\d:\d\dZ$/);
+    for (const limit of Object.values(vars.limits)) {
+      expect(typeof limit).toBe("number");
+    }
+  });
+
+  test("should generate the same values for the same seed", async () => {
+    const first = await env.generateSampleVars({ seed: 7 });
+    const second = await env.generateSampleVars({ seed: 7 });
+
+    expect(second).toEqual(first);
+  });
+
+  test("should generate values programs accept", async () => {
+    const program = await env.compile(
+      "user.age >= 0 && size(user.roles) > 0 && size(limits) > 0",
+    );
+    const { user, limits } = await env.generateSampleVars({ seed: 3 });
+
+    expect(await program.eval({ user, limits })).toBe(true);
+  });
+});
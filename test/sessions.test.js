@@ -0,0 +1,59 @@
+import { Env, Session, CELFunction } from "../dist/index.js";
+
+describe("Sessions", () => {
+  test("should destroy environments and programs together", async () => {
+    const session = await Session.new();
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+      functions: [
+        CELFunction.new("double")
+          .param("x", "double")
+          .returns("double")
+          .implement((x) => x * 2),
+      ],
+      session,
+    });
+
+    const program = await env.compile("double(x)");
+    expect(await program.eval({ x: 21 })).toBe(42);
+
+    session.destroy();
+    expect(session.isDestroyed()).toBe(true);
+
+    await expect(program.eval({ x: 1 })).rejects.toThrow(
+      "Program has been destroyed",
+    );
+    await expect(env.compile("x")).rejects.toThrow(
+      "Environment has been destroyed",
+    );
+
+    // Destroying handles of a destroyed session is a no-op
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should leave environments outside the session untouched", async () => {
+    const session = await Session.new();
+    const inside = await Env.new({ session });
+    const outside = await Env.new();
+
+    session.destroy();
+
+    const program = await outside.compile("1 + 1");
+    expect(await program.eval()).toBe(2);
+
+    await expect(inside.compile("1")).rejects.toThrow();
+
+    program.destroy();
+    outside.destroy();
+  });
+
+  test("should reject environments in a destroyed session", async () => {
+    const session = await Session.new();
+    session.destroy();
+
+    await expect(Env.new({ session })).rejects.toThrow(
+      "Session has been destroyed",
+    );
+  });
+});
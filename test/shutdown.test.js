@@ -0,0 +1,43 @@
+import { Env, CELFunction, shutdown } from "../dist/index.js";
+
+describe("shutdown", () => {
+  test("should tear down all state and reinitialize on next use", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+      functions: [
+        CELFunction.new("inc")
+          .param("x", "double")
+          .returns("double")
+          .implement((x) => x + 1),
+      ],
+    });
+    const program = await env.compile("inc(x)");
+    expect(await program.eval({ x: 1 })).toBe(2);
+
+    await shutdown();
+    expect(typeof globalThis.createEnv).toBe("undefined");
+
+    // The next call loads a fresh instance
+    const fresh = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const freshProgram = await fresh.compile("x * 2.0");
+    expect(await freshProgram.eval({ x: 21 })).toBe(42);
+
+    // Handles from before the shutdown never reach the new instance
+    await expect(program.eval({ x: 1 })).rejects.toThrow(
+      "Program has been destroyed",
+    );
+    program.destroy();
+    env.destroy();
+
+    freshProgram.destroy();
+    fresh.destroy();
+  });
+
+  test("should be a no-op when called twice", async () => {
+    await Env.new();
+    await shutdown();
+    await shutdown();
+  });
+});
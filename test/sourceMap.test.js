@@ -0,0 +1,102 @@
+import { Env } from "../dist/index.js";
+
+/** Pairs the text of each generated range with the original text it maps to */
+function mapped(generated, original, sourceMap) {
+  return sourceMap.map((m) => [
+    generated.slice(m.generated.start, m.generated.end),
+    original.slice(m.original.start, m.original.end),
+  ]);
+}
+
+describe("Source maps", () => {
+  test("should map the optimized source to the expression", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "int" }],
+    });
+    const expr = "x + (2 * 3)   > 10";
+    const program = await env.compile(expr, {
+      optimize: true,
+      optimizedSource: true,
+      sourceMap: true,
+    });
+
+    expect(program.optimizedSource).toBe("x + 6 > 10");
+    expect(mapped(program.optimizedSource, expr, program.sourceMap)).toEqual([
+      ["x + 6 > 10", "x + (2 * 3)   > 10"],
+      ["x + 6", "x + (2 * 3)"],
+      ["x", "x"],
+      ["6", "2 * 3"],
+      ["10", "10"],
+    ]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should map macros as they were written", async () => {
+    const env = await Env.new({
+      variables: [{ name: "l", type: "list<int>" }],
+    });
+    const expr = "l.all(i,  i > 0) && has({'a': 1}.a)";
+    const program = await env.compile(expr, {
+      optimizedSource: true,
+      sourceMap: true,
+    });
+
+    const pairs = mapped(program.optimizedSource, expr, program.sourceMap);
+    expect(pairs).toContainEqual(["l.all(i, i > 0)", "l.all(i,  i > 0)"]);
+    expect(pairs).toContainEqual(["i > 0", "i > 0"]);
+    expect(pairs).toContainEqual(['has({"a": 1}.a)', "has({'a': 1}.a)"]);
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should count ranges in UTF-16 code units", async () => {
+    const env = await Env.new({
+      variables: [{ name: "s", type: "string" }],
+    });
+    const expr = "'é😀' + s";
+    const { canonical, sourceMap } = await env.canonicalHash(expr);
+
+    expect(mapped(canonical, expr, sourceMap)).toEqual([
+      ['"é😀" + s', "'é😀' + s"],
+      ['"é😀"', "'é😀'"],
+      ["s", "s"],
+    ]);
+
+    env.destroy();
+  });
+
+  test("should map the new source of a replaced program", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "int" }],
+    });
+    const program = await env.compile("x > 1", {
+      optimizedSource: true,
+      sourceMap: true,
+    });
+
+    await program.replace("x  * 2 == 4");
+    expect(program.optimizedSource).toBe("x * 2 == 4");
+    expect(program.sourceMap[0]).toEqual({
+      generated: { start: 0, end: 10 },
+      original: { start: 0, end: 11 },
+    });
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should only map when the optimized source is requested", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "int" }],
+    });
+    const program = await env.compile("x > 1", { sourceMap: true });
+
+    expect(program.sourceMap).toBeUndefined();
+
+    program.destroy();
+    env.destroy();
+  });
+});
@@ -0,0 +1,74 @@
+import { instantiate } from "../dist/node.js";
+import { StaleHandleError } from "../dist/index.js";
+
+describe("Stale handles", () => {
+  test("should reject the ID of a destroyed program", async () => {
+    const cel = await instantiate();
+    const { envID } = cel.createEnv([{ name: "x", type: "double" }]);
+
+    const { programID } = cel.compileExpr(envID, "x + 1.0");
+    cel.destroyProgram(programID);
+
+    expect(cel.evalProgram(programID, { x: 1 })).toEqual({
+      error: `stale program handle: ${programID} was destroyed`,
+      errorCode: "stale_handle",
+      handle: programID,
+    });
+
+    cel.destroyEnv(envID);
+  });
+
+  test("should give reused slots new IDs", async () => {
+    const cel = await instantiate();
+    const { envID } = cel.createEnv([{ name: "x", type: "double" }]);
+
+    const first = cel.compileExpr(envID, "x + 1.0").programID;
+    cel.destroyProgram(first);
+    const second = cel.compileExpr(envID, "x + 2.0").programID;
+
+    expect(second).not.toBe(first);
+    expect(cel.evalProgram(second, { x: 1 }).result).toBe(3);
+    expect(cel.evalProgram(first, { x: 1 }).errorCode).toBe("stale_handle");
+
+    cel.destroyProgram(second);
+    cel.destroyEnv(envID);
+  });
+
+  test("should reject the ID of a destroyed environment", async () => {
+    const cel = await instantiate();
+    const { envID } = cel.createEnv([]);
+    cel.destroyEnv(envID);
+
+    expect(cel.compileExpr(envID, "1 + 1").errorCode).toBe("stale_handle");
+  });
+
+  test("should reject the IDs of another instance", async () => {
+    const previous = await instantiate();
+    const { envID } = previous.createEnv([]);
+    const { sessionID } = previous.createSession();
+
+    const cel = await instantiate();
+    const result = cel.compileExpr(envID, "1 + 1");
+    expect(result.errorCode).toBe("stale_handle");
+    expect(result.error).toMatch(/issued by another instance of the module/);
+    expect(cel.destroySession(sessionID).errorCode).toBe("stale_handle");
+
+    previous.destroySession(sessionID);
+    previous.destroyEnv(envID);
+  });
+
+  test("should report unknown IDs as not found", async () => {
+    const cel = await instantiate();
+
+    expect(cel.evalProgram("prg_99_1", {}).error).toBe(
+      "program not found: prg_99_1",
+    );
+  });
+
+  test("should be exported as an error class", () => {
+    const error = new StaleHandleError("stale", "prg_0_1");
+    expect(error).toBeInstanceOf(Error);
+    expect(error.code).toBe("stale_handle");
+    expect(error.handle).toBe("prg_0_1");
+  });
+});
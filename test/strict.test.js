@@ -0,0 +1,90 @@
+import { Env, InvalidVariablesError, Program } from "../dist/index.js";
+
+describe("Strict evaluation", () => {
+  let env;
+  let program;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [
+        { name: "count", type: "int" },
+        { name: "ratio", type: "double" },
+        { name: "tags", type: "list<string>" },
+        { name: "limits", type: "map<string, list<double>>" },
+        { name: "extra", type: "dyn" },
+      ],
+    });
+    program = await env.compile(
+      "ratio > 0.5 && 'beta' in tags && size(limits) > 0 && extra != null",
+    );
+  });
+
+  afterAll(() => {
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should evaluate variables matching their declarations", async () => {
+    const result = await program.eval(
+      { ratio: 1, tags: ["beta"], limits: { a: [1.5] }, extra: "x" },
+      { strict: true },
+    );
+    expect(result).toBe(true);
+  });
+
+  test("should report every mismatching value by path", async () => {
+    const vars = {
+      ratio: "high",
+      tags: ["beta", 3],
+      limits: { a: [1, "2"], b: 4 },
+      extra: null,
+    };
+    const error = await program
+      .eval(vars, { strict: true })
+      .catch((err) => err);
+    expect(error).toBeInstanceOf(InvalidVariablesError);
+    expect(error.variableErrors).toEqual([
+      { path: 'limits["a"][1]', message: "expected double, got string" },
+      { path: 'limits["b"]', message: "expected list(double), got double" },
+      { path: "ratio", message: "expected double, got string" },
+      { path: "tags[1]", message: "expected string, got double" },
+    ]);
+    expect(error.message).toContain("ratio: expected double, got string");
+  });
+
+  test("should report missing variables the program reads", async () => {
+    const error = await program
+      .eval({ ratio: 1, tags: [] }, { strict: true })
+      .catch((err) => err);
+    expect(error.variableErrors).toEqual([
+      { path: "extra", message: "missing variable" },
+      { path: "limits", message: "missing variable" },
+    ]);
+  });
+
+  test("should explain numbers passed for int variables", async () => {
+    const countProgram = await env.compile("count + 1");
+    await expect(
+      countProgram.eval({ count: 1 }, { strict: true }),
+    ).rejects.toThrow("count: expected int, got double");
+    countProgram.destroy();
+  });
+
+  test("should leave non-strict evaluations unchanged", async () => {
+    await expect(
+      program.eval({ ratio: 1, tags: ["beta"], limits: {}, extra: 1 }),
+    ).resolves.toBe(false);
+  });
+
+  test("should validate each program of evalAll", async () => {
+    const ratioProgram = await env.compile("ratio * 2.0");
+    const [outcome] = await Program.evalAll(
+      [ratioProgram],
+      { ratio: "x" },
+      { strict: true },
+    );
+    expect(outcome.status).toBe("rejected");
+    expect(outcome.reason).toBeInstanceOf(InvalidVariablesError);
+    ratioProgram.destroy();
+  });
+});
@@ -0,0 +1,69 @@
+import { Env } from "../dist/index.js";
+
+describe("Expression templates", () => {
+  let env;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "user", type: "map<string, dyn>" }],
+    });
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  const user = { name: "o'neil", role: "admin", score: 0.5 };
+
+  test("should bind strings as escaped literals", async () => {
+    const program = await env.instantiateTemplate("user.name == {{name}}", {
+      name: "o'neil",
+    });
+    await expect(program.eval({ user })).resolves.toBe(true);
+    program.destroy();
+  });
+
+  test("should not let bindings change the expression", async () => {
+    const program = await env.instantiateTemplate("user.name == {{ name }}", {
+      name: `x" || true || "`,
+    });
+    await expect(program.eval({ user })).resolves.toBe(false);
+    program.destroy();
+  });
+
+  test("should render lists, maps and numbers", async () => {
+    const program = await env.instantiateTemplate(
+      "user.role in {{roles}} && {{limits}}[user.role] > user.score && " +
+        "user.score > {{min}}",
+      { roles: ["admin", "owner"], limits: { admin: 1.5 }, min: -1 },
+    );
+    await expect(program.eval({ user })).resolves.toBe(true);
+    program.destroy();
+  });
+
+  test("should render whole numbers as ints", async () => {
+    const program = await env.instantiateTemplate(
+      "size(user) == {{n}} && type({{half}}) == double",
+      { n: 3, half: 0.5 },
+    );
+    await expect(program.eval({ user })).resolves.toBe(true);
+    program.destroy();
+  });
+
+  test("should leave placeholders in string literals", async () => {
+    const program = await env.instantiateTemplate("'{{name}}' + {{name}}", {
+      name: "!",
+    });
+    await expect(program.eval({})).resolves.toBe("{{name}}!");
+    program.destroy();
+  });
+
+  test("should reject placeholders without a binding", async () => {
+    await expect(
+      env.instantiateTemplate("user.name == {{name}}", {}),
+    ).rejects.toThrow("no binding for placeholder {{name}}");
+    await expect(env.instantiateTemplate("{{}}", {})).rejects.toThrow(
+      "malformed placeholder at offset 0",
+    );
+  });
+});
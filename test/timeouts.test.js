@@ -0,0 +1,93 @@
+import { Env, CELFunction } from "../dist/index.js";
+
+describe("Evaluation timeouts", () => {
+  let env;
+  let contexts;
+
+  beforeAll(async () => {
+    env = await Env.new({
+      variables: [{ name: "xs", type: "list<double>" }],
+      functions: [
+        CELFunction.new("hang")
+          .param("x", "double")
+          .returns("bool")
+          .implement((x, context) => {
+            contexts.push(context);
+            return new Promise(() => {});
+          }),
+        CELFunction.new("identity")
+          .param("x", "double")
+          .returns("double")
+          .implement((x, context) => {
+            contexts.push(context);
+            return x;
+          }),
+      ],
+    });
+  });
+
+  beforeEach(() => {
+    contexts = [];
+  });
+
+  afterAll(() => {
+    env.destroy();
+  });
+
+  test("should stop long comprehensions", async () => {
+    const program = await env.compile(
+      "xs.all(a, xs.all(b, xs.all(c, xs.all(d, a + b + c + d >= 0.0))))",
+    );
+    const xs = Array.from({ length: 60 }, (_, i) => i);
+
+    const start = Date.now();
+    await expect(program.eval({ xs }, { timeoutMs: 20 })).rejects.toThrow(
+      "evaluation timed out after 20ms",
+    );
+    expect(Date.now() - start).toBeLessThan(1000);
+    program.destroy();
+  });
+
+  test("should not time out evaluations that finish in time", async () => {
+    const program = await env.compile("xs.all(x, x >= 0.0)");
+    await expect(
+      program.eval({ xs: [1, 2, 3] }, { timeoutMs: 1000 }),
+    ).resolves.toBe(true);
+    program.destroy();
+  });
+
+  test("should reject negative timeouts", async () => {
+    const program = await env.compile("size(xs)");
+    await expect(program.eval({ xs: [] }, { timeoutMs: -1 })).rejects.toThrow(
+      "timeoutMs must not be negative",
+    );
+    program.destroy();
+  });
+
+  test("should stop waiting for hung functions", async () => {
+    const program = await env.compile("hang(1.0)");
+
+    await expect(
+      program.evalConcurrent({ xs: [] }, { timeoutMs: 50 }),
+    ).rejects.toThrow("evaluation timed out after 50ms");
+
+    const [context] = contexts;
+    expect(context.remainingMs).toBeGreaterThan(0);
+    expect(context.remainingMs).toBeLessThanOrEqual(50);
+    expect(context.deadline).toBeGreaterThan(Date.now() - 1000);
+    expect(context.signal.aborted).toBe(true);
+    expect(context.signal.reason.name).toBe("TimeoutError");
+    program.destroy();
+  });
+
+  test("should pass a context only to concurrent evaluations", async () => {
+    const program = await env.compile("identity(1.0)");
+
+    await program.eval({ xs: [] });
+    await program.evalConcurrent({ xs: [] });
+    expect(contexts[0]).toBeUndefined();
+    expect(contexts[1].signal.aborted).toBe(false);
+    expect(contexts[1].remainingMs).toBeUndefined();
+    program.destroy();
+  });
+});
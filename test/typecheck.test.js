@@ -111,6 +111,42 @@ describe("CEL Typechecking", () => {
     });
   });
 
+  describe("Type expressions", () => {
+    test("should declare variables with nested type expressions", async () => {
+      const env = await Env.new({
+        variables: [
+          { name: "rows", type: "list<map<string,int>>" },
+          { name: "series", type: "map<string, list<double>>" },
+        ],
+      });
+      expect((await env.typecheck("rows")).type).toEqual({
+        kind: "list",
+        elementType: { kind: "map", keyType: "string", valueType: "int" },
+      });
+      expect((await env.typecheck("series['a'][0]")).type).toBe("double");
+    });
+
+    test("should accept type expressions inside object types", async () => {
+      const env = await Env.new({
+        variables: [
+          {
+            name: "groups",
+            type: { kind: "map", keyType: "string", valueType: "list<int>" },
+          },
+        ],
+      });
+      const result = await env.typecheck("groups['a'][0] + 1");
+      expect(result.type).toBe("int");
+    });
+
+    test("should fall back to dyn for malformed type expressions", async () => {
+      const env = await Env.new({
+        variables: [{ name: "x", type: "list<int" }],
+      });
+      expect((await env.typecheck("x")).type).toBe("dyn");
+    });
+  });
+
   describe("Comparison expressions", () => {
     test("should typecheck comparison expression", async () => {
       const env = await Env.new({
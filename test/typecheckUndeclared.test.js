@@ -0,0 +1,55 @@
+import { Env } from "../dist/index.js";
+
+describe("Typechecking with undeclared identifiers", () => {
+  test("should check undeclared identifiers as dyn", async () => {
+    const env = await Env.new({ variables: [{ name: "x", type: "int" }] });
+
+    const result = await env.typecheck("x + count > limit", {
+      allowUndeclared: true,
+    });
+    expect(result.type).toBe("bool");
+    expect(result.undeclared).toEqual(["count", "limit"]);
+    expect(result.issues).toEqual([
+      expect.objectContaining({
+        severity: "warning",
+        message: "undeclared reference to 'count', checked as dyn",
+        location: { line: 1, column: 4 },
+      }),
+      expect.objectContaining({
+        severity: "warning",
+        message: "undeclared reference to 'limit', checked as dyn",
+        location: { line: 1, column: 12 },
+      }),
+    ]);
+
+    env.destroy();
+  });
+
+  test("should suggest declared names", async () => {
+    const env = await Env.new({
+      variables: [{ name: "user", type: "map<string, string>" }],
+    });
+
+    const result = await env.typecheck("usr.name", { allowUndeclared: true });
+    expect(result.type).toBe("dyn");
+    expect(result.issues[0].didYouMean).toEqual(["user"]);
+
+    env.destroy();
+  });
+
+  test("should still fail on other errors", async () => {
+    const env = await Env.new({ variables: [{ name: "x", type: "int" }] });
+
+    await expect(
+      env.typecheck("x + 'a' + y", { allowUndeclared: true }),
+    ).rejects.toThrow("found no matching overload");
+    await expect(
+      env.typecheck("missing(y)", { allowUndeclared: true }),
+    ).rejects.toThrow("undeclared reference to 'missing'");
+    await expect(env.typecheck("y")).rejects.toThrow(
+      "undeclared reference to 'y'",
+    );
+
+    env.destroy();
+  });
+});
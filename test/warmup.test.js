@@ -0,0 +1,58 @@
+import { Env, ProgramOptions } from "../dist/index.js";
+
+describe("Warmup", () => {
+  test("should prepare a program for strict evaluations", async () => {
+    const env = await Env.new({
+      variables: [{ name: "x", type: "double" }],
+    });
+    const program = await env.compile("x * 2.0");
+
+    await program.warmup();
+    expect(await program.eval({ x: 21 }, { strict: true })).toBe(42);
+    await expect(program.eval({ x: "21" }, { strict: true })).rejects.toThrow(
+      "expected double, got string",
+    );
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reuse the unknowns of the previous evaluation", async () => {
+    const env = await Env.new({
+      variables: [{ name: "request", type: "dyn" }],
+    });
+    const program = await env.compile(
+      "request.auth.admin || request.public",
+      { programOptions: [ProgramOptions.evalOptions(["OptPartialEval"])] },
+    );
+
+    const options = { unknowns: ["request.auth"] };
+    expect(
+      await program.evalDetailed({ request: { public: false } }, options),
+    ).toEqual({ result: null, unknown: ["request.auth"] });
+    expect(
+      await program.evalDetailed({ request: { public: true } }, options),
+    ).toEqual({ result: true });
+    expect(
+      await program.evalDetailed(
+        { request: { public: false } },
+        { unknowns: ["request"] },
+      ),
+    ).toEqual({ result: null, unknown: ["request"] });
+
+    program.destroy();
+    env.destroy();
+  });
+
+  test("should reject destroyed programs", async () => {
+    const env = await Env.new();
+    const program = await env.compile("1");
+    program.destroy();
+
+    await expect(program.warmup()).rejects.toThrow(
+      "Program has been destroyed",
+    );
+
+    env.destroy();
+  });
+});